@@ -70,6 +70,42 @@ func S2I(s string) int64 {
 	}
 }
 
+// StringToIntOr parses s as a base-10 int, ignoring surrounding whitespace.
+// It returns def when s is empty or not a valid int, which suits optional
+// query parameters:
+//
+//	page := common.StringToIntOr(r.URL.Query().Get("page"), 1)
+func StringToIntOr(s string, def int) int {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+// StringToBoolOr parses s as a boolean. In addition to the values accepted
+// by strconv.ParseBool it understands "yes"/"no" and "on"/"off" (sent by
+// HTML checkboxes), case-insensitively. It returns def for anything else.
+func StringToBoolOr(s string, def bool) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "yes", "y", "on":
+		return true
+	case "0", "f", "false", "no", "n", "off":
+		return false
+	}
+	return def
+}
+
+// StringToFloatOr parses s as a float64, ignoring surrounding whitespace.
+// It returns def when s is empty, malformed, NaN or infinite.
+func StringToFloatOr(s string, def float64) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return def
+	}
+	return f
+}
+
 // S2B converts a string to a byte slice.
 // It returns the byte representation of the string.
 func S2B(s string) []byte {
@@ -197,12 +233,51 @@ func Reverse(s string) string {
 
 // Trunc500 truncates a string to a maximum length of 500 characters.
 func Trunc500(s string) string {
-	if len(s) > 500 {
-		return s[:500]
+	return Trunc(s, 500)
+}
+
+// Trunc truncates a string to at most n characters (runes), never cutting
+// a multi-byte character in half. A non-positive n returns "".
+func Trunc(s string, n int) string {
+	if i := runeOffset(s, n); i >= 0 {
+		return s[:i]
 	}
 	return s
 }
 
+// TruncEllipsis truncates a string like Trunc but marks the cut with
+// "...", which counts towards the n characters. Strings of n characters or
+// fewer are returned unchanged, and when n is too small to fit the
+// ellipsis the string is cut without one.
+func TruncEllipsis(s string, n int) string {
+	if runeOffset(s, n) < 0 {
+		return s
+	}
+	if n <= len("...") {
+		return Trunc(s, n)
+	}
+	return Trunc(s, n-len("...")) + "..."
+}
+
+// runeOffset returns the byte offset of the n-th rune of s, or -1 when s
+// has n runes or fewer
+func runeOffset(s string, n int) int {
+	if n <= 0 {
+		if s == "" {
+			return -1
+		}
+		return 0
+	}
+	count := 0
+	for i := range s {
+		if count == n {
+			return i
+		}
+		count++
+	}
+	return -1
+}
+
 // GetSuffix returns the portion of a string after the final occurrence of the
 // supplied split delimiter.
 func GetSuffix(s string, split string) string {
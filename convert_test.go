@@ -17,7 +17,11 @@
 // utilities implemented in convert.go.
 package common
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
 
 // TestCamelCase verifies that CamelCase converts dash separated strings into
 // camel cased words.
@@ -72,6 +76,73 @@ func TestS2F(t *testing.T) {
 	}
 }
 
+// TestStringToIntOr checks parsing with a fallback default.
+func TestStringToIntOr(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"42", 42},
+		{" -7 ", -7},
+		{"", 10},
+		{"abc", 10},
+		{"3.5", 10},
+		{"99999999999999999999", 10},
+	}
+	for _, tt := range tests {
+		if got := StringToIntOr(tt.in, 10); got != tt.want {
+			t.Errorf("StringToIntOr(%q, 10) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestStringToBoolOr checks the accepted spellings and the fallback.
+func TestStringToBoolOr(t *testing.T) {
+	tests := []struct {
+		in   string
+		def  bool
+		want bool
+	}{
+		{"true", false, true},
+		{"1", false, true},
+		{"ON", false, true},
+		{" yes ", false, true},
+		{"false", true, false},
+		{"0", true, false},
+		{"off", true, false},
+		{"No", true, false},
+		{"", true, true},
+		{"", false, false},
+		{"maybe", true, true},
+	}
+	for _, tt := range tests {
+		if got := StringToBoolOr(tt.in, tt.def); got != tt.want {
+			t.Errorf("StringToBoolOr(%q, %v) = %v, want %v", tt.in, tt.def, got, tt.want)
+		}
+	}
+}
+
+// TestStringToFloatOr checks parsing with a fallback default.
+func TestStringToFloatOr(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"3.14", 3.14},
+		{" 2 ", 2},
+		{"-1e3", -1000},
+		{"", 0.5},
+		{"bogus", 0.5},
+		{"NaN", 0.5},
+		{"Inf", 0.5},
+	}
+	for _, tt := range tests {
+		if got := StringToFloatOr(tt.in, 0.5); got != tt.want {
+			t.Errorf("StringToFloatOr(%q, 0.5) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
 // TestRound exercises rounding with various precisions and negative numbers.
 func TestRound(t *testing.T) {
 	tests := []struct {
@@ -93,3 +164,50 @@ func TestRound(t *testing.T) {
 		})
 	}
 }
+
+// TestTrunc checks rune-aware truncation, with and without an ellipsis.
+func TestTrunc(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		n            int
+		want         string
+		wantEllipsis string
+	}{
+		{"short", "hello", 10, "hello", "hello"},
+		{"exact", "hello", 5, "hello", "hello"},
+		{"ascii", "hello world", 8, "hello wo", "hello..."},
+		{"accents", "crème brûlée", 8, "crème br", "crème..."},
+		{"cjk", "日本語のテキスト", 5, "日本語のテ", "日本..."},
+		{"emoji", "👍👍👍👍👍", 4, "👍👍👍👍", "👍..."},
+		{"too short for ellipsis", "日本語のテキスト", 2, "日本", "日本"},
+		{"zero", "hello", 0, "", ""},
+		{"negative", "hello", -1, "", ""},
+		{"empty", "", 3, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Trunc(tt.in, tt.n)
+			if got != tt.want {
+				t.Errorf("Trunc(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Trunc(%q, %d) = %q is not valid UTF-8", tt.in, tt.n, got)
+			}
+
+			got = TruncEllipsis(tt.in, tt.n)
+			if got != tt.wantEllipsis {
+				t.Errorf("TruncEllipsis(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.wantEllipsis)
+			}
+			if n := utf8.RuneCountInString(got); n > max(tt.n, 0) {
+				t.Errorf("TruncEllipsis(%q, %d) has %d runes", tt.in, tt.n, n)
+			}
+		})
+	}
+
+	// Trunc500 counts characters, not bytes
+	long := strings.Repeat("é", 600)
+	if got := Trunc500(long); utf8.RuneCountInString(got) != 500 || !utf8.ValidString(got) {
+		t.Errorf("Trunc500 kept %d runes, want 500", utf8.RuneCountInString(got))
+	}
+}
@@ -18,9 +18,13 @@
 package common
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -99,36 +103,8 @@ func GetCookieID(w http.ResponseWriter, r *http.Request) string {
 	if err != nil || cookie == nil || cookie.Value == "" {
 		Debug("Error: %v", err)
 		Debug("New Cookie...")
-		// Generate cryptographically secure random ID instead of MD5 hash
-		id, err = GenerateSecureID()
-		if err != nil {
-			Error("Failed to generate secure cookie ID: %v", err)
-			// Fallback: use SHA-256 of timestamp + IP (still better than MD5)
-			ts := strconv.FormatInt(time.Now().UnixNano(), 10)
-			id = SecureHash(ts + r.RemoteAddr)
-		}
-		host := r.Host
-		if h, _, err := net.SplitHostPort(host); err == nil {
-			host = h
-		}
-		
-		// Determine if we're on localhost for development
-		isLocalhost := host == "localhost" || host == "127.0.0.1"
-		
-		ck := &http.Cookie{
-			Name:     "ID",
-			Value:    id,
-			Path:     "/",
-			Expires:  time.Now().Add(time.Hour * 24 * 30),
-			HttpOnly: true,
-			Secure:   !isLocalhost, // false for localhost, true for production
-			SameSite: http.SameSiteLaxMode,
-		}
-		if !isLocalhost {
-			// Set the domain so the cookie is shared across subdomains
-			ck.Domain = host
-		}
-		http.SetCookie(w, ck)
+		id = newCookieID(r)
+		setIDCookie(w, r, id)
 		Debug("New Cookie = %v", id)
 		/*
 			key := datastore.NewKey(c, "Visitors", id, 0, nil)
@@ -170,3 +146,98 @@ func GetCookieID(w http.ResponseWriter, r *http.Request) string {
 	}
 	return id
 }
+
+// GetSignedCookieID is GetCookieID with a tamper-evident cookie. The cookie
+// holds the visitor ID followed by an HMAC-SHA256 signature keyed with
+// secret, so clients cannot choose or alter their ID:
+//
+//	id := common.GetSignedCookieID(w, r, []byte(os.Getenv("COOKIE_SECRET")))
+//
+// A cookie with a missing or invalid signature, including a plaintext
+// cookie issued by GetCookieID, is replaced by a new signed ID. Switching
+// from GetCookieID therefore gives existing visitors new IDs once; keep
+// GetCookieID where that is not acceptable. The cookie attributes match
+// GetCookieID. An empty secret falls back to GetCookieID and logs an error.
+func GetSignedCookieID(w http.ResponseWriter, r *http.Request, secret []byte) string {
+	if len(secret) == 0 {
+		Error("GetSignedCookieID: empty secret, issuing an unsigned cookie")
+		return GetCookieID(w, r)
+	}
+
+	if cookie, err := r.Cookie("ID"); err == nil && cookie.Value != "" {
+		if id, ok := verifyCookieID(cookie.Value, secret); ok {
+			return id
+		}
+		Warn("GetSignedCookieID: invalid cookie signature, issuing a new ID")
+	}
+
+	id := newCookieID(r)
+	setIDCookie(w, r, signCookieID(id, secret))
+	return id
+}
+
+// newCookieID generates a random visitor ID
+func newCookieID(r *http.Request) string {
+	// Generate cryptographically secure random ID instead of MD5 hash
+	id, err := GenerateSecureID()
+	if err != nil {
+		Error("Failed to generate secure cookie ID: %v", err)
+		// Fallback: use SHA-256 of timestamp + IP (still better than MD5)
+		ts := strconv.FormatInt(time.Now().UnixNano(), 10)
+		id = SecureHash(ts + r.RemoteAddr)
+	}
+	return id
+}
+
+// setIDCookie sets the visitor ID cookie with the attributes documented on
+// GetCookieID
+func setIDCookie(w http.ResponseWriter, r *http.Request, value string) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	// Determine if we're on localhost for development
+	isLocalhost := host == "localhost" || host == "127.0.0.1"
+
+	ck := &http.Cookie{
+		Name:     "ID",
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(time.Hour * 24 * 30),
+		HttpOnly: true,
+		Secure:   !isLocalhost, // false for localhost, true for production
+		SameSite: http.SameSiteLaxMode,
+	}
+	if !isLocalhost {
+		// Set the domain so the cookie is shared across subdomains
+		ck.Domain = host
+	}
+	http.SetCookie(w, ck)
+}
+
+// signCookieID returns "<id>.<signature>"
+func signCookieID(id string, secret []byte) string {
+	return id + "." + cookieSignature(id, secret)
+}
+
+// verifyCookieID returns the ID of a value produced by signCookieID and
+// whether its signature is valid
+func verifyCookieID(value string, secret []byte) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return "", false
+	}
+	id, signature := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(cookieSignature(id, secret))) {
+		return "", false
+	}
+	return id, true
+}
+
+// cookieSignature returns the unpadded base64url HMAC-SHA256 of id
+func cookieSignature(id string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
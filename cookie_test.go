@@ -18,6 +18,7 @@ package common
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -136,3 +137,72 @@ func TestGetCookieIDLocalIP(t *testing.T) {
 		t.Error("Secure flag should be false on 127.0.0.1")
 	}
 }
+
+// idCookie returns the ID cookie set on w, or nil
+func idCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "ID" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// TestGetSignedCookieID verifies signed IDs are issued, accepted back, and
+// replaced when tampered with or unsigned.
+func TestGetSignedCookieID(t *testing.T) {
+	secret := []byte("test-cookie-secret")
+
+	w := httptest.NewRecorder()
+	id := GetSignedCookieID(w, httptest.NewRequest("GET", "http://example.com/", nil), secret)
+	issued := idCookie(w)
+	if id == "" || issued == nil {
+		t.Fatal("expected a new signed cookie")
+	}
+	if !strings.HasPrefix(issued.Value, id+".") || !issued.HttpOnly || !issued.Secure {
+		t.Fatalf("unexpected cookie %+v for id %q", issued, id)
+	}
+
+	last := "A"
+	if strings.HasSuffix(issued.Value, last) {
+		last = "B"
+	}
+	tampered := issued.Value[:len(issued.Value)-1] + last
+
+	tests := []struct {
+		name    string
+		value   string
+		secret  []byte
+		wantNew bool
+	}{
+		{name: "Valid signature", value: issued.Value, secret: secret, wantNew: false},
+		{name: "Tampered ID", value: "forged" + issued.Value[len(id):], secret: secret, wantNew: true},
+		{name: "Tampered signature", value: tampered, secret: secret, wantNew: true},
+		{name: "Unsigned legacy cookie", value: id, secret: secret, wantNew: true},
+		{name: "Other secret", value: issued.Value, secret: []byte("rotated-secret"), wantNew: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "http://example.com/", nil)
+			r.AddCookie(&http.Cookie{Name: "ID", Value: tt.value})
+
+			got := GetSignedCookieID(w, r, tt.secret)
+			reissued := idCookie(w)
+
+			if !tt.wantNew {
+				if got != id || reissued != nil {
+					t.Errorf("got id %q (new cookie %v), want existing %q", got, reissued != nil, id)
+				}
+				return
+			}
+			if got == id || got == "forged" || reissued == nil {
+				t.Fatalf("expected a new ID, got %q", got)
+			}
+			if _, ok := verifyCookieID(reissued.Value, tt.secret); !ok {
+				t.Errorf("reissued cookie %q is not signed", reissued.Value)
+			}
+		})
+	}
+}
@@ -17,8 +17,10 @@ package common
 // This file provides small cryptographic helpers used across the repository.
 //
 // SecureHash and GenerateSecureID provide cryptographically secure hashing and ID generation.
+// GenerateToken and SecureCompare create and check URL-safe random tokens.
 // Hash returns the CRC32 hash of a given string (for non-security checksums).
 // Encrypt and Decrypt perform authenticated encryption using AES-GCM.
+// Keyring (keyring.go) adds key IDs to ciphertexts so keys can be rotated.
 //
 // DEPRECATED: MD5() is deprecated and should not be used for security purposes.
 // Use SecureHash() for integrity checking or GenerateSecureID() for identifiers.
@@ -28,6 +30,8 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"hash/crc32"
@@ -54,6 +58,34 @@ func GenerateSecureID() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+// MinTokenBytes is the smallest entropy GenerateToken accepts (128 bits)
+const MinTokenBytes = 16
+
+// GenerateToken returns nBytes of crypto/rand entropy encoded as unpadded
+// URL-safe base64, suitable for unsubscribe links, webhook IDs and other
+// bearer tokens. Subpackages use it for request IDs, CSP nonces and email
+// message and content IDs instead of hand-rolling crypto/rand reads.
+// Requests below MinTokenBytes are rejected rather than producing a
+// guessable token, and a crypto/rand failure is returned as an error, never
+// a weak fallback.
+func GenerateToken(nBytes int) (string, error) {
+	if nBytes < MinTokenBytes {
+		return "", fmt.Errorf("token size %d bytes is below the minimum of %d", nBytes, MinTokenBytes)
+	}
+	b := make([]byte, nBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SecureCompare reports whether a and b are equal in constant time, so
+// comparing a secret token does not leak how many leading bytes matched.
+// Only the lengths of the inputs may be inferred from timing.
+func SecureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
 func Hash(data string) uint32 {
 	return crc32.ChecksumIEEE([]byte(data))
 }
@@ -16,6 +16,8 @@ package common
 
 import (
 	"context"
+	"encoding/base64"
+	"strings"
 	"testing"
 )
 
@@ -118,3 +120,61 @@ func TestDeriveKey(t *testing.T) {
 		t.Fatal("different inputs produced same derived key")
 	}
 }
+
+func TestGenerateToken(t *testing.T) {
+	for _, n := range []int{16, 32, 33, 64} {
+		token, err := GenerateToken(n)
+		if err != nil {
+			t.Fatalf("GenerateToken(%d) error: %v", n, err)
+		}
+		if want := base64.RawURLEncoding.EncodedLen(n); len(token) != want {
+			t.Errorf("GenerateToken(%d) length = %d, want %d", n, len(token), want)
+		}
+		if strings.ContainsAny(token, "+/=") {
+			t.Errorf("GenerateToken(%d) = %q is not URL-safe", n, token)
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(decoded) != n {
+			t.Errorf("GenerateToken(%d) decodes to %d bytes (err %v)", n, len(decoded), err)
+		}
+	}
+
+	for _, n := range []int{-1, 0, 8, MinTokenBytes - 1} {
+		if _, err := GenerateToken(n); err == nil {
+			t.Errorf("GenerateToken(%d) expected error", n)
+		}
+	}
+}
+
+func TestGenerateTokenUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		token, err := GenerateToken(MinTokenBytes)
+		if err != nil {
+			t.Fatalf("GenerateToken error: %v", err)
+		}
+		if seen[token] {
+			t.Fatalf("duplicate token %q after %d iterations", token, i)
+		}
+		seen[token] = true
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"token", "token", true},
+		{"", "", true},
+		{"token", "tokem", false},
+		{"token", "Token", false},
+		{"token", "token2", false},
+		{"token", "", false},
+	}
+	for _, tt := range tests {
+		if got := SecureCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("SecureCompare(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
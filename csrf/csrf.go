@@ -21,7 +21,8 @@
 //
 //	store := csrf.NewTokenStore()
 //	handler := store.Middleware(yourHandler)
-//	http.ListenAndServe(":8080", handler)
+//	srv := common.NewServer(":8080", handler, common.ServerOptions{})
+//	srv.ListenAndServeGraceful(context.Background())
 //
 // In HTML forms, include the CSRF token as a hidden field:
 //
@@ -41,48 +42,169 @@
 package csrf
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	tokenLength = 32
-	cookieName  = "csrf_token"
-	headerName  = "X-CSRF-Token"
-	formField   = "csrf_token"
+	cookieName = "csrf_token"
+	headerName = "X-CSRF-Token"
+	formField  = "csrf_token"
 )
 
+const (
+	// DefaultTokenBytes is the default token entropy (256 bits)
+	DefaultTokenBytes = 32
+	// MinTokenBytes is the smallest accepted token entropy (128 bits)
+	MinTokenBytes = 16
+)
+
+// randRead is crypto/rand.Read, replaceable in tests to simulate failures
+var randRead = rand.Read
+
+// Config holds the names used to transport the CSRF token.
+type Config struct {
+	// CookieName is the name of the cookie carrying the token.
+	CookieName string
+	// HeaderName is the request header checked for AJAX submissions.
+	HeaderName string
+	// FieldName is the form field checked for HTML form submissions.
+	FieldName string
+
+	// CheckOrigin enables Origin/Referer verification for state-changing
+	// methods as defense-in-depth on top of the token check. The Origin
+	// header is used when present, otherwise the Referer. Requests carrying
+	// neither header fall back to the token check alone.
+	CheckOrigin bool
+	// TrustedHosts lists additional hosts (e.g. "app.example.com") allowed
+	// to submit requests besides the request's own Host. Full origins such
+	// as "https://app.example.com" are accepted too.
+	TrustedHosts []string
+	// AllowNullOrigin permits requests sending "Origin: null" (sandboxed
+	// iframes, some redirects). Leave false unless such flows are required.
+	AllowNullOrigin bool
+
+	// TokenBytes is the number of random bytes per token. 16 (128 bits)
+	// keeps cookies small; values below MinTokenBytes are raised to it.
+	// Defaults to DefaultTokenBytes.
+	TokenBytes int
+
+	// SameSite is the SameSite attribute of the token cookie. Defaults to
+	// http.SameSiteStrictMode. Apps embedded cross-site in an iframe need
+	// http.SameSiteNoneMode, since browsers do not send Strict or Lax
+	// cookies with requests from a cross-site frame; SameSite=None always
+	// sets Secure, as browsers require.
+	SameSite http.SameSite
+	// Secure always marks the token cookie Secure. Otherwise it is Secure
+	// on TLS or X-Forwarded-Proto https requests, except on localhost.
+	Secure bool
+}
+
+// DefaultConfig returns the default CSRF configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		CookieName: cookieName,
+		HeaderName: headerName,
+		FieldName:  formField,
+		TokenBytes: DefaultTokenBytes,
+		SameSite:   http.SameSiteStrictMode,
+	}
+}
+
 // TokenStore manages CSRF tokens with automatic expiry and cleanup
 type TokenStore struct {
 	mu     sync.RWMutex
 	tokens map[string]time.Time
+	config *Config
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// tokenContextKey is the context key under which Middleware stores the
+// current token so templates can render it on the same request.
+type tokenContextKey struct{}
+
+// requestToken is the value stored in the request context by Middleware.
+type requestToken struct {
+	token     string
+	fieldName string
 }
 
 // NewTokenStore creates a new token store and starts a background cleanup goroutine
 // that removes expired tokens every hour
 func NewTokenStore() *TokenStore {
+	return NewTokenStoreWithConfig(nil)
+}
+
+// NewTokenStoreWithConfig creates a token store using custom cookie, header
+// and form field names. Empty fields fall back to the defaults.
+func NewTokenStoreWithConfig(config *Config) *TokenStore {
+	defaults := DefaultConfig()
+	if config == nil {
+		config = defaults
+	}
+	cfg := *config
+	if cfg.CookieName == "" {
+		cfg.CookieName = defaults.CookieName
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = defaults.HeaderName
+	}
+	if cfg.FieldName == "" {
+		cfg.FieldName = defaults.FieldName
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = defaults.SameSite
+	}
+	if cfg.TokenBytes == 0 {
+		cfg.TokenBytes = defaults.TokenBytes
+	} else if cfg.TokenBytes < MinTokenBytes {
+		cfg.TokenBytes = MinTokenBytes
+	}
+
 	store := &TokenStore{
 		tokens: make(map[string]time.Time),
+		config: &cfg,
+		stop:   make(chan struct{}),
 	}
 	// Cleanup expired tokens periodically
 	go store.cleanup()
 	return store
 }
 
-// GenerateToken creates a cryptographically secure random token
-// Returns the base64-encoded token string or an error if random generation fails
-func (ts *TokenStore) GenerateToken() (string, error) {
-	b := make([]byte, tokenLength)
-	if _, err := rand.Read(b); err != nil {
+// GenerateTokenN returns a base64-encoded token of n random bytes without
+// registering it in any store. n must be at least MinTokenBytes. A
+// crypto/rand failure is returned as an error; no token is produced.
+func GenerateTokenN(n int) (string, error) {
+	if n < MinTokenBytes {
+		return "", fmt.Errorf("CSRF token size must be at least %d bytes, got %d", MinTokenBytes, n)
+	}
+
+	b := make([]byte, n)
+	if _, err := randRead(b); err != nil {
 		return "", fmt.Errorf("failed to generate CSRF token: %w", err)
 	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
 
-	token := base64.URLEncoding.EncodeToString(b)
+// GenerateToken creates a cryptographically secure random token of the
+// configured size (256 bits by default) and registers it in the store.
+// Returns the base64-encoded token string or an error if random generation fails
+func (ts *TokenStore) GenerateToken() (string, error) {
+	token, err := GenerateTokenN(ts.config.TokenBytes)
+	if err != nil {
+		return "", err
+	}
 
 	// Store token with 24-hour expiry
 	ts.mu.Lock()
@@ -114,13 +236,26 @@ func (ts *TokenStore) ValidateToken(token string) bool {
 	return true
 }
 
+// Stop ends the background cleanup goroutine. The store keeps working but
+// expired tokens are then only removed when they are presented. It is safe
+// to call Stop more than once, e.g. from a common.LifecycleManager hook.
+func (ts *TokenStore) Stop() {
+	ts.stopOnce.Do(func() { close(ts.stop) })
+}
+
 // cleanup removes expired tokens from the store
-// Runs continuously in a background goroutine
+// Runs in a background goroutine until Stop is called
 func (ts *TokenStore) cleanup() {
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ts.stop:
+			return
+		case <-ticker.C:
+		}
+
 		ts.mu.Lock()
 		now := time.Now()
 		for token, expiry := range ts.tokens {
@@ -132,6 +267,52 @@ func (ts *TokenStore) cleanup() {
 	}
 }
 
+// RotateToken invalidates the token currently presented by the request and
+// issues a fresh one in the cookie. Call it right after a successful login so
+// a token planted before authentication cannot be reused (session fixation).
+// Returns the new token so handlers can render it immediately.
+func (ts *TokenStore) RotateToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	// Revoke every token the client may present for this request
+	ts.mu.Lock()
+	if cookie, err := r.Cookie(ts.config.CookieName); err == nil {
+		delete(ts.tokens, cookie.Value)
+	}
+	if headerToken := r.Header.Get(ts.config.HeaderName); headerToken != "" {
+		delete(ts.tokens, headerToken)
+	}
+	ts.mu.Unlock()
+
+	token, err := ts.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	ts.setCookie(w, r, token)
+	return token, nil
+}
+
+// setCookie writes the CSRF cookie carrying token to the response
+func (ts *TokenStore) setCookie(w http.ResponseWriter, r *http.Request, token string) {
+	// Determine if connection is secure
+	isSecure := r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+	if r.Host == "localhost" || r.Host == "127.0.0.1" {
+		isSecure = false // Allow insecure cookies on localhost for development
+	}
+	if ts.config.Secure || ts.config.SameSite == http.SameSiteNoneMode {
+		isSecure = true
+	}
+
+	// Set cookie (HttpOnly=false so JavaScript can read it for AJAX)
+	http.SetCookie(w, &http.Cookie{
+		Name:     ts.config.CookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: false, // JavaScript needs to read this for AJAX requests
+		Secure:   isSecure,
+		SameSite: ts.config.SameSite,
+		MaxAge:   86400, // 24 hours
+	})
+}
+
 // Middleware provides CSRF protection for HTTP handlers
 // Safe methods (GET, HEAD, OPTIONS) generate and set a new token
 // State-changing methods (POST, PUT, DELETE, PATCH) validate the token
@@ -145,41 +326,33 @@ func (ts *TokenStore) Middleware(next http.Handler) http.Handler {
 				return
 			}
 
-			// Determine if connection is secure
-			isSecure := r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
-			if r.Host == "localhost" || r.Host == "127.0.0.1" {
-				isSecure = false // Allow insecure cookies on localhost for development
-			}
+			ts.setCookie(w, r, token)
 
-			// Set cookie (HttpOnly=false so JavaScript can read it for AJAX)
-			http.SetCookie(w, &http.Cookie{
-				Name:     cookieName,
-				Value:    token,
-				Path:     "/",
-				HttpOnly: false, // JavaScript needs to read this for AJAX requests
-				Secure:   isSecure,
-				SameSite: http.SameSiteStrictMode,
-				MaxAge:   86400, // 24 hours
-			})
-
-			next.ServeHTTP(w, r)
+			// Expose the fresh token to templates rendered by this request;
+			// the cookie is only visible to the handler on the next request.
+			next.ServeHTTP(w, ts.withToken(r, token))
 			return
 		}
 
 		// Validate token for state-changing methods
 		if r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" || r.Method == "PATCH" {
-			cookieToken, err := r.Cookie(cookieName)
+			if ts.config.CheckOrigin && !ts.originAllowed(r) {
+				http.Error(w, "CSRF origin check failed", http.StatusForbidden)
+				return
+			}
+
+			cookieToken, err := r.Cookie(ts.config.CookieName)
 			if err != nil {
 				http.Error(w, "CSRF token cookie missing", http.StatusForbidden)
 				return
 			}
 
 			// Check header first (for AJAX), then form field
-			requestToken := r.Header.Get(headerName)
+			requestToken := r.Header.Get(ts.config.HeaderName)
 			if requestToken == "" {
 				// Parse form to get csrf_token field
 				if err := r.ParseForm(); err == nil {
-					requestToken = r.FormValue(formField)
+					requestToken = r.FormValue(ts.config.FieldName)
 				}
 			}
 
@@ -200,22 +373,112 @@ func (ts *TokenStore) Middleware(next http.Handler) http.Handler {
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, ts.withToken(r, cookieToken.Value))
 			return
 		}
 
 		// Other methods (TRACE, CONNECT, etc.) - pass through
-		next.ServeHTTP(w, r)
+		token := ""
+		if cookie, err := r.Cookie(ts.config.CookieName); err == nil {
+			token = cookie.Value
+		}
+		next.ServeHTTP(w, ts.withToken(r, token))
 	})
 }
 
-// GetToken retrieves the CSRF token from the request cookie
+// withToken stores token in the request context so GetToken and Field
+// return it without knowing the configured cookie name
+func (ts *TokenStore) withToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey{}, requestToken{
+		token:     token,
+		fieldName: ts.config.FieldName,
+	})
+	return r.WithContext(ctx)
+}
+
+// originAllowed reports whether the Origin (or, failing that, Referer) of r
+// matches the request host or one of the trusted hosts. Requests without
+// either header are allowed so the token check remains authoritative.
+func (ts *TokenStore) originAllowed(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return true
+	}
+	if source == "null" {
+		return ts.config.AllowNullOrigin
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if host == strings.ToLower(r.Host) {
+		return true
+	}
+	for _, trusted := range ts.config.TrustedHosts {
+		if strings.Contains(trusted, "://") {
+			if tu, err := url.Parse(trusted); err == nil {
+				trusted = tu.Host
+			}
+		}
+		if host == strings.ToLower(trusted) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetToken retrieves the CSRF token for the request
 // This helper function is useful for injecting the token into templates
-// Returns empty string if the cookie is not present
+// Behind Middleware it returns the token the store issued or validated,
+// read from the configured cookie; the default-named cookie is only
+// consulted for requests that did not pass through any Middleware.
+// Returns empty string if no token is present
 func GetToken(r *http.Request) string {
+	if rt, ok := r.Context().Value(tokenContextKey{}).(requestToken); ok {
+		return rt.token
+	}
 	cookie, err := r.Cookie(cookieName)
 	if err != nil {
 		return ""
 	}
 	return cookie.Value
 }
+
+// Field returns a hidden form input carrying the CSRF token for the request,
+// ready to be embedded in an HTML form. The input name follows the field name
+// configured on the TokenStore that issued the token. The name and value are
+// HTML-escaped. An empty string is returned when no token is available.
+//
+//	<form method="POST">
+//	    {{ csrfField .Request }}
+//	</form>
+func Field(r *http.Request) template.HTML {
+	name := formField
+	token := ""
+	if rt, ok := r.Context().Value(tokenContextKey{}).(requestToken); ok {
+		name = rt.fieldName
+		token = rt.token
+	} else {
+		token = GetToken(r)
+	}
+	if token == "" {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) +
+		`" value="` + template.HTMLEscapeString(token) + `">`)
+}
+
+// FuncMap returns template functions exposing the CSRF helpers. Register it
+// with template.New(...).Funcs(csrf.FuncMap()) to use {{ csrfField .Request }}
+// and {{ csrfToken .Request }} in templates.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrfField": Field,
+		"csrfToken": GetToken,
+	}
+}
@@ -15,6 +15,8 @@
 package csrf
 
 import (
+	"errors"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -368,6 +370,26 @@ func TestTokenExpiry(t *testing.T) {
 	}
 }
 
+func TestTokenStoreStop(t *testing.T) {
+	store := NewTokenStore()
+	store.Stop()
+	store.Stop() // must not panic when called twice
+
+	select {
+	case <-store.stop:
+	default:
+		t.Fatal("Stop should close the cleanup channel")
+	}
+
+	token, err := store.GenerateToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if !store.ValidateToken(token) {
+		t.Error("Store should keep working after Stop")
+	}
+}
+
 func TestGetToken(t *testing.T) {
 	t.Run("returns token from cookie", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/", nil)
@@ -444,6 +466,51 @@ func TestSecureCookie(t *testing.T) {
 	})
 }
 
+func TestCookieSameSite(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Config
+		host         string
+		wantSameSite http.SameSite
+		wantSecure   bool
+	}{
+		{"default is Strict", nil, "example.com", http.SameSiteStrictMode, false},
+		{"Lax", &Config{SameSite: http.SameSiteLaxMode}, "example.com", http.SameSiteLaxMode, false},
+		{"None forces Secure", &Config{SameSite: http.SameSiteNoneMode}, "localhost", http.SameSiteNoneMode, true},
+		{"Secure on plain HTTP", &Config{Secure: true}, "example.com", http.SameSiteStrictMode, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewTokenStoreWithConfig(tt.config)
+			defer store.Stop()
+			handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "http://"+tt.host+"/", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			var csrfCookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == cookieName {
+					csrfCookie = c
+				}
+			}
+			if csrfCookie == nil {
+				t.Fatal("CSRF cookie not found")
+			}
+			if csrfCookie.SameSite != tt.wantSameSite {
+				t.Errorf("SameSite = %v, want %v", csrfCookie.SameSite, tt.wantSameSite)
+			}
+			if csrfCookie.Secure != tt.wantSecure {
+				t.Errorf("Secure = %v, want %v", csrfCookie.Secure, tt.wantSecure)
+			}
+		})
+	}
+}
+
 func BenchmarkGenerateToken(b *testing.B) {
 	store := NewTokenStore()
 	b.ResetTimer()
@@ -480,3 +547,350 @@ func BenchmarkMiddleware(b *testing.B) {
 		handler.ServeHTTP(w, req)
 	}
 }
+
+func TestField(t *testing.T) {
+	t.Run("renders token issued by middleware", func(t *testing.T) {
+		store := NewTokenStore()
+		var rendered template.HTML
+		handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rendered = Field(r)
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		var token string
+		for _, c := range w.Result().Cookies() {
+			if c.Name == cookieName {
+				token = c.Value
+			}
+		}
+		want := `<input type="hidden" name="csrf_token" value="` + token + `">`
+		if string(rendered) != want {
+			t.Errorf("Field() = %q, want %q", rendered, want)
+		}
+	})
+
+	t.Run("respects configured field name", func(t *testing.T) {
+		store := NewTokenStoreWithConfig(&Config{CookieName: "app_csrf", FieldName: "_csrf"})
+		var rendered template.HTML
+		handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rendered = Field(r)
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		if !strings.Contains(string(rendered), `name="_csrf"`) {
+			t.Errorf("Field() = %q, want name=_csrf", rendered)
+		}
+		found := false
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "app_csrf" {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("Expected cookie named app_csrf")
+		}
+	})
+
+	t.Run("escapes cookie value", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: `x><script>alert(1)</script>`})
+		rendered := string(Field(req))
+		if strings.Contains(rendered, "<script>") {
+			t.Errorf("Field() did not escape value: %q", rendered)
+		}
+		if !strings.Contains(rendered, "&lt;script&gt;") {
+			t.Errorf("Field() = %q, want escaped markup", rendered)
+		}
+	})
+
+	t.Run("empty without token", func(t *testing.T) {
+		if got := Field(httptest.NewRequest("GET", "/", nil)); got != "" {
+			t.Errorf("Field() = %q, want empty", got)
+		}
+	})
+}
+
+func TestFuncMap(t *testing.T) {
+	store := NewTokenStore()
+	tmpl := template.Must(template.New("form").Funcs(FuncMap()).Parse(
+		`<form>{{ csrfField .Request }}</form>`))
+
+	var body strings.Builder
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := tmpl.Execute(&body, struct{ Request *http.Request }{r}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !strings.Contains(body.String(), `<form><input type="hidden" name="csrf_token" value="`) {
+		t.Errorf("Rendered template = %q", body.String())
+	}
+}
+
+func TestConfiguredNamesValidate(t *testing.T) {
+	store := NewTokenStoreWithConfig(&Config{CookieName: "app_csrf", FieldName: "_csrf"})
+	var seen string
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetToken(r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	token, err := store.GenerateToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	form := url.Values{}
+	form.Set("_csrf", token)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "app_csrf", Value: token})
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: "stale-default"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	if seen != token {
+		t.Errorf("GetToken() = %q, want the app_csrf cookie %q", seen, token)
+	}
+
+	// Pass-through methods never fall back to the default cookie
+	req = httptest.NewRequest("TRACE", "/", nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: "stale-default"})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "" {
+		t.Errorf("GetToken() = %q without an app_csrf cookie, want empty", seen)
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	store := NewTokenStore()
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	oldToken, err := store.GenerateToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	// Simulate the login handler rotating the token
+	loginReq := httptest.NewRequest("POST", "/login", nil)
+	loginReq.AddCookie(&http.Cookie{Name: cookieName, Value: oldToken})
+	loginW := httptest.NewRecorder()
+	newToken, err := store.RotateToken(loginW, loginReq)
+	if err != nil {
+		t.Fatalf("RotateToken failed: %v", err)
+	}
+	if newToken == "" || newToken == oldToken {
+		t.Fatalf("RotateToken returned %q, want a fresh token", newToken)
+	}
+
+	var cookieValue string
+	for _, c := range loginW.Result().Cookies() {
+		if c.Name == cookieName {
+			cookieValue = c.Value
+		}
+	}
+	if cookieValue != newToken {
+		t.Errorf("Cookie value = %q, want rotated token %q", cookieValue, newToken)
+	}
+
+	post := func(token string) int {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set(headerName, token)
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: token})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(oldToken); code != http.StatusForbidden {
+		t.Errorf("Old token: expected 403, got %d", code)
+	}
+	if code := post(newToken); code != http.StatusOK {
+		t.Errorf("New token: expected 200, got %d", code)
+	}
+}
+
+func TestOriginCheck(t *testing.T) {
+	store := NewTokenStoreWithConfig(&Config{
+		CheckOrigin:  true,
+		TrustedHosts: []string{"https://admin.example.com"},
+	})
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	token, err := store.GenerateToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		origin  string
+		referer string
+		want    int
+	}{
+		{"matching origin", "https://app.example.com", "", http.StatusOK},
+		{"trusted origin", "https://admin.example.com", "", http.StatusOK},
+		{"mismatched origin", "https://evil.example.net", "", http.StatusForbidden},
+		{"matching referer fallback", "", "https://app.example.com/form", http.StatusOK},
+		{"mismatched referer", "", "https://evil.example.net/form", http.StatusForbidden},
+		{"origin wins over referer", "https://evil.example.net", "https://app.example.com/", http.StatusForbidden},
+		{"missing headers", "", "", http.StatusOK},
+		{"null origin rejected", "null", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "https://app.example.com/submit", nil)
+			req.Header.Set(headerName, token)
+			req.AddCookie(&http.Cookie{Name: cookieName, Value: token})
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+
+	t.Run("null origin allowed when configured", func(t *testing.T) {
+		permissive := NewTokenStoreWithConfig(&Config{CheckOrigin: true, AllowNullOrigin: true})
+		h := permissive.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		tok, _ := permissive.GenerateToken()
+		req := httptest.NewRequest("POST", "https://app.example.com/submit", nil)
+		req.Header.Set("Origin", "null")
+		req.Header.Set(headerName, tok)
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: tok})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		plain := NewTokenStore()
+		h := plain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		tok, _ := plain.GenerateToken()
+		req := httptest.NewRequest("POST", "https://app.example.com/submit", nil)
+		req.Header.Set("Origin", "https://evil.example.net")
+		req.Header.Set(headerName, tok)
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: tok})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", w.Code)
+		}
+	})
+}
+
+func TestGenerateTokenN(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		wantLen int // base64 URL-encoded length
+		wantErr bool
+	}{
+		{"128-bit", 16, 24, false},
+		{"256-bit", 32, 44, false},
+		{"512-bit", 64, 88, false},
+		{"too small", 8, 0, true},
+		{"zero", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateTokenN(tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateTokenN(%d) error = %v, wantErr %v", tt.n, err, tt.wantErr)
+			}
+			if len(token) != tt.wantLen {
+				t.Errorf("GenerateTokenN(%d) length = %d, want %d", tt.n, len(token), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestTokenStoreTokenBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		tokenBytes int
+		wantLen    int
+	}{
+		{"default", 0, 44},
+		{"128-bit", 16, 24},
+		{"raised to minimum", 4, 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewTokenStoreWithConfig(&Config{TokenBytes: tt.tokenBytes})
+			defer store.Stop()
+
+			token, err := store.GenerateToken()
+			if err != nil {
+				t.Fatalf("GenerateToken failed: %v", err)
+			}
+			if len(token) != tt.wantLen {
+				t.Errorf("token length = %d, want %d", len(token), tt.wantLen)
+			}
+			if !store.ValidateToken(token) {
+				t.Error("Expected the token to be stored")
+			}
+		})
+	}
+}
+
+func TestGenerateTokenRandFailure(t *testing.T) {
+	orig := randRead
+	randRead = func(b []byte) (int, error) { return 0, errors.New("entropy unavailable") }
+	defer func() { randRead = orig }()
+
+	if token, err := GenerateTokenN(32); err == nil || token != "" {
+		t.Errorf("GenerateTokenN() = %q, %v; want an error and no token", token, err)
+	}
+
+	store := NewTokenStore()
+	defer store.Stop()
+	if _, err := store.GenerateToken(); err == nil {
+		t.Fatal("Expected GenerateToken to fail")
+	}
+	store.mu.RLock()
+	stored := len(store.tokens)
+	store.mu.RUnlock()
+	if stored != 0 {
+		t.Errorf("Expected no token to be stored, got %d", stored)
+	}
+
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not run without a token")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rec.Code)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("Expected no CSRF cookie to be set")
+	}
+}
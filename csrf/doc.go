@@ -24,7 +24,10 @@ with all state-changing requests.
 
 Create a token store and wrap your HTTP handler with the CSRF middleware:
 
-	import "github.com/patdeg/common/csrf"
+	import (
+		"github.com/patdeg/common"
+		"github.com/patdeg/common/csrf"
+	)
 
 	func main() {
 		store := csrf.NewTokenStore()
@@ -34,7 +37,8 @@ Create a token store and wrap your HTTP handler with the CSRF middleware:
 		// Wrap with CSRF protection
 		handler := store.Middleware(mux)
 
-		http.ListenAndServe(":8080", handler)
+		srv := common.NewServer(":8080", handler, common.ServerOptions{})
+		srv.ListenAndServeGraceful(context.Background())
 	}
 
 # How It Works
@@ -83,6 +87,28 @@ Or use the common package helper:
 	    CSRFToken: common.GetCSRFToken(r),
 	}
 
+# Template Helper
+
+Instead of writing the hidden input by hand, register FuncMap and render it
+with csrfField. The input name follows the store configuration, so renaming
+the field does not require touching every template:
+
+	tmpl := template.Must(template.New("form").Funcs(csrf.FuncMap()).Parse(`
+	<form method="POST" action="/submit">
+	    {{ csrfField .Request }}
+	    <button type="submit">Submit</button>
+	</form>
+	`))
+
+	tmpl.Execute(w, struct{ Request *http.Request }{r})
+
+Custom names are set when creating the store:
+
+	store := csrf.NewTokenStoreWithConfig(&csrf.Config{
+	    CookieName: "app_csrf",
+	    FieldName:  "_csrf",
+	})
+
 # AJAX Requests
 
 For AJAX requests, include the token in the X-CSRF-Token header:
@@ -140,6 +166,20 @@ For HTMX, add the token to all requests automatically:
   - Expired tokens are automatically cleaned up every hour
   - Each token is validated using constant-time comparison to prevent timing attacks
 
+# Login and Token Rotation
+
+Rotate the token right after a successful login so a token obtained before
+authentication cannot be replayed afterwards:
+
+	func loginHandler(w http.ResponseWriter, r *http.Request) {
+	    // ... authenticate the user ...
+	    if _, err := store.RotateToken(w, r); err != nil {
+	        http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	        return
+	    }
+	    http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+
 # Security Considerations
 
   - The CSRF cookie has HttpOnly=false so JavaScript can read it for AJAX requests
@@ -165,6 +205,22 @@ The middleware returns HTTP 403 Forbidden with descriptive messages:
   - "CSRF token missing from request" - No token in header or form
   - "CSRF token invalid or expired" - Token doesn't exist or has expired
   - "CSRF token validation failed" - Cookie and request tokens don't match
+  - "CSRF origin check failed" - Origin/Referer host is not trusted (CheckOrigin only)
+
+# Origin Verification
+
+As defense-in-depth, the middleware can also verify that the Origin header
+(falling back to Referer) of state-changing requests names the request host
+or one of the configured trusted hosts:
+
+	store := csrf.NewTokenStoreWithConfig(&csrf.Config{
+	    CheckOrigin:  true,
+	    TrustedHosts: []string{"admin.example.com"},
+	})
+
+Requests without either header are still accepted when the token is valid,
+since privacy tools and some proxies strip Referer. "Origin: null" is
+rejected unless AllowNullOrigin is set.
 
 # Testing
 
@@ -193,7 +249,10 @@ When writing tests, you can either:
 2. Generate a token directly:
 
 	store := csrf.NewTokenStore()
-	token, _ := store.GenerateToken()
+	token, err := store.GenerateToken()
+	if err != nil {
+	    t.Fatalf("GenerateToken failed: %v", err)
+	}
 
 	req := httptest.NewRequest("POST", "/", nil)
 	req.Header.Set("X-CSRF-Token", token)
@@ -204,9 +263,11 @@ When writing tests, you can either:
 	package main
 
 	import (
+	    "context"
 	    "html/template"
 	    "net/http"
 
+	    "github.com/patdeg/common"
 	    "github.com/patdeg/common/csrf"
 	)
 
@@ -233,7 +294,8 @@ When writing tests, you can either:
 
 	    handler := store.Middleware(mux)
 
-	    http.ListenAndServe(":8080", handler)
+	    srv := common.NewServer(":8080", handler, common.ServerOptions{})
+	    srv.ListenAndServeGraceful(context.Background())
 	}
 
 	func formHandler(w http.ResponseWriter, r *http.Request) {
@@ -21,11 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/datastore"
 	"github.com/patdeg/common"
+	"google.golang.org/api/iterator"
 )
 
 // Repository defines the generic repository interface for entity operations
@@ -42,6 +46,17 @@ type Repository interface {
 	// Query executes a query and returns results
 	Query(ctx context.Context, query Query) ([]interface{}, error)
 
+	// QueryRecords executes a query and returns results paired with their
+	// keys. Results are ordered by key unless the query sets Orders. When
+	// Limit is set and a full page came back, the returned cursor resumes
+	// after the last record (pass it as Query.Cursor); it is empty once the
+	// results are exhausted.
+	QueryRecords(ctx context.Context, query Query) ([]Record, string, error)
+
+	// PutMulti saves several records of the same kind in one call, each
+	// under its key name or, when Key is empty, its numeric ID
+	PutMulti(ctx context.Context, kind string, records []Record) error
+
 	// Transaction executes operations in a transaction
 	Transaction(ctx context.Context, fn func(tx Transaction) error) error
 }
@@ -60,6 +75,7 @@ type Query struct {
 	Orders  []Order
 	Limit   int
 	Offset  int
+	Cursor  string // Resume point returned by QueryRecords
 }
 
 // Filter represents a query filter
@@ -69,12 +85,38 @@ type Filter struct {
 	Value    interface{}
 }
 
+// Record pairs an entity with its key. It is the unit used when streaming a
+// whole kind in or out, e.g. for backups. Exactly one of Key (the key name)
+// and ID (the numeric ID) is set. The key strings taken by Get, Put and
+// Delete are always key names. QueryRecords returns Entity as a
+// map[string]Property so property types survive JSON; PutMulti accepts that
+// form (or its decoded JSON) as well as any entity Put accepts.
+type Record struct {
+	Key    string      `json:"key,omitempty"`
+	ID     int64       `json:"id,omitempty,string"`
+	Entity interface{} `json:"entity"`
+}
+
 // Order represents a query ordering
 type Order struct {
 	Field      string
 	Descending bool
 }
 
+// recordKey returns the datastore key of a record of kind
+func recordKey(kind string, rec Record) (*datastore.Key, error) {
+	switch {
+	case rec.Key != "" && rec.ID != 0:
+		return nil, fmt.Errorf("record has both key %q and ID %d", rec.Key, rec.ID)
+	case rec.Key != "":
+		return datastore.NameKey(kind, rec.Key, nil), nil
+	case rec.ID > 0:
+		return datastore.IDKey(kind, rec.ID, nil), nil
+	default:
+		return nil, fmt.Errorf("record has no key")
+	}
+}
+
 // CloudRepository implements Repository using Google Cloud Datastore
 type CloudRepository struct {
 	client    *datastore.Client
@@ -85,6 +127,7 @@ type CloudRepository struct {
 // LocalRepository implements Repository using in-memory storage for development
 type LocalRepository struct {
 	data map[string]map[string]interface{} // kind -> key -> entity
+	ids  map[string]map[int64]interface{}  // kind -> numeric ID -> entity, see Record
 	mu   sync.RWMutex
 }
 
@@ -124,6 +167,7 @@ func NewCloudRepository(ctx context.Context) (*CloudRepository, error) {
 func NewLocalRepository() *LocalRepository {
 	return &LocalRepository{
 		data: make(map[string]map[string]interface{}),
+		ids:  make(map[string]map[int64]interface{}),
 	}
 }
 
@@ -206,6 +250,111 @@ func (r *CloudRepository) Query(ctx context.Context, query Query) ([]interface{}
 	return results, err
 }
 
+// QueryRecords executes a query on cloud datastore and returns each entity
+// with its key. Entities are loaded as generic property maps. Pages are
+// chained with datastore cursors, which unlike offsets do not rescan the
+// skipped entities.
+func (r *CloudRepository) QueryRecords(ctx context.Context, query Query) ([]Record, string, error) {
+	q := datastore.NewQuery(query.Kind)
+
+	for _, filter := range query.Filters {
+		q = q.Filter(fmt.Sprintf("%s %s", filter.Field, filter.Operator), filter.Value)
+	}
+
+	if len(query.Orders) == 0 {
+		// Stable ordering so pages do not overlap
+		q = q.Order("__key__")
+	}
+	for _, order := range query.Orders {
+		field := order.Field
+		if order.Descending {
+			field = "-" + field
+		}
+		q = q.Order(field)
+	}
+
+	if query.Limit > 0 {
+		q = q.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		q = q.Offset(query.Offset)
+	}
+	if query.Cursor != "" {
+		cursor, err := datastore.DecodeCursor(query.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode cursor: %v", err)
+		}
+		q = q.Start(cursor)
+	}
+
+	records := []Record{}
+	it := r.client.Run(ctx, q)
+	for {
+		var props datastore.PropertyList
+		k, err := it.Next(&props)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		entity, err := encodeEntity(props)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode %v: %v", k, err)
+		}
+		records = append(records, Record{Key: k.Name, ID: k.ID, Entity: entity})
+	}
+
+	if query.Limit <= 0 || len(records) < query.Limit {
+		return records, "", nil
+	}
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get cursor: %v", err)
+	}
+	return records, next.String(), nil
+}
+
+// PutMulti saves several records to cloud datastore. Entities given as typed
+// properties (see Property), such as decoded QueryRecords output, are
+// converted back to property lists before saving.
+func (r *CloudRepository) PutMulti(ctx context.Context, kind string, records []Record) error {
+	// Cloud Datastore accepts at most 500 entities per call
+	const maxBatch = 500
+	for start := 0; start < len(records); start += maxBatch {
+		end := start + maxBatch
+		if end > len(records) {
+			end = len(records)
+		}
+
+		dsKeys := make([]*datastore.Key, 0, end-start)
+		values := make([]interface{}, 0, end-start)
+		for i := start; i < end; i++ {
+			k, err := recordKey(kind, records[i])
+			if err != nil {
+				return fmt.Errorf("record %d: %v", i, err)
+			}
+			entity, err := recordEntity(records[i])
+			if err != nil {
+				return fmt.Errorf("record %d: %v", i, err)
+			}
+			dsKeys = append(dsKeys, k)
+			values = append(values, entity)
+		}
+
+		if _, err := r.client.PutMulti(ctx, dsKeys, values); err != nil {
+			return err
+		}
+
+		for i := start; i < end; i++ {
+			if records[i].Key != "" {
+				r.cache.Delete(fmt.Sprintf("%s:%s", kind, records[i].Key))
+			}
+		}
+	}
+	return nil
+}
+
 // Transaction executes operations in a cloud datastore transaction
 func (r *CloudRepository) Transaction(ctx context.Context, fn func(tx Transaction) error) error {
 	_, err := r.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
@@ -284,12 +433,18 @@ func (r *LocalRepository) Query(ctx context.Context, query Query) ([]interface{}
 	defer r.mu.RUnlock()
 
 	kindData, ok := r.data[query.Kind]
-	if !ok {
+	if !ok && r.ids[query.Kind] == nil {
 		return []interface{}{}, nil
 	}
 
 	// Collect all entities
 	var results []interface{}
+	for _, entity := range r.ids[query.Kind] {
+		results = append(results, entity)
+		if query.Limit > 0 && len(results) >= query.Limit {
+			return results, nil
+		}
+	}
 	for _, entity := range kindData {
 		// TODO: Apply filters, ordering, limit, offset
 		// This is a simplified implementation
@@ -302,6 +457,123 @@ func (r *LocalRepository) Query(ctx context.Context, query Query) ([]interface{}
 	return results, nil
 }
 
+// QueryRecords executes a query on local storage and returns each entity with
+// its key. As in Cloud Datastore, records with numeric IDs come first in ID
+// order, followed by key names in order; the cursor encodes the last key of
+// the page. Filters and Orders are not applied (see Query).
+func (r *LocalRepository) QueryRecords(ctx context.Context, query Query) ([]Record, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]int64, 0, len(r.ids[query.Kind]))
+	for id := range r.ids[query.Kind] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	names := make([]string, 0, len(r.data[query.Kind]))
+	for k := range r.data[query.Kind] {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	if query.Cursor != "" {
+		// Resume strictly after the last key of the previous page
+		kind, last, ok := strings.Cut(query.Cursor, ":")
+		switch {
+		case ok && kind == "id":
+			id, err := strconv.ParseInt(last, 10, 64)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to decode cursor: %v", err)
+			}
+			ids = ids[sort.Search(len(ids), func(i int) bool { return ids[i] > id }):]
+		case ok && kind == "name":
+			ids = nil
+			names = names[sort.Search(len(names), func(i int) bool { return names[i] > last }):]
+		default:
+			return nil, "", fmt.Errorf("failed to decode cursor: %q", query.Cursor)
+		}
+	}
+
+	records := make([]Record, 0, len(ids)+len(names))
+	for _, id := range ids {
+		records = append(records, Record{ID: id})
+	}
+	for _, k := range names {
+		records = append(records, Record{Key: k})
+	}
+
+	if query.Offset > 0 {
+		if query.Offset >= len(records) {
+			return []Record{}, "", nil
+		}
+		records = records[query.Offset:]
+	}
+	next := ""
+	if query.Limit > 0 && len(records) >= query.Limit {
+		records = records[:query.Limit]
+		if last := records[len(records)-1]; last.Key != "" {
+			next = "name:" + last.Key
+		} else {
+			next = "id:" + strconv.FormatInt(last.ID, 10)
+		}
+	}
+
+	for i, rec := range records {
+		var entity interface{}
+		if rec.Key != "" {
+			entity = deepCopy(r.data[query.Kind][rec.Key])
+		} else {
+			entity = deepCopy(r.ids[query.Kind][rec.ID])
+		}
+		if m, ok := entity.(map[string]interface{}); ok {
+			p, err := encodeValue(m)
+			if err != nil {
+				return nil, "", fmt.Errorf("record %d: %v", i, err)
+			}
+			entity = p.Value
+		}
+		records[i].Entity = entity
+	}
+	return records, next, nil
+}
+
+// PutMulti saves several records to local storage. Typed properties (see
+// Property) are stored as plain values.
+func (r *LocalRepository) PutMulti(ctx context.Context, kind string, records []Record) error {
+	entities := make([]interface{}, len(records))
+	for i, rec := range records {
+		if _, err := recordKey(kind, rec); err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		entity, err := recordEntity(rec)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		if props, ok := entity.(*datastore.PropertyList); ok {
+			entity = plainEntity(*props)
+		}
+		entities[i] = entity
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rec := range records {
+		if rec.Key != "" {
+			if r.data[kind] == nil {
+				r.data[kind] = make(map[string]interface{})
+			}
+			r.data[kind][rec.Key] = deepCopy(entities[i])
+			continue
+		}
+		if r.ids[kind] == nil {
+			r.ids[kind] = make(map[int64]interface{})
+		}
+		r.ids[kind][rec.ID] = deepCopy(entities[i])
+	}
+	return nil
+}
+
 // Transaction executes operations in a local transaction (simplified)
 func (r *LocalRepository) Transaction(ctx context.Context, fn func(tx Transaction) error) error {
 	// Simplified transaction for local storage
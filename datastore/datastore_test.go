@@ -0,0 +1,114 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestRecordKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec     Record
+		want    *datastore.Key
+		wantErr bool
+	}{
+		{"Name key", Record{Key: "alice"}, datastore.NameKey("User", "alice", nil), false},
+		{"ID key", Record{ID: 42}, datastore.IDKey("User", 42, nil), false},
+		{"Name that looks like an ID", Record{Key: "id:42"}, datastore.NameKey("User", "id:42", nil), false},
+		{"Both name and ID", Record{Key: "alice", ID: 42}, nil, true},
+		{"No key", Record{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := recordKey("User", tt.rec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("recordKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("recordKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalQueryRecordsCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewLocalRepository()
+	for i := 0; i < 6; i++ {
+		if err := repo.Put(ctx, "User", fmt.Sprintf("u%d", i), map[string]interface{}{"n": i}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	ids := []Record{
+		{ID: 7, Entity: map[string]Property{"n": {Type: "int", Value: "7"}}},
+		{ID: 3, Entity: map[string]Property{"n": {Type: "int", Value: "3"}}},
+	}
+	if err := repo.PutMulti(ctx, "User", ids); err != nil {
+		t.Fatalf("PutMulti failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantPages []int
+	}{
+		{"Uneven pages", 3, []int{3, 3, 2}},
+		{"Exact pages", 4, []int{4, 4, 0}},
+		{"No limit", 0, []int{8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages []int
+			var order []string
+			seen := map[string]bool{}
+			cursor := ""
+			for {
+				records, next, err := repo.QueryRecords(ctx, Query{Kind: "User", Limit: tt.limit, Cursor: cursor})
+				if err != nil {
+					t.Fatalf("QueryRecords failed: %v", err)
+				}
+				pages = append(pages, len(records))
+				for _, rec := range records {
+					key := rec.Key
+					if key == "" {
+						key = fmt.Sprint(rec.ID)
+					}
+					if seen[key] {
+						t.Errorf("key %s returned twice", key)
+					}
+					seen[key] = true
+					order = append(order, key)
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+			if got, want := fmt.Sprint(order), "[3 7 u0 u1 u2 u3 u4 u5]"; got != want {
+				t.Errorf("keys = %s, want %s", got, want)
+			}
+		})
+	}
+}
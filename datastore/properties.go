@@ -0,0 +1,325 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+// Property is the typed form of a property value in a Record entity. Plain
+// JSON cannot tell an integer from a float, a timestamp from a string or a
+// key from its name, so every value carries its type:
+//
+//	{"type": "int", "value": "42"}
+//	{"type": "time", "value": "2025-01-01T00:00:00Z"}
+//	{"type": "key", "value": {"kind": "User", "name": "alice"}}
+//
+// The types are null, int (a decimal string so 64-bit values survive JSON),
+// float, bool, string, time (RFC 3339), blob (base64), geo ({"lat", "lng"}),
+// key, entity (an object of Property values) and array (a list of Property
+// values).
+type Property struct {
+	Type    string      `json:"type"`
+	Value   interface{} `json:"value"`
+	NoIndex bool        `json:"noindex,omitempty"`
+}
+
+// propertyKey is the JSON form of a *datastore.Key
+type propertyKey struct {
+	Kind      string       `json:"kind"`
+	ID        int64        `json:"id,omitempty,string"`
+	Name      string       `json:"name,omitempty"`
+	Namespace string       `json:"namespace,omitempty"`
+	Parent    *propertyKey `json:"parent,omitempty"`
+}
+
+// geoPoint is the JSON form of a datastore.GeoPoint
+type geoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// rawProperty is a Property whose value has not been decoded yet
+type rawProperty struct {
+	Type    string          `json:"type"`
+	Value   json.RawMessage `json:"value"`
+	NoIndex bool            `json:"noindex"`
+}
+
+// encodeEntity converts a datastore property list into typed properties
+func encodeEntity(props datastore.PropertyList) (map[string]Property, error) {
+	m := make(map[string]Property, len(props))
+	for _, p := range props {
+		v, err := encodeValue(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %v", p.Name, err)
+		}
+		v.NoIndex = p.NoIndex
+		m[p.Name] = v
+	}
+	return m, nil
+}
+
+// encodeValue converts a property value into its typed form. Besides the
+// types Cloud Datastore loads, it accepts the generic values held by the
+// local repository (maps become entities).
+func encodeValue(v interface{}) (Property, error) {
+	switch val := v.(type) {
+	case nil:
+		return Property{Type: "null"}, nil
+	case int64:
+		return Property{Type: "int", Value: strconv.FormatInt(val, 10)}, nil
+	case int:
+		return Property{Type: "int", Value: strconv.Itoa(val)}, nil
+	case float64:
+		return Property{Type: "float", Value: val}, nil
+	case bool:
+		return Property{Type: "bool", Value: val}, nil
+	case string:
+		return Property{Type: "string", Value: val}, nil
+	case time.Time:
+		return Property{Type: "time", Value: val.Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return Property{Type: "blob", Value: val}, nil
+	case datastore.GeoPoint:
+		return Property{Type: "geo", Value: geoPoint{Lat: val.Lat, Lng: val.Lng}}, nil
+	case *datastore.Key:
+		if val == nil {
+			return Property{Type: "null"}, nil
+		}
+		return Property{Type: "key", Value: encodeKey(val)}, nil
+	case *datastore.Entity:
+		if val == nil {
+			return Property{Type: "null"}, nil
+		}
+		m, err := encodeEntity(val.Properties)
+		if err != nil {
+			return Property{}, err
+		}
+		return Property{Type: "entity", Value: m}, nil
+	case map[string]interface{}:
+		m := make(map[string]Property, len(val))
+		for k, item := range val {
+			p, err := encodeValue(item)
+			if err != nil {
+				return Property{}, fmt.Errorf("property %s: %v", k, err)
+			}
+			m[k] = p
+		}
+		return Property{Type: "entity", Value: m}, nil
+	case []interface{}:
+		items := make([]Property, len(val))
+		for i, item := range val {
+			p, err := encodeValue(item)
+			if err != nil {
+				return Property{}, fmt.Errorf("item %d: %v", i, err)
+			}
+			items[i] = p
+		}
+		return Property{Type: "array", Value: items}, nil
+	default:
+		return Property{}, fmt.Errorf("unsupported property type %T", v)
+	}
+}
+
+func encodeKey(k *datastore.Key) *propertyKey {
+	if k == nil {
+		return nil
+	}
+	return &propertyKey{
+		Kind:      k.Kind,
+		ID:        k.ID,
+		Name:      k.Name,
+		Namespace: k.Namespace,
+		Parent:    encodeKey(k.Parent),
+	}
+}
+
+func (k *propertyKey) datastoreKey() *datastore.Key {
+	if k == nil {
+		return nil
+	}
+	return &datastore.Key{
+		Kind:      k.Kind,
+		ID:        k.ID,
+		Name:      k.Name,
+		Namespace: k.Namespace,
+		Parent:    k.Parent.datastoreKey(),
+	}
+}
+
+// decodeEntity converts typed properties, either a map[string]Property or
+// its decoded JSON form, back into a datastore property list
+func decodeEntity(entity interface{}) (datastore.PropertyList, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entity: %v", err)
+	}
+	var raw map[string]rawProperty
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode entity: %v", err)
+	}
+	return decodeProperties(raw)
+}
+
+func decodeProperties(raw map[string]rawProperty) (datastore.PropertyList, error) {
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	props := make(datastore.PropertyList, 0, len(raw))
+	for _, name := range names {
+		v, err := raw[name].decode()
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %v", name, err)
+		}
+		props = append(props, datastore.Property{Name: name, Value: v, NoIndex: raw[name].NoIndex})
+	}
+	return props, nil
+}
+
+func (p rawProperty) decode() (interface{}, error) {
+	switch p.Type {
+	case "null":
+		return nil, nil
+	case "int":
+		var s string
+		if err := p.unmarshal(&s); err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int value %q", s)
+		}
+		return n, nil
+	case "float":
+		var f float64
+		err := p.unmarshal(&f)
+		return f, err
+	case "bool":
+		var b bool
+		err := p.unmarshal(&b)
+		return b, err
+	case "string":
+		var s string
+		err := p.unmarshal(&s)
+		return s, err
+	case "time":
+		var s string
+		if err := p.unmarshal(&s); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time value %q", s)
+		}
+		return t, nil
+	case "blob":
+		var b []byte
+		err := p.unmarshal(&b)
+		return b, err
+	case "geo":
+		var g geoPoint
+		err := p.unmarshal(&g)
+		return datastore.GeoPoint{Lat: g.Lat, Lng: g.Lng}, err
+	case "key":
+		var k *propertyKey
+		if err := p.unmarshal(&k); err != nil {
+			return nil, err
+		}
+		return k.datastoreKey(), nil
+	case "entity":
+		var raw map[string]rawProperty
+		if err := p.unmarshal(&raw); err != nil {
+			return nil, err
+		}
+		props, err := decodeProperties(raw)
+		if err != nil {
+			return nil, err
+		}
+		return &datastore.Entity{Properties: props}, nil
+	case "array":
+		var raw []rawProperty
+		if err := p.unmarshal(&raw); err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, len(raw))
+		for i, item := range raw {
+			v, err := item.decode()
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			items[i] = v
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown property type %q", p.Type)
+	}
+}
+
+func (p rawProperty) unmarshal(dst interface{}) error {
+	if err := json.Unmarshal(p.Value, dst); err != nil {
+		return fmt.Errorf("invalid %s value: %v", p.Type, err)
+	}
+	return nil
+}
+
+// plainEntity converts a property list into a generic map, the form the local
+// repository stores. Nested entities become maps.
+func plainEntity(props datastore.PropertyList) map[string]interface{} {
+	m := make(map[string]interface{}, len(props))
+	for _, p := range props {
+		m[p.Name] = plainValue(p.Value)
+	}
+	return m
+}
+
+func plainValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *datastore.Entity:
+		return plainEntity(val.Properties)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = plainValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// recordEntity returns what to save for rec: typed properties (see Property)
+// are decoded into a property list, other entities are saved as they are
+func recordEntity(rec Record) (interface{}, error) {
+	switch rec.Entity.(type) {
+	case map[string]interface{}, map[string]Property:
+		props, err := decodeEntity(rec.Entity)
+		if err != nil {
+			return nil, err
+		}
+		return &props, nil
+	default:
+		return rec.Entity, nil
+	}
+}
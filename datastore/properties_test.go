@@ -0,0 +1,111 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestPropertiesRoundTripThroughJSON(t *testing.T) {
+	parent := datastore.NameKey("Org", "example", nil)
+	parent.Namespace = "tenant-a"
+	owner := datastore.IDKey("User", 1<<60+1, parent)
+	owner.Namespace = "tenant-a"
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"Time", time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)},
+		{"Large int", int64(1<<62 + 1)},
+		{"Key with parent and namespace", owner},
+		{"Name key", datastore.NameKey("User", "alice", nil)},
+		{"Float", 1.5},
+		{"Whole float", 2.0},
+		{"String", "42"},
+		{"Bool", true},
+		{"Null", nil},
+		{"Blob", []byte{0, 1, 2}},
+		{"Geo point", datastore.GeoPoint{Lat: 46.5, Lng: 6.6}},
+		{"Array", []interface{}{int64(1), "two", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{"Entity", &datastore.Entity{Properties: datastore.PropertyList{
+			{Name: "count", Value: int64(3)},
+			{Name: "seen", Value: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := datastore.PropertyList{{Name: "v", Value: tt.value, NoIndex: true}}
+			encoded, err := encodeEntity(props)
+			if err != nil {
+				t.Fatalf("encodeEntity failed: %v", err)
+			}
+
+			// Go through JSON as a backup file would
+			data, err := json.Marshal(Record{Key: "k", Entity: encoded})
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var rec Record
+			if err := json.Unmarshal(data, &rec); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			entity, err := recordEntity(rec)
+			if err != nil {
+				t.Fatalf("recordEntity failed: %v", err)
+			}
+			got, ok := entity.(*datastore.PropertyList)
+			if !ok {
+				t.Fatalf("recordEntity() = %T, want *datastore.PropertyList", entity)
+			}
+			if !reflect.DeepEqual(*got, props) {
+				t.Errorf("round trip = %#v, want %#v", *got, props)
+			}
+		})
+	}
+}
+
+func TestDecodeEntityRejectsUntypedValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		entity interface{}
+	}{
+		{"Plain value", map[string]interface{}{"name": "alice"}},
+		{"Unknown type", map[string]interface{}{"name": map[string]interface{}{"type": "money", "value": "1"}}},
+		{"Int as number", map[string]interface{}{"n": map[string]interface{}{"type": "int", "value": 1.5}}},
+		{"Bad time", map[string]Property{"t": {Type: "time", Value: "yesterday"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeEntity(tt.entity); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestEncodeValueRejectsUnsupportedTypes(t *testing.T) {
+	if _, err := encodeValue(struct{}{}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
@@ -23,9 +23,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"mime"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/patdeg/common"
@@ -36,12 +40,20 @@ type Service interface {
 	// Send sends an email message
 	Send(ctx context.Context, message *Message) error
 
+	// SendWithResult sends an email message and reports the provider's
+	// message ID and which recipients it accepted
+	SendWithResult(ctx context.Context, message *Message) (*SendResult, error)
+
 	// SendTemplate sends a templated email
 	SendTemplate(ctx context.Context, templateName string, data interface{}, recipients []string) error
 
 	// SendBatch sends multiple emails in batch
 	SendBatch(ctx context.Context, messages []*Message) error
 
+	// SendPersonalizedBatch sends a template to each recipient with the
+	// recipient's own merge fields
+	SendPersonalizedBatch(ctx context.Context, templateName string, recipients []PersonalizedRecipient) error
+
 	// ValidateEmail validates an email address
 	ValidateEmail(email string) error
 
@@ -64,6 +76,72 @@ type Message struct {
 	Metadata     map[string]string      `json:"metadata,omitempty"`
 	TemplateID   string                 `json:"template_id,omitempty"`
 	TemplateData map[string]interface{} `json:"template_data,omitempty"`
+	SendAt       time.Time              `json:"send_at,omitzero"` // Deliver no earlier than this; zero sends now
+}
+
+// SendResult describes a message accepted by the provider
+type SendResult struct {
+	// MessageID identifies the message with the provider, e.g. SendGrid's
+	// X-Message-Id, which prefixes the sg_message_id of its webhook events.
+	// Local delivery generates one.
+	MessageID string `json:"message_id"`
+
+	// Provider is the GetProvider name of the service that sent it
+	Provider string `json:"provider"`
+
+	// Accepted and Rejected list recipient addresses (To, CC and BCC).
+	// SendGrid accepts or rejects a request as a whole, so Rejected is only
+	// set by providers that report recipients individually.
+	Accepted []string `json:"accepted"`
+	Rejected []string `json:"rejected,omitempty"`
+}
+
+// recipientEmails returns the To, CC and BCC addresses of message
+func recipientEmails(message *Message) []string {
+	var emails []string
+	for _, list := range [][]Address{message.To, message.CC, message.BCC} {
+		for _, addr := range list {
+			emails = append(emails, addr.Email)
+		}
+	}
+	return emails
+}
+
+// MaxScheduleAhead is how far in the future SendAt may be. It matches
+// SendGrid's 72 hour limit so scheduling behaves the same on every provider;
+// longer delays belong in the application's own job queue.
+const MaxScheduleAhead = 72 * time.Hour
+
+// validateSendAt rejects SendAt values beyond MaxScheduleAhead. Times in the
+// past are allowed and mean "send now".
+func validateSendAt(sendAt, now time.Time) error {
+	if !sendAt.IsZero() && sendAt.After(now.Add(MaxScheduleAhead)) {
+		return fmt.Errorf("send_at %s is more than %v in the future", sendAt.Format(time.RFC3339), MaxScheduleAhead)
+	}
+	return nil
+}
+
+// WithUnsubscribe sets the List-Unsubscribe headers required by Gmail and
+// Yahoo for bulk senders. An https URL also enables one-click unsubscribe
+// (RFC 8058), which means the URL must accept a POST with the body
+// "List-Unsubscribe=One-Click" and unsubscribe without further confirmation.
+// A mailto: URL only sets List-Unsubscribe. It returns the message for
+// chaining.
+func (m *Message) WithUnsubscribe(url string) *Message {
+	if url == "" {
+		return m
+	}
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+
+	m.Headers["List-Unsubscribe"] = "<" + url + ">"
+	if strings.HasPrefix(strings.ToLower(url), "https://") {
+		m.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	} else {
+		delete(m.Headers, "List-Unsubscribe-Post")
+	}
+	return m
 }
 
 // Address represents an email address
@@ -104,10 +182,13 @@ type SendGridService struct {
 	client    *http.Client
 }
 
-// LocalService implements Service for local development
+// LocalService implements Service for local development. Messages with a
+// future SendAt are held until a worker collects them with DueMessages.
 type LocalService struct {
-	config   Config
-	messages []*Message // Store messages for inspection
+	config    Config
+	messages  []*Message // Store messages for inspection
+	mu        sync.Mutex // Guards scheduled
+	scheduled []*Message // Messages waiting for their SendAt
 }
 
 // NewService creates a new email service based on configuration
@@ -174,25 +255,51 @@ func NewSendGridService(config Config) (*SendGridService, error) {
 
 // Send sends an email via SendGrid
 func (s *SendGridService) Send(ctx context.Context, message *Message) error {
+	_, err := s.SendWithResult(ctx, message)
+	return err
+}
+
+// SendWithResult sends an email via SendGrid and returns the X-Message-Id
+// it assigned, for correlating webhook events
+func (s *SendGridService) SendWithResult(ctx context.Context, message *Message) (*SendResult, error) {
 	// Set default from if not specified
 	if message.From.Email == "" {
 		message.From.Email = s.fromEmail
 		message.From.Name = s.fromName
 	}
 
+	if err := validateSendAt(message.SendAt, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// Build SendGrid request
-	sgReq := s.buildSendGridRequest(message)
+	header, err := s.post(ctx, s.buildSendGridRequest(message))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SendResult{
+		MessageID: header.Get("X-Message-Id"),
+		Provider:  s.GetProvider(),
+		Accepted:  recipientEmails(message),
+	}
+	common.Info("[EMAIL] Sent email via SendGrid: %s to %d recipients (message %s)", message.Subject, len(message.To), result.MessageID)
+	return result, nil
+}
 
+// post sends a mail/send request to SendGrid and returns the response
+// headers
+func (s *SendGridService) post(ctx context.Context, sgReq map[string]interface{}) (http.Header, error) {
 	// Marshal to JSON
 	data, err := json.Marshal(sgReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.sendgrid.com/v3/mail/send", bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.apiKey)
@@ -201,46 +308,31 @@ func (s *SendGridService) Send(ctx context.Context, message *Message) error {
 	// Send request
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return nil, fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		var errResp map[string]interface{}
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			return fmt.Errorf("SendGrid error (status %d): failed to decode error response: %v", resp.StatusCode, err)
+			return nil, fmt.Errorf("SendGrid error (status %d): failed to decode error response: %v", resp.StatusCode, err)
 		}
-		return fmt.Errorf("SendGrid error (status %d): %v", resp.StatusCode, errResp)
+		return nil, fmt.Errorf("SendGrid error (status %d): %v", resp.StatusCode, errResp)
 	}
-
-	common.Info("[EMAIL] Sent email via SendGrid: %s to %d recipients", message.Subject, len(message.To))
-	return nil
+	return resp.Header, nil
 }
 
 // SendTemplate sends a templated email via SendGrid
 func (s *SendGridService) SendTemplate(ctx context.Context, templateName string, data interface{}, recipients []string) error {
-	// Build template content
-	tmplContent, ok := s.config.Templates[templateName]
-	if !ok {
-		return fmt.Errorf("template not found: %s", templateName)
-	}
-
-	// Parse and execute template
-	tmpl, err := template.New(templateName).Parse(tmplContent)
+	body, err := renderTemplate(s.config.Templates, templateName, data)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %v", err)
-	}
-
-	var htmlBuf, textBuf bytes.Buffer
-	if err := tmpl.Execute(&htmlBuf, data); err != nil {
-		return fmt.Errorf("failed to execute template: %v", err)
+		return err
 	}
 
 	// Create message
 	message := &Message{
 		Subject: fmt.Sprintf("Message from %s", s.fromName),
-		HTML:    htmlBuf.String(),
-		Text:    textBuf.String(),
+		HTML:    body,
 	}
 
 	// Add recipients
@@ -329,20 +421,83 @@ func (s *SendGridService) buildSendGridRequest(message *Message) map[string]inte
 	if len(message.Attachments) > 0 {
 		var attachments []map[string]string
 		for _, att := range message.Attachments {
-			attachments = append(attachments, map[string]string{
-				"content":     att.Content,
-				"type":        att.Type,
-				"filename":    att.Filename,
-				"disposition": att.Disposition,
-				"content_id":  att.ContentID,
-			})
+			attachments = append(attachments, sendGridAttachment(att))
 		}
 		req["attachments"] = attachments
 	}
 
+	// Schedule delivery; SendGrid expects a Unix timestamp
+	if !message.SendAt.IsZero() {
+		req["send_at"] = message.SendAt.Unix()
+	}
+
+	// Add custom headers, skipping the ones SendGrid manages itself
+	if headers := sendGridHeaders(message.Headers); len(headers) > 0 {
+		req["headers"] = headers
+	}
+
 	return req
 }
 
+// sendGridAttachment converts an attachment to SendGrid's format. An
+// attachment with a ContentID is sent inline so HTML can reference it as
+// "cid:<ContentID>"; SendGrid rejects content_id on regular attachments, so
+// it is only included for inline ones.
+func sendGridAttachment(att Attachment) map[string]string {
+	disposition := att.Disposition
+	if disposition == "" {
+		disposition = "attachment"
+		if att.ContentID != "" {
+			disposition = "inline"
+		}
+	}
+
+	result := map[string]string{
+		"content":     att.Content,
+		"type":        att.Type,
+		"filename":    att.Filename,
+		"disposition": disposition,
+	}
+	if disposition == "inline" && att.ContentID != "" {
+		result["content_id"] = att.ContentID
+	}
+	return result
+}
+
+// sendGridReservedHeaders lists headers SendGrid rejects in the "headers"
+// field because they are derived from other request fields.
+var sendGridReservedHeaders = map[string]bool{
+	"bcc":                       true,
+	"cc":                        true,
+	"content-transfer-encoding": true,
+	"content-type":              true,
+	"dkim-signature":            true,
+	"from":                      true,
+	"received":                  true,
+	"reply-to":                  true,
+	"subject":                   true,
+	"to":                        true,
+	"x-sg-eid":                  true,
+	"x-sg-id":                   true,
+}
+
+// sendGridHeaders returns the custom headers allowed by SendGrid
+func sendGridHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if sendGridReservedHeaders[strings.ToLower(name)] {
+			common.Warn("[EMAIL] Ignoring reserved header %s", name)
+			continue
+		}
+		result[name] = value
+	}
+	return result
+}
+
 // NewLocalService creates a new local email service for development
 func NewLocalService(config Config) *LocalService {
 	return &LocalService{
@@ -353,12 +508,42 @@ func NewLocalService(config Config) *LocalService {
 
 // Send logs the email locally
 func (s *LocalService) Send(ctx context.Context, message *Message) error {
+	_, err := s.SendWithResult(ctx, message)
+	return err
+}
+
+// SendWithResult logs the email locally under a generated message ID.
+// Every recipient is accepted.
+func (s *LocalService) SendWithResult(ctx context.Context, message *Message) (*SendResult, error) {
 	// Set default from if not specified
 	if message.From.Email == "" {
 		message.From.Email = s.config.FromEmail
 		message.From.Name = s.config.FromName
 	}
 
+	now := time.Now()
+	if err := validateSendAt(message.SendAt, now); err != nil {
+		return nil, err
+	}
+
+	id, err := common.GenerateToken(common.MinTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %v", err)
+	}
+	result := &SendResult{
+		MessageID: "local-" + id,
+		Provider:  s.GetProvider(),
+		Accepted:  recipientEmails(message),
+	}
+
+	if message.SendAt.After(now) {
+		s.mu.Lock()
+		s.scheduled = append(s.scheduled, message)
+		s.mu.Unlock()
+		common.Info("[LOCAL_EMAIL] Email scheduled for %s: %s", message.SendAt.Format(time.RFC3339), message.Subject)
+		return result, nil
+	}
+
 	// Store message
 	s.messages = append(s.messages, message)
 
@@ -373,6 +558,7 @@ func (s *LocalService) Send(ctx context.Context, message *Message) error {
 		common.Info("  BCC: %v", formatAddresses(message.BCC))
 	}
 	common.Info("  Subject: %s", message.Subject)
+	common.Info("  Message ID: %s", result.MessageID)
 
 	if s.config.IsDev && message.HTML != "" {
 		// In dev mode, save HTML to file for inspection
@@ -384,7 +570,7 @@ func (s *LocalService) Send(ctx context.Context, message *Message) error {
 		}
 	}
 
-	return nil
+	return result, nil
 }
 
 // SendTemplate sends a templated email locally
@@ -430,12 +616,57 @@ func (s *LocalService) GetMessages() []*Message {
 	return s.messages
 }
 
+// DueMessages removes and returns the scheduled messages whose SendAt is at
+// or before now, oldest first. A worker flushes them periodically:
+//
+//	for _, msg := range svc.DueMessages(time.Now()) {
+//	    svc.Send(ctx, msg)
+//	}
+func (s *LocalService) DueMessages(now time.Time) []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*Message
+	pending := s.scheduled[:0]
+	for _, msg := range s.scheduled {
+		if msg.SendAt.After(now) {
+			pending = append(pending, msg)
+		} else {
+			due = append(due, msg)
+		}
+	}
+	s.scheduled = pending
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].SendAt.Before(due[j].SendAt)
+	})
+	return due
+}
+
 // ClearMessages clears all stored messages (for testing)
 func (s *LocalService) ClearMessages() {
 	s.messages = make([]*Message, 0)
 }
 
-// Helper functions
+// renderTemplate parses the named template from templates and executes it
+// with data
+func renderTemplate(templates map[string]string, templateName string, data interface{}) (string, error) {
+	tmplContent, ok := templates[templateName]
+	if !ok {
+		return "", fmt.Errorf("template not found: %s", templateName)
+	}
+
+	tmpl, err := template.New(templateName).Parse(tmplContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
+	return buf.String(), nil
+}
 
 func isValidEmail(email string) bool {
 	// Basic email validation
@@ -494,6 +725,50 @@ func AttachmentFromFile(filename string, contentType string) (*Attachment, error
 	}, nil
 }
 
+// InlineImageFromFile creates an inline image attachment and returns the
+// content ID to reference it from the HTML body:
+//
+//	img, cid, err := email.InlineImageFromFile("static/logo.png")
+//	if err != nil { ... }
+//	msg.Attachments = append(msg.Attachments, *img)
+//	msg.HTML = `<img src="cid:` + cid + `" alt="Logo">`
+//
+// The content type is derived from the file extension, falling back to
+// sniffing the content, and must be an image. Each call generates a new
+// random content ID so several images can be embedded in one message.
+func InlineImageFromFile(path string) (*Attachment, string, error) {
+	// #nosec G304 -- path should be validated or restricted to safe directories by the caller.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("%s is not an image (%s)", path, contentType)
+	}
+
+	id, err := common.GenerateToken(common.MinTokenBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate content ID: %v", err)
+	}
+	cid := "img-" + id
+
+	return &Attachment{
+		Content:     base64.StdEncoding.EncodeToString(data),
+		Type:        contentType,
+		Filename:    filepath.Base(path),
+		Disposition: "inline",
+		ContentID:   cid,
+	}, cid, nil
+}
+
 // StandardTemplates provides standard email templates
 var StandardTemplates = map[string]string{
 	"welcome": `
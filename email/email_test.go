@@ -0,0 +1,428 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSendGridService(t *testing.T) *SendGridService {
+	t.Helper()
+	svc, err := NewSendGridService(Config{
+		APIKey:    "test-api-key",
+		FromEmail: "noreply@example.com",
+		FromName:  "Example",
+	})
+	if err != nil {
+		t.Fatalf("NewSendGridService failed: %v", err)
+	}
+	return svc
+}
+
+// buildPayload builds the SendGrid request and round-trips it through JSON
+// so assertions see exactly what would be sent.
+func buildPayload(t *testing.T, svc *SendGridService, msg *Message) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(svc.buildSendGridRequest(msg))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return payload
+}
+
+func TestBuildSendGridRequestHeaders(t *testing.T) {
+	svc := newTestSendGridService(t)
+	msg := &Message{
+		From:    Address{Email: "noreply@example.com"},
+		To:      []Address{{Email: "jane@example.com"}},
+		Subject: "Newsletter",
+		Text:    "Hello",
+		Headers: map[string]string{
+			"X-Campaign": "spring",
+			"Subject":    "overridden",
+		},
+	}
+
+	payload := buildPayload(t, svc, msg)
+	headers, ok := payload["headers"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("payload has no headers: %v", payload)
+	}
+	if headers["X-Campaign"] != "spring" {
+		t.Errorf("X-Campaign = %v, want spring", headers["X-Campaign"])
+	}
+	if _, ok := headers["Subject"]; ok {
+		t.Error("reserved Subject header should not be sent")
+	}
+}
+
+func TestBuildSendGridRequestWithoutHeaders(t *testing.T) {
+	svc := newTestSendGridService(t)
+	payload := buildPayload(t, svc, &Message{
+		To:      []Address{{Email: "jane@example.com"}},
+		Subject: "Hi",
+		Text:    "Hello",
+	})
+	if _, ok := payload["headers"]; ok {
+		t.Error("expected no headers field when Message.Headers is empty")
+	}
+}
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestInlineImageFromFile(t *testing.T) {
+	dir := t.TempDir()
+	logo := filepath.Join(dir, "logo.png")
+	if err := os.WriteFile(logo, pngHeader, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	img, cid, err := InlineImageFromFile(logo)
+	if err != nil {
+		t.Fatalf("InlineImageFromFile failed: %v", err)
+	}
+	if img.Type != "image/png" || img.Filename != "logo.png" || img.Disposition != "inline" || img.ContentID != cid {
+		t.Errorf("unexpected attachment: %+v", img)
+	}
+
+	_, cid2, err := InlineImageFromFile(logo)
+	if err != nil || cid2 == cid {
+		t.Errorf("expected a unique content ID, got %q twice (err %v)", cid, err)
+	}
+
+	// Content sniffing when the extension is unknown
+	noExt := filepath.Join(dir, "logo")
+	os.WriteFile(noExt, pngHeader, 0o600)
+	if img, _, err := InlineImageFromFile(noExt); err != nil || img.Type != "image/png" {
+		t.Errorf("sniffed attachment = %+v, %v", img, err)
+	}
+
+	notImage := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notImage, []byte("hello"), 0o600)
+	if _, _, err := InlineImageFromFile(notImage); err == nil {
+		t.Error("expected error for a non-image file")
+	}
+	if _, _, err := InlineImageFromFile(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestBuildSendGridRequestInlineImage(t *testing.T) {
+	dir := t.TempDir()
+	logo := filepath.Join(dir, "logo.png")
+	if err := os.WriteFile(logo, pngHeader, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	img, cid, err := InlineImageFromFile(logo)
+	if err != nil {
+		t.Fatalf("InlineImageFromFile failed: %v", err)
+	}
+
+	svc := newTestSendGridService(t)
+	payload := buildPayload(t, svc, &Message{
+		To:      []Address{{Email: "jane@example.com"}},
+		Subject: "Hi",
+		HTML:    `<img src="cid:` + cid + `">`,
+		Attachments: []Attachment{
+			*img,
+			{Content: "aGVsbG8=", Type: "text/plain", Filename: "notes.txt"},
+		},
+	})
+
+	attachments, ok := payload["attachments"].([]interface{})
+	if !ok || len(attachments) != 2 {
+		t.Fatalf("unexpected attachments: %v", payload["attachments"])
+	}
+
+	inline := attachments[0].(map[string]interface{})
+	if inline["disposition"] != "inline" || inline["content_id"] != cid {
+		t.Errorf("inline attachment = %v, want disposition inline and content_id %s", inline, cid)
+	}
+
+	regular := attachments[1].(map[string]interface{})
+	if regular["disposition"] != "attachment" {
+		t.Errorf("regular attachment disposition = %v, want attachment", regular["disposition"])
+	}
+	if _, ok := regular["content_id"]; ok {
+		t.Error("regular attachment should not carry a content_id")
+	}
+
+	content := payload["content"].([]interface{})[0].(map[string]interface{})
+	if !strings.Contains(content["value"].(string), "cid:"+cid) {
+		t.Errorf("HTML does not reference cid:%s", cid)
+	}
+}
+
+func TestWithUnsubscribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		wantList string
+		wantPost string
+	}{
+		{
+			name:     "https enables one-click",
+			url:      "https://example.com/unsubscribe?u=123",
+			wantList: "<https://example.com/unsubscribe?u=123>",
+			wantPost: "List-Unsubscribe=One-Click",
+		},
+		{
+			name:     "mailto only",
+			url:      "mailto:unsubscribe@example.com",
+			wantList: "<mailto:unsubscribe@example.com>",
+			wantPost: "",
+		},
+		{
+			name: "empty url is a no-op",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSendGridService(t)
+			msg := (&Message{
+				To:      []Address{{Email: "jane@example.com"}},
+				Subject: "Newsletter",
+				Text:    "Hello",
+			}).WithUnsubscribe(tt.url)
+
+			payload := buildPayload(t, svc, msg)
+			headers, _ := payload["headers"].(map[string]interface{})
+
+			got, _ := headers["List-Unsubscribe"].(string)
+			if got != tt.wantList {
+				t.Errorf("List-Unsubscribe = %q, want %q", got, tt.wantList)
+			}
+			gotPost, _ := headers["List-Unsubscribe-Post"].(string)
+			if gotPost != tt.wantPost {
+				t.Errorf("List-Unsubscribe-Post = %q, want %q", gotPost, tt.wantPost)
+			}
+		})
+	}
+}
+
+func TestBuildSendGridRequestSendAt(t *testing.T) {
+	svc := newTestSendGridService(t)
+	sendAt := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+	msg := &Message{
+		From:    Address{Email: "noreply@example.com"},
+		To:      []Address{{Email: "jane@example.com"}},
+		Subject: "Day 2",
+		Text:    "Hello",
+		SendAt:  sendAt,
+	}
+
+	payload := buildPayload(t, svc, msg)
+	if got, ok := payload["send_at"].(float64); !ok || int64(got) != sendAt.Unix() {
+		t.Errorf("send_at = %v, want %d", payload["send_at"], sendAt.Unix())
+	}
+
+	msg.SendAt = time.Time{}
+	payload = buildPayload(t, svc, msg)
+	if _, ok := payload["send_at"]; ok {
+		t.Error("send_at should be omitted for immediate sends")
+	}
+}
+
+func TestValidateSendAt(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		sendAt  time.Time
+		wantErr bool
+	}{
+		{name: "Zero", sendAt: time.Time{}},
+		{name: "Past", sendAt: now.Add(-time.Hour)},
+		{name: "Within limit", sendAt: now.Add(MaxScheduleAhead)},
+		{name: "Too far ahead", sendAt: now.Add(MaxScheduleAhead + time.Minute), wantErr: true},
+		{name: "Absurd", sendAt: now.AddDate(10, 0, 0), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSendAt(tt.sendAt, now); (err != nil) != tt.wantErr {
+				t.Errorf("validateSendAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalServiceScheduledSend(t *testing.T) {
+	svc := NewLocalService(Config{FromEmail: "noreply@example.com"})
+	ctx := context.Background()
+	now := time.Now()
+
+	later := &Message{To: []Address{{Email: "jane@example.com"}}, Subject: "later", SendAt: now.Add(2 * time.Hour)}
+	sooner := &Message{To: []Address{{Email: "jane@example.com"}}, Subject: "sooner", SendAt: now.Add(time.Hour)}
+	immediate := &Message{To: []Address{{Email: "jane@example.com"}}, Subject: "now"}
+	for _, msg := range []*Message{later, sooner, immediate} {
+		if err := svc.Send(ctx, msg); err != nil {
+			t.Fatalf("Send(%s) failed: %v", msg.Subject, err)
+		}
+	}
+
+	if got := len(svc.GetMessages()); got != 1 {
+		t.Fatalf("Expected only the immediate message to be sent, got %d", got)
+	}
+	if due := svc.DueMessages(now); len(due) != 0 {
+		t.Errorf("Expected nothing due yet, got %d", len(due))
+	}
+
+	due := svc.DueMessages(now.Add(90 * time.Minute))
+	if len(due) != 1 || due[0] != sooner {
+		t.Fatalf("Expected the sooner message to be due, got %v", due)
+	}
+
+	due = svc.DueMessages(now.Add(3 * time.Hour))
+	if len(due) != 1 || due[0] != later {
+		t.Fatalf("Expected the later message to be due, got %v", due)
+	}
+	if due := svc.DueMessages(now.Add(3 * time.Hour)); len(due) != 0 {
+		t.Errorf("Due messages should only be returned once, got %d", len(due))
+	}
+
+	tooFar := &Message{To: []Address{{Email: "jane@example.com"}}, Subject: "too far", SendAt: now.AddDate(1, 0, 0)}
+	if err := svc.Send(ctx, tooFar); err == nil {
+		t.Error("Expected an error for a SendAt a year ahead")
+	}
+}
+
+func TestLocalServiceDueMessagesOrder(t *testing.T) {
+	svc := NewLocalService(Config{})
+	now := time.Now()
+	for _, offset := range []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour} {
+		msg := &Message{To: []Address{{Email: "jane@example.com"}}, SendAt: now.Add(offset)}
+		if err := svc.Send(context.Background(), msg); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	due := svc.DueMessages(now.Add(4 * time.Hour))
+	if len(due) != 3 {
+		t.Fatalf("Expected 3 due messages, got %d", len(due))
+	}
+	for i := 1; i < len(due); i++ {
+		if due[i].SendAt.Before(due[i-1].SendAt) {
+			t.Errorf("Due messages not ordered by SendAt")
+		}
+	}
+}
+
+// sendGridTransport answers mail/send requests like SendGrid
+type sendGridTransport struct {
+	status    int
+	messageID string
+	body      string
+	requests  int
+}
+
+func (t *sendGridTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	header := make(http.Header)
+	if t.messageID != "" {
+		header.Set("X-Message-Id", t.messageID)
+	}
+	return &http.Response{
+		StatusCode: t.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestSendGridSendWithResult(t *testing.T) {
+	svc := newTestSendGridService(t)
+	transport := &sendGridTransport{status: http.StatusAccepted, messageID: "14c5d75ce93.dfd.64b469.filter0001"}
+	svc.client.Transport = transport
+
+	message := &Message{
+		To:      []Address{{Email: "jane@example.com"}},
+		CC:      []Address{{Email: "ops@example.com"}},
+		BCC:     []Address{{Email: "audit@example.com"}},
+		Subject: "Receipt",
+		Text:    "Thanks",
+	}
+	result, err := svc.SendWithResult(context.Background(), message)
+	if err != nil {
+		t.Fatalf("SendWithResult failed: %v", err)
+	}
+
+	if result.MessageID != transport.messageID {
+		t.Errorf("MessageID = %q, want %q", result.MessageID, transport.messageID)
+	}
+	if result.Provider != "sendgrid" {
+		t.Errorf("Provider = %q, want sendgrid", result.Provider)
+	}
+	want := []string{"jane@example.com", "ops@example.com", "audit@example.com"}
+	if strings.Join(result.Accepted, ",") != strings.Join(want, ",") || len(result.Rejected) != 0 {
+		t.Errorf("Accepted = %v, Rejected = %v, want %v accepted", result.Accepted, result.Rejected, want)
+	}
+
+	// Send still works as a wrapper
+	if err := svc.Send(context.Background(), message); err != nil || transport.requests != 2 {
+		t.Errorf("Send() error = %v after %d requests", err, transport.requests)
+	}
+}
+
+func TestSendGridSendWithResultError(t *testing.T) {
+	svc := newTestSendGridService(t)
+	svc.client.Transport = &sendGridTransport{
+		status: http.StatusBadRequest,
+		body:   `{"errors":[{"message":"Does not contain a valid address.","field":"personalizations.0.to.0.email"}]}`,
+	}
+
+	result, err := svc.SendWithResult(context.Background(), &Message{To: []Address{{Email: "bad"}}, Subject: "x", Text: "x"})
+	if err == nil || result != nil {
+		t.Fatalf("Expected an error and no result, got %+v, %v", result, err)
+	}
+	if !strings.Contains(err.Error(), "status 400") {
+		t.Errorf("error = %v, want the SendGrid status", err)
+	}
+}
+
+func TestLocalServiceSendWithResult(t *testing.T) {
+	svc := NewLocalService(Config{FromEmail: "noreply@example.com"})
+	ctx := context.Background()
+	message := &Message{To: []Address{{Email: "jane@example.com"}}, Subject: "Hello"}
+
+	first, err := svc.SendWithResult(ctx, message)
+	if err != nil {
+		t.Fatalf("SendWithResult failed: %v", err)
+	}
+	second, err := svc.SendWithResult(ctx, message)
+	if err != nil {
+		t.Fatalf("SendWithResult failed: %v", err)
+	}
+
+	if !strings.HasPrefix(first.MessageID, "local-") || first.MessageID == second.MessageID {
+		t.Errorf("Expected distinct local message IDs, got %q and %q", first.MessageID, second.MessageID)
+	}
+	if first.Provider != "local" || len(first.Accepted) != 1 || first.Accepted[0] != "jane@example.com" {
+		t.Errorf("result = %+v", first)
+	}
+}
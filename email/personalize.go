@@ -0,0 +1,232 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package email
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/patdeg/common"
+)
+
+// MaxPersonalizations is the number of recipients SendGrid accepts in one
+// mail/send request. Larger batches are split.
+const MaxPersonalizations = 1000
+
+// mergeFieldPattern restricts merge field names to characters that survive
+// HTML and URL escaping unchanged, so substitution tags reach SendGrid intact
+var mergeFieldPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// PersonalizedRecipient is one recipient of SendPersonalizedBatch with the
+// merge fields used when rendering their copy, e.g.
+// {"first_name": "Ada", "unsubscribe_url": "https://example.com/u/123"}.
+type PersonalizedRecipient struct {
+	To   Address           `json:"to"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// SendPersonalizedBatch sends the template to every recipient in as few
+// SendGrid calls as possible. The template is rendered once with each merge
+// field replaced by a substitution tag ("-first_name-"), and every recipient
+// gets a personalization substituting their own HTML-escaped values, so
+// recipients never see each other's data:
+//
+//	<p>Hi {{.first_name}}</p> <a href="{{.unsubscribe_url}}">Unsubscribe</a>
+//
+// Because the template sees tags rather than values, merge fields may only
+// be printed; conditionals on them are not supported. Field names must be
+// letters, digits and underscores. A recipient without a field gets an
+// empty value.
+//
+// Values skip html/template's contextual escaping, so they are escaped
+// here instead: every value is HTML-escaped, and values of fields printed
+// in URL attributes such as href must be relative or use http, https or
+// mailto, otherwise they are replaced by "#ZgotmplZ" as html/template
+// does; a field printed both in a URL attribute and elsewhere is filtered
+// everywhere, since it has one value. Unlike html/template, URLs are not percent-encoded and values in
+// inline scripts or styles get HTML escaping only, so keep merge fields out
+// of those. LocalService renders each copy with html/template directly.
+func (s *SendGridService) SendPersonalizedBatch(ctx context.Context, templateName string, recipients []PersonalizedRecipient) error {
+	requests, err := s.buildPersonalizedRequests(templateName, recipients)
+	if err != nil {
+		return err
+	}
+
+	for i, sgReq := range requests {
+		if _, err := s.post(ctx, sgReq); err != nil {
+			return fmt.Errorf("failed to send batch %d of %d: %v", i+1, len(requests), err)
+		}
+	}
+
+	common.Info("[EMAIL] Sent template %s via SendGrid to %d personalized recipients", templateName, len(recipients))
+	return nil
+}
+
+// buildPersonalizedRequests renders the template with substitution tags and
+// returns one SendGrid request per MaxPersonalizations recipients
+func (s *SendGridService) buildPersonalizedRequests(templateName string, recipients []PersonalizedRecipient) ([]map[string]interface{}, error) {
+	fields, err := mergeFields(recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(fields))
+	for _, field := range fields {
+		tags[field] = substitutionTag(field)
+	}
+	body, err := renderTemplate(s.config.Templates, templateName, tags)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := urlFields(s.config.Templates, templateName, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		From:    Address{Email: s.fromEmail, Name: s.fromName},
+		Subject: fmt.Sprintf("Message from %s", s.fromName),
+		HTML:    body,
+	}
+
+	var requests []map[string]interface{}
+	for start := 0; start < len(recipients); start += MaxPersonalizations {
+		end := min(start+MaxPersonalizations, len(recipients))
+
+		personalizations := make([]map[string]interface{}, 0, end-start)
+		for _, recipient := range recipients[start:end] {
+			substitutions := make(map[string]string, len(fields))
+			for _, field := range fields {
+				value := recipient.Data[field]
+				if urls[field] {
+					value = safeMergeURL(value)
+				}
+				substitutions[substitutionTag(field)] = html.EscapeString(value)
+			}
+			personalizations = append(personalizations, map[string]interface{}{
+				"to":            convertAddresses([]Address{recipient.To}),
+				"substitutions": substitutions,
+			})
+		}
+
+		sgReq := s.buildSendGridRequest(message)
+		sgReq["personalizations"] = personalizations
+		requests = append(requests, sgReq)
+	}
+	return requests, nil
+}
+
+// SendPersonalizedBatch renders the template for each recipient with their
+// own data and sends one message per recipient
+func (s *LocalService) SendPersonalizedBatch(ctx context.Context, templateName string, recipients []PersonalizedRecipient) error {
+	if _, err := mergeFields(recipients); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		body, err := renderTemplate(s.config.Templates, templateName, recipient.Data)
+		if err != nil {
+			return err
+		}
+
+		data := make(map[string]interface{}, len(recipient.Data))
+		for field, value := range recipient.Data {
+			data[field] = value
+		}
+		message := &Message{
+			To:           []Address{recipient.To},
+			Subject:      fmt.Sprintf("Message from %s", s.config.FromName),
+			HTML:         body,
+			TemplateID:   templateName,
+			TemplateData: data,
+		}
+		if err := s.Send(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFields returns the sorted union of the recipients' field names and
+// rejects names that cannot be used as substitution tags
+func mergeFields(recipients []PersonalizedRecipient) ([]string, error) {
+	seen := make(map[string]bool)
+	var fields []string
+	for _, recipient := range recipients {
+		if !isValidEmail(recipient.To.Email) {
+			return nil, fmt.Errorf("invalid email address: %s", recipient.To.Email)
+		}
+		for field := range recipient.Data {
+			if seen[field] {
+				continue
+			}
+			if !mergeFieldPattern.MatchString(field) {
+				return nil, fmt.Errorf("invalid merge field name: %q", field)
+			}
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// unsafeURLReplacement is what html/template prints for URLs it refuses
+const unsafeURLReplacement = "#ZgotmplZ"
+
+// urlFields returns the merge fields the template prints in URL attributes.
+// The template is rendered once per field with an unsafe URL as its value;
+// html/template replaces it only where a URL is expected.
+func urlFields(templates map[string]string, templateName string, tags map[string]string) (map[string]bool, error) {
+	urls := make(map[string]bool)
+	for field := range tags {
+		probe := make(map[string]string, len(tags))
+		for f, tag := range tags {
+			probe[f] = tag
+		}
+		probe[field] = "javascript:" + tags[field]
+
+		body, err := renderTemplate(templates, templateName, probe)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(body, unsafeURLReplacement) {
+			urls[field] = true
+		}
+	}
+	return urls, nil
+}
+
+// safeMergeURL applies html/template's URL filter: relative URLs and the
+// http, https and mailto schemes pass, anything else is replaced
+func safeMergeURL(value string) string {
+	if i := strings.IndexRune(value, ':'); i >= 0 && !strings.ContainsRune(value[:i], '/') {
+		switch strings.ToLower(value[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return unsafeURLReplacement
+		}
+	}
+	return value
+}
+
+// substitutionTag returns the SendGrid substitution tag for a merge field
+func substitutionTag(field string) string {
+	return "-" + field + "-"
+}
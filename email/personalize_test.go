@@ -0,0 +1,204 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const welcomeTemplate = `<p>Hi {{.first_name}}</p><a href="{{.unsubscribe_url}}">Unsubscribe</a>`
+
+func personalizedRecipients() []PersonalizedRecipient {
+	return []PersonalizedRecipient{
+		{To: Address{Email: "ada@example.com"}, Data: map[string]string{"first_name": "Ada", "unsubscribe_url": "https://example.com/u/1?list=news&t=a"}},
+		{To: Address{Email: "bob@example.com"}, Data: map[string]string{"first_name": "<Bob>", "unsubscribe_url": "https://example.com/u/2"}},
+	}
+}
+
+// applySubstitutions mimics SendGrid replacing substitution tags in content
+func applySubstitutions(content string, substitutions map[string]interface{}) string {
+	for tag, value := range substitutions {
+		content = strings.ReplaceAll(content, tag, value.(string))
+	}
+	return content
+}
+
+func TestSendGridPersonalizedBatch(t *testing.T) {
+	svc := newTestSendGridService(t)
+	svc.config.Templates = map[string]string{"welcome": welcomeTemplate}
+
+	requests, err := svc.buildPersonalizedRequests("welcome", personalizedRecipients())
+	if err != nil {
+		t.Fatalf("buildPersonalizedRequests failed: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+
+	data, err := json.Marshal(requests[0])
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var payload struct {
+		Personalizations []struct {
+			To            []map[string]string    `json:"to"`
+			Substitutions map[string]interface{} `json:"substitutions"`
+		} `json:"personalizations"`
+		Content []map[string]string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	shared := payload.Content[0]["value"]
+	if !strings.Contains(shared, "Hi -first_name-") || strings.Contains(shared, "Ada") {
+		t.Fatalf("shared content should only hold tags, got %s", shared)
+	}
+
+	want := []string{
+		`<p>Hi Ada</p><a href="https://example.com/u/1?list=news&amp;t=a">Unsubscribe</a>`,
+		`<p>Hi &lt;Bob&gt;</p><a href="https://example.com/u/2">Unsubscribe</a>`,
+	}
+	if len(payload.Personalizations) != len(want) {
+		t.Fatalf("got %d personalizations, want %d", len(payload.Personalizations), len(want))
+	}
+	for i, p := range payload.Personalizations {
+		if len(p.To) != 1 || p.To[0]["email"] != personalizedRecipients()[i].To.Email {
+			t.Errorf("personalization %d: to = %v", i, p.To)
+		}
+		if got := applySubstitutions(shared, p.Substitutions); got != want[i] {
+			t.Errorf("personalization %d rendered\n%s\nwant\n%s", i, got, want[i])
+		}
+	}
+}
+
+// TestSendGridPersonalizedBatchURLs verifies unsafe URLs are filtered in
+// URL attributes only, matching what html/template renders
+func TestSendGridPersonalizedBatchURLs(t *testing.T) {
+	const tmpl = `<p>{{.label}}</p><a href="{{.link}}">Open</a><a title="{{.label}}">x</a>`
+	svc := newTestSendGridService(t)
+	svc.config.Templates = map[string]string{"links": tmpl}
+
+	tests := []struct {
+		name string
+		link string
+	}{
+		{"https", "https://example.com/a?b=c"},
+		{"relative", "/u/1"},
+		{"mailto", "mailto:ada@example.com"},
+		{"javascript", "javascript:alert(1)"},
+		{"mixed case scheme", "JavaScript:alert(1)"},
+		{"data", "data:text/html,hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]string{"link": tt.link, "label": "javascript:alert(1)"}
+			requests, err := svc.buildPersonalizedRequests("links", []PersonalizedRecipient{{To: Address{Email: "ada@example.com"}, Data: data}})
+			if err != nil {
+				t.Fatalf("buildPersonalizedRequests failed: %v", err)
+			}
+			content := requests[0]["content"].([]map[string]string)[0]["value"]
+			personalization := requests[0]["personalizations"].([]map[string]interface{})[0]
+			substitutions := make(map[string]interface{})
+			for tag, value := range personalization["substitutions"].(map[string]string) {
+				substitutions[tag] = value
+			}
+
+			want, err := renderTemplate(svc.config.Templates, "links", data)
+			if err != nil {
+				t.Fatalf("renderTemplate failed: %v", err)
+			}
+			if got := applySubstitutions(content, substitutions); got != want {
+				t.Errorf("rendered\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSendGridPersonalizedBatchSplits(t *testing.T) {
+	svc := newTestSendGridService(t)
+	svc.config.Templates = map[string]string{"welcome": welcomeTemplate}
+
+	recipients := make([]PersonalizedRecipient, MaxPersonalizations+1)
+	for i := range recipients {
+		recipients[i] = PersonalizedRecipient{To: Address{Email: "user@example.com"}}
+	}
+	requests, err := svc.buildPersonalizedRequests("welcome", recipients)
+	if err != nil {
+		t.Fatalf("buildPersonalizedRequests failed: %v", err)
+	}
+	if len(requests) != 2 || len(requests[1]["personalizations"].([]map[string]interface{})) != 1 {
+		t.Errorf("expected a full batch and a batch of one, got %d requests", len(requests))
+	}
+}
+
+func TestPersonalizedBatchValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		template   string
+		recipients []PersonalizedRecipient
+	}{
+		{name: "Unknown template", template: "missing", recipients: personalizedRecipients()},
+		{name: "Invalid field name", template: "welcome", recipients: []PersonalizedRecipient{{To: Address{Email: "ada@example.com"}, Data: map[string]string{"first name": "Ada"}}}},
+		{name: "Invalid address", template: "welcome", recipients: []PersonalizedRecipient{{To: Address{Email: "not-an-address"}}}},
+	}
+
+	svc := newTestSendGridService(t)
+	svc.config.Templates = map[string]string{"welcome": welcomeTemplate}
+	local := NewLocalService(Config{Templates: map[string]string{"welcome": welcomeTemplate}})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.buildPersonalizedRequests(tt.template, tt.recipients); err == nil {
+				t.Error("expected SendGrid error")
+			}
+			if err := local.SendPersonalizedBatch(context.Background(), tt.template, tt.recipients); err == nil {
+				t.Error("expected local error")
+			}
+		})
+	}
+}
+
+func TestLocalServicePersonalizedBatch(t *testing.T) {
+	svc := NewLocalService(Config{
+		FromEmail: "noreply@example.com",
+		Templates: map[string]string{"welcome": welcomeTemplate},
+	})
+
+	if err := svc.SendPersonalizedBatch(context.Background(), "welcome", personalizedRecipients()); err != nil {
+		t.Fatalf("SendPersonalizedBatch failed: %v", err)
+	}
+
+	messages := svc.GetMessages()
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	for i, want := range []string{"Hi Ada", "Hi &lt;Bob&gt;"} {
+		msg := messages[i]
+		if len(msg.To) != 1 || msg.To[0].Email != personalizedRecipients()[i].To.Email {
+			t.Errorf("message %d sent to %v", i, msg.To)
+		}
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("message %d HTML = %s, want %q", i, msg.HTML, want)
+		}
+	}
+	if strings.Contains(messages[0].HTML, "Bob") || strings.Contains(messages[1].HTML, "Ada") {
+		t.Error("recipient data leaked into another message")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// Handlers that return bare fmt.Errorf values leave the HTTP layer guessing
+// at the status code. AppError carries the status, a stable machine-readable
+// code and a client-safe message, while keeping the underlying cause for
+// logs. (The type is not called Error because that name is the logging
+// function.)
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes used by the AppError constructors
+const (
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeInternal     = "internal"
+
+	// CodePayloadTooLarge is used by ReadJSONRequest for oversized bodies
+	CodePayloadTooLarge = "payload_too_large"
+)
+
+// AppError is an error with an HTTP status. Message is shown to clients by
+// WriteError; Cause is only included in Error() for logging.
+type AppError struct {
+	Code    string // Machine-readable code, e.g. "not_found"
+	Message string // Client-safe description
+	Status  int    // HTTP status code
+	Cause   error  // Underlying error, never sent to clients
+}
+
+// NewAppError creates an AppError with the given status, code and message
+func NewAppError(status int, code, message string) *AppError {
+	return &AppError{Code: code, Message: message, Status: status}
+}
+
+// BadRequest returns a 400 error for invalid client input
+func BadRequest(message string) *AppError {
+	return NewAppError(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Unauthorized returns a 401 error for missing or invalid credentials
+func Unauthorized(message string) *AppError {
+	return NewAppError(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden returns a 403 error for authenticated callers lacking access
+func Forbidden(message string) *AppError {
+	return NewAppError(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound returns a 404 error
+func NotFound(message string) *AppError {
+	return NewAppError(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict returns a 409 error, e.g. for duplicates or stale updates
+func Conflict(message string) *AppError {
+	return NewAppError(http.StatusConflict, CodeConflict, message)
+}
+
+// Internal returns a 500 error wrapping cause. Clients only see a generic
+// message.
+func Internal(cause error) *AppError {
+	return &AppError{Code: CodeInternal, Message: "internal server error", Status: http.StatusInternalServerError, Cause: cause}
+}
+
+// WithCause returns a copy of e wrapping cause:
+//
+//	return common.NotFound("invoice not found").WithCause(err)
+func (e *AppError) WithCause(cause error) *AppError {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+// Error returns the code, message and cause for logging
+func (e *AppError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the cause so errors.Is and errors.As see through AppError
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// HTTPStatus returns the status of the first AppError in err's chain, or
+// 500 for any other non-nil error. A nil error maps to 200.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Status != 0 {
+		return appErr.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// errorResponse is the JSON body written by WriteError
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// WriteError writes err as a JSON response with the matching status:
+//
+//	{"error": "invoice not found", "code": "not_found"}
+//
+// Errors without an AppError in their chain are written as a 500 with a
+// generic message so internal details do not leak. Server errors (5xx) are
+// logged with their cause.
+func WriteError(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		appErr = Internal(err)
+	}
+	status := appErr.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		Error("[HTTP] %v", err)
+	}
+
+	if werr := WriteJSONWithStatus(w, status, errorResponse{Error: appErr.Message, Code: appErr.Code}); werr != nil {
+		Error("[HTTP] Failed to write error response: %v", werr)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAppErrorStatusMapping checks constructors and HTTPStatus, including wrapped errors
+func TestAppErrorStatusMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "Bad request", err: BadRequest("missing name"), wantStatus: http.StatusBadRequest, wantCode: CodeBadRequest},
+		{name: "Unauthorized", err: Unauthorized("login required"), wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "Forbidden", err: Forbidden("admins only"), wantStatus: http.StatusForbidden, wantCode: CodeForbidden},
+		{name: "Not found", err: NotFound("invoice not found"), wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+		{name: "Conflict", err: Conflict("email already registered"), wantStatus: http.StatusConflict, wantCode: CodeConflict},
+		{name: "Internal", err: Internal(errors.New("db down")), wantStatus: http.StatusInternalServerError, wantCode: CodeInternal},
+		{name: "Custom", err: NewAppError(http.StatusTooManyRequests, "rate_limited", "slow down"), wantStatus: http.StatusTooManyRequests, wantCode: "rate_limited"},
+		{name: "Wrapped", err: fmt.Errorf("failed to load invoice: %w", NotFound("invoice not found")), wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+		{name: "Plain error", err: errors.New("boom"), wantStatus: http.StatusInternalServerError},
+		{name: "Nil", err: nil, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.wantStatus {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.wantStatus)
+			}
+			var appErr *AppError
+			if errors.As(tt.err, &appErr) && appErr.Code != tt.wantCode {
+				t.Errorf("Code = %q, want %q", appErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestAppErrorCause checks that the cause is unwrapped and kept out of the original
+func TestAppErrorCause(t *testing.T) {
+	base := NotFound("user not found")
+	cause := errors.New("no rows")
+	err := base.WithCause(cause)
+
+	if !errors.Is(err, cause) {
+		t.Error("Expected errors.Is to find the cause")
+	}
+	if base.Cause != nil {
+		t.Error("WithCause should not modify the original error")
+	}
+	if got, want := err.Error(), "not_found: user not found: no rows"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got, want := base.Error(), "not_found: user not found"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// TestWriteError checks the status and JSON body, and that internal details are not leaked
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   map[string]string
+	}{
+		{
+			name:       "Not found",
+			err:        NotFound("invoice not found"),
+			wantStatus: http.StatusNotFound,
+			wantBody:   map[string]string{"error": "invoice not found", "code": "not_found"},
+		},
+		{
+			name:       "Wrapped conflict",
+			err:        fmt.Errorf("failed to create user: %w", Conflict("email already registered").WithCause(errors.New("unique violation"))),
+			wantStatus: http.StatusConflict,
+			wantBody:   map[string]string{"error": "email already registered", "code": "conflict"},
+		},
+		{
+			name:       "Plain error hides details",
+			err:        errors.New("connection refused to 10.0.0.1"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   map[string]string{"error": "internal server error", "code": "internal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteError(rec, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", ct)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if len(body) != len(tt.wantBody) {
+				t.Errorf("Expected body %v, got %v", tt.wantBody, body)
+			}
+			for k, v := range tt.wantBody {
+				if body[k] != v {
+					t.Errorf("Expected %s=%q, got %q", k, v, body[k])
+				}
+			}
+		})
+	}
+}
@@ -26,11 +26,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"time"
 
 	"github.com/patdeg/common"
 	"github.com/patdeg/common/auth"
@@ -65,16 +65,14 @@ func main() {
 	// cookie before redirecting back to the requested page.
 	http.HandleFunc("/goog_callback", auth.GoogleCallbackHandler)
 
-	// Create an HTTP server with sensible timeouts to avoid slowloris and
-	// similar attacks. These values are safe defaults for most applications.
-	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      nil, // Default ServeMux
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
+	// NewServer applies safe read, write and idle timeouts to avoid
+	// slowloris and similar attacks. ListenAndServeGraceful drains
+	// in-flight requests when App Engine sends SIGTERM before shutting the
+	// instance down.
+	server := common.NewServer(":"+port, http.DefaultServeMux, common.ServerOptions{})
 
 	common.Info("Starting server on port %s", port)
-	log.Fatal(server.ListenAndServe())
+	if err := server.ListenAndServeGraceful(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 }
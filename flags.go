@@ -0,0 +1,179 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// Flags are runtime feature toggles read from environment variables, so a
+// feature can be switched per deployment without a code change. A flag
+// named "strict_csp" is read from FLAG_STRICT_CSP on every lookup. Tests
+// pin values with Set, and FlagMiddleware lets canary requests override
+// selected flags with a header.
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultFlagEnvPrefix is prepended to flag names to form environment
+	// variable names
+	DefaultFlagEnvPrefix = "FLAG_"
+
+	// FlagOverrideHeader carries per-request overrides for FlagMiddleware,
+	// as comma-separated name=value pairs: "new_checkout=on, page_size=50"
+	FlagOverrideHeader = "X-Feature-Flags"
+)
+
+// flagOverridesKey is the context key for per-request flag overrides
+type flagOverridesKey struct{}
+
+// Flags looks up feature flags. Values come, in order of precedence, from
+// per-request overrides set by FlagMiddleware, values pinned with Set, and
+// environment variables; anything else yields the getter's default. It is
+// safe for concurrent use.
+//
+//	flags := common.NewFlags("")
+//	if flags.Bool(r.Context(), "strict_csp", false) {
+//	    handler = web.StrictCSPMiddleware(nil)(handler)
+//	}
+type Flags struct {
+	prefix string
+
+	mu     sync.RWMutex
+	pinned map[string]string
+}
+
+// NewFlags returns flags read from environment variables named prefix plus
+// the upper-cased flag name. An empty prefix uses DefaultFlagEnvPrefix.
+func NewFlags(prefix string) *Flags {
+	if prefix == "" {
+		prefix = DefaultFlagEnvPrefix
+	}
+	return &Flags{prefix: prefix, pinned: make(map[string]string)}
+}
+
+// Set pins a flag to value until Unset, taking precedence over the
+// environment. It is meant for tests and admin endpoints.
+func (f *Flags) Set(name, value string) {
+	f.mu.Lock()
+	f.pinned[normalizeFlagName(name)] = value
+	f.mu.Unlock()
+}
+
+// Unset removes a value pinned with Set
+func (f *Flags) Unset(name string) {
+	f.mu.Lock()
+	delete(f.pinned, normalizeFlagName(name))
+	f.mu.Unlock()
+}
+
+// String returns the flag's value, or def when it is not set or empty
+func (f *Flags) String(ctx context.Context, name, def string) string {
+	if value, ok := f.lookup(ctx, name); ok {
+		return value
+	}
+	return def
+}
+
+// Bool returns the flag as a boolean, accepting the spellings of
+// StringToBoolOr. It returns def when the flag is not set or not a boolean.
+func (f *Flags) Bool(ctx context.Context, name string, def bool) bool {
+	value, _ := f.lookup(ctx, name)
+	return StringToBoolOr(value, def)
+}
+
+// Int returns the flag as an int, or def when it is not set or not an int
+func (f *Flags) Int(ctx context.Context, name string, def int) int {
+	value, _ := f.lookup(ctx, name)
+	return StringToIntOr(value, def)
+}
+
+// lookup returns the first non-empty value for name
+func (f *Flags) lookup(ctx context.Context, name string) (string, bool) {
+	name = normalizeFlagName(name)
+
+	if ctx != nil {
+		if overrides, ok := ctx.Value(flagOverridesKey{}).(map[string]string); ok {
+			if value := overrides[name]; value != "" {
+				return value, true
+			}
+		}
+	}
+
+	f.mu.RLock()
+	value := f.pinned[name]
+	f.mu.RUnlock()
+	if value != "" {
+		return value, true
+	}
+
+	if value := os.Getenv(f.prefix + strings.ToUpper(name)); value != "" {
+		return value, true
+	}
+	return "", false
+}
+
+// FlagMiddleware applies per-request overrides from the X-Feature-Flags
+// header, for trying a feature on canary traffic before enabling it:
+//
+//	handler := common.FlagMiddleware("new_checkout", "beta_search")(mux)
+//	// curl -H "X-Feature-Flags: new_checkout=on" https://app.example.com/
+//
+// Only the listed flags can be overridden; other names in the header are
+// ignored, so clients cannot switch off flags that guard security features.
+// Anyone can send the header, so only list flags that are safe for any
+// user to toggle, or strip the header at the edge for untrusted clients.
+func FlagMiddleware(overridable ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(overridable))
+	for _, name := range overridable {
+		allowed[normalizeFlagName(name)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(FlagOverrideHeader)
+			if header == "" || len(allowed) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			overrides := make(map[string]string)
+			for _, pair := range strings.Split(header, ",") {
+				name, value, ok := strings.Cut(pair, "=")
+				name = normalizeFlagName(name)
+				if !ok || !allowed[name] {
+					Debug("[FLAGS] Ignoring override %q", strings.TrimSpace(pair))
+					continue
+				}
+				overrides[name] = strings.TrimSpace(value)
+			}
+			if len(overrides) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), flagOverridesKey{}, overrides)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// normalizeFlagName lower-cases a flag name and maps '-' and '.' to '_',
+// so "strict-csp" and "STRICT_CSP" name the same flag
+func normalizeFlagName(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
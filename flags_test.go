@@ -0,0 +1,130 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestFlagsEnv checks env parsing, typed getters and defaults.
+func TestFlagsEnv(t *testing.T) {
+	t.Setenv("TESTFLAG_STRICT_CSP", "on")
+	t.Setenv("TESTFLAG_PAGE_SIZE", " 50 ")
+	t.Setenv("TESTFLAG_THEME", "dark")
+	t.Setenv("TESTFLAG_BROKEN", "maybe")
+	t.Setenv("TESTFLAG_EMPTY", "")
+
+	flags := NewFlags("TESTFLAG_")
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"bool from env", flags.Bool(ctx, "strict_csp", false), true},
+		{"name normalized", flags.Bool(ctx, "Strict-CSP", false), true},
+		{"bool default", flags.Bool(ctx, "missing", true), true},
+		{"malformed bool uses default", flags.Bool(ctx, "broken", true), true},
+		{"int from env", flags.Int(ctx, "page_size", 10), 50},
+		{"malformed int uses default", flags.Int(ctx, "theme", 10), 10},
+		{"string from env", flags.String(ctx, "theme", "light"), "dark"},
+		{"empty string uses default", flags.String(ctx, "empty", "light"), "light"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	// The environment is read on every lookup
+	t.Setenv("TESTFLAG_STRICT_CSP", "off")
+	if flags.Bool(ctx, "strict_csp", true) {
+		t.Error("Expected the changed env var to apply")
+	}
+
+	if got := NewFlags("").prefix; got != DefaultFlagEnvPrefix {
+		t.Errorf("default prefix = %q, want %q", got, DefaultFlagEnvPrefix)
+	}
+}
+
+// TestFlagsSet checks that pinned values win over the environment until unset.
+func TestFlagsSet(t *testing.T) {
+	t.Setenv("TESTFLAG_BETA", "false")
+	flags := NewFlags("TESTFLAG_")
+	ctx := context.Background()
+
+	flags.Set("beta", "true")
+	if !flags.Bool(ctx, "beta", false) {
+		t.Error("Expected Set to override the environment")
+	}
+
+	flags.Unset("BETA")
+	if flags.Bool(ctx, "beta", true) {
+		t.Error("Expected the environment value after Unset")
+	}
+}
+
+// TestFlagMiddleware checks per-request overrides and the allow list.
+func TestFlagMiddleware(t *testing.T) {
+	flags := NewFlags("TESTFLAG_")
+	flags.Set("strict_csp", "on")
+
+	tests := []struct {
+		name        string
+		header      string
+		wantBeta    bool
+		wantCSP     bool
+		wantPercent int
+	}{
+		{"no header", "", false, true, 0},
+		{"override", "beta=on, rollout_percent=25", true, true, 25},
+		{"disallowed flag ignored", "strict_csp=off,beta=yes", true, true, 0},
+		{"malformed pair ignored", "beta, rollout_percent=5", false, true, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var beta, csp bool
+			var percent int
+			handler := FlagMiddleware("beta", "rollout-percent")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				beta = flags.Bool(r.Context(), "beta", false)
+				csp = flags.Bool(r.Context(), "strict_csp", false)
+				percent = flags.Int(r.Context(), "rollout_percent", 0)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(FlagOverrideHeader, tt.header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if beta != tt.wantBeta || csp != tt.wantCSP || percent != tt.wantPercent {
+				t.Errorf("beta=%v strict_csp=%v rollout_percent=%d, want %v %v %d",
+					beta, csp, percent, tt.wantBeta, tt.wantCSP, tt.wantPercent)
+			}
+		})
+	}
+
+	// Overrides are scoped to the request
+	if flags.Bool(context.Background(), "beta", false) {
+		t.Error("Expected overrides not to leak outside the request")
+	}
+}
@@ -26,11 +26,15 @@
 //	ga.TrackGAEvent(r.Context(), ga.PropertyID, event)
 //
 // The PropertyID variable holds the default Google Analytics Tracking ID.
+//
+// Privacy: set AnonymizeIP to send aip=1 with every hit, and record the
+// visitor's choice in GAEvent.Consent. Hits with ConsentDenied are never
+// sent; set RequireConsent to also drop hits where no choice was recorded.
 package ga
 
 import (
-	"bytes"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -45,6 +49,35 @@ import (
 var (
 	PropertyID string = "UA-63208527-1"
 	// PropertyID string = "UA-68699208-1"
+
+	// AnonymizeIP adds aip=1 to every hit so Google Analytics drops the
+	// last octet of the visitor IP before storing it.
+	AnonymizeIP bool
+
+	// RequireConsent suppresses hits whose Consent is ConsentUnknown. Hits
+	// with ConsentDenied are suppressed regardless of this setting.
+	RequireConsent bool
+)
+
+// endpointURL is the Measurement Protocol collection endpoint
+const endpointURL = "https://www.google-analytics.com/collect?"
+
+// clientFor returns the HTTP client used to send hits. Tests replace it to
+// capture requests without App Engine.
+var clientFor = func(c context.Context) *http.Client {
+	return urlfetch.Client(c)
+}
+
+// Consent records whether the visitor agreed to analytics tracking
+type Consent int
+
+const (
+	// ConsentUnknown means no choice was recorded for the hit
+	ConsentUnknown Consent = iota
+	// ConsentGranted means the visitor accepted analytics tracking
+	ConsentGranted
+	// ConsentDenied means the visitor refused analytics tracking
+	ConsentDenied
 )
 
 // https://developers.google.com/analytics/devguides/collection/protocol/v1/parameters
@@ -162,6 +195,10 @@ type GAEvent struct {
 	ExperimentID string `json:"ExperimentID,omitempty"`
 	// xvar – Experiment Variant.
 	ExperimentVariant string `json:"ExperimentVariant,omitempty"`
+	// aip – Anonymize IP for this hit (also enabled by the AnonymizeIP variable).
+	AnonymizeIP bool `json:"AnonymizeIP,omitempty"`
+	// Consent gates whether the hit is sent at all; it is not transmitted.
+	Consent Consent `json:"Consent,omitempty"`
 }
 
 func setIfNotEmpty(v *url.Values, key string, value string) {
@@ -230,16 +267,79 @@ func setEvent(etype string, event GAEvent) url.Values {
 	setIfNotEmpty(&v, "ul", event.UserLanguage)
 	setIfNotEmpty(&v, "xid", event.ExperimentID)
 	setIfNotEmpty(&v, "xvar", event.ExperimentVariant)
+	if AnonymizeIP || event.AnonymizeIP {
+		v.Set("aip", "1")
+	}
 
 	return v
 }
 
+// ClientIDCookie is the first-party cookie holding the visitor's client ID.
+// It is shared with common.GetCookieID so both identify the same visitor.
+const ClientIDCookie = "ID"
+
+// ClientIDMaxAge is the lifetime of the cookie set by EnsureClientID,
+// matching the two years used by the GA JavaScript libraries.
+const ClientIDMaxAge = 2 * 365 * 24 * time.Hour
+
+// EnsureClientID returns the visitor's client ID, setting a durable
+// first-party cookie when the request has none. The new cookie is also
+// added to r, so GetEvent(r) in the same request reports the same ID.
+// Call it before writing the response body.
+//
+// Example:
+//
+//	ga.EnsureClientID(w, r)
+//	event := ga.GetEvent(r)
+func EnsureClientID(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(ClientIDCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	id, err := common.GenerateSecureID()
+	if err != nil {
+		common.Error("GA: Failed to generate client ID: %v", err)
+		return ""
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	isLocalhost := host == "localhost" || host == "127.0.0.1"
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ClientIDCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(ClientIDMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   !isLocalhost,
+		SameSite: http.SameSiteLaxMode,
+	})
+	r.AddCookie(&http.Cookie{Name: ClientIDCookie, Value: id})
+	return id
+}
+
+// WithUserID returns event with the User-ID (uid) set so hits from the same
+// signed-in user are joined across devices and sessions. userID must be an
+// opaque internal identifier: Google Analytics forbids personal data, so
+// values that look like email addresses are dropped.
+func WithUserID(event GAEvent, userID string) GAEvent {
+	if strings.Contains(userID, "@") {
+		common.Warn("GA: Ignoring user ID that looks like an email address")
+		return event
+	}
+	event.UserId = userID
+	return event
+}
+
 // GetEvent populates a GAEvent from the HTTP request. It extracts common
 // information such as the visitor ID, IP address and referer that are often
 // included in Measurement Protocol hits.
 func GetEvent(r *http.Request) GAEvent {
 	guid := ""
-	cookie, err := r.Cookie("ID")
+	cookie, err := r.Cookie(ClientIDCookie)
 	if err == nil {
 		if cookie != nil {
 			guid = cookie.Value
@@ -297,24 +397,7 @@ func GetEvent(r *http.Request) GAEvent {
 //	event := ga.GetEvent(r)
 //	ga.TrackGAPage(r.Context(), ga.PropertyID, event)
 func TrackGAPage(c context.Context, PropertyID string, event GAEvent) {
-	endpointUrl := "https://www.google-analytics.com/collect?"
-	v := setEvent("pageview", event)
-	v.Set("tid", PropertyID)
-	payload_data := v.Encode()
-	common.Info("GA: Calling %v with %v", endpointUrl, payload_data)
-
-	req, err := http.NewRequest("POST", endpointUrl, bytes.NewBufferString(payload_data))
-	if err != nil {
-		common.Error("Error while tracking Google Analytics: %v", err)
-		return
-	}
-	resp, err := urlfetch.Client(c).Do(req)
-	if err != nil {
-		common.Error("Error while tracking Google Analytics: %v", err)
-		return
-	}
-
-	common.Debug("GA status code %v", resp.StatusCode)
+	sendHit(c, "pageview", PropertyID, event)
 }
 
 // TrackGAEvent sends an event hit to Google Analytics using the supplied
@@ -327,24 +410,48 @@ func TrackGAPage(c context.Context, PropertyID string, event GAEvent) {
 //	event.Action = "click"
 //	ga.TrackGAEvent(r.Context(), ga.PropertyID, event)
 func TrackGAEvent(c context.Context, PropertyID string, event GAEvent) {
-	endpointUrl := "https://www.google-analytics.com/collect?"
-	v := setEvent("event", event)
+	sendHit(c, "event", PropertyID, event)
+}
+
+// hasConsent reports whether a hit may be sent for event
+func hasConsent(event GAEvent) bool {
+	switch event.Consent {
+	case ConsentGranted:
+		return true
+	case ConsentDenied:
+		return false
+	default:
+		return !RequireConsent
+	}
+}
+
+// sendHit posts a hit of type etype to the Measurement Protocol endpoint
+// unless consent is missing. Transient failures are queued when
+// EnablePersistentQueue is active.
+func sendHit(c context.Context, etype string, PropertyID string, event GAEvent) {
+	if !hasConsent(event) {
+		common.Debug("GA: Skipping %v hit without analytics consent", etype)
+		return
+	}
+
+	v := setEvent(etype, event)
 	v.Set("tid", PropertyID)
 	payload_data := v.Encode()
-	common.Info("GA: Calling %v with %v", endpointUrl, payload_data)
+	common.Info("GA: Calling %v with %v", endpointURL, payload_data)
 
-	req, err := http.NewRequest("POST", endpointUrl, bytes.NewBufferString(payload_data))
-	if err != nil {
-		common.Error("Error while tracking Google Analytics: %v", err)
+	retry, err := deliverHit(c, clientFor(c), payload_data)
+	if err == nil {
 		return
 	}
-	resp, err := urlfetch.Client(c).Do(req)
-	if err != nil {
-		common.Error("Error while tracking Google Analytics: %v", err)
-		return
+	if q := currentQueue(); q != nil && retry {
+		qerr := q.enqueue(payload_data)
+		if qerr == nil {
+			common.Warn("GA: Queued hit for retry: %v", err)
+			return
+		}
+		common.Error("GA: Failed to queue hit: %v", qerr)
 	}
-
-	common.Debug("GA status code %v", resp.StatusCode)
+	common.Error("Error while tracking Google Analytics: %v", err)
 }
 
 // GATrackServeError renders an HTTP error response and records the failure as a
@@ -0,0 +1,164 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ga
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// captureTransport records the bodies of requests instead of sending them
+type captureTransport struct {
+	bodies []url.Values
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	values, _ := url.ParseQuery(string(body))
+	t.bodies = append(t.bodies, values)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+// withCapture swaps the HTTP client and resets package settings after the test
+func withCapture(t *testing.T) *captureTransport {
+	t.Helper()
+	transport := &captureTransport{}
+	savedClient, savedAIP, savedConsent := clientFor, AnonymizeIP, RequireConsent
+	clientFor = func(context.Context) *http.Client { return &http.Client{Transport: transport} }
+	t.Cleanup(func() {
+		clientFor, AnonymizeIP, RequireConsent = savedClient, savedAIP, savedConsent
+	})
+	return transport
+}
+
+func TestSetEventAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		global bool
+		event  GAEvent
+		want   string
+	}{
+		{"disabled", false, GAEvent{IP: "192.0.2.1"}, ""},
+		{"package level", true, GAEvent{IP: "192.0.2.1"}, "1"},
+		{"per event", false, GAEvent{IP: "192.0.2.1", AnonymizeIP: true}, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCapture(t)
+			AnonymizeIP = tt.global
+			v := setEvent("pageview", tt.event)
+			if got := v.Get("aip"); got != tt.want {
+				t.Errorf("aip = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackGAPageSendsAnonymizedHit(t *testing.T) {
+	transport := withCapture(t)
+	AnonymizeIP = true
+
+	TrackGAPage(context.Background(), "UA-TEST-1", GAEvent{Guid: "visitor", DocumentPath: "/home"})
+
+	if len(transport.bodies) != 1 {
+		t.Fatalf("sent %d hits, want 1", len(transport.bodies))
+	}
+	hit := transport.bodies[0]
+	if hit.Get("aip") != "1" || hit.Get("t") != "pageview" || hit.Get("tid") != "UA-TEST-1" {
+		t.Errorf("unexpected hit: %v", hit)
+	}
+}
+
+func TestConsentGating(t *testing.T) {
+	tests := []struct {
+		name     string
+		require  bool
+		consent  Consent
+		wantHits int
+	}{
+		{"unknown consent allowed by default", false, ConsentUnknown, 1},
+		{"unknown consent suppressed when required", true, ConsentUnknown, 0},
+		{"granted", true, ConsentGranted, 1},
+		{"denied", false, ConsentDenied, 0},
+		{"denied when required", true, ConsentDenied, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := withCapture(t)
+			RequireConsent = tt.require
+
+			event := GAEvent{Guid: "visitor", Category: "signup", Action: "click", Consent: tt.consent}
+			TrackGAEvent(context.Background(), "UA-TEST-1", event)
+
+			if len(transport.bodies) != tt.wantHits {
+				t.Errorf("sent %d hits, want %d", len(transport.bodies), tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestEnsureClientID(t *testing.T) {
+	// First visit sets the cookie
+	req := httptest.NewRequest(http.MethodGet, "https://www.example.com/", nil)
+	rec := httptest.NewRecorder()
+	id := EnsureClientID(rec, req)
+	if id == "" {
+		t.Fatal("expected a client ID")
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("set %d cookies, want 1", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Name != ClientIDCookie || cookie.Value != id || !cookie.HttpOnly || !cookie.Secure || cookie.MaxAge < 365*24*3600 {
+		t.Errorf("unexpected cookie: %+v", cookie)
+	}
+
+	// The same request now carries the ID for GetEvent
+	if got := GetEvent(req).Guid; got != id {
+		t.Errorf("GetEvent Guid = %q, want %q", got, id)
+	}
+
+	// A returning visitor keeps the ID and no cookie is written
+	again := httptest.NewRequest(http.MethodGet, "https://www.example.com/next", nil)
+	again.AddCookie(&http.Cookie{Name: ClientIDCookie, Value: id})
+	rec = httptest.NewRecorder()
+	if got := EnsureClientID(rec, again); got != id {
+		t.Errorf("EnsureClientID = %q on return visit, want %q", got, id)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("expected no cookie for a returning visitor")
+	}
+}
+
+func TestWithUserID(t *testing.T) {
+	event := WithUserID(GAEvent{Guid: "visitor"}, "user-42")
+	if got := setEvent("pageview", event).Get("uid"); got != "user-42" {
+		t.Errorf("uid = %q, want user-42", got)
+	}
+
+	event = WithUserID(GAEvent{Guid: "visitor"}, "jane@example.com")
+	if got := setEvent("pageview", event).Get("uid"); got != "" {
+		t.Errorf("uid = %q, want email addresses dropped", got)
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ga
+
+// Without a queue, hits that fail to reach Google Analytics are logged and
+// dropped. EnablePersistentQueue stores them in a HitStore instead, and a
+// background flusher retries them with exponential backoff. Delivered hits
+// carry the Measurement Protocol queue time (qt) so GA records them at the
+// time they happened; hits older than MaxHitAge are dropped since GA may
+// discard them anyway.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+const (
+	// MaxHitAge is the oldest hit the queue delivers. GA may not process
+	// hits with a queue time above four hours.
+	MaxHitAge = 4 * time.Hour
+
+	// MaxHitAttempts is the number of delivery attempts before a hit is
+	// dropped
+	MaxHitAttempts = 10
+)
+
+var (
+	// QueueFlushInterval is how often the background flusher retries
+	// queued hits
+	QueueFlushInterval = 30 * time.Second
+
+	// queueRetryBase and queueRetryMax bound the backoff between attempts
+	queueRetryBase = 30 * time.Second
+	queueRetryMax  = 30 * time.Minute
+
+	// queueClient sends queued hits. The flusher runs outside any request,
+	// so it cannot use the request-scoped client from clientFor.
+	queueClient = &http.Client{Timeout: 30 * time.Second}
+
+	// queueNow is replaced by tests to simulate the passage of time
+	queueNow = time.Now
+)
+
+// QueuedHit is a hit waiting to be delivered
+type QueuedHit struct {
+	ID          string    `json:"id"`
+	Payload     string    `json:"payload"` // URL-encoded Measurement Protocol parameters
+	QueuedAt    time.Time `json:"queued_at"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// HitStore persists queued hits. Implementations must be safe for
+// concurrent use.
+type HitStore interface {
+	// Put inserts or replaces the hit with the same ID
+	Put(hit QueuedHit) error
+
+	// List returns all stored hits
+	List() ([]QueuedHit, error)
+
+	// Delete removes a hit; deleting a missing hit is not an error
+	Delete(id string) error
+}
+
+// QueueStats reports the activity of the persistent queue since it was
+// enabled
+type QueueStats struct {
+	Queued  int64 // Hits stored after a failed delivery
+	Sent    int64 // Queued hits delivered on retry
+	Dropped int64 // Queued hits given up on (too old, too many attempts or rejected)
+	Pending int   // Hits currently in the store
+}
+
+// hitQueue is the state behind EnablePersistentQueue
+type hitQueue struct {
+	store HitStore
+	stop  chan struct{}
+	done  chan struct{}
+	flush sync.Mutex // serializes flushes
+
+	queued, sent, dropped atomic.Int64
+}
+
+var (
+	queueMu sync.Mutex
+	queue   *hitQueue
+)
+
+// EnablePersistentQueue stores hits that fail to send in store and starts
+// a background flusher that retries them every QueueFlushInterval. Hits
+// left in the store by a previous process are retried on the first flush.
+// Calling it again replaces the previous queue.
+//
+// Example:
+//
+//	store, err := ga.NewFileHitStore("/var/lib/app/ga-queue")
+//	if err != nil { ... }
+//	ga.EnablePersistentQueue(store)
+//	defer ga.DisablePersistentQueue()
+func EnablePersistentQueue(store HitStore) {
+	q := &hitQueue{store: store, stop: make(chan struct{}), done: make(chan struct{})}
+
+	queueMu.Lock()
+	previous := queue
+	queue = q
+	queueMu.Unlock()
+	if previous != nil {
+		previous.close()
+	}
+
+	go q.run()
+}
+
+// DisablePersistentQueue stops the flusher. Hits still in the store are
+// kept for the next EnablePersistentQueue.
+func DisablePersistentQueue() {
+	queueMu.Lock()
+	q := queue
+	queue = nil
+	queueMu.Unlock()
+	if q != nil {
+		q.close()
+	}
+}
+
+// FlushQueue retries the queued hits that are due now, for example from a
+// shutdown hook or a cron handler. It does nothing when the queue is
+// disabled.
+func FlushQueue(ctx context.Context) error {
+	q := currentQueue()
+	if q == nil {
+		return nil
+	}
+	return q.flushDue(ctx)
+}
+
+// GetQueueStats returns the queue counters. All values are zero when the
+// queue is disabled.
+func GetQueueStats() QueueStats {
+	q := currentQueue()
+	if q == nil {
+		return QueueStats{}
+	}
+	stats := QueueStats{Queued: q.queued.Load(), Sent: q.sent.Load(), Dropped: q.dropped.Load()}
+	if hits, err := q.store.List(); err == nil {
+		stats.Pending = len(hits)
+	}
+	return stats
+}
+
+func currentQueue() *hitQueue {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	return queue
+}
+
+// run flushes immediately and then every QueueFlushInterval until stopped
+func (q *hitQueue) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(QueueFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := q.flushDue(context.Background()); err != nil {
+			common.Warn("GA: Failed to flush queued hits: %v", err)
+		}
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *hitQueue) close() {
+	close(q.stop)
+	<-q.done
+}
+
+// enqueue stores a hit whose first delivery failed
+func (q *hitQueue) enqueue(payload string) error {
+	id, err := common.GenerateSecureID()
+	if err != nil {
+		return err
+	}
+	now := queueNow()
+	hit := QueuedHit{ID: id, Payload: payload, QueuedAt: now, Attempts: 1, NextAttempt: now.Add(hitBackoff(1))}
+	if err := q.store.Put(hit); err != nil {
+		return err
+	}
+	q.queued.Add(1)
+	return nil
+}
+
+// flushDue delivers the hits whose NextAttempt has passed. It stops at the
+// first transient failure, since GA is most likely still unreachable.
+func (q *hitQueue) flushDue(ctx context.Context) error {
+	q.flush.Lock()
+	defer q.flush.Unlock()
+
+	hits, err := q.store.List()
+	if err != nil {
+		return fmt.Errorf("failed to list queued hits: %v", err)
+	}
+
+	for _, hit := range hits {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		now := queueNow()
+		if now.Sub(hit.QueuedAt) > MaxHitAge {
+			q.drop(hit, "too old")
+			continue
+		}
+		if now.Before(hit.NextAttempt) {
+			continue
+		}
+
+		retry, err := deliverHit(ctx, queueClient, withQueueTime(hit.Payload, now.Sub(hit.QueuedAt)))
+		switch {
+		case err == nil:
+			if err := q.store.Delete(hit.ID); err != nil {
+				return fmt.Errorf("failed to delete delivered hit: %v", err)
+			}
+			q.sent.Add(1)
+		case !retry:
+			q.drop(hit, err.Error())
+		default:
+			hit.Attempts++
+			if hit.Attempts >= MaxHitAttempts {
+				q.drop(hit, err.Error())
+				continue
+			}
+			hit.NextAttempt = now.Add(hitBackoff(hit.Attempts))
+			if err := q.store.Put(hit); err != nil {
+				return fmt.Errorf("failed to update queued hit: %v", err)
+			}
+			common.Debug("GA: Queued hit delivery failed (attempt %d): %v", hit.Attempts, err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// drop removes a hit that will not be delivered
+func (q *hitQueue) drop(hit QueuedHit, reason string) {
+	common.Warn("GA: Dropping queued hit after %d attempts: %s", hit.Attempts, reason)
+	if err := q.store.Delete(hit.ID); err != nil {
+		common.Error("GA: Failed to delete dropped hit: %v", err)
+	}
+	q.dropped.Add(1)
+}
+
+// hitBackoff returns queueRetryBase doubled for every attempt after the
+// first, capped at queueRetryMax
+func hitBackoff(attempts int) time.Duration {
+	delay := queueRetryBase
+	for i := 1; i < attempts && delay < queueRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > queueRetryMax {
+		delay = queueRetryMax
+	}
+	return delay
+}
+
+// withQueueTime adds the qt parameter (milliseconds since the hit happened)
+func withQueueTime(payload string, age time.Duration) string {
+	v, err := url.ParseQuery(payload)
+	if err != nil {
+		return payload
+	}
+	v.Set("qt", strconv.FormatInt(age.Milliseconds(), 10))
+	return v.Encode()
+}
+
+// deliverHit posts payload to the collection endpoint. retry reports
+// whether a failure is transient (network errors and 5xx responses).
+func deliverHit(ctx context.Context, client *http.Client, payload string) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL, bytes.NewBufferString(payload))
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	common.Debug("GA status code %v", resp.StatusCode)
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return true, fmt.Errorf("GA returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 400 {
+		return false, fmt.Errorf("GA returned status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// MemoryHitStore keeps queued hits in memory. Hits do not survive a
+// restart; use it in tests or where losing hits on deploy is acceptable.
+type MemoryHitStore struct {
+	mu   sync.Mutex
+	hits map[string]QueuedHit
+}
+
+// NewMemoryHitStore creates an empty in-memory store
+func NewMemoryHitStore() *MemoryHitStore {
+	return &MemoryHitStore{hits: make(map[string]QueuedHit)}
+}
+
+// Put stores the hit
+func (s *MemoryHitStore) Put(hit QueuedHit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits[hit.ID] = hit
+	return nil
+}
+
+// List returns the stored hits, oldest first
+func (s *MemoryHitStore) List() ([]QueuedHit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hits := make([]QueuedHit, 0, len(s.hits))
+	for _, hit := range s.hits {
+		hits = append(hits, hit)
+	}
+	sortHits(hits)
+	return hits, nil
+}
+
+// Delete removes the hit
+func (s *MemoryHitStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hits, id)
+	return nil
+}
+
+// hitIDPattern restricts IDs so they are safe to use as file names
+var hitIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FileHitStore keeps each queued hit as a JSON file in a directory, so
+// hits survive process restarts
+type FileHitStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileHitStore creates dir if needed and returns a store backed by it
+func NewFileHitStore(dir string) (*FileHitStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create GA queue directory: %v", err)
+	}
+	return &FileHitStore{dir: dir}, nil
+}
+
+// Put writes the hit atomically
+func (s *FileHitStore) Put(hit QueuedHit) error {
+	path, err := s.path(hit.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(hit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hit: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write hit: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write hit: %v", err)
+	}
+	return nil
+}
+
+// List reads all stored hits, oldest first. Unreadable files are skipped.
+func (s *FileHitStore) List() ([]QueuedHit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GA queue directory: %v", err)
+	}
+
+	var hits []QueuedHit
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			common.Warn("GA: Skipping unreadable queued hit %s: %v", entry.Name(), err)
+			continue
+		}
+		var hit QueuedHit
+		if err := json.Unmarshal(data, &hit); err != nil {
+			common.Warn("GA: Skipping malformed queued hit %s: %v", entry.Name(), err)
+			continue
+		}
+		hits = append(hits, hit)
+	}
+	sortHits(hits)
+	return hits, nil
+}
+
+// Delete removes the hit's file
+func (s *FileHitStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete hit: %v", err)
+	}
+	return nil
+}
+
+func (s *FileHitStore) path(id string) (string, error) {
+	if !hitIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid hit ID %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// sortHits orders hits by QueuedAt so the oldest are retried first
+func sortHits(hits []QueuedHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].QueuedAt.Before(hits[j].QueuedAt)
+	})
+}
@@ -0,0 +1,260 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ga
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails with 503 while down and records delivered hits otherwise
+type flakyTransport struct {
+	mu     sync.Mutex
+	down   bool
+	status int // status returned while up, default 200
+	hits   []url.Values
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status := t.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if t.down {
+		status = http.StatusServiceUnavailable
+	} else {
+		body, _ := io.ReadAll(req.Body)
+		values, _ := url.ParseQuery(string(body))
+		t.hits = append(t.hits, values)
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func (t *flakyTransport) setDown(down bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.down = down
+}
+
+func (t *flakyTransport) delivered() []url.Values {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]url.Values(nil), t.hits...)
+}
+
+// fakeClock is a settable clock safe to read from the flusher goroutine
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// withFlakyGA routes all GA traffic through a flakyTransport, freezes the
+// queue clock and disables the queue after the test
+func withFlakyGA(t *testing.T) (*flakyTransport, *fakeClock) {
+	t.Helper()
+	transport := &flakyTransport{}
+	clock := &fakeClock{now: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	savedClient, savedQueueClient, savedNow := clientFor, queueClient, queueNow
+	savedInterval, savedBase := QueueFlushInterval, queueRetryBase
+	clientFor = func(context.Context) *http.Client { return &http.Client{Transport: transport} }
+	queueClient = &http.Client{Transport: transport}
+	queueNow = clock.Now
+	QueueFlushInterval = time.Hour
+	t.Cleanup(func() {
+		DisablePersistentQueue()
+		clientFor, queueClient, queueNow = savedClient, savedQueueClient, savedNow
+		QueueFlushInterval, queueRetryBase = savedInterval, savedBase
+	})
+	return transport, clock
+}
+
+func TestPersistentQueueDowntimeAndRecovery(t *testing.T) {
+	transport, clock := withFlakyGA(t)
+	EnablePersistentQueue(NewMemoryHitStore())
+	ctx := context.Background()
+
+	transport.setDown(true)
+	TrackGAEvent(ctx, "UA-TEST-1", GAEvent{Guid: "visitor", Category: "signup", Action: "click"})
+
+	if stats := GetQueueStats(); stats.Queued != 1 || stats.Pending != 1 {
+		t.Fatalf("stats = %+v, want 1 queued and pending", stats)
+	}
+
+	// Not due yet
+	if err := FlushQueue(ctx); err != nil {
+		t.Fatalf("FlushQueue failed: %v", err)
+	}
+	// Due, but GA is still down
+	clock.Advance(31 * time.Second)
+	if err := FlushQueue(ctx); err != nil {
+		t.Fatalf("FlushQueue failed: %v", err)
+	}
+	if len(transport.delivered()) != 0 || GetQueueStats().Pending != 1 {
+		t.Fatalf("expected the hit to stay queued while GA is down")
+	}
+
+	// GA recovers; the backoff has doubled to a minute
+	transport.setDown(false)
+	clock.Advance(30 * time.Second)
+	if err := FlushQueue(ctx); err != nil {
+		t.Fatalf("FlushQueue failed: %v", err)
+	}
+	if len(transport.delivered()) != 0 {
+		t.Fatal("expected the backoff to delay the retry")
+	}
+	clock.Advance(time.Minute)
+	if err := FlushQueue(ctx); err != nil {
+		t.Fatalf("FlushQueue failed: %v", err)
+	}
+
+	hits := transport.delivered()
+	if len(hits) != 1 {
+		t.Fatalf("delivered %d hits, want 1", len(hits))
+	}
+	if hits[0].Get("ea") != "click" || hits[0].Get("tid") != "UA-TEST-1" {
+		t.Errorf("unexpected hit: %v", hits[0])
+	}
+	if qt, _ := strconv.Atoi(hits[0].Get("qt")); qt != 121000 {
+		t.Errorf("qt = %q, want 121000", hits[0].Get("qt"))
+	}
+	if stats := GetQueueStats(); stats.Sent != 1 || stats.Pending != 0 || stats.Dropped != 0 {
+		t.Errorf("stats = %+v, want 1 sent and nothing pending", stats)
+	}
+}
+
+func TestPersistentQueueBackgroundFlusher(t *testing.T) {
+	transport, _ := withFlakyGA(t)
+	queueNow = time.Now
+	QueueFlushInterval = 5 * time.Millisecond
+	queueRetryBase = time.Millisecond
+	EnablePersistentQueue(NewMemoryHitStore())
+
+	transport.setDown(true)
+	TrackGAPage(context.Background(), "UA-TEST-1", GAEvent{Guid: "visitor", DocumentPath: "/home"})
+	transport.setDown(false)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for GetQueueStats().Sent == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued hit was not delivered, stats = %+v", GetQueueStats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hits := transport.delivered(); len(hits) != 1 || hits[0].Get("dp") != "/home" {
+		t.Errorf("delivered = %v, want the /home pageview", hits)
+	}
+}
+
+func TestPersistentQueueSurvivesRestart(t *testing.T) {
+	transport, clock := withFlakyGA(t)
+	dir := t.TempDir()
+
+	store, err := NewFileHitStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileHitStore failed: %v", err)
+	}
+	EnablePersistentQueue(store)
+	transport.setDown(true)
+	TrackGAEvent(context.Background(), "UA-TEST-1", GAEvent{Guid: "visitor", Category: "order", Action: "paid"})
+	DisablePersistentQueue()
+
+	// A new process opens the same directory
+	restarted, err := NewFileHitStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileHitStore failed: %v", err)
+	}
+	if hits, _ := restarted.List(); len(hits) != 1 {
+		t.Fatalf("restarted store has %d hits, want 1", len(hits))
+	}
+
+	transport.setDown(false)
+	clock.Advance(time.Minute)
+	EnablePersistentQueue(restarted)
+	if err := FlushQueue(context.Background()); err != nil {
+		t.Fatalf("FlushQueue failed: %v", err)
+	}
+	if hits := transport.delivered(); len(hits) != 1 || hits[0].Get("ea") != "paid" {
+		t.Errorf("delivered = %v, want the queued event", hits)
+	}
+	if hits, _ := restarted.List(); len(hits) != 0 {
+		t.Errorf("store still has %d hits after delivery", len(hits))
+	}
+}
+
+func TestPersistentQueueDrops(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		status  int
+	}{
+		{name: "Too old", advance: MaxHitAge + time.Minute},
+		{name: "Rejected by GA", advance: time.Minute, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, clock := withFlakyGA(t)
+			transport.status = tt.status
+			EnablePersistentQueue(NewMemoryHitStore())
+
+			transport.setDown(true)
+			TrackGAEvent(context.Background(), "UA-TEST-1", GAEvent{Guid: "visitor", Action: "click"})
+			transport.setDown(false)
+
+			clock.Advance(tt.advance)
+			if err := FlushQueue(context.Background()); err != nil {
+				t.Fatalf("FlushQueue failed: %v", err)
+			}
+			if stats := GetQueueStats(); stats.Dropped != 1 || stats.Sent != 0 || stats.Pending != 0 {
+				t.Errorf("stats = %+v, want 1 dropped", stats)
+			}
+		})
+	}
+}
+
+func TestFileHitStoreRejectsUnsafeIDs(t *testing.T) {
+	store, err := NewFileHitStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileHitStore failed: %v", err)
+	}
+	if err := store.Put(QueuedHit{ID: "../escape"}); err == nil {
+		t.Error("expected an error for an ID containing a path")
+	}
+	if err := store.Delete("../escape"); err == nil {
+		t.Error("expected an error for an ID containing a path")
+	}
+}
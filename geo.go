@@ -0,0 +1,136 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// Geographic information about a request is provided by the hosting edge
+// rather than looked up locally. App Engine and Cloudflare both attach the
+// visitor's approximate location as request headers; GeoFromRequest reads
+// whichever set is present so handlers do not depend on a specific platform.
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Geo holds the approximate location of the client that sent a request.
+// Fields are empty (or zero) when the platform did not provide them.
+type Geo struct {
+	Country string  // ISO 3166-1 alpha-2 country code, e.g. "US"
+	Region  string  // Region or state code, e.g. "ca"
+	City    string  // City name as reported by the platform
+	Lat     float64 // Latitude of the city
+	Lon     float64 // Longitude of the city
+}
+
+// HasLocation reports whether latitude and longitude were provided
+func (g Geo) HasLocation() bool {
+	return g.Lat != 0 || g.Lon != 0
+}
+
+// IsUnknownCountry reports whether the platform could not determine the
+// country. App Engine uses "ZZ" and Cloudflare uses "XX" for such requests,
+// which usually indicates bot traffic. An empty country (no geo headers at
+// all, as in local development) is not considered unknown.
+func (g Geo) IsUnknownCountry() bool {
+	return g.Country == "ZZ" || g.Country == "XX"
+}
+
+// GeoHeaders names the request headers a platform uses for geo data.
+// LatLong is a single "lat,lon" header; Lat and Lon are separate headers.
+// Empty names are skipped.
+type GeoHeaders struct {
+	Country string
+	Region  string
+	City    string
+	LatLong string
+	Lat     string
+	Lon     string
+}
+
+// AppEngineGeoHeaders are the headers set by Google App Engine
+var AppEngineGeoHeaders = GeoHeaders{
+	Country: "X-AppEngine-Country",
+	Region:  "X-AppEngine-Region",
+	City:    "X-AppEngine-City",
+	LatLong: "X-AppEngine-CityLatLong",
+}
+
+// CloudflareGeoHeaders are the headers set by Cloudflare. Only CF-IPCountry
+// is sent by default; the others require the "Add visitor location headers"
+// managed transform.
+var CloudflareGeoHeaders = GeoHeaders{
+	Country: "CF-IPCountry",
+	Region:  "CF-Region-Code",
+	City:    "CF-IPCity",
+	Lat:     "CF-IPLatitude",
+	Lon:     "CF-IPLongitude",
+}
+
+// GeoHeaderSources lists the header sets GeoFromRequest tries, in order.
+// The first set whose country header is present wins. Applications behind
+// another proxy can append their own set at startup:
+//
+//	common.GeoHeaderSources = append(common.GeoHeaderSources, common.GeoHeaders{
+//	    Country: "X-Client-Country",
+//	})
+var GeoHeaderSources = []GeoHeaders{AppEngineGeoHeaders, CloudflareGeoHeaders}
+
+// GeoFromRequest returns the client location reported by the hosting
+// platform. It returns a zero Geo when no known geo headers are present.
+func GeoFromRequest(r *http.Request) Geo {
+	if r == nil {
+		return Geo{}
+	}
+	for _, h := range GeoHeaderSources {
+		if h.Country == "" || r.Header.Get(h.Country) == "" {
+			continue
+		}
+		return geoFromHeaders(r.Header, h)
+	}
+	return Geo{}
+}
+
+// geoFromHeaders parses a single header set
+func geoFromHeaders(header http.Header, h GeoHeaders) Geo {
+	get := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return strings.TrimSpace(header.Get(name))
+	}
+
+	g := Geo{
+		Country: strings.ToUpper(get(h.Country)),
+		Region:  get(h.Region),
+		City:    get(h.City),
+	}
+
+	if latlon := strings.Split(get(h.LatLong), ","); len(latlon) == 2 {
+		g.Lat, g.Lon = parseCoordinate(latlon[0]), parseCoordinate(latlon[1])
+	} else {
+		g.Lat, g.Lon = parseCoordinate(get(h.Lat)), parseCoordinate(get(h.Lon))
+	}
+	return g
+}
+
+// parseCoordinate returns 0 for empty or malformed values
+func parseCoordinate(s string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
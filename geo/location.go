@@ -3,7 +3,10 @@ package geo
 
 import (
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/patdeg/common"
 )
 
 // Location represents geographic information from AppEngine headers
@@ -16,14 +19,20 @@ type Location struct {
 	DetectedFrom string    `datastore:"detected_from"`  // "session" or "signup"
 }
 
-// ExtractFromRequest extracts geo-location from AppEngine headers
-// AppEngine automatically adds these headers based on the client's IP address
+// ExtractFromRequest extracts geo-location from the platform headers read by
+// common.GeoFromRequest (App Engine, Cloudflare, ...). The platform adds these
+// headers based on the client's IP address
 func ExtractFromRequest(r *http.Request) *Location {
+	g := common.GeoFromRequest(r)
+	latLong := ""
+	if g.HasLocation() {
+		latLong = strconv.FormatFloat(g.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(g.Lon, 'f', -1, 64)
+	}
 	return &Location{
-		Country:      r.Header.Get("X-Appengine-Country"),
-		Region:       r.Header.Get("X-Appengine-Region"),
-		City:         r.Header.Get("X-Appengine-City"),
-		CityLatLong:  r.Header.Get("X-Appengine-CityLatLong"),
+		Country:      g.Country,
+		Region:       g.Region,
+		City:         g.City,
+		CityLatLong:  latLong,
 		DetectedAt:   time.Now().UTC(),
 		DetectedFrom: "", // Will be set by caller ("session" or "signup")
 	}
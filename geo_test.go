@@ -0,0 +1,126 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGeoFromRequest checks parsing of App Engine and Cloudflare header shapes.
+func TestGeoFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    Geo
+	}{
+		{
+			name: "App Engine",
+			headers: map[string]string{
+				"X-AppEngine-Country":     "US",
+				"X-AppEngine-Region":      "ca",
+				"X-AppEngine-City":        "mountain view",
+				"X-AppEngine-CityLatLong": "37.386051,-122.083851",
+			},
+			want: Geo{Country: "US", Region: "ca", City: "mountain view", Lat: 37.386051, Lon: -122.083851},
+		},
+		{
+			name: "App Engine without coordinates",
+			headers: map[string]string{
+				"X-AppEngine-Country": "FR",
+				"X-AppEngine-City":    "paris",
+			},
+			want: Geo{Country: "FR", City: "paris"},
+		},
+		{
+			name: "Cloudflare country only",
+			headers: map[string]string{
+				"CF-IPCountry": "de",
+			},
+			want: Geo{Country: "DE"},
+		},
+		{
+			name: "Cloudflare visitor location headers",
+			headers: map[string]string{
+				"CF-IPCountry":   "GB",
+				"CF-Region-Code": "ENG",
+				"CF-IPCity":      "London",
+				"CF-IPLatitude":  "51.50740",
+				"CF-IPLongitude": "-0.12780",
+			},
+			want: Geo{Country: "GB", Region: "ENG", City: "London", Lat: 51.5074, Lon: -0.1278},
+		},
+		{
+			name: "App Engine takes precedence",
+			headers: map[string]string{
+				"X-AppEngine-Country": "US",
+				"CF-IPCountry":        "CA",
+			},
+			want: Geo{Country: "US"},
+		},
+		{
+			name: "Malformed coordinates",
+			headers: map[string]string{
+				"X-AppEngine-Country":     "US",
+				"X-AppEngine-CityLatLong": "north,west",
+			},
+			want: Geo{Country: "US"},
+		},
+		{
+			name:    "No geo headers",
+			headers: map[string]string{},
+			want:    Geo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := GeoFromRequest(req); got != tt.want {
+				t.Errorf("GeoFromRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGeoFromRequestCustomSource checks that applications can register
+// headers for other proxies.
+func TestGeoFromRequestCustomSource(t *testing.T) {
+	saved := GeoHeaderSources
+	defer func() { GeoHeaderSources = saved }()
+	GeoHeaderSources = append(GeoHeaderSources, GeoHeaders{Country: "X-Client-Country", City: "X-Client-City"})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Client-Country", "JP")
+	req.Header.Set("X-Client-City", "Tokyo")
+
+	want := Geo{Country: "JP", City: "Tokyo"}
+	if got := GeoFromRequest(req); got != want {
+		t.Errorf("GeoFromRequest() = %+v, want %+v", got, want)
+	}
+}
+
+// TestGeoIsUnknownCountry checks the platform placeholders for unknown origin.
+func TestGeoIsUnknownCountry(t *testing.T) {
+	for country, want := range map[string]bool{"ZZ": true, "XX": true, "US": false, "": false} {
+		if got := (Geo{Country: country}).IsUnknownCountry(); got != want {
+			t.Errorf("IsUnknownCountry(%q) = %v, want %v", country, got, want)
+		}
+	}
+}
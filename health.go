@@ -0,0 +1,237 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// HealthChecker replaces per-service /healthz handlers with named checks
+// run concurrently under timeouts. Liveness answers "should this instance
+// be restarted?" and should only include cheap in-process checks;
+// readiness answers "can it take traffic?" and also covers dependencies
+// such as databases.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultHealthCheckTimeout bounds a check that sets no Timeout
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthGroup selects the checks run by HealthChecker.Check
+type HealthGroup string
+
+const (
+	// HealthLiveness runs only checks with Liveness set
+	HealthLiveness HealthGroup = "liveness"
+
+	// HealthReadiness runs every check
+	HealthReadiness HealthGroup = "readiness"
+)
+
+// Health statuses reported by HealthChecker
+const (
+	HealthPass = "pass"
+	HealthFail = "fail"
+)
+
+// HealthCheck is a named check registered with a HealthChecker
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+
+	// Timeout bounds the check. Defaults to the checker's Timeout.
+	Timeout time.Duration
+
+	// Liveness includes the check in the liveness group as well as
+	// readiness
+	Liveness bool
+}
+
+// HealthCheckResult is the outcome of one check. Error is set by Check
+// but never served by Handler, since checker errors can name internal
+// hosts and connection strings.
+type HealthCheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// HealthReport is the JSON body served by HealthChecker handlers
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+// HealthChecker runs registered checks and serves their results:
+//
+//	health := common.NewHealthChecker()
+//	health.Register(common.HealthCheck{Name: "db", Check: db.PingContext})
+//	health.Register(common.HealthCheck{Name: "goroutines", Liveness: true, Check: checkGoroutines})
+//	mux.Handle("/healthz", health.Handler(common.HealthLiveness))
+//	mux.Handle("/readyz", health.Handler(common.HealthReadiness))
+//
+// It is safe for concurrent use.
+type HealthChecker struct {
+	// Timeout is the default per-check timeout
+	Timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]HealthCheck
+}
+
+// NewHealthChecker creates a checker with no checks and
+// DefaultHealthCheckTimeout
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		Timeout: DefaultHealthCheckTimeout,
+		checks:  make(map[string]HealthCheck),
+	}
+}
+
+// Register adds a check. Names must be unique and non-empty.
+func (h *HealthChecker) Register(check HealthCheck) error {
+	if check.Name == "" || check.Check == nil {
+		return fmt.Errorf("health check requires a name and a function")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.checks[check.Name]; exists {
+		return fmt.Errorf("health check already registered: %s", check.Name)
+	}
+	h.checks[check.Name] = check
+	return nil
+}
+
+// Check runs the checks in group concurrently and reports the overall
+// status: HealthPass when every check passed, HealthFail otherwise. A check
+// that panics or outlives its timeout fails.
+func (h *HealthChecker) Check(ctx context.Context, group HealthGroup) HealthReport {
+	h.mu.RLock()
+	var checks []HealthCheck
+	for _, check := range h.checks {
+		if group != HealthLiveness || check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+	timeout := h.Timeout
+	h.mu.RUnlock()
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+
+	results := make([]HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check, timeout)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthPass, Checks: make(map[string]HealthCheckResult, len(checks))}
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != HealthPass {
+			report.Status = HealthFail
+		}
+	}
+	return report
+}
+
+// Handler serves the report for group as JSON with 200 when it passes and
+// 503 when it fails. Health endpoints are usually unauthenticated, so each
+// check only reports its status; the errors of failed checks are logged
+// instead. Responses are never cached.
+func (h *HealthChecker) Handler(group HealthGroup) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context(), group)
+
+		status := http.StatusOK
+		if report.Status != HealthPass {
+			status = http.StatusServiceUnavailable
+			for _, name := range failedChecks(report) {
+				Warn("[HEALTH] %s check %s failed: %s", group, name, report.Checks[name].Error)
+			}
+		}
+		for name, result := range report.Checks {
+			result.Error = ""
+			report.Checks[name] = result
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if err := WriteJSONWithStatus(w, status, report); err != nil {
+			Error("[HEALTH] Failed to write health report: %v", err)
+		}
+	})
+}
+
+// runHealthCheck runs check under its timeout. The check runs in its own
+// goroutine so one that ignores its context still fails on time.
+func runHealthCheck(ctx context.Context, check HealthCheck, timeout time.Duration) HealthCheckResult {
+	if check.Timeout > 0 {
+		timeout = check.Timeout
+	}
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+	}
+
+	result := HealthCheckResult{Status: HealthPass, Duration: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = HealthFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// failedChecks lists the names of failed checks, sorted
+func failedChecks(report HealthReport) []string {
+	var names []string
+	for name, result := range report.Checks {
+		if result.Status != HealthPass {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
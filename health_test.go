@@ -0,0 +1,165 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHealthChecker(t *testing.T, checks ...HealthCheck) *HealthChecker {
+	t.Helper()
+	h := NewHealthChecker()
+	for _, check := range checks {
+		if err := h.Register(check); err != nil {
+			t.Fatalf("Register(%s) failed: %v", check.Name, err)
+		}
+	}
+	return h
+}
+
+func passingCheck(ctx context.Context) error { return nil }
+
+func TestHealthCheckerHandler(t *testing.T) {
+	failing := func(ctx context.Context) error {
+		return errors.New("dial tcp db.internal.example.com:5432: connection refused")
+	}
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		group      HealthGroup
+		wantStatus int
+		wantReport HealthReport
+	}{
+		{
+			name:       "All passing",
+			checks:     []HealthCheck{{Name: "db", Check: passingCheck}, {Name: "cache", Check: passingCheck}},
+			group:      HealthReadiness,
+			wantStatus: http.StatusOK,
+			wantReport: HealthReport{Status: HealthPass, Checks: map[string]HealthCheckResult{
+				"db": {Status: HealthPass}, "cache": {Status: HealthPass},
+			}},
+		},
+		{
+			name:       "One failing",
+			checks:     []HealthCheck{{Name: "db", Check: failing}, {Name: "cache", Check: passingCheck}},
+			group:      HealthReadiness,
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: HealthReport{Status: HealthFail, Checks: map[string]HealthCheckResult{
+				"db": {Status: HealthFail}, "cache": {Status: HealthPass},
+			}},
+		},
+		{
+			name:       "Liveness skips dependencies",
+			checks:     []HealthCheck{{Name: "db", Check: failing}, {Name: "process", Check: passingCheck, Liveness: true}},
+			group:      HealthLiveness,
+			wantStatus: http.StatusOK,
+			wantReport: HealthReport{Status: HealthPass, Checks: map[string]HealthCheckResult{
+				"process": {Status: HealthPass},
+			}},
+		},
+		{
+			name:       "No checks",
+			group:      HealthReadiness,
+			wantStatus: http.StatusOK,
+			wantReport: HealthReport{Status: HealthPass, Checks: map[string]HealthCheckResult{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHealthChecker(t, tt.checks...)
+			logs := captureLogs(t)
+			rec := httptest.NewRecorder()
+			h.Handler(tt.group).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			// Check errors are logged, never served
+			if strings.Contains(rec.Body.String(), "db.internal") {
+				t.Errorf("Body leaks the check error: %s", rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK && !strings.Contains(logs.String(), "db failed: dial tcp db.internal.example.com:5432") {
+				t.Errorf("Logs = %q, want the db check error", logs.String())
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+
+			var got HealthReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Invalid JSON %q: %v", rec.Body.String(), err)
+			}
+			if got.Status != tt.wantReport.Status || len(got.Checks) != len(tt.wantReport.Checks) {
+				t.Fatalf("Report = %+v, want %+v", got, tt.wantReport)
+			}
+			for name, want := range tt.wantReport.Checks {
+				if res := got.Checks[name]; res.Status != want.Status || res.Error != want.Error {
+					t.Errorf("Check %s = %+v, want %+v", name, res, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthCheckerTimeoutAndPanic(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	h := newTestHealthChecker(t,
+		HealthCheck{Name: "slow", Timeout: 20 * time.Millisecond, Check: func(ctx context.Context) error {
+			<-block // ignores its context
+			return nil
+		}},
+		HealthCheck{Name: "panics", Check: func(ctx context.Context) error { panic("boom") }},
+	)
+
+	start := time.Now()
+	report := h.Check(context.Background(), HealthReadiness)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Check took %v, expected the timeout to apply", elapsed)
+	}
+	if report.Status != HealthFail {
+		t.Errorf("Status = %s, want %s", report.Status, HealthFail)
+	}
+	if got := report.Checks["slow"].Error; got != "timed out after 20ms" {
+		t.Errorf("slow error = %q", got)
+	}
+	if got := report.Checks["panics"].Error; got != "panic: boom" {
+		t.Errorf("panics error = %q", got)
+	}
+}
+
+func TestHealthCheckerRegister(t *testing.T) {
+	h := NewHealthChecker()
+	if err := h.Register(HealthCheck{Name: "db", Check: passingCheck}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := h.Register(HealthCheck{Name: "db", Check: passingCheck}); err == nil {
+		t.Error("Expected an error for a duplicate name")
+	}
+	if err := h.Register(HealthCheck{Name: "nil"}); err == nil {
+		t.Error("Expected an error for a missing function")
+	}
+}
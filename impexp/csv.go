@@ -0,0 +1,98 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvRowWriter is the subset of *csv.Writer used by the exporters
+type csvRowWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// newCSVWriter returns a CSV writer honoring Options.Delimiter, UseCRLF and
+// AlwaysQuote. Every CSV export goes through it so all code paths produce
+// the same dialect.
+func newCSVWriter(w io.Writer, opts *Options) csvRowWriter {
+	comma := ','
+	if opts.Delimiter != 0 {
+		comma = opts.Delimiter
+	}
+
+	if opts.AlwaysQuote {
+		return &quotingCSVWriter{w: bufio.NewWriter(w), comma: comma, useCRLF: opts.UseCRLF}
+	}
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = comma
+	csvWriter.UseCRLF = opts.UseCRLF
+	return csvWriter
+}
+
+// errInvalidDelim mirrors the error encoding/csv returns for bad delimiters
+var errInvalidDelim = errors.New("csv: invalid field or comment delimiter")
+
+// quotingCSVWriter writes every field in double quotes, which
+// encoding/csv cannot do. Like csv.Writer with UseCRLF, it also turns
+// newlines inside fields into \r\n.
+type quotingCSVWriter struct {
+	w       *bufio.Writer
+	comma   rune
+	useCRLF bool
+	err     error
+}
+
+func (q *quotingCSVWriter) Write(record []string) error {
+	if q.comma == '"' || q.comma == '\r' || q.comma == '\n' || q.comma == utf8.RuneError || !utf8.ValidRune(q.comma) {
+		return errInvalidDelim
+	}
+
+	for i, field := range record {
+		if i > 0 {
+			q.w.WriteRune(q.comma)
+		}
+		field = strings.ReplaceAll(field, `"`, `""`)
+		if q.useCRLF {
+			field = strings.ReplaceAll(strings.ReplaceAll(field, "\r\n", "\n"), "\n", "\r\n")
+		}
+		q.w.WriteByte('"')
+		q.w.WriteString(field)
+		q.w.WriteByte('"')
+	}
+
+	var err error
+	if q.useCRLF {
+		_, err = q.w.WriteString("\r\n")
+	} else {
+		err = q.w.WriteByte('\n')
+	}
+	return err
+}
+
+func (q *quotingCSVWriter) Flush() {
+	q.err = q.w.Flush()
+}
+
+func (q *quotingCSVWriter) Error() error {
+	return q.err
+}
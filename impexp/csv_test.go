@@ -0,0 +1,103 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+type csvRecord struct {
+	Name string
+	Note string
+}
+
+func TestExportCSVDialect(t *testing.T) {
+	records := []csvRecord{{Name: "Ada", Note: `says "hi"`}, {Name: "Bob", Note: "a;b"}}
+
+	tests := []struct {
+		name string
+		opts *Options
+		want string
+	}{
+		{
+			name: "default",
+			opts: &Options{Format: FormatCSV},
+			want: "Name,Note\nAda,\"says \"\"hi\"\"\"\nBob,a;b\n",
+		},
+		{
+			name: "Excel semicolon with CRLF",
+			opts: &Options{Format: FormatCSV, Delimiter: ';', UseCRLF: true},
+			want: "Name;Note\r\nAda;\"says \"\"hi\"\"\"\r\nBob;\"a;b\"\r\n",
+		},
+		{
+			name: "always quote",
+			opts: &Options{Format: FormatCSV, Delimiter: ';', UseCRLF: true, AlwaysQuote: true},
+			want: "\"Name\";\"Note\"\r\n\"Ada\";\"says \"\"hi\"\"\"\r\n\"Bob\";\"a;b\"\r\n",
+		},
+		{
+			name: "always quote with LF",
+			opts: &Options{Format: FormatCSV, AlwaysQuote: true},
+			want: "\"Name\",\"Note\"\n\"Ada\",\"says \"\"hi\"\"\"\n\"Bob\",\"a;b\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewExporter().Export(context.Background(), records, &buf, tt.opts); err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Export =\n%q\nwant\n%q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestExportBatchCSVDialect(t *testing.T) {
+	source := &sliceSource{items: []interface{}{csvRecord{Name: "Ada", Note: "x"}, csvRecord{Name: "Bob", Note: "y"}}}
+	opts := &Options{Format: FormatCSV, Delimiter: ';', UseCRLF: true, AlwaysQuote: true}
+
+	var buf bytes.Buffer
+	if err := NewExporter().ExportBatch(context.Background(), source, &buf, opts); err != nil {
+		t.Fatalf("ExportBatch failed: %v", err)
+	}
+
+	want := "\"Name\";\"Note\"\r\n\"Ada\";\"x\"\r\n\"Bob\";\"y\"\r\n"
+	if buf.String() != want {
+		t.Errorf("ExportBatch =\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	// The output reads back with the same delimiter
+	var imported []csvRecord
+	if err := NewImporter().Import(context.Background(), &buf, &imported, &Options{Format: FormatCSV, Delimiter: ';'}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(imported) != 2 || imported[1].Name != "Bob" {
+		t.Errorf("round trip = %+v", imported)
+	}
+}
+
+func TestExportCSVInvalidDelimiter(t *testing.T) {
+	for _, alwaysQuote := range []bool{false, true} {
+		var buf bytes.Buffer
+		opts := &Options{Format: FormatCSV, Delimiter: '"', AlwaysQuote: alwaysQuote}
+		if err := NewExporter().Export(context.Background(), []csvRecord{{Name: "Ada"}}, &buf, opts); err == nil {
+			t.Errorf("AlwaysQuote=%v: expected error for a quote delimiter", alwaysQuote)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/patdeg/common/datastore"
+)
+
+type testUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func TestRepositoryBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	src := datastore.NewLocalRepository()
+
+	// More entities than the batch size to exercise paging
+	for i := 0; i < 7; i++ {
+		user := testUser{
+			Name:  fmt.Sprintf("user-%d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Age:   20 + i,
+		}
+		if err := src.Put(ctx, "User", fmt.Sprintf("u%d", i), user); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	sources := map[string]DataSource{
+		"users": NewRepositorySource(src, "User", 3),
+	}
+	if err := Backup(ctx, sources, dir); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "backup-*"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one backup directory, got %v (%v)", matches, err)
+	}
+
+	dst := datastore.NewLocalRepository()
+	sinks := map[string]DataSink{
+		"users": NewRepositorySink(dst, "User"),
+	}
+	if err := Restore(ctx, matches[0], sinks); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	records, _, err := dst.QueryRecords(ctx, datastore.Query{Kind: "User"})
+	if err != nil {
+		t.Fatalf("QueryRecords failed: %v", err)
+	}
+	if len(records) != 7 {
+		t.Fatalf("restored %d records, want 7", len(records))
+	}
+
+	for i := 0; i < 7; i++ {
+		var got testUser
+		if err := dst.Get(ctx, "User", fmt.Sprintf("u%d", i), &got); err != nil {
+			t.Fatalf("Get u%d failed: %v", i, err)
+		}
+		want := testUser{
+			Name:  fmt.Sprintf("user-%d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Age:   20 + i,
+		}
+		if got != want {
+			t.Errorf("u%d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestRepositorySinkRejectsMissingKey(t *testing.T) {
+	sink := NewRepositorySink(datastore.NewLocalRepository(), "User")
+	err := sink.WriteBatch(context.Background(), []interface{}{
+		map[string]interface{}{"entity": map[string]interface{}{"name": map[string]interface{}{"type": "string", "value": "x"}}},
+	})
+	if err == nil {
+		t.Fatal("expected error for record without key")
+	}
+}
+
+func TestRepositoryRoundTripKeepsIDKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := datastore.NewLocalRepository()
+	sink := NewRepositorySink(repo, "User")
+	err := sink.WriteBatch(ctx, []interface{}{
+		map[string]interface{}{"id": "42", "entity": map[string]interface{}{"name": map[string]interface{}{"type": "string", "value": "x"}}},
+		map[string]interface{}{"key": "id:7", "entity": map[string]interface{}{"name": map[string]interface{}{"type": "string", "value": "y"}}},
+	})
+	if err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+
+	source := NewRepositorySource(repo, "User", 1)
+	var exported []interface{}
+	for source.HasMore() {
+		batch, err := source.NextBatch(ctx, 0)
+		if err != nil {
+			t.Fatalf("NextBatch failed: %v", err)
+		}
+		for _, item := range batch {
+			// Go through JSON as a backup file would
+			data, err := json.Marshal(item)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var m map[string]interface{}
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			exported = append(exported, m)
+		}
+	}
+
+	restored := datastore.NewLocalRepository()
+	if err := NewRepositorySink(restored, "User").WriteBatch(ctx, exported); err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+	records, _, err := restored.QueryRecords(ctx, datastore.Query{Kind: "User"})
+	if err != nil {
+		t.Fatalf("QueryRecords failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("restored %d records, want 2", len(records))
+	}
+	if records[0].ID != 42 || records[0].Key != "" {
+		t.Errorf("first record key = %q/%d, want ID 42", records[0].Key, records[0].ID)
+	}
+	if records[1].Key != "id:7" || records[1].ID != 0 {
+		t.Errorf("second record key = %q/%d, want name id:7", records[1].Key, records[1].ID)
+	}
+}
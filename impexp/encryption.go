@@ -0,0 +1,292 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+// Encrypted exports are written as a short header followed by a sequence of
+// AES-GCM sealed chunks, so arbitrarily large exports can be encrypted and
+// decrypted as streams:
+//
+//	header: "IXENC" | version (1 byte) | nonce prefix (7 random bytes)
+//	chunk:  final flag (1 byte) | ciphertext length (4 bytes, big endian) | ciphertext
+//
+// Each chunk's nonce is the prefix, a 4-byte chunk counter and the final
+// flag, and the header is bound to every chunk as additional data. Reordered,
+// dropped or truncated chunks therefore fail authentication.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encryption enables AES-GCM encryption of exports. Load the key from a
+// secret manager or KMS, never from source code.
+type Encryption struct {
+	Key []byte // 16, 24 or 32 bytes, selecting AES-128, AES-192 or AES-256
+}
+
+var (
+	// ErrEncryptedInput is returned when importing encrypted data without
+	// Options.Encryption.
+	ErrEncryptedInput = errors.New("impexp: input is encrypted but no encryption key was supplied")
+
+	// ErrDecryptionFailed is returned when encrypted data cannot be
+	// authenticated, usually because the key is wrong.
+	ErrDecryptionFailed = errors.New("impexp: decryption failed (wrong key or corrupted data)")
+)
+
+const (
+	encryptionMagic   = "IXENC"
+	encryptionVersion = 1
+	noncePrefixLen    = 7
+	encryptHeaderLen  = len(encryptionMagic) + 1 + noncePrefixLen
+
+	// encryptChunkSize is the plaintext size of every chunk but the last
+	encryptChunkSize = 64 << 10
+)
+
+// newGCM validates the key and returns the AEAD
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce derives the nonce of chunk n
+func chunkNonce(prefix []byte, n uint32, final bool) []byte {
+	nonce := make([]byte, 0, noncePrefixLen+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, n)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptWriter seals everything written to it in chunks. Close must be
+// called to write the final chunk; it does not close the underlying writer.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint32
+	closed  bool
+}
+
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, encryptHeaderLen)
+	copy(header, encryptionMagic)
+	header[len(encryptionMagic)] = encryptionVersion
+	if _, err := rand.Read(header[len(encryptionMagic)+1:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, aead: aead, header: header}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("impexp: write to closed encrypted stream")
+	}
+	e.buf = append(e.buf, p...)
+	for len(e.buf) > encryptChunkSize {
+		if err := e.seal(e.buf[:encryptChunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[encryptChunkSize:]
+	}
+	return len(p), nil
+}
+
+// Close writes the final chunk, which may be empty
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(e.buf, true)
+}
+
+func (e *encryptWriter) seal(plaintext []byte, final bool) error {
+	nonce := chunkNonce(e.header[len(encryptionMagic)+1:], e.counter, final)
+	e.counter++
+
+	sealed := e.aead.Seal(nil, nonce, plaintext, e.header)
+	record := make([]byte, 5, 5+len(sealed))
+	if final {
+		record[0] = 1
+	}
+	binary.BigEndian.PutUint32(record[1:], uint32(len(sealed)))
+	_, err := e.w.Write(append(record, sealed...))
+	return err
+}
+
+// decryptReader opens the chunks written by encryptWriter
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint32
+	done    bool
+}
+
+func newDecryptReader(r io.Reader, key []byte) (*decryptReader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, encryptHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecryptionFailed)
+	}
+	if string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New("impexp: input is not encrypted")
+	}
+	if header[len(encryptionMagic)] != encryptionVersion {
+		return nil, fmt.Errorf("impexp: unsupported encryption version %d", header[len(encryptionMagic)])
+	}
+
+	return &decryptReader{r: r, aead: aead, header: header}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// open reads and authenticates the next chunk
+func (d *decryptReader) open() error {
+	var record [5]byte
+	if _, err := io.ReadFull(d.r, record[:]); err != nil {
+		return fmt.Errorf("%w: truncated stream", ErrDecryptionFailed)
+	}
+	final := record[0] == 1
+	size := binary.BigEndian.Uint32(record[1:])
+	if record[0] > 1 || size > encryptChunkSize+uint32(d.aead.Overhead()) {
+		return fmt.Errorf("%w: malformed chunk", ErrDecryptionFailed)
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("%w: truncated stream", ErrDecryptionFailed)
+	}
+
+	nonce := chunkNonce(d.header[len(encryptionMagic)+1:], d.counter, final)
+	plaintext, err := d.aead.Open(sealed[:0], nonce, sealed, d.header)
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+	d.counter++
+	d.buf = plaintext
+	d.done = final
+	return nil
+}
+
+// encodeWriter wraps w with the compression and encryption requested by
+// opts. Data is compressed first, then encrypted, since ciphertext does not
+// compress. The returned finish function flushes both layers and must be
+// called once the export is written.
+func encodeWriter(w io.Writer, opts *Options) (io.Writer, func() error, error) {
+	var closers []io.Closer
+
+	if opts.Encryption != nil {
+		enc, err := newEncryptWriter(w, opts.Encryption.Key)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = enc
+		closers = append(closers, enc)
+	}
+
+	// ZIP archives and Parquet files are already compressed
+	if opts.Compress && opts.Format != FormatZIP && opts.Format != FormatParquet {
+		gz := gzip.NewWriter(w)
+		w = gz
+		closers = append(closers, gz)
+	}
+
+	finish := func() error {
+		// Close the outermost layer (gzip) before the encryption beneath it
+		for i := len(closers) - 1; i >= 0; i-- {
+			if err := closers[i].Close(); err != nil {
+				return fmt.Errorf("failed to finish export: %w", err)
+			}
+		}
+		return nil
+	}
+	return w, finish, nil
+}
+
+// decodeReader undoes encodeWriter: encrypted input (recognized by its
+// header) is decrypted with opts.Encryption, then gzip input is
+// decompressed. Plain input is returned unchanged.
+func decodeReader(r io.Reader, opts *Options) (io.Reader, error) {
+	head, r, err := peek(r, len(encryptionMagic))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(head, []byte(encryptionMagic)) {
+		if opts.Encryption == nil {
+			return nil, ErrEncryptedInput
+		}
+		dec, err := newDecryptReader(r, opts.Encryption.Key)
+		if err != nil {
+			return nil, err
+		}
+		r = dec
+	}
+
+	head, r, err = peek(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(head) == 2 && head[0] == 0x1f && head[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		r = gz
+	}
+	return r, nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKey returns a random AES-256 key
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand.Read failed: %v", err)
+	}
+	return key
+}
+
+func TestEncryptedExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	key := testKey(t)
+
+	// Large enough to span several encryption chunks
+	var users []legacyUser
+	for i := 0; i < 3000; i++ {
+		users = append(users, legacyUser{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i), Age: i % 90})
+	}
+
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			opts := &Options{Format: FormatJSON, Compress: compress, Encryption: &Encryption{Key: key}}
+
+			var buf bytes.Buffer
+			if err := NewExporter().Export(ctx, users, &buf, opts); err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+			if !bytes.HasPrefix(buf.Bytes(), []byte(encryptionMagic)) {
+				t.Fatal("export does not start with the encryption header")
+			}
+			if bytes.Contains(buf.Bytes(), []byte("user1@example.com")) {
+				t.Fatal("export contains plaintext")
+			}
+
+			var got []legacyUser
+			if err := NewImporter().Import(ctx, &buf, &got, &Options{Encryption: &Encryption{Key: key}}); err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if len(got) != len(users) || got[2999] != users[2999] {
+				t.Errorf("round trip lost data: got %d users", len(got))
+			}
+		})
+	}
+}
+
+func TestEncryptedExportWrongKey(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	opts := &Options{Format: FormatCSV, Compress: true, Encryption: &Encryption{Key: testKey(t)}}
+	if err := NewExporter().Export(ctx, []legacyUser{{Name: "Alice"}}, &buf, opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	encrypted := buf.Bytes()
+
+	var got []legacyUser
+	err := NewImporter().Import(ctx, bytes.NewReader(encrypted), &got, &Options{Encryption: &Encryption{Key: testKey(t)}})
+	if !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("wrong key error = %v, want ErrDecryptionFailed", err)
+	}
+
+	err = NewImporter().Import(ctx, bytes.NewReader(encrypted), &got, nil)
+	if !errors.Is(err, ErrEncryptedInput) {
+		t.Errorf("missing key error = %v, want ErrEncryptedInput", err)
+	}
+
+	err = NewImporter().Import(ctx, bytes.NewReader(encrypted), &got, &Options{Encryption: &Encryption{Key: []byte("short")}})
+	if err == nil || !strings.Contains(err.Error(), "invalid encryption key") {
+		t.Errorf("invalid key error = %v", err)
+	}
+}
+
+func TestEncryptedExportTampering(t *testing.T) {
+	ctx := context.Background()
+	key := testKey(t)
+
+	var buf bytes.Buffer
+	if err := NewExporter().Export(ctx, []legacyUser{{Name: "Alice"}}, &buf, &Options{Format: FormatJSON, Encryption: &Encryption{Key: key}}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	tests := map[string][]byte{
+		"truncated": buf.Bytes()[:buf.Len()-1],
+		"flipped":   append([]byte(nil), buf.Bytes()...),
+	}
+	tests["flipped"][len(tests["flipped"])-1] ^= 0xff
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []legacyUser
+			err := NewImporter().Import(ctx, bytes.NewReader(data), &got, &Options{Format: FormatJSON, Encryption: &Encryption{Key: key}})
+			if !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("error = %v, want ErrDecryptionFailed", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedExportFile(t *testing.T) {
+	ctx := context.Background()
+	key := testKey(t)
+	filename := filepath.Join(t.TempDir(), "backup.json.enc")
+
+	opts := &Options{Format: FormatJSON, Compress: true, Encryption: &Encryption{Key: key}}
+	if err := NewExporter().ExportFile(ctx, []legacyUser{{Name: "Alice", Age: 30}}, filename, opts); err != nil {
+		t.Fatalf("ExportFile failed: %v", err)
+	}
+
+	var got []legacyUser
+	if err := NewImporter().ImportFile(ctx, filename, &got, &Options{Encryption: &Encryption{Key: key}}); err != nil {
+		t.Fatalf("ImportFile failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "Alice" || got[0].Age != 30 {
+		t.Errorf("users = %+v", got)
+	}
+}
+
+func TestEncryptedExportBatch(t *testing.T) {
+	ctx := context.Background()
+	key := testKey(t)
+
+	source := &sliceSource{items: []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}}}
+	var buf bytes.Buffer
+	if err := NewExporter().ExportBatch(ctx, source, &buf, &Options{Format: FormatJSON, Encryption: &Encryption{Key: key}}); err != nil {
+		t.Fatalf("ExportBatch failed: %v", err)
+	}
+
+	sink := &recordingSink{}
+	if err := NewImporter().ImportBatch(ctx, &buf, sink, &Options{Format: FormatJSON, Encryption: &Encryption{Key: key}}); err != nil {
+		t.Fatalf("ImportBatch failed: %v", err)
+	}
+	if len(sink.items) != 2 {
+		t.Errorf("imported %d items, want 2", len(sink.items))
+	}
+}
+
+// sliceSource serves items in a single batch
+type sliceSource struct {
+	items []interface{}
+	done  bool
+}
+
+func (s *sliceSource) NextBatch(ctx context.Context, batchSize int) ([]interface{}, error) {
+	s.done = true
+	return s.items, nil
+}
+
+func (s *sliceSource) HasMore() bool { return !s.done }
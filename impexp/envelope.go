@@ -0,0 +1,180 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Envelope wraps JSON exports in an object carrying metadata next to the
+// records, for systems that expect
+//
+//	{"records": [...], "count": 2, "exportedAt": "2025-01-02T15:04:05Z"}
+//
+// instead of a bare array. With Options.Envelope set, Export and
+// ExportBatch write this shape for FormatJSON, and Import and ImportBatch
+// read the records back from RecordsKey, ignoring the other keys.
+type Envelope struct {
+	// RecordsKey holds the exported data. Defaults to "records".
+	RecordsKey string
+
+	// CountKey holds the number of records. Empty omits the count.
+	CountKey string
+
+	// TimestampKey holds the export time in RFC 3339. Empty omits it.
+	TimestampKey string
+
+	// Fields adds static metadata such as a schema version. Keys clashing
+	// with the keys above are overwritten by them.
+	Fields map[string]interface{}
+}
+
+// DefaultEnvelope returns the records/count/exportedAt envelope
+func DefaultEnvelope() *Envelope {
+	return &Envelope{
+		RecordsKey:   "records",
+		CountKey:     "count",
+		TimestampKey: "exportedAt",
+	}
+}
+
+// recordsKey returns the configured records key or the default
+func (e *Envelope) recordsKey() string {
+	if e.RecordsKey != "" {
+		return e.RecordsKey
+	}
+	return "records"
+}
+
+// metadata returns the envelope keys other than the records
+func (e *Envelope) metadata(count int, now time.Time) map[string]interface{} {
+	meta := make(map[string]interface{}, len(e.Fields)+2)
+	for k, v := range e.Fields {
+		meta[k] = v
+	}
+	if e.CountKey != "" {
+		meta[e.CountKey] = count
+	}
+	if e.TimestampKey != "" {
+		meta[e.TimestampKey] = now.UTC().Format(time.RFC3339)
+	}
+	delete(meta, e.recordsKey())
+	return meta
+}
+
+// wrap returns data inside the envelope. Slices and arrays are counted by
+// length; any other value counts as one record.
+func (e *Envelope) wrap(data interface{}) map[string]interface{} {
+	count := 1
+	val := reflect.ValueOf(data)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		count = val.Len()
+	}
+
+	wrapped := e.metadata(count, time.Now())
+	wrapped[e.recordsKey()] = data
+	return wrapped
+}
+
+// openStream returns the bytes preceding a streamed records array
+func (e *Envelope) openStream() ([]byte, error) {
+	key, err := json.Marshal(e.recordsKey())
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte("{\n"), key...), ": [\n"...), nil
+}
+
+// closeStream returns the bytes following a streamed records array of
+// count records: the metadata keys and the closing brace
+func (e *Envelope) closeStream(count int) ([]byte, error) {
+	meta := e.metadata(count, time.Now())
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // same order as encoding/json uses for maps
+
+	var buf bytes.Buffer
+	buf.WriteString("\n]")
+	for _, key := range keys {
+		value := meta[key]
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode envelope field %s: %w", key, err)
+		}
+		buf.WriteString(",\n")
+		buf.Write(k)
+		buf.WriteString(": ")
+		buf.Write(v)
+	}
+	buf.WriteString("\n}")
+	return buf.Bytes(), nil
+}
+
+// unwrap returns the raw records of an enveloped JSON document
+func (e *Envelope) unwrap(r io.Reader) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON envelope: %w", err)
+	}
+	records, ok := doc[e.recordsKey()]
+	if !ok {
+		return nil, fmt.Errorf("JSON envelope has no %q key", e.recordsKey())
+	}
+	return records, nil
+}
+
+// recordReader positions a streaming decoder on the records array of an
+// enveloped document, skipping metadata keys that come before it
+func (e *Envelope) recordReader(r io.Reader) (recordReader, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("failed to read JSON envelope opening: expected an object")
+	}
+
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON envelope: %w", err)
+		}
+		if tok != e.recordsKey() {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("failed to read JSON envelope: %w", err)
+			}
+			continue
+		}
+		if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+			return nil, fmt.Errorf("JSON envelope key %q does not hold an array", e.recordsKey())
+		}
+		return &jsonArrayReader{decoder: decoder}, nil
+	}
+	return nil, fmt.Errorf("JSON envelope has no %q key", e.recordsKey())
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportEnvelopeRoundTrip(t *testing.T) {
+	users := []legacyUser{
+		{Name: "Ada", Email: "ada@example.com", Age: 36},
+		{Name: "Bob", Email: "bob@example.com", Age: 41},
+	}
+	env := DefaultEnvelope()
+	env.Fields = map[string]interface{}{"schemaVersion": "2"}
+	opts := &Options{Format: FormatJSON, Envelope: env}
+
+	var buf bytes.Buffer
+	if err := NewExporter().Export(context.Background(), users, &buf, opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var doc struct {
+		Records       []legacyUser `json:"records"`
+		Count         int          `json:"count"`
+		ExportedAt    time.Time    `json:"exportedAt"`
+		SchemaVersion string       `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not an envelope: %v\n%s", err, buf.String())
+	}
+	if doc.Count != 2 || doc.SchemaVersion != "2" || time.Since(doc.ExportedAt) > time.Minute {
+		t.Errorf("envelope = %+v", doc)
+	}
+
+	var got []legacyUser
+	if err := NewImporter().Import(context.Background(), &buf, &got, opts); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, users) {
+		t.Errorf("round trip = %+v, want %+v", got, users)
+	}
+}
+
+func TestExportBatchEnvelopeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope *Envelope
+		key      string
+	}{
+		{"default keys", DefaultEnvelope(), "records"},
+		{"custom keys", &Envelope{RecordsKey: "data", CountKey: "total"}, "data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &sliceSource{items: []interface{}{
+				map[string]interface{}{"name": "ada"},
+				map[string]interface{}{"name": "bob"},
+				map[string]interface{}{"name": "carol"},
+			}}
+			opts := &Options{Format: FormatJSON, Envelope: tt.envelope, Pretty: true}
+
+			var buf bytes.Buffer
+			if err := NewExporter().ExportBatch(context.Background(), source, &buf, opts); err != nil {
+				t.Fatalf("ExportBatch failed: %v", err)
+			}
+
+			var doc map[string]json.RawMessage
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+			}
+			if _, ok := doc[tt.key]; !ok {
+				t.Errorf("output has no %q key: %s", tt.key, buf.String())
+			}
+			if tt.envelope.CountKey != "" && string(doc[tt.envelope.CountKey]) != "3" {
+				t.Errorf("count = %s, want 3", doc[tt.envelope.CountKey])
+			}
+
+			sink := &recordingSink{}
+			if err := NewImporter().ImportBatch(context.Background(), &buf, sink, opts); err != nil {
+				t.Fatalf("ImportBatch failed: %v", err)
+			}
+			if len(sink.items) != 3 || sink.items[2].(map[string]interface{})["name"] != "carol" {
+				t.Errorf("imported %v, want the three records", sink.items)
+			}
+		})
+	}
+}
+
+func TestImportEnvelopeRecordsAfterMetadata(t *testing.T) {
+	input := `{"count": 1, "meta": {"nested": [1, 2]}, "items": [{"name": "ada"}]}`
+	opts := &Options{Format: FormatJSON, Envelope: &Envelope{RecordsKey: "items"}}
+
+	sink := &recordingSink{}
+	if err := NewImporter().ImportBatch(context.Background(), strings.NewReader(input), sink, opts); err != nil {
+		t.Fatalf("ImportBatch failed: %v", err)
+	}
+	if len(sink.items) != 1 {
+		t.Errorf("imported %v, want one record", sink.items)
+	}
+
+	for _, bad := range []string{`[{"name": "ada"}]`, `{"records": []}`, `{"items": {"name": "ada"}}`} {
+		if err := NewImporter().ImportBatch(context.Background(), strings.NewReader(bad), &recordingSink{}, opts); err == nil {
+			t.Errorf("ImportBatch(%s) succeeded, want an error", bad)
+		}
+	}
+	var dest []map[string]interface{}
+	if err := NewImporter().Import(context.Background(), strings.NewReader(`{"records": []}`), &dest, opts); err == nil {
+		t.Error("Import without the records key succeeded, want an error")
+	}
+}
+
+func TestExportWithoutEnvelopeIsBare(t *testing.T) {
+	data := []map[string]string{{"name": "ada"}}
+
+	var buf bytes.Buffer
+	if err := NewExporter().Export(context.Background(), data, &buf, &Options{Format: FormatJSON}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if got := buf.String(); got != "[{\"name\":\"ada\"}]\n" {
+		t.Errorf("Export = %q, want a bare array", got)
+	}
+
+	buf.Reset()
+	source := &sliceSource{items: []interface{}{map[string]string{"name": "ada"}}}
+	if err := NewExporter().ExportBatch(context.Background(), source, &buf, &Options{Format: FormatJSON}); err != nil {
+		t.Fatalf("ExportBatch failed: %v", err)
+	}
+	if got := buf.String(); got != "[\n{\"name\":\"ada\"}\n\n]" {
+		t.Errorf("ExportBatch = %q, want a bare array", got)
+	}
+}
@@ -18,15 +18,21 @@ package impexp
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,6 +47,10 @@ const (
 	FormatCSV  Format = "csv"
 	FormatXML  Format = "xml"
 	FormatZIP  Format = "zip"
+
+	// FormatParquet writes typed, columnar Parquet files for BigQuery
+	// loads. Only exports are supported.
+	FormatParquet Format = "parquet"
 )
 
 // Options configures import/export operations
@@ -49,13 +59,38 @@ type Options struct {
 	Pretty      bool              // Pretty print JSON
 	Compress    bool              // Compress output
 	Filter      FilterFunc        // Filter function for selective export
-	Transform   TransformFunc     // Transform function for data manipulation
+	Transform   TransformFunc     // Transform function for data manipulation, applied after Transforms
+	Transforms  []TransformFunc   // Transform stages applied in order, e.g. rename, redact, enrich
 	BatchSize   int               // Batch size for large datasets
-	Delimiter   rune              // CSV delimiter
+	Delimiter   rune              // CSV delimiter (default ',')
+	UseCRLF     bool              // End CSV lines with \r\n, as Excel expects
+	AlwaysQuote bool              // Quote every CSV field, not only those that need it
 	Headers     []string          // CSV headers
 	MaxFileSize int64             // Maximum file size in bytes
 	Metadata    map[string]string // Additional metadata
 	Version     string            // Export version for ZIP format (default "1.0")
+	FieldMap    map[string]string // Renames source keys during import (e.g. "user_name" -> "Name")
+	DryRun      bool              // Validate ImportBatch input without writing to the sink
+
+	ContinueOnError bool // Skip malformed ImportBatch records instead of aborting
+	MaxErrors       int  // Cap on errors collected by ImportBatch (default DefaultMaxImportErrors)
+
+	// Envelope wraps JSON exports in an object with metadata keys and
+	// unwraps the records on import. Nil keeps bare JSON.
+	Envelope *Envelope
+
+	// Encryption encrypts exports with AES-GCM and decrypts encrypted input
+	// on import. Compression, when enabled, is applied before encryption.
+	Encryption *Encryption
+
+	// Since limits ExportBatch to items whose TimestampField is at or after
+	// it, for incremental exports. Items without a readable timestamp are
+	// still exported. The zero value exports everything.
+	Since time.Time
+	// TimestampField names the struct field (or JSON tag) or map key holding
+	// each item's last-modified time (default DefaultTimestampField). It may
+	// be a time.Time, *time.Time or RFC 3339 string.
+	TimestampField string
 }
 
 // FilterFunc filters entities during export/import
@@ -64,6 +99,27 @@ type FilterFunc func(entity interface{}) bool
 // TransformFunc transforms entities during export/import
 type TransformFunc func(entity interface{}) (interface{}, error)
 
+// hasTransform reports whether any transform stage is configured
+func (o *Options) hasTransform() bool {
+	return o.Transform != nil || len(o.Transforms) > 0
+}
+
+// transform runs item through Transforms in order and then Transform. The
+// first error stops the pipeline and is returned as is, so callers skip
+// the item exactly as they do for a single Transform.
+func (o *Options) transform(item interface{}) (interface{}, error) {
+	var err error
+	for _, fn := range o.Transforms {
+		if item, err = fn(item); err != nil {
+			return nil, err
+		}
+	}
+	if o.Transform != nil {
+		return o.Transform(item)
+	}
+	return item, nil
+}
+
 // Exporter handles data export operations
 type Exporter interface {
 	// Export exports data to a writer
@@ -72,6 +128,9 @@ type Exporter interface {
 	// ExportFile exports data to a file
 	ExportFile(ctx context.Context, data interface{}, filename string, opts *Options) error
 
+	// ExportTo exports data to a Target such as a Cloud Storage object
+	ExportTo(ctx context.Context, data interface{}, target Target, opts *Options) error
+
 	// ExportBatch exports data in batches
 	ExportBatch(ctx context.Context, dataSource DataSource, w io.Writer, opts *Options) error
 }
@@ -84,6 +143,9 @@ type Importer interface {
 	// ImportFile imports data from a file
 	ImportFile(ctx context.Context, filename string, dest interface{}, opts *Options) error
 
+	// ImportFrom imports data from a Target such as a Cloud Storage object
+	ImportFrom(ctx context.Context, target Target, dest interface{}, opts *Options) error
+
 	// ImportBatch imports data in batches
 	ImportBatch(ctx context.Context, r io.Reader, dataSink DataSink, opts *Options) error
 }
@@ -125,6 +187,18 @@ func (e *DefaultExporter) Export(ctx context.Context, data interface{}, w io.Wri
 		opts = &Options{Format: FormatJSON}
 	}
 
+	out, finish, err := encodeWriter(w, opts)
+	if err != nil {
+		return err
+	}
+	if err := e.export(ctx, data, out, opts); err != nil {
+		return err
+	}
+	return finish()
+}
+
+// export writes data in the requested format
+func (e *DefaultExporter) export(ctx context.Context, data interface{}, w io.Writer, opts *Options) error {
 	switch opts.Format {
 	case FormatJSON:
 		return e.exportJSON(data, w, opts)
@@ -132,69 +206,96 @@ func (e *DefaultExporter) Export(ctx context.Context, data interface{}, w io.Wri
 		return e.exportCSV(data, w, opts)
 	case FormatZIP:
 		return e.exportZIP(ctx, data, w, opts)
+	case FormatParquet:
+		return e.exportParquet(data, w, opts)
 	default:
 		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
-// ExportFile exports data to a file
+// ExportFile exports data to a file, creating missing directories
 func (e *DefaultExporter) ExportFile(ctx context.Context, data interface{}, filename string, opts *Options) error {
-	// Create directory if needed
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0750); err != nil {
-		return fmt.Errorf("failed to create directory: %v", err)
-	}
+	return e.ExportTo(ctx, data, FileTarget(filename), opts)
+}
 
-	// Create file
-	// #nosec G304 -- filename is expected to be application-controlled, not raw user input.
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %v", err)
-	}
-	defer file.Close()
+// ExportBatch exports data in batches
+func (e *DefaultExporter) ExportBatch(ctx context.Context, dataSource DataSource, w io.Writer, opts *Options) error {
+	_, err := e.ExportBatchWithResult(ctx, dataSource, w, opts)
+	return err
+}
 
-	// Export data
-	if err := e.Export(ctx, data, file, opts); err != nil {
-		return err
-	}
+// ExportResult summarizes a batch export
+type ExportResult struct {
+	Exported int // Items written to the output
 
-	common.Info("[IMPEXP] Exported data to %s", filename)
-	return nil
+	// Watermark is the latest timestamp among exported items, or
+	// opts.Since when none had a newer one. Persist it and pass it as
+	// Since on the next run to export only what changed in between.
+	Watermark time.Time
 }
 
-// ExportBatch exports data in batches
-func (e *DefaultExporter) ExportBatch(ctx context.Context, dataSource DataSource, w io.Writer, opts *Options) error {
+// ExportBatchWithResult exports data in batches like ExportBatch and
+// reports how many items were written and the timestamp watermark:
+//
+//	opts := &impexp.Options{Format: impexp.FormatJSON, Since: lastRun}
+//	res, err := exporter.ExportBatchWithResult(ctx, source, w, opts)
+//	if err == nil {
+//	    lastRun = res.Watermark
+//	}
+//
+// Items are compared with Since before Filter and transforms run, using the
+// timestamp of the item as returned by the DataSource.
+func (e *DefaultExporter) ExportBatchWithResult(ctx context.Context, dataSource DataSource, w io.Writer, opts *Options) (*ExportResult, error) {
 	if opts == nil {
 		opts = &Options{Format: FormatJSON, BatchSize: 100}
 	}
+	result := &ExportResult{Watermark: opts.Since}
+	err := e.exportBatch(ctx, dataSource, w, opts, result)
+	return result, err
+}
+
+// exportBatch streams dataSource to w, recording progress in result
+func (e *DefaultExporter) exportBatch(ctx context.Context, dataSource DataSource, w io.Writer, opts *Options, result *ExportResult) error {
 
 	if opts.BatchSize <= 0 {
 		opts.BatchSize = 100
 	}
 
+	w, finish, err := encodeWriter(w, opts)
+	if err != nil {
+		return err
+	}
+
 	// Start export based on format
 	switch opts.Format {
 	case FormatJSON:
 		// Write opening bracket for JSON array
-		if _, err := w.Write([]byte("[\n")); err != nil {
+		opening := []byte("[\n")
+		if opts.Envelope != nil {
+			if opening, err = opts.Envelope.openStream(); err != nil {
+				return fmt.Errorf("failed to encode JSON envelope: %w", err)
+			}
+		}
+		if _, err := w.Write(opening); err != nil {
 			return fmt.Errorf("failed to write JSON array opening: %w", err)
 		}
 	case FormatCSV:
 		// Write CSV headers if provided
 		if len(opts.Headers) > 0 {
-			csvWriter := csv.NewWriter(w)
-			if opts.Delimiter != 0 {
-				csvWriter.Comma = opts.Delimiter
-			}
+			csvWriter := newCSVWriter(w, opts)
 			if err := csvWriter.Write(opts.Headers); err != nil {
 				return err
 			}
 			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return fmt.Errorf("CSV writer error: %w", err)
+			}
 		}
 	}
 
 	totalExported := 0
 	first := true
+	var parquetOut *parquetEncoder
 
 	for dataSource.HasMore() {
 		// Check context cancellation
@@ -212,14 +313,20 @@ func (e *DefaultExporter) ExportBatch(ctx context.Context, dataSource DataSource
 
 		// Export batch
 		for _, item := range batch {
+			// Skip items unchanged since the last incremental export
+			ts, hasTS := itemTimestamp(item, opts.timestampField())
+			if hasTS && ts.Before(opts.Since) {
+				continue
+			}
+
 			// Apply filter if provided
 			if opts.Filter != nil && !opts.Filter(item) {
 				continue
 			}
 
-			// Apply transform if provided
-			if opts.Transform != nil {
-				transformed, err := opts.Transform(item)
+			// Apply transforms if provided
+			if opts.hasTransform() {
+				transformed, err := opts.transform(item)
 				if err != nil {
 					common.Warn("[IMPEXP] Failed to transform item: %v", err)
 					continue
@@ -245,10 +352,7 @@ func (e *DefaultExporter) ExportBatch(ctx context.Context, dataSource DataSource
 				first = false
 			case FormatCSV:
 				// Convert to CSV row using reflection
-				csvWriter := csv.NewWriter(w)
-				if opts.Delimiter != 0 {
-					csvWriter.Comma = opts.Delimiter
-				}
+				csvWriter := newCSVWriter(w, opts)
 
 				// Get headers from item if not already done
 				if len(opts.Headers) == 0 {
@@ -272,18 +376,50 @@ func (e *DefaultExporter) ExportBatch(ctx context.Context, dataSource DataSource
 				if err := csvWriter.Error(); err != nil {
 					return fmt.Errorf("CSV writer error: %w", err)
 				}
+			case FormatParquet:
+				// The schema comes from the first exported item
+				if parquetOut == nil {
+					parquetOut, err = newParquetEncoder(w, reflect.TypeOf(item))
+					if err != nil {
+						return err
+					}
+				}
+				if err := parquetOut.encode(item); err != nil {
+					return err
+				}
 			}
 
 			totalExported++
+			result.Exported = totalExported
+			if hasTS && ts.After(result.Watermark) {
+				result.Watermark = ts
+			}
 		}
 	}
 
 	// Close export based on format
 	switch opts.Format {
 	case FormatJSON:
-		if _, err := w.Write([]byte("\n]")); err != nil {
+		closing := []byte("\n]")
+		if opts.Envelope != nil {
+			if closing, err = opts.Envelope.closeStream(totalExported); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(closing); err != nil {
 			return fmt.Errorf("failed to write JSON array closing: %w", err)
 		}
+	case FormatParquet:
+		if parquetOut == nil {
+			return fmt.Errorf("no items to derive a Parquet schema from")
+		}
+		if err := parquetOut.close(); err != nil {
+			return err
+		}
+	}
+
+	if err := finish(); err != nil {
+		return err
 	}
 
 	common.Info("[IMPEXP] Exported %d items", totalExported)
@@ -296,15 +432,15 @@ func (e *DefaultExporter) exportJSON(data interface{}, w io.Writer, opts *Option
 	if opts.Pretty {
 		encoder.SetIndent("", "  ")
 	}
+	if opts.Envelope != nil {
+		return encoder.Encode(opts.Envelope.wrap(data))
+	}
 	return encoder.Encode(data)
 }
 
 // exportCSV exports data as CSV
 func (e *DefaultExporter) exportCSV(data interface{}, w io.Writer, opts *Options) error {
-	csvWriter := csv.NewWriter(w)
-	if opts.Delimiter != 0 {
-		csvWriter.Comma = opts.Delimiter
-	}
+	csvWriter := newCSVWriter(w, opts)
 
 	// Handle the data based on its type
 	val := reflect.ValueOf(data)
@@ -408,17 +544,7 @@ func getCSVHeaders(val reflect.Value) []string {
 				// Skip unexported fields
 				continue
 			}
-			// Use json tag if available, otherwise use field name
-			tag := field.Tag.Get("json")
-			if tag != "" && tag != "-" {
-				// Handle json tag options like "field,omitempty"
-				if idx := strings.Index(tag, ","); idx != -1 {
-					tag = tag[:idx]
-				}
-				headers = append(headers, tag)
-			} else {
-				headers = append(headers, field.Name)
-			}
+			headers = append(headers, fieldHeader(field))
 		}
 	case reflect.Map:
 		// For maps, use the keys as headers
@@ -430,6 +556,20 @@ func getCSVHeaders(val reflect.Value) []string {
 	return headers
 }
 
+// fieldHeader returns the column name of a struct field: its json tag name
+// if set, otherwise the field name
+func fieldHeader(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag != "" && tag != "-" {
+		// Handle json tag options like "field,omitempty"
+		if idx := strings.Index(tag, ","); idx != -1 {
+			tag = tag[:idx]
+		}
+		return tag
+	}
+	return field.Name
+}
+
 // getCSVRow extracts values from a struct or map based on headers
 func getCSVRow(val reflect.Value, headers []string) []string {
 	if val.Kind() == reflect.Ptr {
@@ -539,10 +679,27 @@ func (e *DefaultExporter) exportZIP(ctx context.Context, data interface{}, w io.
 	return encoder.Encode(data)
 }
 
-// Import imports data from a reader
+// Import imports data from a reader. Encrypted and gzip-compressed input is
+// decoded first; see Options.Encryption.
+// When opts is nil or opts.Format is empty, the format is detected from the
+// content with DetectFormat, so gzip-compressed JSON or CSV is accepted too.
 func (i *DefaultImporter) Import(ctx context.Context, r io.Reader, dest interface{}, opts *Options) error {
 	if opts == nil {
-		opts = &Options{Format: FormatJSON}
+		opts = &Options{}
+	}
+	r, err := decodeReader(r, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Format == "" {
+		format, detected, err := DetectFormat(r)
+		if err != nil {
+			return err
+		}
+		common.Debug("[IMPEXP] Detected %s input", format)
+		sniffed := *opts
+		sniffed.Format = format
+		opts, r = &sniffed, detected
 	}
 
 	switch opts.Format {
@@ -557,39 +714,65 @@ func (i *DefaultImporter) Import(ctx context.Context, r io.Reader, dest interfac
 	}
 }
 
-// ImportFile imports data from a file
+// ImportFile imports data from a file. The format is taken from the file
+// extension when opts.Format is empty.
 func (i *DefaultImporter) ImportFile(ctx context.Context, filename string, dest interface{}, opts *Options) error {
-	// #nosec G304 -- filename is expected to be application-controlled. Validate if sourced from user input.
-	file, err := os.Open(filename)
+	return i.ImportFrom(ctx, FileTarget(filename), dest, opts)
+}
+
+// sniffLen is how many leading bytes DetectFormat inspects
+const sniffLen = 512
+
+// DetectFormat inspects the first bytes of r to guess its format: JSON when
+// the first non-space character is '{' or '[', ZIP for the "PK" signature,
+// and CSV otherwise. Gzip-compressed input is decompressed and the content
+// inside is detected the same way.
+//
+// The returned reader yields the complete stream, including the inspected
+// bytes (decompressed for gzip input), and must be used instead of r.
+func DetectFormat(r io.Reader) (Format, io.Reader, error) {
+	head, r, err := peek(r, sniffLen)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return "", nil, err
 	}
-	defer file.Close()
 
-	// Auto-detect format from extension if not specified
-	if opts == nil {
-		opts = &Options{}
-	}
-	if opts.Format == "" {
-		ext := strings.ToLower(filepath.Ext(filename))
-		switch ext {
-		case ".json":
-			opts.Format = FormatJSON
-		case ".csv":
-			opts.Format = FormatCSV
-		case ".zip":
-			opts.Format = FormatZIP
-		default:
-			opts.Format = FormatJSON
+	if len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		head, r, err = peek(gz, sniffLen)
+		if err != nil {
+			return "", nil, err
 		}
 	}
 
-	if err := i.Import(ctx, file, dest, opts); err != nil {
-		return err
+	return sniffFormat(head), r, nil
+}
+
+// peek reads up to n bytes from r and returns them along with a reader that
+// replays them before the rest of r.
+func peek(r io.Reader, n int) ([]byte, io.Reader, error) {
+	head := make([]byte, n)
+	read, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, fmt.Errorf("failed to read input: %w", err)
 	}
+	head = head[:read]
+	return head, io.MultiReader(bytes.NewReader(head), r), nil
+}
 
-	common.Info("[IMPEXP] Imported data from %s", filename)
-	return nil
+// sniffFormat classifies uncompressed content by its leading bytes
+func sniffFormat(head []byte) Format {
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) || bytes.HasPrefix(head, []byte("PK\x05\x06")) {
+		return FormatZIP
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+	return FormatCSV
 }
 
 // stripBOM removes the UTF-8 BOM if present
@@ -612,8 +795,79 @@ func stripBOM(r io.Reader) io.Reader {
 	return io.MultiReader(bytes.NewReader(buf[:n]), r)
 }
 
+// DefaultMaxImportErrors is the number of errors ImportBatch collects when
+// Options.MaxErrors is not set.
+const DefaultMaxImportErrors = 1000
+
+// maxRawErrorBytes bounds ImportError.Raw so a huge malformed line does not
+// stay in memory.
+const maxRawErrorBytes = 1024
+
+// ErrImportTruncated is returned when the input cannot be read past an error,
+// such as a syntax error inside a JSON array. Records after it were not
+// imported.
+var ErrImportTruncated = errors.New("impexp: input could not be read to the end")
+
+// ImportResult summarizes a batch import
+type ImportResult struct {
+	Valid     int           // Records that passed decoding, filtering and transformation
+	Written   int           // Records handed to the DataSink (always 0 in DryRun mode)
+	Rejected  int           // Records that were rejected, including those beyond the error cap
+	Truncated bool          // Reading stopped early, see ErrImportTruncated
+	Errors    []ImportError // Details for the first MaxErrors rejected records
+}
+
+// ImportError describes a rejected record. Line is the 1-based line number
+// for JSON Lines input and the 1-based record position for JSON arrays.
+// Raw holds the record text when it is available (JSON Lines input),
+// truncated to 1 KiB.
+type ImportError struct {
+	Line int
+	Err  error
+	Raw  string
+}
+
+// Error implements the error interface
+func (e ImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e ImportError) Unwrap() error {
+	return e.Err
+}
+
 // ImportBatch imports data in batches
 func (i *DefaultImporter) ImportBatch(ctx context.Context, r io.Reader, dataSink DataSink, opts *Options) error {
+	_, err := i.ImportBatchWithResult(ctx, r, dataSink, opts)
+	return err
+}
+
+// ImportBatchWithResult imports a JSON array or JSON Lines stream in batches
+// and reports what happened to each record. Records rejected by a
+// transform stage are skipped and listed in the result.
+//
+// With opts.DryRun set, every record is decoded, filtered and transformed
+// but WriteBatch is never called, and decode errors are collected instead of
+// aborting the import, so a customer file can be validated up front:
+//
+//	res, err := importer.ImportBatchWithResult(ctx, f, sink, &impexp.Options{DryRun: true})
+//	for _, e := range res.Errors {
+//	    log.Printf("%v", e) // "line 42: invalid character ..."
+//	}
+//
+// With opts.ContinueOnError set, malformed records are skipped and collected
+// the same way while the valid ones are written, so one bad row does not
+// abort a large import. At most opts.MaxErrors errors are kept; Rejected
+// still counts every skipped record.
+//
+// A syntax error inside a JSON array ends the scan since the rest of the
+// stream cannot be resynchronized; JSON Lines input continues with the next
+// line. Such an error is recorded like any other and sets
+// result.Truncated; with ContinueOnError the records read before it are
+// written and an error wrapping ErrImportTruncated is returned, since the
+// import is incomplete. A dry run reports it without failing.
+func (i *DefaultImporter) ImportBatchWithResult(ctx context.Context, r io.Reader, dataSink DataSink, opts *Options) (*ImportResult, error) {
 	if opts == nil {
 		opts = &Options{Format: FormatJSON, BatchSize: 100}
 	}
@@ -621,36 +875,81 @@ func (i *DefaultImporter) ImportBatch(ctx context.Context, r io.Reader, dataSink
 	if opts.BatchSize <= 0 {
 		opts.BatchSize = 100
 	}
+	maxErrors := opts.MaxErrors
+	if maxErrors <= 0 {
+		maxErrors = DefaultMaxImportErrors
+	}
 
-	// Strip BOM if present
-	r = stripBOM(r)
-
-	decoder := json.NewDecoder(r)
-
-	// Read opening bracket
-	token, err := decoder.Token()
+	r, err := decodeReader(r, opts)
 	if err != nil {
-		return fmt.Errorf("failed to read JSON opening: %w", err)
+		return nil, err
+	}
+
+	// Strip BOM if present
+	var records recordReader
+	if opts.Envelope != nil {
+		records, err = opts.Envelope.recordReader(stripBOM(r))
+	} else {
+		records, err = newRecordReader(stripBOM(r))
 	}
-	if delim, ok := token.(json.Delim); !ok || delim != '[' {
-		return fmt.Errorf("expected JSON array, got %T: %v", token, token)
+	if err != nil {
+		return nil, err
 	}
 
+	result := &ImportResult{}
 	batch := make([]interface{}, 0, opts.BatchSize)
-	totalImported := 0
+
+	// reject records a skipped item, keeping details up to the cap
+	reject := func(line int, raw []byte, err error) {
+		result.Rejected++
+		if len(result.Errors) >= maxErrors {
+			return
+		}
+		if len(raw) > maxRawErrorBytes {
+			raw = raw[:maxRawErrorBytes]
+		}
+		result.Errors = append(result.Errors, ImportError{Line: line, Err: err, Raw: string(raw)})
+	}
+
+	// flush hands the pending batch to the sink unless this is a dry run
+	flush := func() error {
+		if opts.DryRun || len(batch) == 0 {
+			batch = batch[:0]
+			return nil
+		}
+		if err := dataSink.WriteBatch(ctx, batch); err != nil {
+			return fmt.Errorf("failed to write batch: %v", err)
+		}
+		result.Written += len(batch)
+		batch = batch[:0]
+		return nil
+	}
 
 	// Read items
-	for decoder.More() {
+	var truncated error
+	for {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return result, ctx.Err()
 		default:
 		}
 
-		var item interface{}
-		if err := decoder.Decode(&item); err != nil {
-			return err
+		item, raw, line, err := records.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !opts.DryRun && !opts.ContinueOnError {
+				return result, err
+			}
+			reject(line, raw, err)
+			if errors.Is(err, ErrImportTruncated) {
+				result.Truncated = true
+				truncated = err
+				break
+			}
+			continue
 		}
 
 		// Apply filter if provided
@@ -658,48 +957,196 @@ func (i *DefaultImporter) ImportBatch(ctx context.Context, r io.Reader, dataSink
 			continue
 		}
 
-		// Apply transform if provided
-		if opts.Transform != nil {
-			transformed, err := opts.Transform(item)
+		// Apply transforms if provided
+		if opts.hasTransform() {
+			transformed, err := opts.transform(item)
 			if err != nil {
-				common.Warn("[IMPEXP] Failed to transform item: %v", err)
+				common.Warn("[IMPEXP] Failed to transform item on line %d: %v", line, err)
+				reject(line, raw, err)
 				continue
 			}
 			item = transformed
 		}
 
+		result.Valid++
 		batch = append(batch, item)
 
 		// Write batch when full
 		if len(batch) >= opts.BatchSize {
-			if err := dataSink.WriteBatch(ctx, batch); err != nil {
-				return fmt.Errorf("failed to write batch: %v", err)
+			if err := flush(); err != nil {
+				return result, err
 			}
-			totalImported += len(batch)
-			batch = batch[:0]
 		}
 	}
 
 	// Write remaining items
-	if len(batch) > 0 {
-		if err := dataSink.WriteBatch(ctx, batch); err != nil {
-			return fmt.Errorf("failed to write final batch: %v", err)
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	if truncated != nil && !opts.DryRun {
+		common.Warn("[IMPEXP] Import stopped early after %d items", result.Written)
+		return result, truncated
+	}
+
+	if opts.DryRun {
+		common.Info("[IMPEXP] Dry run: %d valid items, %d errors", result.Valid, result.Rejected)
+	} else if result.Rejected > 0 {
+		common.Warn("[IMPEXP] Imported %d items, skipped %d", result.Written, result.Rejected)
+	} else {
+		common.Info("[IMPEXP] Imported %d items", result.Written)
+	}
+	return result, nil
+}
+
+// recordReader yields decoded records one at a time along with their raw
+// bytes when available. next returns io.EOF once the input is exhausted.
+type recordReader interface {
+	next() (item interface{}, raw []byte, line int, err error)
+}
+
+// newRecordReader inspects the first non-space byte to choose between a
+// JSON array and JSON Lines.
+func newRecordReader(r io.Reader) (recordReader, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON opening: %w", err)
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
 		}
-		totalImported += len(batch)
+		if err := br.UnreadByte(); err != nil {
+			return nil, err
+		}
+		if b != '[' {
+			return &jsonLinesReader{r: br}, nil
+		}
+		break
 	}
 
-	common.Info("[IMPEXP] Imported %d items", totalImported)
-	return nil
+	decoder := json.NewDecoder(br)
+	// Consume the opening bracket
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON opening: %w", err)
+	}
+	return &jsonArrayReader{decoder: decoder}, nil
+}
+
+// jsonArrayReader reads the elements of a JSON array
+type jsonArrayReader struct {
+	decoder *json.Decoder
+	count   int
+	done    bool
+}
+
+func (a *jsonArrayReader) next() (interface{}, []byte, int, error) {
+	if a.done || !a.decoder.More() {
+		return nil, nil, a.count, io.EOF
+	}
+	a.count++
+
+	var item interface{}
+	if err := a.decoder.Decode(&item); err != nil {
+		// The decoder cannot resynchronize after a syntax error
+		a.done = true
+		return nil, nil, a.count, fmt.Errorf("%w: %w", ErrImportTruncated, err)
+	}
+	return item, nil, a.count, nil
+}
+
+// jsonLinesReader reads one JSON value per line, skipping blank lines
+type jsonLinesReader struct {
+	r    *bufio.Reader
+	line int
+	done bool
+}
+
+func (j *jsonLinesReader) next() (interface{}, []byte, int, error) {
+	for {
+		if j.done {
+			return nil, nil, j.line, io.EOF
+		}
+		raw, err := j.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			// Read failures are not line-specific; stop after reporting
+			j.done = true
+			return nil, nil, j.line, fmt.Errorf("%w: %w", ErrImportTruncated, err)
+		}
+		if len(raw) == 0 && err == io.EOF {
+			return nil, nil, j.line, io.EOF
+		}
+		j.line++
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+
+		var item interface{}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, raw, j.line, err
+		}
+		return item, raw, j.line, nil
+	}
 }
 
 // importJSON imports data from JSON
 func (i *DefaultImporter) importJSON(r io.Reader, dest interface{}, opts *Options) error {
-	return json.NewDecoder(r).Decode(dest)
+	if opts.Envelope != nil {
+		records, err := opts.Envelope.unwrap(r)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(records)
+	}
+
+	if len(opts.FieldMap) == 0 {
+		return json.NewDecoder(r).Decode(dest)
+	}
+
+	// Decode generically so keys can be renamed before the typed decode
+	var raw interface{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(renameFields(raw, opts.FieldMap))
+	if err != nil {
+		return fmt.Errorf("failed to re-encode renamed JSON: %w", err)
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// renameFields applies fieldMap to the keys of a decoded JSON object, or to
+// each object of a decoded JSON array. Only top-level keys are renamed.
+func renameFields(v interface{}, fieldMap map[string]string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if renamed, ok := fieldMap[k]; ok {
+				k = renamed
+			}
+			out[k] = item
+		}
+		return out
+	case []interface{}:
+		for idx, item := range val {
+			val[idx] = renameFields(item, fieldMap)
+		}
+		return val
+	default:
+		return v
+	}
 }
 
-// importCSV imports data from CSV
+// importCSV imports data from CSV. The first row is the header; each
+// following row is decoded into an element of dest, which must be a pointer
+// to a slice of structs, struct pointers or maps. Struct fields are matched
+// by json tag or field name (case-insensitive).
 func (i *DefaultImporter) importCSV(r io.Reader, dest interface{}, opts *Options) error {
-	csvReader := csv.NewReader(r)
+	csvReader := csv.NewReader(stripBOM(r))
 	if opts.Delimiter != 0 {
 		csvReader.Comma = opts.Delimiter
 	}
@@ -709,12 +1156,169 @@ func (i *DefaultImporter) importCSV(r io.Reader, dest interface{}, opts *Options
 	if err != nil {
 		return err
 	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	headers := make([]string, len(records[0]))
+	for idx, h := range records[0] {
+		if renamed, ok := opts.FieldMap[h]; ok {
+			h = renamed
+		}
+		headers[idx] = h
+	}
+	rows := records[1:]
 
-	// Convert CSV records to destination type
-	// This is a simplified implementation
-	// In production, use reflection to handle various data types
+	if err := decodeCSVRows(headers, rows, dest); err != nil {
+		return err
+	}
 
-	common.Info("[IMPEXP] Imported %d CSV records", len(records))
+	common.Info("[IMPEXP] Imported %d CSV records", len(rows))
+	return nil
+}
+
+// decodeCSVRows appends one element per row to the slice pointed to by dest
+func decodeCSVRows(headers []string, rows [][]string, dest interface{}) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("CSV import requires a pointer to a slice, got %T", dest)
+	}
+	sliceVal := destVal.Elem()
+	elemType := sliceVal.Type().Elem()
+
+	for rowIdx, row := range rows {
+		elem, err := decodeCSVRow(headers, row, elemType)
+		if err != nil {
+			return fmt.Errorf("CSV row %d: %w", rowIdx+1, err)
+		}
+		sliceVal = reflect.Append(sliceVal, elem)
+	}
+	destVal.Elem().Set(sliceVal)
+	return nil
+}
+
+// decodeCSVRow builds a value of type typ from a single CSV row
+func decodeCSVRow(headers []string, row []string, typ reflect.Type) (reflect.Value, error) {
+	isPtr := typ.Kind() == reflect.Ptr
+	baseType := typ
+	if isPtr {
+		baseType = typ.Elem()
+	}
+
+	switch baseType.Kind() {
+	case reflect.Struct:
+		elem := reflect.New(baseType).Elem()
+		for idx, header := range headers {
+			if idx >= len(row) {
+				break
+			}
+			field, ok := findField(elem, header)
+			if !ok {
+				continue
+			}
+			if err := setFieldFromString(field, row[idx]); err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", header, err)
+			}
+		}
+		if isPtr {
+			return elem.Addr(), nil
+		}
+		return elem, nil
+	case reflect.Map:
+		if baseType.Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("unsupported map key type %s", baseType.Key())
+		}
+		m := reflect.MakeMapWithSize(baseType, len(headers))
+		for idx, header := range headers {
+			if idx >= len(row) {
+				break
+			}
+			val := reflect.ValueOf(row[idx])
+			if !val.Type().AssignableTo(baseType.Elem()) {
+				return reflect.Value{}, fmt.Errorf("unsupported map value type %s", baseType.Elem())
+			}
+			m.SetMapIndex(reflect.ValueOf(header).Convert(baseType.Key()), val)
+		}
+		if isPtr {
+			ptr := reflect.New(baseType)
+			ptr.Elem().Set(m)
+			return ptr, nil
+		}
+		return m, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported element type %s", typ)
+	}
+}
+
+// findField locates a settable struct field by json tag or name
+func findField(val reflect.Value, name string) (reflect.Value, bool) {
+	typ := val.Type()
+	for j := 0; j < typ.NumField(); j++ {
+		field := typ.Field(j)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if idx := strings.Index(tag, ","); idx != -1 {
+			tag = tag[:idx]
+		}
+		if tag == "-" {
+			continue
+		}
+		if strings.EqualFold(tag, name) || strings.EqualFold(field.Name, name) {
+			return val.Field(j), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setFieldFromString parses s into field according to the field's kind.
+// Empty strings leave the zero value in place.
+func setFieldFromString(field reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		// Fall back to JSON for composite types (slices, maps, nested structs)
+		return json.Unmarshal([]byte(s), field.Addr().Interface())
+	}
 	return nil
 }
 
@@ -725,7 +1329,9 @@ func (i *DefaultImporter) importZIP(ctx context.Context, r io.Reader, dest inter
 	return fmt.Errorf("ZIP import not fully implemented")
 }
 
-// Backup creates a full backup of data
+// Backup creates a full backup of data in a timestamped directory under
+// outputDir. Alongside one JSON file per source it writes a manifest with
+// each file's SHA-256 checksum and record count, checked by VerifyBackup.
 func Backup(ctx context.Context, sources map[string]DataSource, outputDir string) error {
 	timestamp := time.Now().Format("20060102-150405")
 	backupDir := filepath.Join(outputDir, fmt.Sprintf("backup-%s", timestamp))
@@ -734,10 +1340,15 @@ func Backup(ctx context.Context, sources map[string]DataSource, outputDir string
 		return fmt.Errorf("failed to create backup directory: %v", err)
 	}
 
-	exporter := NewExporter()
+	exporter := &DefaultExporter{}
+	manifest := &Manifest{
+		CreatedAt: time.Now().UTC(),
+		Files:     make(map[string]ManifestFile, len(sources)),
+	}
 
 	for name, source := range sources {
 		filename := filepath.Join(backupDir, fmt.Sprintf("%s.json", name))
+		var entry ManifestFile
 
 		// Use a closure to ensure proper file handling
 		// #nosec G304 -- filename is derived from application-controlled backupDir and map keys.
@@ -754,10 +1365,18 @@ func Backup(ctx context.Context, sources map[string]DataSource, outputDir string
 				BatchSize: 100,
 			}
 
-			if err := exporter.ExportBatch(ctx, source, file, opts); err != nil {
+			h := sha256.New()
+			counter := &countingWriter{w: io.MultiWriter(file, h)}
+			result, err := exporter.ExportBatchWithResult(ctx, source, counter, opts)
+			if err != nil {
 				return fmt.Errorf("failed to export %s: %v", name, err)
 			}
 
+			entry = ManifestFile{
+				SHA256:  hex.EncodeToString(h.Sum(nil)),
+				Size:    counter.n,
+				Records: result.Exported,
+			}
 			return nil
 		}()
 
@@ -765,15 +1384,29 @@ func Backup(ctx context.Context, sources map[string]DataSource, outputDir string
 			return err
 		}
 
+		manifest.Files[filepath.Base(filename)] = entry
 		common.Info("[BACKUP] Backed up %s to %s", name, filename)
 	}
 
+	if err := writeManifest(backupDir, manifest); err != nil {
+		return err
+	}
+
 	common.Info("[BACKUP] Backup completed in %s", backupDir)
 	return nil
 }
 
-// Restore restores data from a backup
+// Restore restores data from a backup. When the backup has a manifest it is
+// verified with VerifyBackup before anything is imported; older backups
+// without one are restored unverified.
 func Restore(ctx context.Context, backupDir string, sinks map[string]DataSink) error {
+	if err := VerifyBackup(backupDir); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		common.Warn("[RESTORE] No manifest in %s, restoring without verification", backupDir)
+	}
+
 	importer := NewImporter()
 
 	for name, sink := range sinks {
@@ -0,0 +1,497 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type legacyUser struct {
+	Name  string
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func TestImportJSONFieldMap(t *testing.T) {
+	legacy := `[
+		{"user_name": "Alice", "mail": "alice@example.com", "age": 30},
+		{"user_name": "Bob", "mail": "bob@example.com", "age": 41}
+	]`
+
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	var users []legacyUser
+	opts := &Options{FieldMap: map[string]string{
+		"user_name": "Name",
+		"mail":      "email",
+	}}
+	if err := NewImporter().ImportFile(context.Background(), path, &users, opts); err != nil {
+		t.Fatalf("ImportFile failed: %v", err)
+	}
+
+	want := []legacyUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 30},
+		{Name: "Bob", Email: "bob@example.com", Age: 41},
+	}
+	if len(users) != len(want) {
+		t.Fatalf("imported %d users, want %d", len(users), len(want))
+	}
+	for i := range want {
+		if users[i] != want[i] {
+			t.Errorf("users[%d] = %+v, want %+v", i, users[i], want[i])
+		}
+	}
+}
+
+func TestImportJSONWithoutFieldMap(t *testing.T) {
+	var user legacyUser
+	err := NewImporter().Import(context.Background(),
+		strings.NewReader(`{"user_name": "Alice", "age": 30}`), &user, &Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if user.Name != "" || user.Age != 30 {
+		t.Errorf("user = %+v, want unmapped name left empty", user)
+	}
+}
+
+func TestImportCSVFieldMap(t *testing.T) {
+	data := "user_name,mail,age\nAlice,alice@example.com,30\nBob,bob@example.com,\n"
+
+	var users []*legacyUser
+	opts := &Options{
+		Format:   FormatCSV,
+		FieldMap: map[string]string{"user_name": "Name", "mail": "email"},
+	}
+	if err := NewImporter().Import(context.Background(), strings.NewReader(data), &users, opts); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("imported %d users, want 2", len(users))
+	}
+	if *users[0] != (legacyUser{Name: "Alice", Email: "alice@example.com", Age: 30}) {
+		t.Errorf("users[0] = %+v", *users[0])
+	}
+	if *users[1] != (legacyUser{Name: "Bob", Email: "bob@example.com"}) {
+		t.Errorf("users[1] = %+v", *users[1])
+	}
+}
+
+func TestImportCSVIntoMaps(t *testing.T) {
+	var rows []map[string]string
+	opts := &Options{Format: FormatCSV, Delimiter: ';'}
+	if err := NewImporter().Import(context.Background(), strings.NewReader("a;b\n1;2\n"), &rows, opts); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["a"] != "1" || rows[0]["b"] != "2" {
+		t.Errorf("rows = %v", rows)
+	}
+}
+
+func TestImportCSVInvalidValue(t *testing.T) {
+	var users []legacyUser
+	err := NewImporter().Import(context.Background(),
+		strings.NewReader("age\nnot-a-number\n"), &users, &Options{Format: FormatCSV})
+	if err == nil {
+		t.Fatal("expected error for non-numeric age")
+	}
+}
+
+// recordingSink remembers every batch it receives
+type recordingSink struct {
+	items []interface{}
+	calls int
+}
+
+func (s *recordingSink) WriteBatch(ctx context.Context, batch []interface{}) error {
+	s.calls++
+	s.items = append(s.items, batch...)
+	return nil
+}
+
+func TestImportBatchDryRunJSONLines(t *testing.T) {
+	data := `{"name": "alice"}
+{"name": "bob"}
+{"name": "broken",
+
+{"name": "carol"}
+`
+	sink := &recordingSink{}
+	res, err := NewImporter().(*DefaultImporter).ImportBatchWithResult(
+		context.Background(), strings.NewReader(data), sink, &Options{DryRun: true, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("ImportBatchWithResult failed: %v", err)
+	}
+
+	if sink.calls != 0 {
+		t.Errorf("WriteBatch called %d times during dry run", sink.calls)
+	}
+	if res.Valid != 3 || res.Written != 0 {
+		t.Errorf("Valid = %d, Written = %d; want 3, 0", res.Valid, res.Written)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Line != 3 {
+		t.Fatalf("Errors = %v, want one error on line 3", res.Errors)
+	}
+	if !strings.HasPrefix(res.Errors[0].Error(), "line 3:") {
+		t.Errorf("error message = %q", res.Errors[0].Error())
+	}
+}
+
+func TestImportBatchDryRunJSONArray(t *testing.T) {
+	transform := func(item interface{}) (interface{}, error) {
+		if m, ok := item.(map[string]interface{}); ok && m["name"] == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		return item, nil
+	}
+
+	sink := &recordingSink{}
+	res, err := NewImporter().(*DefaultImporter).ImportBatchWithResult(context.Background(),
+		strings.NewReader(`[{"name": "alice"}, {"name": ""}, {"name": "bob"}]`), sink,
+		&Options{DryRun: true, Transform: transform})
+	if err != nil {
+		t.Fatalf("ImportBatchWithResult failed: %v", err)
+	}
+	if sink.calls != 0 {
+		t.Errorf("WriteBatch called %d times during dry run", sink.calls)
+	}
+	if res.Valid != 2 || len(res.Errors) != 1 || res.Errors[0].Line != 2 {
+		t.Errorf("result = %+v, want 2 valid and an error on record 2", res)
+	}
+}
+
+func TestImportBatchWritesWithoutDryRun(t *testing.T) {
+	sink := &recordingSink{}
+	err := NewImporter().ImportBatch(context.Background(),
+		strings.NewReader("{\"n\": 1}\n{\"n\": 2}\n{\"n\": 3}\n"), sink, &Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("ImportBatch failed: %v", err)
+	}
+	if len(sink.items) != 3 || sink.calls != 2 {
+		t.Errorf("sink got %d items in %d calls, want 3 in 2", len(sink.items), sink.calls)
+	}
+
+	// Without DryRun a malformed record still aborts the import
+	err = NewImporter().ImportBatch(context.Background(),
+		strings.NewReader("{\"n\": 1}\nnot json\n"), &recordingSink{}, nil)
+	if err == nil {
+		t.Error("expected decode error without DryRun")
+	}
+}
+
+func TestImportBatchContinueOnError(t *testing.T) {
+	data := `{"name": "alice"}
+not json
+{"name": "bob"}
+{"name": "broken",
+{"name": "carol"}
+[1, 2
+{"name": "dave"}
+`
+	sink := &recordingSink{}
+	res, err := NewImporter().(*DefaultImporter).ImportBatchWithResult(context.Background(),
+		strings.NewReader(data), sink, &Options{ContinueOnError: true, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("ImportBatchWithResult failed: %v", err)
+	}
+
+	if res.Valid != 4 || res.Written != 4 || len(sink.items) != 4 {
+		t.Errorf("Valid = %d, Written = %d, sink items = %d; want 4 each", res.Valid, res.Written, len(sink.items))
+	}
+	if res.Rejected != 3 {
+		t.Errorf("Rejected = %d, want 3", res.Rejected)
+	}
+
+	wantErrors := []struct {
+		line int
+		raw  string
+	}{
+		{2, "not json"},
+		{4, `{"name": "broken",`},
+		{6, "[1, 2"},
+	}
+	if len(res.Errors) != len(wantErrors) {
+		t.Fatalf("Errors = %v, want %d entries", res.Errors, len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		got := res.Errors[i]
+		if got.Line != want.line || got.Raw != want.raw || got.Err == nil {
+			t.Errorf("Errors[%d] = {Line: %d, Raw: %q, Err: %v}, want line %d raw %q", i, got.Line, got.Raw, got.Err, want.line, want.raw)
+		}
+	}
+}
+
+func TestImportBatchContinueOnErrorTruncatedArray(t *testing.T) {
+	data := `[{"name": "alice"}, {"name": "bob"}, {"name": oops}, {"name": "carol"}]`
+
+	tests := []struct {
+		name    string
+		opts    *Options
+		wantErr bool
+		written int
+	}{
+		{"ContinueOnError", &Options{ContinueOnError: true, BatchSize: 1}, true, 2},
+		{"DryRun", &Options{DryRun: true}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			res, err := NewImporter().(*DefaultImporter).ImportBatchWithResult(context.Background(),
+				strings.NewReader(data), sink, tt.opts)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrImportTruncated) {
+				t.Errorf("error = %v, want ErrImportTruncated", err)
+			}
+			if !res.Truncated {
+				t.Error("Expected the result to be marked truncated")
+			}
+			if res.Valid != 2 || res.Written != tt.written || len(sink.items) != tt.written {
+				t.Errorf("Valid = %d, Written = %d, sink items = %d; want 2, %d, %d",
+					res.Valid, res.Written, len(sink.items), tt.written, tt.written)
+			}
+			if res.Rejected != 1 || len(res.Errors) != 1 || res.Errors[0].Line != 3 {
+				t.Errorf("Errors = %v, want one error on record 3", res.Errors)
+			}
+		})
+	}
+}
+
+func TestImportBatchContinueOnErrorCap(t *testing.T) {
+	var data strings.Builder
+	for n := 0; n < 10; n++ {
+		data.WriteString("bad\n")
+	}
+	data.WriteString(`{"name": "alice"}` + "\n")
+	data.WriteString("{" + strings.Repeat("x", 2*maxRawErrorBytes) + "\n")
+
+	sink := &recordingSink{}
+	res, err := NewImporter().(*DefaultImporter).ImportBatchWithResult(context.Background(),
+		strings.NewReader(data.String()), sink, &Options{ContinueOnError: true, MaxErrors: 3})
+	if err != nil {
+		t.Fatalf("ImportBatchWithResult failed: %v", err)
+	}
+	if len(sink.items) != 1 {
+		t.Errorf("sink got %d items, want 1", len(sink.items))
+	}
+	if res.Rejected != 11 || len(res.Errors) != 3 {
+		t.Errorf("Rejected = %d, len(Errors) = %d; want 11, 3", res.Rejected, len(res.Errors))
+	}
+
+	// Raw is truncated for oversized records
+	res, _ = NewImporter().(*DefaultImporter).ImportBatchWithResult(context.Background(),
+		strings.NewReader("{"+strings.Repeat("x", 2*maxRawErrorBytes)+"\n"), &recordingSink{}, &Options{ContinueOnError: true})
+	if len(res.Errors) != 1 || len(res.Errors[0].Raw) != maxRawErrorBytes {
+		t.Errorf("expected one error with Raw truncated to %d bytes", maxRawErrorBytes)
+	}
+}
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip write failed: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	if _, err := zw.Create("data.json"); err != nil {
+		t.Fatalf("zip create failed: %v", err)
+	}
+	zw.Close()
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  Format
+		body  string // expected stream content after detection
+	}{
+		{"JSON object", []byte(`{"a": 1}`), FormatJSON, `{"a": 1}`},
+		{"JSON array with whitespace", []byte("\n  [1, 2]"), FormatJSON, "\n  [1, 2]"},
+		{"CSV", []byte("name,email\nalice,alice@example.com\n"), FormatCSV, "name,email\nalice,alice@example.com\n"},
+		{"ZIP", zipBuf.Bytes(), FormatZIP, zipBuf.String()},
+		{"gzip JSON", gzipBytes(t, `[{"a": 1}]`), FormatJSON, `[{"a": 1}]`},
+		{"gzip CSV", gzipBytes(t, "a,b\n1,2\n"), FormatCSV, "a,b\n1,2\n"},
+		{"empty", nil, FormatCSV, ""},
+		{"longer than sniff window", []byte("[" + strings.Repeat(" ", 2*sniffLen) + "]"), FormatJSON, "[" + strings.Repeat(" ", 2*sniffLen) + "]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, r, err := DetectFormat(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("DetectFormat failed: %v", err)
+			}
+			if format != tt.want {
+				t.Errorf("format = %q, want %q", format, tt.want)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading detected stream failed: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("stream content = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestImportDetectsFormat(t *testing.T) {
+	ctx := context.Background()
+
+	var fromJSON []legacyUser
+	if err := NewImporter().Import(ctx, strings.NewReader(`[{"Name": "Alice", "age": 30}]`), &fromJSON, nil); err != nil {
+		t.Fatalf("JSON Import failed: %v", err)
+	}
+	if len(fromJSON) != 1 || fromJSON[0].Name != "Alice" || fromJSON[0].Age != 30 {
+		t.Errorf("JSON users = %+v", fromJSON)
+	}
+
+	var fromCSV []map[string]string
+	if err := NewImporter().Import(ctx, strings.NewReader("a,b\n1,2\n"), &fromCSV, &Options{}); err != nil {
+		t.Fatalf("CSV Import failed: %v", err)
+	}
+	if len(fromCSV) != 1 || fromCSV[0]["a"] != "1" {
+		t.Errorf("CSV rows = %v", fromCSV)
+	}
+
+	var fromGzip []map[string]string
+	if err := NewImporter().Import(ctx, bytes.NewReader(gzipBytes(t, "a,b\n3,4\n")), &fromGzip, &Options{}); err != nil {
+		t.Fatalf("gzip Import failed: %v", err)
+	}
+	if len(fromGzip) != 1 || fromGzip[0]["b"] != "4" {
+		t.Errorf("gzip rows = %v", fromGzip)
+	}
+
+	// The caller's options are not modified by detection
+	opts := &Options{}
+	var rows []map[string]string
+	if err := NewImporter().Import(ctx, strings.NewReader("a\n1\n"), &rows, opts); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if opts.Format != "" {
+		t.Errorf("opts.Format = %q, want it left empty", opts.Format)
+	}
+}
+
+// transformStages returns rename and redact stages that record the order
+// they ran in. redact rejects records without an email.
+func transformStages(order *[]string) (rename, redact TransformFunc) {
+	rename = func(item interface{}) (interface{}, error) {
+		*order = append(*order, "rename")
+		m := item.(map[string]interface{})
+		m["name"] = m["user_name"]
+		delete(m, "user_name")
+		return m, nil
+	}
+	redact = func(item interface{}) (interface{}, error) {
+		*order = append(*order, "redact")
+		m := item.(map[string]interface{})
+		if m["email"] == nil {
+			return nil, fmt.Errorf("email is required")
+		}
+		m["email"] = "redacted@example.com"
+		return m, nil
+	}
+	return rename, redact
+}
+
+func TestImportBatchTransforms(t *testing.T) {
+	var order []string
+	rename, redact := transformStages(&order)
+	enrich := func(item interface{}) (interface{}, error) {
+		order = append(order, "enrich")
+		m := item.(map[string]interface{})
+		m["source"] = "legacy"
+		return m, nil
+	}
+
+	data := `{"user_name": "alice", "email": "alice@example.com"}
+{"user_name": "bob"}
+`
+	sink := &recordingSink{}
+	res, err := NewImporter().(*DefaultImporter).ImportBatchWithResult(context.Background(),
+		strings.NewReader(data), sink, &Options{Transforms: []TransformFunc{rename, redact}, Transform: enrich})
+	if err != nil {
+		t.Fatalf("ImportBatchWithResult failed: %v", err)
+	}
+
+	// bob fails in redact, so enrich never sees him
+	want := []string{"rename", "redact", "enrich", "rename", "redact"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("stage order = %v, want %v", order, want)
+	}
+	if res.Valid != 1 || res.Rejected != 1 || len(res.Errors) != 1 || res.Errors[0].Line != 2 {
+		t.Errorf("result = %+v, want 1 valid and a rejection on line 2", res)
+	}
+	if len(sink.items) != 1 {
+		t.Fatalf("sink got %d items, want 1", len(sink.items))
+	}
+	got := sink.items[0].(map[string]interface{})
+	if got["name"] != "alice" || got["email"] != "redacted@example.com" || got["source"] != "legacy" {
+		t.Errorf("item = %v, want renamed, redacted and enriched", got)
+	}
+}
+
+func TestExportBatchTransforms(t *testing.T) {
+	var order []string
+	rename, redact := transformStages(&order)
+
+	source := &sliceSource{items: []interface{}{
+		map[string]interface{}{"user_name": "alice", "email": "alice@example.com"},
+		map[string]interface{}{"user_name": "bob"},
+		map[string]interface{}{"user_name": "carol", "email": "carol@example.com"},
+	}}
+	var buf bytes.Buffer
+	err := NewExporter().ExportBatch(context.Background(), source, &buf,
+		&Options{Format: FormatJSON, Transforms: []TransformFunc{rename, redact}})
+	if err != nil {
+		t.Fatalf("ExportBatch failed: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "bob") || strings.Contains(out, "user_name") || strings.Contains(out, "alice@example.com") {
+		t.Errorf("output = %s, want bob skipped and others renamed and redacted", out)
+	}
+	if strings.Count(out, "redacted@example.com") != 2 {
+		t.Errorf("output = %s, want two redacted records", out)
+	}
+	if len(order) != 6 || order[0] != "rename" || order[1] != "redact" {
+		t.Errorf("stage order = %v, want rename before redact for each item", order)
+	}
+}
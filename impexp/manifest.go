@@ -0,0 +1,144 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest Backup writes next to the
+// exported files
+const ManifestFileName = "manifest.json"
+
+// ErrBackupCorrupted is returned by VerifyBackup and Restore when a backup
+// file is missing or does not match the checksum recorded in the manifest.
+var ErrBackupCorrupted = errors.New("impexp: backup does not match its manifest")
+
+// Manifest describes the files of a backup so they can be verified before
+// anything is imported
+type Manifest struct {
+	CreatedAt time.Time               `json:"created_at"`
+	Files     map[string]ManifestFile `json:"files"` // keyed by file name
+}
+
+// ManifestFile records the checksum and contents of one backup file
+type ManifestFile struct {
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+	Records int    `json:"records"`
+}
+
+// VerifyBackup checks every file listed in the backup's manifest against its
+// recorded size and SHA-256 checksum. Mismatches and missing files are
+// reported as ErrBackupCorrupted.
+func VerifyBackup(dir string) error {
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := manifest.Files[name]
+		if name != filepath.Base(name) {
+			return fmt.Errorf("%w: invalid file name %q", ErrBackupCorrupted, name)
+		}
+
+		sum, size, err := hashFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%w: %s is missing", ErrBackupCorrupted, name)
+			}
+			return fmt.Errorf("failed to read backup file %s: %v", name, err)
+		}
+		if size != want.Size {
+			return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrBackupCorrupted, name, size, want.Size)
+		}
+		if sum != want.SHA256 {
+			return fmt.Errorf("%w: %s checksum mismatch", ErrBackupCorrupted, name)
+		}
+	}
+	return nil
+}
+
+// readManifest loads the manifest of the backup in dir
+func readManifest(dir string) (*Manifest, error) {
+	// #nosec G304 -- dir is an application-controlled backup directory.
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %v", ErrBackupCorrupted, err)
+	}
+	return &manifest, nil
+}
+
+// writeManifest saves manifest into dir
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// hashFile returns the hex SHA-256 and size of the file at path
+func hashFile(path string) (string, int64, error) {
+	// #nosec G304 -- path is built from the backup directory and manifest entries.
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestBackup backs up two small sources and returns the backup directory
+func newTestBackup(t *testing.T) string {
+	t.Helper()
+	sources := map[string]DataSource{
+		"users":  &sliceSource{items: []interface{}{testUser{Name: "alice", Email: "alice@example.com"}, testUser{Name: "bob", Email: "bob@example.com"}}},
+		"orders": &sliceSource{items: []interface{}{map[string]interface{}{"id": "o1"}}},
+	}
+
+	dir := t.TempDir()
+	if err := Backup(context.Background(), sources, dir); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "backup-*"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one backup directory, got %v (%v)", matches, err)
+	}
+	return matches[0]
+}
+
+func TestBackupManifest(t *testing.T) {
+	dir := newTestBackup(t)
+
+	manifest, err := readManifest(dir)
+	if err != nil {
+		t.Fatalf("readManifest failed: %v", err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("manifest lists %d files, want 2", len(manifest.Files))
+	}
+	if got := manifest.Files["users.json"].Records; got != 2 {
+		t.Errorf("users.json records = %d, want 2", got)
+	}
+	if got := manifest.Files["orders.json"].Records; got != 1 {
+		t.Errorf("orders.json records = %d, want 1", got)
+	}
+
+	sum, size, err := hashFile(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatalf("hashFile failed: %v", err)
+	}
+	if entry := manifest.Files["users.json"]; entry.SHA256 != sum || entry.Size != size {
+		t.Errorf("manifest entry %+v does not match file (%s, %d)", entry, sum, size)
+	}
+
+	if err := VerifyBackup(dir); err != nil {
+		t.Errorf("VerifyBackup on a clean backup: %v", err)
+	}
+}
+
+func TestVerifyBackupCorrupted(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, dir string)
+	}{
+		{"truncated file", func(t *testing.T, dir string) {
+			path := filepath.Join(dir, "users.json")
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Truncate(path, info.Size()/2); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"modified file", func(t *testing.T, dir string) {
+			path := filepath.Join(dir, "users.json")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)/2] ^= 0x01
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"missing file", func(t *testing.T, dir string) {
+			if err := os.Remove(filepath.Join(dir, "orders.json")); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"invalid manifest", func(t *testing.T, dir string) {
+			if err := os.WriteFile(filepath.Join(dir, ManifestFileName), []byte("{"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestBackup(t)
+			tt.corrupt(t, dir)
+
+			if err := VerifyBackup(dir); !errors.Is(err, ErrBackupCorrupted) {
+				t.Fatalf("VerifyBackup error = %v, want ErrBackupCorrupted", err)
+			}
+
+			sink := &recordingSink{}
+			err := Restore(context.Background(), dir, map[string]DataSink{"users": sink, "orders": sink})
+			if !errors.Is(err, ErrBackupCorrupted) {
+				t.Errorf("Restore error = %v, want ErrBackupCorrupted", err)
+			}
+			if sink.calls != 0 {
+				t.Errorf("Restore imported %d batches from a corrupted backup", sink.calls)
+			}
+		})
+	}
+}
+
+func TestRestoreWithoutManifest(t *testing.T) {
+	dir := newTestBackup(t)
+	if err := os.Remove(filepath.Join(dir, ManifestFileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyBackup(dir); err == nil || errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("VerifyBackup error = %v, want a missing manifest error", err)
+	}
+
+	sink := &recordingSink{}
+	if err := Restore(context.Background(), dir, map[string]DataSink{"users": sink}); err != nil {
+		t.Fatalf("Restore of a backup without manifest failed: %v", err)
+	}
+	if len(sink.items) != 2 {
+		t.Errorf("restored %d items, want 2", len(sink.items))
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+// Parquet output is typed and columnar, which makes it much faster to load
+// into BigQuery than JSON or CSV. The schema is derived from the exported
+// struct type using the same column names as CSV headers.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+// parquetRowGroupRows is the number of rows buffered per Parquet row group
+const parquetRowGroupRows = 10000
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parquetColumn maps a struct field to a column of the record builder
+type parquetColumn struct {
+	index int // struct field index
+	name  string
+}
+
+// parquetEncoder streams structs of a single type into a Parquet file.
+//
+// Column types follow the struct fields: strings, booleans, integers and
+// floats map to their Parquet equivalents (unsigned integers are stored as
+// INT64), time.Time to a UTC microsecond TIMESTAMP and []byte to BINARY.
+// Pointer fields are nullable. Slices, maps and nested structs are stored as
+// JSON strings, which BigQuery can parse with JSON functions.
+type parquetEncoder struct {
+	typ     reflect.Type
+	columns []parquetColumn
+	builder *array.RecordBuilder
+	writer  *pqarrow.FileWriter
+	pending int
+}
+
+// parquetSink hides Close from the file writer, which would otherwise
+// close the caller's writer when the footer is written.
+type parquetSink struct {
+	io.Writer
+}
+
+// newParquetEncoder derives a schema from typ, a struct or pointer to struct
+func newParquetEncoder(w io.Writer, typ reflect.Type) (*parquetEncoder, error) {
+	if typ == nil {
+		return nil, fmt.Errorf("cannot derive a Parquet schema from nil")
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return nil, fmt.Errorf("Parquet export requires structs, got %s", typ)
+	}
+
+	var fields []arrow.Field
+	var columns []parquetColumn
+	seen := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := fieldHeader(field)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate Parquet column %q", name)
+		}
+		seen[name] = true
+
+		dataType, nullable, err := parquetType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", field.Name, err)
+		}
+		fields = append(fields, arrow.Field{Name: name, Type: dataType, Nullable: nullable})
+		columns = append(columns, parquetColumn{index: i, name: name})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("type %s has no exported fields", typ)
+	}
+
+	schema := arrow.NewSchema(fields, nil)
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	writer, err := pqarrow.NewFileWriter(schema, parquetSink{w}, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet writer: %v", err)
+	}
+
+	return &parquetEncoder{
+		typ:     typ,
+		columns: columns,
+		builder: array.NewRecordBuilder(memory.DefaultAllocator, schema),
+		writer:  writer,
+	}, nil
+}
+
+// parquetType maps a Go type to an Arrow type and nullability
+func parquetType(typ reflect.Type) (arrow.DataType, bool, error) {
+	if typ.Kind() == reflect.Ptr {
+		dataType, _, err := parquetType(typ.Elem())
+		return dataType, true, err
+	}
+	if typ == timeType {
+		return arrow.FixedWidthTypes.Timestamp_us, false, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return arrow.BinaryTypes.String, false, nil
+	case reflect.Bool:
+		return arrow.FixedWidthTypes.Boolean, false, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return arrow.PrimitiveTypes.Int64, false, nil
+	case reflect.Float32:
+		return arrow.PrimitiveTypes.Float32, false, nil
+	case reflect.Float64:
+		return arrow.PrimitiveTypes.Float64, false, nil
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return arrow.BinaryTypes.Binary, true, nil
+		}
+		return arrow.BinaryTypes.String, true, nil
+	case reflect.Array, reflect.Map, reflect.Struct, reflect.Interface:
+		return arrow.BinaryTypes.String, true, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+// encode appends one item, flushing a row group when enough rows are buffered
+func (p *parquetEncoder) encode(item interface{}) error {
+	val := reflect.ValueOf(item)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return fmt.Errorf("cannot export nil %s to Parquet", val.Type())
+		}
+		val = val.Elem()
+	}
+	if val.Type() != p.typ {
+		return fmt.Errorf("item type %s does not match Parquet schema type %s", val.Type(), p.typ)
+	}
+
+	for i, column := range p.columns {
+		if err := appendParquetValue(p.builder.Field(i), val.Field(column.index)); err != nil {
+			return fmt.Errorf("column %s: %v", column.name, err)
+		}
+	}
+
+	p.pending++
+	if p.pending >= parquetRowGroupRows {
+		return p.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group
+func (p *parquetEncoder) flush() error {
+	if p.pending == 0 {
+		return nil
+	}
+	record := p.builder.NewRecord()
+	defer record.Release()
+	p.pending = 0
+	if err := p.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write Parquet row group: %v", err)
+	}
+	return nil
+}
+
+// close flushes remaining rows and writes the file footer
+func (p *parquetEncoder) close() error {
+	defer p.builder.Release()
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file: %v", err)
+	}
+	return nil
+}
+
+// appendParquetValue appends a field value to its column builder
+func appendParquetValue(b array.Builder, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			b.AppendNull()
+			return nil
+		}
+		v = v.Elem()
+	case reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			b.AppendNull()
+			return nil
+		}
+	}
+
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		if v.Kind() == reflect.String {
+			b.Append(v.String())
+			return nil
+		}
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %v", err)
+		}
+		b.Append(string(data))
+	case *array.BooleanBuilder:
+		b.Append(v.Bool())
+	case *array.Int64Builder:
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v.Uint() > math.MaxInt64 {
+				return fmt.Errorf("value %d overflows INT64", v.Uint())
+			}
+			b.Append(int64(v.Uint()))
+		default:
+			b.Append(v.Int())
+		}
+	case *array.Float32Builder:
+		b.Append(float32(v.Float()))
+	case *array.Float64Builder:
+		b.Append(v.Float())
+	case *array.BinaryBuilder:
+		b.Append(v.Bytes())
+	case *array.TimestampBuilder:
+		b.Append(arrow.Timestamp(v.Interface().(time.Time).UnixMicro()))
+	default:
+		return fmt.Errorf("unsupported column builder %T", b)
+	}
+	return nil
+}
+
+// exportParquet writes a struct, or a slice or array of structs, as Parquet.
+// Filter and the transforms are applied to each element. The schema comes
+// from the element type, or from the first item when a transform is set or the
+// elements are interfaces, in which case there must be at least one item.
+func (e *DefaultExporter) exportParquet(data interface{}, w io.Writer, opts *Options) error {
+	val := reflect.ValueOf(data)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	var items []interface{}
+	var typ reflect.Type
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		typ = val.Type().Elem()
+		for i := 0; i < val.Len(); i++ {
+			items = append(items, val.Index(i).Interface())
+		}
+	case reflect.Struct:
+		typ = val.Type()
+		items = append(items, val.Interface())
+	default:
+		return fmt.Errorf("Parquet export not implemented for type %T", data)
+	}
+	if opts.hasTransform() || typ.Kind() == reflect.Interface {
+		typ = nil
+	}
+
+	var encoder *parquetEncoder
+	if typ != nil {
+		var err error
+		if encoder, err = newParquetEncoder(w, typ); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		if opts.Filter != nil && !opts.Filter(item) {
+			continue
+		}
+		if opts.hasTransform() {
+			transformed, err := opts.transform(item)
+			if err != nil {
+				return fmt.Errorf("failed to transform item: %v", err)
+			}
+			item = transformed
+		}
+		if encoder == nil {
+			var err error
+			if encoder, err = newParquetEncoder(w, reflect.TypeOf(item)); err != nil {
+				return err
+			}
+		}
+		if err := encoder.encode(item); err != nil {
+			return err
+		}
+	}
+
+	if encoder == nil {
+		return fmt.Errorf("no items to derive a Parquet schema from")
+	}
+	return encoder.close()
+}
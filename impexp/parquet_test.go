@@ -0,0 +1,225 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+type parquetRecord struct {
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Score    float64           `json:"score"`
+	Active   bool              `json:"active"`
+	Visits   uint32            `json:"visits"`
+	Created  time.Time         `json:"created"`
+	Nick     *string           `json:"nick,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Note     string
+	internal string
+}
+
+// readParquet loads a Parquet file into an Arrow table
+func readParquet(t *testing.T, data []byte) arrow.Table {
+	t.Helper()
+	table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(data),
+		parquet.NewReaderProperties(memory.DefaultAllocator), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("ReadTable failed: %v", err)
+	}
+	t.Cleanup(table.Release)
+	return table
+}
+
+// column returns the single chunk of a named column
+func column(t *testing.T, table arrow.Table, name string) arrow.Array {
+	t.Helper()
+	indices := table.Schema().FieldIndices(name)
+	if len(indices) != 1 {
+		t.Fatalf("column %q not found in %s", name, table.Schema())
+	}
+	chunks := table.Column(indices[0]).Data().Chunks()
+	if len(chunks) != 1 {
+		t.Fatalf("column %q has %d chunks, want 1", name, len(chunks))
+	}
+	return chunks[0]
+}
+
+func TestExportParquet(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	nick := "ace"
+	records := []parquetRecord{
+		{ID: 1, Name: "Ada", Score: 9.5, Active: true, Visits: 3, Created: created, Nick: &nick, Labels: map[string]string{"team": "core"}, Note: "first", internal: "skipped"},
+		{ID: 2, Name: "Bob", Score: 7.25, Visits: 0, Created: created.Add(time.Hour), Note: "second"},
+	}
+
+	var buf bytes.Buffer
+	if err := NewExporter().Export(context.Background(), records, &buf, &Options{Format: FormatParquet}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	table := readParquet(t, buf.Bytes())
+
+	// Schema follows the struct with CSV header names
+	wantSchema := []struct {
+		name     string
+		typ      arrow.Type
+		nullable bool
+	}{
+		{"id", arrow.INT64, false},
+		{"name", arrow.STRING, false},
+		{"score", arrow.FLOAT64, false},
+		{"active", arrow.BOOL, false},
+		{"visits", arrow.INT64, false},
+		{"created", arrow.TIMESTAMP, false},
+		{"nick", arrow.STRING, true},
+		{"labels", arrow.STRING, true},
+		{"Note", arrow.STRING, false},
+	}
+	fields := table.Schema().Fields()
+	if len(fields) != len(wantSchema) {
+		t.Fatalf("schema has %d fields, want %d: %s", len(fields), len(wantSchema), table.Schema())
+	}
+	for i, want := range wantSchema {
+		if fields[i].Name != want.name || fields[i].Type.ID() != want.typ || fields[i].Nullable != want.nullable {
+			t.Errorf("field %d = %s %s nullable=%v, want %s %s nullable=%v",
+				i, fields[i].Name, fields[i].Type, fields[i].Nullable, want.name, want.typ, want.nullable)
+		}
+	}
+
+	if table.NumRows() != 2 {
+		t.Fatalf("NumRows = %d, want 2", table.NumRows())
+	}
+	ids := column(t, table, "id").(*array.Int64)
+	names := column(t, table, "name").(*array.String)
+	scores := column(t, table, "score").(*array.Float64)
+	active := column(t, table, "active").(*array.Boolean)
+	visits := column(t, table, "visits").(*array.Int64)
+	times := column(t, table, "created").(*array.Timestamp)
+	nicks := column(t, table, "nick").(*array.String)
+	labels := column(t, table, "labels").(*array.String)
+
+	if ids.Value(1) != 2 || names.Value(0) != "Ada" || scores.Value(1) != 7.25 || !active.Value(0) || visits.Value(0) != 3 {
+		t.Errorf("unexpected values: id=%d name=%s score=%v active=%v visits=%d",
+			ids.Value(1), names.Value(0), scores.Value(1), active.Value(0), visits.Value(0))
+	}
+	if got := time.UnixMicro(int64(times.Value(0))).UTC(); !got.Equal(created) {
+		t.Errorf("created = %v, want %v", got, created)
+	}
+	if nicks.Value(0) != "ace" || !nicks.IsNull(1) {
+		t.Errorf("nick column = %v, want [ace null]", nicks)
+	}
+	if labels.Value(0) != `{"team":"core"}` || !labels.IsNull(1) {
+		t.Errorf("labels column = %v, want JSON then null", labels)
+	}
+}
+
+func TestExportParquetFilterTransform(t *testing.T) {
+	records := []parquetRecord{{ID: 1, Name: "ada"}, {ID: 2, Name: "bob"}, {ID: 3, Name: "cy"}}
+	opts := &Options{
+		Format: FormatParquet,
+		Filter: func(entity interface{}) bool { return entity.(parquetRecord).ID != 2 },
+		Transform: func(entity interface{}) (interface{}, error) {
+			r := entity.(parquetRecord)
+			return struct {
+				ID    int64  `json:"id"`
+				Upper string `json:"upper"`
+			}{r.ID, r.Name + "!"}, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewExporter().Export(context.Background(), records, &buf, opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	table := readParquet(t, buf.Bytes())
+	if table.NumCols() != 2 || table.NumRows() != 2 {
+		t.Fatalf("got %d cols x %d rows, want 2 x 2", table.NumCols(), table.NumRows())
+	}
+	upper := column(t, table, "upper").(*array.String)
+	if upper.Value(0) != "ada!" || upper.Value(1) != "cy!" {
+		t.Errorf("upper = %v", upper)
+	}
+}
+
+func TestExportBatchParquet(t *testing.T) {
+	source := &sliceSource{items: []interface{}{
+		&parquetRecord{ID: 1, Name: "Ada"},
+		&parquetRecord{ID: 2, Name: "Bob"},
+		&parquetRecord{ID: 3, Name: "Cy"},
+	}}
+	opts := &Options{
+		Format: FormatParquet,
+		Filter: func(entity interface{}) bool { return entity.(*parquetRecord).ID != 2 },
+	}
+
+	var buf bytes.Buffer
+	if err := NewExporter().ExportBatch(context.Background(), source, &buf, opts); err != nil {
+		t.Fatalf("ExportBatch failed: %v", err)
+	}
+	table := readParquet(t, buf.Bytes())
+	names := column(t, table, "name").(*array.String)
+	if names.Len() != 2 || names.Value(0) != "Ada" || names.Value(1) != "Cy" {
+		t.Errorf("names = %v, want [Ada Cy]", names)
+	}
+}
+
+func TestExportParquetEmptySlice(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewExporter().Export(context.Background(), []parquetRecord{}, &buf, &Options{Format: FormatParquet}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	table := readParquet(t, buf.Bytes())
+	if table.NumRows() != 0 || table.NumCols() != 9 {
+		t.Errorf("got %d cols x %d rows, want 9 x 0", table.NumCols(), table.NumRows())
+	}
+}
+
+func TestExportParquetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+	}{
+		{"maps", []map[string]string{{"a": "b"}}},
+		{"scalars", []int{1, 2}},
+		{"mixed types", []interface{}{parquetRecord{ID: 1}, csvRecord{Name: "x"}}},
+		{"unsupported field", []struct{ C chan int }{{}}},
+		{"no items", []interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewExporter().Export(context.Background(), tt.data, &buf, &Options{Format: FormatParquet}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	// A transform error aborts the export
+	boom := errors.New("boom")
+	opts := &Options{Format: FormatParquet, Transform: func(interface{}) (interface{}, error) { return nil, boom }}
+	if err := NewExporter().Export(context.Background(), []parquetRecord{{ID: 1}}, &bytes.Buffer{}, opts); err == nil {
+		t.Error("expected transform error")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/patdeg/common"
+)
+
+// Target is a place an export is written to and an import is read from,
+// such as a local file or a Cloud Storage object. Writers are closed when
+// the export completes; Close errors are returned, since object stores
+// commit the upload on Close.
+//
+// When an export fails, the context passed to NewWriter is canceled before
+// the writer is closed, and writers with a CloseWithError method (such as
+// *io.PipeWriter) are aborted with it instead of closed. Writers must not
+// commit in either case; Cloud Storage writers abort the upload when their
+// context is canceled.
+type Target interface {
+	// Name identifies the target in logs, and its extension selects the
+	// import format when Options.Format is empty
+	Name() string
+
+	// NewWriter creates or truncates the target
+	NewWriter(ctx context.Context) (io.WriteCloser, error)
+
+	// NewReader opens the target for reading
+	NewReader(ctx context.Context) (io.ReadCloser, error)
+}
+
+// FileTarget returns a Target for a local file. Missing parent directories
+// are created on write. Exports go to a temporary file in the same
+// directory that replaces the file only once complete, so a failed export
+// leaves the previous content in place.
+func FileTarget(filename string) Target {
+	return fileTarget(filename)
+}
+
+// fileTarget is a Target backed by a local file
+type fileTarget string
+
+func (f fileTarget) Name() string { return string(f) }
+
+func (f fileTarget) NewWriter(ctx context.Context) (io.WriteCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(string(f)), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+	// #nosec G304 -- filename is expected to be application-controlled, not raw user input.
+	file, err := os.CreateTemp(filepath.Dir(string(f)), filepath.Base(string(f))+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %v", err)
+	}
+	return &fileWriter{File: file, path: string(f)}, nil
+}
+
+// fileWriter writes to a temporary file that is renamed over path on Close
+type fileWriter struct {
+	*os.File
+	path string
+}
+
+// Close moves the completed file into place
+func (w *fileWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	if err := os.Rename(w.Name(), w.path); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	return nil
+}
+
+// CloseWithError discards the temporary file
+func (w *fileWriter) CloseWithError(err error) error {
+	w.File.Close()
+	return os.Remove(w.Name())
+}
+
+func (f fileTarget) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	// #nosec G304 -- filename is expected to be application-controlled. Validate if sourced from user input.
+	file, err := os.Open(string(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	return file, nil
+}
+
+// StreamTarget adapts reader and writer factories to a Target, so exports
+// can go to object storage without this package depending on its client:
+//
+//	obj := bucket.Object("exports/users.json")
+//	target := &impexp.StreamTarget{
+//	    Path: "gs://my-bucket/exports/users.json",
+//	    Writer: func(ctx context.Context) (io.WriteCloser, error) {
+//	        return obj.NewWriter(ctx), nil
+//	    },
+//	    Reader: func(ctx context.Context) (io.ReadCloser, error) {
+//	        return obj.NewReader(ctx)
+//	    },
+//	}
+//	err := exporter.ExportTo(ctx, users, target, opts)
+//
+// Either factory may be nil when the target is only written or only read.
+type StreamTarget struct {
+	Path   string
+	Writer func(ctx context.Context) (io.WriteCloser, error)
+	Reader func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Name returns Path
+func (s *StreamTarget) Name() string { return s.Path }
+
+// NewWriter calls the Writer factory
+func (s *StreamTarget) NewWriter(ctx context.Context) (io.WriteCloser, error) {
+	if s.Writer == nil {
+		return nil, fmt.Errorf("target %s is not writable", s.Path)
+	}
+	return s.Writer(ctx)
+}
+
+// NewReader calls the Reader factory
+func (s *StreamTarget) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	if s.Reader == nil {
+		return nil, fmt.Errorf("target %s is not readable", s.Path)
+	}
+	return s.Reader(ctx)
+}
+
+// abortWriter is implemented by writers that can discard what was written
+// instead of committing it
+type abortWriter interface {
+	CloseWithError(err error) error
+}
+
+// ExportTo exports data to target like Export. The target's writer is
+// closed only when the export succeeds, and a failed close fails the
+// export. On failure the writer is aborted as described on Target.
+func (e *DefaultExporter) ExportTo(ctx context.Context, data interface{}, target Target, opts *Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w, err := target.NewWriter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := e.Export(ctx, data, w, opts); err != nil {
+		cancel()
+		if a, ok := w.(abortWriter); ok {
+			a.CloseWithError(err)
+		} else {
+			w.Close()
+		}
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", target.Name(), err)
+	}
+
+	common.Info("[IMPEXP] Exported data to %s", target.Name())
+	return nil
+}
+
+// ImportFrom imports data from target like Import. When opts.Format is
+// empty it is taken from the target name's extension (.json, .csv, .zip),
+// falling back to detection from the content.
+func (i *DefaultImporter) ImportFrom(ctx context.Context, target Target, dest interface{}, opts *Options) error {
+	r, err := target.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Format == "" {
+		switch strings.ToLower(filepath.Ext(target.Name())) {
+		case ".json":
+			opts.Format = FormatJSON
+		case ".csv":
+			opts.Format = FormatCSV
+		case ".zip":
+			opts.Format = FormatZIP
+		}
+		// Other extensions are detected from the content by Import
+	}
+
+	if err := i.Import(ctx, r, dest, opts); err != nil {
+		return err
+	}
+
+	common.Info("[IMPEXP] Imported data from %s", target.Name())
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memoryObject is a closable buffer that becomes visible in its store only
+// on Close, like an object storage upload. As with Cloud Storage, canceling
+// the writer's context aborts the upload.
+type memoryObject struct {
+	bytes.Buffer
+	ctx     context.Context
+	store   map[string][]byte
+	name    string
+	failErr error
+}
+
+func (o *memoryObject) Close() error {
+	if o.failErr != nil {
+		return o.failErr
+	}
+	if err := o.ctx.Err(); err != nil {
+		return err
+	}
+	o.store[o.name] = o.Bytes()
+	return nil
+}
+
+// memoryTarget returns a StreamTarget over an in-memory object store
+func memoryTarget(store map[string][]byte, name string, closeErr error) *StreamTarget {
+	return &StreamTarget{
+		Path: "mem://" + name,
+		Writer: func(ctx context.Context) (io.WriteCloser, error) {
+			return &memoryObject{ctx: ctx, store: store, name: name, failErr: closeErr}, nil
+		},
+		Reader: func(ctx context.Context) (io.ReadCloser, error) {
+			data, ok := store[name]
+			if !ok {
+				return nil, errors.New("object not found")
+			}
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+func TestExportToImportFromMemoryTarget(t *testing.T) {
+	ctx := context.Background()
+	users := []legacyUser{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+
+	tests := []struct {
+		name   string
+		object string
+		opts   *Options
+	}{
+		{"JSON by extension", "users.json", &Options{Format: FormatJSON}},
+		{"CSV by extension", "users.csv", &Options{Format: FormatCSV}},
+		{"Gzip detected from content", "users.gz", &Options{Format: FormatJSON, Compress: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := make(map[string][]byte)
+			target := memoryTarget(store, tt.object, nil)
+
+			if err := NewExporter().ExportTo(ctx, users, target, tt.opts); err != nil {
+				t.Fatalf("ExportTo failed: %v", err)
+			}
+			if len(store[tt.object]) == 0 {
+				t.Fatal("Expected the object to be committed on close")
+			}
+
+			var got []legacyUser
+			if err := NewImporter().ImportFrom(ctx, target, &got, nil); err != nil {
+				t.Fatalf("ImportFrom failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, users) {
+				t.Errorf("Imported %+v, want %+v", got, users)
+			}
+		})
+	}
+}
+
+func TestExportToCloseError(t *testing.T) {
+	store := make(map[string][]byte)
+	target := memoryTarget(store, "users.json", errors.New("upload failed"))
+
+	err := NewExporter().ExportTo(context.Background(), []legacyUser{{Name: "Alice"}}, target, nil)
+	if err == nil || !strings.Contains(err.Error(), "upload failed") {
+		t.Fatalf("ExportTo error = %v, want the close error", err)
+	}
+	if _, ok := store["users.json"]; ok {
+		t.Error("Expected nothing to be committed")
+	}
+}
+
+func TestExportToFailureLeavesTarget(t *testing.T) {
+	ctx := context.Background()
+	// A channel cannot be encoded, so the export fails after it started
+	bad := []interface{}{legacyUser{Name: "Alice"}, make(chan int)}
+
+	t.Run("Object store", func(t *testing.T) {
+		store := map[string][]byte{"users.json": []byte("previous")}
+		target := memoryTarget(store, "users.json", nil)
+
+		if err := NewExporter().ExportTo(ctx, bad, target, nil); err == nil {
+			t.Fatal("Expected the export to fail")
+		}
+		if got := string(store["users.json"]); got != "previous" {
+			t.Errorf("Object = %q, want it untouched", got)
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		dir := t.TempDir()
+		filename := filepath.Join(dir, "users.json")
+		if err := os.WriteFile(filename, []byte("previous"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := NewExporter().ExportFile(ctx, bad, filename, nil); err == nil {
+			t.Fatal("Expected the export to fail")
+		}
+		if got, _ := os.ReadFile(filename); string(got) != "previous" {
+			t.Errorf("File = %q, want it untouched", got)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected the temporary file to be removed, found %d entries", len(entries))
+		}
+	})
+
+	t.Run("Pipe", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		target := &StreamTarget{
+			Path:   "pipe://users.json",
+			Writer: func(ctx context.Context) (io.WriteCloser, error) { return pw, nil },
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.ReadAll(pr)
+			done <- err
+		}()
+
+		exportErr := NewExporter().ExportTo(ctx, bad, target, nil)
+		if exportErr == nil {
+			t.Fatal("Expected the export to fail")
+		}
+		if err := <-done; err == nil {
+			t.Error("Expected the reader to see the export error instead of EOF")
+		}
+	})
+}
+
+func TestStreamTargetMissingFactory(t *testing.T) {
+	ctx := context.Background()
+	target := &StreamTarget{Path: "mem://write-only"}
+
+	if err := NewExporter().ExportTo(ctx, []legacyUser{}, target, nil); err == nil {
+		t.Error("Expected an error writing a target without a Writer")
+	}
+	var got []legacyUser
+	if err := NewImporter().ImportFrom(ctx, target, &got, nil); err == nil {
+		t.Error("Expected an error reading a target without a Reader")
+	}
+}
+
+func TestFileTargetCreatesDirectories(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "nested", "dir", "users.json")
+	users := []legacyUser{{Name: "Alice", Age: 30}}
+
+	if err := NewExporter().ExportFile(ctx, users, filename, nil); err != nil {
+		t.Fatalf("ExportFile failed: %v", err)
+	}
+	var got []legacyUser
+	if err := NewImporter().ImportFrom(ctx, FileTarget(filename), &got, nil); err != nil {
+		t.Fatalf("ImportFrom failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, users) {
+		t.Errorf("Imported %+v, want %+v", got, users)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultTimestampField is the item field read for Options.Since
+const DefaultTimestampField = "UpdatedAt"
+
+// timestampField returns the configured timestamp field or the default
+func (o *Options) timestampField() string {
+	if o.TimestampField != "" {
+		return o.TimestampField
+	}
+	return DefaultTimestampField
+}
+
+// itemTimestamp reads field from a struct (by name or JSON tag) or a map
+// with string keys, following pointers. ok is false when the field is
+// missing, zero or not a recognized time value.
+func itemTimestamp(item interface{}, field string) (time.Time, bool) {
+	val := reflect.ValueOf(item)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return time.Time{}, false
+		}
+		val = val.Elem()
+	}
+
+	var fieldVal reflect.Value
+	switch val.Kind() {
+	case reflect.Struct:
+		f, ok := findField(val, field)
+		if !ok {
+			return time.Time{}, false
+		}
+		fieldVal = f
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return time.Time{}, false
+		}
+		fieldVal = val.MapIndex(reflect.ValueOf(field).Convert(val.Type().Key()))
+		if !fieldVal.IsValid() {
+			// Fall back to a case-insensitive match, e.g. "updated_at" vs "Updated_At"
+			iter := val.MapRange()
+			for iter.Next() {
+				if strings.EqualFold(iter.Key().String(), field) {
+					fieldVal = iter.Value()
+					break
+				}
+			}
+		}
+		if !fieldVal.IsValid() {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	var ts time.Time
+	switch v := fieldVal.Interface().(type) {
+	case time.Time:
+		ts = v
+	case *time.Time:
+		if v != nil {
+			ts = *v
+		}
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		ts = parsed
+	}
+	return ts, !ts.IsZero()
+}
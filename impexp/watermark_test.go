@@ -0,0 +1,171 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impexp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type deltaRecord struct {
+	ID       string    `json:"id"`
+	Modified time.Time `json:"modified_at"`
+}
+
+type deltaRecordPtr struct {
+	ID        string     `json:"id"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func TestExportBatchSince(t *testing.T) {
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name          string
+		items         []interface{}
+		opts          Options
+		wantIDs       []string
+		wantWatermark time.Time
+	}{
+		{
+			name: "Struct field by JSON tag",
+			items: []interface{}{
+				deltaRecord{ID: "old", Modified: at(-2)},
+				deltaRecord{ID: "edge", Modified: at(0)},
+				&deltaRecord{ID: "new", Modified: at(3)},
+				deltaRecord{ID: "newer", Modified: at(1)},
+			},
+			opts:          Options{Since: base, TimestampField: "modified_at"},
+			wantIDs:       []string{"edge", "new", "newer"},
+			wantWatermark: at(3),
+		},
+		{
+			name: "Default UpdatedAt field with pointers",
+			items: []interface{}{
+				deltaRecordPtr{ID: "old", UpdatedAt: ptr(at(-1))},
+				deltaRecordPtr{ID: "new", UpdatedAt: ptr(at(2))},
+				deltaRecordPtr{ID: "unknown"},
+			},
+			opts:          Options{Since: base},
+			wantIDs:       []string{"new", "unknown"},
+			wantWatermark: at(2),
+		},
+		{
+			name: "Map items with RFC 3339 strings",
+			items: []interface{}{
+				map[string]interface{}{"id": "old", "updated_at": at(-5).Format(time.RFC3339)},
+				map[string]interface{}{"id": "new", "updated_at": at(5).Format(time.RFC3339)},
+			},
+			opts:          Options{Since: base, TimestampField: "updated_at"},
+			wantIDs:       []string{"new"},
+			wantWatermark: at(5),
+		},
+		{
+			name: "Combined with filter",
+			items: []interface{}{
+				deltaRecord{ID: "old", Modified: at(-1)},
+				deltaRecord{ID: "skip", Modified: at(4)},
+				deltaRecord{ID: "keep", Modified: at(2)},
+			},
+			opts: Options{Since: base, TimestampField: "Modified", Filter: func(item interface{}) bool {
+				return item.(deltaRecord).ID != "skip"
+			}},
+			wantIDs:       []string{"keep"},
+			wantWatermark: at(2),
+		},
+		{
+			name:          "Nothing changed keeps the watermark",
+			items:         []interface{}{deltaRecord{ID: "old", Modified: at(-1)}},
+			opts:          Options{Since: base, TimestampField: "modified_at"},
+			wantIDs:       []string{},
+			wantWatermark: base,
+		},
+		{
+			name: "No Since exports everything",
+			items: []interface{}{
+				deltaRecord{ID: "a", Modified: at(-1)},
+				deltaRecord{ID: "b", Modified: at(1)},
+			},
+			opts:          Options{TimestampField: "modified_at"},
+			wantIDs:       []string{"a", "b"},
+			wantWatermark: at(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Format = FormatJSON
+
+			var buf bytes.Buffer
+			exporter := &DefaultExporter{}
+			res, err := exporter.ExportBatchWithResult(context.Background(), &sliceSource{items: tt.items}, &buf, &opts)
+			if err != nil {
+				t.Fatalf("ExportBatchWithResult failed: %v", err)
+			}
+
+			var got []struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output is not a JSON array: %v\n%s", err, buf.String())
+			}
+			if len(got) != len(tt.wantIDs) || res.Exported != len(tt.wantIDs) {
+				t.Fatalf("exported %d items (result %d), want %v", len(got), res.Exported, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].ID != id {
+					t.Errorf("item %d = %s, want %s", i, got[i].ID, id)
+				}
+			}
+			if !res.Watermark.Equal(tt.wantWatermark) {
+				t.Errorf("Watermark = %v, want %v", res.Watermark, tt.wantWatermark)
+			}
+		})
+	}
+}
+
+func TestItemTimestamp(t *testing.T) {
+	ts := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		item   interface{}
+		field  string
+		wantOK bool
+	}{
+		{"Struct by name", deltaRecord{Modified: ts}, "Modified", true},
+		{"Struct by tag", &deltaRecord{Modified: ts}, "modified_at", true},
+		{"Zero time", deltaRecord{}, "Modified", false},
+		{"Missing field", deltaRecord{Modified: ts}, "created_at", false},
+		{"Map case-insensitive", map[string]interface{}{"UpdatedAt": ts}, "updatedat", true},
+		{"Unparseable string", map[string]interface{}{"UpdatedAt": "yesterday"}, "UpdatedAt", false},
+		{"Not a struct or map", "text", "UpdatedAt", false},
+		{"Nil pointer", (*deltaRecord)(nil), "Modified", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := itemTimestamp(tt.item, tt.field)
+			if ok != tt.wantOK || (ok && !got.Equal(ts)) {
+				t.Errorf("itemTimestamp() = %v, %v; want ok %v", got, ok, tt.wantOK)
+			}
+		})
+	}
+}
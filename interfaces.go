@@ -21,9 +21,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"golang.org/x/net/context"
 )
@@ -90,13 +93,22 @@ func WriteJSON(w http.ResponseWriter, d interface{}) error {
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(jsonData)
-	return nil
+	return err
 }
 
+// WriteJSONWithStatus writes d as a JSON response with statusCode. The
+// value is encoded before anything is sent, so an encoding error produces
+// a 500 with the WriteError body instead of a truncated response; the
+// error is returned for logging:
+//
+//	if err := WriteJSONWithStatus(w, http.StatusCreated, invoice); err != nil { ... }
 func WriteJSONWithStatus(w http.ResponseWriter, statusCode int, d interface{}) error {
 	jsonData, err := json.Marshal(d)
 	if err != nil {
-		return err
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"internal server error","code":"internal"}`))
+		return fmt.Errorf("failed to encode JSON response: %v", err)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -113,6 +125,76 @@ func ReadJSON(b []byte, d interface{}) error {
 	return json.Unmarshal(b, d)
 }
 
+// DefaultMaxJSONBytes bounds request bodies read by ReadJSONRequest when
+// maxBytes is not positive
+const DefaultMaxJSONBytes = 1 << 20
+
+// ReadJSONRequest decodes the JSON body of r into dst. Unlike
+// UnmarshalRequest it reads at most maxBytes (DefaultMaxJSONBytes when not
+// positive), rejects fields dst does not declare and requires exactly one
+// JSON value. Errors are AppErrors with client-safe messages, so handlers
+// can pass them straight to WriteError:
+//
+//	var in CreateInvoice
+//	if err := ReadJSONRequest(r, &in, 64<<10); err != nil {
+//		WriteError(w, err)
+//		return
+//	}
+//
+// Oversized bodies map to 413, everything else to 400.
+func ReadJSONRequest(r *http.Request, dst interface{}, maxBytes int64) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxJSONBytes
+	}
+	if r.Body == nil {
+		return BadRequest("request body is empty")
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return jsonRequestError(err, maxBytes)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return jsonRequestError(err, maxBytes)
+		}
+		return BadRequest("request body must contain a single JSON value")
+	}
+	return nil
+}
+
+// jsonRequestError converts a decoding error into an AppError naming the
+// problem without echoing the body
+func jsonRequestError(err error, maxBytes int64) *AppError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxErr):
+		return NewAppError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxBytes)).WithCause(err)
+	case errors.Is(err, io.EOF):
+		return BadRequest("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest("request body contains incomplete JSON").WithCause(err)
+	case errors.As(err, &syntaxErr):
+		return BadRequest(fmt.Sprintf("request body contains malformed JSON at offset %d", syntaxErr.Offset)).WithCause(err)
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return BadRequest(fmt.Sprintf("field %q must be %s", typeErr.Field, typeErr.Type)).WithCause(err)
+		}
+		return BadRequest(fmt.Sprintf("request body must be %s", typeErr.Type)).WithCause(err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		return BadRequest("request body contains unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")).WithCause(err)
+	default:
+		return BadRequest("request body is not valid JSON").WithCause(err)
+	}
+}
+
 // UnmarshalResponse dumps the response to the debug log, reads the body and
 // unmarshals JSON into value. Example:
 //
@@ -0,0 +1,94 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWriteJSONWithStatus checks the status, Content-Type and encode failures
+func TestWriteJSONWithStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := WriteJSONWithStatus(rec, http.StatusCreated, map[string]int{"id": 7}); err != nil {
+		t.Fatalf("WriteJSONWithStatus failed: %v", err)
+	}
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json" || rec.Body.String() != `{"id":7}` {
+		t.Errorf("got %d %q %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	if err := WriteJSONWithStatus(rec, http.StatusOK, map[string]interface{}{"fn": func() {}}); err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"code":"internal"`) {
+		t.Errorf("expected a 500 error body, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestReadJSONRequest covers decoding and the client errors it reports
+func TestReadJSONRequest(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		maxBytes   int64
+		wantStatus int
+		wantMsg    string
+	}{
+		{name: "Valid", body: `{"name":"widget","count":2}`, wantStatus: http.StatusOK},
+		{name: "Trailing whitespace", body: "{\"name\":\"widget\"}\n", wantStatus: http.StatusOK},
+		{name: "Oversized", body: `{"name":"` + strings.Repeat("x", 100) + `"}`, maxBytes: 32, wantStatus: http.StatusRequestEntityTooLarge, wantMsg: "must not exceed 32 bytes"},
+		{name: "Unknown field", body: `{"name":"widget","admin":true}`, wantStatus: http.StatusBadRequest, wantMsg: `unknown field "admin"`},
+		{name: "Empty", body: "", wantStatus: http.StatusBadRequest, wantMsg: "empty"},
+		{name: "Malformed", body: `{"name":}`, wantStatus: http.StatusBadRequest, wantMsg: "malformed JSON"},
+		{name: "Truncated", body: `{"name":"widget"`, wantStatus: http.StatusBadRequest, wantMsg: "incomplete JSON"},
+		{name: "Wrong type", body: `{"count":"two"}`, wantStatus: http.StatusBadRequest, wantMsg: `field "count" must be int`},
+		{name: "Two values", body: `{"name":"a"}{"name":"b"}`, wantStatus: http.StatusBadRequest, wantMsg: "single JSON value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			var got payload
+			err := ReadJSONRequest(r, &got, tt.maxBytes)
+
+			if tt.wantStatus == http.StatusOK {
+				if err != nil {
+					t.Fatalf("ReadJSONRequest failed: %v", err)
+				}
+				if got.Name != "widget" {
+					t.Errorf("Name = %q, want widget", got.Name)
+				}
+				return
+			}
+
+			var appErr *AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("expected an AppError, got %v", err)
+			}
+			if appErr.Status != tt.wantStatus || !strings.Contains(appErr.Message, tt.wantMsg) {
+				t.Errorf("got %d %q, want %d containing %q", appErr.Status, appErr.Message, tt.wantStatus, tt.wantMsg)
+			}
+		})
+	}
+}
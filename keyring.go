@@ -0,0 +1,197 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// Encrypt and Decrypt take a single key, so rotating that key makes every
+// stored ciphertext unreadable. A Keyring holds several named keys instead:
+// it always encrypts with the primary key and prefixes the ciphertext with
+// that key's ID, so data written before a rotation still decrypts with the
+// older key until it is re-encrypted.
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// KeyringEnvVar is the default environment variable read by KeyringFromEnv
+const KeyringEnvVar = "ENCRYPTION_KEYS"
+
+// keyIDSeparator separates the key ID from the hex payload
+const keyIDSeparator = ":"
+
+// keyIDPattern restricts key IDs so they cannot contain the separator
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ErrUnknownKeyID is returned when a ciphertext names a key the keyring
+// does not hold, typically because the key was retired too early.
+var ErrUnknownKeyID = errors.New("unknown encryption key ID")
+
+// Keyring encrypts with a primary key and decrypts with any of its keys.
+// Ciphertexts have the form "<keyID>:<hex nonce+ciphertext>"; the key ID is
+// authenticated, so it cannot be swapped without failing decryption.
+// Values produced by the unversioned Encrypt are also accepted by Decrypt,
+// which tries each key in turn, easing migration to a keyring.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+	order   []string
+}
+
+// NewKeyring creates a keyring from key ID to secret. Secrets are derived
+// into AES-256 keys the same way as Encrypt, so an existing Encrypt secret
+// can be added under an ID. primary names the key used for new ciphertexts.
+func NewKeyring(primary string, keys map[string]string) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primary)
+	}
+
+	k := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	k.order = append(k.order, primary)
+	for id, secret := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid key ID %q: use up to 32 letters, digits, '-' or '_'", id)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("empty secret for key %q", id)
+		}
+		gcm, err := newGCM(secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher for key %q: %v", id, err)
+		}
+		k.keys[id] = gcm
+		if id != primary {
+			k.order = append(k.order, id)
+		}
+	}
+	return k, nil
+}
+
+// KeyringFromEnv builds a keyring from an environment variable holding a
+// comma-separated list of id:secret pairs. The first pair is the primary
+// key, so a rotation prepends the new key and keeps the old ones listed:
+//
+//	ENCRYPTION_KEYS="k2:<new secret>,k1:<old secret>"
+//
+// An empty envVar reads KeyringEnvVar.
+func KeyringFromEnv(envVar string) (*Keyring, error) {
+	if envVar == "" {
+		envVar = KeyringEnvVar
+	}
+	value := strings.TrimSpace(os.Getenv(envVar))
+	if value == "" {
+		return nil, fmt.Errorf("%s is not set", envVar)
+	}
+
+	var primary string
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), keyIDSeparator)
+		if !ok {
+			return nil, fmt.Errorf("%s: entry without key ID", envVar)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("%s: duplicate key ID %q", envVar, id)
+		}
+		if primary == "" {
+			primary = id
+		}
+		keys[id] = secret
+	}
+	return NewKeyring(primary, keys)
+}
+
+// PrimaryKeyID returns the ID of the key used for new ciphertexts
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// Encrypt encrypts plaintext with the primary key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	gcm := k.keys[k.primary]
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(k.primary))
+	return k.primary + keyIDSeparator + hex.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt with any key in the
+// keyring, or an unversioned value produced by the package-level Encrypt.
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	id, payload, versioned := strings.Cut(ciphertext, keyIDSeparator)
+	if !versioned {
+		return k.decryptLegacy(ciphertext)
+	}
+
+	gcm, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+	plaintext, err := openHex(gcm, payload, []byte(id))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether ciphertext was not written with the primary
+// key, so callers can re-encrypt stored values after a rotation.
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	id, _, versioned := strings.Cut(ciphertext, keyIDSeparator)
+	return !versioned || id != k.primary
+}
+
+// decryptLegacy tries every key on a value without a key ID
+func (k *Keyring) decryptLegacy(ciphertext string) (string, error) {
+	for _, id := range k.order {
+		if plaintext, err := openHex(k.keys[id], ciphertext, nil); err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", errors.New("failed to decrypt: no key matches")
+}
+
+// newGCM returns an AES-256-GCM cipher for a secret derived with deriveKey
+func newGCM(secret string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// openHex decodes a hex nonce+ciphertext and opens it
+func openHex(gcm cipher.AEAD, payload string, additionalData []byte) ([]byte, error) {
+	data, err := hex.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %v", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %v", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeyringRoundTrip(t *testing.T) {
+	k, err := NewKeyring("k1", map[string]string{"k1": "first passphrase"})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+
+	for _, msg := range []string{"hello world", "", "unicode ✓"} {
+		enc, err := k.Encrypt(msg)
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+		if !strings.HasPrefix(enc, "k1:") {
+			t.Errorf("ciphertext %q lacks key ID prefix", enc)
+		}
+		dec, err := k.Decrypt(enc)
+		if err != nil {
+			t.Fatalf("Decrypt failed: %v", err)
+		}
+		if dec != msg {
+			t.Errorf("Decrypt = %q, want %q", dec, msg)
+		}
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	before, err := NewKeyring("k1", map[string]string{"k1": "first passphrase"})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	old, err := before.Encrypt("stored before rotation")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	after, err := NewKeyring("k2", map[string]string{"k1": "first passphrase", "k2": "second passphrase"})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	dec, err := after.Decrypt(old)
+	if err != nil || dec != "stored before rotation" {
+		t.Fatalf("Decrypt(old) = %q, %v", dec, err)
+	}
+	if !after.NeedsRotation(old) {
+		t.Error("expected old ciphertext to need rotation")
+	}
+
+	fresh, err := after.Encrypt("stored after rotation")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !strings.HasPrefix(fresh, "k2:") || after.NeedsRotation(fresh) {
+		t.Errorf("expected new ciphertext under primary key, got %q", fresh)
+	}
+
+	// Once k1 is retired, old data fails with a clear error
+	retired, err := NewKeyring("k2", map[string]string{"k2": "second passphrase"})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	if _, err := retired.Decrypt(old); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("Decrypt with retired key = %v, want ErrUnknownKeyID", err)
+	}
+}
+
+func TestKeyringRejectsTampering(t *testing.T) {
+	k, err := NewKeyring("k2", map[string]string{"k1": "first passphrase", "k2": "second passphrase"})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	enc, err := k.Encrypt("payload")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	_, payload, _ := strings.Cut(enc, ":")
+
+	// Flip the last hex digit of the authentication tag
+	last := payload[len(payload)-1]
+	flipped := byte('0')
+	if last == '0' {
+		flipped = '1'
+	}
+
+	tests := []struct {
+		name       string
+		ciphertext string
+	}{
+		{"modified tag", "k2:" + payload[:len(payload)-1] + string(flipped)},
+		{"swapped key ID", "k1:" + payload},
+		{"truncated", "k2:" + payload[:10]},
+		{"not hex", "k2:zz" + payload[2:]},
+		{"unversioned garbage", "deadbeef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if dec, err := k.Decrypt(tt.ciphertext); err == nil {
+				t.Errorf("expected error, got %q", dec)
+			}
+		})
+	}
+}
+
+func TestKeyringDecryptsLegacyEncrypt(t *testing.T) {
+	legacy := Encrypt(context.Background(), "first passphrase", "legacy value")
+	k, err := NewKeyring("k2", map[string]string{"k1": "first passphrase", "k2": "second passphrase"})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	dec, err := k.Decrypt(legacy)
+	if err != nil || dec != "legacy value" {
+		t.Fatalf("Decrypt(legacy) = %q, %v", dec, err)
+	}
+	if !k.NeedsRotation(legacy) {
+		t.Error("expected legacy ciphertext to need rotation")
+	}
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary string
+		keys    map[string]string
+	}{
+		{"missing primary", "k3", map[string]string{"k1": "a"}},
+		{"separator in ID", "k:1", map[string]string{"k:1": "a"}},
+		{"empty secret", "k1", map[string]string{"k1": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.primary, tt.keys); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestKeyringFromEnv(t *testing.T) {
+	t.Setenv("TEST_ENCRYPTION_KEYS", "k2:second passphrase, k1:first passphrase")
+	k, err := KeyringFromEnv("TEST_ENCRYPTION_KEYS")
+	if err != nil {
+		t.Fatalf("KeyringFromEnv failed: %v", err)
+	}
+	if k.PrimaryKeyID() != "k2" {
+		t.Errorf("PrimaryKeyID = %q, want k2", k.PrimaryKeyID())
+	}
+
+	for _, value := range []string{"", "no-id", "k1:a,k1:b"} {
+		t.Setenv("TEST_ENCRYPTION_KEYS", value)
+		if _, err := KeyringFromEnv("TEST_ENCRYPTION_KEYS"); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// Background goroutines (cache cleanup, queue processors, health checks)
+// outlive the request that created them. LifecycleManager gives them a single
+// place to start and stop so tests and redeploys do not leak them.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// LifecycleHook is a component managed by a LifecycleManager. Either function
+// may be nil.
+type LifecycleHook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// LifecycleManager coordinates startup and graceful shutdown of background
+// components:
+//
+//	lm := common.NewLifecycleManager()
+//	store := csrf.NewTokenStore()
+//	lm.Register(common.LifecycleHook{Name: "csrf", Stop: func(context.Context) error {
+//	    store.Stop()
+//	    return nil
+//	}})
+//	lm.Go("refresh", func(ctx context.Context) { refreshLoop(ctx) })
+//	...
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	lm.Shutdown(ctx)
+//
+// It is safe for concurrent use.
+type LifecycleManager struct {
+	mu       sync.Mutex
+	hooks    []LifecycleHook
+	started  int // number of hooks whose Start has run
+	running  bool
+	stopping bool
+
+	ctx    context.Context // cancelled on Shutdown to stop Go routines
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewLifecycleManager creates an empty manager
+func NewLifecycleManager() *LifecycleManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LifecycleManager{ctx: ctx, cancel: cancel}
+}
+
+// Register adds a hook. Hooks registered after Start are considered started
+// and only their Stop function is used. Registering after Shutdown returns
+// an error.
+func (m *LifecycleManager) Register(hook LifecycleHook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopping {
+		return fmt.Errorf("lifecycle: cannot register %q after shutdown", hook.Name)
+	}
+	m.hooks = append(m.hooks, hook)
+	if m.running {
+		m.started = len(m.hooks)
+	}
+	return nil
+}
+
+// Go runs fn in a goroutine tracked by the manager. The context passed to fn
+// is cancelled when Shutdown begins, and Shutdown waits for fn to return.
+func (m *LifecycleManager) Go(name string, fn func(ctx context.Context)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopping {
+		return fmt.Errorf("lifecycle: cannot start %q after shutdown", name)
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		fn(m.ctx)
+	}()
+	return nil
+}
+
+// Start runs the Start functions of registered hooks in registration order.
+// If one fails, hooks already started are stopped in reverse order and the
+// error is returned.
+func (m *LifecycleManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := append([]LifecycleHook(nil), m.hooks[m.started:]...)
+	offset := m.started
+	m.mu.Unlock()
+
+	for i, hook := range hooks {
+		if hook.Start == nil {
+			continue
+		}
+		if err := hook.Start(ctx); err != nil {
+			Error("[LIFECYCLE] Failed to start %s: %v", hook.Name, err)
+			for j := i - 1; j >= 0; j-- {
+				if hooks[j].Stop != nil {
+					if stopErr := hooks[j].Stop(ctx); stopErr != nil {
+						Warn("[LIFECYCLE] Failed to stop %s after startup error: %v", hooks[j].Name, stopErr)
+					}
+				}
+			}
+			return fmt.Errorf("lifecycle: start %s: %w", hook.Name, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.started = offset + len(hooks)
+	m.running = true
+	m.mu.Unlock()
+	return nil
+}
+
+// Shutdown cancels goroutines started with Go and invokes every Stop
+// function concurrently, then waits for all of them. It returns early with
+// the context error if ctx expires first; components still running are left
+// to finish in the background. Errors returned by Stop functions are joined.
+// Calling Shutdown more than once is a no-op.
+func (m *LifecycleManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopping = true
+	hooks := append([]LifecycleHook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	m.cancel()
+
+	var (
+		errMu sync.Mutex
+		errs  []error
+		wg    sync.WaitGroup
+	)
+	for _, hook := range hooks {
+		if hook.Stop == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(hook LifecycleHook) {
+			defer wg.Done()
+			if err := hook.Stop(ctx); err != nil {
+				errMu.Lock()
+				errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+				errMu.Unlock()
+			}
+		}(hook)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		Warn("[LIFECYCLE] Shutdown interrupted: %v", ctx.Err())
+		return fmt.Errorf("lifecycle: shutdown: %w", ctx.Err())
+	}
+
+	errMu.Lock()
+	defer errMu.Unlock()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestLifecycleShutdownInvokesAllStoppers checks every Stop hook runs and
+// goroutines started with Go observe cancellation.
+func TestLifecycleShutdownInvokesAllStoppers(t *testing.T) {
+	lm := NewLifecycleManager()
+
+	var mu sync.Mutex
+	var started, stopped []string
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		lm.Register(LifecycleHook{
+			Name: name,
+			Start: func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				started = append(started, name)
+				return nil
+			},
+			Stop: func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				stopped = append(stopped, name)
+				return nil
+			},
+		})
+	}
+
+	var loopExited atomic.Bool
+	lm.Go("loop", func(ctx context.Context) {
+		<-ctx.Done()
+		loopExited.Store(true)
+	})
+
+	if err := lm.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if len(started) != 3 || started[0] != "a" || started[2] != "c" {
+		t.Errorf("started = %v, want [a b c]", started)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lm.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if len(stopped) != 3 {
+		t.Errorf("stopped = %v, want all three hooks", stopped)
+	}
+	if !loopExited.Load() {
+		t.Error("Shutdown returned before the Go routine exited")
+	}
+
+	// Shutdown is idempotent and the manager is closed afterwards
+	if err := lm.Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown returned %v", err)
+	}
+	if err := lm.Register(LifecycleHook{Name: "late"}); err == nil {
+		t.Error("expected Register after Shutdown to fail")
+	}
+	if err := lm.Go("late", func(context.Context) {}); err == nil {
+		t.Error("expected Go after Shutdown to fail")
+	}
+}
+
+// TestLifecycleShutdownDeadline checks Shutdown gives up when ctx expires
+func TestLifecycleShutdownDeadline(t *testing.T) {
+	lm := NewLifecycleManager()
+	release := make(chan struct{})
+	defer close(release)
+
+	var fastStopped atomic.Bool
+	lm.Register(LifecycleHook{Name: "slow", Stop: func(context.Context) error {
+		<-release
+		return nil
+	}})
+	lm.Register(LifecycleHook{Name: "fast", Stop: func(context.Context) error {
+		fastStopped.Store(true)
+		return nil
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := lm.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown took %v, should return at the deadline", elapsed)
+	}
+	if !fastStopped.Load() {
+		t.Error("fast stopper should be invoked even though another one hangs")
+	}
+}
+
+// TestLifecycleStopErrors checks Stop errors are joined
+func TestLifecycleStopErrors(t *testing.T) {
+	lm := NewLifecycleManager()
+	errA := errors.New("a failed")
+	lm.Register(LifecycleHook{Name: "a", Stop: func(context.Context) error { return errA }})
+	lm.Register(LifecycleHook{Name: "b", Stop: func(context.Context) error { return nil }})
+
+	err := lm.Shutdown(context.Background())
+	if !errors.Is(err, errA) {
+		t.Errorf("Shutdown error = %v, want to wrap %v", err, errA)
+	}
+}
+
+// TestLifecycleStartFailure checks started hooks are rolled back
+func TestLifecycleStartFailure(t *testing.T) {
+	lm := NewLifecycleManager()
+	var stoppedA, startedC bool
+	lm.Register(LifecycleHook{
+		Name:  "a",
+		Start: func(context.Context) error { return nil },
+		Stop:  func(context.Context) error { stoppedA = true; return nil },
+	})
+	lm.Register(LifecycleHook{
+		Name:  "b",
+		Start: func(context.Context) error { return errors.New("boom") },
+	})
+	lm.Register(LifecycleHook{
+		Name:  "c",
+		Start: func(context.Context) error { startedC = true; return nil },
+	})
+
+	if err := lm.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail")
+	}
+	if !stoppedA {
+		t.Error("hook started before the failure should be stopped")
+	}
+	if startedC {
+		t.Error("hooks after the failure should not start")
+	}
+}
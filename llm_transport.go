@@ -0,0 +1,155 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultTagHeaderPrefix is prepended to tag keys when TaggingTransport
+// sends them as headers, e.g. "X-Demeterics-App".
+const DefaultTagHeaderPrefix = "X-Demeterics-"
+
+// TaggingTransport is an http.RoundTripper that adds Demeterics tags to
+// outbound LLM requests, so calls made through any client carry the same
+// app, flow and environment context as LoggingLLM:
+//
+//	client := &http.Client{Transport: &common.TaggingTransport{
+//	    Tags: map[string]string{common.TagApp: "billing", common.TagEnv: "production"},
+//	}}
+//
+// Every request gets one header per tag. JSON chat requests (a body with a
+// "messages" array) additionally get the tags as "/// KEY value" lines at
+// the top of the first user message, which Demeterics strips before the
+// provider call. Tags already present in that message are not repeated.
+type TaggingTransport struct {
+	// Base performs the request. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Tags maps tag keys such as TagApp to values
+	Tags map[string]string
+
+	// HeaderPrefix overrides DefaultTagHeaderPrefix
+	HeaderPrefix string
+
+	// DisableBodyTags sends tags as headers only
+	DisableBodyTags bool
+}
+
+// RoundTrip adds the tags to a copy of req and passes it to Base
+func (t *TaggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if len(t.Tags) == 0 {
+		return base.RoundTrip(req)
+	}
+
+	prefix := t.HeaderPrefix
+	if prefix == "" {
+		prefix = DefaultTagHeaderPrefix
+	}
+
+	// RoundTrippers must not modify the caller's request
+	tagged := req.Clone(req.Context())
+	for key, val := range t.Tags {
+		tagged.Header.Set(prefix+key, val)
+	}
+
+	if !t.DisableBodyTags && isJSONBody(req) {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if withTags, ok := addChatTags(body, t.Tags); ok {
+			body = withTags
+		}
+		tagged.Body = io.NopCloser(bytes.NewReader(body))
+		tagged.ContentLength = int64(len(body))
+		tagged.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	return base.RoundTrip(tagged)
+}
+
+// isJSONBody reports whether req carries a JSON body
+func isJSONBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// addChatTags prepends the tags to the first user message of a chat
+// completion payload. Only that message's content changes; other values
+// are kept as raw JSON, so numbers and nested objects round-trip exactly.
+// ok is false when body is not a chat request with a text user message.
+func addChatTags(body []byte, tags map[string]string) ([]byte, bool) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false
+	}
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(payload["messages"], &messages); err != nil {
+		return nil, false
+	}
+
+	for _, msg := range messages {
+		var role, content string
+		if json.Unmarshal(msg["role"], &role) != nil || role != "user" {
+			continue
+		}
+		if json.Unmarshal(msg["content"], &content) != nil {
+			return nil, false // multi-part content is left alone
+		}
+
+		missing := make(map[string]string, len(tags))
+		for key, val := range tags {
+			if !strings.Contains(content, "/// "+key+" ") {
+				missing[key] = val
+			}
+		}
+		if len(missing) == 0 {
+			return body, true
+		}
+
+		encoded, err := json.Marshal(formatDemetericsTags(missing) + "\n" + content)
+		if err != nil {
+			return nil, false
+		}
+		msg["content"] = encoded
+
+		if payload["messages"], err = json.Marshal(messages); err != nil {
+			return nil, false
+		}
+		out, err := json.Marshal(payload)
+		if err != nil {
+			return nil, false
+		}
+		return out, true
+	}
+	return nil, false
+}
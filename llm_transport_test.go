@@ -0,0 +1,146 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureTransport records the request it receives
+type captureTransport struct {
+	req   *http.Request
+	body  string
+	calls int
+}
+
+func (c *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls++
+	c.req = req
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		c.body = string(b)
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+}
+
+func TestTaggingTransport(t *testing.T) {
+	tags := map[string]string{TagApp: "billing", TagEnv: "staging", "TEAM": "payments"}
+	chat := `{"model":"m","seed":12345678901234567,"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"Hello"}]}`
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		disableBody bool
+		wantPrefix  string // expected start of the user message, "" for untouched bodies
+	}{
+		{"Chat request", chat, "application/json", false, "/// APP billing\n/// ENV staging\n/// TEAM payments\n\nHello"},
+		{"Chat request with charset", chat, "application/json; charset=utf-8", false, "/// APP billing\n"},
+		{"Body tags disabled", chat, "application/json", true, ""},
+		{"Not a chat request", `{"input":"Hello"}`, "application/json", false, ""},
+		{"Not JSON", "Hello", "text/plain", false, ""},
+		{"Already tagged", `{"messages":[{"role":"user","content":"/// APP billing\n/// ENV staging\n/// TEAM payments\nHi"}]}`, "application/json", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &captureTransport{}
+			transport := &TaggingTransport{Base: base, Tags: tags, DisableBodyTags: tt.disableBody}
+
+			req := httptest.NewRequest(http.MethodPost, "https://llm.example.com/v1/chat/completions", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatalf("RoundTrip failed: %v", err)
+			}
+			if base.calls != 1 {
+				t.Fatalf("Expected the base transport to be called once, got %d", base.calls)
+			}
+			if got := base.req.Header.Get("X-Demeterics-App"); got != "billing" {
+				t.Errorf("X-Demeterics-App = %q, want billing", got)
+			}
+			if got := base.req.Header.Get("X-Demeterics-Team"); got != "payments" {
+				t.Errorf("X-Demeterics-Team = %q, want payments", got)
+			}
+			if req.Header.Get("X-Demeterics-App") != "" {
+				t.Error("Expected the caller's request to be left unmodified")
+			}
+			if base.req.ContentLength != int64(len(base.body)) {
+				t.Errorf("ContentLength = %d, body has %d bytes", base.req.ContentLength, len(base.body))
+			}
+
+			if tt.wantPrefix == "" {
+				if base.body != tt.body {
+					t.Errorf("Expected body to be unchanged, got %s", base.body)
+				}
+				return
+			}
+
+			var payload struct {
+				Seed     json.Number `json:"seed"`
+				Messages []struct {
+					Role    string `json:"role"`
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			dec := json.NewDecoder(strings.NewReader(base.body))
+			dec.UseNumber()
+			if err := dec.Decode(&payload); err != nil {
+				t.Fatalf("Tagged body is not valid JSON: %v", err)
+			}
+			if payload.Seed != "12345678901234567" {
+				t.Errorf("Expected other fields to round-trip, got seed %s", payload.Seed)
+			}
+			if payload.Messages[0].Content != "Be brief." {
+				t.Errorf("Expected the system message to be untouched, got %q", payload.Messages[0].Content)
+			}
+			if !strings.HasPrefix(payload.Messages[1].Content, tt.wantPrefix) {
+				t.Errorf("User message = %q, want prefix %q", payload.Messages[1].Content, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestTaggingTransportWithClient(t *testing.T) {
+	var gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Tag-Flow")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &TaggingTransport{
+		Tags:         map[string]string{TagFlow: "checkout.payment"},
+		HeaderPrefix: "X-Tag-",
+	}}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"messages":[{"role":"user","content":"Hi"}]}`))
+	if err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotHeader != "checkout.payment" {
+		t.Errorf("X-Tag-Flow = %q, want checkout.payment", gotHeader)
+	}
+	if !strings.Contains(gotBody, `/// FLOW checkout.payment\n\nHi`) {
+		t.Errorf("Expected tags in the user message, got %s", gotBody)
+	}
+}
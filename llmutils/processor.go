@@ -408,6 +408,96 @@ func StripCommentsFromMessages(messages []interface{}) []interface{} {
 	return result
 }
 
+// ProcessMessages strips /// comments from all messages like
+// StripCommentsFromMessages and also returns the metadata of every message
+// merged into one ProcessedPrompt, so API middleware can clean and
+// categorize a chat request in one pass.
+//
+// Merging follows message order:
+//   - Params and Metadata: later messages override earlier keys
+//   - Flow and Node: the last message that sets them wins
+//   - Tags: union of all tags in first-seen order, followed by the
+//     flow:/node: tags of the merged Flow and Node
+//
+// CleanedPrompt holds the cleaned string contents joined by blank lines,
+// which is useful for token estimates. Messages that are not maps and
+// non-string contents are passed through and contribute no metadata.
+//
+// Example:
+//
+//	messages := []interface{}{
+//	    map[string]interface{}{"role": "system", "content": "/// flow: support\n/// param: model=gpt-4\nYou are helpful"},
+//	    map[string]interface{}{"role": "user", "content": "/// param: temperature=0.2\nHi"},
+//	}
+//	cleaned, meta := ProcessMessages(messages)
+//	// cleaned[0]["content"] = "You are helpful"
+//	// meta.Params = {"model": "gpt-4", "temperature": "0.2"}
+//	// meta.Flow = "support"
+func ProcessMessages(messages []interface{}) ([]interface{}, ProcessedPrompt) {
+	merged := ProcessedPrompt{
+		Params:   make(map[string]string),
+		Metadata: make(map[string]string),
+		Tags:     make([]string, 0),
+	}
+	seenTags := make(map[string]bool)
+	var contents []string
+
+	for _, msg := range messages {
+		msgMap, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, ok := msgMap["content"].(string)
+		if !ok {
+			continue
+		}
+
+		p := Process(content)
+		for k, v := range p.Params {
+			merged.Params[k] = v
+		}
+		for k, v := range p.Metadata {
+			merged.Metadata[k] = v
+		}
+		if p.Flow != "" {
+			merged.Flow = p.Flow
+		}
+		if p.Node != "" {
+			merged.Node = p.Node
+		}
+
+		// Process appends flow:/node: tags last; they are re-derived below
+		// from the merged values
+		explicit := len(p.Tags)
+		if p.Flow != "" {
+			explicit--
+		}
+		if p.Node != "" {
+			explicit--
+		}
+		for _, tag := range p.Tags[:explicit] {
+			if !seenTags[tag] {
+				seenTags[tag] = true
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+
+		if cleaned := StripComments(content); cleaned != "" {
+			contents = append(contents, cleaned)
+		}
+	}
+
+	if merged.Flow != "" {
+		merged.Tags = append(merged.Tags, "flow:"+merged.Flow)
+	}
+	if merged.Node != "" {
+		merged.Tags = append(merged.Tags, "node:"+merged.Node)
+	}
+	merged.CleanedPrompt = strings.Join(contents, "\n\n")
+
+	return StripCommentsFromMessages(messages), merged
+}
+
 // IMPLEMENTATION NOTES
 //
 // Design Decisions:
@@ -905,3 +905,69 @@ func BenchmarkStripCommentsFromMessages(b *testing.B) {
 		_ = StripCommentsFromMessages(messages)
 	}
 }
+
+func TestProcessMessages(t *testing.T) {
+	messages := []interface{}{
+		map[string]interface{}{
+			"role":    "system",
+			"content": "/// flow: support\n/// node: triage\n/// param: model=gpt-4, temperature=0.7\n/// tag: billing\nYou are helpful /// be nice",
+		},
+		map[string]interface{}{
+			"role":    "user",
+			"content": "/// param: temperature=0.2\n/// node: answer\n/// tags: billing, urgent\n/// owner: team-a\nWhy was I charged twice?",
+		},
+		map[string]interface{}{
+			"role":    "assistant",
+			"content": []interface{}{"non-string content"},
+		},
+		"not a map",
+	}
+
+	cleaned, meta := ProcessMessages(messages)
+
+	if len(cleaned) != len(messages) {
+		t.Fatalf("got %d messages, want %d", len(cleaned), len(messages))
+	}
+	if got := cleaned[0].(map[string]interface{})["content"]; got != "You are helpful" {
+		t.Errorf("system content = %q", got)
+	}
+	if got := cleaned[1].(map[string]interface{})["content"]; got != "Why was I charged twice?" {
+		t.Errorf("user content = %q", got)
+	}
+	if got := cleaned[1].(map[string]interface{})["role"]; got != "user" {
+		t.Errorf("role = %q, want user", got)
+	}
+	if cleaned[3] != "not a map" {
+		t.Errorf("non-map message changed: %v", cleaned[3])
+	}
+
+	// Later messages override earlier params
+	wantParams := map[string]string{"model": "gpt-4", "temperature": "0.2"}
+	if !reflect.DeepEqual(meta.Params, wantParams) {
+		t.Errorf("Params = %v, want %v", meta.Params, wantParams)
+	}
+	if meta.Flow != "support" || meta.Node != "answer" {
+		t.Errorf("Flow/Node = %q/%q, want support/answer", meta.Flow, meta.Node)
+	}
+	if meta.Metadata["owner"] != "team-a" || meta.Metadata["node"] != "answer" || meta.Metadata["flow"] != "support" {
+		t.Errorf("Metadata = %v", meta.Metadata)
+	}
+
+	wantTags := []string{"billing", "urgent", "flow:support", "node:answer"}
+	if !reflect.DeepEqual(meta.Tags, wantTags) {
+		t.Errorf("Tags = %v, want %v", meta.Tags, wantTags)
+	}
+	if meta.CleanedPrompt != "You are helpful\n\nWhy was I charged twice?" {
+		t.Errorf("CleanedPrompt = %q", meta.CleanedPrompt)
+	}
+}
+
+func TestProcessMessagesEmpty(t *testing.T) {
+	cleaned, meta := ProcessMessages(nil)
+	if len(cleaned) != 0 {
+		t.Errorf("expected no messages, got %v", cleaned)
+	}
+	if meta.Params == nil || meta.Metadata == nil || len(meta.Tags) != 0 || meta.CleanedPrompt != "" {
+		t.Errorf("unexpected metadata for no messages: %+v", meta)
+	}
+}
@@ -0,0 +1,50 @@
+package llmutils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// EstimateTokens returns a rough token count for s, suitable for guarding
+// against context-window overflows before a request is sent. It is not a
+// tokenizer: use the llm/tokenizer package when exact counts matter.
+//
+// Heuristic:
+//   - Text is split on whitespace; each word costs about one token per four
+//     characters (at least one token per word)
+//   - Han, Hiragana, Katakana and Hangul characters cost one token each, since
+//     BPE vocabularies rarely merge them
+//
+// For English prose this lands within roughly ±30% of GPT-style tokenizers,
+// usually on the high side, which is the safe direction for limit checks.
+//
+// Example:
+//
+//	if llmutils.EstimateTokens(prompt) > 8000 {
+//	    common.Warn("prompt may exceed the model context window")
+//	}
+func EstimateTokens(s string) int {
+	tokens := 0
+	for _, word := range strings.Fields(s) {
+		cjk, other := 0, 0
+		for _, r := range word {
+			if isCJK(r) {
+				cjk++
+			} else {
+				other++
+			}
+		}
+		tokens += cjk + (other+3)/4
+	}
+	return tokens
+}
+
+// EstimatedTokens returns EstimateTokens of the cleaned prompt
+func (p ProcessedPrompt) EstimatedTokens() int {
+	return EstimateTokens(p.CleanedPrompt)
+}
+
+// isCJK reports whether r belongs to a script that tokenizes per character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,76 @@
+package llmutils
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEstimateTokens checks the heuristic against known samples
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{name: "empty", input: "", expected: 0},
+		{name: "whitespace only", input: " \n\t ", expected: 0},
+		{name: "single short word", input: "hi", expected: 1},
+		{name: "four characters", input: "test", expected: 1},
+		{name: "five characters", input: "tests", expected: 2},
+		{name: "punctuation attached", input: "Hello, world!", expected: 4},
+		{name: "extra whitespace ignored", input: "  Hello,\n\n   world!  ", expected: 4},
+		{name: "CJK per character", input: "你好世界", expected: 4},
+		{name: "mixed CJK and latin", input: "GPT模型", expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.input); got != tt.expected {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestEstimateTokensBounds checks a prose sample stays near real tokenizer counts
+func TestEstimateTokensBounds(t *testing.T) {
+	// 40 words of English prose; GPT-style tokenizers produce about 50 tokens
+	sample := "You are a helpful assistant. Summarize the following customer " +
+		"support conversation in three bullet points, focusing on the problem, " +
+		"the steps already taken, and the next action the agent promised. " +
+		"Keep the tone neutral and do not include personal information."
+
+	got := EstimateTokens(sample)
+	if got < 40 || got > 80 {
+		t.Errorf("EstimateTokens(sample) = %d, want within [40, 80]", got)
+	}
+}
+
+// TestEstimateTokensMonotonic verifies longer text never yields fewer tokens
+func TestEstimateTokensMonotonic(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+
+	prev := 0
+	for i := 1; i <= len(text); i++ {
+		got := EstimateTokens(text[:i])
+		if got < prev {
+			t.Fatalf("EstimateTokens decreased from %d to %d at length %d", prev, got, i)
+		}
+		prev = got
+	}
+
+	short := EstimateTokens(text[:len(text)/4])
+	long := EstimateTokens(text)
+	if long <= short {
+		t.Errorf("expected longer text to have more tokens: short=%d long=%d", short, long)
+	}
+}
+
+// TestProcessedPromptEstimatedTokens verifies comments are not counted
+func TestProcessedPromptEstimatedTokens(t *testing.T) {
+	result := Process("/// a very long comment that should never be counted at all\nBe concise")
+
+	if got, want := result.EstimatedTokens(), EstimateTokens("Be concise"); got != want {
+		t.Errorf("EstimatedTokens() = %d, want %d", got, want)
+	}
+}
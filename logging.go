@@ -14,8 +14,8 @@
 
 // logging.go provides a tiny wrapper around the standard log package.
 // The helpers here format messages consistently and funnel all logs
-// through log.Printf. Debug obeys the global ISDEBUG variable so it
-// can be disabled globally.
+// through log.Printf, or the writer set by SetLogOutput. Debug obeys the
+// global ISDEBUG variable unless a level is chosen with SetLogLevel.
 
 package common
 
@@ -92,34 +92,44 @@ func InitErrorDatastore() error {
 	return nil
 }
 
-// Debug writes a formatted debug message when ISDEBUG is true.
+// Debug writes a formatted debug message when ISDEBUG is true, or when
+// SetLogLevel chose LevelDebug.
 // A newline is appended so callers do not have to include one.
 func Debug(format string, v ...interface{}) {
-	if !ISDEBUG {
+	if !logEnabled(LevelDebug) {
 		return
 	}
 	// Include trailing newline for consistency with other helpers.
-	log.Printf(format+"\n", v...)
+	logPrintf(format+"\n", v...)
 }
 
 // Info writes a formatted informational message.
 // A newline is appended to keep log lines consistent.
 func Info(format string, v ...interface{}) {
-	log.Printf(format+"\n", v...)
+	if !logEnabled(LevelInfo) {
+		return
+	}
+	logPrintf(format+"\n", v...)
 }
 
 // Warn writes a formatted warning message with an "WARNING:" prefix.
 // The prefix helps grep for warnings in log files.
 func Warn(format string, v ...interface{}) {
-	log.Printf("WARNING: "+format+"\n", v...)
+	if !logEnabled(LevelWarn) {
+		return
+	}
+	logPrintf("WARNING: "+format+"\n", v...)
 }
 
 // Error writes a formatted error message with an "ERROR:" prefix.
 // The prefix helps grep for errors in log files.
 // If ERROR_DATASTORE_ENTITY is set, also stores the error in Datastore.
 func Error(format string, v ...interface{}) {
+	if !logEnabled(LevelError) {
+		return
+	}
 	errorMsg := fmt.Sprintf(format, v...)
-	log.Printf("ERROR: %s\n", errorMsg)
+	logPrintf("ERROR: %s\n", errorMsg)
 
 	// Store in Datastore if configured
 	if ERROR_DATASTORE_ENTITY != "" && errorClient != nil {
@@ -150,7 +160,7 @@ func Error(format string, v ...interface{}) {
 // The function logs the message and then calls os.Exit(1).
 func Fatal(format string, v ...interface{}) {
 	errorMsg := fmt.Sprintf(format, v...)
-	log.Printf("FATAL: %s\n", errorMsg)
+	logPrintf("FATAL: %s\n", errorMsg)
 
 	// Store in Datastore if configured (best effort, don't wait)
 	if ERROR_DATASTORE_ENTITY != "" && errorClient != nil {
@@ -50,7 +50,7 @@ func initSanitizer() {
 // This is a PII-safe version of Debug that should be used when logging
 // potentially sensitive information.
 func DebugSafe(format string, v ...interface{}) {
-	if !ISDEBUG {
+	if !logEnabled(LevelDebug) {
 		return
 	}
 
@@ -0,0 +1,153 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// logging_level.go adds runtime control over the printf-style and
+// structured log helpers: a minimum level and a replaceable output.
+// Without calls to SetLogLevel or SetLogOutput the helpers behave as
+// before, with Debug following ISDEBUG and output going through the
+// standard log package.
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevel is the minimum severity written by the log helpers
+type LogLevel int32
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelUnset means no level was chosen, so Debug follows ISDEBUG
+const levelUnset LogLevel = -1
+
+// LogLevelEnvVar may hold a level name ("debug", "info", "warn", "error")
+// applied at startup, as if passed to SetLogLevel
+const LogLevelEnvVar = "COMMON_LOG_LEVEL"
+
+var (
+	// logLevel holds the level set by SetLogLevel, or levelUnset
+	logLevel atomic.Int32
+
+	// logOutput holds the logger set by SetLogOutput; nil uses the
+	// standard logger
+	logOutput atomic.Pointer[log.Logger]
+)
+
+func init() {
+	logLevel.Store(int32(levelUnset))
+	if level, err := ParseLogLevel(os.Getenv(LogLevelEnvVar)); err == nil {
+		SetLogLevel(level)
+	}
+}
+
+// String returns the lowercase level name
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLogLevel converts a level name such as "warn" or "WARNING" to a
+// LogLevel
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return levelUnset, fmt.Errorf("unknown log level: %q", s)
+}
+
+// SetLogLevel drops messages below level from Debug, Info, Warn and Error
+// and their Safe and Ctx variants. Once set, ISDEBUG no longer decides
+// whether debug messages are written. Fatal is always written.
+//
+//	common.SetLogLevel(common.LevelWarn)  // quiet production logs
+//	common.SetLogLevel(common.LevelDebug) // raise verbosity on demand
+func SetLogLevel(level LogLevel) {
+	logLevel.Store(int32(level))
+}
+
+// GetLogLevel returns the effective level: the one set by SetLogLevel, or
+// LevelDebug when ISDEBUG is true and LevelInfo otherwise
+func GetLogLevel() LogLevel {
+	if level := LogLevel(logLevel.Load()); level != levelUnset {
+		return level
+	}
+	if ISDEBUG {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// logEnabled reports whether messages at level are written
+func logEnabled(level LogLevel) bool {
+	return level >= GetLogLevel()
+}
+
+// SetLogOutput sends the printf-style helpers and the structured Ctx
+// helpers to w, which lets tests capture logs:
+//
+//	var buf bytes.Buffer
+//	common.SetLogOutput(&buf)
+//	defer common.SetLogOutput(nil)
+//
+// A nil w restores the defaults: the standard log package for the printf
+// helpers and stdout for structured entries.
+func SetLogOutput(w io.Writer) {
+	structuredLogMu.Lock()
+	defer structuredLogMu.Unlock()
+
+	if w == nil {
+		logOutput.Store(nil)
+		structuredLogOutput = os.Stdout
+		return
+	}
+	logOutput.Store(log.New(w, "", log.LstdFlags))
+	structuredLogOutput = w
+}
+
+// logPrintf writes through the logger set by SetLogOutput, or the
+// standard logger
+func logPrintf(format string, v ...interface{}) {
+	if logger := logOutput.Load(); logger != nil {
+		logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
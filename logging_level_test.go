@@ -0,0 +1,138 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// captureLogs redirects log output and restores the level and output when
+// the test ends
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	prevLevel := logLevel.Load()
+	buf := &bytes.Buffer{}
+	SetLogOutput(buf)
+	t.Cleanup(func() {
+		logLevel.Store(prevLevel)
+		SetLogOutput(nil)
+	})
+	return buf
+}
+
+func TestSetLogLevelFiltering(t *testing.T) {
+	tests := []struct {
+		level LogLevel
+		want  []string
+		skip  []string
+	}{
+		{LevelDebug, []string{"dbg", "inf", "WARNING: wrn", "ERROR: err"}, nil},
+		{LevelInfo, []string{"inf", "WARNING: wrn", "ERROR: err"}, []string{"dbg"}},
+		{LevelWarn, []string{"WARNING: wrn", "ERROR: err"}, []string{"dbg", "inf"}},
+		{LevelError, []string{"ERROR: err"}, []string{"dbg", "inf", "wrn"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			buf := captureLogs(t)
+			SetLogLevel(tt.level)
+
+			Debug("dbg")
+			Info("inf")
+			Warn("wrn")
+			Error("err")
+
+			out := buf.String()
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("Expected %q in output %q", s, out)
+				}
+			}
+			for _, s := range tt.skip {
+				if strings.Contains(out, s) {
+					t.Errorf("Did not expect %q in output %q", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestLogLevelDefaultFollowsISDEBUG(t *testing.T) {
+	buf := captureLogs(t)
+	logLevel.Store(int32(levelUnset))
+	prev := ISDEBUG
+	t.Cleanup(func() { ISDEBUG = prev })
+
+	ISDEBUG = false
+	Debug("hidden")
+	if GetLogLevel() != LevelInfo || strings.Contains(buf.String(), "hidden") {
+		t.Errorf("Expected debug off by default, level %s, output %q", GetLogLevel(), buf.String())
+	}
+
+	ISDEBUG = true
+	Debug("shown")
+	if GetLogLevel() != LevelDebug || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("Expected debug on with ISDEBUG, level %s, output %q", GetLogLevel(), buf.String())
+	}
+
+	SetLogLevel(LevelInfo)
+	Debug("overridden")
+	if strings.Contains(buf.String(), "overridden") {
+		t.Error("Expected SetLogLevel to take precedence over ISDEBUG")
+	}
+}
+
+func TestSetLogOutputStructured(t *testing.T) {
+	buf := captureLogs(t)
+	SetLogLevel(LevelWarn)
+
+	InfoCtx(context.Background(), "quiet", nil)
+	WarnCtx(context.Background(), "loud", nil)
+	InfoSafe("quiet too")
+
+	out := buf.String()
+	if strings.Contains(out, "quiet") {
+		t.Errorf("Did not expect info entries in %q", out)
+	}
+	if !strings.Contains(out, `"message":"loud"`) {
+		t.Errorf("Expected the structured warning in %q", out)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    LogLevel
+		wantErr bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{" warning ", LevelWarn, false},
+		{"warn", LevelWarn, false},
+		{"error", LevelError, false},
+		{"verbose", levelUnset, true},
+		{"", levelUnset, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLogLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
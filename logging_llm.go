@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -72,6 +73,7 @@ type LoggingLLM struct {
 	lastErrorText    string
 	analysisCallback AnalysisCallback
 	tags             map[string]string
+	requestID        string
 }
 
 var (
@@ -79,8 +81,9 @@ var (
 
 	// Global throttle for LLM analysis to prevent duplicate feedback on the same error.
 	// Key: hash of (fileName + funcName), Value: time of last analysis.
-	analysisThrottleMu    sync.Mutex
-	analysisThrottleCache = make(map[string]time.Time)
+	// Bounded to 1000 entries; expiry is checked against AnalysisThrottleDuration
+	// on read so changes to the duration apply immediately.
+	analysisThrottleCache = NewLRUCache[string, time.Time](1000, 0)
 
 	// AnalysisThrottleDuration controls how long to suppress duplicate error analyses.
 	// Default: 60 minutes. Set to 0 to disable throttling.
@@ -156,6 +159,18 @@ func (l *LoggingLLM) SetTag(key, value string) *LoggingLLM {
 	return l
 }
 
+// WithContext attaches the request ID found in ctx (see RequestIDMiddleware)
+// so the summary and LLM analysis can be correlated with the request.
+// Returns the logger for method chaining.
+func (l *LoggingLLM) WithContext(ctx context.Context) *LoggingLLM {
+	if id := RequestID(ctx); id != "" {
+		l.mu.Lock()
+		l.requestID = id
+		l.mu.Unlock()
+	}
+	return l
+}
+
 // Debug logs a debug message and records it inside the markdown summary when
 // ISDEBUG is enabled. The summary stores a PII-sanitized representation.
 func (l *LoggingLLM) Debug(format string, v ...interface{}) {
@@ -258,6 +273,9 @@ func (l *LoggingLLM) MarkdownSummary() string {
 	builder := strings.Builder{}
 	builder.WriteString(l.summary.String())
 	builder.WriteString("\n")
+	if l.requestID != "" {
+		builder.WriteString(fmt.Sprintf("_Request ID %s_\n", l.requestID))
+	}
 	builder.WriteString(fmt.Sprintf("_Duration %s_\n", duration))
 	return builder.String()
 }
@@ -314,10 +332,7 @@ func isThrottled(key string) bool {
 		return false // Throttling disabled
 	}
 
-	analysisThrottleMu.Lock()
-	defer analysisThrottleMu.Unlock()
-
-	lastTime, exists := analysisThrottleCache[key]
+	lastTime, exists := analysisThrottleCache.Get(key)
 	if !exists {
 		return false
 	}
@@ -328,26 +343,13 @@ func isThrottled(key string) bool {
 	}
 
 	// Expired, remove from cache
-	delete(analysisThrottleCache, key)
+	analysisThrottleCache.Delete(key)
 	return false
 }
 
 // markAnalyzed records that an error was just analyzed.
 func markAnalyzed(key string) {
-	analysisThrottleMu.Lock()
-	defer analysisThrottleMu.Unlock()
-
-	analysisThrottleCache[key] = time.Now()
-
-	// Cleanup old entries to prevent memory leak (keep max 1000 entries)
-	if len(analysisThrottleCache) > 1000 {
-		now := time.Now()
-		for k, t := range analysisThrottleCache {
-			if now.Sub(t) > AnalysisThrottleDuration {
-				delete(analysisThrottleCache, k)
-			}
-		}
-	}
+	analysisThrottleCache.Set(key, time.Now())
 }
 
 func (l *LoggingLLM) runLLMAnalysis() {
@@ -381,28 +383,20 @@ func (l *LoggingLLM) buildLLMPrompt() string {
 	// Prepend Demeterics tags (stripped before provider call, no token cost)
 	l.mu.Lock()
 	if len(l.tags) > 0 {
-		// Write tags in a consistent order for readability
-		tagOrder := []string{TagApp, TagFlow, TagProduct, TagCompany, TagUnit,
-			TagUser, TagSession, TagMarket,
-			TagVariant, TagVersion, TagEnv, TagProject}
-		for _, key := range tagOrder {
-			if val, ok := l.tags[key]; ok {
-				b.WriteString(fmt.Sprintf("/// %s %s\n", key, val))
-			}
-		}
-		// Write any custom tags not in the standard order
-		for key, val := range l.tags {
-			if !isStandardTag(key) {
-				b.WriteString(fmt.Sprintf("/// %s %s\n", key, val))
-			}
-		}
+		b.WriteString(formatDemetericsTags(l.tags))
 		b.WriteString("\n")
 	}
 	l.mu.Unlock()
 
 	b.WriteString("You are a senior Go engineer helping debug a failure.\n")
 	b.WriteString("Provide probable root causes, code references, and actionable fixes.\n\n")
-	b.WriteString(fmt.Sprintf("File: %s\nFunction: %s\n\n", l.fileName, l.funcName))
+	b.WriteString(fmt.Sprintf("File: %s\nFunction: %s\n", l.fileName, l.funcName))
+	l.mu.Lock()
+	if l.requestID != "" {
+		b.WriteString(fmt.Sprintf("Request ID: %s\n", l.requestID))
+	}
+	l.mu.Unlock()
+	b.WriteString("\n")
 	if l.lastErrorText != "" {
 		b.WriteString("### Latest error\n")
 		b.WriteString(l.lastErrorText)
@@ -536,6 +530,33 @@ func truncateForLog(value string, limit int) string {
 	return value[:limit]
 }
 
+// standardTagOrder lists the predefined Demeterics tags in display order.
+var standardTagOrder = []string{TagApp, TagFlow, TagProduct, TagCompany, TagUnit,
+	TagUser, TagSession, TagMarket,
+	TagVariant, TagVersion, TagEnv, TagProject}
+
+// formatDemetericsTags renders tags as "/// KEY value" lines, standard tags
+// first in a consistent order, then custom tags sorted by key.
+func formatDemetericsTags(tags map[string]string) string {
+	var b strings.Builder
+	for _, key := range standardTagOrder {
+		if val, ok := tags[key]; ok {
+			b.WriteString(fmt.Sprintf("/// %s %s\n", key, val))
+		}
+	}
+	var custom []string
+	for key := range tags {
+		if !isStandardTag(key) {
+			custom = append(custom, key)
+		}
+	}
+	sort.Strings(custom)
+	for _, key := range custom {
+		b.WriteString(fmt.Sprintf("/// %s %s\n", key, tags[key]))
+	}
+	return b.String()
+}
+
 // isStandardTag checks if a tag key is one of the predefined Demeterics tags.
 func isStandardTag(key string) bool {
 	switch key {
@@ -0,0 +1,192 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// logging_structured.go provides context-aware structured logging helpers.
+// Each entry is written as a single JSON line using the field names that
+// Cloud Logging recognizes (severity, message, trace and span), so logs from
+// the same request can be correlated in the console. The printf-style
+// helpers in logging.go are unchanged.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cloud Logging severity values used by the structured helpers.
+const (
+	SeverityDebug   = "DEBUG"
+	SeverityInfo    = "INFO"
+	SeverityWarning = "WARNING"
+	SeverityError   = "ERROR"
+)
+
+// Special field names understood by Cloud Logging when parsing JSON payloads.
+const (
+	cloudLoggingTraceKey = "logging.googleapis.com/trace"
+	cloudLoggingSpanKey  = "logging.googleapis.com/spanId"
+)
+
+// traceContextKey is a private type for storing trace data in a context.
+type traceContextKey struct{}
+
+// traceInfo holds the trace and span identifiers for a request.
+type traceInfo struct {
+	TraceID string
+	SpanID  string
+}
+
+var (
+	// structuredLogOutput receives JSON log lines. Cloud Run and App Engine
+	// parse JSON written to stdout as structured entries.
+	structuredLogOutput io.Writer = os.Stdout
+	structuredLogMu     sync.Mutex
+)
+
+// WithTrace returns a copy of ctx carrying the given trace and span IDs.
+// Structured log helpers such as InfoCtx attach these IDs to every entry.
+func WithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceInfo{TraceID: traceID, SpanID: spanID})
+}
+
+// TraceFromContext returns the trace and span IDs stored by WithTrace.
+// Empty strings are returned when no trace is present.
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	if ctx == nil {
+		return "", ""
+	}
+	if t, ok := ctx.Value(traceContextKey{}).(traceInfo); ok {
+		return t.TraceID, t.SpanID
+	}
+	return "", ""
+}
+
+// TraceContextFromRequest parses the X-Cloud-Trace-Context header set by
+// Google front ends ("TRACE_ID/SPAN_ID;o=OPTIONS") and returns a context
+// derived from the request context that carries the trace. When the header
+// is missing the request context is returned unchanged.
+func TraceContextFromRequest(r *http.Request) context.Context {
+	header := r.Header.Get("X-Cloud-Trace-Context")
+	if header == "" {
+		return r.Context()
+	}
+	traceID := header
+	spanID := ""
+	if i := strings.Index(header, "/"); i >= 0 {
+		traceID = header[:i]
+		spanID = header[i+1:]
+		if j := strings.Index(spanID, ";"); j >= 0 {
+			spanID = spanID[:j]
+		}
+	}
+	if traceID == "" {
+		return r.Context()
+	}
+	return WithTrace(r.Context(), traceID, spanID)
+}
+
+// DebugCtx writes a structured debug entry when Debug would write.
+func DebugCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	if !logEnabled(LevelDebug) {
+		return
+	}
+	logStructured(ctx, SeverityDebug, msg, fields)
+}
+
+// InfoCtx writes a structured informational entry. Trace and span IDs are
+// pulled from ctx so the entry is grouped with its request in Cloud Logging.
+//
+// Example:
+//
+//	common.InfoCtx(ctx, "order created", map[string]interface{}{
+//	    "order_id": id,
+//	    "amount":   total,
+//	})
+func InfoCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	if !logEnabled(LevelInfo) {
+		return
+	}
+	logStructured(ctx, SeverityInfo, msg, fields)
+}
+
+// WarnCtx writes a structured warning entry.
+func WarnCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	if !logEnabled(LevelWarn) {
+		return
+	}
+	logStructured(ctx, SeverityWarning, msg, fields)
+}
+
+// ErrorCtx writes a structured error entry.
+func ErrorCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	if !logEnabled(LevelError) {
+		return
+	}
+	logStructured(ctx, SeverityError, msg, fields)
+}
+
+// logStructured builds the JSON entry and writes it as a single line.
+// Caller fields never override the reserved severity, message, time and
+// trace keys.
+func logStructured(ctx context.Context, severity, msg string, fields map[string]interface{}) {
+	entry := make(map[string]interface{}, len(fields)+5)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["severity"] = severity
+	entry["message"] = msg
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	if traceID, spanID := TraceFromContext(ctx); traceID != "" {
+		entry[cloudLoggingTraceKey] = formatTraceID(traceID)
+		if spanID != "" {
+			entry[cloudLoggingSpanKey] = spanID
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// Fall back to the plain logger so the message is never lost.
+		logPrintf("%s: %s (failed to encode fields: %v)\n", severity, msg, err)
+		return
+	}
+
+	structuredLogMu.Lock()
+	defer structuredLogMu.Unlock()
+	_, _ = structuredLogOutput.Write(append(data, '\n'))
+}
+
+// formatTraceID expands a bare trace ID into the
+// "projects/PROJECT_ID/traces/TRACE_ID" form expected by Cloud Logging when
+// the project is known.
+func formatTraceID(traceID string) string {
+	if strings.HasPrefix(traceID, "projects/") {
+		return traceID
+	}
+	projectID := os.Getenv("PROJECT_ID")
+	if projectID == "" {
+		projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if projectID == "" {
+		return traceID
+	}
+	return "projects/" + projectID + "/traces/" + traceID
+}
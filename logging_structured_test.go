@@ -0,0 +1,151 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureStructured redirects structured log output for the duration of a
+// test and returns the buffer receiving the JSON lines.
+func captureStructured(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	structuredLogMu.Lock()
+	prev := structuredLogOutput
+	structuredLogOutput = buf
+	structuredLogMu.Unlock()
+	t.Cleanup(func() {
+		structuredLogMu.Lock()
+		structuredLogOutput = prev
+		structuredLogMu.Unlock()
+	})
+	return buf
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	line := strings.TrimSpace(buf.String())
+	if strings.Count(line, "\n") != 0 {
+		t.Fatalf("expected a single JSON line, got %q", line)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", line, err)
+	}
+	return entry
+}
+
+func TestInfoCtxJSONShape(t *testing.T) {
+	buf := captureStructured(t)
+	t.Setenv("PROJECT_ID", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+
+	InfoCtx(context.Background(), "order created", map[string]interface{}{
+		"order_id": "o-1",
+		"amount":   42,
+	})
+
+	entry := decodeEntry(t, buf)
+	if entry["severity"] != SeverityInfo {
+		t.Errorf("severity = %v, want %s", entry["severity"], SeverityInfo)
+	}
+	if entry["message"] != "order created" {
+		t.Errorf("message = %v", entry["message"])
+	}
+	if entry["order_id"] != "o-1" {
+		t.Errorf("order_id = %v", entry["order_id"])
+	}
+	if entry["amount"] != float64(42) {
+		t.Errorf("amount = %v", entry["amount"])
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("time field missing")
+	}
+	if _, ok := entry[cloudLoggingTraceKey]; ok {
+		t.Error("trace field should be absent without a trace in context")
+	}
+}
+
+func TestInfoCtxTraceFields(t *testing.T) {
+	buf := captureStructured(t)
+	t.Setenv("PROJECT_ID", "demo-project")
+
+	ctx := WithTrace(context.Background(), "abc123", "456")
+	InfoCtx(ctx, "hello", nil)
+
+	entry := decodeEntry(t, buf)
+	if got := entry[cloudLoggingTraceKey]; got != "projects/demo-project/traces/abc123" {
+		t.Errorf("trace = %v", got)
+	}
+	if got := entry[cloudLoggingSpanKey]; got != "456" {
+		t.Errorf("spanId = %v", got)
+	}
+}
+
+func TestSeverityMapping(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(context.Context, string, map[string]interface{})
+		want string
+	}{
+		{"info", InfoCtx, SeverityInfo},
+		{"warn", WarnCtx, SeverityWarning},
+		{"error", ErrorCtx, SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureStructured(t)
+			tt.fn(context.Background(), "msg", nil)
+			if got := decodeEntry(t, buf)["severity"]; got != tt.want {
+				t.Errorf("severity = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReservedFieldsNotOverridden(t *testing.T) {
+	buf := captureStructured(t)
+	WarnCtx(context.Background(), "real", map[string]interface{}{
+		"message":  "fake",
+		"severity": "DEBUG",
+	})
+	entry := decodeEntry(t, buf)
+	if entry["message"] != "real" || entry["severity"] != SeverityWarning {
+		t.Errorf("reserved fields overridden: %v", entry)
+	}
+}
+
+func TestTraceContextFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	traceID, spanID := TraceFromContext(TraceContextFromRequest(r))
+	if traceID != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("traceID = %q", traceID)
+	}
+	if spanID != "1" {
+		t.Errorf("spanID = %q", spanID)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	if traceID, _ := TraceFromContext(TraceContextFromRequest(r)); traceID != "" {
+		t.Errorf("traceID = %q, want empty", traceID)
+	}
+}
@@ -6,32 +6,22 @@ import (
 	"crypto/rand"
 	"fmt"
 	"time"
-)
-
-// contextKey is a private type for context keys to avoid collisions.
-type contextKey string
 
-const (
-	// requestIDKey is the context key for storing request IDs.
-	// This matches the ContextKeyRequestID pattern used across services.
-	requestIDKey contextKey = "request_id"
+	"github.com/patdeg/common"
 )
 
 // GetRequestID extracts the request ID from the given context.
 // Returns an empty string if no request ID is found.
+//
+// The ID is shared with common.RequestID, so IDs set by either package's
+// middleware are visible to both.
 func GetRequestID(ctx context.Context) string {
-	if ctx == nil {
-		return ""
-	}
-	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
-		return requestID
-	}
-	return ""
+	return common.RequestID(ctx)
 }
 
 // WithRequestID returns a new context with the given request ID.
 func WithRequestID(ctx context.Context, requestID string) context.Context {
-	return context.WithValue(ctx, requestIDKey, requestID)
+	return common.WithRequestID(ctx, requestID)
 }
 
 // generateULID generates a ULID-like identifier (26 characters, timestamp-prefixed).
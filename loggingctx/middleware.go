@@ -27,7 +27,8 @@ const (
 //	mux := http.NewServeMux()
 //	// ... register handlers ...
 //	handler := loggingctx.RequestIDMiddleware(mux)
-//	http.ListenAndServe(":8080", handler)
+//	srv := common.NewServer(":8080", handler, common.ServerOptions{})
+//	srv.ListenAndServeGraceful(context.Background())
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check if request already has a request ID
@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/patdeg/common"
 )
 
 // TestGetRequestID verifies that GetRequestID extracts request IDs from context.
@@ -205,3 +207,13 @@ func TestLogCall(t *testing.T) {
 		})
 	}
 }
+
+// TestRequestIDSharedWithCommon verifies IDs set by either package are visible to both.
+func TestRequestIDSharedWithCommon(t *testing.T) {
+	if got := common.RequestID(WithRequestID(context.Background(), "from-loggingctx")); got != "from-loggingctx" {
+		t.Errorf("common.RequestID() = %q, want %q", got, "from-loggingctx")
+	}
+	if got := GetRequestID(common.WithRequestID(context.Background(), "from-common")); got != "from-common" {
+		t.Errorf("GetRequestID() = %q, want %q", got, "from-common")
+	}
+}
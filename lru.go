@@ -0,0 +1,141 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRUCache is a thread-safe, size-bounded cache that evicts the least
+// recently used entry once full. Entries optionally expire after a TTL.
+// Use it instead of hand-rolled maps with ad-hoc cleanup:
+//
+//	cache := common.NewLRUCache[string, *User](1000, 5*time.Minute)
+//	cache.Set(id, user)
+//	if user, ok := cache.Get(id); ok { ... }
+type LRUCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // front is most recently used
+	items    map[K]*list.Element
+	now      func() time.Time
+}
+
+// lruEntry is the value stored in the order list
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRUCache creates a cache holding at most capacity entries whose
+// entries expire ttl after they were set. A capacity of zero or less means
+// unbounded and a ttl of zero means entries never expire.
+func NewLRUCache[K comparable, V any](capacity int, ttl time.Duration) *LRUCache[K, V] {
+	return &LRUCache[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value for key and marks it as recently used. Expired
+// entries are removed and reported as missing.
+func (c *LRUCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if c.expired(entry) {
+		c.removeElement(elem)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value under key, resetting its TTL, and evicts the least
+// recently used entry if the cache is over capacity.
+func (c *LRUCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Delete removes key from the cache
+func (c *LRUCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Len returns the number of entries, including expired entries that have
+// not been accessed since they expired.
+func (c *LRUCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Purge removes all entries
+func (c *LRUCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.items = make(map[K]*list.Element)
+}
+
+// expired reports whether entry is past its TTL; callers hold c.mu
+func (c *LRUCache[K, V]) expired(entry *lruEntry[K, V]) bool {
+	return !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt)
+}
+
+// removeElement drops elem from both the list and the index; callers hold c.mu
+func (c *LRUCache[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry[K, V]).key)
+}
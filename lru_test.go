@@ -0,0 +1,128 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestLRUCacheEviction checks that the least recently used entry goes first.
+func TestLRUCacheEviction(t *testing.T) {
+	cache := NewLRUCache[string, int](2, 0)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	// Touch "a" so "b" becomes the eviction candidate
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	cache.Set("c", 3)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := cache.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %d, %v; want %d", key, v, ok, want)
+		}
+	}
+
+	// Updating an existing key does not grow the cache
+	cache.Set("a", 10)
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+	if v, _ := cache.Get("a"); v != 10 {
+		t.Errorf("Get(a) = %d after update, want 10", v)
+	}
+}
+
+// TestLRUCacheTTL checks that entries expire and Set refreshes the TTL.
+func TestLRUCacheTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewLRUCache[string, string](0, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("k", "v")
+	now = now.Add(59 * time.Second)
+	if _, ok := cache.Get("k"); !ok {
+		t.Fatal("expected entry before TTL")
+	}
+
+	cache.Set("k", "v2")
+	now = now.Add(59 * time.Second)
+	if v, ok := cache.Get("k"); !ok || v != "v2" {
+		t.Fatalf("expected refreshed entry, got %q, %v", v, ok)
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := cache.Get("k"); ok {
+		t.Error("expected entry to expire")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Len() = %d after expiry, want 0", cache.Len())
+	}
+}
+
+// TestLRUCacheDeletePurge checks explicit removal.
+func TestLRUCacheDeletePurge(t *testing.T) {
+	cache := NewLRUCache[int, int](0, 0)
+	for i := 0; i < 5; i++ {
+		cache.Set(i, i)
+	}
+	cache.Delete(2)
+	cache.Delete(42)
+	if _, ok := cache.Get(2); ok || cache.Len() != 4 {
+		t.Errorf("Delete failed: Len() = %d", cache.Len())
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Len() = %d after Purge, want 0", cache.Len())
+	}
+	cache.Set(1, 1)
+	if v, ok := cache.Get(1); !ok || v != 1 {
+		t.Error("cache unusable after Purge")
+	}
+}
+
+// TestLRUCacheConcurrent exercises the cache from many goroutines; run with -race.
+func TestLRUCacheConcurrent(t *testing.T) {
+	cache := NewLRUCache[int, int](50, time.Minute)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := (g*31 + i) % 100
+				cache.Set(key, i)
+				cache.Get(key)
+				if i%10 == 0 {
+					cache.Delete(key)
+				}
+				cache.Len()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := cache.Len(); n > 50 {
+		t.Errorf("Len() = %d, exceeds capacity 50", n)
+	}
+}
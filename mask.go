@@ -0,0 +1,100 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Display helpers that partially reveal personal data, e.g. "j***@example.com"
+// on an account page. They are for showing users their own data, not for
+// logs: log messages go through SanitizeMessage, which removes PII
+// entirely. Masks use a fixed "***" so the length of the hidden part is not
+// revealed, and at most half of a value is ever shown.
+
+// maskChars replaces the hidden part of a value
+const maskChars = "***"
+
+// MaskEmail keeps the first character of the local part and the domain:
+// "jane.doe@example.com" becomes "j***@example.com". One-character local
+// parts are fully masked. Values that are not an address are returned as
+// "***", and an empty string stays empty.
+func MaskEmail(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+
+	at := strings.LastIndex(s, "@")
+	if at <= 0 || at == len(s)-1 {
+		return maskChars
+	}
+	local, domain := []rune(s[:at]), s[at+1:]
+
+	if len(local) < 2 {
+		return maskChars + "@" + domain
+	}
+	return string(local[0]) + maskChars + "@" + domain
+}
+
+// MaskPhone replaces every digit except the last two with "*", keeping
+// the formatting: "+1 (415) 555-0134" becomes "+* (***) ***-**34". Numbers
+// with four digits or fewer are fully masked.
+func MaskPhone(s string) string {
+	runes := []rune(strings.TrimSpace(s))
+
+	digits := 0
+	for _, r := range runes {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	keep := 2
+	if digits <= 4 {
+		keep = 0
+	}
+
+	seen := 0
+	for i, r := range runes {
+		if !unicode.IsDigit(r) {
+			continue
+		}
+		seen++
+		if seen <= digits-keep {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
+
+// MaskID keeps the last keepLast characters of an identifier such as an
+// account or card number: MaskID("acct_9f8e7d6c", 4) returns "***7d6c".
+// keepLast is capped at half the length of s, so short IDs are never fully
+// revealed. An empty string stays empty.
+func MaskID(s string, keepLast int) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) == 0 {
+		return ""
+	}
+
+	if keepLast > len(runes)/2 {
+		keepLast = len(runes) / 2
+	}
+	if keepLast <= 0 {
+		return maskChars
+	}
+	return maskChars + string(runes[len(runes)-keepLast:])
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import "testing"
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "Regular address", in: "jane.doe@example.com", want: "j***@example.com"},
+		{name: "Two character local", in: "jd@example.com", want: "j***@example.com"},
+		{name: "One character local", in: "j@example.com", want: "***@example.com"},
+		{name: "Surrounding whitespace", in: "  jane@example.com ", want: "j***@example.com"},
+		{name: "Missing @", in: "jane.example.com", want: "***"},
+		{name: "Missing local", in: "@example.com", want: "***"},
+		{name: "Missing domain", in: "jane@", want: "***"},
+		{name: "Unicode local", in: "élodie@exemple.fr", want: "é***@exemple.fr"},
+		{name: "Unicode domain", in: "yuki@例え.jp", want: "y***@例え.jp"},
+		{name: "Empty", in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskEmail(tt.in); got != tt.want {
+				t.Errorf("MaskEmail(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "Formatted US number", in: "+1 (415) 555-0134", want: "+* (***) ***-**34"},
+		{name: "Digits only", in: "0612345678", want: "********78"},
+		{name: "International", in: "+44 20 7946 0958", want: "+** ** **** **58"},
+		{name: "Short code fully masked", in: "1234", want: "****"},
+		{name: "Full-width digits", in: "０９０１２３４５６７８", want: "*********７８"},
+		{name: "Empty", in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskPhone(tt.in); got != tt.want {
+				t.Errorf("MaskPhone(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskID(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		keepLast int
+		want     string
+	}{
+		{name: "Account ID", in: "acct_9f8e7d6c", keepLast: 4, want: "***7d6c"},
+		{name: "Card number", in: "4242424242424242", keepLast: 4, want: "***4242"},
+		{name: "Capped at half", in: "abc123", keepLast: 5, want: "***123"},
+		{name: "Single character", in: "x", keepLast: 4, want: "***"},
+		{name: "Keep nothing", in: "abc123", keepLast: 0, want: "***"},
+		{name: "Negative keep", in: "abc123", keepLast: -2, want: "***"},
+		{name: "Unicode", in: "日本語テキスト", keepLast: 2, want: "***スト"},
+		{name: "Empty", in: "", keepLast: 4, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskID(tt.in, tt.keepLast); got != tt.want {
+				t.Errorf("MaskID(%q, %d) = %q, want %q", tt.in, tt.keepLast, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+var (
+	// ErrCouponNotFound is returned for unknown coupon codes
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrCouponExpired is returned once a coupon's ExpiresAt has passed
+	ErrCouponExpired = errors.New("coupon expired")
+	// ErrCouponExhausted is returned once a coupon reached MaxRedemptions
+	ErrCouponExhausted = errors.New("coupon redemption limit reached")
+)
+
+// Coupon is a promotional discount. Exactly one of PercentOff or AmountOff
+// is set; AmountOff coupons only apply to amounts in the same Currency.
+type Coupon struct {
+	Code           string     `json:"code"`
+	PercentOff     float64    `json:"percent_off,omitempty"` // 0-100
+	AmountOff      int64      `json:"amount_off,omitempty"`  // In cents
+	Currency       string     `json:"currency,omitempty"`
+	MaxRedemptions int        `json:"max_redemptions,omitempty"` // 0 means unlimited
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	TimesRedeemed  int        `json:"times_redeemed"`
+}
+
+// Discount records a coupon applied to a subscription or charge. It keeps
+// the coupon's terms (PercentOff or FixedOff) so a subscription discount
+// can be recomputed when the plan changes; OriginalAmount, AmountOff and
+// Amount are the figures for the amount it was last computed on.
+type Discount struct {
+	CouponCode     string    `json:"coupon_code"`
+	PercentOff     float64   `json:"percent_off,omitempty"` // Coupon terms
+	FixedOff       int64     `json:"fixed_off,omitempty"`   // Coupon terms, in cents
+	OriginalAmount int64     `json:"original_amount"`       // In cents
+	AmountOff      int64     `json:"amount_off"`            // In cents
+	Amount         int64     `json:"amount"`                // Effective amount, in cents
+	Currency       string    `json:"currency"`
+	AppliedAt      time.Time `json:"applied_at"`
+}
+
+// Off returns the discount in cents the coupon terms give on amount
+func (d *Discount) Off(amount int64, currency string) (int64, error) {
+	terms := Coupon{Code: d.CouponCode, PercentOff: d.PercentOff, AmountOff: d.FixedOff, Currency: d.Currency}
+	return terms.DiscountFor(amount, currency)
+}
+
+// recompute updates the discount figures for a new amount
+func (d *Discount) recompute(amount int64, currency string) error {
+	off, err := d.Off(amount, currency)
+	if err != nil {
+		return err
+	}
+	d.OriginalAmount = amount
+	d.AmountOff = off
+	d.Amount = amount - off
+	d.Currency = currency
+	return nil
+}
+
+// Redemption is one use of a coupon
+type Redemption struct {
+	CouponCode     string    `json:"coupon_code"`
+	CustomerID     string    `json:"customer_id"`
+	SubscriptionID string    `json:"subscription_id,omitempty"`
+	ChargeID       string    `json:"charge_id,omitempty"`
+	AmountOff      int64     `json:"amount_off"` // In cents
+	RedeemedAt     time.Time `json:"redeemed_at"`
+}
+
+// normalizeCouponCode makes coupon codes case-insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks that the coupon is well formed
+func (c *Coupon) Validate() error {
+	if normalizeCouponCode(c.Code) == "" {
+		return fmt.Errorf("coupon code is required")
+	}
+	if (c.PercentOff > 0) == (c.AmountOff > 0) {
+		return fmt.Errorf("coupon %s must set exactly one of percent_off or amount_off", c.Code)
+	}
+	if c.PercentOff > 100 {
+		return fmt.Errorf("coupon %s percent_off must be at most 100", c.Code)
+	}
+	if c.PercentOff < 0 || c.AmountOff < 0 || c.MaxRedemptions < 0 {
+		return fmt.Errorf("coupon %s has negative values", c.Code)
+	}
+	if c.AmountOff > 0 && c.Currency == "" {
+		return fmt.Errorf("coupon %s amount_off requires a currency", c.Code)
+	}
+	return nil
+}
+
+// Redeemable reports why the coupon cannot be used at now, or nil
+func (c *Coupon) Redeemable(now time.Time) error {
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return ErrCouponExpired
+	}
+	if c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	return nil
+}
+
+// DiscountFor returns the discount in cents for amount. Percentages are
+// rounded to the nearest cent and the discount never exceeds amount.
+func (c *Coupon) DiscountFor(amount int64, currency string) (int64, error) {
+	var off int64
+	if c.AmountOff > 0 {
+		if !strings.EqualFold(c.Currency, currency) {
+			return 0, fmt.Errorf("coupon %s is in %s, not %s", c.Code, c.Currency, currency)
+		}
+		off = c.AmountOff
+	} else {
+		off = int64(math.Round(float64(amount) * c.PercentOff / 100))
+	}
+	if off > amount {
+		off = amount
+	}
+	return off, nil
+}
+
+// AddCoupon registers a coupon. Codes are case-insensitive.
+func (m *Manager) AddCoupon(coupon *Coupon) error {
+	if err := coupon.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	m.coupons[coupon.Code] = coupon
+	common.Debug("[PAYMENT] Added coupon: %s", coupon.Code)
+	return nil
+}
+
+// GetCoupon returns a copy of the coupon registered under code
+func (m *Manager) GetCoupon(code string) (*Coupon, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	coupon, ok := m.coupons[normalizeCouponCode(code)]
+	if !ok {
+		return nil, false
+	}
+	c := *coupon
+	return &c, true
+}
+
+// Redemptions returns the recorded uses of a coupon
+func (m *Manager) Redemptions(code string) []Redemption {
+	code = normalizeCouponCode(code)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []Redemption
+	for _, r := range m.redemptions {
+		if r.CouponCode == code {
+			out = append(out, *r)
+		}
+	}
+	return out
+}
+
+// ApplyCoupon discounts a subscription. The discount is stored in
+// Subscription.Discount and applies to every period: SubscriptionAmount
+// returns the discounted recurring amount, and ChangePlan recomputes the
+// discount for the new plan. A subscription carries at most one discount.
+func (m *Manager) ApplyCoupon(ctx context.Context, subscriptionID, code string) (*Discount, error) {
+	sub, err := m.provider.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %v", err)
+	}
+	if sub.Status == StatusCanceled {
+		return nil, fmt.Errorf("subscription %s is canceled", subscriptionID)
+	}
+	if sub.Discount != nil {
+		return nil, fmt.Errorf("subscription %s already has coupon %s", subscriptionID, sub.Discount.CouponCode)
+	}
+
+	plan, ok := m.GetPlan(sub.PlanID)
+	if !ok {
+		return nil, fmt.Errorf("plan not found: %s", sub.PlanID)
+	}
+	redemption, discount, err := m.redeem(code, plan.Amount*subscriptionQuantity(sub), plan.Currency)
+	if err != nil {
+		return nil, err
+	}
+	redemption.CustomerID = sub.CustomerID
+	redemption.SubscriptionID = sub.ID
+
+	sub.Discount = discount
+	sub.UpdatedAt = time.Now()
+	if err := m.provider.UpdateSubscription(ctx, sub); err != nil {
+		m.release(redemption)
+		return nil, fmt.Errorf("failed to update subscription: %v", err)
+	}
+
+	common.Info("[PAYMENT] Applied coupon %s to subscription %s (%d cents off)", discount.CouponCode, subscriptionID, discount.AmountOff)
+	return discount, nil
+}
+
+// SubscriptionAmount returns what a subscription is billed per period: the
+// current plan price times the quantity, less the subscription's discount
+func (m *Manager) SubscriptionAmount(sub *Subscription) (int64, error) {
+	if sub == nil {
+		return 0, fmt.Errorf("subscription is required")
+	}
+	plan, ok := m.GetPlan(sub.PlanID)
+	if !ok {
+		return 0, fmt.Errorf("plan not found: %s", sub.PlanID)
+	}
+
+	amount := plan.Amount * subscriptionQuantity(sub)
+	if sub.Discount == nil {
+		return amount, nil
+	}
+	off, err := sub.Discount.Off(amount, plan.Currency)
+	if err != nil {
+		return 0, err
+	}
+	return amount - off, nil
+}
+
+// subscriptionQuantity returns the billed quantity, at least 1
+func subscriptionQuantity(sub *Subscription) int64 {
+	if sub.Quantity < 1 {
+		return 1
+	}
+	return int64(sub.Quantity)
+}
+
+// ChargeWithCoupon discounts a charge that has not been submitted yet by a
+// coupon, adds tax on the discounted amount and submits it. The coupon is
+// only counted as redeemed if the charge succeeds, and the redemption
+// records the charge ID. A zero Currency defaults to usd.
+func (m *Manager) ChargeWithCoupon(ctx context.Context, charge *Charge, code string) (*Charge, error) {
+	if charge == nil {
+		return nil, fmt.Errorf("charge is required")
+	}
+	if charge.Discount != nil {
+		return nil, fmt.Errorf("charge already has coupon %s", charge.Discount.CouponCode)
+	}
+	if charge.Currency == "" {
+		charge.Currency = "usd"
+	}
+	if charge.CreatedAt.IsZero() {
+		charge.CreatedAt = time.Now()
+	}
+
+	amount := charge.Amount
+	redemption, discount, err := m.redeem(code, amount, charge.Currency)
+	if err != nil {
+		return nil, err
+	}
+	redemption.CustomerID = charge.CustomerID
+	charge.Discount = discount
+	charge.Amount = discount.Amount
+
+	// Leave the charge as it was handed in when it is not submitted
+	fail := func(err error) (*Charge, error) {
+		m.release(redemption)
+		charge.Discount = nil
+		charge.Amount = amount
+		charge.Tax = 0
+		charge.TaxRate = 0
+		return nil, err
+	}
+
+	if err := m.applyChargeTax(ctx, charge); err != nil {
+		return fail(err)
+	}
+	if err := m.provider.ChargePayment(ctx, charge); err != nil {
+		return fail(fmt.Errorf("failed to charge payment: %v", err))
+	}
+
+	m.mu.Lock()
+	redemption.ChargeID = charge.ID
+	m.mu.Unlock()
+	m.recordCharge(charge)
+
+	common.Info("[PAYMENT] Charged %d cents to customer %s with coupon %s", charge.Amount, charge.CustomerID, discount.CouponCode)
+	return charge, nil
+}
+
+// ChargeOneTimeWithCoupon processes a one-time payment in usd discounted
+// by a coupon, see ChargeWithCoupon
+func (m *Manager) ChargeOneTimeWithCoupon(ctx context.Context, customerID string, amount int64, description, code string) (*Charge, error) {
+	return m.ChargeWithCoupon(ctx, &Charge{
+		CustomerID:  customerID,
+		Amount:      amount,
+		Currency:    "usd",
+		Description: description,
+	}, code)
+}
+
+// redeem validates the coupon for amount and counts one redemption
+func (m *Manager) redeem(code string, amount int64, currency string) (*Redemption, *Discount, error) {
+	now := time.Now()
+	code = normalizeCouponCode(code)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	coupon, ok := m.coupons[code]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrCouponNotFound, code)
+	}
+	if err := coupon.Redeemable(now); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", err, code)
+	}
+	off, err := coupon.DiscountFor(amount, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	coupon.TimesRedeemed++
+	redemption := &Redemption{CouponCode: code, AmountOff: off, RedeemedAt: now}
+	m.redemptions = append(m.redemptions, redemption)
+
+	return redemption, &Discount{
+		CouponCode:     code,
+		PercentOff:     coupon.PercentOff,
+		FixedOff:       coupon.AmountOff,
+		OriginalAmount: amount,
+		AmountOff:      off,
+		Amount:         amount - off,
+		Currency:       currency,
+		AppliedAt:      now,
+	}, nil
+}
+
+// release undoes a redemption whose payment operation failed
+func (m *Manager) release(redemption *Redemption) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if coupon, ok := m.coupons[redemption.CouponCode]; ok && coupon.TimesRedeemed > 0 {
+		coupon.TimesRedeemed--
+	}
+	for i, r := range m.redemptions {
+		if r == redemption {
+			m.redemptions = append(m.redemptions[:i], m.redemptions[i+1:]...)
+			break
+		}
+	}
+}
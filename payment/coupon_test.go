@@ -0,0 +1,267 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// couponProvider stores subscriptions and charges in memory
+type couponProvider struct {
+	Provider
+	subs       map[string]*Subscription
+	failCharge bool
+}
+
+func (p *couponProvider) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	sub, ok := p.subs[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	s := *sub
+	return &s, nil
+}
+
+func (p *couponProvider) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	p.subs[sub.ID] = sub
+	return nil
+}
+
+func (p *couponProvider) ChargePayment(ctx context.Context, charge *Charge) error {
+	if p.failCharge {
+		return errors.New("card declined")
+	}
+	charge.ID = "ch_1"
+	charge.Status = ChargeSucceeded
+	return nil
+}
+
+func newCouponManager(t *testing.T) (*Manager, *couponProvider) {
+	t.Helper()
+	provider := &couponProvider{subs: map[string]*Subscription{
+		"sub_1": {ID: "sub_1", CustomerID: "cus_1", PlanID: "pro", Status: StatusActive, Quantity: 2},
+		"sub_2": {ID: "sub_2", CustomerID: "cus_2", PlanID: "pro", Status: StatusActive, Quantity: 1},
+	}}
+	mgr := NewManager(provider)
+	mgr.AddPlan(&Plan{ID: "pro", Amount: 2500, Currency: "usd", Interval: IntervalMonthly, Active: true})
+	return mgr, provider
+}
+
+func TestCouponDiscountFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		amount   int64
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"percent", Coupon{Code: "TEN", PercentOff: 10}, 2500, "usd", 250, false},
+		{"percent rounds", Coupon{Code: "THIRD", PercentOff: 33.333}, 1000, "usd", 333, false},
+		{"fixed", Coupon{Code: "FIVE", AmountOff: 500, Currency: "usd"}, 2500, "usd", 500, false},
+		{"fixed capped at amount", Coupon{Code: "BIG", AmountOff: 5000, Currency: "usd"}, 2500, "usd", 2500, false},
+		{"fixed currency mismatch", Coupon{Code: "EUR", AmountOff: 500, Currency: "eur"}, 2500, "usd", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.coupon.DiscountFor(tt.amount, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiscountFor error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DiscountFor = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddCouponValidation(t *testing.T) {
+	mgr := NewManager(nil)
+	invalid := []*Coupon{
+		{Code: "", PercentOff: 10},
+		{Code: "BOTH", PercentOff: 10, AmountOff: 100, Currency: "usd"},
+		{Code: "NONE"},
+		{Code: "TOOMUCH", PercentOff: 150},
+		{Code: "NOCUR", AmountOff: 100},
+	}
+	for _, c := range invalid {
+		if err := mgr.AddCoupon(c); err == nil {
+			t.Errorf("AddCoupon(%+v) succeeded, want error", c)
+		}
+	}
+}
+
+func TestApplyCoupon(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider := newCouponManager(t)
+
+	if err := mgr.AddCoupon(&Coupon{Code: "launch20", PercentOff: 20}); err != nil {
+		t.Fatalf("AddCoupon failed: %v", err)
+	}
+
+	discount, err := mgr.ApplyCoupon(ctx, "sub_1", "LAUNCH20")
+	if err != nil {
+		t.Fatalf("ApplyCoupon failed: %v", err)
+	}
+	if discount.OriginalAmount != 5000 || discount.AmountOff != 1000 || discount.Amount != 4000 {
+		t.Errorf("unexpected discount: %+v", discount)
+	}
+	if stored := provider.subs["sub_1"].Discount; stored == nil || stored.Amount != 4000 {
+		t.Errorf("discount not recorded on subscription: %+v", stored)
+	}
+
+	if _, err := mgr.ApplyCoupon(ctx, "sub_1", "LAUNCH20"); err == nil {
+		t.Error("expected error applying a second coupon")
+	}
+
+	redemptions := mgr.Redemptions("launch20")
+	if len(redemptions) != 1 || redemptions[0].SubscriptionID != "sub_1" || redemptions[0].CustomerID != "cus_1" {
+		t.Errorf("unexpected redemptions: %+v", redemptions)
+	}
+	if c, _ := mgr.GetCoupon("LAUNCH20"); c.TimesRedeemed != 1 {
+		t.Errorf("TimesRedeemed = %d, want 1", c.TimesRedeemed)
+	}
+}
+
+func TestApplyCouponRejected(t *testing.T) {
+	ctx := context.Background()
+	mgr, _ := newCouponManager(t)
+
+	past := time.Now().Add(-time.Hour)
+	mgr.AddCoupon(&Coupon{Code: "OLD", PercentOff: 50, ExpiresAt: &past})
+	mgr.AddCoupon(&Coupon{Code: "ONCE", AmountOff: 500, Currency: "usd", MaxRedemptions: 1})
+
+	if _, err := mgr.ApplyCoupon(ctx, "sub_1", "OLD"); !errors.Is(err, ErrCouponExpired) {
+		t.Errorf("expired coupon error = %v, want ErrCouponExpired", err)
+	}
+	if _, err := mgr.ApplyCoupon(ctx, "sub_1", "MISSING"); !errors.Is(err, ErrCouponNotFound) {
+		t.Errorf("unknown coupon error = %v, want ErrCouponNotFound", err)
+	}
+
+	if _, err := mgr.ApplyCoupon(ctx, "sub_1", "ONCE"); err != nil {
+		t.Fatalf("first redemption failed: %v", err)
+	}
+	if _, err := mgr.ApplyCoupon(ctx, "sub_2", "ONCE"); !errors.Is(err, ErrCouponExhausted) {
+		t.Errorf("second redemption error = %v, want ErrCouponExhausted", err)
+	}
+}
+
+func TestChargeOneTimeWithCoupon(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider := newCouponManager(t)
+	mgr.AddCoupon(&Coupon{Code: "FIVE", AmountOff: 500, Currency: "usd", MaxRedemptions: 1})
+
+	// A failed charge releases the redemption
+	provider.failCharge = true
+	if _, err := mgr.ChargeOneTimeWithCoupon(ctx, "cus_1", 2000, "Setup fee", "FIVE"); err == nil {
+		t.Fatal("expected charge failure")
+	}
+	if n := len(mgr.Redemptions("FIVE")); n != 0 {
+		t.Fatalf("failed charge left %d redemptions", n)
+	}
+
+	provider.failCharge = false
+	charge, err := mgr.ChargeOneTimeWithCoupon(ctx, "cus_1", 2000, "Setup fee", "FIVE")
+	if err != nil {
+		t.Fatalf("ChargeOneTimeWithCoupon failed: %v", err)
+	}
+	if charge.Amount != 1500 || charge.Discount == nil || charge.Discount.OriginalAmount != 2000 {
+		t.Errorf("unexpected charge: %+v", charge)
+	}
+	if r := mgr.Redemptions("FIVE"); len(r) != 1 || r[0].ChargeID != "ch_1" {
+		t.Errorf("unexpected redemptions: %+v", r)
+	}
+
+	direct := &Charge{CustomerID: "cus_2", Amount: 2000, Currency: "usd"}
+	if _, err := mgr.ChargeWithCoupon(ctx, direct, "FIVE"); !errors.Is(err, ErrCouponExhausted) {
+		t.Errorf("ChargeWithCoupon error = %v, want ErrCouponExhausted", err)
+	}
+	if direct.Amount != 2000 {
+		t.Errorf("rejected coupon changed the amount to %d", direct.Amount)
+	}
+}
+
+func TestChargeWithCouponFailureLeavesCharge(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider := newCouponManager(t)
+	mgr.AddCoupon(&Coupon{Code: "HALF", PercentOff: 50})
+
+	provider.failCharge = true
+	charge := &Charge{CustomerID: "cus_1", Amount: 2000, Currency: "usd", Description: "Setup fee"}
+	if _, err := mgr.ChargeWithCoupon(ctx, charge, "HALF"); err == nil {
+		t.Fatal("expected charge failure")
+	}
+	if charge.Amount != 2000 || charge.Discount != nil {
+		t.Errorf("failed charge was left modified: %+v", charge)
+	}
+	if c, _ := mgr.GetCoupon("HALF"); c.TimesRedeemed != 0 {
+		t.Errorf("TimesRedeemed = %d, want 0", c.TimesRedeemed)
+	}
+
+	// The same charge can be retried once the card works
+	provider.failCharge = false
+	if _, err := mgr.ChargeWithCoupon(ctx, charge, "HALF"); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if charge.Amount != 1000 {
+		t.Errorf("Amount = %d, want 1000", charge.Amount)
+	}
+	if r := mgr.Redemptions("HALF"); len(r) != 1 || r[0].ChargeID != "ch_1" || r[0].CustomerID != "cus_1" {
+		t.Errorf("unexpected redemptions: %+v", r)
+	}
+}
+
+func TestSubscriptionDiscountFollowsPlan(t *testing.T) {
+	tests := []struct {
+		name       string
+		coupon     *Coupon
+		wantBefore int64 // Two seats of the 2500 cent plan
+		wantAfter  int64 // Two seats of the 4000 cent plan
+	}{
+		{"Percent off", &Coupon{Code: "PCT", PercentOff: 20}, 4000, 6400},
+		{"Amount off", &Coupon{Code: "FIXED", AmountOff: 700, Currency: "usd"}, 4300, 7300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mgr, provider := newCouponManager(t)
+			mgr.AddPlan(&Plan{ID: "team", Amount: 4000, Currency: "usd", Interval: IntervalMonthly, Active: true})
+			mgr.AddCoupon(tt.coupon)
+
+			if _, err := mgr.ApplyCoupon(ctx, "sub_1", tt.coupon.Code); err != nil {
+				t.Fatalf("ApplyCoupon failed: %v", err)
+			}
+			if got, err := mgr.SubscriptionAmount(provider.subs["sub_1"]); err != nil || got != tt.wantBefore {
+				t.Errorf("SubscriptionAmount = %d, %v, want %d", got, err, tt.wantBefore)
+			}
+
+			if err := mgr.ChangePlan(ctx, "sub_1", "team"); err != nil {
+				t.Fatalf("ChangePlan failed: %v", err)
+			}
+			sub := provider.subs["sub_1"]
+			if got, err := mgr.SubscriptionAmount(sub); err != nil || got != tt.wantAfter {
+				t.Errorf("SubscriptionAmount after ChangePlan = %d, %v, want %d", got, err, tt.wantAfter)
+			}
+			if sub.Discount.Amount != tt.wantAfter || sub.Discount.OriginalAmount != 8000 {
+				t.Errorf("discount not recomputed: %+v", sub.Discount)
+			}
+		})
+	}
+}
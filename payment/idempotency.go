@@ -0,0 +1,159 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+// DefaultIdempotencyTTL is how long the in-memory store remembers a key
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// ErrIdempotencyKeyReused is returned when a key is sent again with
+// different parameters than the request that first used it
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with different parameters")
+
+// IdempotentProvider is implemented by providers that deduplicate requests
+// by Charge.IdempotencyKey and Subscription.IdempotencyKey themselves, for
+// example by sending Stripe's Idempotency-Key header. When
+// SupportsIdempotency reports true the manager only forwards the key;
+// otherwise it deduplicates in memory.
+type IdempotentProvider interface {
+	SupportsIdempotency() bool
+}
+
+// ChargeOneTimeIdempotent is ChargeOneTime for requests that may be
+// retried. A second call with the same idempotencyKey returns the original
+// charge instead of charging again, and a call that arrives while the first
+// is still in flight waits for its result. Reusing a key with a different
+// customer, amount or description returns ErrIdempotencyKeyReused.
+//
+// Without provider support keys are kept in memory for
+// DefaultIdempotencyTTL, per Manager. Failed charges are forgotten so they
+// can be retried. An empty key behaves like ChargeOneTime.
+func (m *Manager) ChargeOneTimeIdempotent(ctx context.Context, idempotencyKey, customerID string, amount int64, description string) (*Charge, error) {
+	if idempotencyKey == "" || m.nativeIdempotency() {
+		return m.chargeOneTime(ctx, idempotencyKey, customerID, amount, description)
+	}
+
+	fingerprint := fmt.Sprintf("%s|%d|%s", customerID, amount, description)
+	return runIdempotent(ctx, m.idempotency, "charge:"+idempotencyKey, fingerprint, func() (*Charge, error) {
+		return m.chargeOneTime(ctx, idempotencyKey, customerID, amount, description)
+	})
+}
+
+// SubscribeIdempotent is Subscribe for requests that may be retried, with
+// the same guarantees as ChargeOneTimeIdempotent. The key is scoped to
+// subscriptions, so it never collides with a charge using the same key.
+func (m *Manager) SubscribeIdempotent(ctx context.Context, idempotencyKey, customerID, planID string) (*Subscription, error) {
+	if idempotencyKey == "" || m.nativeIdempotency() {
+		return m.subscribe(ctx, idempotencyKey, customerID, planID)
+	}
+
+	fingerprint := customerID + "|" + planID
+	return runIdempotent(ctx, m.idempotency, "subscription:"+idempotencyKey, fingerprint, func() (*Subscription, error) {
+		return m.subscribe(ctx, idempotencyKey, customerID, planID)
+	})
+}
+
+// nativeIdempotency reports whether the provider deduplicates by key itself
+func (m *Manager) nativeIdempotency() bool {
+	p, ok := m.provider.(IdempotentProvider)
+	return ok && p.SupportsIdempotency()
+}
+
+// idempotencyEntry is the outcome of the first request made with a key.
+// done is closed once result and err are set.
+type idempotencyEntry struct {
+	fingerprint string
+	done        chan struct{}
+	result      any
+	err         error
+	expires     time.Time
+}
+
+// idempotencyStore remembers results by key for providers without native
+// idempotency
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		ttl:     DefaultIdempotencyTTL,
+		now:     time.Now,
+	}
+}
+
+// runIdempotent calls fn once per key and returns a copy of its result to
+// every caller using that key. Errors are returned to callers already
+// waiting, then the key is released for a fresh attempt.
+func runIdempotent[T any](ctx context.Context, s *idempotencyStore, key, fingerprint string, fn func() (*T, error)) (*T, error) {
+	s.mu.Lock()
+	now := s.now()
+	for k, e := range s.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	if e, ok := s.entries[key]; ok {
+		s.mu.Unlock()
+		if e.fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if e.err != nil {
+			return nil, e.err
+		}
+		common.Debug("[PAYMENT] Replayed result for idempotency key %s", key)
+		result := *e.result.(*T)
+		return &result, nil
+	}
+
+	e := &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+	s.entries[key] = e
+	s.mu.Unlock()
+
+	result, err := fn()
+
+	s.mu.Lock()
+	if err != nil {
+		e.err = err
+		delete(s.entries, key)
+	} else {
+		stored := *result
+		e.result = &stored
+		e.expires = s.now().Add(s.ttl)
+	}
+	s.mu.Unlock()
+	close(e.done)
+
+	return result, err
+}
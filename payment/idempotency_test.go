@@ -0,0 +1,231 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newIdempotencyManager returns a manager backed by a MockProvider with
+// customers cus_1 and cus_2
+func newIdempotencyManager(t *testing.T) (*Manager, *MockProvider) {
+	t.Helper()
+	provider := NewMockProvider()
+	for _, id := range []string{"cus_1", "cus_2"} {
+		if err := provider.CreateCustomer(context.Background(), &Customer{ID: id}); err != nil {
+			t.Fatalf("CreateCustomer failed: %v", err)
+		}
+	}
+	return NewManager(provider), provider
+}
+
+// blockingProvider holds every charge until release is closed
+type blockingProvider struct {
+	*MockProvider
+	release chan struct{}
+}
+
+func (p *blockingProvider) ChargePayment(ctx context.Context, charge *Charge) error {
+	<-p.release
+	return p.MockProvider.ChargePayment(ctx, charge)
+}
+
+func TestChargeOneTimeIdempotent(t *testing.T) {
+	mgr, provider := newIdempotencyManager(t)
+	ctx := context.Background()
+
+	first, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1")
+	if err != nil {
+		t.Fatalf("ChargeOneTimeIdempotent() error = %v", err)
+	}
+	second, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1")
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+
+	if got := len(provider.Charges()); got != 1 {
+		t.Errorf("provider charged %d times, want 1", got)
+	}
+	if second.ID != first.ID || second.IdempotencyKey != "order-1" {
+		t.Errorf("retry returned %+v, want charge %s", second, first.ID)
+	}
+
+	if _, err := mgr.ChargeOneTimeIdempotent(ctx, "order-2", "cus_1", 1000, "Order 1"); err != nil {
+		t.Fatalf("new key error = %v", err)
+	}
+	if got := len(provider.Charges()); got != 2 {
+		t.Errorf("provider charged %d times after new key, want 2", got)
+	}
+}
+
+func TestChargeOneTimeIdempotentKeyReused(t *testing.T) {
+	tests := []struct {
+		name        string
+		customerID  string
+		amount      int64
+		description string
+	}{
+		{"different amount", "cus_1", 2000, "Order 1"},
+		{"different customer", "cus_2", 1000, "Order 1"},
+		{"different description", "cus_1", 1000, "Order 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, provider := newIdempotencyManager(t)
+			ctx := context.Background()
+
+			if _, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1"); err != nil {
+				t.Fatalf("ChargeOneTimeIdempotent() error = %v", err)
+			}
+			_, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", tt.customerID, tt.amount, tt.description)
+			if !errors.Is(err, ErrIdempotencyKeyReused) {
+				t.Errorf("error = %v, want ErrIdempotencyKeyReused", err)
+			}
+			if got := len(provider.Charges()); got != 1 {
+				t.Errorf("provider charged %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestChargeOneTimeIdempotentConcurrent(t *testing.T) {
+	_, mock := newIdempotencyManager(t)
+	provider := &blockingProvider{MockProvider: mock, release: make(chan struct{})}
+	mgr := NewManager(provider)
+
+	const callers = 10
+	ids := make([]string, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			charge, err := mgr.ChargeOneTimeIdempotent(context.Background(), "order-1", "cus_1", 1000, "Order 1")
+			if err != nil {
+				t.Errorf("caller %d error = %v", i, err)
+				return
+			}
+			ids[i] = charge.ID
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if got := len(mock.Charges()); got != 1 {
+		t.Errorf("provider charged %d times, want 1", got)
+	}
+	for i, id := range ids {
+		if id != "ch_1" {
+			t.Errorf("caller %d got charge %q, want ch_1", i, id)
+		}
+	}
+}
+
+func TestChargeOneTimeIdempotentRetriesFailure(t *testing.T) {
+	mgr, provider := newIdempotencyManager(t)
+	provider.FailNextCharge(errors.New("network error"))
+	ctx := context.Background()
+
+	if _, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1"); err == nil {
+		t.Fatal("Expected the failed charge to return an error")
+	}
+
+	charge, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1")
+	if err != nil {
+		t.Fatalf("retry after failure error = %v", err)
+	}
+	if charge.Status != ChargeSucceeded || len(provider.Charges()) != 1 {
+		t.Errorf("retry = %+v after %d charges, want one successful charge", charge, len(provider.Charges()))
+	}
+}
+
+func TestChargeOneTimeIdempotentExpiry(t *testing.T) {
+	mgr, provider := newIdempotencyManager(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mgr.idempotency.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1"); err != nil {
+		t.Fatalf("ChargeOneTimeIdempotent() error = %v", err)
+	}
+	now = now.Add(DefaultIdempotencyTTL + time.Minute)
+	if _, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1"); err != nil {
+		t.Fatalf("ChargeOneTimeIdempotent() after expiry error = %v", err)
+	}
+	if got := len(provider.Charges()); got != 2 {
+		t.Errorf("provider charged %d times, want 2 after the key expired", got)
+	}
+}
+
+func TestChargeOneTimeIdempotentNativeProvider(t *testing.T) {
+	mgr, provider := newIdempotencyManager(t)
+	provider.SetIdempotency(true)
+	ctx := context.Background()
+
+	var ids []string
+	for range 2 {
+		charge, err := mgr.ChargeOneTimeIdempotent(ctx, "order-1", "cus_1", 1000, "Order 1")
+		if err != nil {
+			t.Fatalf("ChargeOneTimeIdempotent() error = %v", err)
+		}
+		ids = append(ids, charge.ID)
+	}
+
+	// Every request reaches the provider with the key, and it deduplicates
+	charges := provider.Charges()
+	if len(charges) != 1 || charges[0].IdempotencyKey != "order-1" {
+		t.Errorf("provider charges = %+v, want one charge keyed order-1", charges)
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("retry returned charge %s, want %s", ids[1], ids[0])
+	}
+	if len(mgr.idempotency.entries) != 0 {
+		t.Errorf("Expected no in-memory entries, got %d", len(mgr.idempotency.entries))
+	}
+}
+
+func TestSubscribeIdempotent(t *testing.T) {
+	mgr, provider := newIdempotencyManager(t)
+	mgr.AddPlan(&Plan{ID: "pro", Amount: 2500, Currency: "usd", Interval: IntervalMonthly, Active: true})
+	ctx := context.Background()
+
+	first, err := mgr.SubscribeIdempotent(ctx, "signup-1", "cus_1", "pro")
+	if err != nil {
+		t.Fatalf("SubscribeIdempotent() error = %v", err)
+	}
+	second, err := mgr.SubscribeIdempotent(ctx, "signup-1", "cus_1", "pro")
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+	subs, _ := provider.ListSubscriptions(ctx)
+	if second.ID != first.ID || len(subs) != 1 {
+		t.Errorf("retry created %d subscriptions, want 1", len(subs))
+	}
+
+	if _, err := mgr.SubscribeIdempotent(ctx, "signup-1", "cus_2", "pro"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("error = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// Keys are scoped per operation
+	if _, err := mgr.ChargeOneTimeIdempotent(ctx, "signup-1", "cus_1", 1000, "Setup fee"); err != nil {
+		t.Errorf("charge with subscription key error = %v", err)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+// Invoice PDFs are written directly in PDF 1.4 syntax using the standard
+// Helvetica fonts, which every viewer ships, so no font embedding or third
+// party renderer is needed. Content streams are left uncompressed; a
+// one-page invoice is only a few kilobytes.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InvoicePDFOptions brands a generated invoice. All fields are optional.
+type InvoicePDFOptions struct {
+	IssuerName    string    // Company name printed at the top
+	IssuerAddress []string  // Address lines under the company name
+	Customer      *Customer // Printed under "Bill to"; defaults to the invoice CustomerID
+	Footer        string    // Closing line, e.g. payment instructions
+}
+
+// DocumentStore persists generated documents and returns a URL where they
+// can be retrieved, e.g. a Cloud Storage signed URL.
+type DocumentStore interface {
+	Put(ctx context.Context, name, contentType string, data []byte) (url string, err error)
+}
+
+// Page geometry in points (US Letter)
+const (
+	pdfPageWidth  = 612
+	pdfPageHeight = 792
+	pdfMargin     = 50
+	pdfLineHeight = 16
+)
+
+// GenerateInvoicePDF renders inv as a single- or multi-page PDF document
+// with the customer, line items, totals and due date.
+func GenerateInvoicePDF(inv *Invoice) ([]byte, error) {
+	return GenerateInvoicePDFWithOptions(inv, nil)
+}
+
+// GenerateInvoicePDFWithOptions renders inv with the given branding. A nil
+// opts renders an unbranded invoice.
+func GenerateInvoicePDFWithOptions(inv *Invoice, opts *InvoicePDFOptions) ([]byte, error) {
+	if inv == nil {
+		return nil, fmt.Errorf("invoice is required")
+	}
+	if inv.Number == "" && inv.ID == "" {
+		return nil, fmt.Errorf("invoice number or ID is required")
+	}
+	if opts == nil {
+		opts = &InvoicePDFOptions{}
+	}
+
+	r := &invoiceRenderer{}
+	r.newPage()
+
+	// Issuer block on the left, invoice details on the right
+	top := r.y
+	if opts.IssuerName != "" {
+		r.text(pdfMargin, r.y, pdfFontBold, 18, opts.IssuerName)
+		r.y -= 22
+	}
+	for _, line := range opts.IssuerAddress {
+		r.line(pdfMargin, pdfFontRegular, 10, line)
+	}
+
+	detailsY := top
+	r.textRight(pdfPageWidth-pdfMargin, detailsY, pdfFontBold, 18, "INVOICE")
+	detailsY -= 22
+	details := []string{"Invoice #: " + invoiceNumber(inv)}
+	if !inv.CreatedAt.IsZero() {
+		details = append(details, "Date: "+formatInvoiceDate(inv.CreatedAt))
+	}
+	if !inv.DueDate.IsZero() {
+		details = append(details, "Due date: "+formatInvoiceDate(inv.DueDate))
+	}
+	if inv.Status != "" {
+		details = append(details, "Status: "+strings.ToUpper(string(inv.Status)))
+	}
+	for _, d := range details {
+		r.textRight(pdfPageWidth-pdfMargin, detailsY, pdfFontRegular, 10, d)
+		detailsY -= 14
+	}
+	if detailsY < r.y {
+		r.y = detailsY
+	}
+	r.y -= 20
+
+	// Bill to
+	r.line(pdfMargin, pdfFontBold, 11, "Bill to")
+	for _, line := range billToLines(inv, opts.Customer) {
+		r.line(pdfMargin, pdfFontRegular, 10, line)
+	}
+	r.y -= 20
+
+	// Line items
+	r.tableHeader()
+	var subtotal int64
+	var taxLines []InvoiceLine
+	for _, item := range inv.Lines {
+		if item.Tax {
+			taxLines = append(taxLines, item)
+			continue
+		}
+		if r.y < pdfMargin+4*pdfLineHeight {
+			r.newPage()
+			r.tableHeader()
+		}
+		amount := lineAmount(item)
+		subtotal += amount
+
+		r.text(pdfMargin, r.y, pdfFontRegular, 10, truncateToWidth(item.Description, 10, 300))
+		r.textRight(400, r.y, pdfFontRegular, 10, fmt.Sprintf("%d", item.Quantity))
+		r.textRight(480, r.y, pdfFontRegular, 10, formatInvoiceAmount(item.UnitPrice, inv.Currency))
+		r.textRight(pdfPageWidth-pdfMargin, r.y, pdfFontRegular, 10, formatInvoiceAmount(amount, inv.Currency))
+		r.y -= pdfLineHeight
+	}
+	r.rule()
+
+	// Totals
+	if r.y < pdfMargin+4*pdfLineHeight {
+		r.newPage()
+	}
+	total := inv.Amount
+	if total == 0 {
+		total = subtotal
+		for _, tax := range taxLines {
+			total += lineAmount(tax)
+		}
+	}
+	r.totalLine(pdfFontRegular, "Subtotal", formatInvoiceAmount(subtotal, inv.Currency))
+	for _, tax := range taxLines {
+		r.totalLine(pdfFontRegular, tax.Description, formatInvoiceAmount(lineAmount(tax), inv.Currency))
+	}
+	r.totalLine(pdfFontBold, "Total", formatInvoiceAmount(total, inv.Currency))
+	if inv.Status == InvoicePaid {
+		r.totalLine(pdfFontRegular, "Amount due", formatInvoiceAmount(0, inv.Currency))
+	} else {
+		r.totalLine(pdfFontBold, "Amount due", formatInvoiceAmount(total, inv.Currency))
+	}
+
+	if opts.Footer != "" {
+		r.y -= 30
+		r.line(pdfMargin, pdfFontRegular, 9, opts.Footer)
+	}
+
+	return r.bytes(), nil
+}
+
+// StoreInvoicePDF renders inv, saves it through store as
+// "invoices/<number>.pdf" and sets inv.PDFUrl to the returned URL.
+func StoreInvoicePDF(ctx context.Context, store DocumentStore, inv *Invoice, opts *InvoicePDFOptions) error {
+	if store == nil {
+		return fmt.Errorf("document store is required")
+	}
+	data, err := GenerateInvoicePDFWithOptions(inv, opts)
+	if err != nil {
+		return fmt.Errorf("failed to render invoice PDF: %w", err)
+	}
+
+	name := "invoices/" + safeDocumentName(invoiceNumber(inv)) + ".pdf"
+	url, err := store.Put(ctx, name, "application/pdf", data)
+	if err != nil {
+		return fmt.Errorf("failed to store invoice PDF: %w", err)
+	}
+
+	inv.PDFUrl = url
+	return nil
+}
+
+// invoiceNumber prefers the human-facing number over the internal ID
+func invoiceNumber(inv *Invoice) string {
+	if inv.Number != "" {
+		return inv.Number
+	}
+	return inv.ID
+}
+
+// safeDocumentName keeps storage object names to a conservative charset
+func safeDocumentName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// billToLines describes the customer, falling back to the customer ID
+func billToLines(inv *Invoice, c *Customer) []string {
+	if c == nil {
+		return []string{inv.CustomerID}
+	}
+	var lines []string
+	for _, s := range []string{c.Name, c.Company, c.Email} {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+	if a := c.Address; a != nil {
+		for _, s := range []string{a.Line1, a.Line2, strings.TrimSpace(strings.Join([]string{a.City, a.State, a.PostalCode}, " ")), a.Country} {
+			if s != "" {
+				lines = append(lines, s)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, inv.CustomerID)
+	}
+	return lines
+}
+
+// formatInvoiceDate renders dates unambiguously for international customers
+func formatInvoiceDate(t time.Time) string {
+	return t.Format("January 2, 2006")
+}
+
+// formatInvoiceAmount renders cents as "USD 1,234.56"
+func formatInvoiceAmount(cents int64, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	units := fmt.Sprintf("%d", cents/100)
+	for i := len(units) - 3; i > 0; i -= 3 {
+		units = units[:i] + "," + units[i:]
+	}
+	amount := fmt.Sprintf("%s%s.%02d", sign, units, cents%100)
+	if currency == "" {
+		return amount
+	}
+	return strings.ToUpper(currency) + " " + amount
+}
+
+// PDF font resource names
+const (
+	pdfFontRegular = "F1"
+	pdfFontBold    = "F2"
+)
+
+// invoiceRenderer accumulates page content streams
+type invoiceRenderer struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (r *invoiceRenderer) newPage() {
+	r.pages = append(r.pages, &bytes.Buffer{})
+	r.y = pdfPageHeight - pdfMargin
+}
+
+func (r *invoiceRenderer) current() *bytes.Buffer {
+	return r.pages[len(r.pages)-1]
+}
+
+// text draws s with its baseline starting at (x, y)
+func (r *invoiceRenderer) text(x, y float64, font string, size float64, s string) {
+	fmt.Fprintf(r.current(), "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+// textRight draws s so that it ends at x
+func (r *invoiceRenderer) textRight(x, y float64, font string, size float64, s string) {
+	r.text(x-textWidth(s, size), y, font, size, s)
+}
+
+// line draws s at the current position and moves down
+func (r *invoiceRenderer) line(x float64, font string, size float64, s string) {
+	r.text(x, r.y, font, size, s)
+	r.y -= size + 4
+}
+
+// rule draws a horizontal separator across the content area
+func (r *invoiceRenderer) rule() {
+	y := r.y + pdfLineHeight - 4
+	fmt.Fprintf(r.current(), "0.5 w %d %.2f m %d %.2f l S\n", pdfMargin, y, pdfPageWidth-pdfMargin, y)
+	r.y -= 6
+}
+
+func (r *invoiceRenderer) tableHeader() {
+	r.text(pdfMargin, r.y, pdfFontBold, 10, "Description")
+	r.textRight(400, r.y, pdfFontBold, 10, "Qty")
+	r.textRight(480, r.y, pdfFontBold, 10, "Unit price")
+	r.textRight(pdfPageWidth-pdfMargin, r.y, pdfFontBold, 10, "Amount")
+	r.y -= pdfLineHeight
+	r.rule()
+}
+
+func (r *invoiceRenderer) totalLine(font, label, amount string) {
+	r.textRight(480, r.y, font, 10, label)
+	r.textRight(pdfPageWidth-pdfMargin, r.y, font, 10, amount)
+	r.y -= pdfLineHeight
+}
+
+// bytes assembles the PDF file with a cross-reference table
+func (r *invoiceRenderer) bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; each page then takes a page and a content object
+	const firstPage = 5
+	kids := make([]string, len(r.pages))
+	for i := range r.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(r.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range r.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pdfFontRegular, pdfFontBold, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfEscape converts s to a WinAnsi literal string body. Characters outside
+// Latin-1 are replaced with '?' since the standard fonts cannot show them.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '\\' || c == '(' || c == ')':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteRune(c)
+		case c >= 0xa0 && c <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// helveticaWidths holds Helvetica glyph widths (per 1000 units) for ASCII
+// 32-126, used to right-align amounts.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space - /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 - ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ - O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P - _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` - o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p - ~
+}
+
+// textWidth approximates the rendered width of s in points
+func textWidth(s string, size float64) float64 {
+	units := 0
+	for _, c := range s {
+		if c >= 32 && c <= 126 {
+			units += helveticaWidths[c-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// truncateToWidth shortens s with "..." so it fits within maxWidth points
+func truncateToWidth(s string, size, maxWidth float64) string {
+	if textWidth(s, size) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// showTextPattern matches literal strings drawn with the Tj operator
+var showTextPattern = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\) Tj`)
+
+// extractPDFText returns the text drawn by the uncompressed content streams
+func extractPDFText(data []byte) string {
+	var out []string
+	for _, m := range showTextPattern.FindAllSubmatch(data, -1) {
+		var b strings.Builder
+		s := string(m[1])
+		for i := 0; i < len(s); i++ {
+			if s[i] != '\\' || i+1 >= len(s) {
+				b.WriteByte(s[i])
+				continue
+			}
+			i++
+			if i+2 < len(s) && s[i] >= '0' && s[i] <= '7' {
+				n, _ := strconv.ParseUint(s[i:i+3], 8, 8)
+				b.WriteRune(rune(n))
+				i += 2
+				continue
+			}
+			b.WriteByte(s[i])
+		}
+		out = append(out, b.String())
+	}
+	return strings.Join(out, "\n")
+}
+
+func testInvoice() *Invoice {
+	return &Invoice{
+		ID:         "inv_1",
+		CustomerID: "cus_1",
+		Number:     "INV-2025-0042",
+		Status:     InvoiceOpen,
+		Amount:     123456,
+		Currency:   "usd",
+		DueDate:    time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC),
+		CreatedAt:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Lines: []InvoiceLine{
+			{Description: "Pro plan (monthly)", Quantity: 1, UnitPrice: 100000, Amount: 100000},
+			{Description: "Extra seats (Team)", Quantity: 3, UnitPrice: 7818, Amount: 23454},
+			{Description: "Onboarding call", Quantity: 1, UnitPrice: 2},
+		},
+	}
+}
+
+func TestGenerateInvoicePDF(t *testing.T) {
+	data, err := GenerateInvoicePDFWithOptions(testInvoice(), &InvoicePDFOptions{
+		IssuerName:    "Example Corp",
+		IssuerAddress: []string{"1 Main Street", "Springfield"},
+		Customer:      &Customer{Name: "José Example", Email: "billing@example.com"},
+		Footer:        "Thank you for your business.",
+	})
+	if err != nil {
+		t.Fatalf("GenerateInvoicePDF failed: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatal("expected non-empty PDF")
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Error("output is not framed as a PDF document")
+	}
+
+	text := extractPDFText(data)
+	for _, want := range []string{
+		"INV-2025-0042",
+		"Example Corp",
+		"José Example",
+		"billing@example.com",
+		"Pro plan (monthly)",
+		"Extra seats (Team)",
+		"USD 1,000.00",
+		"USD 0.02",
+		"USD 1,234.56",
+		"Due date: March 31, 2025",
+		"Thank you for your business.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF text missing %q", want)
+		}
+	}
+}
+
+func TestGenerateInvoicePDFXref(t *testing.T) {
+	data, err := GenerateInvoicePDF(testInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoicePDF failed: %v", err)
+	}
+
+	// Every xref entry must point at the start of its object
+	start := bytes.LastIndex(data, []byte("startxref\n"))
+	xref, err := strconv.Atoi(strings.Fields(string(data[start+len("startxref\n"):]))[0])
+	if err != nil || !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	lines := strings.Split(string(data[xref:]), "\n")
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for obj := 1; obj < count; obj++ {
+		off, _ := strconv.Atoi(lines[2+obj][:10])
+		if !bytes.HasPrefix(data[off:], []byte(fmt.Sprintf("%d 0 obj", obj))) {
+			t.Errorf("xref entry for object %d points at wrong offset %d", obj, off)
+		}
+	}
+}
+
+func TestGenerateInvoicePDFPagination(t *testing.T) {
+	inv := testInvoice()
+	inv.Lines = nil
+	for i := 0; i < 50; i++ {
+		inv.Lines = append(inv.Lines, InvoiceLine{Description: fmt.Sprintf("Item %d", i), Quantity: 1, UnitPrice: 100})
+	}
+
+	data, err := GenerateInvoicePDF(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoicePDF failed: %v", err)
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Error("expected long invoice to span two pages")
+	}
+	text := extractPDFText(data)
+	if !strings.Contains(text, "Item 49") || !strings.Contains(text, "USD 50.00") {
+		t.Error("expected last line and subtotal on the final page")
+	}
+}
+
+func TestGenerateInvoicePDFErrors(t *testing.T) {
+	if _, err := GenerateInvoicePDF(nil); err == nil {
+		t.Error("expected error for nil invoice")
+	}
+	if _, err := GenerateInvoicePDF(&Invoice{}); err == nil {
+		t.Error("expected error for invoice without number or ID")
+	}
+}
+
+// memoryDocumentStore keeps documents in memory for tests
+type memoryDocumentStore struct {
+	docs map[string][]byte
+	err  error
+}
+
+func (s *memoryDocumentStore) Put(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if s.docs == nil {
+		s.docs = map[string][]byte{}
+	}
+	s.docs[name] = data
+	return "https://files.example.com/" + name, nil
+}
+
+func TestStoreInvoicePDF(t *testing.T) {
+	store := &memoryDocumentStore{}
+	inv := testInvoice()
+
+	if err := StoreInvoicePDF(context.Background(), store, inv, nil); err != nil {
+		t.Fatalf("StoreInvoicePDF failed: %v", err)
+	}
+	if inv.PDFUrl != "https://files.example.com/invoices/INV-2025-0042.pdf" {
+		t.Errorf("PDFUrl = %q", inv.PDFUrl)
+	}
+	if data := store.docs["invoices/INV-2025-0042.pdf"]; !strings.Contains(extractPDFText(data), "INV-2025-0042") {
+		t.Error("stored document does not contain the invoice number")
+	}
+
+	failing := &memoryDocumentStore{err: fmt.Errorf("bucket unavailable")}
+	inv = testInvoice()
+	if err := StoreInvoicePDF(context.Background(), failing, inv, nil); err == nil {
+		t.Error("expected store error to be returned")
+	}
+	if inv.PDFUrl != "" {
+		t.Error("PDFUrl must not be set when storing fails")
+	}
+}
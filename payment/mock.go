@@ -0,0 +1,625 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrMockNotFound is returned by MockProvider for unknown IDs
+var ErrMockNotFound = errors.New("not found")
+
+// MockProvider is an in-memory Provider for tests and local development.
+// It assigns deterministic IDs ("cus_1", "sub_1", "pm_1", "ch_1", "re_1",
+// "in_1", counted per kind), applies the state transitions a real
+// provider would, and can be told to fail the next call to a method:
+//
+//	provider := payment.NewMockProvider()
+//	mgr := payment.NewManager(provider)
+//	customer, _ := mgr.CreateCustomer(ctx, "jane@example.com", "Jane")
+//	provider.FailNextCharge(errors.New("card declined"))
+//	_, err := mgr.ChargeOneTime(ctx, customer.ID, 1000, "Setup fee") // declined
+//
+// It also implements ChargeGetter and SubscriptionLister, so Refund and the
+// reminder queries work against it, and IdempotentProvider once
+// SetIdempotency enables key deduplication. Values are deep-copied in and
+// out, including maps, time pointers and nested structs, so callers cannot
+// change stored state behind its back. It is safe for concurrent use.
+type MockProvider struct {
+	mu            sync.Mutex
+	customers     map[string]*Customer
+	subscriptions map[string]*Subscription
+	subOrder      []string // subscription IDs in creation order
+	methods       map[string]*PaymentMethod
+	charges       map[string]*Charge
+	chargeOrder   []string // charge IDs in creation order
+	refunds       []*Refund
+	invoices      []*Invoice
+	counters      map[string]int // ID prefix -> last number issued
+	failures      map[string]error
+	idempotent    bool
+	keys          map[string]string // "charge:" or "subscription:" + idempotency key -> ID
+	now           func() time.Time
+}
+
+// NewMockProvider returns an empty MockProvider
+func NewMockProvider() *MockProvider {
+	return &MockProvider{
+		customers:     make(map[string]*Customer),
+		subscriptions: make(map[string]*Subscription),
+		methods:       make(map[string]*PaymentMethod),
+		charges:       make(map[string]*Charge),
+		counters:      make(map[string]int),
+		failures:      make(map[string]error),
+		keys:          make(map[string]string),
+		now:           time.Now,
+	}
+}
+
+// SetIdempotency makes the provider deduplicate charges and subscriptions
+// by IdempotencyKey, as Stripe does: a repeated key returns the object
+// created by the first request instead of creating another. The Manager
+// then leaves deduplication to the provider.
+func (p *MockProvider) SetIdempotency(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idempotent = enabled
+}
+
+// SupportsIdempotency reports whether SetIdempotency enabled deduplication
+func (p *MockProvider) SupportsIdempotency() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.idempotent
+}
+
+// FailNext makes the next call to the named Provider method, such as
+// "CreateSubscription", return err without changing any state
+func (p *MockProvider) FailNext(method string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[method] = err
+}
+
+// FailNextCharge makes the next ChargePayment return err, e.g. to simulate
+// a declined card
+func (p *MockProvider) FailNextCharge(err error) {
+	p.FailNext("ChargePayment", err)
+}
+
+// Charges returns every successful charge in creation order
+func (p *MockProvider) Charges() []*Charge {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charges := make([]*Charge, 0, len(p.chargeOrder))
+	for _, id := range p.chargeOrder {
+		charges = append(charges, copyCharge(p.charges[id]))
+	}
+	return charges
+}
+
+// Refunds returns every successful refund in creation order
+func (p *MockProvider) Refunds() []*Refund {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	refunds := make([]*Refund, 0, len(p.refunds))
+	for _, refund := range p.refunds {
+		refunds = append(refunds, copyRefund(refund))
+	}
+	return refunds
+}
+
+// failure consumes the error queued for method; callers hold p.mu
+func (p *MockProvider) failure(method string) error {
+	err, ok := p.failures[method]
+	if !ok {
+		return nil
+	}
+	delete(p.failures, method)
+	return err
+}
+
+// nextID issues the next ID with prefix; callers hold p.mu
+func (p *MockProvider) nextID(prefix string) string {
+	p.counters[prefix]++
+	return fmt.Sprintf("%s_%d", prefix, p.counters[prefix])
+}
+
+// replayed returns the ID stored for an idempotency key of kind, or ""
+// when deduplication is off or the key is new; callers hold p.mu
+func (p *MockProvider) replayed(kind, key string) string {
+	if !p.idempotent || key == "" {
+		return ""
+	}
+	return p.keys[kind+":"+key]
+}
+
+// remember records the ID created for an idempotency key of kind;
+// callers hold p.mu
+func (p *MockProvider) remember(kind, key, id string) {
+	if p.idempotent && key != "" {
+		p.keys[kind+":"+key] = id
+	}
+}
+
+// CreateCustomer stores customer, assigning an ID when it has none
+func (p *MockProvider) CreateCustomer(ctx context.Context, customer *Customer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("CreateCustomer"); err != nil {
+		return err
+	}
+	if customer.ID == "" {
+		customer.ID = p.nextID("cus")
+	}
+	if _, exists := p.customers[customer.ID]; exists {
+		return fmt.Errorf("customer already exists: %s", customer.ID)
+	}
+	customer.ProviderID = customer.ID
+
+	p.customers[customer.ID] = copyCustomer(customer)
+	return nil
+}
+
+// GetCustomer returns a copy of the stored customer
+func (p *MockProvider) GetCustomer(ctx context.Context, customerID string) (*Customer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("GetCustomer"); err != nil {
+		return nil, err
+	}
+	customer, ok := p.customers[customerID]
+	if !ok {
+		return nil, fmt.Errorf("customer %s: %w", customerID, ErrMockNotFound)
+	}
+	return copyCustomer(customer), nil
+}
+
+// UpdateCustomer replaces a stored customer
+func (p *MockProvider) UpdateCustomer(ctx context.Context, customer *Customer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("UpdateCustomer"); err != nil {
+		return err
+	}
+	if _, ok := p.customers[customer.ID]; !ok {
+		return fmt.Errorf("customer %s: %w", customer.ID, ErrMockNotFound)
+	}
+	customer.UpdatedAt = p.now()
+
+	p.customers[customer.ID] = copyCustomer(customer)
+	return nil
+}
+
+// CreateSubscription stores sub for an existing customer. The current
+// period starts now and lasts a month, or until the trial ends for
+// trialing subscriptions.
+func (p *MockProvider) CreateSubscription(ctx context.Context, sub *Subscription) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("CreateSubscription"); err != nil {
+		return err
+	}
+	if _, ok := p.customers[sub.CustomerID]; !ok {
+		return fmt.Errorf("customer %s: %w", sub.CustomerID, ErrMockNotFound)
+	}
+	if id := p.replayed("subscription", sub.IdempotencyKey); id != "" {
+		*sub = *copySubscription(p.subscriptions[id])
+		return nil
+	}
+
+	now := p.now()
+	sub.ID = p.nextID("sub")
+	sub.ProviderID = sub.ID
+	if sub.Status == "" {
+		sub.Status = StatusActive
+	}
+	sub.CurrentPeriodStart = now
+	sub.CurrentPeriodEnd = now.AddDate(0, 1, 0)
+	if sub.Status == StatusTrialing && sub.TrialEnd != nil {
+		sub.CurrentPeriodEnd = *sub.TrialEnd
+	}
+
+	p.subscriptions[sub.ID] = copySubscription(sub)
+	p.subOrder = append(p.subOrder, sub.ID)
+	p.remember("subscription", sub.IdempotencyKey, sub.ID)
+	return nil
+}
+
+// GetSubscription returns a copy of the stored subscription
+func (p *MockProvider) GetSubscription(ctx context.Context, subscriptionID string) (*Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("GetSubscription"); err != nil {
+		return nil, err
+	}
+	sub, ok := p.subscriptions[subscriptionID]
+	if !ok {
+		return nil, fmt.Errorf("subscription %s: %w", subscriptionID, ErrMockNotFound)
+	}
+	return copySubscription(sub), nil
+}
+
+// CancelSubscription cancels a subscription now, or at the end of the
+// current period by setting CancelAt while it stays active
+func (p *MockProvider) CancelSubscription(ctx context.Context, subscriptionID string, immediately bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("CancelSubscription"); err != nil {
+		return err
+	}
+	sub, ok := p.subscriptions[subscriptionID]
+	if !ok {
+		return fmt.Errorf("subscription %s: %w", subscriptionID, ErrMockNotFound)
+	}
+	if sub.Status == StatusCanceled {
+		return fmt.Errorf("subscription %s is already canceled", subscriptionID)
+	}
+
+	now := p.now()
+	if immediately {
+		sub.Status = StatusCanceled
+		sub.CanceledAt = &now
+	} else {
+		cancelAt := sub.CurrentPeriodEnd
+		sub.CancelAt = &cancelAt
+	}
+	sub.UpdatedAt = now
+	return nil
+}
+
+// UpdateSubscription replaces a stored subscription. Canceled
+// subscriptions cannot be updated.
+func (p *MockProvider) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("UpdateSubscription"); err != nil {
+		return err
+	}
+	existing, ok := p.subscriptions[sub.ID]
+	if !ok {
+		return fmt.Errorf("subscription %s: %w", sub.ID, ErrMockNotFound)
+	}
+	if existing.Status == StatusCanceled {
+		return fmt.Errorf("subscription %s is canceled", sub.ID)
+	}
+
+	p.subscriptions[sub.ID] = copySubscription(sub)
+	return nil
+}
+
+// ListSubscriptions returns subscriptions in any of statuses, or all of
+// them, in creation order
+func (p *MockProvider) ListSubscriptions(ctx context.Context, statuses ...SubscriptionStatus) ([]*Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("ListSubscriptions"); err != nil {
+		return nil, err
+	}
+	var subs []*Subscription
+	for _, id := range p.subOrder {
+		sub := p.subscriptions[id]
+		if len(statuses) > 0 && !containsStatus(statuses, sub.Status) {
+			continue
+		}
+		subs = append(subs, copySubscription(sub))
+	}
+	return subs, nil
+}
+
+// containsStatus reports whether status is one of statuses
+func containsStatus(statuses []SubscriptionStatus, status SubscriptionStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatePaymentMethod stores a payment method for an existing customer.
+// The first method of a customer, or one marked IsDefault, becomes the
+// customer's default, and the previous default loses IsDefault.
+func (p *MockProvider) CreatePaymentMethod(ctx context.Context, method *PaymentMethod) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("CreatePaymentMethod"); err != nil {
+		return err
+	}
+	customer, ok := p.customers[method.CustomerID]
+	if !ok {
+		return fmt.Errorf("customer %s: %w", method.CustomerID, ErrMockNotFound)
+	}
+
+	method.ID = p.nextID("pm")
+	method.ProviderID = method.ID
+	if method.CreatedAt.IsZero() {
+		method.CreatedAt = p.now()
+	}
+	if customer.PaymentMethod == nil {
+		method.IsDefault = true
+	}
+
+	if method.IsDefault {
+		if customer.PaymentMethod != nil {
+			if previous, ok := p.methods[customer.PaymentMethod.ID]; ok {
+				previous.IsDefault = false
+			}
+		}
+		customer.PaymentMethod = copyPaymentMethod(method)
+	}
+	p.methods[method.ID] = copyPaymentMethod(method)
+	return nil
+}
+
+// ChargePayment charges an existing customer, using the customer's default
+// payment method when the charge names none, and records a paid invoice
+func (p *MockProvider) ChargePayment(ctx context.Context, charge *Charge) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("ChargePayment"); err != nil {
+		return err
+	}
+	customer, ok := p.customers[charge.CustomerID]
+	if !ok {
+		return fmt.Errorf("customer %s: %w", charge.CustomerID, ErrMockNotFound)
+	}
+	if charge.Amount <= 0 {
+		return fmt.Errorf("invalid charge amount: %d", charge.Amount)
+	}
+	if id := p.replayed("charge", charge.IdempotencyKey); id != "" {
+		*charge = *copyCharge(p.charges[id])
+		return nil
+	}
+
+	now := p.now()
+	charge.ID = p.nextID("ch")
+	charge.ProviderID = charge.ID
+	charge.Status = ChargeSucceeded
+	if charge.PaymentMethod == "" && customer.PaymentMethod != nil {
+		charge.PaymentMethod = customer.PaymentMethod.ID
+	}
+	if charge.CreatedAt.IsZero() {
+		charge.CreatedAt = now
+	}
+
+	c := copyCharge(charge)
+	p.charges[c.ID] = c
+	p.chargeOrder = append(p.chargeOrder, c.ID)
+	p.remember("charge", c.IdempotencyKey, c.ID)
+
+	invoiceID := p.nextID("in")
+	p.invoices = append(p.invoices, &Invoice{
+		ID:         invoiceID,
+		ProviderID: invoiceID,
+		CustomerID: c.CustomerID,
+		Number:     fmt.Sprintf("MOCK-%04d", p.counters["in"]),
+		Status:     InvoicePaid,
+		Amount:     c.Amount,
+		Currency:   c.Currency,
+		DueDate:    now,
+		PaidAt:     &now,
+		Lines: []InvoiceLine{
+			{Description: c.Description, Quantity: 1, UnitPrice: c.Amount, Amount: c.Amount},
+		},
+		CreatedAt: now,
+	})
+	return nil
+}
+
+// GetCharge returns a copy of a stored charge, including AmountRefunded
+func (p *MockProvider) GetCharge(ctx context.Context, chargeID string) (*Charge, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("GetCharge"); err != nil {
+		return nil, err
+	}
+	charge, ok := p.charges[chargeID]
+	if !ok {
+		return nil, fmt.Errorf("charge %s: %w", chargeID, ErrMockNotFound)
+	}
+	return copyCharge(charge), nil
+}
+
+// RefundPayment refunds part or, with a zero Amount, the rest of a charge.
+// Refunding more than is left fails.
+func (p *MockProvider) RefundPayment(ctx context.Context, refund *Refund) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("RefundPayment"); err != nil {
+		return err
+	}
+	charge, ok := p.charges[refund.ChargeID]
+	if !ok {
+		return fmt.Errorf("charge %s: %w", refund.ChargeID, ErrMockNotFound)
+	}
+
+	remaining := charge.Amount - charge.AmountRefunded
+	if refund.Amount == 0 {
+		refund.Amount = remaining
+	}
+	if refund.Amount <= 0 || refund.Amount > remaining {
+		return fmt.Errorf("refund of %d cents exceeds the %d cents left on charge %s",
+			refund.Amount, remaining, charge.ID)
+	}
+
+	refund.ID = p.nextID("re")
+	refund.ProviderID = refund.ID
+	refund.Status = RefundSucceeded
+	if refund.Currency == "" {
+		refund.Currency = charge.Currency
+	}
+	if refund.CreatedAt.IsZero() {
+		refund.CreatedAt = p.now()
+	}
+	charge.AmountRefunded += refund.Amount
+
+	p.refunds = append(p.refunds, copyRefund(refund))
+	return nil
+}
+
+// ListInvoices returns up to limit invoices of a customer, newest first.
+// A limit of zero or less returns them all.
+func (p *MockProvider) ListInvoices(ctx context.Context, customerID string, limit int) ([]*Invoice, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.failure("ListInvoices"); err != nil {
+		return nil, err
+	}
+	var invoices []*Invoice
+	for i := len(p.invoices) - 1; i >= 0; i-- {
+		if p.invoices[i].CustomerID != customerID {
+			continue
+		}
+		invoices = append(invoices, copyInvoice(p.invoices[i]))
+		if limit > 0 && len(invoices) == limit {
+			break
+		}
+	}
+	return invoices, nil
+}
+
+// HandleWebhook decodes payload as a JSON WebhookEvent. The signature is
+// not checked.
+func (p *MockProvider) HandleWebhook(ctx context.Context, payload []byte, signature string) (*WebhookEvent, error) {
+	p.mu.Lock()
+	err := p.failure("HandleWebhook")
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook: %v", err)
+	}
+	if event.Type == "" {
+		return nil, fmt.Errorf("webhook event has no type")
+	}
+	return &event, nil
+}
+
+// copyCustomer deep-copies a customer, including its address, metadata and
+// default payment method
+func copyCustomer(customer *Customer) *Customer {
+	c := *customer
+	if customer.Address != nil {
+		addr := *customer.Address
+		c.Address = &addr
+	}
+	c.Metadata = maps.Clone(customer.Metadata)
+	c.PaymentMethod = copyPaymentMethod(customer.PaymentMethod)
+	return &c
+}
+
+// copyPaymentMethod deep-copies a payment method, which may be nil
+func copyPaymentMethod(method *PaymentMethod) *PaymentMethod {
+	if method == nil {
+		return nil
+	}
+	m := *method
+	if method.Card != nil {
+		card := *method.Card
+		m.Card = &card
+	}
+	return &m
+}
+
+// copySubscription deep-copies a subscription, including its time
+// pointers, metadata, items and discount
+func copySubscription(sub *Subscription) *Subscription {
+	s := *sub
+	s.CancelAt = copyTime(sub.CancelAt)
+	s.CanceledAt = copyTime(sub.CanceledAt)
+	s.TrialStart = copyTime(sub.TrialStart)
+	s.TrialEnd = copyTime(sub.TrialEnd)
+	s.PausedAt = copyTime(sub.PausedAt)
+	s.ResumeAt = copyTime(sub.ResumeAt)
+	s.Metadata = maps.Clone(sub.Metadata)
+	s.Items = slices.Clone(sub.Items)
+	s.Discount = copyDiscount(sub.Discount)
+	return &s
+}
+
+// copyCharge deep-copies a charge, including its discount and metadata
+func copyCharge(charge *Charge) *Charge {
+	c := *charge
+	c.Discount = copyDiscount(charge.Discount)
+	c.Metadata = maps.Clone(charge.Metadata)
+	return &c
+}
+
+// copyRefund deep-copies a refund, including its metadata
+func copyRefund(refund *Refund) *Refund {
+	r := *refund
+	r.Metadata = maps.Clone(refund.Metadata)
+	return &r
+}
+
+// copyInvoice deep-copies an invoice, including its lines
+func copyInvoice(inv *Invoice) *Invoice {
+	i := *inv
+	i.PaidAt = copyTime(inv.PaidAt)
+	i.Lines = slices.Clone(inv.Lines)
+	return &i
+}
+
+// copyDiscount copies a discount, which may be nil
+func copyDiscount(d *Discount) *Discount {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+// copyTime copies a time pointer, which may be nil
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// Compile-time checks that MockProvider implements the provider interfaces
+var (
+	_ Provider           = (*MockProvider)(nil)
+	_ ChargeGetter       = (*MockProvider)(nil)
+	_ SubscriptionLister = (*MockProvider)(nil)
+	_ IdempotentProvider = (*MockProvider)(nil)
+)
@@ -0,0 +1,271 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMockManager returns a manager backed by a MockProvider with one
+// customer and a monthly and a trial plan
+func newMockManager(t *testing.T) (*Manager, *MockProvider, *Customer) {
+	t.Helper()
+	provider := NewMockProvider()
+	mgr := NewManager(provider)
+	mgr.AddPlan(&Plan{ID: "pro", Name: "Pro", Amount: 2000, Currency: "usd", Interval: IntervalMonthly, Active: true})
+	mgr.AddPlan(&Plan{ID: "trial", Name: "Trial", Amount: 2000, Currency: "usd", Interval: IntervalMonthly, Active: true, TrialDays: 14})
+
+	customer, err := mgr.CreateCustomer(context.Background(), "jane@example.com", "Jane")
+	if err != nil {
+		t.Fatalf("CreateCustomer failed: %v", err)
+	}
+	return mgr, provider, customer
+}
+
+func TestMockProviderSubscriptionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider, customer := newMockManager(t)
+
+	if customer.ID != "cus_1" {
+		t.Errorf("Customer ID = %q, want cus_1", customer.ID)
+	}
+
+	sub, err := mgr.Subscribe(ctx, customer.ID, "pro")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if sub.ID != "sub_1" || sub.Status != StatusActive || !sub.CurrentPeriodEnd.After(sub.CurrentPeriodStart) {
+		t.Errorf("Subscription = %+v, want active sub_1 with a current period", sub)
+	}
+
+	// Cancel at period end keeps the subscription active until then
+	if err := mgr.CancelSubscription(ctx, sub.ID, false); err != nil {
+		t.Fatalf("CancelSubscription failed: %v", err)
+	}
+	stored, _ := provider.GetSubscription(ctx, sub.ID)
+	if stored.Status != StatusActive || stored.CancelAt == nil || !stored.CancelAt.Equal(sub.CurrentPeriodEnd) {
+		t.Errorf("After scheduled cancel: status=%s cancelAt=%v", stored.Status, stored.CancelAt)
+	}
+
+	if err := mgr.ChangePlan(ctx, sub.ID, "trial"); err != nil {
+		t.Fatalf("ChangePlan failed: %v", err)
+	}
+
+	if err := mgr.CancelSubscription(ctx, sub.ID, true); err != nil {
+		t.Fatalf("CancelSubscription immediately failed: %v", err)
+	}
+	stored, _ = provider.GetSubscription(ctx, sub.ID)
+	if stored.Status != StatusCanceled || stored.CanceledAt == nil || stored.PlanID != "trial" {
+		t.Errorf("After immediate cancel: %+v", stored)
+	}
+
+	if err := mgr.CancelSubscription(ctx, sub.ID, true); err == nil {
+		t.Error("Expected canceling twice to fail")
+	}
+	if err := mgr.ChangePlan(ctx, sub.ID, "pro"); err == nil {
+		t.Error("Expected changing the plan of a canceled subscription to fail")
+	}
+}
+
+func TestMockProviderTrial(t *testing.T) {
+	ctx := context.Background()
+	mgr, _, customer := newMockManager(t)
+
+	sub, err := mgr.Subscribe(ctx, customer.ID, "trial")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if sub.Status != StatusTrialing || !sub.CurrentPeriodEnd.Equal(*sub.TrialEnd) {
+		t.Errorf("Trial subscription = %+v, want trialing until the trial end", sub)
+	}
+
+	// The mock lists subscriptions for the reminder queries
+	upcoming, err := mgr.UpcomingTrialEnds(ctx, time.Now(), 15*24*time.Hour)
+	if err != nil {
+		t.Fatalf("UpcomingTrialEnds failed: %v", err)
+	}
+	if len(upcoming) != 1 || upcoming[0].ID != sub.ID {
+		t.Errorf("UpcomingTrialEnds = %v, want [%s]", upcoming, sub.ID)
+	}
+}
+
+func TestMockProviderChargeAndRefund(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider, customer := newMockManager(t)
+
+	if err := provider.CreatePaymentMethod(ctx, &PaymentMethod{CustomerID: customer.ID, Type: PaymentCard}); err != nil {
+		t.Fatalf("CreatePaymentMethod failed: %v", err)
+	}
+
+	charge, err := mgr.ChargeOneTime(ctx, customer.ID, 5000, "Setup fee")
+	if err != nil {
+		t.Fatalf("ChargeOneTime failed: %v", err)
+	}
+	if charge.ID != "ch_1" || charge.Status != ChargeSucceeded || charge.PaymentMethod != "pm_1" {
+		t.Errorf("Charge = %+v, want succeeded ch_1 paid with pm_1", charge)
+	}
+
+	if _, err := mgr.Refund(ctx, charge.ID, 2000, "requested_by_customer"); err != nil {
+		t.Fatalf("Partial refund failed: %v", err)
+	}
+	refund, err := mgr.Refund(ctx, charge.ID, 0, "requested_by_customer")
+	if err != nil {
+		t.Fatalf("Refund of the rest failed: %v", err)
+	}
+	if refund.ID != "re_2" || refund.Amount != 3000 || refund.Status != RefundSucceeded {
+		t.Errorf("Refund = %+v, want re_2 for 3000 cents", refund)
+	}
+
+	stored, _ := provider.GetCharge(ctx, charge.ID)
+	if stored.AmountRefunded != 5000 {
+		t.Errorf("AmountRefunded = %d, want 5000", stored.AmountRefunded)
+	}
+	if _, err := mgr.Refund(ctx, charge.ID, 100, "duplicate"); !errors.Is(err, ErrChargeFullyRefunded) {
+		t.Errorf("Refund after full refund error = %v, want ErrChargeFullyRefunded", err)
+	}
+	if got := len(provider.Refunds()); got != 2 {
+		t.Errorf("Refunds() has %d entries, want 2", got)
+	}
+
+	invoices, err := provider.ListInvoices(ctx, customer.ID, 10)
+	if err != nil || len(invoices) != 1 || invoices[0].Status != InvoicePaid || invoices[0].Amount != 5000 {
+		t.Errorf("ListInvoices = %v, %v; want one paid invoice for 5000 cents", invoices, err)
+	}
+}
+
+func TestMockProviderFailNext(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider, customer := newMockManager(t)
+	declined := errors.New("card declined")
+
+	provider.FailNextCharge(declined)
+	if _, err := mgr.ChargeOneTime(ctx, customer.ID, 1000, "Setup fee"); err == nil || !strings.Contains(err.Error(), declined.Error()) {
+		t.Fatalf("ChargeOneTime error = %v, want the forced decline", err)
+	}
+	if len(provider.Charges()) != 0 {
+		t.Error("Expected a declined charge not to be stored")
+	}
+
+	// Only the next call fails
+	if _, err := mgr.ChargeOneTime(ctx, customer.ID, 1000, "Setup fee"); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+
+	provider.FailNext("CreateSubscription", errors.New("provider unavailable"))
+	if _, err := mgr.Subscribe(ctx, customer.ID, "pro"); err == nil {
+		t.Error("Expected the forced subscription failure")
+	}
+	subs, _ := provider.ListSubscriptions(ctx)
+	if len(subs) != 0 {
+		t.Errorf("Expected no subscription after the failure, got %d", len(subs))
+	}
+}
+
+func TestMockProviderUnknownIDs(t *testing.T) {
+	ctx := context.Background()
+	mgr, provider, _ := newMockManager(t)
+
+	if _, err := mgr.Subscribe(ctx, "cus_missing", "pro"); err == nil {
+		t.Error("Expected subscribing an unknown customer to fail")
+	}
+	if _, err := mgr.ChargeOneTime(ctx, "cus_missing", 1000, "Setup fee"); err == nil {
+		t.Error("Expected charging an unknown customer to fail")
+	}
+	if _, err := provider.GetSubscription(ctx, "sub_missing"); !errors.Is(err, ErrMockNotFound) {
+		t.Errorf("GetSubscription error = %v, want ErrMockNotFound", err)
+	}
+	if _, err := mgr.Refund(ctx, "ch_missing", 0, "duplicate"); err == nil {
+		t.Error("Expected refunding an unknown charge to fail")
+	}
+}
+
+func TestMockProviderWebhook(t *testing.T) {
+	mgr, _, _ := newMockManager(t)
+
+	if err := mgr.HandleWebhook(context.Background(), []byte(`{"id":"evt_1","type":"invoice.paid"}`), ""); err != nil {
+		t.Errorf("HandleWebhook failed: %v", err)
+	}
+	if err := mgr.HandleWebhook(context.Background(), []byte(`not json`), ""); err == nil {
+		t.Error("Expected malformed payloads to be rejected")
+	}
+}
+
+func TestMockProviderDeepCopies(t *testing.T) {
+	ctx := context.Background()
+	_, provider, customer := newMockManager(t)
+
+	trialEnd := time.Now().Add(24 * time.Hour)
+	sub := &Subscription{
+		CustomerID: customer.ID,
+		Status:     StatusTrialing,
+		TrialEnd:   &trialEnd,
+		Metadata:   map[string]string{"source": "signup"},
+		Discount:   &Discount{CouponCode: "HALF", PercentOff: 50},
+	}
+	if err := provider.CreateSubscription(ctx, sub); err != nil {
+		t.Fatalf("CreateSubscription failed: %v", err)
+	}
+	if err := provider.CreatePaymentMethod(ctx, &PaymentMethod{CustomerID: customer.ID, Card: &CardDetails{Last4: "4242"}}); err != nil {
+		t.Fatalf("CreatePaymentMethod failed: %v", err)
+	}
+
+	// Changing the caller's values and returned copies leaves the store intact
+	trialEnd = trialEnd.Add(time.Hour)
+	sub.Metadata["source"] = "changed"
+	sub.Discount.PercentOff = 100
+	got, _ := provider.GetSubscription(ctx, sub.ID)
+	got.Metadata["source"] = "changed"
+	*got.TrialEnd = got.TrialEnd.Add(time.Hour)
+	listed, _ := provider.ListSubscriptions(ctx)
+	listed[0].Discount.PercentOff = 100
+
+	stored, _ := provider.GetSubscription(ctx, sub.ID)
+	if stored.Metadata["source"] != "signup" || stored.Discount.PercentOff != 50 || !stored.TrialEnd.Equal(stored.CurrentPeriodEnd) {
+		t.Errorf("stored subscription changed through a copy: %+v", stored)
+	}
+
+	c, _ := provider.GetCustomer(ctx, customer.ID)
+	c.PaymentMethod.Card.Last4 = "0000"
+	c.PaymentMethod.IsDefault = false
+	if c, _ = provider.GetCustomer(ctx, customer.ID); c.PaymentMethod.Card.Last4 != "4242" || !c.PaymentMethod.IsDefault {
+		t.Errorf("stored payment method changed through a copy: %+v", c.PaymentMethod)
+	}
+}
+
+func TestMockProviderDefaultPaymentMethod(t *testing.T) {
+	ctx := context.Background()
+	_, provider, customer := newMockManager(t)
+
+	first := &PaymentMethod{CustomerID: customer.ID}
+	second := &PaymentMethod{CustomerID: customer.ID, IsDefault: true}
+	for _, method := range []*PaymentMethod{first, second} {
+		if err := provider.CreatePaymentMethod(ctx, method); err != nil {
+			t.Fatalf("CreatePaymentMethod failed: %v", err)
+		}
+	}
+
+	c, _ := provider.GetCustomer(ctx, customer.ID)
+	if c.PaymentMethod == nil || c.PaymentMethod.ID != second.ID {
+		t.Fatalf("default payment method = %+v, want %s", c.PaymentMethod, second.ID)
+	}
+	if provider.methods[first.ID].IsDefault || !provider.methods[second.ID].IsDefault {
+		t.Errorf("IsDefault = %v/%v, want only %s", provider.methods[first.ID].IsDefault,
+			provider.methods[second.ID].IsDefault, second.ID)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+// PauseSubscription stops billing for a subscription. While paused it is
+// left out of renewal reminders and usage cannot be recorded against it.
+// When resumeAt is set, ResumeDueSubscriptions resumes it automatically
+// once that time has passed; otherwise it stays paused until
+// ResumeSubscription is called.
+func (m *Manager) PauseSubscription(ctx context.Context, subscriptionID string, resumeAt *time.Time) error {
+	sub, err := m.provider.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %v", err)
+	}
+
+	switch sub.Status {
+	case StatusPaused:
+		return fmt.Errorf("subscription %s is already paused", subscriptionID)
+	case StatusCanceled:
+		return fmt.Errorf("subscription %s is canceled", subscriptionID)
+	}
+
+	now := time.Now()
+	if resumeAt != nil && !resumeAt.After(now) {
+		return fmt.Errorf("resume time must be in the future")
+	}
+
+	sub.Status = StatusPaused
+	sub.PausedAt = &now
+	sub.ResumeAt = resumeAt
+	sub.UpdatedAt = now
+
+	if err := m.provider.UpdateSubscription(ctx, sub); err != nil {
+		return fmt.Errorf("failed to update subscription: %v", err)
+	}
+
+	common.Info("[PAYMENT] Paused subscription: %s", subscriptionID)
+	return nil
+}
+
+// ResumeSubscription restarts billing for a paused subscription. The
+// current period, and a trial that had not ended when the subscription was
+// paused, are extended by the time spent paused so the customer is not
+// charged for it.
+func (m *Manager) ResumeSubscription(ctx context.Context, subscriptionID string) error {
+	sub, err := m.provider.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %v", err)
+	}
+	if sub.Status != StatusPaused {
+		return fmt.Errorf("subscription %s is not paused", subscriptionID)
+	}
+
+	return m.resume(ctx, sub, time.Now())
+}
+
+// ResumeDueSubscriptions resumes paused subscriptions whose scheduled
+// resume time is at or before now, and returns the resumed subscriptions.
+// Run it from a cron alongside the reminder queries. Subscriptions are
+// looked up through the configured SubscriptionLister.
+func (m *Manager) ResumeDueSubscriptions(ctx context.Context, now time.Time) ([]*Subscription, error) {
+	lister, err := m.subscriptionLister()
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := lister.ListSubscriptions(ctx, StatusPaused)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %v", err)
+	}
+
+	var resumed []*Subscription
+	for _, sub := range subs {
+		if sub.Status != StatusPaused || sub.ResumeAt == nil || sub.ResumeAt.After(now) {
+			continue
+		}
+		if err := m.resume(ctx, sub, now); err != nil {
+			return resumed, err
+		}
+		resumed = append(resumed, sub)
+	}
+	return resumed, nil
+}
+
+// resume shifts sub's billing dates by the paused duration and saves it as
+// active, or trialing when its extended trial has not ended yet
+func (m *Manager) resume(ctx context.Context, sub *Subscription, now time.Time) error {
+	if sub.PausedAt != nil && now.After(*sub.PausedAt) {
+		paused := now.Sub(*sub.PausedAt)
+		if !sub.CurrentPeriodEnd.IsZero() {
+			sub.CurrentPeriodEnd = sub.CurrentPeriodEnd.Add(paused)
+		}
+		if sub.TrialEnd != nil && sub.TrialEnd.After(*sub.PausedAt) {
+			trialEnd := sub.TrialEnd.Add(paused)
+			sub.TrialEnd = &trialEnd
+		}
+	}
+
+	sub.Status = StatusActive
+	if sub.TrialEnd != nil && sub.TrialEnd.After(now) {
+		sub.Status = StatusTrialing
+	}
+	sub.PausedAt = nil
+	sub.ResumeAt = nil
+	sub.UpdatedAt = now
+
+	if err := m.provider.UpdateSubscription(ctx, sub); err != nil {
+		return fmt.Errorf("failed to update subscription: %v", err)
+	}
+
+	common.Info("[PAYMENT] Resumed subscription: %s", sub.ID)
+	return nil
+}
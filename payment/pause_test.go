@@ -0,0 +1,175 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPauseSubscription(t *testing.T) {
+	ctx := context.Background()
+	future := time.Now().Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		status   SubscriptionStatus
+		resumeAt *time.Time
+		wantErr  string
+	}{
+		{name: "Active without resume date", status: StatusActive},
+		{name: "Trialing with resume date", status: StatusTrialing, resumeAt: &future},
+		{name: "Already paused", status: StatusPaused, wantErr: "already paused"},
+		{name: "Canceled", status: StatusCanceled, wantErr: "canceled"},
+		{name: "Resume date in the past", status: StatusActive, resumeAt: &past, wantErr: "future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &couponProvider{subs: map[string]*Subscription{
+				"sub_1": {ID: "sub_1", Status: tt.status},
+			}}
+			mgr := NewManager(provider)
+
+			err := mgr.PauseSubscription(ctx, "sub_1", tt.resumeAt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PauseSubscription() error = %v, want %q", err, tt.wantErr)
+				}
+				if provider.subs["sub_1"].Status != tt.status {
+					t.Errorf("Status changed to %s on error", provider.subs["sub_1"].Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PauseSubscription() error = %v", err)
+			}
+
+			sub := provider.subs["sub_1"]
+			if sub.Status != StatusPaused || sub.PausedAt == nil {
+				t.Errorf("Expected paused with PausedAt set, got %s %v", sub.Status, sub.PausedAt)
+			}
+			if tt.resumeAt != nil && (sub.ResumeAt == nil || !sub.ResumeAt.Equal(*tt.resumeAt)) {
+				t.Errorf("ResumeAt = %v, want %v", sub.ResumeAt, tt.resumeAt)
+			}
+		})
+	}
+}
+
+func TestResumeSubscription(t *testing.T) {
+	ctx := context.Background()
+	pausedAt := time.Now().Add(-72 * time.Hour)
+	periodEnd := time.Now().Add(-48 * time.Hour)
+	trialEnd := time.Now().Add(-24 * time.Hour)
+
+	provider := &couponProvider{subs: map[string]*Subscription{
+		"paused": {ID: "paused", Status: StatusPaused, PausedAt: &pausedAt, CurrentPeriodEnd: periodEnd, TrialEnd: &trialEnd},
+		"active": {ID: "active", Status: StatusActive},
+	}}
+	mgr := NewManager(provider)
+
+	if err := mgr.ResumeSubscription(ctx, "active"); err == nil {
+		t.Error("Expected an error resuming a subscription that is not paused")
+	}
+	if err := mgr.ResumeSubscription(ctx, "paused"); err != nil {
+		t.Fatalf("ResumeSubscription() error = %v", err)
+	}
+
+	sub := provider.subs["paused"]
+	// The trial had 48h left when paused, so it is trialing again
+	if sub.Status != StatusTrialing {
+		t.Errorf("Status = %s, want %s", sub.Status, StatusTrialing)
+	}
+	if sub.PausedAt != nil || sub.ResumeAt != nil {
+		t.Error("Expected pause fields to be cleared")
+	}
+	if left := time.Until(sub.CurrentPeriodEnd); left < 23*time.Hour || left > 25*time.Hour {
+		t.Errorf("Expected the period to end in about 24h, got %v", left)
+	}
+	if left := time.Until(*sub.TrialEnd); left < 47*time.Hour || left > 49*time.Hour {
+		t.Errorf("Expected the trial to end in about 48h, got %v", left)
+	}
+}
+
+func TestResumeDueSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	subs := memorySubscriptions{
+		{ID: "due", Status: StatusPaused, PausedAt: at(-10 * 24 * time.Hour), ResumeAt: at(-time.Hour), CurrentPeriodEnd: now.Add(-5 * 24 * time.Hour)},
+		{ID: "later", Status: StatusPaused, PausedAt: at(-time.Hour), ResumeAt: at(time.Hour)},
+		{ID: "manual", Status: StatusPaused, PausedAt: at(-time.Hour)},
+		{ID: "active", Status: StatusActive, ResumeAt: at(-time.Hour)},
+	}
+	provider := &couponProvider{subs: map[string]*Subscription{}}
+	for _, sub := range subs {
+		provider.subs[sub.ID] = sub
+	}
+	mgr := NewManager(provider)
+	mgr.SetSubscriptionLister(subs)
+
+	resumed, err := mgr.ResumeDueSubscriptions(ctx, now)
+	if err != nil {
+		t.Fatalf("ResumeDueSubscriptions() error = %v", err)
+	}
+	if ids := subscriptionIDs(resumed); len(ids) != 1 || ids[0] != "due" {
+		t.Fatalf("Resumed %v, want [due]", ids)
+	}
+
+	due := provider.subs["due"]
+	if due.Status != StatusActive {
+		t.Errorf("Status = %s, want %s", due.Status, StatusActive)
+	}
+	if want := now.Add(5 * 24 * time.Hour); !due.CurrentPeriodEnd.Equal(want) {
+		t.Errorf("CurrentPeriodEnd = %v, want %v", due.CurrentPeriodEnd, want)
+	}
+	for _, id := range []string{"later", "manual"} {
+		if provider.subs[id].Status != StatusPaused {
+			t.Errorf("Expected %s to stay paused", id)
+		}
+	}
+
+	if _, err := NewManager(nil).ResumeDueSubscriptions(ctx, now); err == nil {
+		t.Error("Expected an error without a subscription lister")
+	}
+}
+
+func TestPausedSubscriptionNotBilled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := now.Add(24 * time.Hour)
+
+	provider := &couponProvider{subs: map[string]*Subscription{
+		"sub_1": {ID: "sub_1", Status: StatusPaused, CurrentPeriodEnd: periodEnd},
+	}}
+	mgr := NewManager(provider)
+	mgr.SetSubscriptionLister(memorySubscriptions{provider.subs["sub_1"]})
+
+	if err := mgr.TrackUsage(ctx, &UsageRecord{SubscriptionID: "sub_1", Metric: "calls", Quantity: 1}); err == nil {
+		t.Error("Expected usage against a paused subscription to be rejected")
+	}
+
+	renewals, err := mgr.UpcomingRenewals(ctx, now, 48*time.Hour)
+	if err != nil {
+		t.Fatalf("UpcomingRenewals() error = %v", err)
+	}
+	if len(renewals) != 0 {
+		t.Errorf("Expected no renewals for a paused subscription, got %v", subscriptionIDs(renewals))
+	}
+}
@@ -107,6 +107,10 @@ type Subscription struct {
 	TrialEnd           *time.Time         `json:"trial_end,omitempty"`
 	Metadata           map[string]string  `json:"metadata,omitempty"`
 	Items              []SubscriptionItem `json:"items,omitempty"`
+	Discount           *Discount          `json:"discount,omitempty"`
+	PausedAt           *time.Time         `json:"paused_at,omitempty"`
+	ResumeAt           *time.Time         `json:"resume_at,omitempty"`
+	IdempotencyKey     string             `json:"idempotency_key,omitempty"` // Forwarded to the provider, see SubscribeIdempotent
 	CreatedAt          time.Time          `json:"created_at"`
 	UpdatedAt          time.Time          `json:"updated_at"`
 }
@@ -143,6 +147,7 @@ type Plan struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	Active      bool              `json:"active"`
 	TrialDays   int               `json:"trial_days"`
+	Limits      map[string]int64  `json:"limits,omitempty"` // Usage caps per metric, e.g. LimitSeats
 }
 
 // BillingInterval represents billing frequency
@@ -189,12 +194,17 @@ type Charge struct {
 	ID             string            `json:"id"`
 	ProviderID     string            `json:"provider_id"`
 	CustomerID     string            `json:"customer_id"`
-	Amount         int64             `json:"amount"` // In cents
+	Amount         int64             `json:"amount"` // In cents, including Tax
 	Currency       string            `json:"currency"`
 	Description    string            `json:"description"`
 	Status         ChargeStatus      `json:"status"`
+	Tax            int64             `json:"tax,omitempty"`      // In cents
+	TaxRate        float64           `json:"tax_rate,omitempty"` // e.g. 0.0825 for 8.25%
 	PaymentMethod  string            `json:"payment_method"`
 	FailureMessage string            `json:"failure_message,omitempty"`
+	Discount       *Discount         `json:"discount,omitempty"`
+	AmountRefunded int64             `json:"amount_refunded,omitempty"` // In cents, as reported by the provider
+	IdempotencyKey string            `json:"idempotency_key,omitempty"` // Forwarded to the provider, see ChargeOneTimeIdempotent
 	Metadata       map[string]string `json:"metadata,omitempty"`
 	CreatedAt      time.Time         `json:"created_at"`
 }
@@ -260,10 +270,12 @@ const (
 
 // InvoiceLine represents an invoice line item
 type InvoiceLine struct {
-	Description string `json:"description"`
-	Quantity    int    `json:"quantity"`
-	UnitPrice   int64  `json:"unit_price"` // In cents
-	Amount      int64  `json:"amount"`     // In cents
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   int64   `json:"unit_price"`         // In cents
+	Amount      int64   `json:"amount"`             // In cents
+	Tax         bool    `json:"tax,omitempty"`      // Tax line added by AddInvoiceTax
+	TaxRate     float64 `json:"tax_rate,omitempty"` // Rate of a tax line
 }
 
 // WebhookEvent represents a webhook event
@@ -276,16 +288,27 @@ type WebhookEvent struct {
 
 // Manager handles payment operations
 type Manager struct {
-	provider Provider
-	plans    map[string]*Plan
-	mu       sync.RWMutex
+	provider    Provider
+	plans       map[string]*Plan
+	coupons     map[string]*Coupon
+	redemptions []*Redemption
+	lister      SubscriptionLister
+	tax         TaxCalculator
+	charges     map[string]*Charge // charges made through the manager
+	refunded    map[string]int64   // charge ID -> cents refunded
+	idempotency *idempotencyStore  // fallback for providers without native keys
+	mu          sync.RWMutex
 }
 
 // NewManager creates a new payment manager
 func NewManager(provider Provider) *Manager {
 	return &Manager{
-		provider: provider,
-		plans:    make(map[string]*Plan),
+		provider:    provider,
+		plans:       make(map[string]*Plan),
+		coupons:     make(map[string]*Coupon),
+		charges:     make(map[string]*Charge),
+		refunded:    make(map[string]int64),
+		idempotency: newIdempotencyStore(),
 	}
 }
 
@@ -309,6 +332,11 @@ func (m *Manager) CreateCustomer(ctx context.Context, email, name string) (*Cust
 
 // Subscribe creates a subscription for a customer
 func (m *Manager) Subscribe(ctx context.Context, customerID, planID string) (*Subscription, error) {
+	return m.subscribe(ctx, "", customerID, planID)
+}
+
+// subscribe creates a subscription, passing idempotencyKey to the provider
+func (m *Manager) subscribe(ctx context.Context, idempotencyKey, customerID, planID string) (*Subscription, error) {
 	// Get plan
 	m.mu.RLock()
 	plan, ok := m.plans[planID]
@@ -319,12 +347,13 @@ func (m *Manager) Subscribe(ctx context.Context, customerID, planID string) (*Su
 	}
 
 	sub := &Subscription{
-		CustomerID: customerID,
-		PlanID:     planID,
-		Status:     StatusActive,
-		Quantity:   1,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		CustomerID:     customerID,
+		PlanID:         planID,
+		Status:         StatusActive,
+		Quantity:       1,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
 	// Add trial if configured
@@ -354,13 +383,26 @@ func (m *Manager) CancelSubscription(ctx context.Context, subscriptionID string,
 	return nil
 }
 
-// ChangePlan changes subscription plan
+// ChangePlan changes subscription plan. A discount on the subscription is
+// recomputed for the new plan's price.
 func (m *Manager) ChangePlan(ctx context.Context, subscriptionID, newPlanID string) error {
 	sub, err := m.provider.GetSubscription(ctx, subscriptionID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscription: %v", err)
 	}
 
+	if sub.Discount != nil {
+		plan, ok := m.GetPlan(newPlanID)
+		if !ok {
+			return fmt.Errorf("plan not found: %s", newPlanID)
+		}
+		discount := *sub.Discount
+		if err := discount.recompute(plan.Amount*subscriptionQuantity(sub), plan.Currency); err != nil {
+			return err
+		}
+		sub.Discount = &discount
+	}
+
 	sub.PlanID = newPlanID
 	sub.UpdatedAt = time.Now()
 
@@ -372,21 +414,33 @@ func (m *Manager) ChangePlan(ctx context.Context, subscriptionID, newPlanID stri
 	return nil
 }
 
-// ChargeOneTime processes a one-time payment
+// ChargeOneTime processes a one-time payment. Tax from the configured
+// TaxCalculator is added on top of amount.
 func (m *Manager) ChargeOneTime(ctx context.Context, customerID string, amount int64, description string) (*Charge, error) {
+	return m.chargeOneTime(ctx, "", customerID, amount, description)
+}
+
+// chargeOneTime charges a customer, passing idempotencyKey to the provider
+func (m *Manager) chargeOneTime(ctx context.Context, idempotencyKey, customerID string, amount int64, description string) (*Charge, error) {
 	charge := &Charge{
-		CustomerID:  customerID,
-		Amount:      amount,
-		Currency:    "usd",
-		Description: description,
-		CreatedAt:   time.Now(),
+		CustomerID:     customerID,
+		Amount:         amount,
+		Currency:       "usd",
+		Description:    description,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := m.applyChargeTax(ctx, charge); err != nil {
+		return nil, err
 	}
 
 	if err := m.provider.ChargePayment(ctx, charge); err != nil {
 		return nil, fmt.Errorf("failed to charge payment: %v", err)
 	}
+	m.recordCharge(charge)
 
-	common.Info("[PAYMENT] Charged %d cents to customer %s", amount, customerID)
+	common.Info("[PAYMENT] Charged %d cents to customer %s", charge.Amount, customerID)
 	return charge, nil
 }
 
@@ -438,6 +492,10 @@ func (m *Manager) HandleWebhook(ctx context.Context, payload []byte, signature s
 		common.Info("[PAYMENT] Webhook: Subscription updated")
 	case "subscription.canceled":
 		common.Info("[PAYMENT] Webhook: Subscription canceled")
+	case "subscription.paused":
+		common.Info("[PAYMENT] Webhook: Subscription paused")
+	case "subscription.resumed":
+		common.Info("[PAYMENT] Webhook: Subscription resumed")
 	case "invoice.paid":
 		common.Info("[PAYMENT] Webhook: Invoice paid")
 	case "invoice.payment_failed":
@@ -462,8 +520,19 @@ type UsageRecord struct {
 	Timestamp      time.Time `json:"timestamp"`
 }
 
-// TrackUsage records usage for metered billing
+// TrackUsage records usage for metered billing. Usage against a paused
+// subscription is rejected.
 func (m *Manager) TrackUsage(ctx context.Context, record *UsageRecord) error {
+	if record.SubscriptionID != "" {
+		sub, err := m.provider.GetSubscription(ctx, record.SubscriptionID)
+		if err != nil {
+			return fmt.Errorf("failed to get subscription: %v", err)
+		}
+		if sub.Status == StatusPaused {
+			return fmt.Errorf("subscription %s is paused", record.SubscriptionID)
+		}
+	}
+
 	// This would be implemented based on the payment provider's usage API
 	common.Debug("[PAYMENT] Tracked usage: %s = %d for customer %s",
 		record.Metric, record.Quantity, record.CustomerID)
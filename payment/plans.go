@@ -0,0 +1,151 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// LimitSeats is the Plan.Limits key checked against Subscription.Quantity.
+// Any other key is checked against the usage tracked for that metric during
+// the current billing period.
+const LimitSeats = "seats"
+
+// PlanChange classifies a move from one plan to another
+type PlanChange string
+
+const (
+	PlanUpgrade   PlanChange = "upgrade"
+	PlanDowngrade PlanChange = "downgrade"
+	PlanLateral   PlanChange = "lateral"
+)
+
+// PlanDelta describes the price difference between two plans
+type PlanDelta struct {
+	Direction PlanChange `json:"direction"`
+	// MonthlyDifference is the target plan's monthly price minus the current
+	// plan's, in cents. Positive for upgrades.
+	MonthlyDifference int64 `json:"monthly_difference"`
+}
+
+// MonthlyAmount returns the plan price normalized to one month, in cents.
+// Yearly prices are divided by 12 and weekly prices scaled by 52/12, rounded
+// to the nearest cent. One-time plans have no recurring cost and return 0.
+func (p *Plan) MonthlyAmount() int64 {
+	if p == nil {
+		return 0
+	}
+	switch p.Interval {
+	case IntervalYearly:
+		return roundDiv(p.Amount, 12)
+	case IntervalWeekly:
+		return roundDiv(p.Amount*52, 12)
+	case IntervalOneTime:
+		return 0
+	default:
+		return p.Amount
+	}
+}
+
+// roundDiv divides n by d rounding half away from zero
+func roundDiv(n, d int64) int64 {
+	if n < 0 {
+		return -roundDiv(-n, d)
+	}
+	return (n + d/2) / d
+}
+
+// ComparePlans reports whether moving from plan a to plan b is an upgrade,
+// downgrade or lateral move, comparing prices normalized to a monthly
+// interval so a yearly plan can be compared with a monthly one. A nil plan
+// counts as free. Both plans are assumed to be priced in the same currency.
+func ComparePlans(a, b *Plan) PlanDelta {
+	diff := b.MonthlyAmount() - a.MonthlyAmount()
+
+	delta := PlanDelta{Direction: PlanLateral, MonthlyDifference: diff}
+	switch {
+	case diff > 0:
+		delta.Direction = PlanUpgrade
+	case diff < 0:
+		delta.Direction = PlanDowngrade
+	}
+	return delta
+}
+
+// CanChangePlan reports whether sub may move to newPlan. When it may not, the
+// returned string explains why and is suitable for display on a pricing page.
+//
+// A change is refused when the plan is inactive or one-time, the
+// subscription is canceled, the currency differs from the current plan, or
+// the subscription's current seats or period usage exceed newPlan.Limits.
+func (m *Manager) CanChangePlan(ctx context.Context, sub *Subscription, newPlan *Plan) (bool, string) {
+	if sub == nil {
+		return false, "subscription not found"
+	}
+	if newPlan == nil {
+		return false, "plan not found"
+	}
+	if !newPlan.Active {
+		return false, "plan is not available"
+	}
+	if newPlan.Interval == IntervalOneTime {
+		return false, "plan is not a subscription plan"
+	}
+	if sub.Status == StatusCanceled {
+		return false, "subscription is canceled"
+	}
+	if sub.PlanID == newPlan.ID {
+		return false, "already subscribed to this plan"
+	}
+
+	if current, ok := m.GetPlan(sub.PlanID); ok && current.Currency != newPlan.Currency {
+		return false, fmt.Sprintf("cannot switch currency from %s to %s", current.Currency, newPlan.Currency)
+	}
+
+	// Check limits in a stable order so the reason is deterministic
+	metrics := make([]string, 0, len(newPlan.Limits))
+	for metric := range newPlan.Limits {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	var usage map[string]int64
+	for _, metric := range metrics {
+		limit := newPlan.Limits[metric]
+		var used int64
+		if metric == LimitSeats {
+			used = int64(sub.Quantity)
+		} else {
+			if usage == nil {
+				records, err := m.GetUsage(ctx, sub.CustomerID, sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+				if err != nil {
+					return false, "unable to verify current usage"
+				}
+				usage = make(map[string]int64)
+				for _, record := range records {
+					usage[record.Metric] += record.Quantity
+				}
+			}
+			used = usage[metric]
+		}
+		if used > limit {
+			return false, fmt.Sprintf("current %s usage (%d) exceeds the plan limit of %d", metric, used, limit)
+		}
+	}
+
+	return true, ""
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestComparePlans(t *testing.T) {
+	basic := &Plan{ID: "basic", Amount: 1000, Interval: IntervalMonthly}
+	pro := &Plan{ID: "pro", Amount: 2500, Interval: IntervalMonthly}
+	basicYearly := &Plan{ID: "basic-yearly", Amount: 12000, Interval: IntervalYearly}
+	proYearly := &Plan{ID: "pro-yearly", Amount: 24000, Interval: IntervalYearly}
+
+	tests := []struct {
+		name      string
+		from, to  *Plan
+		direction PlanChange
+		diff      int64
+	}{
+		{"upgrade", basic, pro, PlanUpgrade, 1500},
+		{"downgrade", pro, basic, PlanDowngrade, -1500},
+		{"lateral same price", basic, &Plan{ID: "basic-2", Amount: 1000, Interval: IntervalMonthly}, PlanLateral, 0},
+		{"monthly to equivalent yearly", basic, basicYearly, PlanLateral, 0},
+		{"yearly discount is a downgrade", pro, proYearly, PlanDowngrade, -500},
+		{"yearly to monthly upgrade", basicYearly, pro, PlanUpgrade, 1500},
+		{"from free", nil, basic, PlanUpgrade, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComparePlans(tt.from, tt.to)
+			if got.Direction != tt.direction || got.MonthlyDifference != tt.diff {
+				t.Errorf("ComparePlans = %+v, want {%s %d}", got, tt.direction, tt.diff)
+			}
+		})
+	}
+}
+
+func TestPlanMonthlyAmount(t *testing.T) {
+	tests := []struct {
+		plan *Plan
+		want int64
+	}{
+		{&Plan{Amount: 1000, Interval: IntervalMonthly}, 1000},
+		{&Plan{Amount: 9900, Interval: IntervalYearly}, 825},
+		{&Plan{Amount: 1000, Interval: IntervalYearly}, 83},  // 83.33
+		{&Plan{Amount: 500, Interval: IntervalWeekly}, 2167}, // 2166.67
+		{&Plan{Amount: 5000, Interval: IntervalOneTime}, 0},
+		{nil, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.plan.MonthlyAmount(); got != tt.want {
+			t.Errorf("MonthlyAmount(%+v) = %d, want %d", tt.plan, got, tt.want)
+		}
+	}
+}
+
+func TestCanChangePlan(t *testing.T) {
+	m := NewManager(nil)
+	current := &Plan{ID: "team", Amount: 5000, Currency: "usd", Interval: IntervalMonthly, Active: true}
+	m.AddPlan(current)
+
+	small := &Plan{ID: "small", Amount: 1000, Currency: "usd", Interval: IntervalMonthly, Active: true,
+		Limits: map[string]int64{LimitSeats: 3}}
+	large := &Plan{ID: "large", Amount: 9000, Currency: "usd", Interval: IntervalMonthly, Active: true,
+		Limits: map[string]int64{LimitSeats: 50}}
+
+	tests := []struct {
+		name    string
+		sub     *Subscription
+		plan    *Plan
+		allowed bool
+		reason  string
+	}{
+		{"upgrade allowed", &Subscription{PlanID: "team", Status: StatusActive, Quantity: 5}, large, true, ""},
+		{"downgrade below seats", &Subscription{PlanID: "team", Status: StatusActive, Quantity: 5}, small, false, "seats usage (5) exceeds"},
+		{"downgrade within seats", &Subscription{PlanID: "team", Status: StatusActive, Quantity: 2}, small, true, ""},
+		{"same plan", &Subscription{PlanID: "team", Status: StatusActive}, current, false, "already subscribed"},
+		{"inactive plan", &Subscription{PlanID: "team", Status: StatusActive}, &Plan{ID: "legacy", Interval: IntervalMonthly}, false, "not available"},
+		{"one-time plan", &Subscription{PlanID: "team", Status: StatusActive}, &Plan{ID: "once", Interval: IntervalOneTime, Active: true}, false, "not a subscription plan"},
+		{"canceled subscription", &Subscription{PlanID: "team", Status: StatusCanceled}, large, false, "canceled"},
+		{"currency change", &Subscription{PlanID: "team", Status: StatusActive}, &Plan{ID: "eu", Currency: "eur", Interval: IntervalMonthly, Active: true}, false, "currency"},
+		{"missing plan", &Subscription{PlanID: "team", Status: StatusActive}, nil, false, "plan not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := m.CanChangePlan(context.Background(), tt.sub, tt.plan)
+			if allowed != tt.allowed {
+				t.Errorf("allowed = %v, want %v (reason %q)", allowed, tt.allowed, reason)
+			}
+			if !strings.Contains(reason, tt.reason) || (tt.reason == "" && reason != "") {
+				t.Errorf("reason = %q, want it to contain %q", reason, tt.reason)
+			}
+		})
+	}
+}
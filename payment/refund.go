@@ -0,0 +1,161 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+var (
+	// ErrChargeNotFound is returned when Refund cannot look up the charge
+	ErrChargeNotFound = errors.New("charge not found")
+	// ErrOverRefund is returned when a refund exceeds the amount left to refund
+	ErrOverRefund = errors.New("refund exceeds refundable amount")
+	// ErrChargeFullyRefunded is returned once nothing is left to refund
+	ErrChargeFullyRefunded = errors.New("charge already fully refunded")
+)
+
+// ChargeGetter looks up a charge by ID. Providers implementing it let
+// Refund validate charges the manager did not create itself.
+type ChargeGetter interface {
+	GetCharge(ctx context.Context, chargeID string) (*Charge, error)
+}
+
+// recordCharge remembers a charge made through the manager so it can be
+// refunded without a provider lookup
+func (m *Manager) recordCharge(charge *Charge) {
+	if charge.ID == "" {
+		return
+	}
+	c := *charge
+	m.mu.Lock()
+	m.charges[charge.ID] = &c
+	m.mu.Unlock()
+}
+
+// Refund refunds amount cents of a charge, never more than the charge minus
+// what was already refunded. An amount of 0 refunds the remaining balance.
+//
+// The charge is looked up with the provider when it implements
+// ChargeGetter, otherwise among charges made by ChargeOneTime and
+// ChargeOneTimeWithCoupon. Refunds are tracked per charge, and a provider
+// reporting Charge.AmountRefunded is trusted when it reports more, so
+// refunds made elsewhere are counted too. Concurrent refunds of the same
+// charge cannot together exceed its amount.
+func (m *Manager) Refund(ctx context.Context, chargeID string, amount int64, reason string) (*Refund, error) {
+	if amount < 0 {
+		return nil, fmt.Errorf("invalid refund amount: %d", amount)
+	}
+
+	charge, err := m.lookupCharge(ctx, chargeID)
+	if err != nil {
+		return nil, err
+	}
+	if charge.Status == ChargeFailed {
+		return nil, fmt.Errorf("charge %s failed and cannot be refunded", chargeID)
+	}
+
+	amount, err = m.reserveRefund(charge, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &Refund{
+		ChargeID:  chargeID,
+		Amount:    amount,
+		Currency:  charge.Currency,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if err := m.provider.RefundPayment(ctx, refund); err != nil {
+		m.releaseRefund(chargeID, amount)
+		return nil, fmt.Errorf("failed to refund payment: %v", err)
+	}
+	if refund.Status == RefundFailed {
+		m.releaseRefund(chargeID, amount)
+		return refund, fmt.Errorf("refund of charge %s failed", chargeID)
+	}
+
+	common.Info("[PAYMENT] Refunded %d cents of charge %s", amount, chargeID)
+	return refund, nil
+}
+
+// RefundedAmount returns the cents refunded through Refund for a charge
+func (m *Manager) RefundedAmount(chargeID string) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.refunded[chargeID]
+}
+
+// lookupCharge finds a charge with the provider or among recorded charges
+func (m *Manager) lookupCharge(ctx context.Context, chargeID string) (*Charge, error) {
+	if getter, ok := m.provider.(ChargeGetter); ok {
+		charge, err := getter.GetCharge(ctx, chargeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get charge: %v", err)
+		}
+		if charge == nil {
+			return nil, fmt.Errorf("%w: %s", ErrChargeNotFound, chargeID)
+		}
+		return charge, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	charge, ok := m.charges[chargeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChargeNotFound, chargeID)
+	}
+	c := *charge
+	return &c, nil
+}
+
+// reserveRefund checks amount against the refundable balance and counts it
+// as refunded. It returns the amount to refund, resolving 0 to the balance.
+func (m *Manager) reserveRefund(charge *Charge, amount int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refunded := m.refunded[charge.ID]
+	if charge.AmountRefunded > refunded {
+		refunded = charge.AmountRefunded
+	}
+	remaining := charge.Amount - refunded
+	if remaining <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrChargeFullyRefunded, charge.ID)
+	}
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return 0, fmt.Errorf("%w: %d cents requested, %d of %d cents left on charge %s",
+			ErrOverRefund, amount, remaining, charge.Amount, charge.ID)
+	}
+
+	m.refunded[charge.ID] = refunded + amount
+	return amount, nil
+}
+
+// releaseRefund undoes reserveRefund after a failed refund
+func (m *Manager) releaseRefund(chargeID string, amount int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refunded[chargeID] -= amount
+}
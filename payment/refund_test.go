@@ -0,0 +1,174 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// refundProvider charges and refunds in memory
+type refundProvider struct {
+	Provider
+	refunds    []*Refund
+	failRefund bool
+}
+
+func (p *refundProvider) ChargePayment(ctx context.Context, charge *Charge) error {
+	charge.ID = "ch_1"
+	charge.Status = ChargeSucceeded
+	return nil
+}
+
+func (p *refundProvider) RefundPayment(ctx context.Context, refund *Refund) error {
+	if p.failRefund {
+		return errors.New("provider unavailable")
+	}
+	refund.ID = "re_1"
+	refund.Status = RefundSucceeded
+	p.refunds = append(p.refunds, refund)
+	return nil
+}
+
+// chargeLookupProvider also reports charges, including refunds made elsewhere
+type chargeLookupProvider struct {
+	refundProvider
+	charges map[string]*Charge
+}
+
+func (p *chargeLookupProvider) GetCharge(ctx context.Context, chargeID string) (*Charge, error) {
+	charge, ok := p.charges[chargeID]
+	if !ok {
+		return nil, errors.New("no such charge")
+	}
+	return charge, nil
+}
+
+func TestRefund(t *testing.T) {
+	ctx := context.Background()
+	provider := &refundProvider{}
+	mgr := NewManager(provider)
+
+	charge, err := mgr.ChargeOneTime(ctx, "cus_1", 1000, "order 42")
+	if err != nil {
+		t.Fatalf("ChargeOneTime failed: %v", err)
+	}
+
+	// Partial refund
+	refund, err := mgr.Refund(ctx, charge.ID, 300, "damaged item")
+	if err != nil {
+		t.Fatalf("partial Refund failed: %v", err)
+	}
+	if refund.Amount != 300 || refund.Currency != "usd" || refund.Reason != "damaged item" {
+		t.Errorf("unexpected refund: %+v", refund)
+	}
+
+	// Over-refund of the remaining 700
+	if _, err := mgr.Refund(ctx, charge.ID, 800, "mistake"); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("over-refund error = %v, want ErrOverRefund", err)
+	}
+
+	// A failed provider call does not count
+	provider.failRefund = true
+	if _, err := mgr.Refund(ctx, charge.ID, 100, "retry"); err == nil {
+		t.Error("expected provider error")
+	}
+	provider.failRefund = false
+	if got := mgr.RefundedAmount(charge.ID); got != 300 {
+		t.Errorf("RefundedAmount after failed refund = %d, want 300", got)
+	}
+
+	// Amount 0 refunds the rest
+	refund, err = mgr.Refund(ctx, charge.ID, 0, "cancel order")
+	if err != nil {
+		t.Fatalf("full Refund failed: %v", err)
+	}
+	if refund.Amount != 700 {
+		t.Errorf("remaining refund = %d, want 700", refund.Amount)
+	}
+
+	// Nothing left
+	if _, err := mgr.Refund(ctx, charge.ID, 1, "again"); !errors.Is(err, ErrChargeFullyRefunded) {
+		t.Errorf("double refund error = %v, want ErrChargeFullyRefunded", err)
+	}
+	if len(provider.refunds) != 2 {
+		t.Errorf("provider received %d refunds, want 2", len(provider.refunds))
+	}
+}
+
+func TestRefundFullAmount(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager(&refundProvider{})
+	charge, err := mgr.ChargeOneTime(ctx, "cus_1", 1000, "order 43")
+	if err != nil {
+		t.Fatalf("ChargeOneTime failed: %v", err)
+	}
+
+	if _, err := mgr.Refund(ctx, charge.ID, 1000, "cancel"); err != nil {
+		t.Fatalf("full Refund failed: %v", err)
+	}
+	if _, err := mgr.Refund(ctx, charge.ID, 0, "cancel"); !errors.Is(err, ErrChargeFullyRefunded) {
+		t.Errorf("error = %v, want ErrChargeFullyRefunded", err)
+	}
+}
+
+func TestRefundValidation(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager(&refundProvider{})
+
+	if _, err := mgr.Refund(ctx, "ch_unknown", 100, "x"); !errors.Is(err, ErrChargeNotFound) {
+		t.Errorf("unknown charge error = %v, want ErrChargeNotFound", err)
+	}
+
+	charge, err := mgr.ChargeOneTime(ctx, "cus_1", 1000, "order 44")
+	if err != nil {
+		t.Fatalf("ChargeOneTime failed: %v", err)
+	}
+	if _, err := mgr.Refund(ctx, charge.ID, -5, "x"); err == nil {
+		t.Error("expected error for negative amount")
+	}
+	if _, err := mgr.Refund(ctx, charge.ID, 1001, "x"); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("error = %v, want ErrOverRefund", err)
+	}
+}
+
+func TestRefundUsesProviderCharge(t *testing.T) {
+	ctx := context.Background()
+	provider := &chargeLookupProvider{charges: map[string]*Charge{
+		"ch_ext":    {ID: "ch_ext", Amount: 5000, Currency: "eur", Status: ChargeSucceeded, AmountRefunded: 4500},
+		"ch_failed": {ID: "ch_failed", Amount: 5000, Currency: "eur", Status: ChargeFailed},
+	}}
+	mgr := NewManager(provider)
+
+	// 4500 was refunded outside the manager, so only 500 is left
+	if _, err := mgr.Refund(ctx, "ch_ext", 600, "x"); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("error = %v, want ErrOverRefund", err)
+	}
+	refund, err := mgr.Refund(ctx, "ch_ext", 500, "x")
+	if err != nil {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if refund.Currency != "eur" {
+		t.Errorf("currency = %q, want eur", refund.Currency)
+	}
+
+	if _, err := mgr.Refund(ctx, "ch_failed", 100, "x"); err == nil {
+		t.Error("expected error refunding a failed charge")
+	}
+	if _, err := mgr.Refund(ctx, "ch_missing", 100, "x"); err == nil {
+		t.Error("expected error for a charge the provider does not know")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SubscriptionLister enumerates stored subscriptions. Providers or local
+// stores implement it to support the reminder queries below.
+type SubscriptionLister interface {
+	// ListSubscriptions returns subscriptions in any of the given statuses,
+	// or all subscriptions when none are given.
+	ListSubscriptions(ctx context.Context, statuses ...SubscriptionStatus) ([]*Subscription, error)
+}
+
+// SetSubscriptionLister configures where UpcomingTrialEnds and
+// UpcomingRenewals look up subscriptions. By default the provider is used
+// when it implements SubscriptionLister.
+func (m *Manager) SetSubscriptionLister(lister SubscriptionLister) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lister = lister
+}
+
+// subscriptionLister returns the configured lister or the provider
+func (m *Manager) subscriptionLister() (SubscriptionLister, error) {
+	m.mu.RLock()
+	lister := m.lister
+	m.mu.RUnlock()
+
+	if lister != nil {
+		return lister, nil
+	}
+	if lister, ok := m.provider.(SubscriptionLister); ok {
+		return lister, nil
+	}
+	return nil, fmt.Errorf("no subscription lister configured")
+}
+
+// UpcomingTrialEnds returns trialing subscriptions whose trial ends after
+// now and no later than now+within, earliest first. Run it from a cron to
+// remind customers before their first charge.
+func (m *Manager) UpcomingTrialEnds(ctx context.Context, now time.Time, within time.Duration) ([]*Subscription, error) {
+	return m.upcoming(ctx, now, within, []SubscriptionStatus{StatusTrialing}, func(sub *Subscription) *time.Time {
+		return sub.TrialEnd
+	})
+}
+
+// UpcomingRenewals returns active subscriptions whose current period ends
+// after now and no later than now+within, earliest first. Subscriptions
+// scheduled to cancel are skipped since they will not renew.
+func (m *Manager) UpcomingRenewals(ctx context.Context, now time.Time, within time.Duration) ([]*Subscription, error) {
+	return m.upcoming(ctx, now, within, []SubscriptionStatus{StatusActive}, func(sub *Subscription) *time.Time {
+		if sub.CancelAt != nil || sub.CurrentPeriodEnd.IsZero() {
+			return nil
+		}
+		return &sub.CurrentPeriodEnd
+	})
+}
+
+// upcoming lists subscriptions in statuses whose date falls in (now, now+within]
+func (m *Manager) upcoming(ctx context.Context, now time.Time, within time.Duration, statuses []SubscriptionStatus, date func(*Subscription) *time.Time) ([]*Subscription, error) {
+	lister, err := m.subscriptionLister()
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := lister.ListSubscriptions(ctx, statuses...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %v", err)
+	}
+
+	end := now.Add(within)
+	var result []*Subscription
+	for _, sub := range subs {
+		if !hasStatus(sub.Status, statuses) {
+			continue
+		}
+		if at := date(sub); at != nil && at.After(now) && !at.After(end) {
+			result = append(result, sub)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return date(result[i]).Before(*date(result[j]))
+	})
+	return result, nil
+}
+
+// hasStatus reports whether status is one of statuses
+func hasStatus(status SubscriptionStatus, statuses []SubscriptionStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
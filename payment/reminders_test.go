@@ -0,0 +1,117 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memorySubscriptions lists subscriptions without filtering, so the
+// manager's own filtering is exercised
+type memorySubscriptions []*Subscription
+
+func (s memorySubscriptions) ListSubscriptions(ctx context.Context, statuses ...SubscriptionStatus) ([]*Subscription, error) {
+	return s, nil
+}
+
+// listingProvider is a provider that can list subscriptions itself
+type listingProvider struct {
+	Provider
+	memorySubscriptions
+}
+
+func subscriptionIDs(subs []*Subscription) []string {
+	ids := make([]string, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	return ids
+}
+
+func TestUpcomingTrialEnds(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	mgr := NewManager(nil)
+	mgr.SetSubscriptionLister(memorySubscriptions{
+		{ID: "in-2d", Status: StatusTrialing, TrialEnd: at(48 * time.Hour)},
+		{ID: "in-1d", Status: StatusTrialing, TrialEnd: at(24 * time.Hour)},
+		{ID: "edge", Status: StatusTrialing, TrialEnd: at(72 * time.Hour)},
+		{ID: "too-late", Status: StatusTrialing, TrialEnd: at(96 * time.Hour)},
+		{ID: "already-ended", Status: StatusTrialing, TrialEnd: at(-time.Hour)},
+		{ID: "converted", Status: StatusActive, TrialEnd: at(24 * time.Hour)},
+		{ID: "no-trial", Status: StatusTrialing},
+	})
+
+	subs, err := mgr.UpcomingTrialEnds(context.Background(), now, 72*time.Hour)
+	if err != nil {
+		t.Fatalf("UpcomingTrialEnds failed: %v", err)
+	}
+	got := subscriptionIDs(subs)
+	want := []string{"in-1d", "in-2d", "edge"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestUpcomingRenewals(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cancelAt := now.Add(7 * 24 * time.Hour)
+
+	provider := &listingProvider{memorySubscriptions: memorySubscriptions{
+		{ID: "renews", Status: StatusActive, CurrentPeriodEnd: now.Add(5 * 24 * time.Hour)},
+		{ID: "later", Status: StatusActive, CurrentPeriodEnd: now.Add(30 * 24 * time.Hour)},
+		{ID: "canceling", Status: StatusActive, CurrentPeriodEnd: now.Add(5 * 24 * time.Hour), CancelAt: &cancelAt},
+		{ID: "canceled", Status: StatusCanceled, CurrentPeriodEnd: now.Add(5 * 24 * time.Hour)},
+		{ID: "past", Status: StatusActive, CurrentPeriodEnd: now.Add(-24 * time.Hour)},
+	}}
+	mgr := NewManager(provider)
+
+	subs, err := mgr.UpcomingRenewals(context.Background(), now, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("UpcomingRenewals failed: %v", err)
+	}
+	if got := subscriptionIDs(subs); len(got) != 1 || got[0] != "renews" {
+		t.Errorf("got %v, want [renews]", got)
+	}
+}
+
+// failingLister returns an error for every query
+type failingLister struct{}
+
+func (failingLister) ListSubscriptions(ctx context.Context, statuses ...SubscriptionStatus) ([]*Subscription, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestUpcomingWithoutLister(t *testing.T) {
+	mgr := NewManager(nil)
+	if _, err := mgr.UpcomingRenewals(context.Background(), time.Now(), time.Hour); err == nil {
+		t.Error("expected error without a subscription lister")
+	}
+
+	mgr.SetSubscriptionLister(failingLister{})
+	if _, err := mgr.UpcomingTrialEnds(context.Background(), time.Now(), time.Hour); err == nil {
+		t.Error("expected store error to be returned")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// TaxCalculator computes the tax owed on an amount for a billing address.
+// Implementations typically look up the rate for addr.Country and
+// addr.State, or call a tax service. addr is never nil: a charge or invoice
+// without a billing address fails before the calculator is called.
+type TaxCalculator interface {
+	CalculateTax(ctx context.Context, amount int64, currency string, addr *Address) (taxCents int64, rate float64, err error)
+}
+
+// ZeroTaxCalculator charges no tax. It is the Manager default.
+type ZeroTaxCalculator struct{}
+
+// CalculateTax always returns zero
+func (ZeroTaxCalculator) CalculateTax(ctx context.Context, amount int64, currency string, addr *Address) (int64, float64, error) {
+	return 0, 0, nil
+}
+
+// SetTaxCalculator configures the tax added to one-time charges and by
+// AddInvoiceTax. A nil calc restores ZeroTaxCalculator.
+func (m *Manager) SetTaxCalculator(calc TaxCalculator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tax = calc
+}
+
+// taxCalculator returns the configured calculator, or nil for zero tax
+func (m *Manager) taxCalculator() TaxCalculator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.tax
+}
+
+// calculateTax runs calc and rejects negative results
+func calculateTax(ctx context.Context, calc TaxCalculator, amount int64, currency string, addr *Address) (int64, float64, error) {
+	if addr == nil {
+		return 0, 0, fmt.Errorf("failed to calculate tax: no billing address")
+	}
+	tax, rate, err := calc.CalculateTax(ctx, amount, currency, addr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to calculate tax: %v", err)
+	}
+	if tax < 0 || rate < 0 {
+		return 0, 0, fmt.Errorf("failed to calculate tax: negative tax %d at rate %g", tax, rate)
+	}
+	return tax, rate, nil
+}
+
+// applyChargeTax adds tax for the customer's billing address to a charge
+// that has not been submitted yet. Without a calculator the customer is
+// not looked up at all.
+func (m *Manager) applyChargeTax(ctx context.Context, charge *Charge) error {
+	calc := m.taxCalculator()
+	if calc == nil {
+		return nil
+	}
+
+	customer, err := m.provider.GetCustomer(ctx, charge.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to get customer: %v", err)
+	}
+	if customer == nil || customer.Address == nil {
+		return fmt.Errorf("failed to calculate tax: customer %s has no billing address", charge.CustomerID)
+	}
+
+	tax, rate, err := calculateTax(ctx, calc, charge.Amount, charge.Currency, customer.Address)
+	if err != nil {
+		return err
+	}
+	charge.Tax = tax
+	charge.TaxRate = rate
+	charge.Amount += tax
+	return nil
+}
+
+// AddInvoiceTax appends a tax line to an invoice being built and sets
+// inv.Amount to the line subtotal plus tax. No line is added when the tax
+// is zero. An invoice can only be taxed once.
+func (m *Manager) AddInvoiceTax(ctx context.Context, inv *Invoice, addr *Address) error {
+	if inv == nil {
+		return fmt.Errorf("invoice is required")
+	}
+
+	var subtotal int64
+	for _, line := range inv.Lines {
+		if line.Tax {
+			return fmt.Errorf("invoice %s already has a tax line", invoiceNumber(inv))
+		}
+		subtotal += lineAmount(line)
+	}
+
+	calc := m.taxCalculator()
+	if calc == nil {
+		calc = ZeroTaxCalculator{}
+	}
+	tax, rate, err := calculateTax(ctx, calc, subtotal, inv.Currency, addr)
+	if err != nil {
+		return err
+	}
+
+	if tax > 0 {
+		inv.Lines = append(inv.Lines, InvoiceLine{
+			Description: taxLineDescription(rate),
+			Quantity:    1,
+			UnitPrice:   tax,
+			Amount:      tax,
+			Tax:         true,
+			TaxRate:     rate,
+		})
+	}
+	inv.Amount = subtotal + tax
+	return nil
+}
+
+// lineAmount returns the line amount, deriving it from quantity and unit
+// price when unset
+func lineAmount(line InvoiceLine) int64 {
+	if line.Amount != 0 {
+		return line.Amount
+	}
+	return line.UnitPrice * int64(line.Quantity)
+}
+
+// taxLineDescription renders a rate such as 0.0825 as "Tax (8.25%)"
+func taxLineDescription(rate float64) string {
+	if rate == 0 {
+		return "Tax"
+	}
+	return fmt.Sprintf("Tax (%g%%)", math.Round(rate*100000)/1000)
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fixedRateTax charges rate on every amount, or fails for addresses
+// without a country. It dereferences addr, as real calculators do.
+type fixedRateTax struct {
+	rate float64
+}
+
+func (c fixedRateTax) CalculateTax(ctx context.Context, amount int64, currency string, addr *Address) (int64, float64, error) {
+	if addr.Country == "" {
+		return 0, 0, errors.New("address required")
+	}
+	return int64(math.Round(float64(amount) * c.rate)), c.rate, nil
+}
+
+// taxProvider charges in memory and knows one customer's address
+type taxProvider struct {
+	couponProvider
+	customers map[string]*Customer
+	charged   []*Charge
+}
+
+func (p *taxProvider) GetCustomer(ctx context.Context, id string) (*Customer, error) {
+	customer, ok := p.customers[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return customer, nil
+}
+
+func (p *taxProvider) ChargePayment(ctx context.Context, charge *Charge) error {
+	p.charged = append(p.charged, charge)
+	return p.couponProvider.ChargePayment(ctx, charge)
+}
+
+func newTaxManager() (*Manager, *taxProvider) {
+	provider := &taxProvider{customers: map[string]*Customer{
+		"cus_1": {ID: "cus_1", Address: &Address{City: "Austin", State: "TX", Country: "US"}},
+		"cus_2": {ID: "cus_2"},
+		"cus_3": {ID: "cus_3", Address: &Address{City: "Austin"}},
+	}}
+	return NewManager(provider), provider
+}
+
+func TestChargeOneTimeTax(t *testing.T) {
+	tests := []struct {
+		name       string
+		calc       TaxCalculator
+		customerID string
+		wantTax    int64
+		wantAmount int64
+		wantErr    bool
+	}{
+		{"Default zero tax", nil, "cus_1", 0, 1000, false},
+		{"Fixed rate", fixedRateTax{rate: 0.0825}, "cus_1", 83, 1083, false},
+		{"No address", fixedRateTax{rate: 0.0825}, "cus_2", 0, 0, true},
+		{"Calculator error", fixedRateTax{rate: 0.0825}, "cus_3", 0, 0, true},
+		{"Unknown customer", fixedRateTax{rate: 0.0825}, "cus_9", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, provider := newTaxManager()
+			mgr.SetTaxCalculator(tt.calc)
+
+			charge, err := mgr.ChargeOneTime(context.Background(), tt.customerID, 1000, "Consulting")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				if len(provider.charged) != 0 {
+					t.Error("Expected no charge to reach the provider")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChargeOneTime failed: %v", err)
+			}
+			if charge.Tax != tt.wantTax || charge.Amount != tt.wantAmount {
+				t.Errorf("Expected tax %d and amount %d, got %d and %d", tt.wantTax, tt.wantAmount, charge.Tax, charge.Amount)
+			}
+			if len(provider.charged) != 1 || provider.charged[0].Amount != tt.wantAmount {
+				t.Errorf("Expected the provider to charge %d", tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestChargeOneTimeWithCouponTax(t *testing.T) {
+	mgr, _ := newTaxManager()
+	mgr.SetTaxCalculator(fixedRateTax{rate: 0.10})
+	if err := mgr.AddCoupon(&Coupon{Code: "HALF", PercentOff: 50}); err != nil {
+		t.Fatalf("AddCoupon failed: %v", err)
+	}
+
+	charge, err := mgr.ChargeOneTimeWithCoupon(context.Background(), "cus_1", 2000, "Workshop", "half")
+	if err != nil {
+		t.Fatalf("ChargeOneTimeWithCoupon failed: %v", err)
+	}
+	// Tax applies to the discounted amount
+	if charge.Tax != 100 || charge.Amount != 1100 || charge.TaxRate != 0.10 {
+		t.Errorf("Expected tax 100 on 1000, got tax %d amount %d rate %g", charge.Tax, charge.Amount, charge.TaxRate)
+	}
+
+	// A failed tax calculation releases the redemption
+	if _, err := mgr.ChargeOneTimeWithCoupon(context.Background(), "cus_2", 2000, "Workshop", "half"); err == nil {
+		t.Fatal("Expected an error for a customer without address")
+	}
+	if got := len(mgr.Redemptions("HALF")); got != 1 {
+		t.Errorf("Expected 1 redemption, got %d", got)
+	}
+}
+
+func TestAddInvoiceTax(t *testing.T) {
+	ctx := context.Background()
+	addr := &Address{State: "TX", Country: "US"}
+	newInvoice := func() *Invoice {
+		return &Invoice{ID: "inv_1", Currency: "usd", Lines: []InvoiceLine{
+			{Description: "Pro plan", Quantity: 1, UnitPrice: 2500, Amount: 2500},
+			{Description: "Extra seats", Quantity: 3, UnitPrice: 500},
+		}}
+	}
+
+	mgr, _ := newTaxManager()
+
+	// Zero tax adds no line
+	inv := newInvoice()
+	if err := mgr.AddInvoiceTax(ctx, inv, addr); err != nil {
+		t.Fatalf("AddInvoiceTax failed: %v", err)
+	}
+	if len(inv.Lines) != 2 || inv.Amount != 4000 {
+		t.Errorf("Expected 2 lines totaling 4000, got %d lines totaling %d", len(inv.Lines), inv.Amount)
+	}
+
+	mgr.SetTaxCalculator(fixedRateTax{rate: 0.0825})
+	inv = newInvoice()
+	if err := mgr.AddInvoiceTax(ctx, inv, addr); err != nil {
+		t.Fatalf("AddInvoiceTax failed: %v", err)
+	}
+	if len(inv.Lines) != 3 {
+		t.Fatalf("Expected a tax line, got %d lines", len(inv.Lines))
+	}
+	line := inv.Lines[2]
+	if !line.Tax || line.Amount != 330 || line.TaxRate != 0.0825 || line.Description != "Tax (8.25%)" {
+		t.Errorf("Unexpected tax line %+v", line)
+	}
+	if inv.Amount != 4330 {
+		t.Errorf("Expected total 4330, got %d", inv.Amount)
+	}
+
+	if err := mgr.AddInvoiceTax(ctx, inv, addr); err == nil {
+		t.Error("Expected an error when taxing twice")
+	}
+	if err := mgr.AddInvoiceTax(ctx, newInvoice(), nil); err == nil {
+		t.Error("Expected an error for a nil address")
+	}
+
+	// The PDF shows tax under the subtotal
+	data, err := GenerateInvoicePDF(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoicePDF failed: %v", err)
+	}
+	text := extractPDFText(data)
+	for _, want := range []string{"Subtotal\nUSD 40.00", "Tax (8.25%)\nUSD 3.30", "Total\nUSD 43.30"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF text missing %q", want)
+		}
+	}
+}
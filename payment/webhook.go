@@ -0,0 +1,67 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/patdeg/common"
+)
+
+// MaxWebhookBodyBytes bounds the size of webhook payloads read by
+// WebhookHandler. Provider events are far smaller; anything larger is
+// rejected before signature verification.
+const MaxWebhookBodyBytes = 1 << 20 // 1 MB
+
+// WebhookHandler returns an http.HandlerFunc that verifies and processes
+// provider webhooks. The raw body is passed unmodified to HandleWebhook
+// together with the value of signatureHeader (e.g. "Stripe-Signature"), since
+// signatures are computed over the exact bytes sent by the provider.
+//
+// The handler responds 200 when the event was accepted, 400 when the payload
+// is too large, unsigned, or fails verification, and 405 for non-POST
+// requests.
+func (m *Manager) WebhookHandler(signatureHeader string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		signature := r.Header.Get(signatureHeader)
+		if signature == "" {
+			common.Warn("[PAYMENT] Webhook rejected: missing %s header", signatureHeader)
+			http.Error(w, "Missing signature", http.StatusBadRequest)
+			return
+		}
+
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxWebhookBodyBytes))
+		if err != nil {
+			common.Warn("[PAYMENT] Webhook rejected: failed to read body: %v", err)
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		if err := m.HandleWebhook(r.Context(), payload, signature); err != nil {
+			common.Warn("[PAYMENT] Webhook rejected: %v", err)
+			http.Error(w, "Invalid webhook", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// webhookProvider verifies HMAC-SHA256 signatures like a real provider would.
+// Only HandleWebhook is implemented; other methods panic if called.
+type webhookProvider struct {
+	Provider
+	events []string
+}
+
+func (p *webhookProvider) HandleWebhook(ctx context.Context, payload []byte, signature string) (*WebhookEvent, error) {
+	if !hmac.Equal([]byte(signature), []byte(signWebhook(payload))) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	p.events = append(p.events, event.Type)
+	return &event, nil
+}
+
+func signWebhook(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	payload := `{"id":"evt_1","type":"invoice.paid"}`
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		signature  string
+		wantStatus int
+		wantEvents int
+	}{
+		{"valid signature", http.MethodPost, payload, signWebhook([]byte(payload)), http.StatusOK, 1},
+		{"bad signature", http.MethodPost, payload, "deadbeef", http.StatusBadRequest, 0},
+		{"missing signature", http.MethodPost, payload, "", http.StatusBadRequest, 0},
+		{"tampered body", http.MethodPost, strings.Replace(payload, "paid", "void", 1), signWebhook([]byte(payload)), http.StatusBadRequest, 0},
+		{"oversized body", http.MethodPost, strings.Repeat("x", MaxWebhookBodyBytes+1), "sig", http.StatusBadRequest, 0},
+		{"wrong method", http.MethodGet, "", "sig", http.StatusMethodNotAllowed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &webhookProvider{}
+			handler := NewManager(provider).WebhookHandler("X-Signature")
+
+			req := httptest.NewRequest(tt.method, "/webhooks/payment", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set("X-Signature", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if len(provider.events) != tt.wantEvents {
+				t.Errorf("processed %d events, want %d", len(provider.events), tt.wantEvents)
+			}
+		})
+	}
+}
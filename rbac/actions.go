@@ -0,0 +1,85 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"sort"
+)
+
+// AllowedActions returns the actions userID may perform on resource in
+// tenantID, sorted, so a UI can decide which controls to show:
+//
+//	actions := mgr.AllowedActions(ctx, "alice", "invoices", "acme")
+//	// e.g. ["read", "write"]
+//
+// Wildcard grants are expanded into the known actions: the
+// StandardPermissions actions, Config.Actions, and every concrete action
+// named by a role permission or policy rule. Each candidate is decided as
+// HasPermission would decide it, so policy denies still apply. Like
+// Explain it is a dry run: nothing is audited or cached. It returns nil
+// once ctx is done.
+func (m *DefaultManager) AllowedActions(ctx context.Context, userID, resource, tenantID string) []string {
+	var allowed []string
+	for _, action := range m.knownActions() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if ok, _ := m.checkPermission(ctx, userID, resource, action, tenantID); ok {
+			allowed = append(allowed, action)
+		}
+	}
+	return allowed
+}
+
+// knownActions returns the sorted set of concrete actions wildcards expand to
+func (m *DefaultManager) knownActions() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	add := func(action string) {
+		if action != "" && action != "*" {
+			seen[action] = true
+		}
+	}
+
+	add(StandardPermissions.Read)
+	add(StandardPermissions.Write)
+	add(StandardPermissions.Delete)
+	add(StandardPermissions.Admin)
+	for _, action := range m.actions {
+		add(action)
+	}
+	for _, role := range m.roles {
+		for _, perm := range role.Permissions {
+			add(perm.Action)
+		}
+	}
+	for _, policy := range m.policies {
+		for _, rule := range policy.Rules {
+			for _, action := range rule.Actions {
+				add(action)
+			}
+		}
+	}
+
+	actions := make([]string, 0, len(seen))
+	for action := range seen {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAllowedActions(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit, Actions: []string{"publish"}})
+
+	if err := mgr.CreateRole(ctx, &Role{ID: "author", Permissions: []Permission{
+		{ID: "posts_write", Resource: "posts", Action: "write"},
+		{ID: "posts_read", Resource: "posts", Action: "read"},
+	}}); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if err := mgr.CreatePolicy(ctx, &Policy{ID: "no-delete", TenantID: "acme", Enabled: true, Rules: []PolicyRule{{
+		Resource: "posts", Actions: []string{"delete"}, Effect: EffectDeny, Principals: []string{"bob"},
+	}}}); err != nil {
+		t.Fatalf("CreatePolicy failed: %v", err)
+	}
+	for user, role := range map[string]string{"alice": "author", "bob": StandardRoles.Admin, "carol": StandardRoles.Admin} {
+		if err := mgr.AssignRole(ctx, user, role, "acme"); err != nil {
+			t.Fatalf("AssignRole failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		userID   string
+		resource string
+		want     []string
+	}{
+		{"Wildcard action expands to known actions", "carol", "posts", []string{"admin", "delete", "publish", "read", "write"}},
+		{"Specific actions only", "alice", "posts", []string{"read", "write"}},
+		{"Specific role on another resource", "alice", "comments", nil},
+		{"Policy deny overrides role allow", "bob", "posts", []string{"admin", "publish", "read", "write"}},
+		{"No roles", "dave", "posts", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mgr.AllowedActions(ctx, tt.userID, tt.resource, "acme")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllowedActions(%s, %s) = %v, want %v", tt.userID, tt.resource, got, tt.want)
+			}
+		})
+	}
+
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+	if len(audit.entries) != 0 {
+		t.Errorf("Expected AllowedActions not to audit, got %d decisions", len(audit.entries))
+	}
+}
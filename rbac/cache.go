@@ -0,0 +1,108 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+// DefaultPermissionCacheSize bounds the permission cache when
+// Config.PermissionCacheSize is not set
+const DefaultPermissionCacheSize = 10000
+
+// cacheGeneration identifies the state a cached decision was computed
+// from. The global counter changes when roles or policies change, the user
+// counter when that user's assignments change.
+type cacheGeneration struct {
+	global, user uint64
+}
+
+// cachedDecision is a HasPermission result
+type cachedDecision struct {
+	allowed    bool
+	reason     string
+	generation cacheGeneration
+	validUntil time.Time // earliest expiry of the user's temporary roles
+}
+
+// newPermissionCache returns the cache configured by config, or nil when
+// caching is disabled
+func newPermissionCache(config *Config) *common.LRUCache[string, cachedDecision] {
+	if !config.CachePermissions {
+		return nil
+	}
+	size := config.PermissionCacheSize
+	if size <= 0 {
+		size = DefaultPermissionCacheSize
+	}
+	return common.NewLRUCache[string, cachedDecision](size, 0)
+}
+
+// permissionCacheKey joins the decision inputs with a separator that
+// cannot appear in IDs
+func permissionCacheKey(userID, resource, action, tenantID string) string {
+	return userID + "\x00" + tenantID + "\x00" + resource + "\x00" + action
+}
+
+// cacheState returns the current generation for userID and the time at
+// which the user's earliest active temporary role in tenantID expires,
+// directly or through a group, so a cached allow does not outlive the
+// grant that produced it.
+func (m *DefaultManager) cacheState(userID, tenantID string) (cacheGeneration, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var validUntil time.Time
+	now := time.Now()
+	for _, grant := range m.activeGrants(userID, tenantID, now) {
+		if grant.expiresAt == nil {
+			continue
+		}
+		if validUntil.IsZero() || grant.expiresAt.Before(validUntil) {
+			validUntil = *grant.expiresAt
+		}
+	}
+	return cacheGeneration{global: m.cacheGen, user: m.userCacheGen[userID]}, validUntil
+}
+
+// cachedPermission returns a still-valid cached decision
+func (m *DefaultManager) cachedPermission(key string, generation cacheGeneration) (cachedDecision, bool) {
+	decision, ok := m.permCache.Get(key)
+	if !ok || decision.generation != generation {
+		return cachedDecision{}, false
+	}
+	if !decision.validUntil.IsZero() && !time.Now().Before(decision.validUntil) {
+		m.permCache.Delete(key)
+		return cachedDecision{}, false
+	}
+	return decision, true
+}
+
+// invalidateUser drops cached decisions for userID; callers hold m.mu
+func (m *DefaultManager) invalidateUser(userID string) {
+	if m.permCache != nil {
+		m.userCacheGen[userID]++
+	}
+}
+
+// invalidateAll drops every cached decision; callers hold m.mu
+func (m *DefaultManager) invalidateAll() {
+	if m.permCache != nil {
+		m.cacheGen++
+		m.permCache.Purge()
+	}
+}
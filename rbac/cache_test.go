@@ -0,0 +1,152 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newCachedManager(audit AuditLogger) *DefaultManager {
+	return NewManagerWithConfig(&Config{AuditLogger: audit, CachePermissions: true}).(*DefaultManager)
+}
+
+func TestPermissionCacheRevoke(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := newCachedManager(audit)
+
+	if err := mgr.AssignRole(ctx, "alice", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if !mgr.HasPermission(ctx, "alice", "documents", "write", "acme") {
+			t.Fatalf("check %d: expected admin to write documents", i)
+		}
+	}
+	if mgr.permCache.Len() != 1 {
+		t.Errorf("cache holds %d decisions, want 1", mgr.permCache.Len())
+	}
+	if len(audit.entries) != 2 {
+		t.Errorf("recorded %d audit entries, want one per check", len(audit.entries))
+	}
+
+	if err := mgr.RevokeRole(ctx, "alice", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("RevokeRole failed: %v", err)
+	}
+	if mgr.HasPermission(ctx, "alice", "documents", "write", "acme") {
+		t.Error("revoke did not invalidate the cached allow")
+	}
+}
+
+func TestPermissionCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	deny := &Policy{
+		ID:       "freeze",
+		TenantID: "acme",
+		Enabled:  true,
+		Rules:    []PolicyRule{{Resource: "documents", Actions: []string{"write"}, Effect: EffectDeny, Principals: []string{"*"}}},
+	}
+
+	tests := []struct {
+		name   string
+		change func(mgr Manager) error
+	}{
+		{"CreatePolicy", func(mgr Manager) error { return mgr.CreatePolicy(ctx, deny) }},
+		{"UpdateRole", func(mgr Manager) error {
+			return mgr.UpdateRole(ctx, &Role{ID: "writer", Permissions: []Permission{{ID: "docs-read", Resource: "documents", Action: "read"}}})
+		}},
+		{"DeleteRole", func(mgr Manager) error { return mgr.DeleteRole(ctx, "writer") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newCachedManager(nil)
+			err := mgr.CreateRole(ctx, &Role{ID: "writer", Permissions: []Permission{{ID: "docs-write", Resource: "documents", Action: "write"}}})
+			if err != nil {
+				t.Fatalf("CreateRole failed: %v", err)
+			}
+			mgr.AssignRole(ctx, "bob", "writer", "acme")
+
+			if !mgr.HasPermission(ctx, "bob", "documents", "write", "acme") {
+				t.Fatal("expected writer to write documents")
+			}
+			if err := tt.change(mgr); err != nil {
+				t.Fatalf("%s failed: %v", tt.name, err)
+			}
+			if mgr.HasPermission(ctx, "bob", "documents", "write", "acme") {
+				t.Errorf("%s did not invalidate the cached allow", tt.name)
+			}
+		})
+	}
+
+	// Removing a deny policy restores access
+	mgr := newCachedManager(nil)
+	mgr.AssignRole(ctx, "bob", StandardRoles.Admin, "acme")
+	mgr.CreatePolicy(ctx, deny)
+	if mgr.HasPermission(ctx, "bob", "documents", "write", "acme") {
+		t.Fatal("expected policy to deny")
+	}
+	mgr.DeletePolicy(ctx, deny.ID)
+	if !mgr.HasPermission(ctx, "bob", "documents", "write", "acme") {
+		t.Error("DeletePolicy did not invalidate the cached deny")
+	}
+}
+
+func TestPermissionCacheTemporaryRole(t *testing.T) {
+	ctx := context.Background()
+	mgr := newCachedManager(nil)
+
+	if err := mgr.GrantTemporaryRole(ctx, "support", StandardRoles.Admin, "acme", 20*time.Millisecond, "ticket"); err != nil {
+		t.Fatalf("GrantTemporaryRole failed: %v", err)
+	}
+	if !mgr.HasPermission(ctx, "support", "billing", "write", "acme") {
+		t.Fatal("expected temporary admin to have permissions")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if mgr.HasPermission(ctx, "support", "billing", "write", "acme") {
+		t.Error("cached allow outlived the temporary role")
+	}
+}
+
+// benchmarkHasPermission checks a user holding several custom roles
+func benchmarkHasPermission(b *testing.B, cache bool) {
+	ctx := context.Background()
+	mgr := NewManagerWithConfig(&Config{CachePermissions: cache})
+	for i := 0; i < 20; i++ {
+		roleID := fmt.Sprintf("role-%d", i)
+		var perms []Permission
+		for j := 0; j < 20; j++ {
+			perms = append(perms, Permission{ID: fmt.Sprintf("%s-%d", roleID, j), Resource: fmt.Sprintf("resource-%d-%d", i, j), Action: "read"})
+		}
+		mgr.CreateRole(ctx, &Role{ID: roleID, Permissions: perms})
+		mgr.AssignRole(ctx, "alice", roleID, "acme")
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !mgr.HasPermission(ctx, "alice", "resource-19-19", "read", "acme") {
+			b.Fatal("expected permission")
+		}
+	}
+}
+
+func BenchmarkHasPermission(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) { benchmarkHasPermission(b, false) })
+	b.Run("cached", func(b *testing.B) { benchmarkHasPermission(b, true) })
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Step kinds reported in a Decision
+const (
+	StepPolicy = "policy"
+	StepRole   = "role"
+)
+
+// Decision explains how an access decision was reached. It is returned by
+// Explain for support and debugging.
+type Decision struct {
+	Effect         Effect         // EffectAllow or EffectDeny
+	Reason         string         // Reason HasPermission reports to the audit logger
+	DecidingPolicy string         // Policy that decided, if any
+	DecidingRole   string         // Role that granted access, if any
+	Steps          []DecisionStep // Policies then roles, in evaluation order
+}
+
+// Allowed reports whether the decision grants access
+func (d Decision) Allowed() bool {
+	return d.Effect == EffectAllow
+}
+
+// DecisionStep is one policy or role considered for a decision
+type DecisionStep struct {
+	Kind     string // StepPolicy or StepRole
+	ID       string // Policy or role ID
+	Effect   Effect // Effect of the matching rule or permission; empty if none matched
+	Detail   string // What matched, or why nothing did
+	Decisive bool   // Whether this step decided the outcome
+}
+
+// Explain reports the decision HasPermission would make for the request
+// along with every policy and role evaluated:
+//
+//	d := mgr.Explain(ctx, "alice", "billing", "read", "acme")
+//	for _, step := range d.Steps {
+//	    log.Printf("%s %s: %s %s", step.Kind, step.ID, step.Effect, step.Detail)
+//	}
+//
+// It is a dry run: nothing is audited and the permission cache is neither
+// read nor written. Policies are listed by descending Priority, then ID;
+// the order does not affect the outcome since any matching deny wins. Once
+// ctx is done it returns a deny with the context error as the reason.
+func (m *DefaultManager) Explain(ctx context.Context, userID, resource, action, tenantID string) Decision {
+	if err := ctx.Err(); err != nil {
+		return Decision{Effect: EffectDeny, Reason: err.Error()}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	strict := m.isStrict(resource)
+	roleIDs := m.activeRoleIDs(userID, tenantID, now)
+
+	var d Decision
+	deny, allow := -1, -1
+
+	for _, policy := range m.sortedPolicies(tenantID) {
+		step := DecisionStep{Kind: StepPolicy, ID: policy.ID, Detail: "no matching rule"}
+		for i, rule := range policy.Rules {
+			if !ruleMatches(rule, userID, resource, action, roleIDs, strict) {
+				continue
+			}
+			if step.Effect != EffectDeny {
+				step.Effect = rule.Effect
+				step.Detail = fmt.Sprintf("rule %d (%s on %s)", i, rule.Effect, rule.Resource)
+			}
+		}
+		switch {
+		case step.Effect == EffectDeny && deny < 0:
+			deny = len(d.Steps)
+		case step.Effect == EffectAllow && allow < 0:
+			allow = len(d.Steps)
+		}
+		d.Steps = append(d.Steps, step)
+	}
+
+	granted, wildcardOnly := -1, false
+	for _, roleID := range roleIDs {
+		role, exists := m.roles[roleID]
+		if !exists {
+			continue
+		}
+		step := DecisionStep{Kind: StepRole, ID: role.ID, Detail: "no matching permission"}
+		for _, perm := range role.Permissions {
+			if !matchesResource(perm.Resource, resource) || !matchesAction(perm.Action, action) {
+				continue
+			}
+			if strict && perm.Resource != resource {
+				wildcardOnly = true
+				step.Detail = fmt.Sprintf("permission %s ignored: strict resource requires an explicit grant", perm.ID)
+				continue
+			}
+			step.Effect = EffectAllow
+			step.Detail = "permission " + perm.ID
+			break
+		}
+		if step.Effect == EffectAllow && granted < 0 {
+			granted = len(d.Steps)
+		}
+		d.Steps = append(d.Steps, step)
+	}
+
+	// Same precedence as checkPermission
+	switch {
+	case deny >= 0:
+		d.Effect, d.DecidingPolicy = EffectDeny, d.Steps[deny].ID
+		d.Reason = policyReason(EffectDeny, d.DecidingPolicy)
+		d.Steps[deny].Decisive = true
+	case allow >= 0:
+		d.Effect, d.DecidingPolicy = EffectAllow, d.Steps[allow].ID
+		d.Reason = policyReason(EffectAllow, d.DecidingPolicy)
+		d.Steps[allow].Decisive = true
+	case granted >= 0:
+		step := &d.Steps[granted]
+		d.Effect, d.DecidingRole = EffectAllow, step.ID
+		d.Reason = fmt.Sprintf("granted by role %s (%s)", step.ID, step.Detail)
+		step.Decisive = true
+	case wildcardOnly:
+		d.Effect, d.Reason = EffectDeny, "strict resource requires an explicit grant"
+	default:
+		d.Effect, d.Reason = EffectDeny, "no matching role or policy"
+	}
+	return d
+}
+
+// sortedPolicies returns the enabled policies of tenantID by descending
+// Priority, then ID. The caller must hold m.mu.
+func (m *DefaultManager) sortedPolicies(tenantID string) []*Policy {
+	var policies []*Policy
+	for _, policy := range m.policies {
+		if policy.Enabled && policy.TenantID == tenantID {
+			policies = append(policies, policy)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Priority != policies[j].Priority {
+			return policies[i].Priority > policies[j].Priority
+		}
+		return policies[i].ID < policies[j].ID
+	})
+	return policies
+}
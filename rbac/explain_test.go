@@ -0,0 +1,135 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"testing"
+)
+
+// findStep returns the step for kind and id, failing the test if absent
+func findStep(t *testing.T, d Decision, kind, id string) DecisionStep {
+	t.Helper()
+	for _, step := range d.Steps {
+		if step.Kind == kind && step.ID == id {
+			return step
+		}
+	}
+	t.Fatalf("no %s step %q in %+v", kind, id, d.Steps)
+	return DecisionStep{}
+}
+
+func TestExplainDenyNamesPolicy(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+
+	if err := mgr.AssignRole(ctx, "bob", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	for _, policy := range []*Policy{
+		{ID: "no-billing", TenantID: "acme", Enabled: true, Priority: 10, Rules: []PolicyRule{{
+			Resource: "billing", Actions: []string{"*"}, Effect: EffectDeny, Principals: []string{"bob"},
+		}}},
+		{ID: "unrelated", TenantID: "acme", Enabled: true, Rules: []PolicyRule{{
+			Resource: "logs", Actions: []string{"read"}, Effect: EffectAllow, Principals: []string{"*"},
+		}}},
+	} {
+		if err := mgr.CreatePolicy(ctx, policy); err != nil {
+			t.Fatalf("CreatePolicy failed: %v", err)
+		}
+	}
+
+	d := mgr.Explain(ctx, "bob", "billing", "read", "acme")
+	if d.Allowed() || d.DecidingPolicy != "no-billing" {
+		t.Fatalf("Expected deny by no-billing, got %+v", d)
+	}
+	if d.Reason != "denied by policy no-billing" {
+		t.Errorf("Reason = %q", d.Reason)
+	}
+	if step := findStep(t, d, StepPolicy, "no-billing"); !step.Decisive || step.Effect != EffectDeny {
+		t.Errorf("Expected no-billing to be the decisive deny, got %+v", step)
+	}
+	if step := findStep(t, d, StepPolicy, "unrelated"); step.Decisive || step.Effect != "" {
+		t.Errorf("Expected unrelated policy not to match, got %+v", step)
+	}
+	// The admin role would have allowed but was overridden
+	if step := findStep(t, d, StepRole, StandardRoles.Admin); step.Decisive || step.Effect != EffectAllow {
+		t.Errorf("Expected admin role to match without deciding, got %+v", step)
+	}
+	if d.Steps[0].ID != "no-billing" {
+		t.Errorf("Expected higher priority policy first, got %s", d.Steps[0].ID)
+	}
+
+	if len(audit.entries) != 0 {
+		t.Errorf("Explain should not audit, got %d entries", len(audit.entries))
+	}
+	if mgr.HasPermission(ctx, "bob", "billing", "read", "acme") {
+		t.Error("HasPermission should agree with Explain")
+	}
+}
+
+func TestExplainAllowNamesRole(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager()
+
+	if err := mgr.AssignRole(ctx, "alice", StandardRoles.User, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if err := mgr.AssignRole(ctx, "alice", StandardRoles.Viewer, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	d := mgr.Explain(ctx, "alice", "reports", "read", "acme")
+	if !d.Allowed() || d.DecidingRole != StandardRoles.Viewer {
+		t.Fatalf("Expected allow by viewer, got %+v", d)
+	}
+	if d.Reason != "granted by role viewer (permission read_all)" {
+		t.Errorf("Reason = %q", d.Reason)
+	}
+	if step := findStep(t, d, StepRole, StandardRoles.User); step.Decisive || step.Effect != "" {
+		t.Errorf("Expected user role not to match, got %+v", step)
+	}
+	if step := findStep(t, d, StepRole, StandardRoles.Viewer); !step.Decisive {
+		t.Errorf("Expected viewer role to be decisive, got %+v", step)
+	}
+
+	d = mgr.Explain(ctx, "alice", "reports", "delete", "acme")
+	if d.Allowed() || d.Reason != "no matching role or policy" {
+		t.Errorf("Expected default deny, got %+v", d)
+	}
+	for _, step := range d.Steps {
+		if step.Decisive {
+			t.Errorf("Default deny should have no decisive step, got %+v", step)
+		}
+	}
+}
+
+func TestExplainStrictResource(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManagerWithConfig(&Config{StrictResources: []string{"secrets"}})
+
+	if err := mgr.AssignRole(ctx, "carol", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	d := mgr.Explain(ctx, "carol", "secrets", "read", "acme")
+	if d.Allowed() || d.Reason != "strict resource requires an explicit grant" {
+		t.Errorf("Expected strict deny, got %+v", d)
+	}
+	if got := mgr.HasPermission(ctx, "carol", "secrets", "read", "acme"); got != d.Allowed() {
+		t.Errorf("HasPermission = %v, Explain = %v", got, d.Allowed())
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+// Group is a named set of users, such as a team, that roles can be
+// assigned to as a whole. Members hold every role assigned to the group
+// for as long as they belong to it.
+type Group struct {
+	ID          string    `json:"id" datastore:"id"`
+	Name        string    `json:"name" datastore:"name"`
+	Description string    `json:"description" datastore:"description,noindex"`
+	Members     []string  `json:"members" datastore:"members"` // User IDs
+	TenantID    string    `json:"tenant_id" datastore:"tenant_id"`
+	CreatedAt   time.Time `json:"created_at" datastore:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" datastore:"updated_at"`
+}
+
+// GroupRole represents the assignment of a role to a group
+type GroupRole struct {
+	GroupID   string     `json:"group_id" datastore:"group_id"`
+	RoleID    string     `json:"role_id" datastore:"role_id"`
+	TenantID  string     `json:"tenant_id" datastore:"tenant_id"`
+	GrantedBy string     `json:"granted_by" datastore:"granted_by"`
+	GrantedAt time.Time  `json:"granted_at" datastore:"granted_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" datastore:"expires_at"`
+}
+
+// CreateGroup creates a new group. Members listed on the group are added
+// with it.
+func (m *DefaultManager) CreateGroup(ctx context.Context, group *Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if group.ID == "" {
+		group.ID = fmt.Sprintf("group_%d", time.Now().UnixNano())
+	}
+
+	if _, exists := m.groups[group.ID]; exists {
+		return fmt.Errorf("group already exists: %s", group.ID)
+	}
+
+	stored := *group
+	stored.Members = nil
+	stored.CreatedAt = time.Now()
+	stored.UpdatedAt = stored.CreatedAt
+	m.groups[stored.ID] = &stored
+	for _, userID := range group.Members {
+		m.addGroupMember(&stored, userID)
+	}
+	group.CreatedAt, group.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+
+	common.Info("[RBAC] Created group: %s", group.ID)
+	return nil
+}
+
+// GetGroup retrieves a copy of a group from any tenant. Use
+// GetGroupForTenant when the ID comes from a tenant's request.
+func (m *DefaultManager) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return nil, fmt.Errorf("group not found: %s", groupID)
+	}
+
+	return copyGroup(group), nil
+}
+
+// DeleteGroup deletes a group and its role assignments. Its members lose
+// the roles they held through it.
+func (m *DefaultManager) DeleteGroup(ctx context.Context, groupID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return fmt.Errorf("group not found: %s", groupID)
+	}
+
+	for _, userID := range append([]string(nil), group.Members...) {
+		m.removeGroupMember(group, userID)
+	}
+	delete(m.groupRoles, groupID)
+	delete(m.groups, groupID)
+
+	common.Info("[RBAC] Deleted group: %s", groupID)
+	return nil
+}
+
+// ListGroups lists copies of all groups for a tenant
+func (m *DefaultManager) ListGroups(ctx context.Context, tenantID string) ([]*Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var groups []*Group
+	for _, group := range m.groups {
+		if group.TenantID == tenantID || group.TenantID == "" {
+			groups = append(groups, copyGroup(group))
+		}
+	}
+
+	return groups, nil
+}
+
+// AddGroupMember adds a user to a group
+func (m *DefaultManager) AddGroupMember(ctx context.Context, groupID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return fmt.Errorf("group not found: %s", groupID)
+	}
+	if !m.addGroupMember(group, userID) {
+		return fmt.Errorf("user already in group")
+	}
+
+	common.Info("[RBAC] Added user %s to group %s", userID, groupID)
+	return nil
+}
+
+// RemoveGroupMember removes a user from a group. The user keeps roles
+// assigned directly or through other groups.
+func (m *DefaultManager) RemoveGroupMember(ctx context.Context, groupID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return fmt.Errorf("group not found: %s", groupID)
+	}
+	if !m.removeGroupMember(group, userID) {
+		return fmt.Errorf("user not in group")
+	}
+
+	common.Info("[RBAC] Removed user %s from group %s", userID, groupID)
+	return nil
+}
+
+// GetUserGroups gets copies of the groups a user belongs to, in the order
+// the user joined them
+func (m *DefaultManager) GetUserGroups(ctx context.Context, userID string) ([]*Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var groups []*Group
+	for _, groupID := range m.userGroups[userID] {
+		groups = append(groups, copyGroup(m.groups[groupID]))
+	}
+
+	return groups, nil
+}
+
+// AssignRoleToGroup assigns a role to every current and future member of
+// a group
+func (m *DefaultManager) AssignRoleToGroup(ctx context.Context, groupID, roleID, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkGroupRole(groupID, roleID); err != nil {
+		return err
+	}
+
+	for _, gr := range m.groupRoles[groupID] {
+		if gr.RoleID == roleID && gr.TenantID == tenantID {
+			return fmt.Errorf("role already assigned")
+		}
+	}
+
+	groupRole := &GroupRole{
+		GroupID:   groupID,
+		RoleID:    roleID,
+		TenantID:  tenantID,
+		GrantedAt: time.Now(),
+	}
+
+	m.groupRoles[groupID] = append(m.groupRoles[groupID], groupRole)
+	m.invalidateGroup(groupID)
+
+	common.Info("[RBAC] Assigned role %s to group %s", roleID, groupID)
+	return nil
+}
+
+// GrantTemporaryRoleToGroup assigns a role to a group that expires after
+// duration, with the same rules as GrantTemporaryRole
+func (m *DefaultManager) GrantTemporaryRoleToGroup(ctx context.Context, groupID, roleID, tenantID string, duration time.Duration, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("reason is required for temporary grants")
+	}
+
+	expiresAt, err := m.grantTemporaryGroupRole(groupID, roleID, tenantID, duration)
+	if err != nil {
+		return err
+	}
+
+	// Audit after releasing the lock, as in GrantTemporaryRole
+	m.audit.LogDecision(ctx, "group:"+groupID, "role:"+roleID, "grant", tenantID, true,
+		fmt.Sprintf("temporary grant until %s: %s", expiresAt.UTC().Format(time.RFC3339), reason))
+	common.Info("[RBAC] Granted role %s to group %s until %s", roleID, groupID, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// grantTemporaryGroupRole records the temporary group assignment under the
+// lock and returns its expiry
+func (m *DefaultManager) grantTemporaryGroupRole(groupID, roleID, tenantID string, duration time.Duration) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkGroupRole(groupID, roleID); err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(duration)
+
+	var groupRole *GroupRole
+	for _, gr := range m.groupRoles[groupID] {
+		if gr.RoleID == roleID && gr.TenantID == tenantID {
+			if gr.ExpiresAt == nil {
+				return time.Time{}, fmt.Errorf("role already assigned")
+			}
+			groupRole = gr
+		}
+	}
+	if groupRole == nil {
+		groupRole = &GroupRole{
+			GroupID:  groupID,
+			RoleID:   roleID,
+			TenantID: tenantID,
+		}
+		m.groupRoles[groupID] = append(m.groupRoles[groupID], groupRole)
+	}
+	groupRole.GrantedAt = now
+	groupRole.ExpiresAt = &expiresAt
+	m.invalidateGroup(groupID)
+	return expiresAt, nil
+}
+
+// RevokeRoleFromGroup revokes a role from a group
+func (m *DefaultManager) RevokeRoleFromGroup(ctx context.Context, groupID, roleID, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var filtered []*GroupRole
+	found := false
+
+	for _, gr := range m.groupRoles[groupID] {
+		if gr.RoleID == roleID && gr.TenantID == tenantID {
+			found = true
+		} else {
+			filtered = append(filtered, gr)
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("role assignment not found")
+	}
+
+	m.groupRoles[groupID] = filtered
+	m.invalidateGroup(groupID)
+
+	common.Info("[RBAC] Revoked role %s from group %s", roleID, groupID)
+	return nil
+}
+
+// checkGroupRole verifies that both the group and the role exist. The
+// caller must hold m.mu.
+func (m *DefaultManager) checkGroupRole(groupID, roleID string) error {
+	if _, exists := m.groups[groupID]; !exists {
+		return fmt.Errorf("group not found: %s", groupID)
+	}
+	if _, exists := m.roles[roleID]; !exists {
+		return fmt.Errorf("role not found: %s", roleID)
+	}
+	return nil
+}
+
+// addGroupMember adds userID to group and reports whether it was added.
+// The caller must hold m.mu.
+func (m *DefaultManager) addGroupMember(group *Group, userID string) bool {
+	for _, member := range group.Members {
+		if member == userID {
+			return false
+		}
+	}
+	group.Members = append(group.Members, userID)
+	group.UpdatedAt = time.Now()
+	m.userGroups[userID] = append(m.userGroups[userID], group.ID)
+	m.invalidateUser(userID)
+	return true
+}
+
+// removeGroupMember removes userID from group and reports whether it was a
+// member. The caller must hold m.mu.
+func (m *DefaultManager) removeGroupMember(group *Group, userID string) bool {
+	members := removeString(group.Members, userID)
+	if len(members) == len(group.Members) {
+		return false
+	}
+	group.Members = members
+	group.UpdatedAt = time.Now()
+
+	if groupIDs := removeString(m.userGroups[userID], group.ID); len(groupIDs) == 0 {
+		delete(m.userGroups, userID)
+	} else {
+		m.userGroups[userID] = groupIDs
+	}
+	m.invalidateUser(userID)
+	return true
+}
+
+// removeExpiredGroupRoles deletes group role assignments whose expiry has
+// passed and returns how many were removed. The caller must hold m.mu.
+func (m *DefaultManager) removeExpiredGroupRoles(now time.Time) int {
+	removed := 0
+	for groupID, groupRoles := range m.groupRoles {
+		var kept []*GroupRole
+		for _, gr := range groupRoles {
+			if gr.ExpiresAt != nil && now.After(*gr.ExpiresAt) {
+				removed++
+				m.invalidateGroup(groupID)
+				continue
+			}
+			kept = append(kept, gr)
+		}
+		if len(kept) == 0 {
+			delete(m.groupRoles, groupID)
+		} else {
+			m.groupRoles[groupID] = kept
+		}
+	}
+	return removed
+}
+
+// invalidateGroup drops cached decisions for every member of groupID;
+// callers hold m.mu
+func (m *DefaultManager) invalidateGroup(groupID string) {
+	if group, exists := m.groups[groupID]; exists {
+		for _, userID := range group.Members {
+			m.invalidateUser(userID)
+		}
+	}
+}
+
+// copyGroup returns a copy of group that callers may keep after m.mu is
+// released
+func copyGroup(group *Group) *Group {
+	c := *group
+	c.Members = append([]string(nil), group.Members...)
+	return &c
+}
+
+// removeString returns a copy of values without s
+func removeString(values []string, s string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != s {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
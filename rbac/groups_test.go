@@ -0,0 +1,202 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newGroupManager returns a manager with an "editor" role assigned to the
+// "writers" group in tenant acme
+func newGroupManager(t *testing.T, config *Config) Manager {
+	t.Helper()
+	ctx := context.Background()
+	mgr := NewManagerWithConfig(config)
+	if err := mgr.CreateRole(ctx, &Role{ID: "editor", Permissions: []Permission{
+		{ID: "edit_docs", Resource: "documents", Action: "write"},
+	}}); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if err := mgr.CreateGroup(ctx, &Group{ID: "writers", TenantID: "acme"}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "writers", "editor", "acme"); err != nil {
+		t.Fatalf("AssignRoleToGroup failed: %v", err)
+	}
+	return mgr
+}
+
+func TestGroupMemberInheritsRole(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		ctx := context.Background()
+		mgr := newGroupManager(t, &Config{CachePermissions: cache})
+
+		if mgr.HasPermission(ctx, "alice", "documents", "write", "acme") {
+			t.Fatalf("cache=%v: expected no permission before joining", cache)
+		}
+
+		if err := mgr.AddGroupMember(ctx, "writers", "alice"); err != nil {
+			t.Fatalf("AddGroupMember failed: %v", err)
+		}
+		if !mgr.HasRole(ctx, "alice", "editor", "acme") {
+			t.Errorf("cache=%v: expected member to hold the group role", cache)
+		}
+		if !mgr.HasPermission(ctx, "alice", "documents", "write", "acme") {
+			t.Errorf("cache=%v: expected member to inherit the group permission", cache)
+		}
+		if mgr.HasPermission(ctx, "alice", "documents", "write", "other") {
+			t.Errorf("cache=%v: group role leaked into another tenant", cache)
+		}
+
+		if err := mgr.RemoveGroupMember(ctx, "writers", "alice"); err != nil {
+			t.Fatalf("RemoveGroupMember failed: %v", err)
+		}
+		if mgr.HasRole(ctx, "alice", "editor", "acme") {
+			t.Errorf("cache=%v: expected role to be lost with membership", cache)
+		}
+		if mgr.HasPermission(ctx, "alice", "documents", "write", "acme") {
+			t.Errorf("cache=%v: expected permission to be lost with membership", cache)
+		}
+	}
+}
+
+func TestGroupMultipleGroups(t *testing.T) {
+	ctx := context.Background()
+	mgr := newGroupManager(t, nil)
+
+	if err := mgr.CreateGroup(ctx, &Group{ID: "reviewers", TenantID: "acme", Members: []string{"alice"}}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	for _, roleID := range []string{"editor", StandardRoles.Viewer} {
+		if err := mgr.AssignRoleToGroup(ctx, "reviewers", roleID, "acme"); err != nil {
+			t.Fatalf("AssignRoleToGroup(%s) failed: %v", roleID, err)
+		}
+	}
+	if err := mgr.AddGroupMember(ctx, "writers", "alice"); err != nil {
+		t.Fatalf("AddGroupMember failed: %v", err)
+	}
+	if err := mgr.AssignRole(ctx, "alice", "editor", "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	roles, _ := mgr.GetUserRoles(ctx, "alice", "acme")
+	var ids []string
+	for _, role := range roles {
+		ids = append(ids, role.ID)
+	}
+	if len(ids) != 2 || ids[0] != "editor" || ids[1] != StandardRoles.Viewer {
+		t.Errorf("GetUserRoles = %v, want [editor viewer]", ids)
+	}
+
+	groups, _ := mgr.GetUserGroups(ctx, "alice")
+	if len(groups) != 2 || groups[0].ID != "reviewers" || groups[1].ID != "writers" {
+		t.Errorf("GetUserGroups returned %d groups, want reviewers then writers", len(groups))
+	}
+
+	// Leaving one group keeps the roles held another way
+	if err := mgr.RemoveGroupMember(ctx, "reviewers", "alice"); err != nil {
+		t.Fatalf("RemoveGroupMember failed: %v", err)
+	}
+	if mgr.HasRole(ctx, "alice", StandardRoles.Viewer, "acme") {
+		t.Error("expected viewer to be lost with the reviewers group")
+	}
+	if err := mgr.RevokeRole(ctx, "alice", "editor", "acme"); err != nil {
+		t.Fatalf("RevokeRole failed: %v", err)
+	}
+	if !mgr.HasPermission(ctx, "alice", "documents", "write", "acme") {
+		t.Error("expected editor to remain through the writers group")
+	}
+
+	// Deleting the group removes what it granted
+	if err := mgr.DeleteGroup(ctx, "writers"); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	if mgr.HasRole(ctx, "alice", "editor", "acme") {
+		t.Error("expected deleted group to grant nothing")
+	}
+	if groups, _ := mgr.GetUserGroups(ctx, "alice"); len(groups) != 0 {
+		t.Errorf("expected no groups after delete, got %d", len(groups))
+	}
+}
+
+func TestGroupTemporaryRole(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := newGroupManager(t, &Config{AuditLogger: audit, CachePermissions: true})
+
+	if err := mgr.CreateGroup(ctx, &Group{ID: "oncall", Members: []string{"bob"}}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if err := mgr.GrantTemporaryRoleToGroup(ctx, "oncall", StandardRoles.Admin, "acme", 20*time.Millisecond, "incident"); err != nil {
+		t.Fatalf("GrantTemporaryRoleToGroup failed: %v", err)
+	}
+	if grant := audit.last(t); grant.userID != "group:oncall" || grant.action != "grant" {
+		t.Errorf("unexpected audit entry: %+v", grant)
+	}
+	if !mgr.HasPermission(ctx, "bob", "billing", "write", "acme") {
+		t.Fatal("expected temporary group admin to have permissions")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if mgr.HasRole(ctx, "bob", StandardRoles.Admin, "acme") {
+		t.Error("expected expired group role to be inactive")
+	}
+	if mgr.HasPermission(ctx, "bob", "billing", "write", "acme") {
+		t.Error("cached allow outlived the temporary group role")
+	}
+
+	if n := mgr.RemoveExpiredRoles(ctx); n != 1 {
+		t.Errorf("RemoveExpiredRoles removed %d, want 1", n)
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	ctx := context.Background()
+	mgr := newGroupManager(t, nil)
+	if err := mgr.AddGroupMember(ctx, "writers", "alice"); err != nil {
+		t.Fatalf("AddGroupMember failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"duplicate group", mgr.CreateGroup(ctx, &Group{ID: "writers"})},
+		{"unknown group", mgr.AddGroupMember(ctx, "missing", "alice")},
+		{"duplicate member", mgr.AddGroupMember(ctx, "writers", "alice")},
+		{"not a member", mgr.RemoveGroupMember(ctx, "writers", "bob")},
+		{"unknown role", mgr.AssignRoleToGroup(ctx, "writers", "missing", "acme")},
+		{"duplicate role", mgr.AssignRoleToGroup(ctx, "writers", "editor", "acme")},
+		{"permanent role", mgr.GrantTemporaryRoleToGroup(ctx, "writers", "editor", "acme", time.Hour, "ticket")},
+		{"no reason", mgr.GrantTemporaryRoleToGroup(ctx, "writers", "editor", "acme", time.Hour, " ")},
+		{"unassigned role", mgr.RevokeRoleFromGroup(ctx, "writers", StandardRoles.Admin, "acme")},
+		{"unknown delete", mgr.DeleteGroup(ctx, "missing")},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// Deleting a role drops its group assignments
+	if err := mgr.DeleteRole(ctx, "editor"); err != nil {
+		t.Fatalf("DeleteRole failed: %v", err)
+	}
+	if err := mgr.RevokeRoleFromGroup(ctx, "writers", "editor", "acme"); err == nil {
+		t.Error("expected the group assignment to be gone with the role")
+	}
+}
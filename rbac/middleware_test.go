@@ -0,0 +1,133 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func headerFn(name string) RequestFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+func newMiddlewareTestManager(t *testing.T) Manager {
+	t.Helper()
+	ctx := context.Background()
+	mgr := NewManager()
+	if err := mgr.AssignRole(ctx, "alice", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if err := mgr.AssignRole(ctx, "bob", StandardRoles.Viewer, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	return mgr
+}
+
+func TestRequirePermission(t *testing.T) {
+	mgr := newMiddlewareTestManager(t)
+
+	tests := []struct {
+		name       string
+		user       string
+		tenant     string
+		action     string
+		wantStatus int
+	}{
+		{"admin allowed to write", "alice", "acme", "write", http.StatusOK},
+		{"viewer allowed to read", "bob", "acme", "read", http.StatusOK},
+		{"viewer denied write", "bob", "acme", "write", http.StatusForbidden},
+		{"other tenant denied", "alice", "globex", "read", http.StatusForbidden},
+		{"missing user", "", "acme", "read", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+			handler := RequirePermission(mgr, "reports", tt.action, headerFn("X-User"), headerFn("X-Tenant"))(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			req.Header.Set("X-User", tt.user)
+			req.Header.Set("X-Tenant", tt.tenant)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("next called = %v, want %v", called, tt.wantStatus == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	mgr := newMiddlewareTestManager(t)
+
+	tests := []struct {
+		name       string
+		user       string
+		wantStatus int
+	}{
+		{"has role", "alice", http.StatusOK},
+		{"lacks role", "bob", http.StatusForbidden},
+		{"missing user", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := RequireRole(mgr, StandardRoles.Admin, headerFn("X-User"), func(*http.Request) string { return "acme" })(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("X-User", tt.user)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionNilTenantFn(t *testing.T) {
+	mgr := NewManager()
+	if err := mgr.AssignRole(context.Background(), "alice", StandardRoles.Viewer, ""); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	handler := RequirePermission(mgr, "reports", "read", headerFn("X-User"), nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+	req.Header.Set("X-User", "alice")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
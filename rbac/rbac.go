@@ -85,11 +85,13 @@ const (
 	EffectDeny  Effect = "deny"
 )
 
-// Manager handles RBAC operations
+// Manager handles RBAC operations. Methods stop once ctx is done: those
+// returning an error return ctx.Err(), and permission checks deny.
 type Manager interface {
 	// Role management
 	CreateRole(ctx context.Context, role *Role) error
 	GetRole(ctx context.Context, roleID string) (*Role, error)
+	GetRoleForTenant(ctx context.Context, roleID, tenantID string) (*Role, error)
 	UpdateRole(ctx context.Context, role *Role) error
 	DeleteRole(ctx context.Context, roleID string) error
 	ListRoles(ctx context.Context, tenantID string) ([]*Role, error)
@@ -97,37 +99,132 @@ type Manager interface {
 	// User-Role assignment
 	AssignRole(ctx context.Context, userID, roleID, tenantID string) error
 	RevokeRole(ctx context.Context, userID, roleID, tenantID string) error
+	GrantTemporaryRole(ctx context.Context, userID, roleID, tenantID string, duration time.Duration, reason string) error
+	RemoveExpiredRoles(ctx context.Context) int
 	GetUserRoles(ctx context.Context, userID, tenantID string) ([]*Role, error)
 	HasRole(ctx context.Context, userID, roleID, tenantID string) bool
 
+	// Groups: members hold the roles assigned to their groups
+	CreateGroup(ctx context.Context, group *Group) error
+	GetGroup(ctx context.Context, groupID string) (*Group, error)
+	GetGroupForTenant(ctx context.Context, groupID, tenantID string) (*Group, error)
+	DeleteGroup(ctx context.Context, groupID string) error
+	ListGroups(ctx context.Context, tenantID string) ([]*Group, error)
+	AddGroupMember(ctx context.Context, groupID, userID string) error
+	RemoveGroupMember(ctx context.Context, groupID, userID string) error
+	GetUserGroups(ctx context.Context, userID string) ([]*Group, error)
+	AssignRoleToGroup(ctx context.Context, groupID, roleID, tenantID string) error
+	GrantTemporaryRoleToGroup(ctx context.Context, groupID, roleID, tenantID string, duration time.Duration, reason string) error
+	RevokeRoleFromGroup(ctx context.Context, groupID, roleID, tenantID string) error
+
 	// Permission checking
 	HasPermission(ctx context.Context, userID, resource, action, tenantID string) bool
 	GetUserPermissions(ctx context.Context, userID, tenantID string) ([]Permission, error)
+	AllowedActions(ctx context.Context, userID, resource, tenantID string) []string
 
 	// Policy management
 	CreatePolicy(ctx context.Context, policy *Policy) error
 	GetPolicy(ctx context.Context, policyID string) (*Policy, error)
+	GetPolicyForTenant(ctx context.Context, policyID, tenantID string) (*Policy, error)
 	UpdatePolicy(ctx context.Context, policy *Policy) error
 	DeletePolicy(ctx context.Context, policyID string) error
 	EvaluatePolicy(ctx context.Context, userID, resource, action, tenantID string) Effect
+	Explain(ctx context.Context, userID, resource, action, tenantID string) Decision
+}
+
+// AuditLogger records access decisions for compliance. Implementations must
+// be safe for concurrent use and should not block, since they are called on
+// every permission check.
+type AuditLogger interface {
+	// LogDecision records that userID was allowed or denied action on
+	// resource. reason names the deciding role or policy.
+	LogDecision(ctx context.Context, userID, resource, action, tenantID string, allowed bool, reason string)
+}
+
+// noopAuditLogger discards all decisions
+type noopAuditLogger struct{}
+
+func (noopAuditLogger) LogDecision(ctx context.Context, userID, resource, action, tenantID string, allowed bool, reason string) {
+}
+
+// Config configures a DefaultManager
+type Config struct {
+	// AuditLogger receives every decision made by HasPermission and
+	// EvaluatePolicy. Defaults to a no-op logger.
+	AuditLogger AuditLogger
+
+	// CachePermissions caches HasPermission decisions per user. The cache
+	// is invalidated by every role, assignment and policy change made
+	// through the Manager, but not by mutating a *Role or *Policy in place;
+	// leave it off when callers do that or need strict consistency.
+	CachePermissions bool
+
+	// PermissionCacheSize bounds the number of cached decisions.
+	// Defaults to DefaultPermissionCacheSize.
+	PermissionCacheSize int
+
+	// StrictResources lists resources that are denied by default, as exact
+	// names or prefix patterns such as "secrets:*". Wildcard grants do not
+	// reach a strict resource: a role permission or allow rule must name
+	// the resource exactly. Deny rules apply as usual. For example, an
+	// admin holding "*" cannot read "billing" when it is strict unless a
+	// role or policy grants "billing" itself.
+	StrictResources []string
+
+	// Actions lists application actions, such as "publish" or "export",
+	// that AllowedActions expands wildcard grants into in addition to the
+	// StandardPermissions actions and those named by roles and policies.
+	Actions []string
 }
 
 // DefaultManager implements the Manager interface
 type DefaultManager struct {
 	roles       map[string]*Role
 	userRoles   map[string][]*UserRole // userID -> roles
+	groups      map[string]*Group
+	groupRoles  map[string][]*GroupRole // groupID -> roles
+	userGroups  map[string][]string     // userID -> groupIDs
 	policies    map[string]*Policy
 	permissions map[string]*Permission
+	audit       AuditLogger
+	strict      []string // see Config.StrictResources
+	actions     []string // see Config.Actions
 	mu          sync.RWMutex
+
+	// Optional permission cache, see Config.CachePermissions
+	permCache    *common.LRUCache[string, cachedDecision]
+	cacheGen     uint64
+	userCacheGen map[string]uint64
 }
 
 // NewManager creates a new RBAC manager
 func NewManager() Manager {
+	return NewManagerWithConfig(nil)
+}
+
+// NewManagerWithConfig creates a new RBAC manager with the given
+// configuration. A nil config uses the defaults.
+func NewManagerWithConfig(config *Config) Manager {
+	if config == nil {
+		config = &Config{}
+	}
+
 	m := &DefaultManager{
-		roles:       make(map[string]*Role),
-		userRoles:   make(map[string][]*UserRole),
-		policies:    make(map[string]*Policy),
-		permissions: make(map[string]*Permission),
+		roles:        make(map[string]*Role),
+		userRoles:    make(map[string][]*UserRole),
+		groups:       make(map[string]*Group),
+		groupRoles:   make(map[string][]*GroupRole),
+		userGroups:   make(map[string][]string),
+		policies:     make(map[string]*Policy),
+		permissions:  make(map[string]*Permission),
+		audit:        config.AuditLogger,
+		strict:       append([]string(nil), config.StrictResources...),
+		actions:      append([]string(nil), config.Actions...),
+		permCache:    newPermissionCache(config),
+		userCacheGen: make(map[string]uint64),
+	}
+	if m.audit == nil {
+		m.audit = noopAuditLogger{}
 	}
 
 	// Initialize with default roles
@@ -184,6 +281,9 @@ func (m *DefaultManager) initializeDefaultRoles() {
 
 // CreateRole creates a new role
 func (m *DefaultManager) CreateRole(ctx context.Context, role *Role) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -205,8 +305,12 @@ func (m *DefaultManager) CreateRole(ctx context.Context, role *Role) error {
 	return nil
 }
 
-// GetRole retrieves a role by ID
+// GetRole retrieves a role by ID from any tenant. Use GetRoleForTenant
+// when the ID comes from a tenant's request.
 func (m *DefaultManager) GetRole(ctx context.Context, roleID string) (*Role, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -220,6 +324,9 @@ func (m *DefaultManager) GetRole(ctx context.Context, roleID string) (*Role, err
 
 // UpdateRole updates an existing role
 func (m *DefaultManager) UpdateRole(ctx context.Context, role *Role) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -234,6 +341,7 @@ func (m *DefaultManager) UpdateRole(ctx context.Context, role *Role) error {
 
 	role.UpdatedAt = time.Now()
 	m.roles[role.ID] = role
+	m.invalidateAll()
 
 	common.Info("[RBAC] Updated role: %s", role.ID)
 	return nil
@@ -241,6 +349,9 @@ func (m *DefaultManager) UpdateRole(ctx context.Context, role *Role) error {
 
 // DeleteRole deletes a role
 func (m *DefaultManager) DeleteRole(ctx context.Context, roleID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -263,8 +374,18 @@ func (m *DefaultManager) DeleteRole(ctx context.Context, roleID string) error {
 		}
 		m.userRoles[userID] = filtered
 	}
+	for groupID, groupRoles := range m.groupRoles {
+		var filtered []*GroupRole
+		for _, gr := range groupRoles {
+			if gr.RoleID != roleID {
+				filtered = append(filtered, gr)
+			}
+		}
+		m.groupRoles[groupID] = filtered
+	}
 
 	delete(m.roles, roleID)
+	m.invalidateAll()
 
 	common.Info("[RBAC] Deleted role: %s", roleID)
 	return nil
@@ -272,6 +393,9 @@ func (m *DefaultManager) DeleteRole(ctx context.Context, roleID string) error {
 
 // ListRoles lists all roles for a tenant
 func (m *DefaultManager) ListRoles(ctx context.Context, tenantID string) ([]*Role, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -287,6 +411,9 @@ func (m *DefaultManager) ListRoles(ctx context.Context, tenantID string) ([]*Rol
 
 // AssignRole assigns a role to a user
 func (m *DefaultManager) AssignRole(ctx context.Context, userID, roleID, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -310,6 +437,7 @@ func (m *DefaultManager) AssignRole(ctx context.Context, userID, roleID, tenantI
 	}
 
 	m.userRoles[userID] = append(m.userRoles[userID], userRole)
+	m.invalidateUser(userID)
 
 	common.Info("[RBAC] Assigned role %s to user %s", roleID, userID)
 	return nil
@@ -317,6 +445,9 @@ func (m *DefaultManager) AssignRole(ctx context.Context, userID, roleID, tenantI
 
 // RevokeRole revokes a role from a user
 func (m *DefaultManager) RevokeRole(ctx context.Context, userID, roleID, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -336,45 +467,146 @@ func (m *DefaultManager) RevokeRole(ctx context.Context, userID, roleID, tenantI
 	}
 
 	m.userRoles[userID] = filtered
+	m.invalidateUser(userID)
 
 	common.Info("[RBAC] Revoked role %s from user %s", roleID, userID)
 	return nil
 }
 
-// GetUserRoles gets all roles assigned to a user
-func (m *DefaultManager) GetUserRoles(ctx context.Context, userID, tenantID string) ([]*Role, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// GrantTemporaryRole assigns a role that expires after duration, for
+// just-in-time access such as support staff needing admin rights for a
+// short window. The grant and its reason are recorded by the audit logger.
+// Granting a role the user already holds temporarily replaces the expiry;
+// a permanent assignment is left untouched and an error is returned.
+func (m *DefaultManager) GrantTemporaryRole(ctx context.Context, userID, roleID, tenantID string, duration time.Duration, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("reason is required for temporary grants")
+	}
 
-	var roles []*Role
+	expiresAt, err := m.grantTemporaryRole(userID, roleID, tenantID, duration)
+	if err != nil {
+		return err
+	}
 
+	// Audit after releasing the lock, the logger may call back into the manager
+	m.audit.LogDecision(ctx, userID, "role:"+roleID, "grant", tenantID, true,
+		fmt.Sprintf("temporary grant until %s: %s", expiresAt.UTC().Format(time.RFC3339), reason))
+	common.Info("[RBAC] Granted role %s to user %s until %s", roleID, userID, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// grantTemporaryRole records the temporary assignment under the lock and
+// returns its expiry
+func (m *DefaultManager) grantTemporaryRole(userID, roleID, tenantID string, duration time.Duration) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.roles[roleID]; !exists {
+		return time.Time{}, fmt.Errorf("role not found: %s", roleID)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(duration)
+
+	var userRole *UserRole
 	for _, ur := range m.userRoles[userID] {
-		if ur.TenantID == tenantID {
-			// Check if not expired
-			if ur.ExpiresAt != nil && time.Now().After(*ur.ExpiresAt) {
-				continue
+		if ur.RoleID == roleID && ur.TenantID == tenantID {
+			if ur.ExpiresAt == nil {
+				return time.Time{}, fmt.Errorf("role already assigned")
 			}
+			userRole = ur
+		}
+	}
+	if userRole == nil {
+		userRole = &UserRole{
+			UserID:   userID,
+			RoleID:   roleID,
+			TenantID: tenantID,
+		}
+		m.userRoles[userID] = append(m.userRoles[userID], userRole)
+	}
+	userRole.GrantedAt = now
+	userRole.ExpiresAt = &expiresAt
+	m.invalidateUser(userID)
+	return expiresAt, nil
+}
 
-			if role, exists := m.roles[ur.RoleID]; exists {
-				roles = append(roles, role)
+// RemoveExpiredRoles deletes user and group role assignments whose expiry
+// has passed and returns how many were removed. Expired assignments are already ignored by
+// permission checks; this keeps the assignment list from growing. Run it
+// periodically, e.g. from a goroutine registered with common.LifecycleManager.
+func (m *DefaultManager) RemoveExpiredRoles(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for userID, userRoles := range m.userRoles {
+		var kept []*UserRole
+		for _, ur := range userRoles {
+			if ur.ExpiresAt != nil && now.After(*ur.ExpiresAt) {
+				removed++
+				m.invalidateUser(userID)
+				continue
 			}
+			kept = append(kept, ur)
+		}
+		if len(kept) == 0 {
+			delete(m.userRoles, userID)
+		} else {
+			m.userRoles[userID] = kept
+		}
+	}
+	removed += m.removeExpiredGroupRoles(now)
+
+	if removed > 0 {
+		common.Info("[RBAC] Removed %d expired role assignments", removed)
+	}
+	return removed
+}
+
+// GetUserRoles gets all roles assigned to a user, directly or through the
+// user's groups. A role held several ways is listed once.
+func (m *DefaultManager) GetUserRoles(ctx context.Context, userID, tenantID string) ([]*Role, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var roles []*Role
+
+	for _, roleID := range m.activeRoleIDs(userID, tenantID, time.Now()) {
+		if role, exists := m.roles[roleID]; exists {
+			roles = append(roles, role)
 		}
 	}
 
 	return roles, nil
 }
 
-// HasRole checks if a user has a specific role
+// HasRole checks if a user has a specific role, directly or through one
+// of the user's groups. It returns false once ctx is done.
 func (m *DefaultManager) HasRole(ctx context.Context, userID, roleID, tenantID string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	for _, ur := range m.userRoles[userID] {
-		if ur.RoleID == roleID && ur.TenantID == tenantID {
-			// Check if not expired
-			if ur.ExpiresAt != nil && time.Now().After(*ur.ExpiresAt) {
-				return false
-			}
+	for _, grant := range m.activeGrants(userID, tenantID, time.Now()) {
+		if grant.roleID == roleID {
 			return true
 		}
 	}
@@ -382,33 +614,94 @@ func (m *DefaultManager) HasRole(ctx context.Context, userID, roleID, tenantID s
 	return false
 }
 
-// HasPermission checks if a user has a specific permission
+// HasPermission checks if a user has a specific permission. Decisions are
+// made in this order:
+//
+//  1. A matching deny rule in any enabled policy denies.
+//  2. A matching allow rule allows.
+//  3. A permission of one of the user's active roles allows.
+//  4. Otherwise the request is denied.
+//
+// For resources in Config.StrictResources, steps 2 and 3 only consider
+// rules and permissions naming the resource exactly, so wildcards such as
+// the admin role's "*" do not apply. With Config.CachePermissions the
+// decision is served from the cache when the user's roles and the policies
+// are unchanged; it is still audited. Use Explain to see how a decision was
+// reached. Once ctx is done the request is denied, with the context error
+// as the audited reason, and nothing is cached.
 func (m *DefaultManager) HasPermission(ctx context.Context, userID, resource, action, tenantID string) bool {
-	// First check policies
-	effect := m.EvaluatePolicy(ctx, userID, resource, action, tenantID)
-	if effect == EffectDeny {
+	if err := ctx.Err(); err != nil {
+		m.audit.LogDecision(ctx, userID, resource, action, tenantID, false, err.Error())
 		return false
 	}
-	if effect == EffectAllow {
-		return true
+
+	if m.permCache == nil {
+		allowed, reason := m.checkPermission(ctx, userID, resource, action, tenantID)
+		m.audit.LogDecision(ctx, userID, resource, action, tenantID, allowed, reason)
+		return allowed
+	}
+
+	key := permissionCacheKey(userID, resource, action, tenantID)
+	generation, validUntil := m.cacheState(userID, tenantID)
+	decision, ok := m.cachedPermission(key, generation)
+	if !ok {
+		decision.allowed, decision.reason = m.checkPermission(ctx, userID, resource, action, tenantID)
+		decision.generation = generation
+		decision.validUntil = validUntil
+		// A decision cut short by cancellation says nothing about the user
+		if ctx.Err() == nil {
+			m.permCache.Set(key, decision)
+		}
+	}
+
+	m.audit.LogDecision(ctx, userID, resource, action, tenantID, decision.allowed, decision.reason)
+	return decision.allowed
+}
+
+// checkPermission resolves a permission from policies and roles and
+// returns the decision with the reason reported to the audit logger. It
+// denies with the context error as the reason once ctx is done.
+func (m *DefaultManager) checkPermission(ctx context.Context, userID, resource, action, tenantID string) (bool, string) {
+	// First check policies
+	effect, policyID, err := m.evaluatePolicy(ctx, userID, resource, action, tenantID)
+	if err != nil {
+		return false, err.Error()
+	}
+	if effect != "" {
+		return effect == EffectAllow, policyReason(effect, policyID)
 	}
 
 	// Then check role-based permissions
-	roles, _ := m.GetUserRoles(ctx, userID, tenantID)
+	roles, err := m.GetUserRoles(ctx, userID, tenantID)
+	if err != nil {
+		return false, err.Error()
+	}
+	strict := m.isStrict(resource)
+	wildcardOnly := false
 
 	for _, role := range roles {
 		for _, perm := range role.Permissions {
 			if matchesResource(perm.Resource, resource) && matchesAction(perm.Action, action) {
-				return true
+				if strict && perm.Resource != resource {
+					wildcardOnly = true
+					continue
+				}
+				return true, fmt.Sprintf("granted by role %s (permission %s)", role.ID, perm.ID)
 			}
 		}
 	}
 
-	return false
+	if wildcardOnly {
+		return false, "strict resource requires an explicit grant"
+	}
+	return false, "no matching role or policy"
 }
 
 // GetUserPermissions gets all permissions for a user
 func (m *DefaultManager) GetUserPermissions(ctx context.Context, userID, tenantID string) ([]Permission, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	roles, err := m.GetUserRoles(ctx, userID, tenantID)
 	if err != nil {
 		return nil, err
@@ -417,6 +710,9 @@ func (m *DefaultManager) GetUserPermissions(ctx context.Context, userID, tenantI
 	permMap := make(map[string]Permission)
 
 	for _, role := range roles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		for _, perm := range role.Permissions {
 			permMap[perm.ID] = perm
 		}
@@ -432,6 +728,9 @@ func (m *DefaultManager) GetUserPermissions(ctx context.Context, userID, tenantI
 
 // CreatePolicy creates a new policy
 func (m *DefaultManager) CreatePolicy(ctx context.Context, policy *Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -444,13 +743,18 @@ func (m *DefaultManager) CreatePolicy(ctx context.Context, policy *Policy) error
 	}
 
 	m.policies[policy.ID] = policy
+	m.invalidateAll()
 
 	common.Info("[RBAC] Created policy: %s (%s)", policy.ID, policy.Name)
 	return nil
 }
 
-// GetPolicy retrieves a policy by ID
+// GetPolicy retrieves a policy by ID from any tenant. Use
+// GetPolicyForTenant when the ID comes from a tenant's request.
 func (m *DefaultManager) GetPolicy(ctx context.Context, policyID string) (*Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -464,6 +768,9 @@ func (m *DefaultManager) GetPolicy(ctx context.Context, policyID string) (*Polic
 
 // UpdatePolicy updates an existing policy
 func (m *DefaultManager) UpdatePolicy(ctx context.Context, policy *Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -472,6 +779,7 @@ func (m *DefaultManager) UpdatePolicy(ctx context.Context, policy *Policy) error
 	}
 
 	m.policies[policy.ID] = policy
+	m.invalidateAll()
 
 	common.Info("[RBAC] Updated policy: %s", policy.ID)
 	return nil
@@ -479,6 +787,9 @@ func (m *DefaultManager) UpdatePolicy(ctx context.Context, policy *Policy) error
 
 // DeletePolicy deletes a policy
 func (m *DefaultManager) DeletePolicy(ctx context.Context, policyID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -487,80 +798,168 @@ func (m *DefaultManager) DeletePolicy(ctx context.Context, policyID string) erro
 	}
 
 	delete(m.policies, policyID)
+	m.invalidateAll()
 
 	common.Info("[RBAC] Deleted policy: %s", policyID)
 	return nil
 }
 
-// EvaluatePolicy evaluates policies for a user action
+// EvaluatePolicy evaluates policies for a user action. Decisions (allow or
+// deny) are reported to the audit logger; an empty effect means no policy
+// applied and is not logged. Once ctx is done it fails closed, returning
+// EffectDeny with the context error as the audited reason.
 func (m *DefaultManager) EvaluatePolicy(ctx context.Context, userID, resource, action, tenantID string) Effect {
+	effect, policyID, err := m.evaluatePolicy(ctx, userID, resource, action, tenantID)
+	if err != nil {
+		m.audit.LogDecision(ctx, userID, resource, action, tenantID, false, err.Error())
+		return EffectDeny
+	}
+	if effect != "" {
+		m.audit.LogDecision(ctx, userID, resource, action, tenantID, effect == EffectAllow,
+			policyReason(effect, policyID))
+	}
+	return effect
+}
+
+// policyReason is the audited reason for a decision made by a policy
+func policyReason(effect Effect, policyID string) string {
+	if effect == EffectAllow {
+		return "allowed by policy " + policyID
+	}
+	return "denied by policy " + policyID
+}
+
+// evaluatePolicy returns the policy effect for a user action together with
+// the ID of the deciding policy. It stops with the context error once ctx
+// is done.
+func (m *DefaultManager) evaluatePolicy(ctx context.Context, userID, resource, action, tenantID string) (Effect, string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Get user's roles
-	var userRoleIDs []string
-	for _, ur := range m.userRoles[userID] {
-		if ur.TenantID == tenantID {
-			userRoleIDs = append(userRoleIDs, ur.RoleID)
-		}
-	}
+	userRoleIDs := m.activeRoleIDs(userID, tenantID, time.Now())
 
 	// Evaluate policies in priority order
 	var effect Effect
+	var decidingPolicy string
+	strict := m.isStrict(resource)
 
 	for _, policy := range m.policies {
 		if !policy.Enabled || policy.TenantID != tenantID {
 			continue
 		}
+		if err := ctx.Err(); err != nil {
+			return "", "", err
+		}
 
 		for _, rule := range policy.Rules {
-			// Check if rule applies to this resource and action
-			if !matchesResource(rule.Resource, resource) {
-				continue
-			}
-
-			actionMatches := false
-			for _, a := range rule.Actions {
-				if matchesAction(a, action) {
-					actionMatches = true
-					break
-				}
-			}
-			if !actionMatches {
-				continue
-			}
-
-			// Check if rule applies to this user
-			principalMatches := false
-			for _, principal := range rule.Principals {
-				if principal == userID || principal == "*" {
-					principalMatches = true
-					break
-				}
-				// Check if principal is a role
-				for _, roleID := range userRoleIDs {
-					if principal == "role:"+roleID {
-						principalMatches = true
-						break
-					}
-				}
-			}
-
-			if principalMatches {
+			if ruleMatches(rule, userID, resource, action, userRoleIDs, strict) {
 				effect = rule.Effect
+				decidingPolicy = policy.ID
 				// Deny takes precedence
 				if effect == EffectDeny {
-					return EffectDeny
+					return EffectDeny, policy.ID, nil
 				}
 			}
 		}
 	}
 
-	return effect
+	return effect, decidingPolicy, nil
 }
 
 // Helper functions
 
+// roleGrant is a role a user holds, directly or through a group
+type roleGrant struct {
+	roleID    string
+	expiresAt *time.Time
+}
+
+// activeGrants returns the user's unexpired direct assignments in tenantID
+// followed by those of the user's groups, in the order the user joined
+// them. A role held several ways appears once per grant. The caller must
+// hold m.mu.
+func (m *DefaultManager) activeGrants(userID, tenantID string, now time.Time) []roleGrant {
+	var grants []roleGrant
+	for _, ur := range m.userRoles[userID] {
+		if ur.TenantID == tenantID && (ur.ExpiresAt == nil || !now.After(*ur.ExpiresAt)) {
+			grants = append(grants, roleGrant{roleID: ur.RoleID, expiresAt: ur.ExpiresAt})
+		}
+	}
+	for _, groupID := range m.userGroups[userID] {
+		for _, gr := range m.groupRoles[groupID] {
+			if gr.TenantID == tenantID && (gr.ExpiresAt == nil || !now.After(*gr.ExpiresAt)) {
+				grants = append(grants, roleGrant{roleID: gr.RoleID, expiresAt: gr.ExpiresAt})
+			}
+		}
+	}
+	return grants
+}
+
+// activeRoleIDs returns the IDs of the user's unexpired roles in tenantID,
+// including those inherited from groups, without duplicates. The caller
+// must hold m.mu.
+func (m *DefaultManager) activeRoleIDs(userID, tenantID string, now time.Time) []string {
+	var roleIDs []string
+	seen := make(map[string]bool)
+	for _, grant := range m.activeGrants(userID, tenantID, now) {
+		if !seen[grant.roleID] {
+			seen[grant.roleID] = true
+			roleIDs = append(roleIDs, grant.roleID)
+		}
+	}
+	return roleIDs
+}
+
+// ruleMatches reports whether a policy rule applies to the user, resource
+// and action. Only explicit allow rules apply to strict resources.
+func ruleMatches(rule PolicyRule, userID, resource, action string, userRoleIDs []string, strict bool) bool {
+	if !matchesResource(rule.Resource, resource) {
+		return false
+	}
+	if strict && rule.Effect == EffectAllow && rule.Resource != resource {
+		return false
+	}
+
+	actionMatches := false
+	for _, a := range rule.Actions {
+		if matchesAction(a, action) {
+			actionMatches = true
+			break
+		}
+	}
+	if !actionMatches {
+		return false
+	}
+
+	// Check if rule applies to this user
+	for _, principal := range rule.Principals {
+		if principal == userID || principal == "*" {
+			return true
+		}
+		// Check if principal is a role
+		for _, roleID := range userRoleIDs {
+			if principal == "role:"+roleID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStrict reports whether resource matches Config.StrictResources
+func (m *DefaultManager) isStrict(resource string) bool {
+	for _, pattern := range m.strict {
+		if matchesResource(pattern, resource) {
+			return true
+		}
+	}
+	return false
+}
+
 func matchesResource(pattern, resource string) bool {
 	if pattern == "*" {
 		return true
@@ -0,0 +1,469 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// auditEntry is one decision captured by recordingAuditLogger
+type auditEntry struct {
+	userID, resource, action, tenantID string
+	allowed                            bool
+	reason                             string
+}
+
+// recordingAuditLogger captures decisions for assertions
+type recordingAuditLogger struct {
+	mu      sync.Mutex
+	entries []auditEntry
+}
+
+func (l *recordingAuditLogger) LogDecision(ctx context.Context, userID, resource, action, tenantID string, allowed bool, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, auditEntry{userID, resource, action, tenantID, allowed, reason})
+}
+
+func (l *recordingAuditLogger) last(t *testing.T) auditEntry {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		t.Fatal("no audit entries recorded")
+	}
+	return l.entries[len(l.entries)-1]
+}
+
+func TestAuditLoggerAllowViaRole(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+
+	if err := mgr.AssignRole(ctx, "alice", StandardRoles.Viewer, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	if !mgr.HasPermission(ctx, "alice", "reports", "read", "acme") {
+		t.Fatal("expected viewer to read reports")
+	}
+
+	entry := audit.last(t)
+	if !entry.allowed || entry.userID != "alice" || entry.resource != "reports" || entry.action != "read" || entry.tenantID != "acme" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if !strings.Contains(entry.reason, "role viewer") {
+		t.Errorf("reason %q does not name the deciding role", entry.reason)
+	}
+
+	if len(audit.entries) != 1 {
+		t.Errorf("recorded %d entries for one check, want 1", len(audit.entries))
+	}
+}
+
+func TestAuditLoggerDenyViaPolicy(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+
+	if err := mgr.AssignRole(ctx, "bob", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	err := mgr.CreatePolicy(ctx, &Policy{
+		ID:       "no-billing",
+		Name:     "Block billing",
+		TenantID: "acme",
+		Enabled:  true,
+		Rules: []PolicyRule{{
+			Resource:   "billing",
+			Actions:    []string{"*"},
+			Effect:     EffectDeny,
+			Principals: []string{"bob"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy failed: %v", err)
+	}
+
+	if mgr.HasPermission(ctx, "bob", "billing", "write", "acme") {
+		t.Fatal("expected policy to deny admin on billing")
+	}
+	entry := audit.last(t)
+	if entry.allowed || entry.reason != "denied by policy no-billing" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	if effect := mgr.EvaluatePolicy(ctx, "bob", "billing", "read", "acme"); effect != EffectDeny {
+		t.Fatalf("EvaluatePolicy = %q, want deny", effect)
+	}
+	entry = audit.last(t)
+	if entry.allowed || entry.action != "read" || entry.reason != "denied by policy no-billing" {
+		t.Errorf("unexpected EvaluatePolicy entry: %+v", entry)
+	}
+}
+
+func TestAuditLoggerNoMatch(t *testing.T) {
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+
+	if mgr.HasPermission(context.Background(), "nobody", "reports", "read", "acme") {
+		t.Fatal("expected unknown user to be denied")
+	}
+	if entry := audit.last(t); entry.allowed {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestDefaultManagerWithoutAuditLogger(t *testing.T) {
+	// The default no-op logger must not panic
+	mgr := NewManager()
+	if mgr.HasPermission(context.Background(), "nobody", "reports", "read", "") {
+		t.Fatal("expected unknown user to be denied")
+	}
+}
+
+// expireRole moves an assignment's expiry into the past
+func expireRole(t *testing.T, mgr Manager, userID, roleID string) {
+	t.Helper()
+	dm := mgr.(*DefaultManager)
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	past := time.Now().Add(-time.Minute)
+	for _, ur := range dm.userRoles[userID] {
+		if ur.RoleID == roleID {
+			ur.ExpiresAt = &past
+			return
+		}
+	}
+	t.Fatalf("no assignment of %s to %s", roleID, userID)
+}
+
+func TestGrantTemporaryRole(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+
+	if err := mgr.GrantTemporaryRole(ctx, "support", StandardRoles.Admin, "acme", time.Hour, "ticket 1234"); err != nil {
+		t.Fatalf("GrantTemporaryRole failed: %v", err)
+	}
+
+	grant := audit.last(t)
+	if !grant.allowed || grant.resource != "role:admin" || grant.action != "grant" || !strings.Contains(grant.reason, "ticket 1234") {
+		t.Errorf("unexpected audit entry: %+v", grant)
+	}
+
+	// Active before expiry
+	if !mgr.HasRole(ctx, "support", StandardRoles.Admin, "acme") {
+		t.Error("expected temporary role to be active")
+	}
+	if !mgr.HasPermission(ctx, "support", "billing", "write", "acme") {
+		t.Error("expected temporary admin to have permissions")
+	}
+
+	// Gone after expiry
+	expireRole(t, mgr, "support", StandardRoles.Admin)
+	if mgr.HasRole(ctx, "support", StandardRoles.Admin, "acme") {
+		t.Error("expected expired role to be inactive")
+	}
+	if mgr.HasPermission(ctx, "support", "billing", "write", "acme") {
+		t.Error("expected expired role to grant nothing")
+	}
+
+	if n := mgr.RemoveExpiredRoles(ctx); n != 1 {
+		t.Errorf("RemoveExpiredRoles removed %d, want 1", n)
+	}
+	if n := mgr.RemoveExpiredRoles(ctx); n != 0 {
+		t.Errorf("second RemoveExpiredRoles removed %d, want 0", n)
+	}
+}
+
+func TestGrantTemporaryRoleExpiredPolicyPrincipal(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager()
+	err := mgr.CreatePolicy(ctx, &Policy{
+		ID:       "oncall-logs",
+		TenantID: "acme",
+		Enabled:  true,
+		Rules: []PolicyRule{{
+			Resource:   "logs",
+			Actions:    []string{"read"},
+			Effect:     EffectAllow,
+			Principals: []string{"role:viewer"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy failed: %v", err)
+	}
+
+	if err := mgr.GrantTemporaryRole(ctx, "oncall", StandardRoles.Viewer, "acme", time.Hour, "incident"); err != nil {
+		t.Fatalf("GrantTemporaryRole failed: %v", err)
+	}
+	if effect := mgr.EvaluatePolicy(ctx, "oncall", "logs", "read", "acme"); effect != EffectAllow {
+		t.Fatalf("EvaluatePolicy = %q before expiry, want allow", effect)
+	}
+
+	expireRole(t, mgr, "oncall", StandardRoles.Viewer)
+	if effect := mgr.EvaluatePolicy(ctx, "oncall", "logs", "read", "acme"); effect != "" {
+		t.Errorf("EvaluatePolicy = %q after expiry, want no match", effect)
+	}
+}
+
+func TestGrantTemporaryRoleValidation(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager()
+
+	tests := []struct {
+		name     string
+		roleID   string
+		duration time.Duration
+		reason   string
+	}{
+		{"zero duration", StandardRoles.Admin, 0, "ticket"},
+		{"missing reason", StandardRoles.Admin, time.Hour, " "},
+		{"unknown role", "superuser", time.Hour, "ticket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := mgr.GrantTemporaryRole(ctx, "support", tt.roleID, "acme", tt.duration, tt.reason); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	// A permanent assignment is not downgraded to a temporary one
+	if err := mgr.AssignRole(ctx, "owner", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if err := mgr.GrantTemporaryRole(ctx, "owner", StandardRoles.Admin, "acme", time.Hour, "ticket"); err == nil {
+		t.Error("expected error for existing permanent assignment")
+	}
+}
+
+func TestGrantTemporaryRoleAuditsWithoutLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		grant func(Manager) error
+	}{
+		{"user", func(m Manager) error {
+			return m.GrantTemporaryRole(context.Background(), "support", StandardRoles.Admin, "acme", time.Hour, "ticket")
+		}},
+		{"group", func(m Manager) error {
+			return m.GrantTemporaryRoleToGroup(context.Background(), "oncall", StandardRoles.Admin, "acme", time.Hour, "incident")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &lockingAuditLogger{}
+			mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+			audit.mgr = mgr
+			if err := mgr.CreateGroup(context.Background(), &Group{ID: "oncall"}); err != nil {
+				t.Fatalf("CreateGroup failed: %v", err)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- tt.grant(mgr) }()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("grant failed: %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("temporary grant deadlocked while auditing")
+			}
+		})
+	}
+}
+
+func TestStrictResources(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit, StrictResources: []string{"billing", "secrets:*"}})
+
+	if err := mgr.AssignRole(ctx, "root", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	// The admin wildcard still covers ordinary resources
+	if !mgr.HasPermission(ctx, "root", "reports", "write", "acme") {
+		t.Error("expected admin to write reports")
+	}
+
+	// ...but not strict ones
+	for _, resource := range []string{"billing", "secrets:db"} {
+		if mgr.HasPermission(ctx, "root", resource, "read", "acme") {
+			t.Errorf("expected admin to be denied on strict resource %s", resource)
+		}
+		if entry := audit.last(t); !strings.Contains(entry.reason, "explicit grant") {
+			t.Errorf("reason %q does not mention the explicit grant", entry.reason)
+		}
+	}
+
+	// A wildcard allow rule does not open a strict resource either
+	err := mgr.CreatePolicy(ctx, &Policy{
+		ID:       "allow-everything",
+		TenantID: "acme",
+		Enabled:  true,
+		Rules:    []PolicyRule{{Resource: "*", Actions: []string{"*"}, Effect: EffectAllow, Principals: []string{"*"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy failed: %v", err)
+	}
+	if mgr.HasPermission(ctx, "root", "billing", "read", "acme") {
+		t.Error("expected wildcard policy not to grant a strict resource")
+	}
+
+	// An explicit role permission grants it
+	err = mgr.CreateRole(ctx, &Role{
+		ID:          "billing-reader",
+		Permissions: []Permission{{ID: "billing_read", Resource: "billing", Action: "read"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if err := mgr.AssignRole(ctx, "root", "billing-reader", "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if !mgr.HasPermission(ctx, "root", "billing", "read", "acme") {
+		t.Error("expected explicit role permission to grant billing read")
+	}
+	if mgr.HasPermission(ctx, "root", "billing", "write", "acme") {
+		t.Error("expected explicit read grant not to cover write")
+	}
+
+	// An explicit allow rule grants a strict resource too
+	err = mgr.CreatePolicy(ctx, &Policy{
+		ID:       "db-secret",
+		TenantID: "acme",
+		Enabled:  true,
+		Rules:    []PolicyRule{{Resource: "secrets:db", Actions: []string{"read"}, Effect: EffectAllow, Principals: []string{"root"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy failed: %v", err)
+	}
+	if !mgr.HasPermission(ctx, "root", "secrets:db", "read", "acme") {
+		t.Error("expected explicit allow rule to grant secrets:db")
+	}
+	if mgr.HasPermission(ctx, "root", "secrets:api", "read", "acme") {
+		t.Error("expected other strict secrets to stay denied")
+	}
+}
+
+func TestStrictResourcesDenyRuleStillApplies(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManagerWithConfig(&Config{StrictResources: []string{"billing"}})
+	err := mgr.CreateRole(ctx, &Role{
+		ID:          "billing-admin",
+		Permissions: []Permission{{ID: "billing_all", Resource: "billing", Action: "*"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if err := mgr.AssignRole(ctx, "carol", "billing-admin", "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if !mgr.HasPermission(ctx, "carol", "billing", "delete", "acme") {
+		t.Fatal("expected explicit billing permission to allow")
+	}
+
+	// Wildcard deny rules keep precedence over explicit grants
+	err = mgr.CreatePolicy(ctx, &Policy{
+		ID:       "freeze",
+		TenantID: "acme",
+		Enabled:  true,
+		Rules:    []PolicyRule{{Resource: "*", Actions: []string{"delete"}, Effect: EffectDeny, Principals: []string{"*"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy failed: %v", err)
+	}
+	if mgr.HasPermission(ctx, "carol", "billing", "delete", "acme") {
+		t.Error("expected deny rule to win on a strict resource")
+	}
+}
+
+// TestCanceledContext verifies methods stop with the context error once ctx is done
+func TestCanceledContext(t *testing.T) {
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit, CachePermissions: true})
+	if err := mgr.AssignRole(context.Background(), "alice", StandardRoles.Admin, "acme"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errorCalls := []struct {
+		name string
+		call func() error
+	}{
+		{"CreateRole", func() error { return mgr.CreateRole(ctx, &Role{ID: "editor"}) }},
+		{"GetRole", func() error { _, err := mgr.GetRole(ctx, StandardRoles.Admin); return err }},
+		{"AssignRole", func() error { return mgr.AssignRole(ctx, "bob", StandardRoles.Viewer, "acme") }},
+		{"GetUserRoles", func() error { _, err := mgr.GetUserRoles(ctx, "alice", "acme"); return err }},
+		{"GetUserPermissions", func() error { _, err := mgr.GetUserPermissions(ctx, "alice", "acme"); return err }},
+		{"CreateGroup", func() error { return mgr.CreateGroup(ctx, &Group{ID: "ops"}) }},
+		{"GetRoleForTenant", func() error { _, err := mgr.GetRoleForTenant(ctx, StandardRoles.Admin, "acme"); return err }},
+		{"CreatePolicy", func() error { return mgr.CreatePolicy(ctx, &Policy{ID: "p1"}) }},
+	}
+	for _, tt := range errorCalls {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != context.Canceled {
+				t.Errorf("%s error = %v, want context.Canceled", tt.name, err)
+			}
+		})
+	}
+
+	if _, err := mgr.GetRole(context.Background(), "editor"); err == nil {
+		t.Error("Expected CreateRole with a canceled context to have no effect")
+	}
+
+	if mgr.HasPermission(ctx, "alice", "billing", "read", "acme") {
+		t.Error("Expected HasPermission to deny with a canceled context")
+	}
+	if entry := audit.last(t); entry.allowed || entry.reason != context.Canceled.Error() {
+		t.Errorf("Audit entry = %+v, want a deny citing the cancellation", entry)
+	}
+	if !mgr.HasPermission(context.Background(), "alice", "billing", "read", "acme") {
+		t.Error("Expected the canceled decision not to be cached")
+	}
+
+	if mgr.EvaluatePolicy(ctx, "alice", "billing", "read", "acme") != EffectDeny {
+		t.Error("Expected EvaluatePolicy to fail closed with a canceled context")
+	}
+	if mgr.HasRole(ctx, "alice", StandardRoles.Admin, "acme") {
+		t.Error("Expected HasRole to return false with a canceled context")
+	}
+	if actions := mgr.AllowedActions(ctx, "alice", "billing", "acme"); actions != nil {
+		t.Errorf("AllowedActions = %v, want nil", actions)
+	}
+	if d := mgr.Explain(ctx, "alice", "billing", "read", "acme"); d.Allowed() || d.Reason != context.Canceled.Error() {
+		t.Errorf("Explain = %+v, want a deny citing the cancellation", d)
+	}
+}
+
+// TestDeadlineExceeded verifies an expired deadline is reported as such
+func TestDeadlineExceeded(t *testing.T) {
+	mgr := NewManager()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	if _, err := mgr.ListRoles(ctx, "acme"); err != context.DeadlineExceeded {
+		t.Errorf("ListRoles error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTenantManager returns a manager with roles, policies and groups in
+// tenants acme and globex plus tenantless ones
+func newTenantManager(t *testing.T) (Manager, *recordingAuditLogger) {
+	t.Helper()
+	ctx := context.Background()
+	audit := &recordingAuditLogger{}
+	mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+
+	for _, role := range []*Role{
+		{ID: "acme-billing", Name: "Billing", TenantID: "acme"},
+		{ID: "globex-billing", Name: "Billing", TenantID: "globex"},
+		{ID: "shared", Name: "Shared"},
+	} {
+		if err := mgr.CreateRole(ctx, role); err != nil {
+			t.Fatalf("CreateRole failed: %v", err)
+		}
+	}
+	for _, policy := range []*Policy{
+		{ID: "acme-policy", TenantID: "acme", Enabled: true},
+		{ID: "globex-policy", TenantID: "globex", Enabled: true},
+	} {
+		if err := mgr.CreatePolicy(ctx, policy); err != nil {
+			t.Fatalf("CreatePolicy failed: %v", err)
+		}
+	}
+	for _, group := range []*Group{
+		{ID: "acme-team", TenantID: "acme"},
+		{ID: "globex-team", TenantID: "globex"},
+		{ID: "everyone"},
+	} {
+		if err := mgr.CreateGroup(ctx, group); err != nil {
+			t.Fatalf("CreateGroup failed: %v", err)
+		}
+	}
+	return mgr, audit
+}
+
+func TestGetForTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		get      func(mgr Manager) (string, error)
+		wantID   string
+		resource string // audited resource when denied
+	}{
+		{"own role", roleGetter("acme-billing"), "acme-billing", ""},
+		{"system role", roleGetter(StandardRoles.Admin), StandardRoles.Admin, ""},
+		{"tenantless role", roleGetter("shared"), "shared", ""},
+		{"other tenant's role", roleGetter("globex-billing"), "", "role:globex-billing"},
+		{"missing role", roleGetter("nope"), "", ""},
+		{"own policy", policyGetter("acme-policy"), "acme-policy", ""},
+		{"other tenant's policy", policyGetter("globex-policy"), "", "policy:globex-policy"},
+		{"own group", groupGetter("acme-team"), "acme-team", ""},
+		{"tenantless group", groupGetter("everyone"), "everyone", ""},
+		{"other tenant's group", groupGetter("globex-team"), "", "group:globex-team"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, audit := newTenantManager(t)
+			id, err := tt.get(mgr)
+
+			if tt.wantID != "" {
+				if err != nil || id != tt.wantID {
+					t.Fatalf("got %q, %v; want %q", id, err, tt.wantID)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("Expected no audit entries, got %+v", audit.entries)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected not found, got %q", id)
+			}
+			if tt.resource == "" {
+				if len(audit.entries) != 0 {
+					t.Errorf("Expected missing IDs not to be audited, got %+v", audit.entries)
+				}
+				return
+			}
+			entry := audit.last(t)
+			if entry.resource != tt.resource || entry.action != "read" || entry.tenantID != "acme" || entry.allowed {
+				t.Errorf("audit entry = %+v, want denied read of %s from acme", entry, tt.resource)
+			}
+		})
+	}
+}
+
+// TestGetForTenantIndistinguishable verifies cross-tenant reads look like missing IDs
+func TestGetForTenantIndistinguishable(t *testing.T) {
+	ctx := context.Background()
+	mgr, _ := newTenantManager(t)
+
+	_, crossErr := mgr.GetRoleForTenant(ctx, "globex-billing", "acme")
+	_, missingErr := mgr.GetRoleForTenant(ctx, "globex-missing", "acme")
+	if crossErr == nil || missingErr == nil {
+		t.Fatal("Expected both lookups to fail")
+	}
+	if want := "role not found: globex-billing"; crossErr.Error() != want {
+		t.Errorf("error = %q, want %q", crossErr, want)
+	}
+
+	// The unscoped getter is unchanged
+	if _, err := mgr.GetRole(ctx, "globex-billing"); err != nil {
+		t.Errorf("GetRole() error = %v", err)
+	}
+}
+
+func roleGetter(id string) func(mgr Manager) (string, error) {
+	return func(mgr Manager) (string, error) {
+		role, err := mgr.GetRoleForTenant(context.Background(), id, "acme")
+		if err != nil {
+			return "", err
+		}
+		return role.ID, nil
+	}
+}
+
+func policyGetter(id string) func(mgr Manager) (string, error) {
+	return func(mgr Manager) (string, error) {
+		policy, err := mgr.GetPolicyForTenant(context.Background(), id, "acme")
+		if err != nil {
+			return "", err
+		}
+		return policy.ID, nil
+	}
+}
+
+func groupGetter(id string) func(mgr Manager) (string, error) {
+	return func(mgr Manager) (string, error) {
+		group, err := mgr.GetGroupForTenant(context.Background(), id, "acme")
+		if err != nil {
+			return "", err
+		}
+		return group.ID, nil
+	}
+}
+
+// lockingAuditLogger writes to the manager while logging, which deadlocks
+// if the caller still holds the manager's lock
+type lockingAuditLogger struct {
+	mgr Manager
+}
+
+func (l *lockingAuditLogger) LogDecision(ctx context.Context, userID, resource, action, tenantID string, allowed bool, reason string) {
+	_ = l.mgr.CreateGroup(ctx, &Group{ID: "audit-" + resource})
+}
+
+func TestGetForTenantAuditsWithoutLock(t *testing.T) {
+	tests := []struct {
+		name string
+		get  func(Manager) error
+	}{
+		{"role", func(m Manager) error {
+			_, err := m.GetRoleForTenant(context.Background(), "globex-billing", "acme")
+			return err
+		}},
+		{"policy", func(m Manager) error {
+			_, err := m.GetPolicyForTenant(context.Background(), "globex-policy", "acme")
+			return err
+		}},
+		{"group", func(m Manager) error {
+			_, err := m.GetGroupForTenant(context.Background(), "globex-team", "acme")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &lockingAuditLogger{}
+			mgr := NewManagerWithConfig(&Config{AuditLogger: audit})
+			audit.mgr = mgr
+			ctx := context.Background()
+			if err := mgr.CreateRole(ctx, &Role{ID: "globex-billing", Name: "Billing", TenantID: "globex"}); err != nil {
+				t.Fatalf("CreateRole failed: %v", err)
+			}
+			if err := mgr.CreatePolicy(ctx, &Policy{ID: "globex-policy", TenantID: "globex", Enabled: true}); err != nil {
+				t.Fatalf("CreatePolicy failed: %v", err)
+			}
+			if err := mgr.CreateGroup(ctx, &Group{ID: "globex-team", TenantID: "globex"}); err != nil {
+				t.Fatalf("CreateGroup failed: %v", err)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- tt.get(mgr) }()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Error("expected cross-tenant read to fail")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("cross-tenant read deadlocked while auditing")
+			}
+		})
+	}
+}
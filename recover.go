@@ -0,0 +1,181 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// RecoverMiddleware turns handler panics into a clean 500 response instead
+// of a dropped connection, and feeds the panic and its stack trace into a
+// LoggingLLM so the failure is analyzed like any other logged error.
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Response formats for RecoverConfig.Format
+const (
+	RecoverFormatJSON = "json"
+	RecoverFormatHTML = "html"
+)
+
+// RecoverConfig configures RecoverMiddlewareWithConfig.
+type RecoverConfig struct {
+	// Format of the 500 response body: RecoverFormatJSON (default) or
+	// RecoverFormatHTML. Both include the request ID when one is set.
+	Format string
+	// AnalysisCallback receives the LLM analysis of each panic, e.g. to
+	// file an incident. Optional.
+	AnalysisCallback AnalysisCallback
+	// Tags are attached to the LoggingLLM created for each panic.
+	Tags map[string]string
+}
+
+// DefaultRecoverConfig returns the default configuration (JSON responses).
+func DefaultRecoverConfig() *RecoverConfig {
+	return &RecoverConfig{Format: RecoverFormatJSON}
+}
+
+// RecoverMiddleware recovers panics with the default configuration.
+//
+// Example:
+//
+//	handler := common.RequestIDMiddleware(common.RecoverMiddleware(mux))
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return RecoverMiddlewareWithConfig(nil)(next)
+}
+
+// RecoverMiddlewareWithConfig returns a middleware that recovers panics,
+// logs them with their stack trace through a LoggingLLM (which triggers LLM
+// analysis when COMMON_LLM_API_KEY is set) and responds with a 500. If the
+// handler already started the response, only the logging happens.
+// http.ErrAbortHandler is re-raised so net/http can abort the connection.
+// A nil config uses DefaultRecoverConfig.
+func RecoverMiddlewareWithConfig(config *RecoverConfig) func(http.Handler) http.Handler {
+	if config == nil {
+		config = DefaultRecoverConfig()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoverResponseWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				logPanic(r, rec, debug.Stack(), config)
+				if !rw.wroteHeader {
+					writePanicResponse(w, RequestID(r.Context()), config.Format)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// logPanic records the panic in a LoggingLLM named after the panicking
+// function, which also triggers the LLM analysis.
+func logPanic(r *http.Request, rec interface{}, stack []byte, config *RecoverConfig) {
+	fileName, funcName := panicOrigin()
+	if funcName == "" {
+		fileName, funcName = "recover.go", r.Method+" "+r.URL.Path
+	}
+
+	log := CreateLoggingLLMWithCallback(fileName, funcName, config.AnalysisCallback, "").
+		WithContext(r.Context())
+	if len(config.Tags) > 0 {
+		log.WithTags(config.Tags)
+	}
+	// The query string is left out since it may carry personal data
+	log.Info("Handling %s %s", r.Method, r.URL.Path)
+	log.Error("panic: %v\n\n```\n%s\n```", rec, stack)
+}
+
+// panicOrigin returns the file and function that panicked, i.e. the first
+// frame after runtime.gopanic on the current stack.
+func panicOrigin() (string, string) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	afterPanic := false
+	for {
+		frame, more := frames.Next()
+		if afterPanic && !strings.HasPrefix(frame.Function, "runtime.") {
+			return frame.File, frame.Function
+		}
+		if frame.Function == "runtime.gopanic" {
+			afterPanic = true
+		}
+		if !more {
+			return "", ""
+		}
+	}
+}
+
+// writePanicResponse writes the 500 body in the configured format
+func writePanicResponse(w http.ResponseWriter, requestID, format string) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if format == RecoverFormatHTML {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		body := "<!DOCTYPE html>\n<html><head><title>Internal Server Error</title></head><body>\n" +
+			"<h1>Internal Server Error</h1>\n<p>Something went wrong. Please try again later.</p>\n"
+		if requestID != "" {
+			body += fmt.Sprintf("<p>Request ID: <code>%s</code></p>\n", html.EscapeString(requestID))
+		}
+		fmt.Fprint(w, body+"</body></html>\n")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	resp := map[string]string{"error": "internal server error"}
+	if requestID != "" {
+		resp["request_id"] = requestID
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// recoverResponseWriter records whether the response has started
+type recoverResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoverResponseWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoverResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *recoverResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
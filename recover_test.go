@@ -0,0 +1,155 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicHandler panics with a recognizable value
+func panicHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+// TestRecoverMiddleware checks the 500 response in both formats.
+func TestRecoverMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *RecoverConfig
+		contentType string
+		bodyHas     string
+	}{
+		{name: "Default JSON", config: nil, contentType: "application/json", bodyHas: `"request_id":"req-123"`},
+		{name: "HTML", config: &RecoverConfig{Format: RecoverFormatHTML}, contentType: "text/html; charset=utf-8", bodyHas: "<code>req-123</code>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequestIDMiddleware(RecoverMiddlewareWithConfig(tt.config)(http.HandlerFunc(panicHandler)))
+
+			req := httptest.NewRequest(http.MethodGet, "/crash", nil)
+			req.Header.Set(RequestIDHeader, "req-123")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("Expected status 500, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.bodyHas) {
+				t.Errorf("Body %q does not contain %q", rec.Body.String(), tt.bodyHas)
+			}
+			if strings.Contains(rec.Body.String(), "boom") {
+				t.Error("Panic value leaked into the response")
+			}
+		})
+	}
+}
+
+// TestRecoverMiddlewareResponseStarted checks that a started response is left alone.
+func TestRecoverMiddlewareResponseStarted(t *testing.T) {
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected original status 202, got %d", rec.Code)
+	}
+}
+
+// TestRecoverMiddlewareAbortHandler checks that http.ErrAbortHandler is re-raised.
+func TestRecoverMiddlewareAbortHandler(t *testing.T) {
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to propagate, got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+// TestRecoverMiddlewareTriggersAnalysis checks that the panic and its stack
+// trace reach the LLM provider and the analysis callback.
+func TestRecoverMiddlewareTriggersAnalysis(t *testing.T) {
+	prompts := make(chan string, 1)
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []llmChatMessage `json:"messages"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		if len(payload.Messages) > 0 {
+			prompts <- payload.Messages[len(payload.Messages)-1].Content
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"nil map write"}}]}`)
+	}))
+	defer stub.Close()
+
+	savedKey, savedURL, savedThrottle := LLMAPIKey, LLMBaseURL, AnalysisThrottleDuration
+	defer func() {
+		LLMAPIKey, LLMBaseURL, AnalysisThrottleDuration = savedKey, savedURL, savedThrottle
+	}()
+	LLMAPIKey, LLMBaseURL, AnalysisThrottleDuration = "stub", stub.URL, 0
+
+	analyses := make(chan string, 1)
+	handler := RecoverMiddlewareWithConfig(&RecoverConfig{
+		AnalysisCallback: func(analysis string) error {
+			analyses <- analysis
+			return nil
+		},
+	})(http.HandlerFunc(panicHandler))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", rec.Code)
+	}
+
+	select {
+	case prompt := <-prompts:
+		for _, want := range []string{"panic: boom", "panicHandler", "POST /orders"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("Prompt does not contain %q", want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("LLM analysis was not requested")
+	}
+
+	select {
+	case analysis := <-analyses:
+		if analysis != "nil map write" {
+			t.Errorf("Callback got %q", analysis)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Analysis callback was not invoked")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// request_id.go propagates a per-request identifier so log lines, LLM
+// summaries and client-visible errors can be correlated. The ID travels in
+// the X-Request-ID header and the request context.
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestIDHeader is the header carrying the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds inbound IDs so a client cannot bloat log lines
+const maxRequestIDLength = 128
+
+// requestIDContextKey is a private type for storing the request ID in a context.
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the given request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestID returns the request ID stored by RequestIDMiddleware or
+// WithRequestID, or an empty string when none is present.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// RequestIDMiddleware reuses the inbound X-Request-ID header or generates a
+// new ID, stores it in the request context and echoes it in the response
+// header. Inbound IDs that are too long or contain characters outside
+// [A-Za-z0-9._:-] are replaced so they cannot inject content into logs.
+//
+// Example:
+//
+//	handler := common.RequestIDMiddleware(mux)
+//	// in a handler:
+//	log := common.CreateLoggingLLM("orders.go", "Create", "").WithContext(r.Context())
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// isValidRequestID reports whether an inbound ID is safe to reuse
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random URL-safe ID. A request ID is not a secret,
+// so a timestamp is used if the random source fails rather than failing
+// the request.
+func newRequestID() string {
+	id, err := GenerateToken(MinTokenBytes)
+	if err != nil {
+		return "req-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return id
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveWithRequestID runs RequestIDMiddleware and returns the ID seen by the
+// handler and the ID echoed in the response header.
+func serveWithRequestID(t *testing.T, inbound string) (seen, echoed string) {
+	t.Helper()
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if inbound != "" {
+		req.Header.Set(RequestIDHeader, inbound)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return seen, rec.Header().Get(RequestIDHeader)
+}
+
+func TestRequestIDMiddlewarePassthrough(t *testing.T) {
+	inbound := "01HQZX9P7J8K5M6N4Q3R2T1W0V"
+	seen, echoed := serveWithRequestID(t, inbound)
+	if seen != inbound || echoed != inbound {
+		t.Errorf("context ID = %q, header ID = %q, want %q", seen, echoed, inbound)
+	}
+}
+
+func TestRequestIDMiddlewareGenerates(t *testing.T) {
+	first, echoed := serveWithRequestID(t, "")
+	if first == "" || first != echoed {
+		t.Fatalf("context ID = %q, header ID = %q, want the same non-empty ID", first, echoed)
+	}
+	second, _ := serveWithRequestID(t, "")
+	if second == first {
+		t.Errorf("generated IDs are not unique: %q", first)
+	}
+}
+
+func TestRequestIDMiddlewareRejectsUnsafeIDs(t *testing.T) {
+	for _, inbound := range []string{"bad id", "line\nbreak", "<script>", strings.Repeat("a", maxRequestIDLength+1)} {
+		seen, echoed := serveWithRequestID(t, inbound)
+		if seen == inbound || seen == "" || seen != echoed {
+			t.Errorf("inbound %q: context ID = %q, header ID = %q, want a fresh ID", inbound, seen, echoed)
+		}
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	if got := RequestID(context.Background()); got != "" {
+		t.Errorf("RequestID(empty) = %q", got)
+	}
+	if got := RequestID(WithRequestID(context.Background(), "abc")); got != "abc" {
+		t.Errorf("RequestID = %q, want abc", got)
+	}
+}
+
+func TestLoggingLLMIncludesRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-42")
+	logger := CreateLoggingLLM("orders.go", "Create", "").WithContext(ctx)
+
+	if summary := logger.MarkdownSummary(); !strings.Contains(summary, "_Request ID req-42_") {
+		t.Errorf("summary does not contain the request ID:\n%s", summary)
+	}
+	if prompt := logger.buildLLMPrompt(); !strings.Contains(prompt, "Request ID: req-42") {
+		t.Errorf("prompt does not contain the request ID:\n%s", prompt)
+	}
+
+	// Without a request ID in the context nothing is added
+	plain := CreateLoggingLLM("orders.go", "Create", "").WithContext(context.Background())
+	if strings.Contains(plain.MarkdownSummary(), "Request ID") {
+		t.Error("summary mentions a request ID that was never set")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry helpers for calls to external services (GA, LLM providers, email).
+// Transient failures are retried with exponential backoff and jitter so that
+// many instances recovering at once do not hammer the remote service in sync.
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Retry stops immediately and returns err.
+// Use it for failures that will not succeed on a second try, such as
+// validation or authentication errors.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retry calls fn up to attempts times until it returns nil. Between attempts
+// it waits backoff, doubling each time, with random jitter of up to half the
+// delay. Retry stops early when fn returns an error wrapped with Permanent or
+// when ctx is done, in which case the context error is returned.
+//
+// Example:
+//
+//	err := common.Retry(ctx, 3, 200*time.Millisecond, func() error {
+//	    return sendEvent(ctx, event)
+//	})
+func Retry(ctx context.Context, attempts int, backoff time.Duration, fn func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+
+		if attempt == attempts-1 {
+			break
+		}
+
+		Debug("[RETRY] Attempt %d/%d failed: %v", attempt+1, attempts, err)
+		if ctxErr := sleepContext(ctx, retryDelay(backoff, attempt)); ctxErr != nil {
+			return ctxErr
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// RetryableHTTP sends req with client, retrying on network errors, 429 and
+// 5xx responses. A Retry-After header on the response overrides the computed
+// backoff. When every attempt receives a retryable status, the last response
+// is returned so the caller can inspect it. A nil client uses
+// http.DefaultClient.
+//
+// Request bodies are replayed on each attempt; requests built with
+// http.NewRequest from a bytes or strings reader support this natively,
+// other bodies are buffered in memory first.
+func RetryableHTTP(ctx context.Context, client *http.Client, req *http.Request, attempts int, backoff time.Duration) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	if req.Body != nil && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to buffer request body: %w", err)
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.WithContext(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := client.Do(attemptReq)
+		last := attempt == attempts-1
+
+		delay := retryDelay(backoff, attempt)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || last {
+				return nil, err
+			}
+			Debug("[RETRY] %s %s attempt %d/%d failed: %v", req.Method, req.URL.Redacted(), attempt+1, attempts, err)
+		case isRetryableStatus(resp.StatusCode) && !last:
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = d
+			}
+			Debug("[RETRY] %s %s attempt %d/%d got status %d", req.Method, req.URL.Redacted(), attempt+1, attempts, resp.StatusCode)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if ctxErr := sleepContext(ctx, delay); ctxErr != nil {
+			return nil, ctxErr
+		}
+	}
+}
+
+// isRetryableStatus reports whether a response status is worth retrying
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryDelay returns backoff*2^attempt plus up to 50% random jitter
+func retryDelay(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	if attempt > 30 {
+		attempt = 30 // avoid overflowing the shift
+	}
+	delay := backoff << uint(attempt)
+	if half := int64(delay / 2); half > 0 {
+		delay += time.Duration(rand.Int63n(half))
+	}
+	return delay
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
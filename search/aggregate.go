@@ -0,0 +1,89 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import "fmt"
+
+// Aggregation operations accepted in Aggregation.Op
+const (
+	AggMin = "min"
+	AggMax = "max"
+	AggAvg = "avg"
+	AggSum = "sum"
+)
+
+// Aggregation computes a statistic over a numeric metadata field of every
+// matching document, not just the returned page
+type Aggregation struct {
+	Field string `json:"field"` // Metadata key
+	Op    string `json:"op"`    // AggMin, AggMax, AggAvg or AggSum
+}
+
+// AggregationResult is the value of one Aggregation. Documents where the
+// field is missing or not numeric are skipped; Count says how many were
+// used, and Value is 0 when none were.
+type AggregationResult struct {
+	Field string  `json:"field"`
+	Op    string  `json:"op"`
+	Value float64 `json:"value"`
+	Count int     `json:"count"`
+}
+
+// validateAggregations rejects aggregations without a field or with an
+// unknown operation
+func validateAggregations(aggs []Aggregation) error {
+	for _, agg := range aggs {
+		if agg.Field == "" {
+			return fmt.Errorf("aggregation field is required")
+		}
+		switch agg.Op {
+		case AggMin, AggMax, AggAvg, AggSum:
+		default:
+			return fmt.Errorf("unknown aggregation op %q for field %s", agg.Op, agg.Field)
+		}
+	}
+	return nil
+}
+
+// calculateAggregations computes aggs over results, in query order. Numeric
+// strings count as numbers, as they do for geo coordinates.
+func calculateAggregations(results []Document, aggs []Aggregation) []AggregationResult {
+	out := make([]AggregationResult, len(aggs))
+	for i, agg := range aggs {
+		res := AggregationResult{Field: agg.Field, Op: agg.Op}
+		for _, doc := range results {
+			v, ok := metadataNumber(doc.Metadata, agg.Field)
+			if !ok {
+				continue
+			}
+			switch {
+			case res.Count == 0:
+				res.Value = v
+			case agg.Op == AggMin:
+				res.Value = min(res.Value, v)
+			case agg.Op == AggMax:
+				res.Value = max(res.Value, v)
+			default:
+				res.Value += v
+			}
+			res.Count++
+		}
+		if agg.Op == AggAvg && res.Count > 0 {
+			res.Value /= float64(res.Count)
+		}
+		out[i] = res
+	}
+	return out
+}
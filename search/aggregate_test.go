@@ -0,0 +1,91 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func aggregationEngine(t *testing.T) *InMemoryEngine {
+	t.Helper()
+	engine := NewInMemoryEngine()
+	docs := []Document{
+		{ID: "1", Title: "Red chair", Metadata: map[string]interface{}{"price": 40.0, "rating": 4}},
+		{ID: "2", Title: "Blue chair", Metadata: map[string]interface{}{"price": 60.0, "rating": "5"}},
+		{ID: "3", Title: "Green chair", Metadata: map[string]interface{}{"price": "n/a", "rating": 3}},
+		{ID: "4", Title: "Oak chair", Metadata: map[string]interface{}{"price": 20}},
+		{ID: "5", Title: "Oak table", Metadata: map[string]interface{}{"price": 500.0, "rating": 5}},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return engine
+}
+
+func TestSearchAggregations(t *testing.T) {
+	engine := aggregationEngine(t)
+	query := NewQueryBuilder("chair").
+		WithAggregation("price", AggAvg).
+		WithAggregation("rating", AggMax).
+		WithAggregation("price", AggMin).
+		WithAggregation("price", AggSum).
+		WithAggregation("weight", AggAvg).
+		Build()
+	query.Size = 1 // aggregations cover every match, not just the page
+
+	results, err := engine.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Hits) != 1 || results.Total != 4 {
+		t.Fatalf("Expected 1 hit of 4, got %d of %d", len(results.Hits), results.Total)
+	}
+
+	want := []AggregationResult{
+		{Field: "price", Op: AggAvg, Value: 40, Count: 3}, // "n/a" is skipped
+		{Field: "rating", Op: AggMax, Value: 5, Count: 3}, // numeric string counts, missing is skipped
+		{Field: "price", Op: AggMin, Value: 20, Count: 3},
+		{Field: "price", Op: AggSum, Value: 120, Count: 3},
+		{Field: "weight", Op: AggAvg, Value: 0, Count: 0},
+	}
+	if !reflect.DeepEqual(results.Aggregations, want) {
+		t.Errorf("Aggregations = %+v, want %+v", results.Aggregations, want)
+	}
+}
+
+func TestSearchAggregationsInvalid(t *testing.T) {
+	engine := aggregationEngine(t)
+
+	tests := []struct {
+		name string
+		agg  Aggregation
+	}{
+		{"Unknown op", Aggregation{Field: "price", Op: "median"}},
+		{"Missing field", Aggregation{Op: AggAvg}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := engine.Search(context.Background(), Query{Aggregations: []Aggregation{tt.agg}})
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
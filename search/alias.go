@@ -0,0 +1,114 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+// Aliases give callers a stable index name while the index behind it is
+// rebuilt. Build the new index under a fresh name, then swap the alias:
+//
+//	engine.CreateAlias(ctx, "products", "products_v2")
+//	// ... index every document into "products_v3" ...
+//	old, _ := engine.SwapAlias(ctx, "products", "products_v3")
+//	engine.DeleteIndex(ctx, old)
+//
+// Queries against "products" see products_v2 until the swap and
+// products_v3 afterwards; the swap happens under the engine lock, so no
+// query observes a half-built index.
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/patdeg/common"
+)
+
+// CreateAlias makes alias resolve to index in Search and Index. The alias
+// must not already exist or collide with a concrete index, and aliases
+// cannot point to other aliases. The target index need not exist yet.
+func (e *InMemoryEngine) CreateAlias(ctx context.Context, alias, index string) error {
+	if alias == "" || index == "" {
+		return fmt.Errorf("alias and index names are required")
+	}
+	if alias == index {
+		return fmt.Errorf("alias %s cannot point to itself", alias)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if target, ok := e.aliases[alias]; ok {
+		return fmt.Errorf("alias %s already exists for index %s", alias, target)
+	}
+	if _, ok := e.indices[alias]; ok {
+		return fmt.Errorf("alias %s conflicts with an existing index", alias)
+	}
+	if _, ok := e.aliases[index]; ok {
+		return fmt.Errorf("alias %s cannot point to alias %s", alias, index)
+	}
+
+	e.aliases[alias] = index
+	e.cache.purge()
+
+	common.Info("[SEARCH] Created alias %s -> %s", alias, index)
+	return nil
+}
+
+// SwapAlias atomically repoints an existing alias to index and returns the
+// index it pointed to before, typically to be deleted with DeleteIndex.
+func (e *InMemoryEngine) SwapAlias(ctx context.Context, alias, index string) (string, error) {
+	if index == "" {
+		return "", fmt.Errorf("index name is required")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous, ok := e.aliases[alias]
+	if !ok {
+		return "", fmt.Errorf("alias not found: %s", alias)
+	}
+	if index == alias {
+		return "", fmt.Errorf("alias %s cannot point to itself", alias)
+	}
+	if _, ok := e.aliases[index]; ok {
+		return "", fmt.Errorf("alias %s cannot point to alias %s", alias, index)
+	}
+
+	e.aliases[alias] = index
+	e.cache.purge()
+
+	common.Info("[SEARCH] Swapped alias %s: %s -> %s", alias, previous, index)
+	return previous, nil
+}
+
+// Aliases returns a copy of the alias -> index mapping
+func (e *InMemoryEngine) Aliases() map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	aliases := make(map[string]string, len(e.aliases))
+	for alias, index := range e.aliases {
+		aliases[alias] = index
+	}
+	return aliases
+}
+
+// resolveIndexLocked returns the index an alias points to, or name itself
+// when it is not an alias. The caller must hold e.mu.
+func (e *InMemoryEngine) resolveIndexLocked(name string) string {
+	if index, ok := e.aliases[name]; ok {
+		return index
+	}
+	return name
+}
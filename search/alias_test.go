@@ -0,0 +1,252 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// indexCatalog indexes n products into index, tagging each with version
+func indexCatalog(t *testing.T, engine *InMemoryEngine, index, version string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		doc := Document{
+			ID:       fmt.Sprintf("p%d", i),
+			Index:    index,
+			Title:    "Widget " + version,
+			Content:  "A useful widget",
+			Metadata: map[string]interface{}{"version": version},
+		}
+		if err := engine.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index(%s) failed: %v", doc.ID, err)
+		}
+	}
+}
+
+// TestSwapAliasNoLostQueries rebuilds an index under a new name while
+// queries run against the alias, then swaps it in
+func TestSwapAliasNoLostQueries(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	const n = 50
+
+	indexCatalog(t, engine, "products_v2", "v2", n)
+	if err := engine.CreateAlias(ctx, "products", "products_v2"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 1)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				results, err := engine.Search(ctx, Query{Text: "widget", Index: "products", Size: n})
+				if err == nil && results.Total != n {
+					err = fmt.Errorf("search through alias returned %d of %d documents", results.Total, n)
+				}
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	// Same IDs in a second index must not disturb the live one
+	indexCatalog(t, engine, "products_v3", "v3", n)
+	previous, err := engine.SwapAlias(ctx, "products", "products_v3")
+	if err != nil {
+		t.Fatalf("SwapAlias failed: %v", err)
+	}
+	if previous != "products_v2" {
+		t.Errorf("SwapAlias() previous = %q, want products_v2", previous)
+	}
+	if err := engine.DeleteIndex(ctx, previous); err != nil {
+		t.Fatalf("DeleteIndex(%s) failed: %v", previous, err)
+	}
+
+	close(stop)
+	wg.Wait()
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+
+	results, err := engine.Search(ctx, Query{Text: "widget", Index: "products", Size: n})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results.Total != n {
+		t.Fatalf("Expected %d documents after swap, got %d", n, results.Total)
+	}
+	for _, hit := range results.Hits {
+		if hit.Index != "products_v3" || hit.Metadata["version"] != "v3" {
+			t.Errorf("Expected hit %s from products_v3, got index %s version %v", hit.ID, hit.Index, hit.Metadata["version"])
+		}
+	}
+}
+
+// TestIndexThroughAlias verifies documents indexed via an alias land in its target
+func TestIndexThroughAlias(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	if err := engine.CreateAlias(ctx, "products", "products_v1"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+	if err := engine.Index(ctx, Document{ID: "1", Index: "products", Title: "Widget"}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	doc, err := engine.GetDocument(ctx, "1")
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if doc.Index != "products_v1" {
+		t.Errorf("Expected document in products_v1, got %s", doc.Index)
+	}
+	if got := searchIDs(t, engine, Query{Text: "widget", Index: "products_v1"}); len(got) != 1 {
+		t.Errorf("Expected one hit in products_v1, got %v", got)
+	}
+}
+
+// TestSameIDAcrossIndices verifies per-index document identity
+func TestSameIDAcrossIndices(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	indexCatalog(t, engine, "a", "va", 1)
+	indexCatalog(t, engine, "b", "vb", 1)
+
+	// Unscoped searches see one copy per ID, the most recently indexed one
+	results, err := engine.Search(ctx, Query{Text: "widget"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results.Total != 1 || len(results.Hits) != 1 || results.Hits[0].Index != "b" {
+		t.Errorf("Expected only the copy in b without an index, got %+v", results.Hits)
+	}
+	if doc, err := engine.GetDocument(ctx, "p0"); err != nil || doc.Index != "b" {
+		t.Errorf("GetDocument = %+v, %v, want the copy in b", doc, err)
+	}
+	for _, index := range []string{"a", "b"} {
+		if got := searchIDs(t, engine, Query{Text: "widget", Index: index}); len(got) != 1 {
+			t.Errorf("Expected the copy in %s when scoped, got %v", index, got)
+		}
+	}
+
+	if err := engine.UpdateDocument(ctx, "p0", map[string]interface{}{"title": "Gadget"}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	for _, index := range []string{"a", "b"} {
+		if got := searchIDs(t, engine, Query{Text: "gadget", Index: index}); len(got) != 1 {
+			t.Errorf("Expected the update to reach the copy in %s, got %v", index, got)
+		}
+	}
+
+	if err := engine.Delete(ctx, "p0"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := engine.GetDocument(ctx, "p0"); err == nil {
+		t.Error("Expected the document to be gone from every index")
+	}
+	suggestions, err := engine.Suggest(ctx, "gad", 5)
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if len(suggestions) != 0 {
+		t.Errorf("Expected no suggestions after delete, got %v", suggestions)
+	}
+}
+
+// TestAliasErrors verifies invalid alias operations are rejected
+func TestAliasErrors(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	indexCatalog(t, engine, "products_v1", "v1", 1)
+	if err := engine.CreateAlias(ctx, "products", "products_v1"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"Empty alias", func() error { return engine.CreateAlias(ctx, "", "products_v1") }},
+		{"Self alias", func() error { return engine.CreateAlias(ctx, "x", "x") }},
+		{"Duplicate alias", func() error { return engine.CreateAlias(ctx, "products", "products_v2") }},
+		{"Alias shadows index", func() error { return engine.CreateAlias(ctx, "products_v1", "products_v2") }},
+		{"Alias to alias", func() error { return engine.CreateAlias(ctx, "shop", "products") }},
+		{"Swap unknown alias", func() error { _, err := engine.SwapAlias(ctx, "missing", "products_v1"); return err }},
+		{"Swap to alias", func() error { _, err := engine.SwapAlias(ctx, "products", "products"); return err }},
+		{"Delete alias", func() error { return engine.DeleteIndex(ctx, "products") }},
+		{"Delete aliased index", func() error { return engine.DeleteIndex(ctx, "products_v1") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if got := engine.Aliases(); len(got) != 1 || got["products"] != "products_v1" {
+		t.Errorf("Expected aliases to be unchanged, got %v", got)
+	}
+}
+
+// TestSaveLoadAliases verifies aliases and duplicate IDs survive a snapshot
+func TestSaveLoadAliases(t *testing.T) {
+	ctx := context.Background()
+	original := NewInMemoryEngine()
+	indexCatalog(t, original, "products_v1", "v1", 3)
+	indexCatalog(t, original, "products_v2", "v2", 3)
+	if err := original.CreateAlias(ctx, "products", "products_v2"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := original.SaveIndex(&buf); err != nil {
+		t.Fatalf("SaveIndex failed: %v", err)
+	}
+	restored := NewInMemoryEngine()
+	if err := restored.LoadIndex(&buf); err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+
+	if got := restored.Aliases()["products"]; got != "products_v2" {
+		t.Errorf("Expected alias to products_v2, got %q", got)
+	}
+	for _, index := range []string{"products", "products_v1"} {
+		if got := searchIDs(t, restored, Query{Text: "widget", Index: index}); len(got) != 3 {
+			t.Errorf("Expected 3 hits in %s, got %v", index, got)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"strings"
+)
+
+// Analyzer turns text into index terms for one language: it tokenizes,
+// drops stop words and stems what is left, so "Running shoes" and "run
+// shoe" produce the same English terms.
+type Analyzer struct {
+	// StopWords are lowercase terms that carry no meaning for search
+	StopWords map[string]bool
+
+	// Stem reduces a lowercase term to its stem. Nil keeps terms unchanged.
+	Stem func(term string) string
+}
+
+// Analyze returns the terms of text in order, stop words removed
+func (a *Analyzer) Analyze(text string) []string {
+	var terms []string
+	for _, term := range tokenize(text) {
+		if a.StopWords[term] {
+			continue
+		}
+		if a.Stem != nil {
+			term = a.Stem(term)
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// DefaultAnalyzers returns the built-in analyzers keyed by language code
+func DefaultAnalyzers() map[string]*Analyzer {
+	return map[string]*Analyzer{
+		"en": EnglishAnalyzer(),
+		"fr": FrenchAnalyzer(),
+	}
+}
+
+// EnglishAnalyzer returns an analyzer with common English stop words and a
+// light suffix stemmer for plurals, -ing and -ed
+func EnglishAnalyzer() *Analyzer {
+	return &Analyzer{StopWords: wordSet(englishStopWords), Stem: stemEnglish}
+}
+
+// FrenchAnalyzer returns an analyzer with common French stop words,
+// including elided articles such as the "l" of "l'école", and a light
+// stemmer for plural and feminine endings
+func FrenchAnalyzer() *Analyzer {
+	return &Analyzer{StopWords: wordSet(frenchStopWords), Stem: stemFrench}
+}
+
+// normalizeLanguage reduces a language tag such as "fr-CA" to its primary
+// subtag
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	return language
+}
+
+// analyzedDocument holds the terms of a document's searchable fields as
+// produced by the analyzer of its language
+type analyzedDocument struct {
+	analyzer *Analyzer
+	title    []string
+	content  []string
+	tags     [][]string
+}
+
+// analyzerForLocked returns the analyzer for language, falling back to
+// Config.DefaultLanguage. Nil means plain substring matching. The caller
+// must hold e.mu.
+func (e *InMemoryEngine) analyzerForLocked(language string) *Analyzer {
+	language = normalizeLanguage(language)
+	if language == "" {
+		language = e.defaultLanguage
+	}
+	return e.analyzers[language]
+}
+
+// analyzeLocked records the analyzed fields of doc when its language has
+// an analyzer. The caller must hold e.mu for writing.
+func (e *InMemoryEngine) analyzeLocked(doc *Document) {
+	analyzer := e.analyzerForLocked(doc.Language)
+	if analyzer == nil {
+		return
+	}
+	analyzed := &analyzedDocument{
+		analyzer: analyzer,
+		title:    analyzer.Analyze(doc.Title),
+		content:  analyzer.Analyze(doc.Content),
+	}
+	for _, tag := range doc.Tags {
+		analyzed.tags = append(analyzed.tags, analyzer.Analyze(tag))
+	}
+	e.analyzed[docKey(doc)] = analyzed
+}
+
+// scoreAnalyzed is score for an analyzed document: query terms are compared
+// with whole document terms rather than substrings, and a phrase is a run
+// of consecutive terms
+func (s ScoringConfig) scoreAnalyzed(doc *analyzedDocument, queryTerms []string, fields fieldSet) float64 {
+	if len(queryTerms) == 0 {
+		return 0
+	}
+
+	// Fields outside the set are treated as empty
+	var title, content []string
+	if fields.title {
+		title = doc.title
+	}
+	if fields.content {
+		content = doc.content
+	}
+
+	score := 0.0
+	for _, term := range queryTerms {
+		score += float64(countTerm(title, term)) * s.TitleBoost
+		score += float64(countTerm(content, term)) * s.ContentBoost
+		if fields.tags {
+			for _, tag := range doc.tags {
+				if countTerm(tag, term) > 0 {
+					score += s.TagBoost
+				}
+			}
+		}
+	}
+
+	if s.PhraseBoost > 1 {
+		if containsRun(title, queryTerms) {
+			score *= s.PhraseBoost
+		} else if containsRun(content, queryTerms) {
+			score *= 1 + (s.PhraseBoost-1)/2
+		}
+	}
+
+	if s.ProximityBoost > 1 {
+		equal := func(term, word string) bool { return term == word }
+		closeness := max(proximity(title, queryTerms, equal), proximity(content, queryTerms, equal))
+		score *= 1 + (s.ProximityBoost-1)*closeness
+	}
+	return score
+}
+
+// countTerm returns how often term occurs in terms
+func countTerm(terms []string, term string) int {
+	n := 0
+	for _, t := range terms {
+		if t == term {
+			n++
+		}
+	}
+	return n
+}
+
+// containsRun reports whether run occurs in terms as consecutive terms
+func containsRun(terms, run []string) bool {
+	for i := 0; i+len(run) <= len(terms); i++ {
+		match := true
+		for j, term := range run {
+			if terms[i+j] != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// stemEnglish strips common inflections: plural -s/-es/-ies, -ing, -ed and
+// a final -e, so "indexes", "indexing" and "indexed" all become "index"
+// and "make", "makes" and "making" become "mak". It is deliberately light;
+// stems only need to agree with each other, not be words.
+func stemEnglish(term string) string {
+	if len([]rune(term)) <= 3 {
+		return term
+	}
+
+	switch {
+	case strings.HasSuffix(term, "ies") && len(term) > 4:
+		term = term[:len(term)-3] + "y"
+	case strings.HasSuffix(term, "sses"), strings.HasSuffix(term, "xes"),
+		strings.HasSuffix(term, "zes"), strings.HasSuffix(term, "ches"), strings.HasSuffix(term, "shes"):
+		term = term[:len(term)-2]
+	case strings.HasSuffix(term, "s") && !strings.HasSuffix(term, "ss") &&
+		!strings.HasSuffix(term, "us") && !strings.HasSuffix(term, "is"):
+		term = term[:len(term)-1]
+	}
+
+	switch {
+	case strings.HasSuffix(term, "ied") && len(term) > 4:
+		term = term[:len(term)-3] + "y"
+	case strings.HasSuffix(term, "ing") && len(term) > 5:
+		term = undouble(term[:len(term)-3])
+	case strings.HasSuffix(term, "ed") && len(term) > 4:
+		term = undouble(term[:len(term)-2])
+	}
+
+	if strings.HasSuffix(term, "e") && len(term) > 3 {
+		term = term[:len(term)-1]
+	}
+	return term
+}
+
+// undouble turns a doubled final consonant back into one, as in "running"
+// -> "runn" -> "run". Doubled l, s and z are kept ("spelled", "passed").
+func undouble(term string) string {
+	n := len(term)
+	if n < 2 || term[n-1] != term[n-2] {
+		return term
+	}
+	switch term[n-1] {
+	case 'a', 'e', 'i', 'o', 'u', 'l', 's', 'z':
+		return term
+	}
+	return term[:n-1]
+}
+
+// stemFrench strips plural, feminine and past participle endings:
+// "chevaux" becomes "cheval", "jeux" becomes "jeu", "grand", "grande", "grands" and "grandes"
+// all become "grand", and "aimé" and "aimées" become "aim"
+func stemFrench(term string) string {
+	if len([]rune(term)) <= 3 {
+		return term
+	}
+
+	switch {
+	case strings.HasSuffix(term, "aux") && len(term) > 4:
+		term = term[:len(term)-3] + "al"
+	case strings.HasSuffix(term, "s"), strings.HasSuffix(term, "ux"):
+		term = term[:len(term)-1]
+	}
+
+	if strings.HasSuffix(term, "e") && len([]rune(term)) > 3 {
+		term = term[:len(term)-1]
+	}
+	if strings.HasSuffix(term, "é") && len([]rune(term)) > 3 {
+		term = strings.TrimSuffix(term, "é")
+	}
+	return term
+}
+
+// wordSet builds a lookup set from a space-separated word list
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+const englishStopWords = `a an and are as at be but by for from has have he her his i in into is it
+its me my no not of on or our she so than that the their them then there these they this to
+was we were what when where which who will with you your`
+
+const frenchStopWords = `a à au aux avec c ce ces cette d dans de des du elle elles en est et eux
+il ils j je l la le les leur leurs lui m ma mais me mes moi mon n ne nos notre nous on ou où
+par pas pour qu que qui s sa se ses son sur t ta te tes toi ton tu un une vos votre vous y`
@@ -0,0 +1,134 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAnalyzers(t *testing.T) {
+	tests := []struct {
+		name     string
+		analyzer *Analyzer
+		text     string
+		want     []string
+	}{
+		{name: "English stemming", analyzer: EnglishAnalyzer(), text: "Indexing the indexed indexes", want: []string{"index", "index", "index"}},
+		{name: "English plurals", analyzer: EnglishAnalyzer(), text: "Running shoes for runners", want: []string{"run", "sho", "runner"}},
+		{name: "French stop words", analyzer: FrenchAnalyzer(), text: "Le prix de l'école et des chevaux", want: []string{"prix", "écol", "cheval"}},
+		{name: "French feminine plural", analyzer: FrenchAnalyzer(), text: "grandes aimées jeux", want: []string{"grand", "aim", "jeu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.analyzer.Analyze(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Analyze(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func newLanguageEngine(t *testing.T, config *Config) *InMemoryEngine {
+	t.Helper()
+	engine := NewInMemoryEngineWithConfig(config)
+	docs := []Document{
+		{ID: "en", Language: "en", Title: "Running shoes", Content: "Lightweight shoes for running the trails"},
+		{ID: "fr", Language: "fr-CA", Title: "Chaussures de course", Content: "Les chaussures pour les sentiers"},
+		{ID: "plain", Title: "Runner's guide", Content: "The shoe store"},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return engine
+}
+
+// queryHitIDs returns the sorted IDs of the documents matching query
+func queryHitIDs(t *testing.T, engine *InMemoryEngine, query Query) []string {
+	t.Helper()
+	results, err := engine.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	ids := []string{}
+	for _, hit := range results.Hits {
+		ids = append(ids, hit.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSearchLanguageAnalyzers(t *testing.T) {
+	engine := newLanguageEngine(t, nil)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		// English stemming: "runs" and "shoe" match "running shoes"
+		{name: "English stemming", query: Query{Text: "runs"}, want: []string{"en"}},
+		// Only the plain document matches substrings such as "runner" in "runner's"
+		{name: "Plain substring fallback", query: Query{Text: "runner"}, want: []string{"plain"}},
+		// French stop words are ignored, so only "sentiers" has to match
+		{name: "French stop words removed", query: Query{Text: "les sentiers", Language: "fr"}, want: []string{"fr"}},
+		{name: "French stop word alone", query: Query{Text: "les", Language: "fr"}, want: []string{}},
+		// Without a query language the query is analyzed like each
+		// document, so French stemming matches "sentiers"
+		{name: "Query analyzed per document", query: Query{Text: "sentier"}, want: []string{"fr"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queryHitIDs(t, engine, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchDefaultLanguage(t *testing.T) {
+	// With English as the default, the unlabelled document is stemmed too
+	engine := newLanguageEngine(t, &Config{DefaultLanguage: "en"})
+	if got := queryHitIDs(t, engine, Query{Text: "shoe"}); !reflect.DeepEqual(got, []string{"en", "plain"}) {
+		t.Errorf("hits = %v, want [en plain]", got)
+	}
+
+	// An empty analyzer map keeps substring matching for every document
+	engine = newLanguageEngine(t, &Config{Analyzers: map[string]*Analyzer{}})
+	if got := queryHitIDs(t, engine, Query{Text: "runs"}); len(got) != 0 {
+		t.Errorf("expected no substring match for runs, got %v", got)
+	}
+}
+
+func TestUpdateDocumentLanguage(t *testing.T) {
+	ctx := context.Background()
+	engine := newLanguageEngine(t, nil)
+	if got := queryHitIDs(t, engine, Query{Text: "run"}); !reflect.DeepEqual(got, []string{"en", "plain"}) {
+		t.Fatalf("hits = %v, want [en plain] before the update", got)
+	}
+
+	if err := engine.UpdateDocument(ctx, "plain", map[string]interface{}{"language": "en"}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	// "run" is a substring of "Runner's" but not an English term of it
+	if got := queryHitIDs(t, engine, Query{Text: "run"}); !reflect.DeepEqual(got, []string{"en"}) {
+		t.Errorf("hits = %v, want only [en] once the document is analyzed", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+// DefaultResultCacheTTL is how long cached results are served when
+// Config.ResultCacheTTL is zero
+const DefaultResultCacheTTL = time.Minute
+
+// resultCache holds recent Search results keyed on the normalized query.
+// The engine purges it on every write while holding its write lock, so a
+// Search storing results under the read lock never caches a stale view.
+type resultCache struct {
+	entries *common.LRUCache[string, Results]
+}
+
+// newResultCache returns the cache configured by config, or nil when
+// result caching is off
+func newResultCache(config *Config) *resultCache {
+	if config.ResultCacheSize <= 0 {
+		return nil
+	}
+	ttl := config.ResultCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultResultCacheTTL
+	}
+	return &resultCache{
+		entries: common.NewLRUCache[string, Results](config.ResultCacheSize, ttl),
+	}
+}
+
+// get returns a copy of the results cached under key, marked as Cached
+func (c *resultCache) get(key string) (*Results, bool) {
+	cached, ok := c.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+	results := copyResults(&cached)
+	results.Cached = true
+	return results, true
+}
+
+// set caches a copy of results under key, so callers may modify what
+// Search returned
+func (c *resultCache) set(key string, results *Results) {
+	c.entries.Set(key, *copyResults(results))
+}
+
+// purge drops every cached result
+func (c *resultCache) purge() {
+	if c != nil {
+		c.entries.Purge()
+	}
+}
+
+// copyResults returns a deep copy of results: hits with their tags and
+// metadata, facets and aggregations can all be modified without affecting
+// the original
+func copyResults(results *Results) *Results {
+	copied := *results
+	if results.Hits != nil {
+		copied.Hits = make([]Document, len(results.Hits))
+		for i, hit := range results.Hits {
+			hit.Tags = append([]string(nil), hit.Tags...)
+			if hit.Metadata != nil {
+				hit.Metadata = copyValue(hit.Metadata).(map[string]interface{})
+			}
+			copied.Hits[i] = hit
+		}
+	}
+	if results.Facets != nil {
+		copied.Facets = make(map[string][]FacetItem, len(results.Facets))
+		for field, items := range results.Facets {
+			copied.Facets[field] = append([]FacetItem(nil), items...)
+		}
+	}
+	copied.Aggregations = append([]AggregationResult(nil), results.Aggregations...)
+	return &copied
+}
+
+// copyValue deep-copies the maps and slices a metadata value may hold;
+// other values are returned as is
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = copyValue(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, val := range v {
+			s[i] = copyValue(val)
+		}
+		return s
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
+
+// resultCacheKey identifies a query for the result cache. Text is compared
+// case-insensitively with whitespace collapsed, since neither changes the
+// results, and pagination is normalized the way Search applies it.
+func resultCacheKey(query Query) (string, bool) {
+	query.Text = strings.Join(strings.Fields(strings.ToLower(query.Text)), " ")
+	query.Language = normalizeLanguage(query.Language)
+	if query.From < 0 {
+		query.From = 0
+	}
+	if query.Size <= 0 {
+		query.Size = 10
+	}
+
+	// Filters may hold values that cannot be encoded; such queries are
+	// not cached
+	key, err := json.Marshal(query)
+	if err != nil {
+		return "", false
+	}
+	return string(key), true
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newCachedEngine returns an engine with result caching and one document
+func newCachedEngine(t *testing.T, ttl time.Duration) *InMemoryEngine {
+	t.Helper()
+	engine := NewInMemoryEngineWithConfig(&Config{ResultCacheSize: 10, ResultCacheTTL: ttl})
+
+	doc := Document{
+		ID:       "1",
+		Title:    "Quarterly revenue report",
+		Tags:     []string{"finance"},
+		Metadata: map[string]interface{}{"pages": 12.0, "owner": map[string]interface{}{"team": "finance"}},
+	}
+	if err := engine.Index(context.Background(), doc); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	return engine
+}
+
+// searchTotal runs a search and returns the total and whether it was cached
+func searchTotal(t *testing.T, engine *InMemoryEngine, text string) (int, bool) {
+	t.Helper()
+	results, err := engine.Search(context.Background(), Query{Text: text})
+	if err != nil {
+		t.Fatalf("Search(%q) failed: %v", text, err)
+	}
+	return results.Total, results.Cached
+}
+
+// TestResultCacheHit verifies repeated and equivalent queries are served from the cache
+func TestResultCacheHit(t *testing.T) {
+	engine := newCachedEngine(t, time.Minute)
+
+	if _, cached := searchTotal(t, engine, "revenue"); cached {
+		t.Fatal("Expected the first search to miss the cache")
+	}
+	if total, cached := searchTotal(t, engine, "revenue"); !cached || total != 1 {
+		t.Errorf("Repeat search: total=%d cached=%v, want 1 cached", total, cached)
+	}
+
+	results, _ := engine.Search(context.Background(), Query{Text: "  Revenue ", Size: 10})
+	if !results.Cached || results.Query != "  Revenue " {
+		t.Errorf("Expected an equivalent query to hit the cache echoing its text, got cached=%v query=%q", results.Cached, results.Query)
+	}
+
+	// Modifying returned hits must not affect the cache
+	results.Hits[0].Title = "changed"
+	again, _ := engine.Search(context.Background(), Query{Text: "revenue"})
+	if again.Hits[0].Title != "Quarterly revenue report" {
+		t.Errorf("Cached hit was modified: %q", again.Hits[0].Title)
+	}
+
+	if _, cached := searchTotal(t, engine, "report"); cached {
+		t.Error("Expected a different query to miss the cache")
+	}
+}
+
+// TestResultCacheDeepCopy verifies nothing reachable from returned results
+// is shared with the cache
+func TestResultCacheDeepCopy(t *testing.T) {
+	engine := newCachedEngine(t, time.Minute)
+	query := Query{Text: "revenue", Facets: []string{"tags"}, Aggregations: []Aggregation{{Field: "pages", Op: AggSum}}}
+
+	for i := 0; i < 2; i++ {
+		results, err := engine.Search(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		hit := results.Hits[0]
+		if hit.Tags[0] != "finance" || hit.Metadata["owner"].(map[string]interface{})["team"] != "finance" {
+			t.Fatalf("Search %d: hit was modified through an earlier result: %+v", i, hit)
+		}
+		if results.Facets["tags"][0].Count != 1 || len(results.Aggregations) != 1 || results.Aggregations[0].Count != 1 {
+			t.Fatalf("Search %d: facets or aggregations were modified: %+v %+v", i, results.Facets, results.Aggregations)
+		}
+
+		// Scribble over everything a caller could reach
+		hit.Tags[0] = "changed"
+		hit.Metadata["owner"].(map[string]interface{})["team"] = "changed"
+		results.Facets["tags"][0].Count = 99
+		results.Aggregations[0].Count = 99
+	}
+}
+
+// TestResultCacheInvalidation verifies every write clears the cache
+func TestResultCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		write func(engine *InMemoryEngine) error
+		want  int
+	}{
+		{"Index", func(engine *InMemoryEngine) error {
+			return engine.Index(ctx, Document{ID: "2", Title: "Annual revenue"})
+		}, 2},
+		{"Delete", func(engine *InMemoryEngine) error {
+			return engine.Delete(ctx, "1")
+		}, 0},
+		{"UpdateDocument", func(engine *InMemoryEngine) error {
+			return engine.UpdateDocument(ctx, "1", map[string]interface{}{"title": "Quarterly costs"})
+		}, 0},
+		{"DeleteIndex", func(engine *InMemoryEngine) error {
+			return engine.DeleteIndex(ctx, "default")
+		}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newCachedEngine(t, time.Minute)
+			searchTotal(t, engine, "revenue")
+
+			if err := tt.write(engine); err != nil {
+				t.Fatalf("%s failed: %v", tt.name, err)
+			}
+			total, cached := searchTotal(t, engine, "revenue")
+			if cached || total != tt.want {
+				t.Errorf("After %s: total=%d cached=%v, want %d uncached", tt.name, total, cached, tt.want)
+			}
+		})
+	}
+}
+
+// TestResultCacheTTL verifies cached results expire
+func TestResultCacheTTL(t *testing.T) {
+	engine := newCachedEngine(t, 50*time.Millisecond)
+	searchTotal(t, engine, "revenue")
+
+	if _, cached := searchTotal(t, engine, "revenue"); !cached {
+		t.Error("Expected results within the TTL to be cached")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, cached := searchTotal(t, engine, "revenue"); cached {
+		t.Error("Expected results past the TTL to be recomputed")
+	}
+}
+
+// TestResultCacheDisabledByDefault verifies engines cache nothing unless configured
+func TestResultCacheDisabledByDefault(t *testing.T) {
+	engine := NewInMemoryEngine()
+	if err := engine.Index(context.Background(), Document{ID: "1", Title: "revenue"}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	searchTotal(t, engine, "revenue")
+	if _, cached := searchTotal(t, engine, "revenue"); cached {
+		t.Error("Expected no caching without Config.ResultCacheSize")
+	}
+}
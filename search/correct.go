@@ -0,0 +1,155 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"strings"
+)
+
+// correctionRatio is how many times more documents a neighbouring term must
+// appear in before it replaces a query term that is itself indexed
+const correctionRatio = 10
+
+// termDistance pairs a term with its edit distance from a query term
+type termDistance struct {
+	termCount
+	distance int
+}
+
+// Correct suggests a spelling correction for query, for a "did you mean"
+// prompt when a search returns nothing. Each query term that is not in the
+// index is replaced with the closest indexed term (edit distance with
+// transpositions, at most 1 for terms up to 5 letters and 2 beyond), the
+// most frequent one winning ties. An indexed term is only replaced when a
+// neighbour one edit away appears in at least correctionRatio times as many
+// documents. Terms shorter than 3 letters are never corrected.
+//
+// The suggestion is the lowercase, space-separated corrected query. ok is
+// false when no term changed.
+func (e *InMemoryEngine) Correct(ctx context.Context, query string) (suggestion string, ok bool) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return "", false
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	changed := false
+	for i, term := range terms {
+		if best, ok := e.correctTermLocked(term); ok {
+			terms[i] = best
+			changed = true
+		}
+	}
+	if !changed {
+		return "", false
+	}
+	return strings.Join(terms, " "), true
+}
+
+// correctTermLocked returns the replacement for a single term. The caller
+// must hold e.mu.
+func (e *InMemoryEngine) correctTermLocked(term string) (string, bool) {
+	length := len([]rune(term))
+	if length < 3 {
+		return "", false
+	}
+	maxDistance := 1
+	if length > 5 {
+		maxDistance = 2
+	}
+
+	freq := e.terms.frequency(term)
+	if freq > 0 {
+		maxDistance = 1
+	}
+
+	var best termDistance
+	for _, c := range e.terms.within(term, maxDistance) {
+		if c.distance == 0 || (freq > 0 && c.freq < freq*correctionRatio) {
+			continue
+		}
+		if best.term == "" || c.distance < best.distance ||
+			(c.distance == best.distance && (c.freq > best.freq || (c.freq == best.freq && c.term < best.term))) {
+			best = c
+		}
+	}
+	return best.term, best.term != ""
+}
+
+// frequency returns the document frequency of term, 0 if it is not indexed
+func (t *termTrie) frequency(term string) int {
+	node := t.root
+	for _, r := range term {
+		child, ok := node.children[r]
+		if !ok {
+			return 0
+		}
+		node = child
+	}
+	return node.freq
+}
+
+// within returns indexed terms at most maxDistance edits from term, where
+// an edit is an insertion, deletion, substitution or transposition of two
+// adjacent letters. The trie is walked once, computing one row of the edit
+// distance table per node and pruning branches that cannot get closer.
+func (t *termTrie) within(term string, maxDistance int) []termDistance {
+	target := []rune(term)
+	first := make([]int, len(target)+1)
+	for i := range first {
+		first[i] = i
+	}
+
+	var results []termDistance
+	var walk func(n *trieNode, word []rune, prev, prevPrev []int)
+	walk = func(n *trieNode, word []rune, prev, prevPrev []int) {
+		r := word[len(word)-1]
+		row := make([]int, len(target)+1)
+		row[0] = prev[0] + 1
+		rowMin := row[0]
+		for i := 1; i <= len(target); i++ {
+			cost := 1
+			if target[i-1] == r {
+				cost = 0
+			}
+			row[i] = min(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+			if prevPrev != nil && i > 1 && target[i-1] == word[len(word)-2] && target[i-2] == r {
+				row[i] = min(row[i], prevPrev[i-2]+1)
+			}
+			rowMin = min(rowMin, row[i])
+		}
+
+		if n.freq > 0 && row[len(target)] <= maxDistance {
+			results = append(results, termDistance{
+				termCount: termCount{term: string(word), freq: n.freq},
+				distance:  row[len(target)],
+			})
+		}
+		// Distances only grow along a branch, so stop once every cell is too far
+		if rowMin <= maxDistance {
+			for next, child := range n.children {
+				walk(child, append(word, next), row, prev)
+			}
+		}
+	}
+
+	for r, child := range t.root.children {
+		walk(child, []rune{r}, first, nil)
+	}
+	return results
+}
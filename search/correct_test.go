@@ -0,0 +1,125 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func newCorrectEngine(t *testing.T) *InMemoryEngine {
+	t.Helper()
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+
+	docs := []Document{
+		{ID: "1", Title: "Programming in Go", Content: "Concurrency and channels"},
+		{ID: "2", Title: "Go programming patterns", Content: "Concurrency in practice"},
+		{ID: "3", Title: "Programming languages", Content: "From assembly to Go"},
+		{ID: "4", Title: "Progressive web apps", Content: "Offline first"},
+		{ID: "5", Title: "Database design", Content: "Indexes and normalization"},
+	}
+	// "form" is rare and "from" common, for the frequency rule
+	for i := 0; i < 12; i++ {
+		docs = append(docs, Document{ID: fmt.Sprintf("f%d", i), Title: "Notes", Content: "from the archive"})
+	}
+	docs = append(docs, Document{ID: "form", Title: "Contact form"})
+
+	for _, doc := range docs {
+		if err := engine.Index(ctx, doc); err != nil {
+			t.Fatalf("Index(%s) failed: %v", doc.ID, err)
+		}
+	}
+	return engine
+}
+
+func TestCorrect(t *testing.T) {
+	engine := newCorrectEngine(t)
+
+	tests := []struct {
+		name   string
+		query  string
+		want   string
+		wantOK bool
+	}{
+		{"Substitution", "programmimg", "programming", true},
+		{"Transposition", "cnocurrency", "concurrency", true},
+		{"Deletion", "databse", "database", true},
+		{"Two edits in a long term", "progamming", "programming", true},
+		{"Mixed case and multiple terms", "Go Concurency PATERNS", "go concurrency patterns", true},
+		{"Valid query", "go programming", "", false},
+		{"Too far from any term", "xylophone", "", false},
+		{"Short terms are not corrected", "gp", "", false},
+		{"Rare indexed term replaced by a common neighbour", "form", "from", true},
+		{"Indexed term without a dominant neighbour", "design", "", false},
+		{"Empty query", "  ", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := engine.Correct(context.Background(), tt.query)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Correct(%q) = %q, %v; want %q, %v", tt.query, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCorrectPrefersFrequentTerms(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	for i, title := range []string{"cart", "cart", "card"} {
+		if err := engine.Index(ctx, Document{ID: fmt.Sprint(i), Title: title}); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+
+	// "carx" is one edit from both; the more frequent term wins
+	if got, ok := engine.Correct(ctx, "carx"); got != "cart" || !ok {
+		t.Errorf("Correct(carx) = %q, %v; want cart", got, ok)
+	}
+
+	// Deleted documents no longer contribute terms
+	for _, id := range []string{"0", "1"} {
+		if err := engine.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+	if got, _ := engine.Correct(ctx, "carx"); got != "card" {
+		t.Errorf("Correct(carx) after delete = %q, want card", got)
+	}
+}
+
+func TestTermTrieWithin(t *testing.T) {
+	trie := newTermTrie()
+	for _, term := range []string{"the", "then", "they", "ten", "hte", "tea"} {
+		trie.add(term)
+	}
+
+	got := make(map[string]int)
+	for _, m := range trie.within("teh", 1) {
+		got[m.term] = m.distance
+	}
+	want := map[string]int{"the": 1, "ten": 1, "tea": 1}
+	if len(got) != len(want) {
+		t.Fatalf("within(teh, 1) = %v, want %v", got, want)
+	}
+	for term, d := range want {
+		if got[term] != d {
+			t.Errorf("distance(teh, %s) = %d, want %d", term, got[term], d)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// SortGeoDistance orders results by distance from Query.GeoFilter
+const SortGeoDistance = "geo_distance"
+
+// earthRadiusKm is the mean Earth radius used by the haversine formula
+const earthRadiusKm = 6371.0
+
+// GeoFilter restricts results to documents within RadiusKm of a point.
+// Document coordinates are read from the "lat" and "lon" metadata keys
+// ("latitude", "longitude" and "lng" are also accepted); documents without
+// coordinates are excluded. A RadiusKm of zero keeps every document that has
+// coordinates, which is useful together with a geo_distance sort.
+type GeoFilter struct {
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km,omitempty"`
+}
+
+// validate checks the filter's center and radius
+func (g *GeoFilter) validate() error {
+	if math.IsNaN(g.Lat) || g.Lat < -90 || g.Lat > 90 {
+		return fmt.Errorf("invalid geo filter latitude %v", g.Lat)
+	}
+	if math.IsNaN(g.Lon) || g.Lon < -180 || g.Lon > 180 {
+		return fmt.Errorf("invalid geo filter longitude %v", g.Lon)
+	}
+	if math.IsNaN(g.RadiusKm) || g.RadiusKm < 0 {
+		return fmt.Errorf("invalid geo filter radius %v", g.RadiusKm)
+	}
+	return nil
+}
+
+// distanceTo returns the distance in km from the filter center to doc, and
+// false when the document has no usable coordinates.
+func (g *GeoFilter) distanceTo(doc *Document) (float64, bool) {
+	lat, ok := metadataNumber(doc.Metadata, "lat", "latitude")
+	if !ok || lat < -90 || lat > 90 {
+		return 0, false
+	}
+	lon, ok := metadataNumber(doc.Metadata, "lon", "lng", "longitude")
+	if !ok || lon < -180 || lon > 180 {
+		return 0, false
+	}
+	return haversineKm(g.Lat, g.Lon, lat, lon), true
+}
+
+// metadataNumber returns the first numeric value found under keys
+func metadataNumber(metadata map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		value, ok := metadata[key]
+		if !ok {
+			continue
+		}
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case float32:
+			f = float64(v)
+		case int:
+			f = float64(v)
+		case int64:
+			f = float64(v)
+		case string:
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, false
+			}
+			f = parsed
+		default:
+			return 0, false
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// haversineKm returns the great-circle distance between two points in km
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
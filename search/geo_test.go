@@ -0,0 +1,117 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Paris city hall, used as the query center
+const parisLat, parisLon = 48.8566, 2.3522
+
+func geoEngine(t *testing.T) *InMemoryEngine {
+	t.Helper()
+	engine := NewInMemoryEngine()
+	docs := []Document{
+		{ID: "versailles", Title: "Versailles cafe", Metadata: map[string]interface{}{"lat": 48.8049, "lon": 2.1204}},        // ~17.9 km
+		{ID: "louvre", Title: "Louvre cafe", Metadata: map[string]interface{}{"lat": 48.8606, "lon": 2.3376}},                // ~1.2 km
+		{ID: "montmartre", Title: "Montmartre cafe", Metadata: map[string]interface{}{"latitude": "48.8867", "lng": 2.3431}}, // ~3.4 km
+		{ID: "lyon", Title: "Lyon cafe", Metadata: map[string]interface{}{"lat": 45.7640, "lon": 4.8357}},                    // ~392 km
+		{ID: "online", Title: "Online cafe"},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return engine
+}
+
+func TestHaversineKm(t *testing.T) {
+	// Paris to Lyon is about 392 km
+	if d := haversineKm(parisLat, parisLon, 45.7640, 4.8357); math.Abs(d-392) > 2 {
+		t.Errorf("haversineKm(Paris, Lyon) = %.1f, want about 392", d)
+	}
+	if d := haversineKm(parisLat, parisLon, parisLat, parisLon); d != 0 {
+		t.Errorf("haversineKm(same point) = %v, want 0", d)
+	}
+}
+
+func TestGeoFilter(t *testing.T) {
+	engine := geoEngine(t)
+	nearest := []SortField{{Field: SortGeoDistance, Order: "asc"}}
+
+	tests := []struct {
+		name   string
+		filter GeoFilter
+		sort   []SortField
+		want   []string
+	}{
+		{"within 10km nearest first", GeoFilter{Lat: parisLat, Lon: parisLon, RadiusKm: 10}, nearest, []string{"louvre", "montmartre"}},
+		{"within 20km nearest first", GeoFilter{Lat: parisLat, Lon: parisLon, RadiusKm: 20}, nearest, []string{"louvre", "montmartre", "versailles"}},
+		{"no radius keeps located documents", GeoFilter{Lat: parisLat, Lon: parisLon}, nearest, []string{"louvre", "montmartre", "versailles", "lyon"}},
+		{"farthest first", GeoFilter{Lat: parisLat, Lon: parisLon, RadiusKm: 20}, []SortField{{Field: SortGeoDistance, Order: "desc"}}, []string{"versailles", "montmartre", "louvre"}},
+		{"nothing in range", GeoFilter{Lat: 0, Lon: 0, RadiusKm: 100}, nearest, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			results, err := engine.Search(context.Background(), Query{GeoFilter: &filter, Sort: tt.sort})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			ids := make([]string, len(results.Hits))
+			for i, hit := range results.Hits {
+				ids[i] = hit.ID
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("hits = %v, want %v", ids, tt.want)
+			}
+			if results.Total != len(tt.want) {
+				t.Errorf("Total = %d, want %d", results.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGeoFilterWithText(t *testing.T) {
+	engine := geoEngine(t)
+	query := NewQueryBuilder("cafe").WithGeoFilter(parisLat, parisLon, 5).WithSort(SortGeoDistance, "asc").Build()
+	results, err := engine.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Hits) != 2 || results.Hits[0].ID != "louvre" || results.Hits[1].ID != "montmartre" {
+		t.Errorf("unexpected hits: %+v", results.Hits)
+	}
+}
+
+func TestGeoFilterValidation(t *testing.T) {
+	engine := geoEngine(t)
+	for _, filter := range []GeoFilter{
+		{Lat: 91, Lon: 0},
+		{Lat: 0, Lon: -181},
+		{Lat: 0, Lon: 0, RadiusKm: -1},
+		{Lat: math.NaN(), Lon: 0},
+	} {
+		filter := filter
+		if _, err := engine.Search(context.Background(), Query{GeoFilter: &filter}); err == nil {
+			t.Errorf("expected error for %+v", filter)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// rawTextElements hold content that is not visible text
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// highlightHTML wraps query words in <mark> like highlightMatches, but only
+// inside text nodes: tags, attributes, comments, character references and
+// the content of script and style elements are left untouched. Markup is
+// found with the html tokenizer and copied byte for byte.
+func highlightHTML(content string, queryWords []string) string {
+	re := highlightPattern(queryWords)
+	if re == nil {
+		return content
+	}
+
+	var b strings.Builder
+	b.Grow(len(content))
+	rawUntil := "" // closing tag name while inside a raw text element
+
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		// Copy before Text or TagName, which unescape the buffer in place
+		raw := string(z.Raw())
+
+		switch tt {
+		case html.ErrorToken:
+			// End of input; an unterminated tag or comment is kept as is
+			b.WriteString(raw)
+			return b.String()
+		case html.TextToken:
+			if rawUntil != "" {
+				b.WriteString(raw)
+			} else {
+				b.WriteString(highlightText(raw, string(z.Text()), re))
+			}
+		case html.StartTagToken:
+			b.WriteString(raw)
+			name, _ := z.TagName()
+			if rawUntil == "" && rawTextElements[string(name)] {
+				rawUntil = string(name)
+			}
+		case html.EndTagToken:
+			b.WriteString(raw)
+			if name, _ := z.TagName(); string(name) == rawUntil {
+				rawUntil = ""
+			}
+		default:
+			b.WriteString(raw)
+		}
+	}
+}
+
+// highlightPattern builds one case-insensitive alternation of the query
+// words, longest first, so a single pass never marks inside another mark.
+func highlightPattern(queryWords []string) *regexp.Regexp {
+	words := make([]string, 0, len(queryWords))
+	for _, word := range queryWords {
+		if word != "" {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	return regexp.MustCompile("(?i)" + strings.Join(words, "|"))
+}
+
+// highlightText marks matches in a text node given as its source and its
+// unescaped text. Text without character references is marked in place;
+// otherwise matches are found in the unescaped text, which is escaped
+// again around the marks, so a reference is never split.
+func highlightText(raw, text string, re *regexp.Regexp) string {
+	if raw == text {
+		return re.ReplaceAllString(raw, "<mark>$0</mark>")
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
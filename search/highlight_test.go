@@ -0,0 +1,121 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		words []string
+		want  string
+	}{
+		{
+			name:  "tag names untouched",
+			html:  `<p>An img tag: <img src="a.png"></p>`,
+			words: []string{"img"},
+			want:  `<p>An <mark>img</mark> tag: <img src="a.png"></p>`,
+		},
+		{
+			name:  "attribute values untouched",
+			html:  `<a href="/docs/search" title="search docs">Search here</a>`,
+			words: []string{"search"},
+			want:  `<a href="/docs/search" title="search docs"><mark>Search</mark> here</a>`,
+		},
+		{
+			name:  "quoted > inside attribute",
+			html:  `<span data-x="a>b">b</span>`,
+			words: []string{"b"},
+			want:  `<span data-x="a>b"><mark>b</mark></span>`,
+		},
+		{
+			name:  "script, style and comments untouched",
+			html:  `<style>.go{}</style><script>var go = 1;</script><!-- go --><p>go</p>`,
+			words: []string{"go"},
+			want:  `<style>.go{}</style><script>var go = 1;</script><!-- go --><p><mark>go</mark></p>`,
+		},
+		{
+			name:  "character references untouched",
+			html:  `<p>Tom &amp; Jerry</p>`,
+			words: []string{"amp", "jerry"},
+			want:  `<p>Tom &amp; <mark>Jerry</mark></p>`,
+		},
+		{
+			name:  "matches across character references",
+			html:  `<p>AT&amp;T and Caf&eacute;</p>`,
+			words: []string{"at&t", "café"},
+			want:  `<p><mark>AT&amp;T</mark> and <mark>Café</mark></p>`,
+		},
+		{
+			name:  "unterminated tag kept",
+			html:  `<p>go</p><a href="go`,
+			words: []string{"go"},
+			want:  `<p><mark>go</mark></p><a href="go`,
+		},
+		{
+			name:  "overlapping words in one pass",
+			html:  `<b>marked market</b>`,
+			words: []string{"mark", "market"},
+			want:  `<b><mark>mark</mark>ed <mark>market</mark></b>`,
+		},
+		{
+			name:  "bare less-than is text",
+			html:  `<p>1 < 2 means less</p>`,
+			words: []string{"less"},
+			want:  `<p>1 < 2 means <mark>less</mark></p>`,
+		},
+		{
+			name:  "no words",
+			html:  `<p>text</p>`,
+			words: nil,
+			want:  `<p>text</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightHTML(tt.html, tt.words); got != tt.want {
+				t.Errorf("highlightHTML() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHighlightHTMLDocument(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	engine.Index(ctx, Document{ID: "html", Title: "Images", Content: `<p>Use the img element: <img src="img.png" alt="img"></p>`, HTML: true})
+	engine.Index(ctx, Document{ID: "text", Title: "Plain", Content: "Raw img text"})
+
+	results, err := engine.Search(ctx, Query{Text: "img", Highlight: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, hit := range results.Hits {
+		got[hit.ID] = hit.Content
+	}
+	if want := `<p>Use the <mark>img</mark> element: <img src="img.png" alt="img"></p>`; got["html"] != want {
+		t.Errorf("HTML content = %s\nwant %s", got["html"], want)
+	}
+	if want := "Raw <mark>img</mark> text"; got["text"] != want {
+		t.Errorf("text content = %s, want %s", got["text"], want)
+	}
+}
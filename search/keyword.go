@@ -0,0 +1,81 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keywordSet holds the metadata keys declared in Config.KeywordFields
+type keywordSet map[string]bool
+
+// newKeywordSet builds a keywordSet from field names
+func newKeywordSet(fields []string) keywordSet {
+	set := make(keywordSet, len(fields))
+	for _, field := range fields {
+		set[field] = true
+	}
+	return set
+}
+
+// validate rejects query keywords on fields not declared as keyword fields,
+// so a misspelled field fails instead of silently matching nothing
+func (s keywordSet) validate(keywords map[string]string) error {
+	for field := range keywords {
+		if !s[field] {
+			return fmt.Errorf("%q is not a keyword field", field)
+		}
+	}
+	return nil
+}
+
+// matchesKeywords reports whether every keyword equals the document's
+// metadata value for that field, ignoring case. Multi-valued fields
+// ([]string or []interface{}) match when any element is equal.
+func matchesKeywords(doc *Document, keywords map[string]string) bool {
+	for field, want := range keywords {
+		if !keywordValueMatches(doc.Metadata[field], want) {
+			return false
+		}
+	}
+	return true
+}
+
+// keywordValueMatches compares one metadata value against want
+func keywordValueMatches(value interface{}, want string) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.EqualFold(v, want)
+	case []string:
+		for _, item := range v {
+			if strings.EqualFold(item, want) {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if keywordValueMatches(item, want) {
+				return true
+			}
+		}
+		return false
+	default:
+		return strings.EqualFold(fmt.Sprint(v), want)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func keywordEngine(t *testing.T) *InMemoryEngine {
+	t.Helper()
+	engine := NewInMemoryEngineWithConfig(&Config{KeywordFields: []string{"status", "sku", "labels", "priority"}})
+	docs := []Document{
+		{ID: "t1", Title: "Login fails", Metadata: map[string]interface{}{"status": "open", "sku": "AB-100", "priority": 1}},
+		{ID: "t2", Title: "Login slow", Metadata: map[string]interface{}{"status": "reopened", "sku": "AB-1000"}},
+		{ID: "t3", Title: "Export broken", Metadata: map[string]interface{}{"status": "OPEN", "labels": []interface{}{"Billing", "urgent"}}},
+		{ID: "t4", Title: "Login page typo", Metadata: map[string]interface{}{"status": "closed", "labels": []string{"ui"}}},
+		{ID: "t5", Title: "No metadata"},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return engine
+}
+
+func TestKeywordFields(t *testing.T) {
+	engine := keywordEngine(t)
+
+	tests := []struct {
+		name     string
+		text     string
+		keywords map[string]string
+		want     []string
+	}{
+		{"exact case-insensitive", "", map[string]string{"status": "OPEN"}, []string{"t1", "t3"}},
+		{"no partial match", "", map[string]string{"status": "open"}, []string{"t1", "t3"}},
+		{"full value only", "", map[string]string{"sku": "ab-100"}, []string{"t1"}},
+		{"multi-valued field", "", map[string]string{"labels": "billing"}, []string{"t3"}},
+		{"non-string value", "", map[string]string{"priority": "1"}, []string{"t1"}},
+		{"all keywords must match", "", map[string]string{"status": "open", "sku": "AB-100"}, []string{"t1"}},
+		{"combined with text", "login", map[string]string{"status": "Open"}, []string{"t1"}},
+		{"no match", "", map[string]string{"status": "pending"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := engine.Search(context.Background(), Query{Text: tt.text, Keywords: tt.keywords})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			ids := make([]string, len(results.Hits))
+			for i, hit := range results.Hits {
+				ids[i] = hit.ID
+			}
+			sort.Strings(ids)
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("hits = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeywordFieldsBuilder(t *testing.T) {
+	engine := keywordEngine(t)
+	query := NewQueryBuilder("").WithKeyword("status", "REOPENED").Build()
+	results, err := engine.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Hits) != 1 || results.Hits[0].ID != "t2" {
+		t.Errorf("unexpected hits: %+v", results.Hits)
+	}
+}
+
+func TestKeywordFieldsUndeclared(t *testing.T) {
+	engine := keywordEngine(t)
+	if _, err := engine.Search(context.Background(), Query{Keywords: map[string]string{"state": "open"}}); err == nil {
+		t.Error("expected error for undeclared keyword field")
+	}
+	if _, err := NewInMemoryEngine().Search(context.Background(), Query{Keywords: map[string]string{"status": "open"}}); err == nil {
+		t.Error("expected error when the engine declares no keyword fields")
+	}
+}
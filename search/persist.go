@@ -0,0 +1,183 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/patdeg/common"
+)
+
+// snapshotFormat identifies index snapshots written by SaveIndex
+const snapshotFormat = "patdeg-common-search"
+
+// SnapshotVersion is the current snapshot format version. Version 2 added
+// aliases and lets one ID appear in several indices; version 1 snapshots
+// are a subset and still load. LoadIndex rejects other versions instead of
+// guessing.
+const SnapshotVersion = 2
+
+// indexSnapshot is the on-disk representation of an InMemoryEngine
+type indexSnapshot struct {
+	Format    string              `json:"format"`
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"created_at"`
+	Documents []Document          `json:"documents"`
+	Indices   map[string][]string `json:"indices"` // index -> document IDs
+	Aliases   map[string]string   `json:"aliases,omitempty"`
+}
+
+// SaveIndex writes a snapshot of all documents, index memberships and
+// aliases to w as JSON. Metadata values go through encoding/json, so numbers come back as
+// float64 after LoadIndex.
+func (e *InMemoryEngine) SaveIndex(w io.Writer) error {
+	e.mu.RLock()
+	snap := indexSnapshot{
+		Format:    snapshotFormat,
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Indices:   make(map[string][]string, len(e.indices)),
+	}
+	for index, docs := range e.indices {
+		ids := make([]string, 0, len(docs))
+		for id, doc := range docs {
+			ids = append(ids, id)
+			snap.Documents = append(snap.Documents, *doc)
+		}
+		sort.Strings(ids)
+		snap.Indices[index] = ids
+	}
+	if len(e.aliases) > 0 {
+		snap.Aliases = make(map[string]string, len(e.aliases))
+		for alias, index := range e.aliases {
+			snap.Aliases[alias] = index
+		}
+	}
+	e.mu.RUnlock()
+
+	// Stable ordering keeps snapshots diffable
+	sort.Slice(snap.Documents, func(i, j int) bool {
+		if snap.Documents[i].ID != snap.Documents[j].ID {
+			return snap.Documents[i].ID < snap.Documents[j].ID
+		}
+		return snap.Documents[i].Index < snap.Documents[j].Index
+	})
+
+	if err := json.NewEncoder(w).Encode(&snap); err != nil {
+		return fmt.Errorf("failed to encode index snapshot: %w", err)
+	}
+
+	common.Debug("[SEARCH] Saved snapshot with %d documents", len(snap.Documents))
+	return nil
+}
+
+// LoadIndex replaces the engine contents with a snapshot written by
+// SaveIndex. The engine is left untouched if the snapshot cannot be decoded
+// or was written with an unsupported version.
+func (e *InMemoryEngine) LoadIndex(r io.Reader) error {
+	var snap indexSnapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to decode index snapshot: %w", err)
+	}
+	if snap.Format != snapshotFormat {
+		return fmt.Errorf("not a search index snapshot (format %q)", snap.Format)
+	}
+	if snap.Version < 1 || snap.Version > SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d (expected 1 to %d)", snap.Version, SnapshotVersion)
+	}
+
+	// Documents carry their index; Indices also preserves empty indices
+	indices := make(map[string]map[string]*Document, len(snap.Indices))
+	for index := range snap.Indices {
+		indices[index] = make(map[string]*Document)
+	}
+	for i := range snap.Documents {
+		doc := snap.Documents[i]
+		if doc.ID == "" {
+			return fmt.Errorf("snapshot contains a document without ID")
+		}
+		if doc.Index == "" {
+			doc.Index = "default"
+		}
+		if indices[doc.Index] == nil {
+			indices[doc.Index] = make(map[string]*Document)
+		}
+		indices[doc.Index][doc.ID] = &doc
+	}
+
+	aliases := make(map[string]string, len(snap.Aliases))
+	for alias, index := range snap.Aliases {
+		aliases[alias] = index
+	}
+
+	e.mu.Lock()
+	e.indices = indices
+	e.aliases = aliases
+	// The term dictionary is derived data and is rebuilt rather than stored
+	e.terms = newTermTrie()
+	e.docTerms = make(map[string][]string, len(snap.Documents))
+	e.analyzed = make(map[string]*analyzedDocument, len(snap.Documents))
+	for _, indexDocs := range indices {
+		for _, doc := range indexDocs {
+			e.addTermsLocked(doc)
+		}
+	}
+	e.cache.purge()
+	e.mu.Unlock()
+
+	common.Info("[SEARCH] Loaded snapshot with %d documents in %d indices", len(snap.Documents), len(indices))
+	return nil
+}
+
+// SaveToFile writes a snapshot to path. The snapshot is written to a
+// temporary file first and renamed into place so a crash never leaves a
+// truncated snapshot behind.
+func (e *InMemoryEngine) SaveToFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := e.SaveIndex(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move snapshot into place: %w", err)
+	}
+	return nil
+}
+
+// LoadFromFile loads a snapshot previously written by SaveToFile
+func (e *InMemoryEngine) LoadFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	return e.LoadIndex(f)
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEngine(t *testing.T) *InMemoryEngine {
+	t.Helper()
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	docs := []Document{
+		{ID: "1", Index: "products", Type: "book", Title: "Go Programming", Content: "Learn Go from scratch", Tags: []string{"go", "programming"}, Timestamp: ts},
+		{ID: "2", Index: "products", Type: "book", Title: "Advanced Go", Content: "Concurrency patterns in Go", Tags: []string{"go"}, Timestamp: ts.Add(time.Hour)},
+		{ID: "3", Index: "products", Type: "video", Title: "Python Basics", Content: "Intro to Python, not Go", Timestamp: ts.Add(2 * time.Hour)},
+		{ID: "4", Index: "articles", Title: "Why Go", Content: "Go is simple", Metadata: map[string]interface{}{"author": "jane"}, Timestamp: ts},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(ctx, doc); err != nil {
+			t.Fatalf("Index(%s) failed: %v", doc.ID, err)
+		}
+	}
+	return engine
+}
+
+// searchIDs returns "id:score" pairs sorted by ID. Equal scores have no
+// defined order, so results are compared as sets.
+func searchIDs(t *testing.T, engine *InMemoryEngine, query Query) []string {
+	t.Helper()
+	results, err := engine.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	ids := make([]string, len(results.Hits))
+	for i, hit := range results.Hits {
+		ids[i] = fmt.Sprintf("%s:%g", hit.ID, hit.Score)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSaveLoadIndex(t *testing.T) {
+	original := newTestEngine(t)
+
+	var buf bytes.Buffer
+	if err := original.SaveIndex(&buf); err != nil {
+		t.Fatalf("SaveIndex failed: %v", err)
+	}
+
+	restored := NewInMemoryEngine()
+	if err := restored.LoadIndex(&buf); err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+
+	queries := []Query{
+		{Text: "go"},
+		{Text: "go", Index: "products"},
+		{Text: "python", Type: "video"},
+		{Tags: []string{"go"}},
+		{Index: "articles"},
+	}
+	for _, q := range queries {
+		want := searchIDs(t, original, q)
+		got := searchIDs(t, restored, q)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("query %+v: got %v, want %v", q, got, want)
+		}
+	}
+
+	doc, err := restored.GetDocument(context.Background(), "4")
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if doc.Metadata["author"] != "jane" {
+		t.Errorf("metadata not restored: %v", doc.Metadata)
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	original := newTestEngine(t)
+	path := filepath.Join(t.TempDir(), "index.json")
+
+	if err := original.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	restored := NewInMemoryEngine()
+	if err := restored.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	want := searchIDs(t, original, Query{Text: "go"})
+	got := searchIDs(t, restored, Query{Text: "go"})
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLoadIndexVersion1(t *testing.T) {
+	engine := NewInMemoryEngine()
+	snapshot := `{"format":"patdeg-common-search","version":1,
+		"documents":[{"id":"1","index":"docs","title":"Go basics"}],
+		"indices":{"docs":["1"]}}`
+	if err := engine.LoadIndex(strings.NewReader(snapshot)); err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	if doc, err := engine.GetDocument(context.Background(), "1"); err != nil || doc.Index != "docs" {
+		t.Errorf("GetDocument = %+v, %v, want the document in docs", doc, err)
+	}
+	if ids := searchIDs(t, engine, Query{Text: "go"}); len(ids) != 1 {
+		t.Errorf("got %v, want one hit", ids)
+	}
+}
+
+func TestLoadIndexRejectsBadSnapshots(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "garbage"},
+		{"wrong format", `{"format":"other","version":1}`},
+		{"future version", `{"format":"patdeg-common-search","version":99}`},
+		{"missing version", `{"format":"patdeg-common-search"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(t)
+			if err := engine.LoadIndex(strings.NewReader(tt.data)); err == nil {
+				t.Fatal("expected error")
+			}
+			// A failed load must not wipe the existing index
+			if ids := searchIDs(t, engine, Query{Text: "go"}); len(ids) == 0 {
+				t.Error("engine contents lost after failed load")
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// fieldSet selects which document fields take part in scoring
+type fieldSet struct {
+	title, content, tags bool
+}
+
+// allFields is used when a query does not restrict its fields
+var allFields = fieldSet{title: true, content: true, tags: true}
+
+// parseFields converts Query.Fields into a fieldSet. An empty list selects
+// every field.
+func parseFields(fields []string) (fieldSet, error) {
+	if len(fields) == 0 {
+		return allFields, nil
+	}
+	var set fieldSet
+	for _, field := range fields {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case FieldTitle:
+			set.title = true
+		case FieldContent:
+			set.content = true
+		case FieldTags:
+			set.tags = true
+		default:
+			return fieldSet{}, fmt.Errorf("unknown search field %q", field)
+		}
+	}
+	return set, nil
+}
+
+// ScoringConfig holds the relevance weights used by InMemoryEngine.
+//
+// Each occurrence of a query word in the title or content adds TitleBoost or
+// ContentBoost to the score, and each tag containing the word adds TagBoost.
+// A zero boost ignores that field. When the whole query appears as a phrase,
+// the score is multiplied by PhraseBoost for a title match, or by half the
+// extra boost (1 + (PhraseBoost-1)/2) for a content match. A PhraseBoost of
+// 1 or less disables the phrase bonus.
+//
+// ProximityBoost rewards documents whose query words sit close together
+// even when they do not form the exact phrase. For queries of two or more
+// words, the score is multiplied by 1 + (ProximityBoost-1) * n/span, where
+// n is the number of distinct query words and span is the length in words
+// of the shortest stretch of the title or content containing all of them.
+// Adjacent words get the full boost and words paragraphs apart almost none.
+// It applies on top of PhraseBoost; 1 or less (the default) disables it.
+type ScoringConfig struct {
+	TitleBoost     float64
+	ContentBoost   float64
+	TagBoost       float64
+	PhraseBoost    float64
+	ProximityBoost float64
+}
+
+// DefaultScoringConfig returns the weights used when none are configured
+func DefaultScoringConfig() *ScoringConfig {
+	return &ScoringConfig{
+		TitleBoost:   2.0,
+		ContentBoost: 1.0,
+		TagBoost:     1.5,
+		PhraseBoost:  2.0,
+	}
+}
+
+// score computes the relevance of doc for the lowercased query words,
+// looking only at the selected fields
+func (s ScoringConfig) score(doc *Document, queryWords []string, fields fieldSet) float64 {
+	score := 0.0
+
+	// Fields outside the set are treated as empty
+	titleLower, contentLower := "", ""
+	if fields.title {
+		titleLower = strings.ToLower(doc.Title)
+	}
+	if fields.content {
+		contentLower = strings.ToLower(doc.Content)
+	}
+
+	for _, word := range queryWords {
+		// Title matches (weighted higher by default)
+		titleCount := strings.Count(titleLower, word)
+		score += float64(titleCount) * s.TitleBoost
+
+		// Content matches
+		contentCount := strings.Count(contentLower, word)
+		score += float64(contentCount) * s.ContentBoost
+
+		// Tag matches
+		if fields.tags {
+			for _, tag := range doc.Tags {
+				if strings.Contains(strings.ToLower(tag), word) {
+					score += s.TagBoost
+				}
+			}
+		}
+	}
+
+	// Boost for exact phrase match
+	if s.PhraseBoost > 1 {
+		fullQuery := strings.Join(queryWords, " ")
+		if strings.Contains(titleLower, fullQuery) {
+			score *= s.PhraseBoost
+		} else if strings.Contains(contentLower, fullQuery) {
+			score *= 1 + (s.PhraseBoost-1)/2
+		}
+	}
+
+	if s.ProximityBoost > 1 {
+		contains := strings.Contains
+		closeness := max(proximity(tokenize(titleLower), queryWords, contains),
+			proximity(tokenize(contentLower), queryWords, contains))
+		score *= 1 + (s.ProximityBoost-1)*closeness
+	}
+
+	return score
+}
+
+// proximity returns n/span for the shortest window of terms in which every
+// one of the n distinct query words matches some term, or 0 when a word is
+// missing or there are fewer than two words. match reports whether a
+// document term matches a query word.
+func proximity(terms, words []string, match func(term, word string) bool) float64 {
+	distinct := make([]string, 0, len(words))
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			distinct = append(distinct, word)
+		}
+	}
+	if len(distinct) < 2 {
+		return 0
+	}
+
+	// matches[i] lists the query words matched by terms[i]
+	matches := make([][]int, len(terms))
+	for i, term := range terms {
+		for w, word := range distinct {
+			if match(term, word) {
+				matches[i] = append(matches[i], w)
+			}
+		}
+	}
+
+	// Sliding window: grow on the right, shrink from the left while every
+	// word stays covered
+	counts := make([]int, len(distinct))
+	covered, left, best := 0, 0, 0
+	for right := range terms {
+		for _, w := range matches[right] {
+			if counts[w] == 0 {
+				covered++
+			}
+			counts[w]++
+		}
+		for covered == len(distinct) {
+			if span := right - left + 1; best == 0 || span < best {
+				best = span
+			}
+			for _, w := range matches[left] {
+				counts[w]--
+				if counts[w] == 0 {
+					covered--
+				}
+			}
+			left++
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+	// One term can match several words, so the span may be shorter than n
+	return min(1, float64(len(distinct))/float64(best))
+}
+
+// recencyFactor returns 0.5^(age/halfLife) for a document stamped ts.
+// Documents dated in the future count as new; documents without a
+// timestamp decay to 0 and rank after every dated match.
+func recencyFactor(ts, now time.Time, halfLife time.Duration) float64 {
+	if ts.IsZero() {
+		return 0
+	}
+	age := now.Sub(ts)
+	if age <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(age) / float64(halfLife))
+}
@@ -0,0 +1,318 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scoringEngine(t *testing.T, config *Config) *InMemoryEngine {
+	t.Helper()
+	engine := NewInMemoryEngineWithConfig(config)
+	docs := []Document{
+		{ID: "title", Title: "Golang", Content: "A language overview"},
+		{ID: "content", Title: "Overview", Content: "golang tips, golang tools and golang idioms"},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return engine
+}
+
+func hitIDs(t *testing.T, engine *InMemoryEngine, text string) []string {
+	t.Helper()
+	results, err := engine.Search(context.Background(), Query{Text: text})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	ids := make([]string, len(results.Hits))
+	for i, hit := range results.Hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestScoringConfigTitleBoost(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   []string
+	}{
+		// Three content matches (4.5) outrank one title match (4.0)
+		{"defaults", nil, []string{"content", "title"}},
+		{"title boosted", &Config{Scoring: &ScoringConfig{TitleBoost: 4, ContentBoost: 1, TagBoost: 1.5, PhraseBoost: 2}}, []string{"title", "content"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hitIDs(t, scoringEngine(t, tt.config), "golang")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoringConfigScore(t *testing.T) {
+	doc := &Document{Title: "Go patterns", Content: "go patterns in go", Tags: []string{"golang"}}
+	words := []string{"go", "patterns"}
+
+	tests := []struct {
+		name    string
+		scoring ScoringConfig
+		want    float64
+	}{
+		// go: title 1, content 2, tag 1; patterns: title 1, content 1; title phrase
+		{"defaults", *DefaultScoringConfig(), (2*2 + 3*1 + 1.5) * 2},
+		{"content ignored", ScoringConfig{TitleBoost: 2, TagBoost: 1.5, PhraseBoost: 2}, (2*2 + 1.5) * 2},
+		{"phrase disabled", ScoringConfig{TitleBoost: 2, ContentBoost: 1, TagBoost: 1.5, PhraseBoost: 1}, 2*2 + 3*1 + 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scoring.score(doc, words, allFields); got != tt.want {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Content phrase matches get half the extra phrase boost
+	contentOnly := &Document{Title: "Other", Content: "go patterns"}
+	if got := DefaultScoringConfig().score(contentOnly, words, allFields); got != 2*1.5 {
+		t.Errorf("content phrase score = %v, want 3", got)
+	}
+}
+
+func TestQueryFields(t *testing.T) {
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+	docs := []Document{
+		{ID: "title", Title: "Kubernetes guide", Content: "Deploying services"},
+		{ID: "content", Title: "Cluster notes", Content: "Running kubernetes in production"},
+		{ID: "tag", Title: "Ops", Content: "Runbooks", Tags: []string{"kubernetes"}},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(ctx, doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []string
+	}{
+		{"all fields by default", nil, []string{"content", "tag", "title"}},
+		{"title only", []string{FieldTitle}, []string{"title"}},
+		{"content only", []string{"content"}, []string{"content"}},
+		{"title and tags", []string{"Title", "tags"}, []string{"tag", "title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := NewQueryBuilder("kubernetes").WithFields(tt.fields...).Build()
+			results, err := engine.Search(ctx, query)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var got []string
+			for _, hit := range results.Hits {
+				got = append(got, hit.ID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := engine.Search(ctx, Query{Text: "kubernetes", Fields: []string{"body"}}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestProximity(t *testing.T) {
+	contains := strings.Contains
+	tests := []struct {
+		name  string
+		text  string
+		words []string
+		want  float64
+	}{
+		{"adjacent", "report credit card fraud today", []string{"credit", "card", "fraud"}, 1},
+		{"any order", "fraud on the card credit", []string{"credit", "card", "fraud"}, 3.0 / 5},
+		{"tightest window wins", "credit x x x card fraud then credit card y fraud", []string{"credit", "card", "fraud"}, 3.0 / 4},
+		{"missing word", "credit card", []string{"credit", "card", "fraud"}, 0},
+		{"single word", "credit card", []string{"credit"}, 0},
+		{"repeated word", "credit report card", []string{"credit", "credit", "card"}, 2.0 / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proximity(tokenize(tt.text), tt.words, contains); got != tt.want {
+				t.Errorf("proximity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoringConfigProximityBoost(t *testing.T) {
+	scattered := "Credit limits are reviewed yearly. " + strings.Repeat("Unrelated filler text. ", 30) +
+		"Report a lost card at any branch. " + strings.Repeat("More filler here. ", 30) + "Fraud alerts are sent by text."
+	docs := []Document{
+		{ID: "scattered", Title: "Bank FAQ", Content: scattered},
+		{ID: "near", Title: "Bank FAQ", Content: "How we detect credit card and wire fraud quickly."},
+	}
+
+	tests := []struct {
+		name      string
+		proximity float64
+	}{
+		// Both documents contain each word once, so only proximity separates them
+		{"disabled", 0},
+		{"enabled", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoring := DefaultScoringConfig()
+			scoring.ProximityBoost = tt.proximity
+			engine := NewInMemoryEngineWithConfig(&Config{Scoring: scoring})
+			for _, doc := range docs {
+				if err := engine.Index(context.Background(), doc); err != nil {
+					t.Fatalf("Index failed: %v", err)
+				}
+			}
+
+			results, err := engine.Search(context.Background(), Query{Text: "credit card fraud"})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results.Hits) != 2 {
+				t.Fatalf("hits = %d, want 2", len(results.Hits))
+			}
+			near, far := results.Hits[0], results.Hits[1]
+			if tt.proximity == 0 {
+				if near.Score != far.Score {
+					t.Errorf("scores = %v and %v, want equal without proximity", near.Score, far.Score)
+				}
+				return
+			}
+			if near.ID != "near" || near.Score <= far.Score {
+				t.Errorf("hits = %s (%v), %s (%v), want near ranked first", near.ID, near.Score, far.ID, far.Score)
+			}
+		})
+	}
+}
+
+func TestScoreAnalyzedProximityBoost(t *testing.T) {
+	analyzer := EnglishAnalyzer()
+	scoring := DefaultScoringConfig()
+	scoring.PhraseBoost = 1
+	scoring.ProximityBoost = 2
+	query := analyzer.Analyze("credit card fraud")
+
+	adjacent := &analyzedDocument{content: analyzer.Analyze("Stopping credit card fraud")}
+	scattered := &analyzedDocument{content: analyzer.Analyze("Credit scores, then a long story about a card, then finally fraud")}
+
+	if got := scoring.scoreAnalyzed(adjacent, query, allFields); got != 3*2 {
+		t.Errorf("adjacent score = %v, want 6", got)
+	}
+	if near, far := scoring.scoreAnalyzed(adjacent, query, allFields), scoring.scoreAnalyzed(scattered, query, allFields); near <= far {
+		t.Errorf("adjacent score %v should exceed scattered score %v", near, far)
+	}
+}
+
+func TestRecencyHalfLife(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	docs := []Document{
+		{ID: "old", Title: "Rate decision", Content: "Central bank holds rates", Timestamp: now.Add(-72 * time.Hour)},
+		{ID: "new", Title: "Rate decision", Content: "Central bank holds rates", Timestamp: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		halfLife time.Duration
+		wantTop  string
+	}{
+		{"time-neutral by default", 0, ""},
+		{"decay enabled", 24 * time.Hour, "new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewInMemoryEngine()
+			for _, doc := range docs {
+				if err := engine.Index(ctx, doc); err != nil {
+					t.Fatalf("Index failed: %v", err)
+				}
+			}
+
+			results, err := engine.Search(ctx, NewQueryBuilder("bank rates").WithRecency(tt.halfLife).Build())
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results.Hits) != 2 {
+				t.Fatalf("hits = %d, want 2", len(results.Hits))
+			}
+			first, second := results.Hits[0], results.Hits[1]
+			if tt.wantTop == "" {
+				if first.Score != second.Score {
+					t.Errorf("scores = %v and %v, want equal", first.Score, second.Score)
+				}
+				return
+			}
+			if first.ID != tt.wantTop || first.Score <= second.Score {
+				t.Errorf("hits = %s (%v), %s (%v), want %s first", first.ID, first.Score, second.ID, second.Score, tt.wantTop)
+			}
+			// Three half-lives apart: the older document keeps about an eighth
+			if ratio := second.Score / first.Score; math.Abs(ratio-0.125*math.Exp2(1.0/24)) > 0.01 {
+				t.Errorf("score ratio = %v, want about 1/8", ratio)
+			}
+		})
+	}
+}
+
+func TestRecencyFactor(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		ts   time.Time
+		want float64
+	}{
+		{"now", now, 1},
+		{"future", now.Add(time.Hour), 1},
+		{"one half-life", now.Add(-24 * time.Hour), 0.5},
+		{"two half-lives", now.Add(-48 * time.Hour), 0.25},
+		{"no timestamp", time.Time{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recencyFactor(tt.ts, now, 24*time.Hour); got != tt.want {
+				t.Errorf("recencyFactor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
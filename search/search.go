@@ -47,6 +47,9 @@ type Engine interface {
 
 	// UpdateDocument partially updates a document
 	UpdateDocument(ctx context.Context, id string, updates map[string]interface{}) error
+
+	// Suggest returns indexed terms starting with prefix for autocomplete
+	Suggest(ctx context.Context, prefix string, limit int) ([]string, error)
 }
 
 // Document represents a searchable document
@@ -56,6 +59,8 @@ type Document struct {
 	Type      string                 `json:"type,omitempty"`
 	Title     string                 `json:"title"`
 	Content   string                 `json:"content"`
+	HTML      bool                   `json:"html,omitempty"`     // Content is HTML; highlighting leaves markup intact
+	Language  string                 `json:"language,omitempty"` // Language code such as "en" selecting the analyzer
 	Tags      []string               `json:"tags,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Timestamp time.Time              `json:"timestamp"`
@@ -74,8 +79,27 @@ type Query struct {
 	Sort      []SortField            `json:"sort,omitempty"`
 	Highlight bool                   `json:"highlight"`
 	Facets    []string               `json:"facets,omitempty"`
+	Fields    []string               `json:"fields,omitempty"` // Restrict text matching to FieldTitle, FieldContent, FieldTags
+	GeoFilter *GeoFilter             `json:"geo_filter,omitempty"`
+	Keywords  map[string]string      `json:"keywords,omitempty"` // Exact, case-insensitive match on Config.KeywordFields
+	Language  string                 `json:"language,omitempty"` // Analyzer for Text; empty analyzes it like each document
+
+	Aggregations []Aggregation `json:"aggregations,omitempty"` // Numeric statistics over metadata fields
+
+	// RecencyHalfLife boosts newer documents for news-like content: text
+	// scores are multiplied by 0.5^(age/RecencyHalfLife), where age is the
+	// time since Document.Timestamp, so a document one half-life old counts
+	// half as much as a new one. Zero keeps ranking time-neutral.
+	RecencyHalfLife time.Duration `json:"recency_half_life,omitempty"`
 }
 
+// Searchable fields accepted in Query.Fields
+const (
+	FieldTitle   = "title"
+	FieldContent = "content"
+	FieldTags    = "tags"
+)
+
 // SortField defines sorting criteria
 type SortField struct {
 	Field string `json:"field"`
@@ -89,6 +113,9 @@ type Results struct {
 	Facets map[string][]FacetItem `json:"facets,omitempty"`
 	Took   time.Duration          `json:"took"`
 	Query  string                 `json:"query"`
+	Cached bool                   `json:"cached,omitempty"` // Served from the result cache, see Config.ResultCacheSize
+
+	Aggregations []AggregationResult `json:"aggregations,omitempty"` // In Query.Aggregations order
 }
 
 // FacetItem represents a facet value and count
@@ -97,22 +124,112 @@ type FacetItem struct {
 	Count int    `json:"count"`
 }
 
-// InMemoryEngine implements an in-memory search engine
+// InMemoryEngine implements an in-memory search engine. A document is
+// identified by its index and ID, so the same ID may exist in several
+// indices, e.g. while an index is rebuilt behind an alias. Calls that take
+// only an ID see one logical document: unscoped searches and GetDocument
+// return the most recently indexed copy, while UpdateDocument and Delete
+// apply to every copy. Scope queries with Query.Index (or an alias) to see
+// a single index.
 type InMemoryEngine struct {
-	documents map[string]*Document
-	indices   map[string]map[string]*Document // index -> id -> document
-	mu        sync.RWMutex
+	indices  map[string]map[string]*Document // index -> id -> document
+	aliases  map[string]string               // alias -> index
+	terms    *termTrie                       // term dictionary for Suggest
+	docTerms map[string][]string             // docKey -> unique terms
+	analyzed map[string]*analyzedDocument    // docKey -> analyzed fields
+	scoring  ScoringConfig
+	keywords keywordSet
+	mu       sync.RWMutex
+
+	analyzers       map[string]*Analyzer // see Config.Analyzers
+	defaultLanguage string
+
+	cache *resultCache // nil unless Config.ResultCacheSize is set
+}
+
+// Config holds the tunable settings of an InMemoryEngine
+type Config struct {
+	// Scoring sets the relevance weights. Nil uses DefaultScoringConfig.
+	Scoring *ScoringConfig
+
+	// KeywordFields lists metadata keys holding exact values such as a SKU
+	// or status. Query.Keywords filters on them with a whole-value,
+	// case-insensitive comparison instead of term scoring, so "open"
+	// matches "OPEN" but not "reopened".
+	KeywordFields []string
+
+	// Analyzers maps language codes to the analyzer applied to documents
+	// and queries in that language, removing stop words and stemming.
+	// Nil uses DefaultAnalyzers (English and French); an empty map
+	// disables analysis.
+	Analyzers map[string]*Analyzer
+
+	// DefaultLanguage is assumed for documents without a Language. When
+	// empty, such documents, and documents in a language without an
+	// analyzer, keep plain case-insensitive substring matching.
+	DefaultLanguage string
+
+	// ResultCacheSize enables caching of Search results for up to this
+	// many distinct queries, for dashboards repeating the same searches.
+	// Any Index, Delete, UpdateDocument, DeleteIndex, alias change or
+	// LoadIndex clears the cache. Cached results are marked with
+	// Results.Cached. Zero disables caching, so every Search reflects the
+	// current documents and, with Query.RecencyHalfLife, the current time.
+	ResultCacheSize int
+
+	// ResultCacheTTL bounds how long results are served from the cache.
+	// Defaults to DefaultResultCacheTTL.
+	ResultCacheTTL time.Duration
+}
+
+// DefaultConfig returns the default engine configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Scoring: DefaultScoringConfig(),
+	}
 }
 
 // NewInMemoryEngine creates a new in-memory search engine
 func NewInMemoryEngine() *InMemoryEngine {
+	return NewInMemoryEngineWithConfig(nil)
+}
+
+// NewInMemoryEngineWithConfig creates an in-memory search engine with custom
+// settings. A nil config uses DefaultConfig.
+func NewInMemoryEngineWithConfig(config *Config) *InMemoryEngine {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	scoring := config.Scoring
+	if scoring == nil {
+		scoring = DefaultScoringConfig()
+	}
+
+	analyzers := make(map[string]*Analyzer)
+	if config.Analyzers == nil {
+		analyzers = DefaultAnalyzers()
+	}
+	for language, analyzer := range config.Analyzers {
+		analyzers[normalizeLanguage(language)] = analyzer
+	}
+
 	return &InMemoryEngine{
-		documents: make(map[string]*Document),
-		indices:   make(map[string]map[string]*Document),
+		indices:         make(map[string]map[string]*Document),
+		aliases:         make(map[string]string),
+		terms:           newTermTrie(),
+		docTerms:        make(map[string][]string),
+		analyzed:        make(map[string]*analyzedDocument),
+		scoring:         *scoring,
+		keywords:        newKeywordSet(config.KeywordFields),
+		analyzers:       analyzers,
+		defaultLanguage: normalizeLanguage(config.DefaultLanguage),
+		cache:           newResultCache(config),
 	}
 }
 
-// Index adds or updates a document
+// Index adds or updates a document. An existing document with the same ID
+// in the same index is replaced. doc.Index may name an alias, in which case
+// the document is stored in the index the alias points to.
 func (e *InMemoryEngine) Index(ctx context.Context, doc Document) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -124,42 +241,86 @@ func (e *InMemoryEngine) Index(ctx context.Context, doc Document) error {
 	if doc.Index == "" {
 		doc.Index = "default"
 	}
+	doc.Index = e.resolveIndexLocked(doc.Index)
 
 	if doc.Timestamp.IsZero() {
 		doc.Timestamp = time.Now()
 	}
 
-	// Store document
-	e.documents[doc.ID] = &doc
+	// Replace any previous version in this index
+	if _, ok := e.indices[doc.Index][doc.ID]; ok {
+		e.removeTermsLocked(&doc)
+	}
 
 	// Add to index
 	if e.indices[doc.Index] == nil {
 		e.indices[doc.Index] = make(map[string]*Document)
 	}
 	e.indices[doc.Index][doc.ID] = &doc
+	e.addTermsLocked(&doc)
+	e.cache.purge()
 
 	common.Debug("[SEARCH] Indexed document %s in index %s", doc.ID, doc.Index)
 	return nil
 }
 
-// Search performs a search query
+// Search performs a search query. query.Index may name an alias. Without an
+// index, every index is searched and each ID is returned once, using its
+// most recently indexed copy. With Config.ResultCacheSize, repeated
+// queries are answered from the cache until the next write.
 func (e *InMemoryEngine) Search(ctx context.Context, query Query) (*Results, error) {
 	start := time.Now()
 
+	fields, err := parseFields(query.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if query.GeoFilter != nil {
+		if err := query.GeoFilter.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateAggregations(query.Aggregations); err != nil {
+		return nil, err
+	}
+	if err := e.keywords.validate(query.Keywords); err != nil {
+		return nil, err
+	}
+
+	var cacheKey string
+	if e.cache != nil {
+		var ok bool
+		if cacheKey, ok = resultCacheKey(query); ok {
+			if cached, hit := e.cache.get(cacheKey); hit {
+				cached.Took = time.Since(start)
+				cached.Query = query.Text
+				return cached, nil
+			}
+		}
+	}
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
 	// Get documents from specified index
 	var searchDocs []*Document
 	if query.Index != "" {
-		if indexDocs, ok := e.indices[query.Index]; ok {
+		if indexDocs, ok := e.indices[e.resolveIndexLocked(query.Index)]; ok {
 			for _, doc := range indexDocs {
 				searchDocs = append(searchDocs, doc)
 			}
 		}
 	} else {
-		// Search all documents
-		for _, doc := range e.documents {
+		// Search all indices, keeping one copy per ID
+		latest := make(map[string]*Document)
+		for _, indexDocs := range e.indices {
+			for id, doc := range indexDocs {
+				if cur, ok := latest[id]; !ok || newerCopy(doc, cur) {
+					latest[id] = doc
+				}
+			}
+		}
+		for _, doc := range latest {
 			searchDocs = append(searchDocs, doc)
 		}
 	}
@@ -186,21 +347,78 @@ func (e *InMemoryEngine) Search(ctx context.Context, query Query) (*Results, err
 		searchDocs = filtered
 	}
 
+	// Filter by keyword fields
+	if len(query.Keywords) > 0 {
+		var filtered []*Document
+		for _, doc := range searchDocs {
+			if matchesKeywords(doc, query.Keywords) {
+				filtered = append(filtered, doc)
+			}
+		}
+		searchDocs = filtered
+	}
+
+	// Filter by distance
+	var distances map[string]float64
+	if geo := query.GeoFilter; geo != nil {
+		distances = make(map[string]float64)
+		var filtered []*Document
+		for _, doc := range searchDocs {
+			distance, ok := geo.distanceTo(doc)
+			if !ok || (geo.RadiusKm > 0 && distance > geo.RadiusKm) {
+				continue
+			}
+			distances[docKey(doc)] = distance
+			filtered = append(filtered, doc)
+		}
+		searchDocs = filtered
+	}
+
 	// Text search
 	var results []Document
 	if query.Text != "" {
 		queryLower := strings.ToLower(query.Text)
 		queryWords := strings.Fields(queryLower)
 
+		// Analyzed documents are matched against the query's terms
+		// under the query language, or under their own language
+		var queryAnalyzer *Analyzer
+		if query.Language != "" {
+			queryAnalyzer = e.analyzerForLocked(query.Language)
+		}
+		queryTerms := make(map[*Analyzer][]string)
+		now := time.Now()
+
 		for _, doc := range searchDocs {
-			score := calculateScore(doc, queryWords)
+			var score float64
+			if analyzed := e.analyzed[docKey(doc)]; analyzed != nil {
+				analyzer := queryAnalyzer
+				if analyzer == nil {
+					analyzer = analyzed.analyzer
+				}
+				terms, ok := queryTerms[analyzer]
+				if !ok {
+					terms = analyzer.Analyze(query.Text)
+					queryTerms[analyzer] = terms
+				}
+				score = e.scoring.scoreAnalyzed(analyzed, terms, fields)
+			} else {
+				score = e.scoring.score(doc, queryWords, fields)
+			}
 			if score > 0 {
+				if query.RecencyHalfLife > 0 {
+					score *= recencyFactor(doc.Timestamp, now, query.RecencyHalfLife)
+				}
 				docCopy := *doc
 				docCopy.Score = score
 
 				// Highlight matches if requested
 				if query.Highlight {
-					docCopy.Content = highlightMatches(docCopy.Content, queryWords)
+					if docCopy.HTML {
+						docCopy.Content = highlightHTML(docCopy.Content, queryWords)
+					} else {
+						docCopy.Content = highlightMatches(docCopy.Content, queryWords)
+					}
 					docCopy.Title = highlightMatches(docCopy.Title, queryWords)
 				}
 
@@ -221,7 +439,7 @@ func (e *InMemoryEngine) Search(ctx context.Context, query Query) (*Results, err
 
 	// Apply custom sorting
 	if len(query.Sort) > 0 {
-		applySorting(results, query.Sort)
+		applySorting(results, query.Sort, distances)
 	}
 
 	// Calculate facets if requested
@@ -229,6 +447,10 @@ func (e *InMemoryEngine) Search(ctx context.Context, query Query) (*Results, err
 	if len(query.Facets) > 0 {
 		facets = calculateFacets(results, query.Facets)
 	}
+	var aggregations []AggregationResult
+	if len(query.Aggregations) > 0 {
+		aggregations = calculateAggregations(results, query.Aggregations)
+	}
 
 	// Pagination
 	total := len(results)
@@ -253,42 +475,58 @@ func (e *InMemoryEngine) Search(ctx context.Context, query Query) (*Results, err
 		results = []Document{}
 	}
 
-	return &Results{
-		Total:  total,
-		Hits:   results,
-		Facets: facets,
-		Took:   time.Since(start),
-		Query:  query.Text,
-	}, nil
+	searchResults := &Results{
+		Total:        total,
+		Hits:         results,
+		Facets:       facets,
+		Took:         time.Since(start),
+		Query:        query.Text,
+		Aggregations: aggregations,
+	}
+	if cacheKey != "" {
+		// Stored under the read lock, so no write can slip in between
+		e.cache.set(cacheKey, searchResults)
+	}
+	return searchResults, nil
 }
 
-// Delete removes a document
+// Delete removes a document from every index holding it
 func (e *InMemoryEngine) Delete(ctx context.Context, id string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	doc, ok := e.documents[id]
-	if !ok {
-		return fmt.Errorf("document not found: %s", id)
+	found := false
+	for _, indexDocs := range e.indices {
+		if doc, ok := indexDocs[id]; ok {
+			e.removeTermsLocked(doc)
+			delete(indexDocs, id)
+			found = true
+		}
 	}
-
-	// Remove from index
-	if indexDocs, ok := e.indices[doc.Index]; ok {
-		delete(indexDocs, id)
+	if !found {
+		return fmt.Errorf("document not found: %s", id)
 	}
-
-	// Remove from documents
-	delete(e.documents, id)
+	e.cache.purge()
 
 	common.Debug("[SEARCH] Deleted document %s", id)
 	return nil
 }
 
-// DeleteIndex removes all documents from an index
+// DeleteIndex removes all documents from an index. Aliases and indices an
+// alias points to cannot be deleted; swap the alias first.
 func (e *InMemoryEngine) DeleteIndex(ctx context.Context, index string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if target, ok := e.aliases[index]; ok {
+		return fmt.Errorf("%s is an alias for index %s", index, target)
+	}
+	for alias, target := range e.aliases {
+		if target == index {
+			return fmt.Errorf("index %s is in use by alias %s", index, alias)
+		}
+	}
+
 	// Get all documents in index
 	indexDocs, ok := e.indices[index]
 	if !ok {
@@ -296,39 +534,62 @@ func (e *InMemoryEngine) DeleteIndex(ctx context.Context, index string) error {
 	}
 
 	// Remove documents
-	for id := range indexDocs {
-		delete(e.documents, id)
+	for _, doc := range indexDocs {
+		e.removeTermsLocked(doc)
 	}
 
 	// Remove index
 	delete(e.indices, index)
+	e.cache.purge()
 
 	common.Info("[SEARCH] Deleted index %s", index)
 	return nil
 }
 
-// GetDocument retrieves a document by ID
+// GetDocument retrieves a document by ID. When several indices hold the
+// ID, the most recently indexed copy is returned.
 func (e *InMemoryEngine) GetDocument(ctx context.Context, id string) (*Document, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	doc, ok := e.documents[id]
-	if !ok {
+	docs := e.documentsLocked(id)
+	if len(docs) == 0 {
 		return nil, fmt.Errorf("document not found: %s", id)
 	}
 
-	return doc, nil
+	latest := docs[0]
+	for _, doc := range docs[1:] {
+		if newerCopy(doc, latest) {
+			latest = doc
+		}
+	}
+	return latest, nil
 }
 
-// UpdateDocument partially updates a document
+// UpdateDocument partially updates a document in every index holding it
 func (e *InMemoryEngine) UpdateDocument(ctx context.Context, id string, updates map[string]interface{}) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	doc, ok := e.documents[id]
-	if !ok {
+	docs := e.documentsLocked(id)
+	if len(docs) == 0 {
 		return fmt.Errorf("document not found: %s", id)
 	}
+	for _, doc := range docs {
+		e.updateDocumentLocked(doc, updates)
+	}
+	e.cache.purge()
+
+	common.Debug("[SEARCH] Updated document %s", id)
+	return nil
+}
+
+// updateDocumentLocked applies updates to doc and re-derives its terms.
+// The caller must hold e.mu for writing.
+func (e *InMemoryEngine) updateDocumentLocked(doc *Document, updates map[string]interface{}) {
+	// Re-derive the document's terms from the updated fields
+	e.removeTermsLocked(doc)
+	defer e.addTermsLocked(doc)
 
 	// Apply updates
 	for key, value := range updates {
@@ -349,13 +610,43 @@ func (e *InMemoryEngine) UpdateDocument(ctx context.Context, id string, updates
 			if v, ok := value.(map[string]interface{}); ok {
 				doc.Metadata = v
 			}
+		case "language":
+			if v, ok := value.(string); ok {
+				doc.Language = v
+			}
 		}
 	}
 
 	doc.Timestamp = time.Now()
+}
 
-	common.Debug("[SEARCH] Updated document %s", id)
-	return nil
+// documentsLocked returns every copy of the document with id, ordered by
+// index name. The caller must hold e.mu.
+func (e *InMemoryEngine) documentsLocked(id string) []*Document {
+	var docs []*Document
+	for _, indexDocs := range e.indices {
+		if doc, ok := indexDocs[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Index < docs[j].Index
+	})
+	return docs
+}
+
+// newerCopy reports whether a is preferred over b among copies of one ID:
+// the most recently indexed wins, then the first index by name
+func newerCopy(a, b *Document) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.After(b.Timestamp)
+	}
+	return a.Index < b.Index
+}
+
+// docKey identifies a document across indices
+func docKey(doc *Document) string {
+	return doc.Index + "\x00" + doc.ID
 }
 
 // Helper functions
@@ -375,40 +666,6 @@ func hasAnyTag(docTags, queryTags []string) bool {
 	return false
 }
 
-func calculateScore(doc *Document, queryWords []string) float64 {
-	score := 0.0
-
-	titleLower := strings.ToLower(doc.Title)
-	contentLower := strings.ToLower(doc.Content)
-
-	for _, word := range queryWords {
-		// Title matches (weighted higher)
-		titleCount := strings.Count(titleLower, word)
-		score += float64(titleCount) * 2.0
-
-		// Content matches
-		contentCount := strings.Count(contentLower, word)
-		score += float64(contentCount)
-
-		// Tag matches
-		for _, tag := range doc.Tags {
-			if strings.Contains(strings.ToLower(tag), word) {
-				score += 1.5
-			}
-		}
-	}
-
-	// Boost for exact phrase match
-	fullQuery := strings.Join(queryWords, " ")
-	if strings.Contains(titleLower, fullQuery) {
-		score *= 2.0
-	} else if strings.Contains(contentLower, fullQuery) {
-		score *= 1.5
-	}
-
-	return score
-}
-
 func highlightMatches(text string, queryWords []string) string {
 	result := text
 
@@ -427,7 +684,10 @@ func highlightMatches(text string, queryWords []string) string {
 	return result
 }
 
-func applySorting(results []Document, sortFields []SortField) {
+// applySorting orders results by sortFields. distances holds each result's
+// distance from the geo filter center and is nil without a filter, in which
+// case SortGeoDistance has no effect.
+func applySorting(results []Document, sortFields []SortField, distances map[string]float64) {
 	sort.Slice(results, func(i, j int) bool {
 		for _, field := range sortFields {
 			var cmp int
@@ -447,6 +707,13 @@ func applySorting(results []Document, sortFields []SortField) {
 				}
 			case "title":
 				cmp = strings.Compare(results[i].Title, results[j].Title)
+			case SortGeoDistance:
+				di, dj := distances[docKey(&results[i])], distances[docKey(&results[j])]
+				if di < dj {
+					cmp = -1
+				} else if di > dj {
+					cmp = 1
+				}
 			}
 
 			if cmp != 0 {
@@ -551,18 +818,52 @@ func (qb *QueryBuilder) WithSort(field, order string) *QueryBuilder {
 	return qb
 }
 
+// WithKeyword filters on an exact, case-insensitive keyword field value
+func (qb *QueryBuilder) WithKeyword(field, value string) *QueryBuilder {
+	if qb.query.Keywords == nil {
+		qb.query.Keywords = make(map[string]string)
+	}
+	qb.query.Keywords[field] = value
+	return qb
+}
+
+// WithGeoFilter restricts results to documents within radiusKm of lat/lon
+func (qb *QueryBuilder) WithGeoFilter(lat, lon, radiusKm float64) *QueryBuilder {
+	qb.query.GeoFilter = &GeoFilter{Lat: lat, Lon: lon, RadiusKm: radiusKm}
+	return qb
+}
+
 // WithHighlight enables highlighting
 func (qb *QueryBuilder) WithHighlight() *QueryBuilder {
 	qb.query.Highlight = true
 	return qb
 }
 
+// WithFields restricts text matching to the given fields
+func (qb *QueryBuilder) WithFields(fields ...string) *QueryBuilder {
+	qb.query.Fields = fields
+	return qb
+}
+
 // WithFacets adds facet fields
 func (qb *QueryBuilder) WithFacets(fields ...string) *QueryBuilder {
 	qb.query.Facets = fields
 	return qb
 }
 
+// WithAggregation adds a numeric aggregation over a metadata field
+func (qb *QueryBuilder) WithAggregation(field, op string) *QueryBuilder {
+	qb.query.Aggregations = append(qb.query.Aggregations, Aggregation{Field: field, Op: op})
+	return qb
+}
+
+// WithRecency boosts newer documents, halving a document's score for
+// every halfLife of age
+func (qb *QueryBuilder) WithRecency(halfLife time.Duration) *QueryBuilder {
+	qb.query.RecencyHalfLife = halfLife
+	return qb
+}
+
 // Build returns the constructed query
 func (qb *QueryBuilder) Build() Query {
 	return qb.query
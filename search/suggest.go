@@ -0,0 +1,200 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// defaultSuggestLimit is used when Suggest is called with a non-positive limit
+const defaultSuggestLimit = 10
+
+// termTrie is a prefix tree of indexed terms. Each terminal node records the
+// number of documents containing the term (document frequency).
+type termTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	freq     int
+}
+
+// termCount pairs a term with its document frequency
+type termCount struct {
+	term string
+	freq int
+}
+
+func newTermTrie() *termTrie {
+	return &termTrie{root: &trieNode{}}
+}
+
+// add increments the document frequency of term
+func (t *termTrie) add(term string) {
+	node := t.root
+	for _, r := range term {
+		if node.children == nil {
+			node.children = make(map[rune]*trieNode)
+		}
+		child, ok := node.children[r]
+		if !ok {
+			child = &trieNode{}
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.freq++
+}
+
+// remove decrements the document frequency of term and prunes empty branches
+func (t *termTrie) remove(term string) {
+	runes := []rune(term)
+	path := make([]*trieNode, 0, len(runes)+1)
+	node := t.root
+	path = append(path, node)
+	for _, r := range runes {
+		child, ok := node.children[r]
+		if !ok {
+			return
+		}
+		node = child
+		path = append(path, node)
+	}
+	if node.freq > 0 {
+		node.freq--
+	}
+
+	// Prune nodes that no longer lead to any term
+	for i := len(runes) - 1; i >= 0; i-- {
+		child := path[i+1]
+		if child.freq > 0 || len(child.children) > 0 {
+			break
+		}
+		delete(path[i].children, runes[i])
+	}
+}
+
+// withPrefix returns every term starting with prefix
+func (t *termTrie) withPrefix(prefix string) []termCount {
+	node := t.root
+	for _, r := range prefix {
+		child, ok := node.children[r]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+
+	var results []termCount
+	var walk func(n *trieNode, term []rune)
+	walk = func(n *trieNode, term []rune) {
+		if n.freq > 0 {
+			results = append(results, termCount{term: string(term), freq: n.freq})
+		}
+		for r, child := range n.children {
+			walk(child, append(term, r))
+		}
+	}
+	walk(node, []rune(prefix))
+	return results
+}
+
+// tokenize splits text into lowercase terms on any non letter/digit rune
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// documentTerms returns the unique terms of a document's title, content and tags
+func documentTerms(doc *Document) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(text string) {
+		for _, term := range tokenize(text) {
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	add(doc.Title)
+	add(doc.Content)
+	for _, tag := range doc.Tags {
+		add(tag)
+	}
+	return terms
+}
+
+// addTermsLocked records the terms of doc in the suggestion trie and its
+// analyzed fields. The caller must hold e.mu for writing.
+func (e *InMemoryEngine) addTermsLocked(doc *Document) {
+	terms := documentTerms(doc)
+	for _, term := range terms {
+		e.terms.add(term)
+	}
+	e.docTerms[docKey(doc)] = terms
+	e.analyzeLocked(doc)
+}
+
+// removeTermsLocked removes the terms previously recorded for doc.
+// The caller must hold e.mu for writing.
+func (e *InMemoryEngine) removeTermsLocked(doc *Document) {
+	key := docKey(doc)
+	for _, term := range e.docTerms[key] {
+		e.terms.remove(term)
+	}
+	delete(e.docTerms, key)
+	delete(e.analyzed, key)
+}
+
+// Suggest returns up to limit indexed terms starting with prefix, most
+// frequent first (ties are ordered alphabetically). Matching is
+// case-insensitive. An empty prefix returns no suggestions.
+func (e *InMemoryEngine) Suggest(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return []string{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+
+	e.mu.RLock()
+	matches := e.terms.withPrefix(prefix)
+	e.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].freq != matches[j].freq {
+			return matches[i].freq > matches[j].freq
+		}
+		return matches[i].term < matches[j].term
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	suggestions := make([]string, len(matches))
+	for i, m := range matches {
+		suggestions[i] = m.term
+	}
+	return suggestions, nil
+}
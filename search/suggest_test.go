@@ -0,0 +1,134 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func newSuggestEngine(t *testing.T) *InMemoryEngine {
+	t.Helper()
+	ctx := context.Background()
+	engine := NewInMemoryEngine()
+
+	docs := []Document{
+		{ID: "1", Title: "Programming in Go", Content: "Programs and programmers"},
+		{ID: "2", Title: "Go programming patterns", Content: "Production ready code"},
+		{ID: "3", Title: "Python programming", Content: "Project layout"},
+		{ID: "4", Title: "Cooking", Content: "Recipes", Tags: []string{"Produce"}},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(ctx, doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return engine
+}
+
+func TestSuggest(t *testing.T) {
+	engine := newSuggestEngine(t)
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []string
+	}{
+		// "programming" appears in 3 documents, the others in 1
+		{"ranked by frequency", "pro", 0, []string{"programming", "produce", "production", "programmers", "programs", "project"}},
+		{"case insensitive", "PROG", 0, []string{"programming", "programmers", "programs"}},
+		{"limit applied", "pro", 2, []string{"programming", "produce"}},
+		{"exact term", "python", 0, []string{"python"}},
+		{"no match", "xyz", 0, []string{}},
+		{"empty prefix", "", 0, []string{}},
+		{"whitespace prefix", "   ", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Suggest(context.Background(), tt.prefix, tt.limit)
+			if err != nil {
+				t.Fatalf("Suggest failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestTracksChanges(t *testing.T) {
+	ctx := context.Background()
+	engine := newSuggestEngine(t)
+
+	if err := engine.Delete(ctx, "3"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	got, _ := engine.Suggest(ctx, "py", 0)
+	if len(got) != 0 {
+		t.Errorf("after delete Suggest(py) = %v, want none", got)
+	}
+
+	if err := engine.UpdateDocument(ctx, "4", map[string]interface{}{"title": "Pythonic cooking"}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	got, _ = engine.Suggest(ctx, "py", 0)
+	if !reflect.DeepEqual(got, []string{"pythonic"}) {
+		t.Errorf("after update Suggest(py) = %v, want [pythonic]", got)
+	}
+
+	// Re-indexing the same ID must not double count its terms
+	if err := engine.Index(ctx, Document{ID: "4", Title: "Pythonic cooking"}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if err := engine.Index(ctx, Document{ID: "5", Title: "Python tips"}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	got, _ = engine.Suggest(ctx, "py", 0)
+	if !reflect.DeepEqual(got, []string{"python", "pythonic"}) {
+		t.Errorf("after re-index Suggest(py) = %v, want [python pythonic]", got)
+	}
+
+	if err := engine.DeleteIndex(ctx, "default"); err != nil {
+		t.Fatalf("DeleteIndex failed: %v", err)
+	}
+	got, _ = engine.Suggest(ctx, "p", 0)
+	if len(got) != 0 {
+		t.Errorf("after DeleteIndex Suggest(p) = %v, want none", got)
+	}
+}
+
+func TestSuggestAfterLoadIndex(t *testing.T) {
+	ctx := context.Background()
+	original := newSuggestEngine(t)
+
+	var buf bytes.Buffer
+	if err := original.SaveIndex(&buf); err != nil {
+		t.Fatalf("SaveIndex failed: %v", err)
+	}
+	restored := NewInMemoryEngine()
+	if err := restored.LoadIndex(&buf); err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+
+	want, _ := original.Suggest(ctx, "pro", 0)
+	got, _ := restored.Suggest(ctx, "pro", 0)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restored Suggest = %v, want %v", got, want)
+	}
+}
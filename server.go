@@ -0,0 +1,165 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+// http.ListenAndServe has no timeouts, so a client trickling headers or
+// never reading the response holds a connection forever (slowloris).
+// NewServer starts from safe timeouts and ListenAndServeGraceful drains
+// in-flight requests when the platform sends SIGTERM before a redeploy.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Default timeouts applied by NewServer to zero ServerOptions fields
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 15 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+
+	// DefaultShutdownTimeout fits within the 10 second grace period Cloud
+	// Run and Kubernetes give between SIGTERM and SIGKILL
+	DefaultShutdownTimeout = 9 * time.Second
+)
+
+// ServerOptions configures NewServer. Zero fields use the Default*
+// constants; a negative duration disables that timeout, e.g. WriteTimeout
+// for a server streaming server-sent events.
+type ServerOptions struct {
+	// ReadHeaderTimeout bounds reading the request headers
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout bounds reading the whole request, body included
+	ReadTimeout time.Duration
+
+	// WriteTimeout bounds the time from the end of the request headers to
+	// the end of the response
+	WriteTimeout time.Duration
+
+	// IdleTimeout bounds how long a keep-alive connection waits for the
+	// next request
+	IdleTimeout time.Duration
+
+	// ShutdownTimeout bounds how long ListenAndServeGraceful waits for
+	// in-flight requests to finish
+	ShutdownTimeout time.Duration
+
+	// MaxHeaderBytes limits the size of request headers. Zero uses
+	// http.DefaultMaxHeaderBytes.
+	MaxHeaderBytes int
+}
+
+// Server is an http.Server with safe timeouts and graceful shutdown
+type Server struct {
+	*http.Server
+	shutdownTimeout time.Duration
+}
+
+// NewServer returns a server for handler on addr with the timeouts of
+// opts:
+//
+//	srv := common.NewServer(":"+port, mux, common.ServerOptions{})
+//	if err := srv.ListenAndServeGraceful(context.Background()); err != nil {
+//	    common.Fatal("Server failed: %v", err)
+//	}
+func NewServer(addr string, handler http.Handler, opts ServerOptions) *Server {
+	return &Server{
+		Server: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: serverTimeout(opts.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+			ReadTimeout:       serverTimeout(opts.ReadTimeout, DefaultReadTimeout),
+			WriteTimeout:      serverTimeout(opts.WriteTimeout, DefaultWriteTimeout),
+			IdleTimeout:       serverTimeout(opts.IdleTimeout, DefaultIdleTimeout),
+			MaxHeaderBytes:    opts.MaxHeaderBytes,
+		},
+		shutdownTimeout: serverTimeout(opts.ShutdownTimeout, DefaultShutdownTimeout),
+	}
+}
+
+// serverTimeout applies the default to a zero value and maps negative
+// values to zero, which net/http treats as no timeout
+func serverTimeout(value, def time.Duration) time.Duration {
+	switch {
+	case value == 0:
+		return def
+	case value < 0:
+		return 0
+	default:
+		return value
+	}
+}
+
+// ListenAndServeGraceful listens on the server's address and serves until
+// ctx is done or the process receives SIGTERM or an interrupt. It then
+// stops accepting connections and waits up to the shutdown timeout for
+// in-flight requests to finish. It returns nil after a clean drain.
+func (s *Server) ListenAndServeGraceful(ctx context.Context) error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.ServeGraceful(ctx, ln)
+}
+
+// ServeGraceful is ListenAndServeGraceful on an existing listener
+func (s *Server) ServeGraceful(ctx context.Context, ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ln)
+	}()
+	Info("[SERVER] Listening on %s", ln.Addr())
+
+	select {
+	case err := <-served:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	Info("[SERVER] Shutting down, draining in-flight requests")
+	// ctx is already done, so the drain gets a context of its own
+	shutdownCtx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.shutdownTimeout)
+		defer cancel()
+	}
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		s.Close()
+		return fmt.Errorf("failed to drain in-flight requests: %w", err)
+	}
+	<-served
+	Info("[SERVER] Shutdown complete")
+	return nil
+}
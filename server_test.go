@@ -0,0 +1,201 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestNewServerTimeouts checks defaults, overrides and disabled timeouts.
+func TestNewServerTimeouts(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            ServerOptions
+		wantReadHeader  time.Duration
+		wantRead        time.Duration
+		wantWrite       time.Duration
+		wantIdle        time.Duration
+		wantShutdown    time.Duration
+		wantHeaderBytes int
+	}{
+		{
+			name:           "Defaults",
+			wantReadHeader: DefaultReadHeaderTimeout,
+			wantRead:       DefaultReadTimeout,
+			wantWrite:      DefaultWriteTimeout,
+			wantIdle:       DefaultIdleTimeout,
+			wantShutdown:   DefaultShutdownTimeout,
+		},
+		{
+			name: "Overrides",
+			opts: ServerOptions{
+				ReadHeaderTimeout: time.Second,
+				ReadTimeout:       2 * time.Second,
+				WriteTimeout:      3 * time.Second,
+				IdleTimeout:       4 * time.Second,
+				ShutdownTimeout:   5 * time.Second,
+				MaxHeaderBytes:    8 << 10,
+			},
+			wantReadHeader:  time.Second,
+			wantRead:        2 * time.Second,
+			wantWrite:       3 * time.Second,
+			wantIdle:        4 * time.Second,
+			wantShutdown:    5 * time.Second,
+			wantHeaderBytes: 8 << 10,
+		},
+		{
+			name:           "Streaming without write timeout",
+			opts:           ServerOptions{WriteTimeout: -1},
+			wantReadHeader: DefaultReadHeaderTimeout,
+			wantRead:       DefaultReadTimeout,
+			wantWrite:      0,
+			wantIdle:       DefaultIdleTimeout,
+			wantShutdown:   DefaultShutdownTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(":8080", http.NotFoundHandler(), tt.opts)
+			if srv.Addr != ":8080" || srv.Handler == nil {
+				t.Errorf("Addr = %q, Handler = %v", srv.Addr, srv.Handler)
+			}
+			if srv.ReadHeaderTimeout != tt.wantReadHeader {
+				t.Errorf("ReadHeaderTimeout = %v, want %v", srv.ReadHeaderTimeout, tt.wantReadHeader)
+			}
+			if srv.ReadTimeout != tt.wantRead {
+				t.Errorf("ReadTimeout = %v, want %v", srv.ReadTimeout, tt.wantRead)
+			}
+			if srv.WriteTimeout != tt.wantWrite {
+				t.Errorf("WriteTimeout = %v, want %v", srv.WriteTimeout, tt.wantWrite)
+			}
+			if srv.IdleTimeout != tt.wantIdle {
+				t.Errorf("IdleTimeout = %v, want %v", srv.IdleTimeout, tt.wantIdle)
+			}
+			if srv.shutdownTimeout != tt.wantShutdown {
+				t.Errorf("shutdownTimeout = %v, want %v", srv.shutdownTimeout, tt.wantShutdown)
+			}
+			if srv.MaxHeaderBytes != tt.wantHeaderBytes {
+				t.Errorf("MaxHeaderBytes = %d, want %d", srv.MaxHeaderBytes, tt.wantHeaderBytes)
+			}
+		})
+	}
+}
+
+// startGracefulServer serves handler on a local port until ctx is done and
+// returns the base URL and the channel receiving ServeGraceful's result.
+func startGracefulServer(t *testing.T, ctx context.Context, handler http.Handler, opts ServerOptions) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	srv := NewServer(ln.Addr().String(), handler, opts)
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ServeGraceful(ctx, ln)
+	}()
+	return "http://" + ln.Addr().String(), done
+}
+
+// TestServeGracefulDrainsInFlightRequests checks shutdown waits for a
+// request that is still being handled.
+func TestServeGracefulDrainsInFlightRequests(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startGracefulServer(t, ctx, handler, ServerOptions{})
+
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- response{string(body), err}
+	}()
+
+	<-entered
+	cancel()
+
+	select {
+	case err := <-done:
+		t.Fatalf("ServeGraceful returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if resp := <-responses; resp.err != nil || resp.body != "done" {
+		t.Errorf("In-flight request got %q, %v; want it to complete", resp.body, resp.err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeGraceful returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeGraceful did not return after the drain")
+	}
+
+	if _, err := http.Get(url); err == nil {
+		t.Error("Expected new connections to be refused after shutdown")
+	}
+}
+
+// TestServeGracefulShutdownTimeout checks a request outliving the shutdown
+// timeout is cut off with an error.
+func TestServeGracefulShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startGracefulServer(t, ctx, handler, ServerOptions{ShutdownTimeout: 20 * time.Millisecond})
+	go http.Get(url)
+
+	<-entered
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error when in-flight requests outlive the shutdown timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeGraceful ignored the shutdown timeout")
+	}
+}
@@ -20,19 +20,16 @@
 package track
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/patdeg/common"
-	"github.com/patdeg/common/gcp"
 
 	"github.com/mssola/user_agent"
 	"golang.org/x/net/context"
-	bigquery "google.golang.org/api/bigquery/v2"
 	appengine "google.golang.org/appengine/v2"
 	"google.golang.org/appengine/v2/user"
 )
@@ -72,8 +69,8 @@ type Click struct {
 	Country         string    `json:"country,omitempty"`
 	Region          string    `json:"region,omitempty"`
 	City            string    `json:"city,omitempty"`
-	Lat             float64   `json:"lat,omitempty"`
-	Lon             float64   `json:"lon,omitempty"`
+	Lat             float64   `json:"lat,omitempty" description:"City latitude"`
+	Lon             float64   `json:"lon,omitempty" description:"City longitude"`
 	AcceptLanguage  string    `json:"acceptLanguage,omitempty"`
 	UserAgent       string    `json:"userAgent,omitempty"`
 	IsMobile        bool      `json:"isMobile,omitempty"`
@@ -87,86 +84,55 @@ type Click struct {
 	BrowserVersion  string    `json:"browserVersion,omitempty"`
 }
 
+// clickTracker stores AdWords clicks in daily tables of the adwords dataset.
+// The insertId combines the client address with the click time so retried
+// inserts of the same click are de-duplicated.
+var clickTracker = mustEventTracker[Click](EventTrackerConfig{
+	ProjectID:    adwordsProjectID,
+	DatasetID:    adwordsDataset,
+	FriendlyName: "Daily Clicks table",
+	Description:  "This table is created automatically to store daily AdWords clicks to Deglon Consulting properties ",
+}).WithInsertID(func(click *Click, _ time.Time) string {
+	return click.RemoteAddr + common.I2S(click.Time.UnixNano())
+})
+
+// filterClicks enables click filtering in AdWordsTrackingHandler; see
+// ConfigureClickFiltering
+var filterClicks = getEnv("ADWORDS_FILTER_CLICKS", "") == "true"
+
+// filteredClicks counts clicks AdWordsTrackingHandler did not store
+var filteredClicks atomic.Int64
+
+// FilteredClicks returns how many clicks were redirected without being
+// stored since the process started
+func FilteredClicks() int64 {
+	return filteredClicks.Load()
+}
+
+// clickFilterReason returns why the click in r should not be stored, or an
+// empty string for a click that looks human. Bots are checked first since
+// IsHacker caches a verdict for the client address.
+func clickFilterReason(r *http.Request) string {
+	if common.IsBot(r.UserAgent()) {
+		return "bot user agent"
+	}
+	if common.IsSpam(r.Context(), r.Referer()) {
+		return "spam referer"
+	}
+	if common.IsHacker(r) {
+		return "suspicious request"
+	}
+	return ""
+}
+
 // createClicksTableInBigQuery creates the daily AdWords clicks table named
 // by the YYYYMMDD string d. The function ensures the dataset exists and
 // then attempts to create the table. It returns any error encountered
 // while creating the dataset or table, or when d does not match the
 // expected date format.
 func createClicksTableInBigQuery(c context.Context, d string) error {
-
 	common.Info("Create a new daily clicks table in BigQuery")
-
-	if err := gcp.CreateDatasetIfNotExists(c, adwordsProjectID, adwordsDataset); err != nil {
-		common.Error("Error ensuring dataset %s: %v", adwordsDataset, err)
-		return err
-	}
-
-	if len(d) != 8 {
-		return errors.New("table name is badly formatted - expected 8 characters")
-	}
-	newTable := &bigquery.Table{
-		TableReference: &bigquery.TableReference{
-			ProjectId: adwordsProjectID,
-			DatasetId: adwordsDataset,
-			TableId:   d,
-		},
-		FriendlyName: "Daily Clicks table",
-		Description:  "This table is created automatically to store daily AdWords clicks to Deglon Consulting properties ",
-		//ExpirationTime: expirationTime.Unix() * 1000,
-		Schema: &bigquery.TableSchema{
-			Fields: []*bigquery.TableFieldSchema{
-				{Name: "Time", Type: "TIMESTAMP", Description: "Time"},
-				{Name: "RedirectUrl", Type: "STRING", Description: "RedirectUrl"},
-				{Name: "Query", Type: "STRING", Description: "Query"},
-				{Name: "Campaignid", Type: "STRING", Description: "Campaignid"},
-				{Name: "Adgroupid", Type: "STRING", Description: "Adgroupid"},
-				{Name: "Feeditemid", Type: "STRING", Description: "Feeditemid"},
-				{Name: "Targetid", Type: "STRING", Description: "Targetid"},
-				{Name: "Loc_Physical_Ms", Type: "STRING", Description: "Loc_Physical_Ms"},
-				{Name: "Loc_Interest_Ms", Type: "STRING", Description: "Loc_Interest_Ms"},
-				{Name: "Matchtype", Type: "STRING", Description: "Matchtype"},
-				{Name: "Network", Type: "STRING", Description: "Network"},
-				{Name: "Device", Type: "STRING", Description: "Device"},
-				{Name: "Devicemodel", Type: "STRING", Description: "Devicemodel"},
-				{Name: "Creative", Type: "STRING", Description: "Creative"},
-				{Name: "Keyword", Type: "STRING", Description: "Keyword"},
-				{Name: "Placement", Type: "STRING", Description: "Placement"},
-				{Name: "Target", Type: "STRING", Description: "Target"},
-				{Name: "Param1", Type: "STRING", Description: "Param1"},
-				{Name: "Param2", Type: "STRING", Description: "Param2"},
-				{Name: "Random", Type: "STRING", Description: "Random"},
-				{Name: "Aceid", Type: "STRING", Description: "Aceid"},
-				{Name: "Adposition", Type: "STRING", Description: "Adposition"},
-				{Name: "Ignore", Type: "STRING", Description: "Ignore"},
-				{Name: "Lpurl", Type: "STRING", Description: "Lpurl"},
-				{Name: "Cookie", Type: "STRING", Description: "Cookie"},
-				{Name: "Referer", Type: "STRING", Description: "Referer"},
-				{Name: "Host", Type: "STRING", Description: "Host"},
-				{Name: "RemoteAddr", Type: "STRING", Description: "RemoteAddr"},
-				{Name: "InstanceId", Type: "STRING", Description: "InstanceId"},
-				{Name: "VersionId", Type: "STRING", Description: "VersionId"},
-				{Name: "Scheme", Type: "STRING", Description: "Scheme"},
-				{Name: "Country", Type: "STRING", Description: "Country"},
-				{Name: "Region", Type: "STRING", Description: "Region"},
-				{Name: "City", Type: "STRING", Description: "City"},
-				{Name: "Lat", Type: "FLOAT", Description: "City latitude"},
-				{Name: "Lon", Type: "FLOAT", Description: "City longitude"},
-				{Name: "AcceptLanguage", Type: "STRING", Description: "AcceptLanguage"},
-				{Name: "UserAgent", Type: "STRING", Description: "UserAgent"},
-				{Name: "IsMobile", Type: "BOOLEAN", Description: "IsMobile"},
-				{Name: "IsBot", Type: "BOOLEAN", Description: "IsBot"},
-				{Name: "MozillaVersion", Type: "STRING", Description: "MozillaVersion"},
-				{Name: "Platform", Type: "STRING", Description: "Platform"},
-				{Name: "OS", Type: "STRING", Description: "OS"},
-				{Name: "EngineName", Type: "STRING", Description: "EngineName"},
-				{Name: "EngineVersion", Type: "STRING", Description: "EngineVersion"},
-				{Name: "BrowserName", Type: "STRING", Description: "BrowserName"},
-				{Name: "BrowserVersion", Type: "STRING", Description: "BrowserVersion"},
-			},
-		},
-	}
-
-	return gcp.CreateTableInBigQuery(c, newTable)
+	return clickTracker.CreateTable(c, d)
 }
 
 // CreateTodayClicksTableInBigQueryHandler sets up today's clicks table in
@@ -228,68 +194,7 @@ func CreateTomorrowClicksTableInBigQueryHandler(w http.ResponseWriter, r *http.R
 // before retrying the insert. Any error from BigQuery or table creation is
 // returned to the caller.
 func StoreClickInBigQuery(c context.Context, click *Click) error {
-
-	req := &bigquery.TableDataInsertAllRequest{
-		Kind: "bigquery#tableDataInsertAllRequest",
-		Rows: []*bigquery.TableDataInsertAllRequestRows{
-			{
-				InsertId: click.RemoteAddr + common.I2S(click.Time.UnixNano()),
-				Json: map[string]bigquery.JsonValue{
-					"Time":            click.Time,
-					"RedirectUrl":     click.RedirectUrl,
-					"Query":           click.Query,
-					"Campaignid":      click.Campaignid,
-					"Adgroupid":       click.Adgroupid,
-					"Feeditemid":      click.Feeditemid,
-					"Targetid":        click.Targetid,
-					"Loc_Physical_Ms": click.Loc_Physical_Ms,
-					"Loc_Interest_Ms": click.Loc_Interest_Ms,
-					"Matchtype":       click.Matchtype,
-					"Network":         click.Network,
-					"Device":          click.Device,
-					"Devicemodel":     click.Devicemodel,
-					"Creative":        click.Creative,
-					"Keyword":         click.Keyword,
-					"Placement":       click.Placement,
-					"Target":          click.Target,
-					"Param1":          click.Param1,
-					"Param2":          click.Param2,
-					"Random":          click.Random,
-					"Aceid":           click.Aceid,
-					"Adposition":      click.Adposition,
-					"Ignore":          click.Ignore,
-					"Lpurl":           click.Lpurl,
-					"Cookie":          click.Cookie,
-					"Referer":         click.Referer,
-					"Host":            click.Host,
-					"RemoteAddr":      click.RemoteAddr,
-					"InstanceId":      click.InstanceId,
-					"VersionId":       click.VersionId,
-					"Scheme":          click.Scheme,
-					"Country":         click.Country,
-					"Region":          click.Region,
-					"City":            click.City,
-					"Lat":             click.Lat,
-					"Lon":             click.Lon,
-					"AcceptLanguage":  click.AcceptLanguage,
-					"UserAgent":       click.UserAgent,
-					"IsMobile":        click.IsMobile,
-					"IsBot":           click.IsBot,
-					"MozillaVersion":  click.MozillaVersion,
-					"Platform":        click.Platform,
-					"OS":              click.OS,
-					"EngineName":      click.EngineName,
-					"EngineVersion":   click.EngineVersion,
-					"BrowserName":     click.BrowserName,
-					"BrowserVersion":  click.BrowserVersion,
-				},
-			},
-		},
-	}
-
-	tableName := time.Now().Format("20060102")
-
-	return insertWithTableCreation(c, adwordsProjectID, adwordsDataset, tableName, req, createClicksTableInBigQuery)
+	return clickTracker.Store(c, click)
 }
 
 // AdWordsTrackingHandler collects detailed AdWords click information from the
@@ -297,6 +202,11 @@ func StoreClickInBigQuery(c context.Context, click *Click) error {
 // include `url` for the landing page as well as `k` (keyword), `cm` (campaign
 // ID) and other standard Google Ads values. After recording the click, the
 // handler redirects the user to the validated `url` parameter.
+//
+// With click filtering enabled (see ConfigureClickFiltering), clicks from
+// bots, spam referrers and requests flagged by common.IsHacker are
+// redirected without being stored or tracked, and counted by
+// FilteredClicks.
 func AdWordsTrackingHandler(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 
@@ -319,17 +229,20 @@ func AdWordsTrackingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if filterClicks {
+		if reason := clickFilterReason(r); reason != "" {
+			filteredClicks.Add(1)
+			common.Info("Click not stored (%s), redirect to %v", reason, redirectUrl)
+			http.Redirect(w, r, redirectUrl, http.StatusFound)
+			return
+		}
+	}
+
 	ua := user_agent.New(r.Header.Get("User-Agent"))
 	engineName, engineversion := ua.Engine()
 	browserName, browserVersion := ua.Browser()
 
-	lat := float64(0)
-	lon := float64(0)
-	latlon := strings.Split(r.Header.Get("X-AppEngine-CityLatLong"), ",")
-	if len(latlon) == 2 {
-		lat = common.S2F(latlon[0])
-		lon = common.S2F(latlon[1])
-	}
+	geo := common.GeoFromRequest(r)
 
 	query := ""
 	if r.Header.Get("Referer") != "" {
@@ -374,11 +287,11 @@ func AdWordsTrackingHandler(w http.ResponseWriter, r *http.Request) {
 		InstanceId:      appengine.InstanceID(),
 		VersionId:       appengine.VersionID(c),
 		Scheme:          r.URL.Scheme,
-		Country:         r.Header.Get("X-AppEngine-Country"),
-		Region:          r.Header.Get("X-AppEngine-Region"),
-		City:            r.Header.Get("X-AppEngine-City"),
-		Lat:             lat,
-		Lon:             lon,
+		Country:         geo.Country,
+		Region:          geo.Region,
+		City:            geo.City,
+		Lat:             geo.Lat,
+		Lon:             geo.Lon,
 		AcceptLanguage:  r.Header.Get("Accept-Language"), // browser locale
 		UserAgent:       r.Header.Get("User-Agent"),
 		IsMobile:        ua.Mobile(),
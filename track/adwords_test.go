@@ -19,6 +19,10 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+
+	"github.com/patdeg/common/gcp"
+	"golang.org/x/net/context"
+	bigquery "google.golang.org/api/bigquery/v2"
 )
 
 // TestAdWordsTrackingHandlerInvalidURL verifies invalid redirect URLs return 400.
@@ -34,3 +38,65 @@ func TestAdWordsTrackingHandlerInvalidURL(t *testing.T) {
 		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusBadRequest)
 	}
 }
+
+// TestAdWordsTrackingHandlerClickFiltering verifies filtered clicks are
+// redirected without being stored, and that filtering is opt-in.
+func TestAdWordsTrackingHandlerClickFiltering(t *testing.T) {
+	os.Setenv("GAE_INSTANCE", "test")
+	os.Setenv("GAE_VERSION", "1")
+	os.Setenv("GAE_DEPLOYMENT_ID", "1")
+	defer ConfigureClickFiltering(false)
+
+	// Run the event goroutine inline so every insert is done, and counted
+	// without a race, before the assertions
+	runAsync = func(fn func()) { fn() }
+	defer func() { runAsync = func(fn func()) { go fn() } }()
+
+	stored := 0
+	streamDataFn = func(_ context.Context, _, datasetID, _ string, _ *bigquery.TableDataInsertAllRequest) error {
+		if datasetID == adwordsDataset {
+			stored++
+		}
+		return nil
+	}
+	defer func() { streamDataFn = gcp.StreamDataInBigquery }()
+
+	const (
+		botUA   = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+		humanUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	)
+	tests := []struct {
+		name         string
+		filter       bool
+		userAgent    string
+		wantStored   int
+		wantFiltered int64
+	}{
+		{name: "Bot filtered", filter: true, userAgent: botUA, wantStored: 0, wantFiltered: 1},
+		{name: "Human stored", filter: true, userAgent: humanUA, wantStored: 1, wantFiltered: 0},
+		{name: "Bot stored when filtering is off", filter: false, userAgent: botUA, wantStored: 1, wantFiltered: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ConfigureClickFiltering(tt.filter)
+			stored = 0
+			filteredBefore := FilteredClicks()
+
+			r := httptest.NewRequest("GET", "/tracking?k=shoes&url=https%3A%2F%2Fwww.example.com%2F", nil)
+			r.Header.Set("User-Agent", tt.userAgent)
+			w := httptest.NewRecorder()
+			AdWordsTrackingHandler(w, r)
+
+			if w.Code != http.StatusFound || w.Header().Get("Location") != "https://www.example.com/" {
+				t.Errorf("got %d to %q, want a redirect to the landing page", w.Code, w.Header().Get("Location"))
+			}
+			if stored != tt.wantStored {
+				t.Errorf("stored %d clicks, want %d", stored, tt.wantStored)
+			}
+			if got := FilteredClicks() - filteredBefore; got != tt.wantFiltered {
+				t.Errorf("FilteredClicks grew by %d, want %d", got, tt.wantFiltered)
+			}
+		})
+	}
+}
@@ -32,6 +32,10 @@ import (
 // variable so tests can replace it with a stub implementation.
 var streamDataFn = gcp.StreamDataInBigquery
 
+// runAsync starts the background work of the tracking functions. Tests
+// replace it to run that work synchronously.
+var runAsync = func(fn func()) { go fn() }
+
 // insertWithTableCreation streams data to BigQuery and creates the table if it
 // does not exist. When the initial insert returns a 404 error, the provided
 // createTable callback is invoked to ensure the dataset and table exist before
@@ -46,3 +46,14 @@ func ConfigureTouchpoints(projectID, datasetID string) {
 		touchpointsDataset = datasetID
 	}
 }
+
+// ConfigureClickFiltering controls whether AdWordsTrackingHandler skips
+// storing clicks from bots, spam referrers and suspicious requests. The
+// visitor is redirected either way. Filtering is off unless enabled here or
+// with ADWORDS_FILTER_CLICKS=true, so existing deployments keep every click.
+// Call it at startup, before serving requests:
+//
+//	track.ConfigureClickFiltering(true)
+func ConfigureClickFiltering(enabled bool) {
+	filterClicks = enabled
+}
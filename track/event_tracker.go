@@ -0,0 +1,312 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+// This file contains EventTracker, a generic pipeline that stores any struct
+// in daily BigQuery tables. The table schema is derived from the struct
+// definition, so a new event kind only needs a struct and a config instead of
+// a hand-written schema, table creation function and insert request.
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/patdeg/common"
+	"github.com/patdeg/common/gcp"
+
+	"golang.org/x/net/context"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// EventTrackerConfig describes where and how an event kind is stored.
+type EventTrackerConfig struct {
+	ProjectID    string // BigQuery project
+	DatasetID    string // BigQuery dataset holding the daily tables
+	FriendlyName string // Friendly name of the daily tables
+	Description  string // Description of the daily tables
+}
+
+// EventTracker streams rows of type T into daily BigQuery tables named
+// YYYYMMDD. Columns are derived from the exported fields of T:
+//
+//   - string, bool, integer and float fields map to STRING, BOOLEAN,
+//     INTEGER and FLOAT columns; time.Time maps to TIMESTAMP and []byte
+//     to BYTES
+//   - slices become REPEATED columns and nested structs become RECORD
+//     columns; pointers are followed
+//   - the `bigquery:"name"` tag renames a column and `bigquery:"-"` skips
+//     the field; the `description:"..."` tag sets the column description,
+//     which defaults to the column name
+type EventTracker[T any] struct {
+	config EventTrackerConfig
+	schema *bigquery.TableSchema
+	// insertID returns the de-duplication ID of a row
+	insertID func(row *T, now time.Time) string
+}
+
+// NewEventTracker derives the schema of T and returns a tracker storing rows
+// according to config. It returns an error if T is not a struct or contains
+// a field type that has no BigQuery equivalent (maps, interfaces, channels).
+func NewEventTracker[T any](config EventTrackerConfig) (*EventTracker[T], error) {
+	if config.ProjectID == "" || config.DatasetID == "" {
+		return nil, errors.New("event tracker requires a project and dataset")
+	}
+
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("event tracker requires a struct type, got %v", typ)
+	}
+
+	fields, err := schemaFields(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventTracker[T]{
+		config: config,
+		schema: &bigquery.TableSchema{Fields: fields},
+		insertID: func(_ *T, now time.Time) string {
+			return defaultInsertID(now)
+		},
+	}, nil
+}
+
+// generateToken is common.GenerateToken, replaceable in tests to simulate
+// failures
+var generateToken = common.GenerateToken
+
+// defaultInsertID combines the timestamp with a random suffix to avoid
+// collisions between instances. If no suffix can be generated it logs the
+// error and returns an empty ID, so BigQuery stores the row without
+// de-duplication rather than dropping it as a duplicate.
+func defaultInsertID(now time.Time) string {
+	suffix, err := generateToken(common.MinTokenBytes)
+	if err != nil {
+		common.Error("Error while generating BigQuery insert ID: %v", err)
+		return ""
+	}
+	return strconv.FormatInt(now.UnixNano(), 10) + "-" + suffix
+}
+
+// mustEventTracker panics if the tracker cannot be created. It is used for
+// the package's own event kinds, whose struct definitions are fixed.
+func mustEventTracker[T any](config EventTrackerConfig) *EventTracker[T] {
+	t, err := NewEventTracker[T](config)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// WithInsertID sets the function computing each row's insertId, which
+// BigQuery uses to de-duplicate retried inserts. By default a timestamp
+// combined with a random suffix is used.
+func (t *EventTracker[T]) WithInsertID(fn func(row *T, now time.Time) string) *EventTracker[T] {
+	if fn != nil {
+		t.insertID = fn
+	}
+	return t
+}
+
+// Schema returns the table schema derived from T.
+func (t *EventTracker[T]) Schema() *bigquery.TableSchema {
+	return t.schema
+}
+
+// CreateTable ensures the dataset exists and creates the daily table named by
+// the YYYYMMDD string d. It is suitable for cron handlers that pre-create
+// tomorrow's table.
+func (t *EventTracker[T]) CreateTable(c context.Context, d string) error {
+	common.Info(">>>> EventTracker.CreateTable %s.%s", t.config.DatasetID, d)
+
+	if err := gcp.CreateDatasetIfNotExists(c, t.config.ProjectID, t.config.DatasetID); err != nil {
+		common.Error("Error ensuring dataset %s: %v", t.config.DatasetID, err)
+		return err
+	}
+
+	table, err := t.tableDefinition(d)
+	if err != nil {
+		return err
+	}
+	return gcp.CreateTableInBigQuery(c, table)
+}
+
+// Store streams row into today's table, creating the table first when
+// BigQuery reports that it does not exist.
+func (t *EventTracker[T]) Store(c context.Context, row *T) error {
+	now := time.Now()
+	req := t.insertRequest(row, now)
+	tableName := now.Format("20060102")
+	return insertWithTableCreation(c, t.config.ProjectID, t.config.DatasetID, tableName, req, t.CreateTable)
+}
+
+// tableDefinition describes the daily table named d
+func (t *EventTracker[T]) tableDefinition(d string) (*bigquery.Table, error) {
+	if len(d) != 8 {
+		return nil, errors.New("table name is badly formatted - expected 8 characters")
+	}
+	return &bigquery.Table{
+		TableReference: &bigquery.TableReference{
+			ProjectId: t.config.ProjectID,
+			DatasetId: t.config.DatasetID,
+			TableId:   d,
+		},
+		FriendlyName: t.config.FriendlyName,
+		Description:  t.config.Description,
+		Schema:       t.schema,
+	}, nil
+}
+
+// insertRequest builds the streaming insert request for a single row
+func (t *EventTracker[T]) insertRequest(row *T, now time.Time) *bigquery.TableDataInsertAllRequest {
+	return &bigquery.TableDataInsertAllRequest{
+		Kind: "bigquery#tableDataInsertAllRequest",
+		Rows: []*bigquery.TableDataInsertAllRequestRows{
+			{
+				InsertId: t.insertID(row, now),
+				Json:     rowValues(reflect.ValueOf(row).Elem()),
+			},
+		},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// columnName returns the column name of a struct field, or "" to skip it
+func columnName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name := f.Tag.Get("bigquery")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name
+}
+
+// schemaFields derives the BigQuery columns of a struct type
+func schemaFields(typ reflect.Type) ([]*bigquery.TableFieldSchema, error) {
+	var fields []*bigquery.TableFieldSchema
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name := columnName(f)
+		if name == "" {
+			continue
+		}
+
+		field, err := schemaField(name, f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		field.Description = name
+		if desc := f.Tag.Get("description"); desc != "" {
+			field.Description = desc
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// schemaField maps a Go type to a BigQuery column
+func schemaField(name string, typ reflect.Type) (*bigquery.TableFieldSchema, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	field := &bigquery.TableFieldSchema{Name: name}
+	if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+		elem, err := schemaField(name, typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if elem.Mode == "REPEATED" {
+			return nil, errors.New("nested slices are not supported")
+		}
+		elem.Mode = "REPEATED"
+		return elem, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		field.Type = "STRING"
+	case reflect.Bool:
+		field.Type = "BOOLEAN"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		field.Type = "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		field.Type = "FLOAT"
+	case reflect.Slice:
+		field.Type = "BYTES"
+	case reflect.Struct:
+		if typ == timeType {
+			field.Type = "TIMESTAMP"
+			break
+		}
+		nested, err := schemaFields(typ)
+		if err != nil {
+			return nil, err
+		}
+		field.Type = "RECORD"
+		field.Fields = nested
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+	return field, nil
+}
+
+// rowValues converts a struct value into the JSON row expected by the
+// streaming insert API, using the same column names as schemaFields.
+func rowValues(v reflect.Value) map[string]bigquery.JsonValue {
+	row := make(map[string]bigquery.JsonValue, v.NumField())
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		name := columnName(typ.Field(i))
+		if name == "" {
+			continue
+		}
+		row[name] = columnValue(v.Field(i))
+	}
+	return row
+}
+
+// columnValue converts a field value, expanding nested structs and slices
+func columnValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		return rowValues(v)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = columnValue(v.Index(i))
+		}
+		return values
+	}
+	return v.Interface()
+}
@@ -0,0 +1,233 @@
+// Copyright 2025 Patrick Deglon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+type signupAddress struct {
+	City    string
+	Country string
+}
+
+type signupEvent struct {
+	Time     time.Time
+	Email    string `bigquery:"email" description:"Address used to sign up"`
+	Plan     *string
+	Seats    int
+	Score    float64
+	Trial    bool
+	Tags     []string
+	Avatar   []byte
+	Address  signupAddress
+	Internal string `bigquery:"-"`
+	private  string
+}
+
+func TestEventTrackerSchema(t *testing.T) {
+	tracker, err := NewEventTracker[signupEvent](EventTrackerConfig{ProjectID: "p", DatasetID: "signups"})
+	if err != nil {
+		t.Fatalf("NewEventTracker failed: %v", err)
+	}
+
+	want := []*bigquery.TableFieldSchema{
+		{Name: "Time", Type: "TIMESTAMP", Description: "Time"},
+		{Name: "email", Type: "STRING", Description: "Address used to sign up"},
+		{Name: "Plan", Type: "STRING", Description: "Plan"},
+		{Name: "Seats", Type: "INTEGER", Description: "Seats"},
+		{Name: "Score", Type: "FLOAT", Description: "Score"},
+		{Name: "Trial", Type: "BOOLEAN", Description: "Trial"},
+		{Name: "Tags", Type: "STRING", Mode: "REPEATED", Description: "Tags"},
+		{Name: "Avatar", Type: "BYTES", Description: "Avatar"},
+		{Name: "Address", Type: "RECORD", Description: "Address", Fields: []*bigquery.TableFieldSchema{
+			{Name: "City", Type: "STRING", Description: "City"},
+			{Name: "Country", Type: "STRING", Description: "Country"},
+		}},
+	}
+	if got := tracker.Schema().Fields; !reflect.DeepEqual(got, want) {
+		for i := range got {
+			t.Logf("got[%d] = %+v", i, got[i])
+		}
+		t.Errorf("schema mismatch")
+	}
+}
+
+func TestEventTrackerInsertRequest(t *testing.T) {
+	tracker, err := NewEventTracker[signupEvent](EventTrackerConfig{ProjectID: "p", DatasetID: "signups"})
+	if err != nil {
+		t.Fatalf("NewEventTracker failed: %v", err)
+	}
+	tracker.WithInsertID(func(e *signupEvent, now time.Time) string {
+		return e.Email + "-" + now.Format("20060102")
+	})
+
+	plan := "pro"
+	event := &signupEvent{
+		Time:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Email:    "user@example.com",
+		Plan:     &plan,
+		Seats:    3,
+		Score:    0.5,
+		Trial:    true,
+		Tags:     []string{"a", "b"},
+		Avatar:   []byte{1, 2},
+		Address:  signupAddress{City: "Paris", Country: "FR"},
+		Internal: "hidden",
+	}
+	got := tracker.insertRequest(event, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	want := &bigquery.TableDataInsertAllRequest{
+		Kind: "bigquery#tableDataInsertAllRequest",
+		Rows: []*bigquery.TableDataInsertAllRequestRows{
+			{
+				InsertId: "user@example.com-20250102",
+				Json: map[string]bigquery.JsonValue{
+					"Time":    event.Time,
+					"email":   "user@example.com",
+					"Plan":    "pro",
+					"Seats":   3,
+					"Score":   0.5,
+					"Trial":   true,
+					"Tags":    []interface{}{"a", "b"},
+					"Avatar":  []byte{1, 2},
+					"Address": map[string]bigquery.JsonValue{"City": "Paris", "Country": "FR"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("insertRequest mismatch\n got %#v\nwant %#v", got.Rows[0].Json, want.Rows[0].Json)
+	}
+
+	event.Plan = nil
+	if v := tracker.insertRequest(event, time.Now()).Rows[0].Json["Plan"]; v != nil {
+		t.Errorf("nil pointer stored as %v, want nil", v)
+	}
+}
+
+func TestEventTrackerDefaultInsertID(t *testing.T) {
+	tracker, err := NewEventTracker[signupEvent](EventTrackerConfig{ProjectID: "p", DatasetID: "signups"})
+	if err != nil {
+		t.Fatalf("NewEventTracker failed: %v", err)
+	}
+	now := time.Unix(0, 42)
+	a := tracker.insertRequest(&signupEvent{}, now).Rows[0].InsertId
+	b := tracker.insertRequest(&signupEvent{}, now).Rows[0].InsertId
+	if !strings.HasPrefix(a, "42-") || a == b {
+		t.Errorf("insert IDs %q and %q should be unique and timestamp-prefixed", a, b)
+	}
+
+	saved := generateToken
+	defer func() { generateToken = saved }()
+	generateToken = func(int) (string, error) { return "", errors.New("entropy unavailable") }
+	if id := tracker.insertRequest(&signupEvent{}, now).Rows[0].InsertId; id != "" {
+		t.Errorf("insert ID = %q after a token failure, want empty", id)
+	}
+}
+
+func TestNewEventTrackerErrors(t *testing.T) {
+	config := EventTrackerConfig{ProjectID: "p", DatasetID: "d"}
+	if _, err := NewEventTracker[string](config); err == nil {
+		t.Error("expected error for non-struct type")
+	}
+	if _, err := NewEventTracker[struct{ Attrs map[string]string }](config); err == nil {
+		t.Error("expected error for map field")
+	}
+	if _, err := NewEventTracker[struct{ Matrix [][]int }](config); err == nil {
+		t.Error("expected error for nested slice field")
+	}
+	if _, err := NewEventTracker[signupEvent](EventTrackerConfig{}); err == nil {
+		t.Error("expected error for missing dataset")
+	}
+}
+
+func TestEventTrackerTableDefinition(t *testing.T) {
+	tracker, _ := NewEventTracker[signupEvent](EventTrackerConfig{ProjectID: "p", DatasetID: "signups", FriendlyName: "Signups"})
+	if _, err := tracker.tableDefinition("2025"); err == nil {
+		t.Error("expected error for malformed table name")
+	}
+	table, err := tracker.tableDefinition("20250102")
+	if err != nil {
+		t.Fatalf("tableDefinition failed: %v", err)
+	}
+	if table.TableReference.ProjectId != "p" || table.TableReference.DatasetId != "signups" || table.TableReference.TableId != "20250102" {
+		t.Errorf("unexpected table reference %+v", table.TableReference)
+	}
+	if table.FriendlyName != "Signups" || table.Schema != tracker.Schema() {
+		t.Error("table definition does not use the tracker config and schema")
+	}
+}
+
+func TestEventTrackerStore(t *testing.T) {
+	saved := streamDataFn
+	defer func() { streamDataFn = saved }()
+
+	var project, dataset, table string
+	var req *bigquery.TableDataInsertAllRequest
+	streamDataFn = func(_ context.Context, p, d, tb string, r *bigquery.TableDataInsertAllRequest) error {
+		project, dataset, table, req = p, d, tb, r
+		return nil
+	}
+
+	tracker, _ := NewEventTracker[signupEvent](EventTrackerConfig{ProjectID: "p", DatasetID: "signups"})
+	if err := tracker.Store(context.Background(), &signupEvent{Email: "user@example.com"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if project != "p" || dataset != "signups" || table != time.Now().Format("20060102") {
+		t.Errorf("streamed to %s.%s.%s", project, dataset, table)
+	}
+	if req == nil || req.Rows[0].Json["email"] != "user@example.com" {
+		t.Error("streamed request does not contain the row")
+	}
+}
+
+// TestClickTrackerSchema checks that the derived click schema keeps the
+// columns of the previous hand-written table definition.
+func TestClickTrackerSchema(t *testing.T) {
+	fields := clickTracker.Schema().Fields
+	if len(fields) != 47 {
+		t.Fatalf("click schema has %d columns, want 47", len(fields))
+	}
+	byName := map[string]*bigquery.TableFieldSchema{}
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	checks := []struct{ name, typ, desc string }{
+		{"Time", "TIMESTAMP", "Time"},
+		{"Loc_Physical_Ms", "STRING", "Loc_Physical_Ms"},
+		{"Lat", "FLOAT", "City latitude"},
+		{"Lon", "FLOAT", "City longitude"},
+		{"IsBot", "BOOLEAN", "IsBot"},
+	}
+	for _, c := range checks {
+		f := byName[c.name]
+		if f == nil || f.Type != c.typ || f.Description != c.desc {
+			t.Errorf("column %s = %+v, want type %s description %q", c.name, f, c.typ, c.desc)
+		}
+	}
+
+	click := &Click{RemoteAddr: "192.0.2.1", Time: time.Unix(0, 5)}
+	if id := clickTracker.insertRequest(click, time.Now()).Rows[0].InsertId; id != "192.0.2.15" {
+		t.Errorf("click insertId = %q", id)
+	}
+}
@@ -78,20 +78,12 @@ func TrackVisit(w http.ResponseWriter, r *http.Request, cookie string) {
 
 	// Country "ZZ" is used by App Engine when the origin is unknown and
 	// usually indicates bot activity.
-	if r.Header.Get("X-AppEngine-Country") == "ZZ" {
-		common.Info("TrackVisit: Country is ZZ - most likely a bot, ignoring")
+	geo := common.GeoFromRequest(r)
+	if geo.IsUnknownCountry() {
+		common.Info("TrackVisit: Country is %s - most likely a bot, ignoring", geo.Country)
 		return
 	}
 
-	// Extract location information if provided by App Engine headers
-	lat := float64(0)
-	lon := float64(0)
-	latlon := strings.Split(r.Header.Get("X-AppEngine-CityLatLong"), ",")
-	if len(latlon) == 2 {
-		lat = common.S2F(latlon[0])
-		lon = common.S2F(latlon[1])
-	}
-
 	// Lookup the current session in memcache.  If none exists, create a new
 	// session identifier and store it with a 30 minute expiration so any
 	// subsequent calls will reuse the same session value.
@@ -126,11 +118,11 @@ func TrackVisit(w http.ResponseWriter, r *http.Request, cookie string) {
 		InstanceId:     appengine.InstanceID(),
 		VersionId:      appengine.VersionID(c),
 		Scheme:         r.URL.Scheme,
-		Country:        r.Header.Get("X-AppEngine-Country"),
-		Region:         r.Header.Get("X-AppEngine-Region"),
-		City:           r.Header.Get("X-AppEngine-City"),
-		Lat:            lat,
-		Lon:            lon,
+		Country:        geo.Country,
+		Region:         geo.Region,
+		City:           geo.City,
+		Lat:            geo.Lat,
+		Lon:            geo.Lon,
 		AcceptLanguage: r.Header.Get("Accept-Language"),
 		UserAgent:      r.Header.Get("User-Agent"),
 		IsMobile:       ua.Mobile(),
@@ -168,7 +160,7 @@ func TrackEventDetails(w http.ResponseWriter, r *http.Request, cookie, category,
 	defer cancel()
 	reqCopy := r.Clone(ctx)
 
-	go func() {
+	runAsync(func() {
 		c := ctx
 		common.Info(">>>> TrackEventDetails")
 
@@ -184,13 +176,7 @@ func TrackEventDetails(w http.ResponseWriter, r *http.Request, cookie, category,
 		}
 
 		// Extract location information if present
-		lat := float64(0)
-		lon := float64(0)
-		latlon := strings.Split(reqCopy.Header.Get("X-AppEngine-CityLatLong"), ",")
-		if len(latlon) == 2 {
-			lat = common.S2F(latlon[0])
-			lon = common.S2F(latlon[1])
-		}
+		geo := common.GeoFromRequest(reqCopy)
 
 		// Use memcache to deduplicate events. The key is based on a hash
 		// of the remote address and user agent to approximate a visitor
@@ -228,11 +214,11 @@ func TrackEventDetails(w http.ResponseWriter, r *http.Request, cookie, category,
 			InstanceId:     appengine.InstanceID(),
 			VersionId:      appengine.VersionID(c),
 			Scheme:         reqCopy.URL.Scheme,
-			Country:        reqCopy.Header.Get("X-AppEngine-Country"),
-			Region:         reqCopy.Header.Get("X-AppEngine-Region"),
-			City:           reqCopy.Header.Get("X-AppEngine-City"),
-			Lat:            lat,
-			Lon:            lon,
+			Country:        geo.Country,
+			Region:         geo.Region,
+			City:           geo.City,
+			Lat:            geo.Lat,
+			Lon:            geo.Lon,
 			AcceptLanguage: reqCopy.Header.Get("Accept-Language"),
 			UserAgent:      reqCopy.Header.Get("User-Agent"),
 			IsMobile:       ua.Mobile(),
@@ -256,7 +242,7 @@ func TrackEventDetails(w http.ResponseWriter, r *http.Request, cookie, category,
 		} else {
 			common.Info("Event stored in BigQuery")
 		}
-	}()
+	})
 }
 
 func TrackEvent(w http.ResponseWriter, r *http.Request, cookie string) {
@@ -305,7 +291,7 @@ func TrackTouchPointWithUser(r *http.Request, category, action, label string, us
 	defer cancel()
 	reqCopy := r.Clone(ctx)
 
-	go func() {
+	runAsync(func() {
 		c := ctx
 		common.Info(">>>> TrackTouchPointWithUser category=%s action=%s label=%s userID=%d", category, action, label, userID)
 
@@ -315,8 +301,9 @@ func TrackTouchPointWithUser(r *http.Request, category, action, label string, us
 			common.Info("TrackTouchPointWithUser: Events from Bots, ignoring")
 			return
 		}
-		if reqCopy.Header.Get("X-AppEngine-Country") == "ZZ" {
-			common.Info("TrackTouchPointWithUser: Country is ZZ - most likely a bot, ignoring")
+		geo := common.GeoFromRequest(reqCopy)
+		if geo.IsUnknownCountry() {
+			common.Info("TrackTouchPointWithUser: Country is %s - most likely a bot, ignoring", geo.Country)
 			return
 		}
 
@@ -357,9 +344,9 @@ func TrackTouchPointWithUser(r *http.Request, category, action, label string, us
 			Host:        reqCopy.Host,
 			RemoteAddr:  reqCopy.RemoteAddr,
 			UserAgent:   uaHeader,
-			Country:     reqCopy.Header.Get("X-AppEngine-Country"),
-			Region:      reqCopy.Header.Get("X-AppEngine-Region"),
-			City:        reqCopy.Header.Get("X-AppEngine-City"),
+			Country:     geo.Country,
+			Region:      geo.Region,
+			City:        geo.City,
 			PayloadJSON: payloadJSON,
 		}
 
@@ -368,7 +355,7 @@ func TrackTouchPointWithUser(r *http.Request, category, action, label string, us
 		} else {
 			common.Info("Touch point stored in BigQuery")
 		}
-	}()
+	})
 }
 
 func TrackRobots(r *http.Request) {
@@ -380,6 +367,7 @@ func TrackRobots(r *http.Request) {
 	userAgent := r.Header.Get("User-Agent")
 	ua := user_agent.New(r.Header.Get("User-Agent"))
 	botName, botVersion := ua.Browser()
+	geo := common.GeoFromRequest(r)
 	// Build the RobotPage entry to persist
 	robotPage := RobotPage{
 		Time:       time.Now(),
@@ -388,9 +376,9 @@ func TrackRobots(r *http.Request) {
 		Host:       r.Host,
 		RemoteAddr: r.RemoteAddr,
 		UserAgent:  userAgent,
-		Country:    r.Header.Get("X-AppEngine-Country"),
-		Region:     r.Header.Get("X-AppEngine-Region"),
-		City:       r.Header.Get("X-AppEngine-City"),
+		Country:    geo.Country,
+		Region:     geo.Region,
+		City:       geo.City,
 		BotName:    botName,
 		BotVersion: botVersion,
 	}
@@ -0,0 +1,102 @@
+package validation
+
+// A RuleSet names the constraints a kind of field always has, so handlers
+// validate a "username" the same way everywhere:
+//
+//	v := validation.NewValidator()
+//	v.Add(validation.UsernameRules.Validate("username", req.Username))
+//	v.Add(validation.EmailRules.Validate("email", req.Email))
+//
+// Rules run in order and stop at the first failure, so each field reports
+// one message.
+
+import "regexp"
+
+// slugPattern matches lowercase words separated by single dashes
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Rule validates a string field. Required, Email and the other
+// single-value validators in this package are Rules; use MinLengthRule,
+// MaxLengthRule and OneOfRule for the parameterized ones.
+type Rule func(field, value string) *ValidationError
+
+// RuleSet is an ordered list of rules applied to one kind of field
+type RuleSet struct {
+	rules []Rule
+}
+
+// NewRuleSet creates a RuleSet running rules in the given order
+func NewRuleSet(rules ...Rule) RuleSet {
+	return RuleSet{rules: append([]Rule(nil), rules...)}
+}
+
+// With returns a copy of the set with rules appended, leaving the original
+// unchanged:
+//
+//	handleRules := validation.UsernameRules.With(validation.NoXSS)
+func (rs RuleSet) With(rules ...Rule) RuleSet {
+	combined := make([]Rule, 0, len(rs.rules)+len(rules))
+	combined = append(combined, rs.rules...)
+	return RuleSet{rules: append(combined, rules...)}
+}
+
+// Validate runs the rules in order and returns the first failure, or nil
+func (rs RuleSet) Validate(field, value string) *ValidationError {
+	for _, rule := range rs.rules {
+		if err := rule(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinLengthRule returns a Rule applying MinLength with min
+func MinLengthRule(min int) Rule {
+	return func(field, value string) *ValidationError {
+		return MinLength(field, value, min)
+	}
+}
+
+// MaxLengthRule returns a Rule applying MaxLength with max
+func MaxLengthRule(max int) Rule {
+	return func(field, value string) *ValidationError {
+		return MaxLength(field, value, max)
+	}
+}
+
+// OneOfRule returns a Rule applying OneOf with allowed
+func OneOfRule(allowed ...string) Rule {
+	return func(field, value string) *ValidationError {
+		return OneOf(field, value, allowed)
+	}
+}
+
+// Slug validates a URL slug: lowercase letters and digits in words
+// separated by single dashes, such as "spring-sale-2025".
+func Slug(field, value string) *ValidationError {
+	if value == "" {
+		return nil // Use Required() separately if the field is mandatory
+	}
+
+	if !slugPattern.MatchString(value) {
+		return &ValidationError{
+			Field:   field,
+			Message: "must contain only lowercase letters, numbers, and single dashes between words",
+			Code:    "invalid_format",
+		}
+	}
+	return nil
+}
+
+// Predefined rule sets for common fields
+var (
+	// UsernameRules requires 3 to 32 letters, numbers, dashes, or underscores
+	UsernameRules = NewRuleSet(Required, MinLengthRule(3), MaxLengthRule(32), AlphanumericDashUnderscore)
+
+	// SlugRules requires a slug of at most 64 characters
+	SlugRules = NewRuleSet(Required, MaxLengthRule(64), Slug)
+
+	// EmailRules requires an email address of at most 254 characters, the
+	// longest address SMTP allows
+	EmailRules = NewRuleSet(Required, MaxLengthRule(254), Email)
+)
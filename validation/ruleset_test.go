@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPredefinedRuleSets(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    RuleSet
+		value    string
+		wantCode string
+	}{
+		{"valid username", UsernameRules, "jane_doe-1", ""},
+		{"empty username", UsernameRules, "", "required"},
+		{"short username", UsernameRules, "jd", "min_length"},
+		{"long username", UsernameRules, strings.Repeat("a", 33), "max_length"},
+		{"username with spaces", UsernameRules, "jane doe", "invalid_format"},
+		{"valid slug", SlugRules, "spring-sale-2025", ""},
+		{"uppercase slug", SlugRules, "Spring-Sale", "invalid_format"},
+		{"double dash slug", SlugRules, "spring--sale", "invalid_format"},
+		{"trailing dash slug", SlugRules, "spring-", "invalid_format"},
+		{"long slug", SlugRules, strings.Repeat("a", 65), "max_length"},
+		{"valid email", EmailRules, "jane@example.com", ""},
+		{"invalid email", EmailRules, "jane@", "invalid_email"},
+		{"long email", EmailRules, strings.Repeat("a", 250) + "@example.com", "max_length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate("field", tt.value)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("Validate(%q) = %v, want nil", tt.value, err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.wantCode || err.Field != "field" {
+				t.Errorf("Validate(%q) = %+v, want code %s", tt.value, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRuleSetComposition(t *testing.T) {
+	var calls []string
+	record := func(name string) Rule {
+		return func(field, value string) *ValidationError {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	base := NewRuleSet(record("first"), record("second"))
+	extended := base.With(record("third"), OneOfRule("red", "green"))
+
+	if err := extended.Validate("color", "red"); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+	if got := strings.Join(calls, ","); got != "first,second,third" {
+		t.Errorf("Rules ran as %s, want first,second,third", got)
+	}
+
+	calls = nil
+	if err := base.Validate("color", "blue"); err != nil {
+		t.Errorf("With modified the original set: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("Expected the base set to run 2 rules, ran %d", len(calls))
+	}
+
+	if err := extended.Validate("color", "blue"); err == nil || err.Code != "invalid_value" {
+		t.Errorf("Validate(blue) = %+v, want invalid_value", err)
+	}
+}
+
+func TestRuleSetStopsAtFirstFailure(t *testing.T) {
+	rules := NewRuleSet(Required, MinLengthRule(3), AlphanumericDashUnderscore)
+	err := rules.Validate("username", "a!")
+	if err == nil || err.Code != "min_length" {
+		t.Errorf("Validate() = %+v, want min_length only", err)
+	}
+}
+
+func TestRuleSetWithValidator(t *testing.T) {
+	v := NewValidator()
+	v.Add(UsernameRules.Validate("username", "jd")).
+		Add(EmailRules.Validate("email", "jane@example.com")).
+		Add(SlugRules.Validate("slug", "Not A Slug"))
+
+	if !v.HasErrors() {
+		t.Fatal("Expected validation errors")
+	}
+	var errs ValidationErrors
+	if !errors.As(v.Errors(), &errs) || len(errs) != 2 {
+		t.Fatalf("Errors() = %v, want 2 errors", v.Errors())
+	}
+	if errs[0].Field != "username" || errs[1].Field != "slug" {
+		t.Errorf("Unexpected fields %q and %q", errs[0].Field, errs[1].Field)
+	}
+	if got := v.Errors().Error(); !strings.HasPrefix(got, "username: must be at least 3 characters") {
+		t.Errorf("Errors() = %q", got)
+	}
+}
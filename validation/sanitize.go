@@ -0,0 +1,148 @@
+package validation
+
+// Normalization helpers complement the validators in validation.go. Handlers
+// should normalize input first and then validate the normalized value, e.g.:
+//
+//	email := validation.NormalizeEmail(req.Email)
+//	v.Add(validation.Required("email", email)).Add(validation.Email("email", email))
+//
+// Normalizers never fail; they return a best-effort cleaned value.
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// NormalizeEmail trims surrounding whitespace and lowercases an email address
+// so the same mailbox is always stored the same way.
+func NormalizeEmail(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// NormalizeEmailStripTag normalizes an email like NormalizeEmail and also
+// removes a "+tag" suffix from the local part ("jane+news@example.com"
+// becomes "jane@example.com"). Use it for de-duplication, not for sending
+// mail, since not every provider treats plus-tags as aliases.
+func NormalizeEmailStripTag(value string) string {
+	email := NormalizeEmail(value)
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + domain
+}
+
+// TrimAndCollapseSpaces trims the value and collapses every run of
+// whitespace (including Unicode spaces such as NBSP and tabs/newlines) into
+// a single ASCII space.
+func TrimAndCollapseSpaces(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// allowedHTMLTags lists the tags SanitizeHTML keeps. All attributes are
+// dropped except href on links.
+var allowedHTMLTags = map[string]bool{
+	"a":          true,
+	"b":          true,
+	"blockquote": true,
+	"br":         true,
+	"code":       true,
+	"em":         true,
+	"i":          true,
+	"li":         true,
+	"ol":         true,
+	"p":          true,
+	"pre":        true,
+	"strong":     true,
+	"u":          true,
+	"ul":         true,
+}
+
+// droppedContentTags lists tags whose content is removed along with the tag.
+var droppedContentTags = map[string]bool{
+	"embed":    true,
+	"iframe":   true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+}
+
+// SanitizeHTML strips every tag that is not in a small formatting allowlist
+// (b, i, em, strong, p, br, lists, code, pre, blockquote, a). Script-like
+// elements are removed together with their content, all attributes except
+// safe link targets are dropped, and text is re-escaped. Use it for rich text
+// that must be stored as HTML; plain text fields should use NoXSS instead.
+func SanitizeHTML(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(value))
+	skipDepth := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// io.EOF or malformed input: return what has been sanitized so far
+			return b.String()
+
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if droppedContentTags[tok.Data] {
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 || !allowedHTMLTags[tok.Data] {
+				continue
+			}
+			b.WriteString("<" + tok.Data)
+			if tok.Data == "a" {
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" && isSafeHref(attr.Val) {
+						b.WriteString(` href="` + html.EscapeString(attr.Val) + `"`)
+					}
+				}
+			}
+			b.WriteString(">")
+
+		case html.EndTagToken:
+			tok := z.Token()
+			if droppedContentTags[tok.Data] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 || !allowedHTMLTags[tok.Data] || tok.Data == "br" {
+				continue
+			}
+			b.WriteString("</" + tok.Data + ">")
+		}
+	}
+}
+
+// isSafeHref reports whether a link target uses a safe scheme or is relative.
+func isSafeHref(href string) bool {
+	h := strings.ToLower(strings.TrimSpace(href))
+	if strings.HasPrefix(h, "http://") || strings.HasPrefix(h, "https://") || strings.HasPrefix(h, "mailto:") {
+		return true
+	}
+	// Relative links without a scheme
+	return !strings.Contains(h, ":") && !strings.HasPrefix(h, "//")
+}
@@ -0,0 +1,107 @@
+package validation
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"already normalized", "jane@example.com", "jane@example.com"},
+		{"mixed case", "Jane.Doe@Example.COM", "jane.doe@example.com"},
+		{"surrounding spaces", "  jane@example.com\t", "jane@example.com"},
+		{"unicode whitespace", "\u00a0jane@example.com\u2003", "jane@example.com"},
+		{"plus tag kept", "Jane+News@example.com", "jane+news@example.com"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.value); got != tt.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmailStripTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plus tag", "Jane+News@Example.com", "jane@example.com"},
+		{"multiple plus", "jane+a+b@example.com", "jane@example.com"},
+		{"no tag", "jane@example.com", "jane@example.com"},
+		{"leading plus kept", "+jane@example.com", "+jane@example.com"},
+		{"no at sign", "jane+tag", "jane+tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmailStripTag(tt.value); got != tt.want {
+				t.Errorf("NormalizeEmailStripTag(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimAndCollapseSpaces(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"single spaces", "hello world", "hello world"},
+		{"multiple spaces", "  hello    world  ", "hello world"},
+		{"tabs and newlines", "hello\t\n\r world", "hello world"},
+		{"nbsp and em space", "hello\u00a0\u2003world", "hello world"},
+		{"ideographic space", "\u3000hello\u3000", "hello"},
+		{"only whitespace", " \t\u00a0 ", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrimAndCollapseSpaces(tt.value); got != tt.want {
+				t.Errorf("TrimAndCollapseSpaces(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain text", "hello world", "hello world"},
+		{"allowed tags", "<p>Hello <b>bold</b> and <em>em</em></p>", "<p>Hello <b>bold</b> and <em>em</em></p>"},
+		{"script removed with content", "hi<script>alert(1)</script>there", "hithere"},
+		{"style removed with content", "<style>body{}</style>text", "text"},
+		{"disallowed tag unwrapped", "<div><span>text</span></div>", "text"},
+		{"attributes stripped", `<p onclick="evil()" class="x">text</p>`, "<p>text</p>"},
+		{"safe link kept", `<a href="https://example.com" onclick="x">link</a>`, `<a href="https://example.com">link</a>`},
+		{"javascript link dropped", `<a href="javascript:alert(1)">link</a>`, "<a>link</a>"},
+		{"relative link kept", `<a href="/docs">docs</a>`, `<a href="/docs">docs</a>`},
+		{"img removed", `<img src=x onerror=alert(1)>caption`, "caption"},
+		{"br self closing", "line<br/>next", "line<br>next"},
+		{"text escaped", "1 &lt; 2 & 3", "1 &lt; 2 &amp; 3"},
+		{"unicode text", "café <i>naïve</i>", "café <i>naïve</i>"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeHTML(tt.value)
+			if got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			if err := NoXSS("field", got); err != nil {
+				t.Errorf("SanitizeHTML(%q) output fails NoXSS: %q", tt.value, got)
+			}
+		})
+	}
+}
@@ -353,6 +353,235 @@ func PastTime(field string, value time.Time) *ValidationError {
 	return nil
 }
 
+// Equal validates that a field matches another field, such as a password
+// confirmation. The values are not included in the message.
+func Equal(field, value, otherField, otherValue string) *ValidationError {
+	if value != otherValue {
+		return &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must match %s", otherField),
+			Code:    "mismatch",
+		}
+	}
+	return nil
+}
+
+// TimeAfter validates that a is strictly after b, such as the end of a date
+// range. Zero times are skipped; use Required-style checks separately.
+func TimeAfter(field string, a, b time.Time) *ValidationError {
+	if a.IsZero() || b.IsZero() {
+		return nil
+	}
+
+	if !a.After(b) {
+		return &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be after %s", b.Format(time.RFC3339)),
+			Code:    "must_be_after",
+		}
+	}
+	return nil
+}
+
+// Common date layouts for DateString. The US and EU layouts differ only in
+// day/month order, so pick the one matching the form's locale.
+const (
+	DateLayoutISO = "2006-01-02"
+	DateLayoutUS  = "01/02/2006"
+	DateLayoutEU  = "02/01/2006"
+)
+
+// DateString validates that a string parses as a date or time with the
+// given time.Parse layout, such as DateLayoutUS.
+func DateString(field, value, layout string) *ValidationError {
+	if value == "" {
+		return nil // Use Required() separately if the field is mandatory
+	}
+
+	if _, err := time.Parse(layout, strings.TrimSpace(value)); err != nil {
+		return &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be a valid date in the format %s", layout),
+			Code:    "invalid_date",
+		}
+	}
+	return nil
+}
+
+// ISO8601 validates that a string is an RFC 3339 timestamp, such as
+// 2025-01-31T14:00:00Z, or an ISO 8601 calendar date such as 2025-01-31.
+func ISO8601(field, value string) *ValidationError {
+	if value == "" {
+		return nil // Use Required() separately if the field is mandatory
+	}
+
+	if _, ok := parseISO8601(value); !ok {
+		return &ValidationError{
+			Field:   field,
+			Message: "must be an ISO 8601 date or timestamp",
+			Code:    "invalid_date",
+		}
+	}
+	return nil
+}
+
+// parseISO8601 parses an RFC 3339 timestamp or a calendar date
+func parseISO8601(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(DateLayoutISO, value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// DateRangeString validates that start and end parse with layout and that
+// start is not after end. An empty layout accepts ISO 8601 values. The
+// error is reported on field, typically the end of the range; empty values
+// are skipped.
+func DateRangeString(field, start, end, layout string) *ValidationError {
+	if start == "" || end == "" {
+		return nil
+	}
+
+	parse := func(value string) (time.Time, bool) {
+		if layout == "" {
+			return parseISO8601(value)
+		}
+		t, err := time.Parse(layout, strings.TrimSpace(value))
+		return t, err == nil
+	}
+
+	s, okStart := parse(start)
+	e, okEnd := parse(end)
+	if !okStart || !okEnd {
+		format := layout
+		if format == "" {
+			format = "ISO 8601"
+		}
+		return &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be a valid date range in the format %s", format),
+			Code:    "invalid_date",
+		}
+	}
+	if s.After(e) {
+		return &ValidationError{
+			Field:   field,
+			Message: "must not be before the start date",
+			Code:    "invalid_date_range",
+		}
+	}
+	return nil
+}
+
+// RequiredIf validates that a field is not empty when otherValue is set,
+// such as a state that is only mandatory once a country is chosen.
+func RequiredIf(field, value, otherValue string) *ValidationError {
+	if strings.TrimSpace(otherValue) == "" {
+		return nil
+	}
+	return Required(field, value)
+}
+
+// CreditCard validates a payment card number with the Luhn checksum.
+// Spaces and dashes are ignored. This is a structural check only; it does
+// not mean the card exists or can be charged.
+func CreditCard(field, value string) *ValidationError {
+	if value == "" {
+		return nil // Use Required() separately if the field is mandatory
+	}
+
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	if len(digits) < 12 || len(digits) > 19 || !luhnValid(digits) {
+		return &ValidationError{
+			Field:   field,
+			Message: "must be a valid card number",
+			Code:    "invalid_card_number",
+		}
+	}
+	return nil
+}
+
+// luhnValid reports whether a string of digits passes the Luhn checksum
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		c := digits[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanLengths maps country codes to their IBAN length (SWIFT IBAN registry)
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16,
+	"BG": 22, "BH": 22, "BI": 27, "BR": 29, "BY": 28, "CH": 21, "CR": 22,
+	"CY": 28, "CZ": 24, "DE": 22, "DJ": 27, "DK": 18, "DO": 28, "EE": 20,
+	"EG": 29, "ES": 24, "FI": 18, "FK": 18, "FO": 18, "FR": 27, "GB": 22,
+	"GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28,
+	"IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30,
+	"KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21,
+	"LY": 25, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27,
+	"MT": 31, "MU": 30, "NI": 28, "NL": 18, "NO": 15, "OM": 23, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33,
+	"SA": 24, "SC": 31, "SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27,
+	"SO": 23, "ST": 25, "SV": 28, "TL": 23, "TN": 24, "TR": 26, "UA": 29,
+	"VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// IBAN validates an International Bank Account Number: the country must be
+// known, the length must match that country and the mod-97 check digits must
+// be correct. Spaces are ignored and letters may be lowercase.
+func IBAN(field, value string) *ValidationError {
+	if value == "" {
+		return nil // Use Required() separately if the field is mandatory
+	}
+
+	iban := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if len(iban) < 4 || ibanLengths[iban[:2]] != len(iban) || !ibanChecksumValid(iban) {
+		return &ValidationError{
+			Field:   field,
+			Message: "must be a valid IBAN",
+			Code:    "invalid_iban",
+		}
+	}
+	return nil
+}
+
+// ibanChecksumValid moves the first four characters to the end, maps
+// letters to 10-35 and checks that the resulting number mod 97 is 1.
+func ibanChecksumValid(iban string) bool {
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for i := 0; i < len(rearranged); i++ {
+		c := rearranged[i]
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
 // NoSQLInjection validates that a string doesn't contain SQL injection patterns.
 // This is a defense-in-depth measure; parameterized queries are still required.
 func NoSQLInjection(field, value string) *ValidationError {
@@ -416,6 +416,227 @@ func TestPastTime(t *testing.T) {
 	}
 }
 
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		other     string
+		wantError bool
+	}{
+		{"matching confirmation", "s3cure-pass", "s3cure-pass", false},
+		{"mismatched confirmation", "s3cure-pass", "s3cure-pas", true},
+		{"empty confirmation", "", "s3cure-pass", true},
+		{"both empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Equal("password_confirmation", tt.value, "password", tt.other)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Equal() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil && contains(err.Error(), tt.value) && tt.value != "" {
+				t.Errorf("Equal() error leaks the value: %v", err)
+			}
+		})
+	}
+}
+
+func TestTimeAfter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		end       time.Time
+		start     time.Time
+		wantError bool
+	}{
+		{"valid range", start.Add(24 * time.Hour), start, false},
+		{"inverted range", start.Add(-24 * time.Hour), start, true},
+		{"empty range", start, start, true},
+		{"missing end", time.Time{}, start, false},
+		{"missing start", start, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TimeAfter("end_date", tt.end, tt.start)
+			if (err != nil) != tt.wantError {
+				t.Errorf("TimeAfter() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDateString(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		layout    string
+		wantError bool
+	}{
+		{"ISO date", "2025-01-31", DateLayoutISO, false},
+		{"US date", "01/31/2025", DateLayoutUS, false},
+		{"EU date", "31/01/2025", DateLayoutEU, false},
+		{"EU date with US layout", "31/01/2025", DateLayoutUS, true},
+		{"surrounding spaces", " 2025-01-31 ", DateLayoutISO, false},
+		{"invalid day", "2025-02-30", DateLayoutISO, true},
+		{"wrong format", "Jan 31 2025", DateLayoutISO, true},
+		{"custom layout", "31 Jan 2025 14:05", "02 Jan 2006 15:04", false},
+		{"empty allowed", "", DateLayoutISO, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DateString("birth_date", tt.value, tt.layout)
+			if (err != nil) != tt.wantError {
+				t.Errorf("DateString() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil && err.Code != "invalid_date" {
+				t.Errorf("DateString() code = %s, want invalid_date", err.Code)
+			}
+		})
+	}
+}
+
+func TestISO8601(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantError bool
+	}{
+		{"timestamp UTC", "2025-01-31T14:00:00Z", false},
+		{"timestamp with offset", "2025-01-31T14:00:00+02:00", false},
+		{"fractional seconds", "2025-01-31T14:00:00.123Z", false},
+		{"calendar date", "2025-01-31", false},
+		{"missing zone", "2025-01-31T14:00:00", true},
+		{"US date", "01/31/2025", true},
+		{"invalid month", "2025-13-01", true},
+		{"empty allowed", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ISO8601("starts_at", tt.value)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ISO8601() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDateRangeString(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		layout   string
+		wantCode string
+	}{
+		{"valid range", "2025-01-01", "2025-01-31", DateLayoutISO, ""},
+		{"same day", "2025-01-01", "2025-01-01", DateLayoutISO, ""},
+		{"inverted range", "2025-01-31", "2025-01-01", DateLayoutISO, "invalid_date_range"},
+		{"EU layout", "02/01/2025", "31/01/2025", DateLayoutEU, ""},
+		{"inverted EU range", "31/01/2025", "02/01/2025", DateLayoutEU, "invalid_date_range"},
+		{"ISO default", "2025-01-01", "2025-01-01T09:00:00Z", "", ""},
+		{"ISO inverted", "2025-01-01T09:00:00Z", "2025-01-01T08:00:00Z", "", "invalid_date_range"},
+		{"unparseable end", "2025-01-01", "soon", DateLayoutISO, "invalid_date"},
+		{"missing start", "", "2025-01-01", DateLayoutISO, ""},
+		{"missing end", "2025-01-01", "", DateLayoutISO, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DateRangeString("end_date", tt.start, tt.end, tt.layout)
+			got := ""
+			if err != nil {
+				got = err.Code
+			}
+			if got != tt.wantCode {
+				t.Errorf("DateRangeString() error = %v, want code %q", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRequiredIf(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		other     string
+		wantError bool
+	}{
+		{"condition not met", "", "", false},
+		{"condition met with value", "CA", "US", false},
+		{"condition met without value", "", "US", true},
+		{"condition met with whitespace", "  ", "US", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RequiredIf("state", tt.value, tt.other)
+			if (err != nil) != tt.wantError {
+				t.Errorf("RequiredIf() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCreditCard(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantError bool
+	}{
+		{"valid Visa test number", "4111111111111111", false},
+		{"valid with spaces", "4111 1111 1111 1111", false},
+		{"valid with dashes", "5555-5555-5555-4444", false},
+		{"valid Amex test number", "378282246310005", false},
+		{"invalid checksum", "4111111111111112", true},
+		{"invalid - letters", "4111a11111111111", true},
+		{"invalid - too short", "42424242", true},
+		{"invalid - too long", "41111111111111111111", true},
+		{"empty string (allowed)", "", false}, // Use Required() separately
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CreditCard("card", tt.value)
+			if (err != nil) != tt.wantError {
+				t.Errorf("CreditCard() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestIBAN(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantError bool
+	}{
+		{"valid GB", "GB82WEST12345698765432", false},
+		{"valid GB with spaces", "GB82 WEST 1234 5698 7654 32", false},
+		{"valid DE", "DE89370400440532013000", false},
+		{"valid FR with letters in BBAN", "FR1420041010050500013M02606", false},
+		{"valid NO lowercase", "no9386011117947", false},
+		{"invalid checksum", "GB82WEST12345698765433", true},
+		{"invalid length for country", "DE8937040044053201300", true},
+		{"invalid - unknown country", "ZZ82WEST12345698765432", true},
+		{"invalid - punctuation", "GB82-WEST-1234-5698-7654", true},
+		{"invalid - too short", "GB8", true},
+		{"empty string (allowed)", "", false}, // Use Required() separately
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := IBAN("iban", tt.value)
+			if (err != nil) != tt.wantError {
+				t.Errorf("IBAN() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
 func TestNoSQLInjection(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -542,6 +763,26 @@ func TestValidator(t *testing.T) {
 		}
 	})
 
+	t.Run("cross-field errors", func(t *testing.T) {
+		start := time.Now()
+		v := NewValidator()
+		v.Add(Equal("password_confirmation", "one", "password", "two"))
+		v.Add(TimeAfter("end_date", start.Add(-time.Hour), start))
+		v.Add(RequiredIf("state", "", "US"))
+
+		err := v.Errors()
+		if err == nil {
+			t.Fatalf("Validator.Errors() = nil, want error")
+		}
+		errs := err.(ValidationErrors)
+		if len(errs) != 3 {
+			t.Fatalf("got %d errors, want 3: %v", len(errs), err)
+		}
+		if errs[0].Code != "mismatch" || errs[1].Code != "must_be_after" || errs[2].Code != "required" {
+			t.Errorf("unexpected codes: %v", errs)
+		}
+	})
+
 	t.Run("multiple errors", func(t *testing.T) {
 		v := NewValidator()
 		v.Add(Required("username", ""))
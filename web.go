@@ -277,8 +277,8 @@ func IsHacker(r *http.Request) bool {
 	// NOTE: Geographic blocking may have GDPR implications - ensure legal basis
 	// is documented and justified (e.g., fraud prevention, security incident response)
 	// Consider: Is this blocking still necessary? Review with legal/compliance team.
-	if r.Header.Get("X-AppEngine-Country") == "UA" {
-		if (r.Header.Get("X-AppEngine-City") == "lviv") || (r.Header.Get("X-AppEngine-City") == "kyiv") {
+	if geo := GeoFromRequest(r); geo.Country == "UA" {
+		if (geo.City == "lviv") || (geo.City == "kyiv") {
 			// Log aggregate data only, not specific city for privacy
 			Info("IsHacker: Suspicious pattern detected from region: UA")
 			gcp.SetMemCacheString(c, "hacker-"+ipHash, "1", 4)
@@ -418,7 +418,8 @@ func GetCSRFToken(r *http.Request) string {
 //	mux := http.NewServeMux()
 //	mux.HandleFunc("/", homeHandler)
 //	secureHandler := common.SecurityHeadersMiddleware(mux)
-//	http.ListenAndServe(":8080", secureHandler)
+//	srv := common.NewServer(":8080", secureHandler, common.ServerOptions{})
+//	srv.ListenAndServeGraceful(context.Background())
 func SecurityHeadersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Prevent clickjacking by blocking iframe embedding
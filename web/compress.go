@@ -0,0 +1,332 @@
+package web
+
+// Response compression shrinks SSR pages and JSON APIs on the wire. It sits
+// inside SecureStack so requests the security layers reject are answered
+// with their short error pages as is:
+//
+//	handler := web.SecureStack(opts)(web.CompressionMiddleware(nil)(mux))
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionMinSize is the smallest response compressed by
+// default. Below roughly a kilobyte the gzip framing and the CPU cost
+// outweigh the savings.
+const DefaultCompressionMinSize = 1024
+
+// CompressionConfig configures CompressionMiddleware
+type CompressionConfig struct {
+	// MinSize is the smallest response body, in bytes, that is compressed.
+	// Smaller responses are sent as is.
+	MinSize int
+
+	// Level is the gzip/deflate compression level, from
+	// gzip.BestSpeed to gzip.BestCompression. Zero uses
+	// gzip.DefaultCompression.
+	Level int
+
+	// SkipTypes lists media types that are already compressed and sent as
+	// is. An entry ending in "/" matches every subtype, e.g. "video/".
+	SkipTypes []string
+}
+
+// DefaultCompressionConfig returns a configuration compressing responses
+// of at least DefaultCompressionMinSize bytes at the default level, except
+// images, audio, video, fonts and archives, which are compressed already.
+// SVG is text and is still compressed.
+func DefaultCompressionConfig() *CompressionConfig {
+	return &CompressionConfig{
+		MinSize: DefaultCompressionMinSize,
+		Level:   gzip.DefaultCompression,
+		SkipTypes: []string{
+			"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+			"audio/", "video/", "font/woff", "font/woff2",
+			"application/zip", "application/gzip", "application/x-gzip",
+			"application/zstd", "application/pdf", "application/octet-stream",
+			"text/event-stream",
+		},
+	}
+}
+
+// CompressionMiddleware compresses responses with gzip or deflate, as
+// negotiated from Accept-Encoding (gzip is preferred on equal weight). A
+// response is compressed only when it is at least cfg.MinSize bytes, has
+// a 2xx status other than 204, is not one of cfg.SkipTypes and carries no
+// Content-Encoding of its own. Error pages are sent as is: they are short
+// and often written by other middlewares. HEAD and Range requests are not
+// compressed. Vary: Accept-Encoding is added to every response so caches
+// keep compressed and plain variants apart. A nil cfg uses
+// DefaultCompressionConfig.
+func CompressionMiddleware(cfg *CompressionConfig) func(http.Handler) http.Handler {
+	if cfg == nil {
+		cfg = DefaultCompressionConfig()
+	}
+	level := cfg.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addVary(w.Header(), "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				cfg:            cfg,
+				encoding:       encoding,
+				level:          level,
+			}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// honouring q-values and "*". It returns "" when neither is acceptable.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		q, ok := weights[encoding]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// addVary appends value to the Vary header unless it is already listed
+func addVary(h http.Header, value string) {
+	for _, line := range h.Values("Vary") {
+		for _, v := range strings.Split(line, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) || strings.TrimSpace(v) == "*" {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
+// compressWriter buffers the start of the response until it knows whether
+// the body reaches the size threshold, then either compresses or passes
+// the response through unchanged.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *CompressionConfig
+	encoding string
+	level    int
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser // nil when passing through
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status != 0 || w.decided {
+		return
+	}
+	// Informational responses are sent straight away and do not end the headers
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	if !w.eligible() {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.cfg.MinSize {
+		if err := w.start(w.eligible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been buffered so far, deciding on compression with
+// the bytes seen up to now, so streamed responses are not held back
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.WriteHeader(http.StatusOK)
+		}
+		if !w.decided {
+			w.start(len(w.buf) >= w.cfg.MinSize && w.eligible())
+		}
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets protocols such as WebSocket take over the connection
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("failed to hijack connection: %w", http.ErrNotSupported)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close sends a response that stayed below the threshold and finishes the
+// compressed stream
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if w.status == 0 {
+			// The handler wrote nothing; let net/http send its default response
+			if len(w.buf) == 0 {
+				return nil
+			}
+			w.status = http.StatusOK
+		}
+		if err := w.start(false); err != nil {
+			return err
+		}
+	}
+	if w.encoder != nil {
+		return w.encoder.Close()
+	}
+	return nil
+}
+
+// eligible reports whether the status and headers allow compression,
+// setting Content-Type from the buffered bytes when the handler did not,
+// since the compressed body could no longer be sniffed
+func (w *compressWriter) eligible() bool {
+	if w.status < 200 || w.status >= 300 || w.status == http.StatusNoContent {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < w.cfg.MinSize {
+			return false
+		}
+	}
+
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		if len(w.buf) == 0 {
+			// Decided again once there is a body to sniff
+			return true
+		}
+		contentType = http.DetectContentType(w.buf)
+		h.Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, skip := range w.cfg.SkipTypes {
+		if mediaType == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(mediaType, skip)) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the headers and the buffered bytes, compressed or not
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+	h := w.Header()
+
+	if compress {
+		var err error
+		switch w.encoding {
+		case "gzip":
+			w.encoder, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		default:
+			w.encoder, err = flate.NewWriter(w.ResponseWriter, w.level)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create %s writer: %w", w.encoding, err)
+		}
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		h.Set("Content-Encoding", w.encoding)
+		// A strong ETag describes the uncompressed bytes
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
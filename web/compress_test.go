@@ -0,0 +1,234 @@
+package web
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNegotiateEncoding verifies Accept-Encoding parsing and preference
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"GZIP", "gzip"},
+		{"gzip;q=0", ""},
+		{"br", ""},
+		{"*", "gzip"},
+		{"*;q=0.1, gzip;q=0", "deflate"},
+		{"identity", ""},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// serveCompressed runs handler behind the default compression middleware
+func serveCompressed(t *testing.T, handler http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/page", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	CompressionMiddleware(nil)(handler).ServeHTTP(rec, r)
+	return rec
+}
+
+// decode returns the response body, decompressing it per Content-Encoding
+func decode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var reader io.Reader = rec.Body
+	switch rec.Header().Get("Content-Encoding") {
+	case "gzip":
+		gz, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("Invalid gzip body: %v", err)
+		}
+		reader = gz
+	case "deflate":
+		reader = flate.NewReader(rec.Body)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return string(body)
+}
+
+// TestCompressionMiddleware verifies negotiation, the size threshold and skipped responses
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"name":"example"},`, 200)
+	small := `{"ok":true}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		wantEncoding   string
+	}{
+		{"gzip", "gzip, deflate", "application/json", http.StatusOK, large, "gzip"},
+		{"deflate", "deflate", "application/json", http.StatusOK, large, "deflate"},
+		{"No Accept-Encoding", "", "application/json", http.StatusOK, large, ""},
+		{"Below threshold", "gzip", "application/json", http.StatusOK, small, ""},
+		{"Sniffed content type", "gzip", "", http.StatusOK, "<html>" + large, "gzip"},
+		{"Already compressed type", "gzip", "image/png", http.StatusOK, large, ""},
+		{"SVG is compressed", "gzip", "image/svg+xml", http.StatusOK, large, "gzip"},
+		{"Error page", "gzip", "text/plain", http.StatusInternalServerError, large, ""},
+		{"Not found", "gzip", "text/html", http.StatusNotFound, large, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCompressed(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				// Write in chunks so the threshold is crossed mid-response
+				for i := 0; i < len(tt.body); i += 100 {
+					io.WriteString(w, tt.body[i:min(i+100, len(tt.body))])
+				}
+			}, tt.acceptEncoding)
+
+			if rec.Code != tt.status {
+				t.Errorf("Status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if got := decode(t, rec); got != tt.body {
+				t.Errorf("Body mismatch: got %d bytes, want %d", len(got), len(tt.body))
+			}
+			if tt.wantEncoding != "" && rec.Body.Len() >= len(tt.body) {
+				t.Errorf("Expected the body to shrink, got %d bytes", rec.Body.Len())
+			}
+		})
+	}
+}
+
+// TestCompressionMiddlewareHeaders verifies headers of compressed responses
+func TestCompressionMiddlewareHeaders(t *testing.T) {
+	body := strings.Repeat("a", 4096)
+
+	rec := serveCompressed(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "4096")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Add("Vary", "Origin")
+		io.WriteString(w, body)
+	}, "gzip")
+
+	if rec.Header().Get("Content-Length") != "" {
+		t.Error("Expected Content-Length to be removed")
+	}
+	if got := rec.Header().Get("ETag"); got != `W/"v1"` {
+		t.Errorf("ETag = %q, want a weak ETag", got)
+	}
+	if got := rec.Header().Values("Vary"); len(got) != 2 {
+		t.Errorf("Vary = %v, want Origin and Accept-Encoding", got)
+	}
+
+	// A handler that encodes its own response is left alone
+	rec = serveCompressed(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, body)
+	}, "gzip")
+	if got := rec.Header().Get("Content-Encoding"); got != "br" {
+		t.Errorf("Content-Encoding = %q, want br", got)
+	}
+	if rec.Body.String() != body {
+		t.Error("Expected the pre-encoded body to pass through")
+	}
+}
+
+// TestCompressionMiddlewareSkippedRequests verifies HEAD and Range requests pass through
+func TestCompressionMiddlewareSkippedRequests(t *testing.T) {
+	handler := CompressionMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, strings.Repeat("a", 4096))
+	}))
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodHead, "/page", nil),
+		httptest.NewRequest(http.MethodGet, "/page", nil),
+	} {
+		r.Header.Set("Accept-Encoding", "gzip")
+		if r.Method == http.MethodGet {
+			r.Header.Set("Range", "bytes=0-99")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: Content-Encoding = %q, want none", r.Method, got)
+		}
+	}
+}
+
+// TestCompressionMiddlewareFlush verifies flushed responses are sent before the handler returns
+func TestCompressionMiddlewareFlush(t *testing.T) {
+	rec := serveCompressed(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first")
+		w.(http.Flusher).Flush()
+		if !underlyingRecorder(w).Flushed {
+			t.Error("Expected Flush to reach the underlying writer")
+		}
+		io.WriteString(w, strings.Repeat("b", 4096))
+	}, "gzip")
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none once flushed below the threshold", got)
+	}
+	if got := decode(t, rec); got != "first"+strings.Repeat("b", 4096) {
+		t.Errorf("Body mismatch: got %d bytes", len(got))
+	}
+}
+
+// underlyingRecorder returns the recorder beneath the compression writer
+func underlyingRecorder(w http.ResponseWriter) *httptest.ResponseRecorder {
+	return w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder)
+}
+
+// TestCompressionInsideSecureStack verifies rejected requests keep their plain error page
+func TestCompressionInsideSecureStack(t *testing.T) {
+	stack := newSecureStackHandlerFor(t, CompressionMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, strings.Repeat("<p>page</p>", 200))
+	})))
+
+	r := secureRequest(http.MethodGet, "/page")
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	stack.ServeHTTP(rec, r)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Security-Policy") == "" {
+		t.Errorf("Expected a compressed page with security headers, got %v", rec.Header())
+	}
+
+	r = secureRequest(http.MethodPost, "/page")
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	stack.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected an uncompressed 403, got %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,154 @@
+package web
+
+// CSP validation catches policy mistakes that browsers silently ignore,
+// such as an unquoted self (which allows a host named "self") or a
+// misspelled scheme, before the header reaches production.
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cspKeywords are the source keywords that must be single-quoted
+var cspKeywords = map[string]bool{
+	"self":                     true,
+	"none":                     true,
+	"unsafe-inline":            true,
+	"unsafe-eval":              true,
+	"unsafe-hashes":            true,
+	"strict-dynamic":           true,
+	"report-sample":            true,
+	"wasm-unsafe-eval":         true,
+	"unsafe-allow-redirects":   true,
+	"inline-speculation-rules": true,
+}
+
+// cspSchemes are the schemes accepted in scheme and host sources
+var cspSchemes = map[string]bool{
+	"http": true, "https": true, "ws": true, "wss": true, "data": true,
+	"blob": true, "mediastream": true, "filesystem": true,
+}
+
+var (
+	// cspNonceOrHash matches 'nonce-...' and 'sha256-...' style sources
+	cspNonceOrHash = regexp.MustCompile(`^'(nonce-[A-Za-z0-9+/_=-]+|sha(256|384|512)-[A-Za-z0-9+/_=-]+)'$`)
+
+	// cspDirectiveName matches values that look like a directive name,
+	// typically a directive pasted into a source list
+	cspDirectiveName = regexp.MustCompile(`^[a-z]+(-[a-z]+)*-(src|src-elem|src-attr|action|ancestors|uri)$`)
+)
+
+// cspDirective is one source list of a SecurityConfig
+type cspDirective struct {
+	name    string
+	sources []string
+}
+
+// cspDirectives lists the configured source lists in header order
+func (c *SecurityConfig) cspDirectives() []cspDirective {
+	return []cspDirective{
+		{"default-src", c.CSPDefaultSrc},
+		{"script-src", c.CSPScriptSrc},
+		{"style-src", c.CSPStyleSrc},
+		{"img-src", c.CSPImgSrc},
+		{"font-src", c.CSPFontSrc},
+		{"connect-src", c.CSPConnectSrc},
+		{"frame-src", c.CSPFrameSrc},
+		{"object-src", c.CSPObjectSrc},
+		{"media-src", c.CSPMediaSrc},
+		{"worker-src", c.CSPWorkerSrc},
+		{"manifest-src", c.CSPManifestSrc},
+		{"form-action", c.CSPFormAction},
+		{"frame-ancestors", c.CSPFrameAncestors},
+		{"base-uri", c.CSPBaseURI},
+	}
+}
+
+// Validate checks every CSP source list and returns all problems found:
+// unquoted keywords such as self, unknown quoted keywords, unknown schemes,
+// values that look like directive names, separators that would break the
+// header, and 'none' combined with other sources.
+func (c *SecurityConfig) Validate() error {
+	var errs []error
+	for _, d := range c.cspDirectives() {
+		for _, source := range d.sources {
+			if err := validateCSPSource(source); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", d.name, err))
+			}
+		}
+		if len(d.sources) > 1 && containsSource(d.sources, "'none'") {
+			errs = append(errs, fmt.Errorf("%s: 'none' cannot be combined with other sources", d.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CSPWarnings reports valid but likely unintended combinations, such as
+// 'unsafe-inline' next to a nonce or hash, where browsers that understand
+// nonces ignore 'unsafe-inline'. SecurityHeadersMiddleware logs each one
+// when it is built.
+func (c *SecurityConfig) CSPWarnings() []string {
+	var warnings []string
+	for _, d := range c.cspDirectives() {
+		if !containsSource(d.sources, "'unsafe-inline'") {
+			continue
+		}
+		for _, source := range d.sources {
+			if cspNonceOrHash.MatchString(source) {
+				warnings = append(warnings, fmt.Sprintf("%s: 'unsafe-inline' is ignored when a nonce or hash is present", d.name))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// validateCSPSource checks a single source expression
+func validateCSPSource(source string) error {
+	if source == "" {
+		return errors.New("empty source")
+	}
+	if strings.ContainsAny(source, " \t\r\n;,") {
+		return fmt.Errorf("source %q contains a separator; use one entry per source", source)
+	}
+
+	lower := strings.ToLower(source)
+	if strings.HasPrefix(source, "'") {
+		keyword := strings.Trim(lower, "'")
+		if len(source) < 2 || !strings.HasSuffix(source, "'") {
+			return fmt.Errorf("source %q has unbalanced quotes", source)
+		}
+		if !cspKeywords[keyword] && !cspNonceOrHash.MatchString(source) {
+			return fmt.Errorf("unknown keyword %s", source)
+		}
+		return nil
+	}
+
+	if cspKeywords[lower] || strings.HasPrefix(lower, "nonce-") || strings.HasPrefix(lower, "sha256-") ||
+		strings.HasPrefix(lower, "sha384-") || strings.HasPrefix(lower, "sha512-") {
+		return fmt.Errorf("keyword %q must be single-quoted ('%s')", source, source)
+	}
+	if cspDirectiveName.MatchString(lower) {
+		return fmt.Errorf("source %q looks like a directive name", source)
+	}
+
+	// Scheme source ("https:") or host source with a scheme ("https://cdn.example.com")
+	if i := strings.Index(lower, ":"); i > 0 && (i == len(lower)-1 || strings.HasPrefix(lower[i:], "://")) {
+		if scheme := lower[:i]; !cspSchemes[scheme] {
+			return fmt.Errorf("unknown scheme %q in %q", scheme, source)
+		}
+	}
+	return nil
+}
+
+// containsSource reports whether sources includes source, ignoring case
+func containsSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/patdeg/common"
+)
+
+// TestSecurityConfigValidate verifies well-formed policies pass and common mistakes are reported
+func TestSecurityConfigValidate(t *testing.T) {
+	for _, config := range []*SecurityConfig{DefaultSecurityConfig(), EmbeddedSecurityConfig("https://parent.example.com")} {
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected built-in config to be valid, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		configure   func(*SecurityConfig)
+		expectedErr string
+	}{
+		{name: "Unquoted self", configure: func(c *SecurityConfig) { c.CSPScriptSrc = []string{"self"} }, expectedErr: "must be single-quoted"},
+		{name: "Unquoted nonce", configure: func(c *SecurityConfig) { c.CSPScriptSrc = []string{"'self'", "nonce-abc123"} }, expectedErr: "must be single-quoted"},
+		{name: "Unknown keyword", configure: func(c *SecurityConfig) { c.CSPStyleSrc = []string{"'slef'"} }, expectedErr: "unknown keyword 'slef'"},
+		{name: "Unbalanced quotes", configure: func(c *SecurityConfig) { c.CSPDefaultSrc = []string{"'self"} }, expectedErr: "unbalanced quotes"},
+		{name: "Unknown scheme", configure: func(c *SecurityConfig) { c.CSPImgSrc = []string{"htps://cdn.example.com"} }, expectedErr: `unknown scheme "htps"`},
+		{name: "Unknown scheme source", configure: func(c *SecurityConfig) { c.CSPImgSrc = []string{"dta:"} }, expectedErr: `unknown scheme "dta"`},
+		{name: "Directive name as source", configure: func(c *SecurityConfig) { c.CSPScriptSrc = []string{"'self'", "scrpt-src"} }, expectedErr: "looks like a directive name"},
+		{name: "Several sources in one entry", configure: func(c *SecurityConfig) { c.CSPConnectSrc = []string{"'self' https://api.example.com"} }, expectedErr: "contains a separator"},
+		{name: "Empty source", configure: func(c *SecurityConfig) { c.CSPFontSrc = []string{""} }, expectedErr: "empty source"},
+		{name: "None with other sources", configure: func(c *SecurityConfig) { c.CSPObjectSrc = []string{"'none'", "'self'"} }, expectedErr: "'none' cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultSecurityConfig()
+			tt.configure(config)
+
+			err := config.Validate()
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+			if !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestSecurityConfigValidateReportsAll verifies every problem is listed with its directive
+func TestSecurityConfigValidateReportsAll(t *testing.T) {
+	config := DefaultSecurityConfig()
+	config.CSPScriptSrc = []string{"self"}
+	config.CSPImgSrc = []string{"ftp:"}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{"script-src:", "img-src:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %q", want, err)
+		}
+	}
+}
+
+// TestSecurityConfigCSPWarnings verifies 'unsafe-inline' next to a nonce or hash is flagged
+func TestSecurityConfigCSPWarnings(t *testing.T) {
+	config := DefaultSecurityConfig()
+	if warnings := config.CSPWarnings(); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for default config, got %v", warnings)
+	}
+
+	config.CSPScriptSrc = []string{"'self'", "'unsafe-inline'", "'nonce-r4nd0m'"}
+	config.CSPStyleSrc = []string{"'self'", "'unsafe-inline'", "'sha256-abc123='"}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected config to be valid, got %v", err)
+	}
+
+	warnings := config.CSPWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %v", warnings)
+	}
+	if !strings.HasPrefix(warnings[0], "script-src:") || !strings.HasPrefix(warnings[1], "style-src:") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+// TestSecurityHeadersMiddlewareLogsCSPWarnings verifies warnings are logged when the middleware is built
+func TestSecurityHeadersMiddlewareLogsCSPWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	common.SetLogOutput(&logs)
+	t.Cleanup(func() { common.SetLogOutput(nil) })
+
+	SecurityHeadersMiddleware(DefaultSecurityConfig())
+	if logs.Len() != 0 {
+		t.Errorf("Expected no warnings for default config, got %q", logs.String())
+	}
+
+	config := DefaultSecurityConfig()
+	config.CSPScriptSrc = []string{"'self'", "'unsafe-inline'", "'nonce-r4nd0m'"}
+	SecurityHeadersMiddleware(config)
+	if got := logs.String(); !strings.Contains(got, "WARNING: [WEB] CSP script-src: 'unsafe-inline' is ignored") {
+		t.Errorf("Expected the script-src warning to be logged, got %q", got)
+	}
+}
+
+// TestSecurityHeadersMiddlewareValidateCSP verifies construction panics on an invalid policy when asked to
+func TestSecurityHeadersMiddlewareValidateCSP(t *testing.T) {
+	config := DefaultSecurityConfig()
+	config.CSPScriptSrc = []string{"self"}
+
+	// Without ValidateCSP the config is used as-is
+	SecurityHeadersMiddleware(config)
+
+	config.ValidateCSP = true
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for invalid CSP")
+		}
+	}()
+	SecurityHeadersMiddleware(config)
+}
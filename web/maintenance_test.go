@@ -0,0 +1,87 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMaintenanceMiddleware verifies the 503 page and each bypass
+func TestMaintenanceMiddleware(t *testing.T) {
+	cfg := DefaultMaintenanceConfig()
+	cfg.Enabled.Store(true)
+	cfg.AllowedPaths = append(cfg.AllowedPaths, "/static/")
+	cfg.RetryAfter = 2 * time.Minute
+	cfg.Bypass = func(r *http.Request) bool { return r.Header.Get("X-Test-Admin") == "yes" }
+
+	handler := MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		admin        bool
+		expectedCode int
+	}{
+		{name: "Regular page blocked", path: "/dashboard", expectedCode: http.StatusServiceUnavailable},
+		{name: "Health check allowed", path: "/healthz", expectedCode: http.StatusOK},
+		{name: "App Engine health check allowed", path: "/_ah/health", expectedCode: http.StatusOK},
+		{name: "Allowed prefix", path: "/static/app.css", expectedCode: http.StatusOK},
+		{name: "Exact path is not a prefix", path: "/healthz/deep", expectedCode: http.StatusServiceUnavailable},
+		{name: "Admin bypass", path: "/dashboard", admin: true, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.admin {
+				req.Header.Set("X-Test-Admin", "yes")
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if tt.expectedCode != http.StatusServiceUnavailable {
+				return
+			}
+			if got := rec.Header().Get("Retry-After"); got != "120" {
+				t.Errorf("Retry-After = %q, want 120", got)
+			}
+			if !strings.Contains(rec.Body.String(), "maintenance") {
+				t.Errorf("Expected maintenance page, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestMaintenanceMiddlewareToggle verifies the flag is read on every request
+func TestMaintenanceMiddlewareToggle(t *testing.T) {
+	t.Setenv(MaintenanceEnvVar, "true")
+	cfg := DefaultMaintenanceConfig()
+	if !cfg.Enabled.Load() {
+		t.Fatal("Expected MAINTENANCE_MODE=true to enable maintenance")
+	}
+
+	handler := MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while enabled, got %d", code)
+	}
+	cfg.Enabled.Store(false)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected 200 once disabled, got %d", code)
+	}
+}
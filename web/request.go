@@ -0,0 +1,282 @@
+package web
+
+// Request guards reject malformed or abusive requests before they reach
+// handlers. They complement the response-side headers in security.go.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// BodyLimit overrides the request body limit for paths starting with
+// PathPrefix. A Limit of zero or less disables the limit for that route.
+type BodyLimit struct {
+	PathPrefix string
+	Limit      int64
+}
+
+// MaxBodyBytesMiddleware caps request bodies at limit bytes, with optional
+// per-route overrides (the longest matching prefix wins):
+//
+//	web.MaxBodyBytesMiddleware(1<<20,
+//	    web.BodyLimit{PathPrefix: "/upload/", Limit: 50 << 20},
+//	    web.BodyLimit{PathPrefix: "/webhooks/", Limit: 64 << 10},
+//	)
+//
+// Requests whose Content-Length exceeds the limit are rejected with 413
+// before the handler runs. Bodies of unknown length are wrapped in
+// http.MaxBytesReader; if the handler hits the limit while reading, its
+// response is replaced with 413 as long as it has not been written yet.
+func MaxBodyBytesMiddleware(limit int64, routes ...BodyLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			max := bodyLimitFor(r.URL.Path, limit, routes)
+			if max <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > max {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, max)}
+			r.Body = body
+			lw := &bodyLimitWriter{ResponseWriter: w, body: body}
+
+			next.ServeHTTP(lw, r)
+
+			if body.exceeded && !lw.wroteHeader {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+// bodyLimitFor returns the limit of the longest matching route prefix
+func bodyLimitFor(path string, limit int64, routes []BodyLimit) int64 {
+	matched := -1
+	for _, route := range routes {
+		if strings.HasPrefix(path, route.PathPrefix) && len(route.PathPrefix) > matched {
+			matched = len(route.PathPrefix)
+			limit = route.Limit
+		}
+	}
+	return limit
+}
+
+// limitedBody records whether the wrapped MaxBytesReader hit its limit
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter turns the handler's response into a 413 when the body
+// limit was hit before the response started.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	body        *limitedBody
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.body.exceeded {
+		w.discard = true
+		http.Error(w.ResponseWriter, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLimitWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RequireContentType rejects requests whose body is not one of the allowed
+// media types with 415 Unsupported Media Type, so a form-encoded body sent
+// to a JSON API fails loudly instead of being mis-parsed:
+//
+//	api := web.RequireContentType("application/json")(apiHandler)
+//
+// A type may use a wildcard subtype such as "application/*", and "*/*"
+// accepts anything that declares a type. Parameters like charset are
+// ignored. Only POST, PUT, PATCH and DELETE requests that carry a body are
+// checked; GET requests and empty bodies pass through.
+func RequireContentType(types ...string) func(http.Handler) http.Handler {
+	allowed := make([]string, 0, len(types))
+	for _, t := range types {
+		allowed = append(allowed, strings.ToLower(strings.TrimSpace(t)))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !methodHasBody(r.Method) || r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !contentTypeAllowed(mediaType, allowed) {
+				http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// methodHasBody reports whether requests with method are expected to carry a body
+func methodHasBody(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// contentTypeAllowed matches a parsed media type against allowed patterns
+func contentTypeAllowed(mediaType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == mediaType || pattern == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSanitizedHeaders lists request headers that only a proxy in front
+// of the app should set: client address and scheme headers, identity
+// headers injected by Identity-Aware Proxy, and hop-by-hop headers that
+// must not reach handlers.
+var DefaultSanitizedHeaders = []string{
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Forwarded-Port",
+	"X-Forwarded-Proto",
+	"X-Real-IP",
+	"X-Client-IP",
+	"True-Client-IP",
+	"X-Goog-Authenticated-User-Email",
+	"X-Goog-Authenticated-User-Id",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Keep-Alive",
+}
+
+// SanitizeHeadersMiddleware deletes the listed request headers before they
+// reach handlers, so a client cannot spoof its address, scheme or identity
+// by sending them itself. A nil headers list uses DefaultSanitizedHeaders.
+// When trusted is true the headers are kept; pass true only when every
+// request arrives through a proxy that overwrites them. To decide per
+// request based on the peer address, use TrustedProxies.SanitizeHeaders.
+func SanitizeHeadersMiddleware(trusted bool, headers []string) func(http.Handler) http.Handler {
+	return sanitizeHeaders(func(*http.Request) bool { return trusted }, headers)
+}
+
+// TrustedProxies is a set of proxy networks whose forwarding headers are
+// believed, such as a load balancer's address ranges.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDRs such as "10.0.0.0/8" or single
+// addresses such as "130.211.0.1"
+func ParseTrustedProxies(cidrs ...string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse trusted proxy %q: %v", cidr, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted proxy %q: %v", cidr, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// Contains reports whether the request's direct peer (RemoteAddr) is a
+// trusted proxy. Forwarding headers are never consulted.
+func (p TrustedProxies) Contains(r *http.Request) bool {
+	addr, err := netip.ParseAddrPort(r.RemoteAddr)
+	var ip netip.Addr
+	if err == nil {
+		ip = addr.Addr()
+	} else if ip, err = netip.ParseAddr(r.RemoteAddr); err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeHeaders is SanitizeHeadersMiddleware that keeps the headers only
+// on requests whose peer is a trusted proxy:
+//
+//	proxies, err := web.ParseTrustedProxies("130.211.0.0/22", "35.191.0.0/16")
+//	handler := proxies.SanitizeHeaders(nil)(mux)
+func (p TrustedProxies) SanitizeHeaders(headers []string) func(http.Handler) http.Handler {
+	return sanitizeHeaders(p.Contains, headers)
+}
+
+// sanitizeHeaders removes headers from requests for which trusted is false
+func sanitizeHeaders(trusted func(*http.Request) bool, headers []string) func(http.Handler) http.Handler {
+	if headers == nil {
+		headers = DefaultSanitizedHeaders
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !trusted(r) {
+				for _, h := range headers {
+					r.Header.Del(h)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoBodyHandler reads the whole body and reports read failures as 400,
+// like a typical JSON handler would.
+func echoBodyHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad body", http.StatusBadRequest)
+		return
+	}
+	w.Write(body)
+}
+
+// unknownLengthReader hides the body length so the server sees a chunked body
+type unknownLengthReader struct {
+	io.Reader
+}
+
+// TestMaxBodyBytesMiddleware verifies bodies under the limit pass and larger ones get 413
+func TestMaxBodyBytesMiddleware(t *testing.T) {
+	handler := MaxBodyBytesMiddleware(10,
+		BodyLimit{PathPrefix: "/upload/", Limit: 100},
+		BodyLimit{PathPrefix: "/upload/small/", Limit: 5},
+		BodyLimit{PathPrefix: "/unlimited/", Limit: 0},
+	)(http.HandlerFunc(echoBodyHandler))
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		unknownLength bool
+		expectedCode  int
+	}{
+		{name: "Under default limit", path: "/api", body: "short", expectedCode: http.StatusOK},
+		{name: "Exactly at limit", path: "/api", body: strings.Repeat("a", 10), expectedCode: http.StatusOK},
+		{name: "Over default limit", path: "/api", body: strings.Repeat("a", 11), expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "Over limit with unknown length", path: "/api", body: strings.Repeat("a", 50), unknownLength: true, expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "Route override allows more", path: "/upload/file", body: strings.Repeat("a", 50), expectedCode: http.StatusOK},
+		{name: "Longest prefix wins", path: "/upload/small/file", body: strings.Repeat("a", 6), expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "Disabled limit", path: "/unlimited/file", body: strings.Repeat("a", 1000), expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.unknownLength {
+				body = unknownLengthReader{body}
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if tt.expectedCode == http.StatusOK && rec.Body.String() != tt.body {
+				t.Errorf("Expected body to pass through unchanged")
+			}
+		})
+	}
+}
+
+// TestMaxBodyBytesMiddlewareHandlerIgnoresError verifies 413 even if the handler writes nothing
+func TestMaxBodyBytesMiddlewareHandlerIgnoresError(t *testing.T) {
+	handler := MaxBodyBytesMiddleware(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", unknownLengthReader{strings.NewReader("too long")})
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rec.Code)
+	}
+}
+
+// TestRequireContentType verifies allowed types pass, others get 415, and bodyless requests pass through
+func TestRequireContentType(t *testing.T) {
+	handler := RequireContentType("application/json", "text/*")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{name: "JSON accepted", method: http.MethodPost, contentType: "application/json", body: "{}", expectedCode: http.StatusOK},
+		{name: "Parameters ignored", method: http.MethodPut, contentType: "Application/JSON; charset=utf-8", body: "{}", expectedCode: http.StatusOK},
+		{name: "Wildcard subtype", method: http.MethodPost, contentType: "text/plain", body: "hi", expectedCode: http.StatusOK},
+		{name: "Form rejected", method: http.MethodPost, contentType: "application/x-www-form-urlencoded", body: "a=1", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Wildcard does not cross types", method: http.MethodPatch, contentType: "textual/plain", body: "hi", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Missing type rejected", method: http.MethodPost, contentType: "", body: "{}", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Malformed type rejected", method: http.MethodPost, contentType: "application/", body: "{}", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Bodyless GET passes", method: http.MethodGet, contentType: "", body: "", expectedCode: http.StatusOK},
+		{name: "GET ignores type", method: http.MethodGet, contentType: "application/xml", body: "", expectedCode: http.StatusOK},
+		{name: "Empty POST passes", method: http.MethodPost, contentType: "", body: "", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "/api", body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+		})
+	}
+}
+
+// spoofedRequest carries forwarding and identity headers a client might forge
+func spoofedRequest(remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Real-IP", "203.0.113.9")
+	r.Header.Set("X-Goog-Authenticated-User-Email", "accounts.google.com:admin@example.com")
+	r.Header.Set("X-Internal-User", "admin")
+	r.Header.Set("Accept", "text/html")
+	return r
+}
+
+// TestSanitizeHeadersMiddleware verifies spoofable headers are removed unless trusted
+func TestSanitizeHeadersMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted bool
+		headers []string
+		removed []string
+		kept    []string
+	}{
+		{
+			name:    "Untrusted with defaults",
+			removed: []string{"X-Forwarded-For", "X-Forwarded-Proto", "X-Real-IP", "X-Goog-Authenticated-User-Email"},
+			kept:    []string{"Accept", "X-Internal-User"},
+		},
+		{
+			name:    "Untrusted with custom list",
+			headers: []string{"x-internal-user"},
+			removed: []string{"X-Internal-User"},
+			kept:    []string{"Accept", "X-Forwarded-For"},
+		},
+		{
+			name:    "Trusted",
+			trusted: true,
+			kept:    []string{"Accept", "X-Forwarded-For", "X-Real-IP", "X-Goog-Authenticated-User-Email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen http.Header
+			handler := SanitizeHeadersMiddleware(tt.trusted, tt.headers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Header.Clone()
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), spoofedRequest("203.0.113.9:5000"))
+
+			for _, h := range tt.removed {
+				if seen.Get(h) != "" {
+					t.Errorf("Expected %s to be removed", h)
+				}
+			}
+			for _, h := range tt.kept {
+				if seen.Get(h) == "" {
+					t.Errorf("Expected %s to be kept", h)
+				}
+			}
+		})
+	}
+}
+
+// TestTrustedProxiesSanitizeHeaders verifies headers survive only from trusted peers
+func TestTrustedProxiesSanitizeHeaders(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8", "192.0.2.1", "2001:db8::/32")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantKept   bool
+	}{
+		{"Proxy in range", "10.1.2.3:443", true},
+		{"Single proxy address", "192.0.2.1:80", true},
+		{"IPv6 proxy", "[2001:db8::1]:443", true},
+		{"IPv4-mapped proxy", "[::ffff:10.1.2.3]:443", true},
+		{"Untrusted client", "203.0.113.9:5000", false},
+		{"Neighbouring address", "192.0.2.2:80", false},
+		{"Unparseable address", "unix-socket", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := proxies.SanitizeHeaders(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("X-Forwarded-For")
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), spoofedRequest(tt.remoteAddr))
+
+			if (got != "") != tt.wantKept {
+				t.Errorf("X-Forwarded-For = %q, wantKept %v", got, tt.wantKept)
+			}
+		})
+	}
+}
+
+// TestParseTrustedProxiesInvalid verifies malformed entries are rejected
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, cidr := range []string{"10.0.0.0/33", "not-an-ip", ""} {
+		if _, err := ParseTrustedProxies(cidr); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) expected an error", cidr)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/patdeg/common/csrf"
+)
+
+// newSecureStackHandler returns a stack with every layer enabled around a
+// handler that always responds 200
+func newSecureStackHandler(t *testing.T) http.Handler {
+	t.Helper()
+	return newSecureStackHandlerFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// newSecureStackHandlerFor returns a stack with every layer enabled around next
+func newSecureStackHandlerFor(t *testing.T, next http.Handler) http.Handler {
+	t.Helper()
+	store := csrf.NewTokenStore()
+	t.Cleanup(store.Stop)
+
+	opts := &SecureStackOptions{
+		Security:    DefaultSecurityConfig(),
+		RedirectTLS: true,
+		CSRF:        store,
+		RateLimit:   &RateLimitConfig{RequestsPerSecond: 0.001, Burst: 3},
+	}
+	opts.Security.AllowedOrigins = []string{"https://app.example.com"}
+	opts.Security.AllowedMethods = []string{"GET", "POST"}
+
+	return SecureStack(opts)(next)
+}
+
+func secureRequest(method, target string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	return r
+}
+
+// TestSecureStackLayers verifies each layer's effect through the composed handler
+func TestSecureStackLayers(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     func() *http.Request
+		wantStatus  int
+		wantHeaders []string
+		check       func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "TLS redirect",
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/page", nil) },
+			wantStatus: http.StatusMovedPermanently,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				if got := rec.Header().Get("Location"); got != "https://example.com/page" {
+					t.Errorf("Location = %q", got)
+				}
+				if rec.Header().Get("Content-Security-Policy") != "" {
+					t.Error("Expected the redirect to happen before any other layer")
+				}
+			},
+		},
+		{
+			name:        "Security headers and CSRF cookie",
+			request:     func() *http.Request { return secureRequest(http.MethodGet, "/page") },
+			wantStatus:  http.StatusOK,
+			wantHeaders: []string{"Strict-Transport-Security", "Content-Security-Policy", "X-Frame-Options", "X-RateLimit-Limit"},
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				if !strings.Contains(rec.Header().Get("Set-Cookie"), "csrf_token=") {
+					t.Errorf("Expected a CSRF cookie, got %q", rec.Header().Get("Set-Cookie"))
+				}
+			},
+		},
+		{
+			name: "CORS preflight answered before CSRF",
+			request: func() *http.Request {
+				r := secureRequest(http.MethodOptions, "/api/items")
+				r.Header.Set("Origin", "https://app.example.com")
+				r.Header.Set("Access-Control-Request-Method", "POST")
+				return r
+			},
+			wantStatus:  http.StatusNoContent,
+			wantHeaders: []string{"Strict-Transport-Security", "Access-Control-Allow-Origin"},
+		},
+		{
+			name: "CSRF rejection keeps security and CORS headers",
+			request: func() *http.Request {
+				r := secureRequest(http.MethodPost, "/api/items")
+				r.Header.Set("Origin", "https://app.example.com")
+				return r
+			},
+			wantStatus:  http.StatusForbidden,
+			wantHeaders: []string{"Strict-Transport-Security", "Content-Security-Policy", "Access-Control-Allow-Origin"},
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				if rec.Header().Get("X-RateLimit-Limit") != "" {
+					t.Error("Expected forged requests to be rejected before the rate limit")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newSecureStackHandler(t)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.request())
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for _, header := range tt.wantHeaders {
+				if rec.Header().Get(header) == "" {
+					t.Errorf("Expected %s header", header)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, rec)
+			}
+		})
+	}
+}
+
+// TestSecureStackRateLimit verifies clients are limited independently
+func TestSecureStackRateLimit(t *testing.T) {
+	handler := newSecureStackHandler(t)
+
+	for i := range 3 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, secureRequest(http.MethodGet, "/page"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Request %d status = %d, want 200", i+1, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, secureRequest(http.MethodGet, "/page"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Status = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Expected Retry-After and an exhausted budget, got %v", rec.Header())
+	}
+	if rec.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("Expected security headers on 429 responses")
+	}
+
+	other := secureRequest(http.MethodGet, "/page")
+	other.RemoteAddr = "198.51.100.7:4321"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, other)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Other client status = %d, want 200", rec.Code)
+	}
+}
+
+// TestSecureStackDisabledLayers verifies layers left out of the options are skipped
+func TestSecureStackDisabledLayers(t *testing.T) {
+	handler := SecureStack(&SecureStackOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/form", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200 without TLS redirect or CSRF", rec.Code)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" {
+		t.Error("Expected security headers to always apply")
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Error("Did not expect rate limit headers")
+	}
+}
+
+// crossSiteCookies returns the cookies of rec a browser would still send
+// from a cross-site iframe: only SameSite=None cookies marked Secure
+func crossSiteCookies(rec *httptest.ResponseRecorder) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.SameSite == http.SameSiteNoneMode && c.Secure {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+// TestSecureStackEmbeddedCSRFRoundTrip verifies a form inside a cross-site
+// iframe can load a token and post it back
+func TestSecureStackEmbeddedCSRFRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		csrfConfig func(security *SecurityConfig) *csrf.Config
+		wantStatus int
+	}{
+		{"Embedded CSRF config", (*SecurityConfig).CSRFConfig, http.StatusOK},
+		{"Default CSRF config", func(*SecurityConfig) *csrf.Config { return nil }, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			security := EmbeddedSecurityConfig("https://partner.example.com")
+			store := csrf.NewTokenStoreWithConfig(tt.csrfConfig(security))
+			t.Cleanup(store.Stop)
+
+			handler := SecureStack(&SecureStackOptions{Security: security, RedirectTLS: true, CSRF: store})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}))
+
+			// The iframe loads the form from the app
+			page := secureRequest(http.MethodGet, "/widget")
+			page.Header.Set("Sec-Fetch-Site", "cross-site")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, page)
+			token := ""
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == "csrf_token" {
+					token = c.Value
+				}
+			}
+			if token == "" {
+				t.Fatal("Expected a CSRF cookie on the widget page")
+			}
+
+			// The form posts back from inside the partner's page
+			post := secureRequest(http.MethodPost, "/widget")
+			post.Host = "example.com"
+			post.Header.Set("Origin", "https://example.com")
+			post.Header.Set("X-CSRF-Token", token)
+			for _, c := range crossSiteCookies(rec) {
+				post.AddCookie(c)
+			}
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, post)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Status = %d, want %d (%s)", rec.Code, tt.wantStatus, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
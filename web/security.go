@@ -21,12 +21,20 @@ package web
 //   consistently used before enabling preload in production environments.
 // - Cookie helpers apply Secure, HttpOnly, SameSite, and opt into __Host- prefix
 //   when safe, minimizing cookie scope and mitigating cookie injection risks.
+// - Embedded mode (CookieEmbedded) relaxes cookies to SameSite=None so they
+//   survive cross-site iframes. SameSite then no longer blocks CSRF, so every
+//   state-changing endpoint MUST be protected by the csrf package, and
+//   CSPFrameAncestors must list the exact hosts allowed to embed the app.
+//   X-Frame-Options: DENY is only sent while frame-ancestors forbids framing.
 
 import (
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/patdeg/common"
+	"github.com/patdeg/common/csrf"
 )
 
 // SecurityConfig holds security-related configuration
@@ -66,8 +74,18 @@ type SecurityConfig struct {
 	CookieHTTPOnly bool
 	CookieSameSite http.SameSite
 
+	// CookieEmbedded forces SameSite=None and Secure on cookies so they are
+	// sent when the app runs inside a cross-site iframe. Only enable it for
+	// widgets that must be embedded; see EmbeddedSecurityConfig.
+	CookieEmbedded bool
+
 	// Feature policy / Permissions policy
 	PermissionsPolicy map[string]string
+
+	// ValidateCSP makes SecurityHeadersMiddleware check the CSP sources
+	// with Validate when it is constructed and panic on errors, so a
+	// malformed policy fails at startup instead of in the browser.
+	ValidateCSP bool
 }
 
 // DefaultSecurityConfig returns the default security configuration
@@ -136,10 +154,20 @@ func SecurityHeadersMiddleware(config *SecurityConfig) func(http.Handler) http.H
 		config = DefaultSecurityConfig()
 	}
 
+	if config.ValidateCSP {
+		if err := config.Validate(); err != nil {
+			panic("web: invalid SecurityConfig: " + err.Error())
+		}
+	}
+	for _, warning := range config.CSPWarnings() {
+		common.Warn("[WEB] CSP %s", warning)
+	}
+
 	// Pre-build static headers for performance
 	cspHeader := buildCSPHeader(config)
 	hstsHeader := buildHSTSHeader(config)
 	permissionsPolicyHeader := buildPermissionsPolicyHeader(config)
+	frameOptionsHeader := buildFrameOptionsHeader(config)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -166,7 +194,9 @@ func SecurityHeadersMiddleware(config *SecurityConfig) func(http.Handler) http.H
 			w.Header().Set("Cross-Origin-Resource-Policy", "same-origin")
 
 			// X-Frame-Options (legacy, but still useful)
-			w.Header().Set("X-Frame-Options", "DENY")
+			if frameOptionsHeader != "" {
+				w.Header().Set("X-Frame-Options", frameOptionsHeader)
+			}
 
 			// X-Content-Type-Options
 			w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -281,6 +311,46 @@ func CORSMiddleware(config *SecurityConfig) func(http.Handler) http.Handler {
 	}
 }
 
+// RouteCORS applies the CORS settings of Config (AllowedOrigins,
+// AllowedMethods, AllowedHeaders, AllowCredentials and MaxAge) to requests
+// whose path starts with PathPrefix. A nil Config denies all cross-origin
+// requests on that route.
+type RouteCORS struct {
+	PathPrefix string
+	Config     *SecurityConfig
+}
+
+// RouteCORSMiddleware applies a different CORS policy per path prefix, with
+// def used for paths that match no route (the longest matching prefix wins):
+//
+//	web.RouteCORSMiddleware(web.DefaultSecurityConfig(),
+//	    web.RouteCORS{PathPrefix: "/api/public/", Config: publicCORS},
+//	    web.RouteCORS{PathPrefix: "/api/private/", Config: nil},
+//	)
+//
+// Each route behaves exactly like CORSMiddleware with its config. A nil def
+// uses DefaultSecurityConfig, which denies all cross-origin requests.
+func RouteCORSMiddleware(def *SecurityConfig, routes ...RouteCORS) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallback := CORSMiddleware(def)(next)
+		handlers := make([]http.Handler, len(routes))
+		for i, route := range routes {
+			handlers[i] = CORSMiddleware(route.Config)(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler, matched := fallback, -1
+			for i, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.PathPrefix) && len(route.PathPrefix) > matched {
+					matched = len(route.PathPrefix)
+					handler = handlers[i]
+				}
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
 // TLSRedirectMiddleware redirects HTTP requests to HTTPS
 func TLSRedirectMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -298,6 +368,49 @@ func TLSRedirectMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// EmbeddedSecurityConfig returns the default configuration adjusted for an app
+// that is embedded cross-site in an iframe. Cookies use SameSite=None; Secure
+// and framing is allowed only for 'self' and the given ancestors (e.g.
+// "https://partner.example.com").
+//
+// Guardrails: with SameSite=None the browser attaches cookies to cross-site
+// requests, so CSRF protection relies entirely on tokens. Only use this
+// configuration together with the csrf middleware, configured with
+// CSRFConfig so its cookie reaches the iframe too, and never pass "*" or a
+// scheme-only source as an ancestor:
+//
+//	security := web.EmbeddedSecurityConfig("https://partner.example.com")
+//	opts := web.DefaultSecureStackOptions()
+//	opts.Security = security
+//	opts.CSRF = csrf.NewTokenStoreWithConfig(security.CSRFConfig())
+func EmbeddedSecurityConfig(frameAncestors ...string) *SecurityConfig {
+	config := DefaultSecurityConfig()
+	config.CookieEmbedded = true
+	config.CookieSecure = true
+	config.CookieSameSite = http.SameSiteNoneMode
+	config.CSPFrameAncestors = append([]string{"'self'"}, frameAncestors...)
+	return config
+}
+
+// CSRFConfig returns a csrf configuration whose token cookie follows the
+// cookie settings of c. In embedded mode the cookie is SameSite=None;
+// Secure, without which browsers drop it from requests made inside a
+// cross-site iframe and every POST fails, and origin checking is enabled
+// since SameSite no longer blocks cross-site requests.
+func (c *SecurityConfig) CSRFConfig() *csrf.Config {
+	config := csrf.DefaultConfig()
+	if c.CookieSameSite != 0 {
+		config.SameSite = c.CookieSameSite
+	}
+	config.Secure = c.CookieSecure
+	if c.CookieEmbedded {
+		config.SameSite = http.SameSiteNoneMode
+		config.Secure = true
+		config.CheckOrigin = true
+	}
+	return config
+}
+
 // SecureCookieConfig applies secure cookie settings to a cookie
 func SecureCookieConfig(cookie *http.Cookie, config *SecurityConfig) {
 	if config == nil {
@@ -309,6 +422,13 @@ func SecureCookieConfig(cookie *http.Cookie, config *SecurityConfig) {
 	cookie.HttpOnly = config.CookieHTTPOnly
 	cookie.SameSite = config.CookieSameSite
 
+	// Browsers reject SameSite=None cookies that are not Secure, so embedded
+	// mode always sets both together.
+	if config.CookieEmbedded {
+		cookie.Secure = true
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+
 	if config.CookiePath != "" {
 		cookie.Path = config.CookiePath
 	}
@@ -322,6 +442,10 @@ func SecureCookieConfig(cookie *http.Cookie, config *SecurityConfig) {
 		if !strings.HasPrefix(cookie.Name, "__Host-") {
 			cookie.Name = "__Host-" + cookie.Name
 		}
+	} else if cookie.Domain != "" {
+		// A domain-scoped cookie (e.g. shared with an embedding subdomain)
+		// cannot carry the __Host- prefix; browsers would drop it silently.
+		cookie.Name = strings.TrimPrefix(cookie.Name, "__Host-")
 	}
 }
 
@@ -464,6 +588,21 @@ func buildHSTSHeader(config *SecurityConfig) string {
 	return header
 }
 
+// buildFrameOptionsHeader constructs the X-Frame-Options header. DENY would
+// override frame-ancestors in older browsers, so it is omitted when the app
+// is embedded or frame-ancestors allows any framing.
+func buildFrameOptionsHeader(config *SecurityConfig) string {
+	if config.CookieEmbedded {
+		return ""
+	}
+	for _, source := range config.CSPFrameAncestors {
+		if source != "'none'" {
+			return ""
+		}
+	}
+	return "DENY"
+}
+
 // buildPermissionsPolicyHeader constructs the Permissions-Policy header
 func buildPermissionsPolicyHeader(config *SecurityConfig) string {
 	if len(config.PermissionsPolicy) == 0 {
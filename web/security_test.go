@@ -18,6 +18,8 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 		wantCORP   bool
 		wantNoSniff bool
 		wantCache   bool
+		config      *SecurityConfig
+		wantXFrame  string
 	}{
 		{
 			name:        "API route with cache control",
@@ -28,6 +30,7 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 			wantCORP:    true,
 			wantNoSniff: true,
 			wantCache:   true,
+			wantXFrame:  "DENY",
 		},
 		{
 			name:        "Auth route with cache control",
@@ -38,6 +41,7 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 			wantCORP:    true,
 			wantNoSniff: true,
 			wantCache:   true,
+			wantXFrame:  "DENY",
 		},
 		{
 			name:        "Regular route without cache control",
@@ -48,6 +52,31 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 			wantCORP:    true,
 			wantNoSniff: true,
 			wantCache:   false,
+			wantXFrame:  "DENY",
+		},
+		{
+			name:        "Embedded mode leaves framing to frame-ancestors",
+			path:        "/dashboard",
+			wantHSTS:    true,
+			wantCSP:     true,
+			wantCOOP:    true,
+			wantCORP:    true,
+			wantNoSniff: true,
+			config:      EmbeddedSecurityConfig("https://partner.example.com"),
+		},
+		{
+			name:        "Custom frame-ancestors without embedded cookies",
+			path:        "/dashboard",
+			wantHSTS:    true,
+			wantCSP:     true,
+			wantCOOP:    true,
+			wantCORP:    true,
+			wantNoSniff: true,
+			config: func() *SecurityConfig {
+				c := DefaultSecurityConfig()
+				c.CSPFrameAncestors = []string{"'self'"}
+				return c
+			}(),
 		},
 	}
 
@@ -57,7 +86,11 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 				w.WriteHeader(http.StatusOK)
 			})
 
-			middleware := SecurityHeadersMiddleware(DefaultSecurityConfig())
+			config := tt.config
+			if config == nil {
+				config = DefaultSecurityConfig()
+			}
+			middleware := SecurityHeadersMiddleware(config)
 			wrapped := middleware(handler)
 
 			req := httptest.NewRequest("GET", tt.path, nil)
@@ -120,8 +153,13 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 
 			// Check X-Frame-Options
 			xframe := rec.Header().Get("X-Frame-Options")
-			if xframe != "DENY" {
-				t.Errorf("Expected X-Frame-Options DENY, got %s", xframe)
+			if xframe != tt.wantXFrame {
+				t.Errorf("Expected X-Frame-Options %q, got %q", tt.wantXFrame, xframe)
+			}
+			if tt.config != nil {
+				if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "frame-ancestors 'self'") {
+					t.Errorf("Expected frame-ancestors to govern framing, got %s", csp)
+				}
 			}
 		})
 	}
@@ -361,6 +399,109 @@ func TestSecureCookieConfig(t *testing.T) {
 	}
 }
 
+// TestSecureCookieConfigEmbedded verifies cookie attributes in strict and embedded modes
+func TestSecureCookieConfigEmbedded(t *testing.T) {
+	strictWithoutSecure := DefaultSecurityConfig()
+	strictWithoutSecure.CookieSecure = false
+
+	embeddedWithDomain := EmbeddedSecurityConfig("https://partner.example.com")
+	embeddedWithDomain.CookieDomain = "example.com"
+
+	embeddedInsecure := EmbeddedSecurityConfig()
+	embeddedInsecure.CookieSecure = false
+
+	tests := []struct {
+		name           string
+		config         *SecurityConfig
+		cookieName     string
+		expectName     string
+		expectSecure   bool
+		expectSameSite http.SameSite
+		expectDomain   string
+	}{
+		{
+			name:           "Strict default",
+			config:         DefaultSecurityConfig(),
+			cookieName:     "csrf_token",
+			expectName:     "__Host-csrf_token",
+			expectSecure:   true,
+			expectSameSite: http.SameSiteStrictMode,
+		},
+		{
+			name:           "Strict without Secure has no prefix",
+			config:         strictWithoutSecure,
+			cookieName:     "csrf_token",
+			expectName:     "csrf_token",
+			expectSecure:   false,
+			expectSameSite: http.SameSiteStrictMode,
+		},
+		{
+			name:           "Embedded host-only keeps __Host- prefix",
+			config:         EmbeddedSecurityConfig(),
+			cookieName:     "csrf_token",
+			expectName:     "__Host-csrf_token",
+			expectSecure:   true,
+			expectSameSite: http.SameSiteNoneMode,
+		},
+		{
+			name:           "Embedded with domain drops __Host- prefix",
+			config:         embeddedWithDomain,
+			cookieName:     "__Host-csrf_token",
+			expectName:     "csrf_token",
+			expectSecure:   true,
+			expectSameSite: http.SameSiteNoneMode,
+			expectDomain:   "example.com",
+		},
+		{
+			name:           "Embedded always forces Secure",
+			config:         embeddedInsecure,
+			cookieName:     "session",
+			expectName:     "__Host-session",
+			expectSecure:   true,
+			expectSameSite: http.SameSiteNoneMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie := &http.Cookie{Name: tt.cookieName, Value: "test-value"}
+			SecureCookieConfig(cookie, tt.config)
+
+			if cookie.Name != tt.expectName {
+				t.Errorf("Expected name %s, got %s", tt.expectName, cookie.Name)
+			}
+			if cookie.Secure != tt.expectSecure {
+				t.Errorf("Expected Secure %v, got %v", tt.expectSecure, cookie.Secure)
+			}
+			if cookie.SameSite != tt.expectSameSite {
+				t.Errorf("Expected SameSite %v, got %v", tt.expectSameSite, cookie.SameSite)
+			}
+			if cookie.Domain != tt.expectDomain {
+				t.Errorf("Expected Domain %q, got %q", tt.expectDomain, cookie.Domain)
+			}
+
+			header := cookie.String()
+			if tt.expectSameSite == http.SameSiteNoneMode && !strings.Contains(header, "SameSite=None") {
+				t.Errorf("Expected SameSite=None in %q", header)
+			}
+		})
+	}
+}
+
+// TestEmbeddedSecurityConfigFrameAncestors verifies framing is limited to listed hosts
+func TestEmbeddedSecurityConfigFrameAncestors(t *testing.T) {
+	config := EmbeddedSecurityConfig("https://partner.example.com")
+
+	csp := buildCSPHeader(config)
+	if !strings.Contains(csp, "frame-ancestors 'self' https://partner.example.com") {
+		t.Errorf("Expected frame-ancestors to allow partner, got %s", csp)
+	}
+
+	if DefaultSecurityConfig().CookieEmbedded {
+		t.Error("Expected embedded mode to be off by default")
+	}
+}
+
 // TestSanitizeRedirectTarget verifies open redirect protection
 func TestSanitizeRedirectTarget(t *testing.T) {
 	tests := []struct {
@@ -560,3 +701,63 @@ func TestRateLimitHeaders(t *testing.T) {
 		t.Errorf("Expected X-RateLimit-Reset 1234567890, got %s", reset)
 	}
 }
+
+// TestRouteCORSMiddleware verifies each route applies its own origin policy
+func TestRouteCORSMiddleware(t *testing.T) {
+	public := DefaultSecurityConfig()
+	public.AllowedOrigins = []string{"*"}
+
+	partner := DefaultSecurityConfig()
+	partner.AllowedOrigins = []string{"https://partner.example.com"}
+	partner.AllowCredentials = true
+
+	def := DefaultSecurityConfig()
+	def.AllowedOrigins = []string{"https://app.example.com"}
+
+	handler := RouteCORSMiddleware(def,
+		RouteCORS{PathPrefix: "/api/public/", Config: public},
+		RouteCORS{PathPrefix: "/api/private/", Config: nil},
+		RouteCORS{PathPrefix: "/api/public/partner/", Config: partner},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name             string
+		path             string
+		method           string
+		origin           string
+		expectAllowed    bool
+		expectStatusCode int
+	}{
+		{name: "Public allows any origin", path: "/api/public/items", method: "GET", origin: "https://anyone.example.org", expectAllowed: true, expectStatusCode: http.StatusOK},
+		{name: "Public preflight allowed", path: "/api/public/items", method: "OPTIONS", origin: "https://anyone.example.org", expectAllowed: true, expectStatusCode: http.StatusNoContent},
+		{name: "Private blocks app origin", path: "/api/private/items", method: "GET", origin: "https://app.example.com", expectAllowed: false, expectStatusCode: http.StatusOK},
+		{name: "Private preflight denied", path: "/api/private/items", method: "OPTIONS", origin: "https://anyone.example.org", expectAllowed: false, expectStatusCode: http.StatusForbidden},
+		{name: "Longest prefix wins", path: "/api/public/partner/feed", method: "GET", origin: "https://anyone.example.org", expectAllowed: false, expectStatusCode: http.StatusOK},
+		{name: "Partner origin on partner route", path: "/api/public/partner/feed", method: "GET", origin: "https://partner.example.com", expectAllowed: true, expectStatusCode: http.StatusOK},
+		{name: "Default allows app origin", path: "/account", method: "GET", origin: "https://app.example.com", expectAllowed: true, expectStatusCode: http.StatusOK},
+		{name: "Default blocks other origin", path: "/account", method: "GET", origin: "https://anyone.example.org", expectAllowed: false, expectStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectStatusCode {
+				t.Errorf("Expected status %d, got %d", tt.expectStatusCode, rec.Code)
+			}
+			allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.expectAllowed && allowOrigin != tt.origin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.origin, allowOrigin)
+			}
+			if !tt.expectAllowed && allowOrigin != "" {
+				t.Errorf("Expected no Access-Control-Allow-Origin, got %q", allowOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestComputeSRI checks the sha384 prefix and digest length, and a known vector
+func TestComputeSRI(t *testing.T) {
+	got := ComputeSRI([]byte("alert('Hello, world.');"))
+
+	digest, ok := strings.CutPrefix(got, "sha384-")
+	if !ok {
+		t.Fatalf("ComputeSRI() = %q, want sha384- prefix", got)
+	}
+	raw, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil || len(raw) != 48 {
+		t.Fatalf("digest %q is not 48 base64-encoded bytes", digest)
+	}
+
+	// Example from the W3C Subresource Integrity specification
+	want := "sha384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO"
+	if got != want {
+		t.Errorf("ComputeSRI() = %q, want %q", got, want)
+	}
+}
+
+// TestSRIScriptTag checks the rendered attributes and escaping
+func TestSRIScriptTag(t *testing.T) {
+	integrity := ComputeSRI([]byte("console.log(1)"))
+	got := string(SRIScriptTag("https://unpkg.com/htmx.org@1.9.12?a=1&b=2", integrity))
+
+	want := `<script src="https://unpkg.com/htmx.org@1.9.12?a=1&amp;b=2" integrity="` + integrity + `" crossorigin="anonymous"></script>`
+	if got != want {
+		t.Errorf("SRIScriptTag() = %s, want %s", got, want)
+	}
+
+	injected := string(SRIScriptTag(`https://cdn.example.com/x.js"><script>alert(1)</script>`, integrity))
+	if strings.Contains(injected, "<script>alert") {
+		t.Errorf("SRIScriptTag() did not escape the url: %s", injected)
+	}
+}
+
+// TestFetchSRI checks the fetched content is hashed and errors are reported
+func TestFetchSRI(t *testing.T) {
+	body := "console.log('cdn')"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.js" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	got, err := FetchSRI(context.Background(), server.URL+"/lib.js")
+	if err != nil {
+		t.Fatalf("FetchSRI() error = %v", err)
+	}
+	if want := ComputeSRI([]byte(body)); got != want {
+		t.Errorf("FetchSRI() = %q, want %q", got, want)
+	}
+
+	if _, err := FetchSRI(context.Background(), server.URL+"/missing.js"); err == nil {
+		t.Error("FetchSRI() expected error for 404")
+	}
+}
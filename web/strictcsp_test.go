@@ -0,0 +1,104 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestStrictCSPMiddlewareHeaderName verifies report-only by default and enforcing when toggled
+func TestStrictCSPMiddlewareHeaderName(t *testing.T) {
+	tests := []struct {
+		name       string
+		enforce    bool
+		wantHeader string
+		noHeader   string
+	}{
+		{name: "Report-only by default", enforce: false, wantHeader: "Content-Security-Policy-Report-Only", noHeader: "Content-Security-Policy"},
+		{name: "Enforcing", enforce: true, wantHeader: "Content-Security-Policy", noHeader: "Content-Security-Policy-Report-Only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultStrictCSPOptions()
+			opts.Enforce = tt.enforce
+			handler := StrictCSPMiddleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			policy := rec.Header().Get(tt.wantHeader)
+			if policy == "" {
+				t.Fatalf("Expected %s header", tt.wantHeader)
+			}
+			if rec.Header().Get(tt.noHeader) != "" {
+				t.Errorf("Did not expect %s header", tt.noHeader)
+			}
+			if strings.Contains(policy, "'unsafe-inline'") {
+				t.Errorf("Expected 'unsafe-inline' to be removed, got %q", policy)
+			}
+		})
+	}
+}
+
+// TestStrictCSPMiddlewareNonce verifies the handler sees the nonce used in the policy
+func TestStrictCSPMiddlewareNonce(t *testing.T) {
+	var seen string
+	handler := StrictCSPMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = CSPNonce(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if seen == "" {
+		t.Fatal("Expected the handler to see a nonce")
+	}
+	policy := rec.Header().Get("Content-Security-Policy-Report-Only")
+	if !strings.Contains(policy, "script-src 'self' https://unpkg.com") || !strings.Contains(policy, "'nonce-"+seen+"'") {
+		t.Errorf("Expected script-src with the handler's nonce, got %q", policy)
+	}
+	if strings.Contains(policy, cspNoncePlaceholder) {
+		t.Errorf("Placeholder leaked into policy %q", policy)
+	}
+
+	first := seen
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if seen == first {
+		t.Error("Expected a new nonce per request")
+	}
+
+	if got := CSPNonce(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != "" {
+		t.Errorf("Expected no nonce outside the middleware, got %q", got)
+	}
+}
+
+// TestStrictCSPMiddlewareOptions verifies reporting and inline style handling
+func TestStrictCSPMiddlewareOptions(t *testing.T) {
+	opts := &StrictCSPOptions{ReportURI: "/csp-report", KeepInlineStyles: true}
+	handler := StrictCSPMiddleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	policy := rec.Header().Get("Content-Security-Policy-Report-Only")
+	if !strings.HasSuffix(policy, "; report-uri /csp-report; report-to csp-endpoint") {
+		t.Errorf("Expected reporting directives, got %q", policy)
+	}
+	if got := rec.Header().Get("Reporting-Endpoints"); got != `csp-endpoint="/csp-report"` {
+		t.Errorf("Reporting-Endpoints = %q", got)
+	}
+	if !strings.Contains(policy, "style-src 'self' 'unsafe-inline'") {
+		t.Errorf("Expected inline styles to be kept, got %q", policy)
+	}
+}
+
+// TestSRIScriptTagNonce verifies the nonce attribute is rendered and escaped
+func TestSRIScriptTagNonce(t *testing.T) {
+	got := string(SRIScriptTagNonce("https://cdn.example.com/app.js", "sha384-abc", `n"1`))
+	want := `<script src="https://cdn.example.com/app.js" integrity="sha384-abc" crossorigin="anonymous" nonce="n&#34;1"></script>`
+	if got != want {
+		t.Errorf("SRIScriptTagNonce() = %s, want %s", got, want)
+	}
+}
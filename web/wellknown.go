@@ -0,0 +1,165 @@
+package web
+
+// Well-known resources live under /.well-known/ (RFC 8615). The one every
+// public site should publish is security.txt (RFC 9116), which tells
+// researchers where to report vulnerabilities.
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by DefaultWellKnownConfig
+const (
+	SecurityContactEnvVar = "SECURITY_TXT_CONTACT" // e.g. "mailto:security@example.com"
+	SecurityPolicyEnvVar  = "SECURITY_TXT_POLICY"  // e.g. "https://example.com/security-policy"
+)
+
+// wellKnownPrefix is the path WellKnownHandler is mounted on
+const wellKnownPrefix = "/.well-known/"
+
+// SecurityTxt holds the fields of a security.txt file
+type SecurityTxt struct {
+	// Contact lists where to report vulnerabilities, as mailto:, https: or
+	// tel: URIs. At least one is required.
+	Contact []string
+
+	// Expires is when the file should be considered stale. A zero value
+	// means one year after the handler was created.
+	Expires time.Time
+
+	// Policy links to the vulnerability disclosure policy. Optional.
+	Policy string
+
+	// Encryption links to a key for encrypted reports. Optional.
+	Encryption string
+
+	// Acknowledgments links to a page thanking reporters. Optional.
+	Acknowledgments string
+
+	// PreferredLanguages is a list of language tags such as "en, fr". Optional.
+	PreferredLanguages string
+
+	// Canonical is the URL the file is served from. Optional.
+	Canonical string
+}
+
+// String renders the file in RFC 9116 format
+func (s *SecurityTxt) String() string {
+	var b strings.Builder
+	for _, contact := range s.Contact {
+		b.WriteString("Contact: " + contact + "\n")
+	}
+	b.WriteString("Expires: " + s.Expires.UTC().Format(time.RFC3339) + "\n")
+
+	optional := []struct{ name, value string }{
+		{"Encryption", s.Encryption},
+		{"Acknowledgments", s.Acknowledgments},
+		{"Preferred-Languages", s.PreferredLanguages},
+		{"Canonical", s.Canonical},
+		{"Policy", s.Policy},
+	}
+	for _, field := range optional {
+		if field.value != "" {
+			b.WriteString(field.name + ": " + field.value + "\n")
+		}
+	}
+	return b.String()
+}
+
+// WellKnownResource is a static file served under /.well-known/
+type WellKnownResource struct {
+	ContentType string
+	Body        []byte
+}
+
+// WellKnownConfig configures WellKnownHandler
+type WellKnownConfig struct {
+	// SecurityTxt is served as /.well-known/security.txt. Nil disables it.
+	SecurityTxt *SecurityTxt
+
+	// Resources maps other names below /.well-known/ to their content,
+	// e.g. "assetlinks.json" or "apple-app-site-association"
+	Resources map[string]WellKnownResource
+
+	// MaxAge sets Cache-Control max-age. Zero disables caching.
+	MaxAge time.Duration
+}
+
+// DefaultWellKnownConfig returns a configuration cached for a day that
+// serves security.txt when SECURITY_TXT_CONTACT is set, with the policy
+// link from SECURITY_TXT_POLICY.
+func DefaultWellKnownConfig() *WellKnownConfig {
+	cfg := &WellKnownConfig{MaxAge: 24 * time.Hour}
+	if contact := os.Getenv(SecurityContactEnvVar); contact != "" {
+		cfg.SecurityTxt = &SecurityTxt{
+			Contact: []string{contact},
+			Policy:  os.Getenv(SecurityPolicyEnvVar),
+		}
+	}
+	return cfg
+}
+
+// WellKnownHandler serves security.txt and other configured resources
+// under /.well-known/. Unknown names get 404 and methods other than GET and
+// HEAD get 405. A nil cfg uses DefaultWellKnownConfig:
+//
+//	cfg := web.DefaultWellKnownConfig()
+//	cfg.SecurityTxt = &web.SecurityTxt{
+//	    Contact: []string{"mailto:security@example.com"},
+//	    Policy:  "https://example.com/security-policy",
+//	}
+//	mux.Handle("/.well-known/", web.WellKnownHandler(cfg))
+func WellKnownHandler(cfg *WellKnownConfig) http.Handler {
+	if cfg == nil {
+		cfg = DefaultWellKnownConfig()
+	}
+
+	resources := make(map[string]WellKnownResource, len(cfg.Resources)+1)
+	for name, res := range cfg.Resources {
+		resources[strings.TrimPrefix(name, "/")] = res
+	}
+	if cfg.SecurityTxt != nil && len(cfg.SecurityTxt.Contact) > 0 {
+		txt := *cfg.SecurityTxt
+		if txt.Expires.IsZero() {
+			txt.Expires = time.Now().AddDate(1, 0, 0)
+		}
+		resources["security.txt"] = WellKnownResource{
+			ContentType: "text/plain; charset=utf-8",
+			Body:        []byte(txt.String()),
+		}
+	}
+
+	cacheControl := "no-cache"
+	if cfg.MaxAge > 0 {
+		cacheControl = "public, max-age=" + strconv.Itoa(int(cfg.MaxAge/time.Second))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, ok := resources[strings.TrimPrefix(r.URL.Path, wellKnownPrefix)]
+		if !ok || !strings.HasPrefix(r.URL.Path, wellKnownPrefix) {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		contentType := res.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(res.Body)))
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if r.Method != http.MethodHead {
+			w.Write(res.Body)
+		}
+	})
+}
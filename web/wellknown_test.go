@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWellKnownHandler verifies content, content types and 404s
+func TestWellKnownHandler(t *testing.T) {
+	cfg := &WellKnownConfig{
+		SecurityTxt: &SecurityTxt{
+			Contact: []string{"mailto:security@example.com"},
+			Expires: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+			Policy:  "https://example.com/security-policy",
+		},
+		Resources: map[string]WellKnownResource{
+			"assetlinks.json": {ContentType: "application/json", Body: []byte(`[]`)},
+		},
+		MaxAge: time.Hour,
+	}
+	handler := WellKnownHandler(cfg)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		contentType string
+		wantBody    string
+	}{
+		{
+			name: "security.txt", method: http.MethodGet, path: "/.well-known/security.txt",
+			wantStatus: http.StatusOK, contentType: "text/plain; charset=utf-8",
+			wantBody: "Contact: mailto:security@example.com\nExpires: 2030-01-02T03:04:05Z\nPolicy: https://example.com/security-policy\n",
+		},
+		{
+			name: "Other resource", method: http.MethodGet, path: "/.well-known/assetlinks.json",
+			wantStatus: http.StatusOK, contentType: "application/json", wantBody: "[]",
+		},
+		{name: "HEAD has no body", method: http.MethodHead, path: "/.well-known/security.txt", wantStatus: http.StatusOK, contentType: "text/plain; charset=utf-8"},
+		{name: "Unknown resource", method: http.MethodGet, path: "/.well-known/change-password", wantStatus: http.StatusNotFound},
+		{name: "Outside well-known", method: http.MethodGet, path: "/security.txt", wantStatus: http.StatusNotFound},
+		{name: "POST rejected", method: http.MethodPost, path: "/.well-known/security.txt", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+				t.Errorf("Cache-Control = %q", got)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("Body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestWellKnownHandlerDefaults verifies the environment configuration and default expiry
+func TestWellKnownHandlerDefaults(t *testing.T) {
+	t.Setenv(SecurityContactEnvVar, "https://example.com/report")
+	t.Setenv(SecurityPolicyEnvVar, "")
+
+	rec := httptest.NewRecorder()
+	WellKnownHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/security.txt", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "Contact: https://example.com/report\nExpires: ") {
+		t.Errorf("Unexpected body %q", body)
+	}
+	if strings.Contains(body, "Policy:") {
+		t.Errorf("Did not expect a policy line in %q", body)
+	}
+	expires, err := time.Parse(time.RFC3339, strings.TrimSpace(strings.SplitN(strings.Split(body, "\n")[1], ": ", 2)[1]))
+	if err != nil || expires.Before(time.Now().AddDate(0, 11, 0)) {
+		t.Errorf("Expected a default expiry about a year out, got %v (%v)", expires, err)
+	}
+
+	t.Setenv(SecurityContactEnvVar, "")
+	rec = httptest.NewRecorder()
+	WellKnownHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/security.txt", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a contact, got %d", rec.Code)
+	}
+}